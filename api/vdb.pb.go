@@ -1,27 +1,16 @@
 // Code generated by protoc-gen-go. DO NOT EDIT.
 // source: vdb.proto
 
-/*
-Package api is a generated protocol buffer package.
-
-It is generated from these files:
-	vdb.proto
-
-It has these top-level messages:
-	EmptyRequest
-	IDRequest
-	IDValueRequest
-	Response
-*/
 package api
 
-import proto "github.com/golang/protobuf/proto"
-import fmt "fmt"
-import math "math"
-
 import (
-	context "golang.org/x/net/context"
+	context "context"
+	fmt "fmt"
+	proto "github.com/golang/protobuf/proto"
 	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+	math "math"
 )
 
 // Reference imports to suppress errors if they are not otherwise used.
@@ -33,24 +22,288 @@ var _ = math.Inf
 // is compatible with the proto package it is being compiled against.
 // A compilation error at this line likely means your copy of the
 // proto package needs to be updated.
-const _ = proto.ProtoPackageIsVersion2 // please upgrade the proto package
+const _ = proto.ProtoPackageIsVersion3 // please upgrade the proto package
+
+// Consistency is the number of replicas that must respond before a request succeeds
+type Consistency int32
+
+const (
+	Consistency_DEFAULT Consistency = 0
+	Consistency_ONE     Consistency = 1
+	Consistency_QUORUM  Consistency = 2
+	Consistency_ALL     Consistency = 3
+)
+
+var Consistency_name = map[int32]string{
+	0: "DEFAULT",
+	1: "ONE",
+	2: "QUORUM",
+	3: "ALL",
+}
+
+var Consistency_value = map[string]int32{
+	"DEFAULT": 0,
+	"ONE":     1,
+	"QUORUM":  2,
+	"ALL":     3,
+}
+
+func (x Consistency) String() string {
+	return proto.EnumName(Consistency_name, int32(x))
+}
+
+func (Consistency) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_049c40c6b3e04bfb, []int{0}
+}
+
+type MemberState int32
+
+const (
+	MemberState_ALIVE   MemberState = 0
+	MemberState_SUSPECT MemberState = 1
+	MemberState_DEAD    MemberState = 2
+)
+
+var MemberState_name = map[int32]string{
+	0: "ALIVE",
+	1: "SUSPECT",
+	2: "DEAD",
+}
+
+var MemberState_value = map[string]int32{
+	"ALIVE":   0,
+	"SUSPECT": 1,
+	"DEAD":    2,
+}
+
+func (x MemberState) String() string {
+	return proto.EnumName(MemberState_name, int32(x))
+}
+
+func (MemberState) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_049c40c6b3e04bfb, []int{1}
+}
+
+// TxnDecision is the outcome of a transaction
+type TxnDecision int32
+
+const (
+	TxnDecision_PENDING TxnDecision = 0
+	TxnDecision_COMMIT  TxnDecision = 1
+	TxnDecision_ABORT   TxnDecision = 2
+)
+
+var TxnDecision_name = map[int32]string{
+	0: "PENDING",
+	1: "COMMIT",
+	2: "ABORT",
+}
+
+var TxnDecision_value = map[string]int32{
+	"PENDING": 0,
+	"COMMIT":  1,
+	"ABORT":   2,
+}
+
+func (x TxnDecision) String() string {
+	return proto.EnumName(TxnDecision_name, int32(x))
+}
+
+func (TxnDecision) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_049c40c6b3e04bfb, []int{2}
+}
+
+// Permission is the access a role has to keys.  Each permission includes the ones before it.
+type Permission int32
+
+const (
+	Permission_NONE  Permission = 0
+	Permission_READ  Permission = 1
+	Permission_WRITE Permission = 2
+	// ADMIN on every key is needed to manage users, roles, indexes and the cluster configuration
+	Permission_ADMIN Permission = 3
+)
+
+var Permission_name = map[int32]string{
+	0: "NONE",
+	1: "READ",
+	2: "WRITE",
+	3: "ADMIN",
+}
+
+var Permission_value = map[string]int32{
+	"NONE":  0,
+	"READ":  1,
+	"WRITE": 2,
+	"ADMIN": 3,
+}
+
+func (x Permission) String() string {
+	return proto.EnumName(Permission_name, int32(x))
+}
+
+func (Permission) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_049c40c6b3e04bfb, []int{3}
+}
 
 type EmptyRequest struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *EmptyRequest) Reset()         { *m = EmptyRequest{} }
+func (m *EmptyRequest) String() string { return proto.CompactTextString(m) }
+func (*EmptyRequest) ProtoMessage()    {}
+func (*EmptyRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_049c40c6b3e04bfb, []int{0}
+}
+
+func (m *EmptyRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_EmptyRequest.Unmarshal(m, b)
+}
+func (m *EmptyRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_EmptyRequest.Marshal(b, m, deterministic)
+}
+func (m *EmptyRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_EmptyRequest.Merge(m, src)
+}
+func (m *EmptyRequest) XXX_Size() int {
+	return xxx_messageInfo_EmptyRequest.Size(m)
+}
+func (m *EmptyRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_EmptyRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_EmptyRequest proto.InternalMessageInfo
+
+// Timestamp is a hybrid logical clock reading
+type Timestamp struct {
+	// physical is the wall clock time in Unix nanoseconds
+	Physical int64 `protobuf:"varint,1,opt,name=physical,proto3" json:"physical,omitempty"`
+	// logical orders events that share the same physical time
+	Logical              uint32   `protobuf:"varint,2,opt,name=logical,proto3" json:"logical,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Timestamp) Reset()         { *m = Timestamp{} }
+func (m *Timestamp) String() string { return proto.CompactTextString(m) }
+func (*Timestamp) ProtoMessage()    {}
+func (*Timestamp) Descriptor() ([]byte, []int) {
+	return fileDescriptor_049c40c6b3e04bfb, []int{1}
+}
+
+func (m *Timestamp) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Timestamp.Unmarshal(m, b)
+}
+func (m *Timestamp) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Timestamp.Marshal(b, m, deterministic)
+}
+func (m *Timestamp) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Timestamp.Merge(m, src)
+}
+func (m *Timestamp) XXX_Size() int {
+	return xxx_messageInfo_Timestamp.Size(m)
+}
+func (m *Timestamp) XXX_DiscardUnknown() {
+	xxx_messageInfo_Timestamp.DiscardUnknown(m)
 }
 
-func (m *EmptyRequest) Reset()                    { *m = EmptyRequest{} }
-func (m *EmptyRequest) String() string            { return proto.CompactTextString(m) }
-func (*EmptyRequest) ProtoMessage()               {}
-func (*EmptyRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{0} }
+var xxx_messageInfo_Timestamp proto.InternalMessageInfo
+
+func (m *Timestamp) GetPhysical() int64 {
+	if m != nil {
+		return m.Physical
+	}
+	return 0
+}
+
+func (m *Timestamp) GetLogical() uint32 {
+	if m != nil {
+		return m.Logical
+	}
+	return 0
+}
+
+type TimeRequest struct {
+	Timestamp            *Timestamp `protobuf:"bytes,1,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}   `json:"-"`
+	XXX_unrecognized     []byte     `json:"-"`
+	XXX_sizecache        int32      `json:"-"`
+}
+
+func (m *TimeRequest) Reset()         { *m = TimeRequest{} }
+func (m *TimeRequest) String() string { return proto.CompactTextString(m) }
+func (*TimeRequest) ProtoMessage()    {}
+func (*TimeRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_049c40c6b3e04bfb, []int{2}
+}
+
+func (m *TimeRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_TimeRequest.Unmarshal(m, b)
+}
+func (m *TimeRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_TimeRequest.Marshal(b, m, deterministic)
+}
+func (m *TimeRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_TimeRequest.Merge(m, src)
+}
+func (m *TimeRequest) XXX_Size() int {
+	return xxx_messageInfo_TimeRequest.Size(m)
+}
+func (m *TimeRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_TimeRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_TimeRequest proto.InternalMessageInfo
+
+func (m *TimeRequest) GetTimestamp() *Timestamp {
+	if m != nil {
+		return m.Timestamp
+	}
+	return nil
+}
 
 type IDRequest struct {
-	ID string `protobuf:"bytes,1,opt,name=ID,json=iD" json:"ID,omitempty"`
+	ID          string      `protobuf:"bytes,1,opt,name=ID,proto3" json:"ID,omitempty"`
+	Consistency Consistency `protobuf:"varint,2,opt,name=consistency,proto3,enum=api.Consistency" json:"consistency,omitempty"`
+	// epoch is the cluster configuration the client routed the request with, or zero if it did not route it
+	Epoch uint64 `protobuf:"varint,3,opt,name=epoch,proto3" json:"epoch,omitempty"`
+	// max_staleness lets a replica answer a Get from its own copy if it has synchronized with the other replicas of the key within this many milliseconds
+	MaxStaleness int64 `protobuf:"varint,4,opt,name=max_staleness,json=maxStaleness,proto3" json:"max_staleness,omitempty"`
+	// namespace is the namespace of the key, the default namespace is empty
+	Namespace            string   `protobuf:"bytes,5,opt,name=namespace,proto3" json:"namespace,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *IDRequest) Reset()         { *m = IDRequest{} }
+func (m *IDRequest) String() string { return proto.CompactTextString(m) }
+func (*IDRequest) ProtoMessage()    {}
+func (*IDRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_049c40c6b3e04bfb, []int{3}
+}
+
+func (m *IDRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_IDRequest.Unmarshal(m, b)
+}
+func (m *IDRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_IDRequest.Marshal(b, m, deterministic)
+}
+func (m *IDRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_IDRequest.Merge(m, src)
+}
+func (m *IDRequest) XXX_Size() int {
+	return xxx_messageInfo_IDRequest.Size(m)
+}
+func (m *IDRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_IDRequest.DiscardUnknown(m)
 }
 
-func (m *IDRequest) Reset()                    { *m = IDRequest{} }
-func (m *IDRequest) String() string            { return proto.CompactTextString(m) }
-func (*IDRequest) ProtoMessage()               {}
-func (*IDRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{1} }
+var xxx_messageInfo_IDRequest proto.InternalMessageInfo
 
 func (m *IDRequest) GetID() string {
 	if m != nil {
@@ -59,15 +312,78 @@ func (m *IDRequest) GetID() string {
 	return ""
 }
 
+func (m *IDRequest) GetConsistency() Consistency {
+	if m != nil {
+		return m.Consistency
+	}
+	return Consistency_DEFAULT
+}
+
+func (m *IDRequest) GetEpoch() uint64 {
+	if m != nil {
+		return m.Epoch
+	}
+	return 0
+}
+
+func (m *IDRequest) GetMaxStaleness() int64 {
+	if m != nil {
+		return m.MaxStaleness
+	}
+	return 0
+}
+
+func (m *IDRequest) GetNamespace() string {
+	if m != nil {
+		return m.Namespace
+	}
+	return ""
+}
+
 type IDValueRequest struct {
-	ID    string `protobuf:"bytes,1,opt,name=ID,json=iD" json:"ID,omitempty"`
-	Value string `protobuf:"bytes,2,opt,name=value" json:"value,omitempty"`
+	ID          string      `protobuf:"bytes,1,opt,name=ID,proto3" json:"ID,omitempty"`
+	Value       string      `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
+	Consistency Consistency `protobuf:"varint,3,opt,name=consistency,proto3,enum=api.Consistency" json:"consistency,omitempty"`
+	// context is the version returned by a previous Get
+	Context *VersionVector `protobuf:"bytes,4,opt,name=context,proto3" json:"context,omitempty"`
+	// epoch is the cluster configuration the client routed the request with, or zero if it did not route it
+	Epoch uint64 `protobuf:"varint,5,opt,name=epoch,proto3" json:"epoch,omitempty"`
+	// namespace is the namespace of the key, the default namespace is empty
+	Namespace string `protobuf:"bytes,6,opt,name=namespace,proto3" json:"namespace,omitempty"`
+	// expires is when the value expires, in milliseconds since the Unix epoch.
+	// Zero applies the default TTL of the key's namespace and a negative value keeps the value until it is replaced.
+	Expires int64 `protobuf:"varint,7,opt,name=expires,proto3" json:"expires,omitempty"`
+	// flags are opaque flags stored with the value, like the flags of memcached items
+	Flags                uint32   `protobuf:"varint,8,opt,name=flags,proto3" json:"flags,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
 }
 
-func (m *IDValueRequest) Reset()                    { *m = IDValueRequest{} }
-func (m *IDValueRequest) String() string            { return proto.CompactTextString(m) }
-func (*IDValueRequest) ProtoMessage()               {}
-func (*IDValueRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{2} }
+func (m *IDValueRequest) Reset()         { *m = IDValueRequest{} }
+func (m *IDValueRequest) String() string { return proto.CompactTextString(m) }
+func (*IDValueRequest) ProtoMessage()    {}
+func (*IDValueRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_049c40c6b3e04bfb, []int{4}
+}
+
+func (m *IDValueRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_IDValueRequest.Unmarshal(m, b)
+}
+func (m *IDValueRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_IDValueRequest.Marshal(b, m, deterministic)
+}
+func (m *IDValueRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_IDValueRequest.Merge(m, src)
+}
+func (m *IDValueRequest) XXX_Size() int {
+	return xxx_messageInfo_IDValueRequest.Size(m)
+}
+func (m *IDValueRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_IDValueRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_IDValueRequest proto.InternalMessageInfo
 
 func (m *IDValueRequest) GetID() string {
 	if m != nil {
@@ -83,171 +399,5332 @@ func (m *IDValueRequest) GetValue() string {
 	return ""
 }
 
-type Response struct {
-	Value string `protobuf:"bytes,1,opt,name=value" json:"value,omitempty"`
+func (m *IDValueRequest) GetConsistency() Consistency {
+	if m != nil {
+		return m.Consistency
+	}
+	return Consistency_DEFAULT
+}
+
+func (m *IDValueRequest) GetContext() *VersionVector {
+	if m != nil {
+		return m.Context
+	}
+	return nil
+}
+
+func (m *IDValueRequest) GetEpoch() uint64 {
+	if m != nil {
+		return m.Epoch
+	}
+	return 0
+}
+
+func (m *IDValueRequest) GetNamespace() string {
+	if m != nil {
+		return m.Namespace
+	}
+	return ""
+}
+
+func (m *IDValueRequest) GetExpires() int64 {
+	if m != nil {
+		return m.Expires
+	}
+	return 0
+}
+
+func (m *IDValueRequest) GetFlags() uint32 {
+	if m != nil {
+		return m.Flags
+	}
+	return 0
+}
+
+type GetManyRequest struct {
+	IDs         []string    `protobuf:"bytes,1,rep,name=IDs,proto3" json:"IDs,omitempty"`
+	Consistency Consistency `protobuf:"varint,2,opt,name=consistency,proto3,enum=api.Consistency" json:"consistency,omitempty"`
+	// epoch is the cluster configuration the client routed the request with, or zero if it did not route it
+	Epoch uint64 `protobuf:"varint,3,opt,name=epoch,proto3" json:"epoch,omitempty"`
+	// namespace is the namespace of the keys, the default namespace is empty
+	Namespace string `protobuf:"bytes,4,opt,name=namespace,proto3" json:"namespace,omitempty"`
+	// max_staleness lets this replica answer for the keys it has synchronized with the other replicas of within this many milliseconds
+	MaxStaleness         int64    `protobuf:"varint,5,opt,name=max_staleness,json=maxStaleness,proto3" json:"max_staleness,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GetManyRequest) Reset()         { *m = GetManyRequest{} }
+func (m *GetManyRequest) String() string { return proto.CompactTextString(m) }
+func (*GetManyRequest) ProtoMessage()    {}
+func (*GetManyRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_049c40c6b3e04bfb, []int{5}
+}
+
+func (m *GetManyRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetManyRequest.Unmarshal(m, b)
+}
+func (m *GetManyRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetManyRequest.Marshal(b, m, deterministic)
+}
+func (m *GetManyRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetManyRequest.Merge(m, src)
+}
+func (m *GetManyRequest) XXX_Size() int {
+	return xxx_messageInfo_GetManyRequest.Size(m)
+}
+func (m *GetManyRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetManyRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GetManyRequest proto.InternalMessageInfo
+
+func (m *GetManyRequest) GetIDs() []string {
+	if m != nil {
+		return m.IDs
+	}
+	return nil
+}
+
+func (m *GetManyRequest) GetConsistency() Consistency {
+	if m != nil {
+		return m.Consistency
+	}
+	return Consistency_DEFAULT
+}
+
+func (m *GetManyRequest) GetEpoch() uint64 {
+	if m != nil {
+		return m.Epoch
+	}
+	return 0
+}
+
+func (m *GetManyRequest) GetNamespace() string {
+	if m != nil {
+		return m.Namespace
+	}
+	return ""
+}
+
+func (m *GetManyRequest) GetMaxStaleness() int64 {
+	if m != nil {
+		return m.MaxStaleness
+	}
+	return 0
+}
+
+type GetManyResponse struct {
+	// responses holds the response for each ID, in the order they were requested
+	Responses            []*Response `protobuf:"bytes,1,rep,name=responses,proto3" json:"responses,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}    `json:"-"`
+	XXX_unrecognized     []byte      `json:"-"`
+	XXX_sizecache        int32       `json:"-"`
+}
+
+func (m *GetManyResponse) Reset()         { *m = GetManyResponse{} }
+func (m *GetManyResponse) String() string { return proto.CompactTextString(m) }
+func (*GetManyResponse) ProtoMessage()    {}
+func (*GetManyResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_049c40c6b3e04bfb, []int{6}
+}
+
+func (m *GetManyResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetManyResponse.Unmarshal(m, b)
+}
+func (m *GetManyResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetManyResponse.Marshal(b, m, deterministic)
+}
+func (m *GetManyResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetManyResponse.Merge(m, src)
+}
+func (m *GetManyResponse) XXX_Size() int {
+	return xxx_messageInfo_GetManyResponse.Size(m)
+}
+func (m *GetManyResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetManyResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GetManyResponse proto.InternalMessageInfo
+
+func (m *GetManyResponse) GetResponses() []*Response {
+	if m != nil {
+		return m.Responses
+	}
+	return nil
+}
+
+type SetManyRequest struct {
+	// values holds the value to set for each ID
+	Values      map[string]string `protobuf:"bytes,1,rep,name=values,proto3" json:"values,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	Consistency Consistency       `protobuf:"varint,2,opt,name=consistency,proto3,enum=api.Consistency" json:"consistency,omitempty"`
+	// epoch is the cluster configuration the client routed the request with, or zero if it did not route it
+	Epoch uint64 `protobuf:"varint,3,opt,name=epoch,proto3" json:"epoch,omitempty"`
+	// namespace is the namespace of the keys, the default namespace is empty
+	Namespace            string   `protobuf:"bytes,4,opt,name=namespace,proto3" json:"namespace,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *SetManyRequest) Reset()         { *m = SetManyRequest{} }
+func (m *SetManyRequest) String() string { return proto.CompactTextString(m) }
+func (*SetManyRequest) ProtoMessage()    {}
+func (*SetManyRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_049c40c6b3e04bfb, []int{7}
+}
+
+func (m *SetManyRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SetManyRequest.Unmarshal(m, b)
+}
+func (m *SetManyRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SetManyRequest.Marshal(b, m, deterministic)
+}
+func (m *SetManyRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SetManyRequest.Merge(m, src)
+}
+func (m *SetManyRequest) XXX_Size() int {
+	return xxx_messageInfo_SetManyRequest.Size(m)
+}
+func (m *SetManyRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_SetManyRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SetManyRequest proto.InternalMessageInfo
+
+func (m *SetManyRequest) GetValues() map[string]string {
+	if m != nil {
+		return m.Values
+	}
+	return nil
+}
+
+func (m *SetManyRequest) GetConsistency() Consistency {
+	if m != nil {
+		return m.Consistency
+	}
+	return Consistency_DEFAULT
+}
+
+func (m *SetManyRequest) GetEpoch() uint64 {
+	if m != nil {
+		return m.Epoch
+	}
+	return 0
+}
+
+func (m *SetManyRequest) GetNamespace() string {
+	if m != nil {
+		return m.Namespace
+	}
+	return ""
+}
+
+type VersionVector struct {
+	Clocks               map[string]uint64 `protobuf:"bytes,1,rep,name=clocks,proto3" json:"clocks,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"varint,2,opt,name=value,proto3"`
+	XXX_NoUnkeyedLiteral struct{}          `json:"-"`
+	XXX_unrecognized     []byte            `json:"-"`
+	XXX_sizecache        int32             `json:"-"`
+}
+
+func (m *VersionVector) Reset()         { *m = VersionVector{} }
+func (m *VersionVector) String() string { return proto.CompactTextString(m) }
+func (*VersionVector) ProtoMessage()    {}
+func (*VersionVector) Descriptor() ([]byte, []int) {
+	return fileDescriptor_049c40c6b3e04bfb, []int{8}
+}
+
+func (m *VersionVector) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_VersionVector.Unmarshal(m, b)
+}
+func (m *VersionVector) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_VersionVector.Marshal(b, m, deterministic)
+}
+func (m *VersionVector) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_VersionVector.Merge(m, src)
+}
+func (m *VersionVector) XXX_Size() int {
+	return xxx_messageInfo_VersionVector.Size(m)
+}
+func (m *VersionVector) XXX_DiscardUnknown() {
+	xxx_messageInfo_VersionVector.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_VersionVector proto.InternalMessageInfo
+
+func (m *VersionVector) GetClocks() map[string]uint64 {
+	if m != nil {
+		return m.Clocks
+	}
+	return nil
+}
+
+type Sibling struct {
+	Value   string         `protobuf:"bytes,1,opt,name=value,proto3" json:"value,omitempty"`
+	Version *VersionVector `protobuf:"bytes,2,opt,name=version,proto3" json:"version,omitempty"`
+	Deleted bool           `protobuf:"varint,3,opt,name=deleted,proto3" json:"deleted,omitempty"`
+	// timestamp is when the version was written
+	Timestamp *Timestamp `protobuf:"bytes,4,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	// expires is when the version expires, in milliseconds since the Unix epoch, or zero if it does not
+	Expires              int64    `protobuf:"varint,5,opt,name=expires,proto3" json:"expires,omitempty"`
+	Flags                uint32   `protobuf:"varint,6,opt,name=flags,proto3" json:"flags,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Sibling) Reset()         { *m = Sibling{} }
+func (m *Sibling) String() string { return proto.CompactTextString(m) }
+func (*Sibling) ProtoMessage()    {}
+func (*Sibling) Descriptor() ([]byte, []int) {
+	return fileDescriptor_049c40c6b3e04bfb, []int{9}
+}
+
+func (m *Sibling) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Sibling.Unmarshal(m, b)
+}
+func (m *Sibling) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Sibling.Marshal(b, m, deterministic)
+}
+func (m *Sibling) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Sibling.Merge(m, src)
+}
+func (m *Sibling) XXX_Size() int {
+	return xxx_messageInfo_Sibling.Size(m)
+}
+func (m *Sibling) XXX_DiscardUnknown() {
+	xxx_messageInfo_Sibling.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Sibling proto.InternalMessageInfo
+
+func (m *Sibling) GetValue() string {
+	if m != nil {
+		return m.Value
+	}
+	return ""
+}
+
+func (m *Sibling) GetVersion() *VersionVector {
+	if m != nil {
+		return m.Version
+	}
+	return nil
+}
+
+func (m *Sibling) GetDeleted() bool {
+	if m != nil {
+		return m.Deleted
+	}
+	return false
+}
+
+func (m *Sibling) GetTimestamp() *Timestamp {
+	if m != nil {
+		return m.Timestamp
+	}
+	return nil
+}
+
+func (m *Sibling) GetExpires() int64 {
+	if m != nil {
+		return m.Expires
+	}
+	return 0
+}
+
+func (m *Sibling) GetFlags() uint32 {
+	if m != nil {
+		return m.Flags
+	}
+	return 0
+}
+
+type Response struct {
+	Value string `protobuf:"bytes,1,opt,name=value,proto3" json:"value,omitempty"`
+	// siblings holds every concurrent version when they could not be resolved
+	Siblings []*Sibling     `protobuf:"bytes,2,rep,name=siblings,proto3" json:"siblings,omitempty"`
+	Context  *VersionVector `protobuf:"bytes,3,opt,name=context,proto3" json:"context,omitempty"`
+	// timestamp is the server's hybrid logical clock, it is only set by Time
+	Timestamp *Timestamp `protobuf:"bytes,4,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	// expires is when the value expires, in milliseconds since the Unix epoch, or zero if it does not
+	Expires int64 `protobuf:"varint,5,opt,name=expires,proto3" json:"expires,omitempty"`
+	// flags are the flags stored with the value
+	Flags                uint32   `protobuf:"varint,6,opt,name=flags,proto3" json:"flags,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Response) Reset()         { *m = Response{} }
+func (m *Response) String() string { return proto.CompactTextString(m) }
+func (*Response) ProtoMessage()    {}
+func (*Response) Descriptor() ([]byte, []int) {
+	return fileDescriptor_049c40c6b3e04bfb, []int{10}
+}
+
+func (m *Response) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Response.Unmarshal(m, b)
+}
+func (m *Response) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Response.Marshal(b, m, deterministic)
+}
+func (m *Response) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Response.Merge(m, src)
+}
+func (m *Response) XXX_Size() int {
+	return xxx_messageInfo_Response.Size(m)
+}
+func (m *Response) XXX_DiscardUnknown() {
+	xxx_messageInfo_Response.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Response proto.InternalMessageInfo
+
+func (m *Response) GetValue() string {
+	if m != nil {
+		return m.Value
+	}
+	return ""
+}
+
+func (m *Response) GetSiblings() []*Sibling {
+	if m != nil {
+		return m.Siblings
+	}
+	return nil
+}
+
+func (m *Response) GetContext() *VersionVector {
+	if m != nil {
+		return m.Context
+	}
+	return nil
+}
+
+func (m *Response) GetTimestamp() *Timestamp {
+	if m != nil {
+		return m.Timestamp
+	}
+	return nil
+}
+
+func (m *Response) GetExpires() int64 {
+	if m != nil {
+		return m.Expires
+	}
+	return 0
+}
+
+func (m *Response) GetFlags() uint32 {
+	if m != nil {
+		return m.Flags
+	}
+	return 0
+}
+
+type ScanRequest struct {
+	// namespace selects the keys of a namespace, the default namespace is empty
+	Namespace string `protobuf:"bytes,1,opt,name=namespace,proto3" json:"namespace,omitempty"`
+	// cursor is where the page starts, zero for the first page
+	Cursor               uint32   `protobuf:"varint,2,opt,name=cursor,proto3" json:"cursor,omitempty"`
+	Count                uint32   `protobuf:"varint,3,opt,name=count,proto3" json:"count,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ScanRequest) Reset()         { *m = ScanRequest{} }
+func (m *ScanRequest) String() string { return proto.CompactTextString(m) }
+func (*ScanRequest) ProtoMessage()    {}
+func (*ScanRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_049c40c6b3e04bfb, []int{11}
+}
+
+func (m *ScanRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ScanRequest.Unmarshal(m, b)
+}
+func (m *ScanRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ScanRequest.Marshal(b, m, deterministic)
+}
+func (m *ScanRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ScanRequest.Merge(m, src)
+}
+func (m *ScanRequest) XXX_Size() int {
+	return xxx_messageInfo_ScanRequest.Size(m)
+}
+func (m *ScanRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ScanRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ScanRequest proto.InternalMessageInfo
+
+func (m *ScanRequest) GetNamespace() string {
+	if m != nil {
+		return m.Namespace
+	}
+	return ""
+}
+
+func (m *ScanRequest) GetCursor() uint32 {
+	if m != nil {
+		return m.Cursor
+	}
+	return 0
+}
+
+func (m *ScanRequest) GetCount() uint32 {
+	if m != nil {
+		return m.Count
+	}
+	return 0
+}
+
+type ScanResponse struct {
+	// IDs holds the internal keys of the page
+	IDs []string `protobuf:"bytes,1,rep,name=IDs,proto3" json:"IDs,omitempty"`
+	// next is the cursor of the next page, or zero after the last page
+	Next                 uint32   `protobuf:"varint,2,opt,name=next,proto3" json:"next,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ScanResponse) Reset()         { *m = ScanResponse{} }
+func (m *ScanResponse) String() string { return proto.CompactTextString(m) }
+func (*ScanResponse) ProtoMessage()    {}
+func (*ScanResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_049c40c6b3e04bfb, []int{12}
+}
+
+func (m *ScanResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ScanResponse.Unmarshal(m, b)
+}
+func (m *ScanResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ScanResponse.Marshal(b, m, deterministic)
+}
+func (m *ScanResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ScanResponse.Merge(m, src)
+}
+func (m *ScanResponse) XXX_Size() int {
+	return xxx_messageInfo_ScanResponse.Size(m)
+}
+func (m *ScanResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_ScanResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ScanResponse proto.InternalMessageInfo
+
+func (m *ScanResponse) GetIDs() []string {
+	if m != nil {
+		return m.IDs
+	}
+	return nil
+}
+
+func (m *ScanResponse) GetNext() uint32 {
+	if m != nil {
+		return m.Next
+	}
+	return 0
+}
+
+type VersionedRequest struct {
+	ID                   string     `protobuf:"bytes,1,opt,name=ID,proto3" json:"ID,omitempty"`
+	Siblings             []*Sibling `protobuf:"bytes,2,rep,name=siblings,proto3" json:"siblings,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}   `json:"-"`
+	XXX_unrecognized     []byte     `json:"-"`
+	XXX_sizecache        int32      `json:"-"`
+}
+
+func (m *VersionedRequest) Reset()         { *m = VersionedRequest{} }
+func (m *VersionedRequest) String() string { return proto.CompactTextString(m) }
+func (*VersionedRequest) ProtoMessage()    {}
+func (*VersionedRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_049c40c6b3e04bfb, []int{13}
+}
+
+func (m *VersionedRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_VersionedRequest.Unmarshal(m, b)
+}
+func (m *VersionedRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_VersionedRequest.Marshal(b, m, deterministic)
+}
+func (m *VersionedRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_VersionedRequest.Merge(m, src)
+}
+func (m *VersionedRequest) XXX_Size() int {
+	return xxx_messageInfo_VersionedRequest.Size(m)
+}
+func (m *VersionedRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_VersionedRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_VersionedRequest proto.InternalMessageInfo
+
+func (m *VersionedRequest) GetID() string {
+	if m != nil {
+		return m.ID
+	}
+	return ""
+}
+
+func (m *VersionedRequest) GetSiblings() []*Sibling {
+	if m != nil {
+		return m.Siblings
+	}
+	return nil
+}
+
+type VersionedResponse struct {
+	ID                   string     `protobuf:"bytes,1,opt,name=ID,proto3" json:"ID,omitempty"`
+	Siblings             []*Sibling `protobuf:"bytes,2,rep,name=siblings,proto3" json:"siblings,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}   `json:"-"`
+	XXX_unrecognized     []byte     `json:"-"`
+	XXX_sizecache        int32      `json:"-"`
+}
+
+func (m *VersionedResponse) Reset()         { *m = VersionedResponse{} }
+func (m *VersionedResponse) String() string { return proto.CompactTextString(m) }
+func (*VersionedResponse) ProtoMessage()    {}
+func (*VersionedResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_049c40c6b3e04bfb, []int{14}
+}
+
+func (m *VersionedResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_VersionedResponse.Unmarshal(m, b)
+}
+func (m *VersionedResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_VersionedResponse.Marshal(b, m, deterministic)
+}
+func (m *VersionedResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_VersionedResponse.Merge(m, src)
+}
+func (m *VersionedResponse) XXX_Size() int {
+	return xxx_messageInfo_VersionedResponse.Size(m)
+}
+func (m *VersionedResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_VersionedResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_VersionedResponse proto.InternalMessageInfo
+
+func (m *VersionedResponse) GetID() string {
+	if m != nil {
+		return m.ID
+	}
+	return ""
+}
+
+func (m *VersionedResponse) GetSiblings() []*Sibling {
+	if m != nil {
+		return m.Siblings
+	}
+	return nil
+}
+
+// NodeLocator addresses a node in the storage tree by the first bytes of its hash
+type NodeLocator struct {
+	ID    uint32 `protobuf:"varint,1,opt,name=ID,proto3" json:"ID,omitempty"`
+	Bytes uint32 `protobuf:"varint,2,opt,name=bytes,proto3" json:"bytes,omitempty"`
+	// namespace selects the storage tree of a namespace
+	Namespace string `protobuf:"bytes,3,opt,name=namespace,proto3" json:"namespace,omitempty"`
+	// shard is the ID of the shard asking for a digest.  Only the keys that both shards replicate are included when it is set.
+	Shard                string   `protobuf:"bytes,4,opt,name=shard,proto3" json:"shard,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *NodeLocator) Reset()         { *m = NodeLocator{} }
+func (m *NodeLocator) String() string { return proto.CompactTextString(m) }
+func (*NodeLocator) ProtoMessage()    {}
+func (*NodeLocator) Descriptor() ([]byte, []int) {
+	return fileDescriptor_049c40c6b3e04bfb, []int{15}
+}
+
+func (m *NodeLocator) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_NodeLocator.Unmarshal(m, b)
+}
+func (m *NodeLocator) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_NodeLocator.Marshal(b, m, deterministic)
+}
+func (m *NodeLocator) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_NodeLocator.Merge(m, src)
+}
+func (m *NodeLocator) XXX_Size() int {
+	return xxx_messageInfo_NodeLocator.Size(m)
+}
+func (m *NodeLocator) XXX_DiscardUnknown() {
+	xxx_messageInfo_NodeLocator.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_NodeLocator proto.InternalMessageInfo
+
+func (m *NodeLocator) GetID() uint32 {
+	if m != nil {
+		return m.ID
+	}
+	return 0
+}
+
+func (m *NodeLocator) GetBytes() uint32 {
+	if m != nil {
+		return m.Bytes
+	}
+	return 0
+}
+
+func (m *NodeLocator) GetNamespace() string {
+	if m != nil {
+		return m.Namespace
+	}
+	return ""
+}
+
+func (m *NodeLocator) GetShard() string {
+	if m != nil {
+		return m.Shard
+	}
+	return ""
+}
+
+type KeyVersions struct {
+	ID       string     `protobuf:"bytes,1,opt,name=ID,proto3" json:"ID,omitempty"`
+	Siblings []*Sibling `protobuf:"bytes,2,rep,name=siblings,proto3" json:"siblings,omitempty"`
+	// expected is the digest the key's stored versions must have for an intent to be prepared, or zero if the intent is unconditional
+	Expected             uint64   `protobuf:"varint,3,opt,name=expected,proto3" json:"expected,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *KeyVersions) Reset()         { *m = KeyVersions{} }
+func (m *KeyVersions) String() string { return proto.CompactTextString(m) }
+func (*KeyVersions) ProtoMessage()    {}
+func (*KeyVersions) Descriptor() ([]byte, []int) {
+	return fileDescriptor_049c40c6b3e04bfb, []int{16}
+}
+
+func (m *KeyVersions) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_KeyVersions.Unmarshal(m, b)
+}
+func (m *KeyVersions) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_KeyVersions.Marshal(b, m, deterministic)
+}
+func (m *KeyVersions) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_KeyVersions.Merge(m, src)
+}
+func (m *KeyVersions) XXX_Size() int {
+	return xxx_messageInfo_KeyVersions.Size(m)
+}
+func (m *KeyVersions) XXX_DiscardUnknown() {
+	xxx_messageInfo_KeyVersions.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_KeyVersions proto.InternalMessageInfo
+
+func (m *KeyVersions) GetID() string {
+	if m != nil {
+		return m.ID
+	}
+	return ""
+}
+
+func (m *KeyVersions) GetSiblings() []*Sibling {
+	if m != nil {
+		return m.Siblings
+	}
+	return nil
+}
+
+func (m *KeyVersions) GetExpected() uint64 {
+	if m != nil {
+		return m.Expected
+	}
+	return 0
+}
+
+type DigestResponse struct {
+	Node   *NodeLocator `protobuf:"bytes,1,opt,name=node,proto3" json:"node,omitempty"`
+	Digest uint64       `protobuf:"varint,2,opt,name=digest,proto3" json:"digest,omitempty"`
+	// children maps each non-empty child to its digest
+	Children map[uint32]uint64 `protobuf:"bytes,3,rep,name=children,proto3" json:"children,omitempty" protobuf_key:"varint,1,opt,name=key,proto3" protobuf_val:"varint,2,opt,name=value,proto3"`
+	// values is only returned for leaf nodes
+	Values               []*KeyVersions `protobuf:"bytes,4,rep,name=values,proto3" json:"values,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}       `json:"-"`
+	XXX_unrecognized     []byte         `json:"-"`
+	XXX_sizecache        int32          `json:"-"`
+}
+
+func (m *DigestResponse) Reset()         { *m = DigestResponse{} }
+func (m *DigestResponse) String() string { return proto.CompactTextString(m) }
+func (*DigestResponse) ProtoMessage()    {}
+func (*DigestResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_049c40c6b3e04bfb, []int{17}
+}
+
+func (m *DigestResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DigestResponse.Unmarshal(m, b)
+}
+func (m *DigestResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DigestResponse.Marshal(b, m, deterministic)
+}
+func (m *DigestResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DigestResponse.Merge(m, src)
+}
+func (m *DigestResponse) XXX_Size() int {
+	return xxx_messageInfo_DigestResponse.Size(m)
+}
+func (m *DigestResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_DigestResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DigestResponse proto.InternalMessageInfo
+
+func (m *DigestResponse) GetNode() *NodeLocator {
+	if m != nil {
+		return m.Node
+	}
+	return nil
+}
+
+func (m *DigestResponse) GetDigest() uint64 {
+	if m != nil {
+		return m.Digest
+	}
+	return 0
+}
+
+func (m *DigestResponse) GetChildren() map[uint32]uint64 {
+	if m != nil {
+		return m.Children
+	}
+	return nil
+}
+
+func (m *DigestResponse) GetValues() []*KeyVersions {
+	if m != nil {
+		return m.Values
+	}
+	return nil
+}
+
+type Member struct {
+	ID      string      `protobuf:"bytes,1,opt,name=ID,proto3" json:"ID,omitempty"`
+	Address string      `protobuf:"bytes,2,opt,name=address,proto3" json:"address,omitempty"`
+	State   MemberState `protobuf:"varint,3,opt,name=state,proto3,enum=api.MemberState" json:"state,omitempty"`
+	// incarnation is incremented by a member to refute suspicion about itself
+	Incarnation uint64 `protobuf:"varint,4,opt,name=incarnation,proto3" json:"incarnation,omitempty"`
+	// updated is when the state last changed, in Unix nanoseconds
+	Updated              int64    `protobuf:"varint,5,opt,name=updated,proto3" json:"updated,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Member) Reset()         { *m = Member{} }
+func (m *Member) String() string { return proto.CompactTextString(m) }
+func (*Member) ProtoMessage()    {}
+func (*Member) Descriptor() ([]byte, []int) {
+	return fileDescriptor_049c40c6b3e04bfb, []int{18}
+}
+
+func (m *Member) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Member.Unmarshal(m, b)
+}
+func (m *Member) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Member.Marshal(b, m, deterministic)
+}
+func (m *Member) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Member.Merge(m, src)
+}
+func (m *Member) XXX_Size() int {
+	return xxx_messageInfo_Member.Size(m)
+}
+func (m *Member) XXX_DiscardUnknown() {
+	xxx_messageInfo_Member.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Member proto.InternalMessageInfo
+
+func (m *Member) GetID() string {
+	if m != nil {
+		return m.ID
+	}
+	return ""
+}
+
+func (m *Member) GetAddress() string {
+	if m != nil {
+		return m.Address
+	}
+	return ""
+}
+
+func (m *Member) GetState() MemberState {
+	if m != nil {
+		return m.State
+	}
+	return MemberState_ALIVE
+}
+
+func (m *Member) GetIncarnation() uint64 {
+	if m != nil {
+		return m.Incarnation
+	}
+	return 0
+}
+
+func (m *Member) GetUpdated() int64 {
+	if m != nil {
+		return m.Updated
+	}
+	return 0
+}
+
+type ShardInfo struct {
+	ID      string `protobuf:"bytes,1,opt,name=ID,proto3" json:"ID,omitempty"`
+	Address string `protobuf:"bytes,2,opt,name=address,proto3" json:"address,omitempty"`
+	// weight is the shard's share of the keys relative to other shards under ring placement
+	Weight               uint32   `protobuf:"varint,3,opt,name=weight,proto3" json:"weight,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ShardInfo) Reset()         { *m = ShardInfo{} }
+func (m *ShardInfo) String() string { return proto.CompactTextString(m) }
+func (*ShardInfo) ProtoMessage()    {}
+func (*ShardInfo) Descriptor() ([]byte, []int) {
+	return fileDescriptor_049c40c6b3e04bfb, []int{19}
+}
+
+func (m *ShardInfo) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ShardInfo.Unmarshal(m, b)
+}
+func (m *ShardInfo) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ShardInfo.Marshal(b, m, deterministic)
+}
+func (m *ShardInfo) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ShardInfo.Merge(m, src)
+}
+func (m *ShardInfo) XXX_Size() int {
+	return xxx_messageInfo_ShardInfo.Size(m)
+}
+func (m *ShardInfo) XXX_DiscardUnknown() {
+	xxx_messageInfo_ShardInfo.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ShardInfo proto.InternalMessageInfo
+
+func (m *ShardInfo) GetID() string {
+	if m != nil {
+		return m.ID
+	}
+	return ""
+}
+
+func (m *ShardInfo) GetAddress() string {
+	if m != nil {
+		return m.Address
+	}
+	return ""
+}
+
+func (m *ShardInfo) GetWeight() uint32 {
+	if m != nil {
+		return m.Weight
+	}
+	return 0
+}
+
+type ClusterConfiguration struct {
+	Epoch  uint64       `protobuf:"varint,1,opt,name=epoch,proto3" json:"epoch,omitempty"`
+	Size   uint32       `protobuf:"varint,2,opt,name=size,proto3" json:"size,omitempty"`
+	Shards []*ShardInfo `protobuf:"bytes,3,rep,name=shards,proto3" json:"shards,omitempty"`
+	// placement is the name of the placement strategy, "chunk" or "ring"
+	Placement string `protobuf:"bytes,4,opt,name=placement,proto3" json:"placement,omitempty"`
+	// virtual_nodes is the number of ring points per unit of shard weight
+	VirtualNodes         uint32   `protobuf:"varint,5,opt,name=virtual_nodes,json=virtualNodes,proto3" json:"virtual_nodes,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ClusterConfiguration) Reset()         { *m = ClusterConfiguration{} }
+func (m *ClusterConfiguration) String() string { return proto.CompactTextString(m) }
+func (*ClusterConfiguration) ProtoMessage()    {}
+func (*ClusterConfiguration) Descriptor() ([]byte, []int) {
+	return fileDescriptor_049c40c6b3e04bfb, []int{20}
+}
+
+func (m *ClusterConfiguration) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ClusterConfiguration.Unmarshal(m, b)
+}
+func (m *ClusterConfiguration) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ClusterConfiguration.Marshal(b, m, deterministic)
+}
+func (m *ClusterConfiguration) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ClusterConfiguration.Merge(m, src)
+}
+func (m *ClusterConfiguration) XXX_Size() int {
+	return xxx_messageInfo_ClusterConfiguration.Size(m)
+}
+func (m *ClusterConfiguration) XXX_DiscardUnknown() {
+	xxx_messageInfo_ClusterConfiguration.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ClusterConfiguration proto.InternalMessageInfo
+
+func (m *ClusterConfiguration) GetEpoch() uint64 {
+	if m != nil {
+		return m.Epoch
+	}
+	return 0
+}
+
+func (m *ClusterConfiguration) GetSize() uint32 {
+	if m != nil {
+		return m.Size
+	}
+	return 0
+}
+
+func (m *ClusterConfiguration) GetShards() []*ShardInfo {
+	if m != nil {
+		return m.Shards
+	}
+	return nil
+}
+
+func (m *ClusterConfiguration) GetPlacement() string {
+	if m != nil {
+		return m.Placement
+	}
+	return ""
+}
+
+func (m *ClusterConfiguration) GetVirtualNodes() uint32 {
+	if m != nil {
+		return m.VirtualNodes
+	}
+	return 0
+}
+
+type PlacementLoadRequest struct {
+	// placement and virtual_nodes describe the placement to measure.  The current placement is measured when placement is empty.
+	Placement    string `protobuf:"bytes,1,opt,name=placement,proto3" json:"placement,omitempty"`
+	VirtualNodes uint32 `protobuf:"varint,2,opt,name=virtual_nodes,json=virtualNodes,proto3" json:"virtual_nodes,omitempty"`
+	// keys is the sample of keys to place.  When it is empty, sample generated keys are placed instead.
+	Keys                 []string `protobuf:"bytes,3,rep,name=keys,proto3" json:"keys,omitempty"`
+	Sample               uint32   `protobuf:"varint,4,opt,name=sample,proto3" json:"sample,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *PlacementLoadRequest) Reset()         { *m = PlacementLoadRequest{} }
+func (m *PlacementLoadRequest) String() string { return proto.CompactTextString(m) }
+func (*PlacementLoadRequest) ProtoMessage()    {}
+func (*PlacementLoadRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_049c40c6b3e04bfb, []int{21}
+}
+
+func (m *PlacementLoadRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_PlacementLoadRequest.Unmarshal(m, b)
+}
+func (m *PlacementLoadRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_PlacementLoadRequest.Marshal(b, m, deterministic)
+}
+func (m *PlacementLoadRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_PlacementLoadRequest.Merge(m, src)
+}
+func (m *PlacementLoadRequest) XXX_Size() int {
+	return xxx_messageInfo_PlacementLoadRequest.Size(m)
+}
+func (m *PlacementLoadRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_PlacementLoadRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_PlacementLoadRequest proto.InternalMessageInfo
+
+func (m *PlacementLoadRequest) GetPlacement() string {
+	if m != nil {
+		return m.Placement
+	}
+	return ""
+}
+
+func (m *PlacementLoadRequest) GetVirtualNodes() uint32 {
+	if m != nil {
+		return m.VirtualNodes
+	}
+	return 0
+}
+
+func (m *PlacementLoadRequest) GetKeys() []string {
+	if m != nil {
+		return m.Keys
+	}
+	return nil
+}
+
+func (m *PlacementLoadRequest) GetSample() uint32 {
+	if m != nil {
+		return m.Sample
+	}
+	return 0
+}
+
+type PlacementLoadResponse struct {
+	Placement string `protobuf:"bytes,1,opt,name=placement,proto3" json:"placement,omitempty"`
+	Keys      uint64 `protobuf:"varint,2,opt,name=keys,proto3" json:"keys,omitempty"`
+	// counts is the number of keys each shard is the primary replica for, by shard ID
+	Counts map[string]uint64 `protobuf:"bytes,3,rep,name=counts,proto3" json:"counts,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"varint,2,opt,name=value,proto3"`
+	// skew is the load of the busiest shard relative to its fair share, 1 is perfectly balanced
+	Skew float64 `protobuf:"fixed64,4,opt,name=skew,proto3" json:"skew,omitempty"`
+	// std_dev is the standard deviation of each shard's load relative to its fair share
+	StdDev               float64  `protobuf:"fixed64,5,opt,name=std_dev,json=stdDev,proto3" json:"std_dev,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *PlacementLoadResponse) Reset()         { *m = PlacementLoadResponse{} }
+func (m *PlacementLoadResponse) String() string { return proto.CompactTextString(m) }
+func (*PlacementLoadResponse) ProtoMessage()    {}
+func (*PlacementLoadResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_049c40c6b3e04bfb, []int{22}
+}
+
+func (m *PlacementLoadResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_PlacementLoadResponse.Unmarshal(m, b)
+}
+func (m *PlacementLoadResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_PlacementLoadResponse.Marshal(b, m, deterministic)
+}
+func (m *PlacementLoadResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_PlacementLoadResponse.Merge(m, src)
+}
+func (m *PlacementLoadResponse) XXX_Size() int {
+	return xxx_messageInfo_PlacementLoadResponse.Size(m)
+}
+func (m *PlacementLoadResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_PlacementLoadResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_PlacementLoadResponse proto.InternalMessageInfo
+
+func (m *PlacementLoadResponse) GetPlacement() string {
+	if m != nil {
+		return m.Placement
+	}
+	return ""
+}
+
+func (m *PlacementLoadResponse) GetKeys() uint64 {
+	if m != nil {
+		return m.Keys
+	}
+	return 0
+}
+
+func (m *PlacementLoadResponse) GetCounts() map[string]uint64 {
+	if m != nil {
+		return m.Counts
+	}
+	return nil
+}
+
+func (m *PlacementLoadResponse) GetSkew() float64 {
+	if m != nil {
+		return m.Skew
+	}
+	return 0
+}
+
+func (m *PlacementLoadResponse) GetStdDev() float64 {
+	if m != nil {
+		return m.StdDev
+	}
+	return 0
+}
+
+type TxnOp struct {
+	ID    string `protobuf:"bytes,1,opt,name=ID,proto3" json:"ID,omitempty"`
+	Value string `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
+	// delete removes the key instead of setting it
+	Delete               bool     `protobuf:"varint,3,opt,name=delete,proto3" json:"delete,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *TxnOp) Reset()         { *m = TxnOp{} }
+func (m *TxnOp) String() string { return proto.CompactTextString(m) }
+func (*TxnOp) ProtoMessage()    {}
+func (*TxnOp) Descriptor() ([]byte, []int) {
+	return fileDescriptor_049c40c6b3e04bfb, []int{23}
+}
+
+func (m *TxnOp) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_TxnOp.Unmarshal(m, b)
+}
+func (m *TxnOp) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_TxnOp.Marshal(b, m, deterministic)
+}
+func (m *TxnOp) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_TxnOp.Merge(m, src)
+}
+func (m *TxnOp) XXX_Size() int {
+	return xxx_messageInfo_TxnOp.Size(m)
+}
+func (m *TxnOp) XXX_DiscardUnknown() {
+	xxx_messageInfo_TxnOp.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_TxnOp proto.InternalMessageInfo
+
+func (m *TxnOp) GetID() string {
+	if m != nil {
+		return m.ID
+	}
+	return ""
+}
+
+func (m *TxnOp) GetValue() string {
+	if m != nil {
+		return m.Value
+	}
+	return ""
+}
+
+func (m *TxnOp) GetDelete() bool {
+	if m != nil {
+		return m.Delete
+	}
+	return false
+}
+
+type TxnRequest struct {
+	Ops         []*TxnOp    `protobuf:"bytes,1,rep,name=ops,proto3" json:"ops,omitempty"`
+	Consistency Consistency `protobuf:"varint,2,opt,name=consistency,proto3,enum=api.Consistency" json:"consistency,omitempty"`
+	// epoch is the cluster configuration the client routed the request with, or zero if it did not route it
+	Epoch uint64 `protobuf:"varint,3,opt,name=epoch,proto3" json:"epoch,omitempty"`
+	// namespace is the namespace of every key in the transaction
+	Namespace            string   `protobuf:"bytes,4,opt,name=namespace,proto3" json:"namespace,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *TxnRequest) Reset()         { *m = TxnRequest{} }
+func (m *TxnRequest) String() string { return proto.CompactTextString(m) }
+func (*TxnRequest) ProtoMessage()    {}
+func (*TxnRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_049c40c6b3e04bfb, []int{24}
+}
+
+func (m *TxnRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_TxnRequest.Unmarshal(m, b)
+}
+func (m *TxnRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_TxnRequest.Marshal(b, m, deterministic)
+}
+func (m *TxnRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_TxnRequest.Merge(m, src)
+}
+func (m *TxnRequest) XXX_Size() int {
+	return xxx_messageInfo_TxnRequest.Size(m)
+}
+func (m *TxnRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_TxnRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_TxnRequest proto.InternalMessageInfo
+
+func (m *TxnRequest) GetOps() []*TxnOp {
+	if m != nil {
+		return m.Ops
+	}
+	return nil
+}
+
+func (m *TxnRequest) GetConsistency() Consistency {
+	if m != nil {
+		return m.Consistency
+	}
+	return Consistency_DEFAULT
+}
+
+func (m *TxnRequest) GetEpoch() uint64 {
+	if m != nil {
+		return m.Epoch
+	}
+	return 0
+}
+
+func (m *TxnRequest) GetNamespace() string {
+	if m != nil {
+		return m.Namespace
+	}
+	return ""
+}
+
+type TxnResponse struct {
+	Txid                 string      `protobuf:"bytes,1,opt,name=txid,proto3" json:"txid,omitempty"`
+	Decision             TxnDecision `protobuf:"varint,2,opt,name=decision,proto3,enum=api.TxnDecision" json:"decision,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}    `json:"-"`
+	XXX_unrecognized     []byte      `json:"-"`
+	XXX_sizecache        int32       `json:"-"`
+}
+
+func (m *TxnResponse) Reset()         { *m = TxnResponse{} }
+func (m *TxnResponse) String() string { return proto.CompactTextString(m) }
+func (*TxnResponse) ProtoMessage()    {}
+func (*TxnResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_049c40c6b3e04bfb, []int{25}
+}
+
+func (m *TxnResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_TxnResponse.Unmarshal(m, b)
+}
+func (m *TxnResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_TxnResponse.Marshal(b, m, deterministic)
+}
+func (m *TxnResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_TxnResponse.Merge(m, src)
+}
+func (m *TxnResponse) XXX_Size() int {
+	return xxx_messageInfo_TxnResponse.Size(m)
+}
+func (m *TxnResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_TxnResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_TxnResponse proto.InternalMessageInfo
+
+func (m *TxnResponse) GetTxid() string {
+	if m != nil {
+		return m.Txid
+	}
+	return ""
+}
+
+func (m *TxnResponse) GetDecision() TxnDecision {
+	if m != nil {
+		return m.Decision
+	}
+	return TxnDecision_PENDING
+}
+
+type PrepareRequest struct {
+	Txid string `protobuf:"bytes,1,opt,name=txid,proto3" json:"txid,omitempty"`
+	// coordinator is the ID of the shard coordinating the transaction
+	Coordinator          string         `protobuf:"bytes,2,opt,name=coordinator,proto3" json:"coordinator,omitempty"`
+	Intents              []*KeyVersions `protobuf:"bytes,3,rep,name=intents,proto3" json:"intents,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}       `json:"-"`
+	XXX_unrecognized     []byte         `json:"-"`
+	XXX_sizecache        int32          `json:"-"`
+}
+
+func (m *PrepareRequest) Reset()         { *m = PrepareRequest{} }
+func (m *PrepareRequest) String() string { return proto.CompactTextString(m) }
+func (*PrepareRequest) ProtoMessage()    {}
+func (*PrepareRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_049c40c6b3e04bfb, []int{26}
+}
+
+func (m *PrepareRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_PrepareRequest.Unmarshal(m, b)
+}
+func (m *PrepareRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_PrepareRequest.Marshal(b, m, deterministic)
+}
+func (m *PrepareRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_PrepareRequest.Merge(m, src)
+}
+func (m *PrepareRequest) XXX_Size() int {
+	return xxx_messageInfo_PrepareRequest.Size(m)
+}
+func (m *PrepareRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_PrepareRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_PrepareRequest proto.InternalMessageInfo
+
+func (m *PrepareRequest) GetTxid() string {
+	if m != nil {
+		return m.Txid
+	}
+	return ""
+}
+
+func (m *PrepareRequest) GetCoordinator() string {
+	if m != nil {
+		return m.Coordinator
+	}
+	return ""
+}
+
+func (m *PrepareRequest) GetIntents() []*KeyVersions {
+	if m != nil {
+		return m.Intents
+	}
+	return nil
+}
+
+type DecideRequest struct {
+	Txid                 string      `protobuf:"bytes,1,opt,name=txid,proto3" json:"txid,omitempty"`
+	Decision             TxnDecision `protobuf:"varint,2,opt,name=decision,proto3,enum=api.TxnDecision" json:"decision,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}    `json:"-"`
+	XXX_unrecognized     []byte      `json:"-"`
+	XXX_sizecache        int32       `json:"-"`
+}
+
+func (m *DecideRequest) Reset()         { *m = DecideRequest{} }
+func (m *DecideRequest) String() string { return proto.CompactTextString(m) }
+func (*DecideRequest) ProtoMessage()    {}
+func (*DecideRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_049c40c6b3e04bfb, []int{27}
+}
+
+func (m *DecideRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DecideRequest.Unmarshal(m, b)
+}
+func (m *DecideRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DecideRequest.Marshal(b, m, deterministic)
+}
+func (m *DecideRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DecideRequest.Merge(m, src)
+}
+func (m *DecideRequest) XXX_Size() int {
+	return xxx_messageInfo_DecideRequest.Size(m)
+}
+func (m *DecideRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_DecideRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DecideRequest proto.InternalMessageInfo
+
+func (m *DecideRequest) GetTxid() string {
+	if m != nil {
+		return m.Txid
+	}
+	return ""
+}
+
+func (m *DecideRequest) GetDecision() TxnDecision {
+	if m != nil {
+		return m.Decision
+	}
+	return TxnDecision_PENDING
+}
+
+type TxnStatusRequest struct {
+	Txid                 string   `protobuf:"bytes,1,opt,name=txid,proto3" json:"txid,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *TxnStatusRequest) Reset()         { *m = TxnStatusRequest{} }
+func (m *TxnStatusRequest) String() string { return proto.CompactTextString(m) }
+func (*TxnStatusRequest) ProtoMessage()    {}
+func (*TxnStatusRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_049c40c6b3e04bfb, []int{28}
+}
+
+func (m *TxnStatusRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_TxnStatusRequest.Unmarshal(m, b)
+}
+func (m *TxnStatusRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_TxnStatusRequest.Marshal(b, m, deterministic)
+}
+func (m *TxnStatusRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_TxnStatusRequest.Merge(m, src)
+}
+func (m *TxnStatusRequest) XXX_Size() int {
+	return xxx_messageInfo_TxnStatusRequest.Size(m)
+}
+func (m *TxnStatusRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_TxnStatusRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_TxnStatusRequest proto.InternalMessageInfo
+
+func (m *TxnStatusRequest) GetTxid() string {
+	if m != nil {
+		return m.Txid
+	}
+	return ""
+}
+
+type TxnStatusResponse struct {
+	Txid                 string      `protobuf:"bytes,1,opt,name=txid,proto3" json:"txid,omitempty"`
+	Decision             TxnDecision `protobuf:"varint,2,opt,name=decision,proto3,enum=api.TxnDecision" json:"decision,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}    `json:"-"`
+	XXX_unrecognized     []byte      `json:"-"`
+	XXX_sizecache        int32       `json:"-"`
+}
+
+func (m *TxnStatusResponse) Reset()         { *m = TxnStatusResponse{} }
+func (m *TxnStatusResponse) String() string { return proto.CompactTextString(m) }
+func (*TxnStatusResponse) ProtoMessage()    {}
+func (*TxnStatusResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_049c40c6b3e04bfb, []int{29}
+}
+
+func (m *TxnStatusResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_TxnStatusResponse.Unmarshal(m, b)
+}
+func (m *TxnStatusResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_TxnStatusResponse.Marshal(b, m, deterministic)
+}
+func (m *TxnStatusResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_TxnStatusResponse.Merge(m, src)
+}
+func (m *TxnStatusResponse) XXX_Size() int {
+	return xxx_messageInfo_TxnStatusResponse.Size(m)
+}
+func (m *TxnStatusResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_TxnStatusResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_TxnStatusResponse proto.InternalMessageInfo
+
+func (m *TxnStatusResponse) GetTxid() string {
+	if m != nil {
+		return m.Txid
+	}
+	return ""
+}
+
+func (m *TxnStatusResponse) GetDecision() TxnDecision {
+	if m != nil {
+		return m.Decision
+	}
+	return TxnDecision_PENDING
+}
+
+type WatchClusterRequest struct {
+	// epoch is the newest configuration the caller already has, only newer configurations are sent
+	Epoch                uint64   `protobuf:"varint,1,opt,name=epoch,proto3" json:"epoch,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *WatchClusterRequest) Reset()         { *m = WatchClusterRequest{} }
+func (m *WatchClusterRequest) String() string { return proto.CompactTextString(m) }
+func (*WatchClusterRequest) ProtoMessage()    {}
+func (*WatchClusterRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_049c40c6b3e04bfb, []int{30}
+}
+
+func (m *WatchClusterRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_WatchClusterRequest.Unmarshal(m, b)
+}
+func (m *WatchClusterRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_WatchClusterRequest.Marshal(b, m, deterministic)
+}
+func (m *WatchClusterRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_WatchClusterRequest.Merge(m, src)
+}
+func (m *WatchClusterRequest) XXX_Size() int {
+	return xxx_messageInfo_WatchClusterRequest.Size(m)
+}
+func (m *WatchClusterRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_WatchClusterRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_WatchClusterRequest proto.InternalMessageInfo
+
+func (m *WatchClusterRequest) GetEpoch() uint64 {
+	if m != nil {
+		return m.Epoch
+	}
+	return 0
+}
+
+type UpdateClusterRequest struct {
+	// expected_epoch must match the current epoch for the update to be applied
+	ExpectedEpoch uint64                `protobuf:"varint,1,opt,name=expected_epoch,json=expectedEpoch,proto3" json:"expected_epoch,omitempty"`
+	Config        *ClusterConfiguration `protobuf:"bytes,2,opt,name=config,proto3" json:"config,omitempty"`
+	// forwarded is set when a shard passes the update on to the config shard.  A forwarded update is never forwarded again.
+	Forwarded            bool     `protobuf:"varint,3,opt,name=forwarded,proto3" json:"forwarded,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *UpdateClusterRequest) Reset()         { *m = UpdateClusterRequest{} }
+func (m *UpdateClusterRequest) String() string { return proto.CompactTextString(m) }
+func (*UpdateClusterRequest) ProtoMessage()    {}
+func (*UpdateClusterRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_049c40c6b3e04bfb, []int{31}
+}
+
+func (m *UpdateClusterRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_UpdateClusterRequest.Unmarshal(m, b)
+}
+func (m *UpdateClusterRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_UpdateClusterRequest.Marshal(b, m, deterministic)
+}
+func (m *UpdateClusterRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_UpdateClusterRequest.Merge(m, src)
+}
+func (m *UpdateClusterRequest) XXX_Size() int {
+	return xxx_messageInfo_UpdateClusterRequest.Size(m)
+}
+func (m *UpdateClusterRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_UpdateClusterRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_UpdateClusterRequest proto.InternalMessageInfo
+
+func (m *UpdateClusterRequest) GetExpectedEpoch() uint64 {
+	if m != nil {
+		return m.ExpectedEpoch
+	}
+	return 0
+}
+
+func (m *UpdateClusterRequest) GetConfig() *ClusterConfiguration {
+	if m != nil {
+		return m.Config
+	}
+	return nil
+}
+
+func (m *UpdateClusterRequest) GetForwarded() bool {
+	if m != nil {
+		return m.Forwarded
+	}
+	return false
+}
+
+type PingRequest struct {
+	// from is the ID of the member sending the ping
+	From string `protobuf:"bytes,1,opt,name=from,proto3" json:"from,omitempty"`
+	// target is the ID of the member to probe on the sender's behalf, or empty for a direct ping
+	Target               string                `protobuf:"bytes,2,opt,name=target,proto3" json:"target,omitempty"`
+	Members              []*Member             `protobuf:"bytes,3,rep,name=members,proto3" json:"members,omitempty"`
+	Config               *ClusterConfiguration `protobuf:"bytes,4,opt,name=config,proto3" json:"config,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}              `json:"-"`
+	XXX_unrecognized     []byte                `json:"-"`
+	XXX_sizecache        int32                 `json:"-"`
+}
+
+func (m *PingRequest) Reset()         { *m = PingRequest{} }
+func (m *PingRequest) String() string { return proto.CompactTextString(m) }
+func (*PingRequest) ProtoMessage()    {}
+func (*PingRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_049c40c6b3e04bfb, []int{32}
+}
+
+func (m *PingRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_PingRequest.Unmarshal(m, b)
+}
+func (m *PingRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_PingRequest.Marshal(b, m, deterministic)
+}
+func (m *PingRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_PingRequest.Merge(m, src)
+}
+func (m *PingRequest) XXX_Size() int {
+	return xxx_messageInfo_PingRequest.Size(m)
+}
+func (m *PingRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_PingRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_PingRequest proto.InternalMessageInfo
+
+func (m *PingRequest) GetFrom() string {
+	if m != nil {
+		return m.From
+	}
+	return ""
+}
+
+func (m *PingRequest) GetTarget() string {
+	if m != nil {
+		return m.Target
+	}
+	return ""
+}
+
+func (m *PingRequest) GetMembers() []*Member {
+	if m != nil {
+		return m.Members
+	}
+	return nil
+}
+
+func (m *PingRequest) GetConfig() *ClusterConfiguration {
+	if m != nil {
+		return m.Config
+	}
+	return nil
+}
+
+type PingResponse struct {
+	Ack                  bool                  `protobuf:"varint,1,opt,name=ack,proto3" json:"ack,omitempty"`
+	Members              []*Member             `protobuf:"bytes,2,rep,name=members,proto3" json:"members,omitempty"`
+	Config               *ClusterConfiguration `protobuf:"bytes,3,opt,name=config,proto3" json:"config,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}              `json:"-"`
+	XXX_unrecognized     []byte                `json:"-"`
+	XXX_sizecache        int32                 `json:"-"`
+}
+
+func (m *PingResponse) Reset()         { *m = PingResponse{} }
+func (m *PingResponse) String() string { return proto.CompactTextString(m) }
+func (*PingResponse) ProtoMessage()    {}
+func (*PingResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_049c40c6b3e04bfb, []int{33}
+}
+
+func (m *PingResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_PingResponse.Unmarshal(m, b)
+}
+func (m *PingResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_PingResponse.Marshal(b, m, deterministic)
+}
+func (m *PingResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_PingResponse.Merge(m, src)
+}
+func (m *PingResponse) XXX_Size() int {
+	return xxx_messageInfo_PingResponse.Size(m)
+}
+func (m *PingResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_PingResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_PingResponse proto.InternalMessageInfo
+
+func (m *PingResponse) GetAck() bool {
+	if m != nil {
+		return m.Ack
+	}
+	return false
+}
+
+func (m *PingResponse) GetMembers() []*Member {
+	if m != nil {
+		return m.Members
+	}
+	return nil
+}
+
+func (m *PingResponse) GetConfig() *ClusterConfiguration {
+	if m != nil {
+		return m.Config
+	}
+	return nil
+}
+
+type MemberEvent struct {
+	// time is when the change was observed, in Unix nanoseconds
+	Time                 int64       `protobuf:"varint,1,opt,name=time,proto3" json:"time,omitempty"`
+	ID                   string      `protobuf:"bytes,2,opt,name=ID,proto3" json:"ID,omitempty"`
+	Address              string      `protobuf:"bytes,3,opt,name=address,proto3" json:"address,omitempty"`
+	From                 MemberState `protobuf:"varint,4,opt,name=from,proto3,enum=api.MemberState" json:"from,omitempty"`
+	To                   MemberState `protobuf:"varint,5,opt,name=to,proto3,enum=api.MemberState" json:"to,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}    `json:"-"`
+	XXX_unrecognized     []byte      `json:"-"`
+	XXX_sizecache        int32       `json:"-"`
+}
+
+func (m *MemberEvent) Reset()         { *m = MemberEvent{} }
+func (m *MemberEvent) String() string { return proto.CompactTextString(m) }
+func (*MemberEvent) ProtoMessage()    {}
+func (*MemberEvent) Descriptor() ([]byte, []int) {
+	return fileDescriptor_049c40c6b3e04bfb, []int{34}
+}
+
+func (m *MemberEvent) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_MemberEvent.Unmarshal(m, b)
+}
+func (m *MemberEvent) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_MemberEvent.Marshal(b, m, deterministic)
+}
+func (m *MemberEvent) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_MemberEvent.Merge(m, src)
+}
+func (m *MemberEvent) XXX_Size() int {
+	return xxx_messageInfo_MemberEvent.Size(m)
+}
+func (m *MemberEvent) XXX_DiscardUnknown() {
+	xxx_messageInfo_MemberEvent.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_MemberEvent proto.InternalMessageInfo
+
+func (m *MemberEvent) GetTime() int64 {
+	if m != nil {
+		return m.Time
+	}
+	return 0
+}
+
+func (m *MemberEvent) GetID() string {
+	if m != nil {
+		return m.ID
+	}
+	return ""
+}
+
+func (m *MemberEvent) GetAddress() string {
+	if m != nil {
+		return m.Address
+	}
+	return ""
+}
+
+func (m *MemberEvent) GetFrom() MemberState {
+	if m != nil {
+		return m.From
+	}
+	return MemberState_ALIVE
+}
+
+func (m *MemberEvent) GetTo() MemberState {
+	if m != nil {
+		return m.To
+	}
+	return MemberState_ALIVE
+}
+
+type MembersResponse struct {
+	Members []*Member `protobuf:"bytes,1,rep,name=members,proto3" json:"members,omitempty"`
+	// events holds the most recent membership changes, oldest first
+	Events               []*MemberEvent `protobuf:"bytes,2,rep,name=events,proto3" json:"events,omitempty"`
+	Epoch                uint64         `protobuf:"varint,3,opt,name=epoch,proto3" json:"epoch,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}       `json:"-"`
+	XXX_unrecognized     []byte         `json:"-"`
+	XXX_sizecache        int32          `json:"-"`
+}
+
+func (m *MembersResponse) Reset()         { *m = MembersResponse{} }
+func (m *MembersResponse) String() string { return proto.CompactTextString(m) }
+func (*MembersResponse) ProtoMessage()    {}
+func (*MembersResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_049c40c6b3e04bfb, []int{35}
+}
+
+func (m *MembersResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_MembersResponse.Unmarshal(m, b)
+}
+func (m *MembersResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_MembersResponse.Marshal(b, m, deterministic)
+}
+func (m *MembersResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_MembersResponse.Merge(m, src)
+}
+func (m *MembersResponse) XXX_Size() int {
+	return xxx_messageInfo_MembersResponse.Size(m)
+}
+func (m *MembersResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_MembersResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_MembersResponse proto.InternalMessageInfo
+
+func (m *MembersResponse) GetMembers() []*Member {
+	if m != nil {
+		return m.Members
+	}
+	return nil
+}
+
+func (m *MembersResponse) GetEvents() []*MemberEvent {
+	if m != nil {
+		return m.Events
+	}
+	return nil
+}
+
+func (m *MembersResponse) GetEpoch() uint64 {
+	if m != nil {
+		return m.Epoch
+	}
+	return 0
+}
+
+type IndexDefinition struct {
+	Name string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	// path is the dot separated path of the indexed field in JSON values, such as "address.city"
+	Path string `protobuf:"bytes,2,opt,name=path,proto3" json:"path,omitempty"`
+	// local only applies the request to the receiving server, it is used between nodes
+	Local                bool     `protobuf:"varint,3,opt,name=local,proto3" json:"local,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *IndexDefinition) Reset()         { *m = IndexDefinition{} }
+func (m *IndexDefinition) String() string { return proto.CompactTextString(m) }
+func (*IndexDefinition) ProtoMessage()    {}
+func (*IndexDefinition) Descriptor() ([]byte, []int) {
+	return fileDescriptor_049c40c6b3e04bfb, []int{36}
+}
+
+func (m *IndexDefinition) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_IndexDefinition.Unmarshal(m, b)
+}
+func (m *IndexDefinition) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_IndexDefinition.Marshal(b, m, deterministic)
+}
+func (m *IndexDefinition) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_IndexDefinition.Merge(m, src)
+}
+func (m *IndexDefinition) XXX_Size() int {
+	return xxx_messageInfo_IndexDefinition.Size(m)
+}
+func (m *IndexDefinition) XXX_DiscardUnknown() {
+	xxx_messageInfo_IndexDefinition.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_IndexDefinition proto.InternalMessageInfo
+
+func (m *IndexDefinition) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *IndexDefinition) GetPath() string {
+	if m != nil {
+		return m.Path
+	}
+	return ""
+}
+
+func (m *IndexDefinition) GetLocal() bool {
+	if m != nil {
+		return m.Local
+	}
+	return false
+}
+
+type IndexStatus struct {
+	Name string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Path string `protobuf:"bytes,2,opt,name=path,proto3" json:"path,omitempty"`
+	// ready is true once every existing value has been indexed
+	Ready bool `protobuf:"varint,3,opt,name=ready,proto3" json:"ready,omitempty"`
+	// progress is the fraction of the existing values that have been indexed, between 0 and 1
+	Progress             float64  `protobuf:"fixed64,4,opt,name=progress,proto3" json:"progress,omitempty"`
+	Entries              uint64   `protobuf:"varint,5,opt,name=entries,proto3" json:"entries,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *IndexStatus) Reset()         { *m = IndexStatus{} }
+func (m *IndexStatus) String() string { return proto.CompactTextString(m) }
+func (*IndexStatus) ProtoMessage()    {}
+func (*IndexStatus) Descriptor() ([]byte, []int) {
+	return fileDescriptor_049c40c6b3e04bfb, []int{37}
+}
+
+func (m *IndexStatus) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_IndexStatus.Unmarshal(m, b)
+}
+func (m *IndexStatus) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_IndexStatus.Marshal(b, m, deterministic)
+}
+func (m *IndexStatus) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_IndexStatus.Merge(m, src)
+}
+func (m *IndexStatus) XXX_Size() int {
+	return xxx_messageInfo_IndexStatus.Size(m)
+}
+func (m *IndexStatus) XXX_DiscardUnknown() {
+	xxx_messageInfo_IndexStatus.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_IndexStatus proto.InternalMessageInfo
+
+func (m *IndexStatus) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *IndexStatus) GetPath() string {
+	if m != nil {
+		return m.Path
+	}
+	return ""
+}
+
+func (m *IndexStatus) GetReady() bool {
+	if m != nil {
+		return m.Ready
+	}
+	return false
+}
+
+func (m *IndexStatus) GetProgress() float64 {
+	if m != nil {
+		return m.Progress
+	}
+	return 0
+}
+
+func (m *IndexStatus) GetEntries() uint64 {
+	if m != nil {
+		return m.Entries
+	}
+	return 0
+}
+
+type IndexesResponse struct {
+	Indexes              []*IndexStatus `protobuf:"bytes,1,rep,name=indexes,proto3" json:"indexes,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}       `json:"-"`
+	XXX_unrecognized     []byte         `json:"-"`
+	XXX_sizecache        int32          `json:"-"`
+}
+
+func (m *IndexesResponse) Reset()         { *m = IndexesResponse{} }
+func (m *IndexesResponse) String() string { return proto.CompactTextString(m) }
+func (*IndexesResponse) ProtoMessage()    {}
+func (*IndexesResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_049c40c6b3e04bfb, []int{38}
+}
+
+func (m *IndexesResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_IndexesResponse.Unmarshal(m, b)
+}
+func (m *IndexesResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_IndexesResponse.Marshal(b, m, deterministic)
+}
+func (m *IndexesResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_IndexesResponse.Merge(m, src)
+}
+func (m *IndexesResponse) XXX_Size() int {
+	return xxx_messageInfo_IndexesResponse.Size(m)
+}
+func (m *IndexesResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_IndexesResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_IndexesResponse proto.InternalMessageInfo
+
+func (m *IndexesResponse) GetIndexes() []*IndexStatus {
+	if m != nil {
+		return m.Indexes
+	}
+	return nil
+}
+
+// QueryIndexRequest selects entries by equality or by range.  The values are JSON literals.
+type QueryIndexRequest struct {
+	Index string `protobuf:"bytes,1,opt,name=index,proto3" json:"index,omitempty"`
+	// equal matches entries equal to the value, min and max are ignored when it is set
+	Equal string `protobuf:"bytes,2,opt,name=equal,proto3" json:"equal,omitempty"`
+	// min and max bound the range of matched entries, an empty bound is open
+	Min          string `protobuf:"bytes,3,opt,name=min,proto3" json:"min,omitempty"`
+	Max          string `protobuf:"bytes,4,opt,name=max,proto3" json:"max,omitempty"`
+	MinExclusive bool   `protobuf:"varint,5,opt,name=min_exclusive,json=minExclusive,proto3" json:"min_exclusive,omitempty"`
+	MaxExclusive bool   `protobuf:"varint,6,opt,name=max_exclusive,json=maxExclusive,proto3" json:"max_exclusive,omitempty"`
+	// limit is the maximum number of entries returned, or zero for no limit
+	Limit uint32 `protobuf:"varint,7,opt,name=limit,proto3" json:"limit,omitempty"`
+	// local only queries the receiving server, it is used between nodes
+	Local bool `protobuf:"varint,8,opt,name=local,proto3" json:"local,omitempty"`
+	// namespace restricts the entries to the keys of one namespace
+	Namespace            string   `protobuf:"bytes,9,opt,name=namespace,proto3" json:"namespace,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *QueryIndexRequest) Reset()         { *m = QueryIndexRequest{} }
+func (m *QueryIndexRequest) String() string { return proto.CompactTextString(m) }
+func (*QueryIndexRequest) ProtoMessage()    {}
+func (*QueryIndexRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_049c40c6b3e04bfb, []int{39}
+}
+
+func (m *QueryIndexRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_QueryIndexRequest.Unmarshal(m, b)
+}
+func (m *QueryIndexRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_QueryIndexRequest.Marshal(b, m, deterministic)
+}
+func (m *QueryIndexRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_QueryIndexRequest.Merge(m, src)
+}
+func (m *QueryIndexRequest) XXX_Size() int {
+	return xxx_messageInfo_QueryIndexRequest.Size(m)
+}
+func (m *QueryIndexRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_QueryIndexRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_QueryIndexRequest proto.InternalMessageInfo
+
+func (m *QueryIndexRequest) GetIndex() string {
+	if m != nil {
+		return m.Index
+	}
+	return ""
+}
+
+func (m *QueryIndexRequest) GetEqual() string {
+	if m != nil {
+		return m.Equal
+	}
+	return ""
+}
+
+func (m *QueryIndexRequest) GetMin() string {
+	if m != nil {
+		return m.Min
+	}
+	return ""
+}
+
+func (m *QueryIndexRequest) GetMax() string {
+	if m != nil {
+		return m.Max
+	}
+	return ""
+}
+
+func (m *QueryIndexRequest) GetMinExclusive() bool {
+	if m != nil {
+		return m.MinExclusive
+	}
+	return false
+}
+
+func (m *QueryIndexRequest) GetMaxExclusive() bool {
+	if m != nil {
+		return m.MaxExclusive
+	}
+	return false
+}
+
+func (m *QueryIndexRequest) GetLimit() uint32 {
+	if m != nil {
+		return m.Limit
+	}
+	return 0
+}
+
+func (m *QueryIndexRequest) GetLocal() bool {
+	if m != nil {
+		return m.Local
+	}
+	return false
+}
+
+func (m *QueryIndexRequest) GetNamespace() string {
+	if m != nil {
+		return m.Namespace
+	}
+	return ""
+}
+
+type IndexEntry struct {
+	ID string `protobuf:"bytes,1,opt,name=ID,proto3" json:"ID,omitempty"`
+	// value is the indexed field as a JSON literal
+	Value                string   `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *IndexEntry) Reset()         { *m = IndexEntry{} }
+func (m *IndexEntry) String() string { return proto.CompactTextString(m) }
+func (*IndexEntry) ProtoMessage()    {}
+func (*IndexEntry) Descriptor() ([]byte, []int) {
+	return fileDescriptor_049c40c6b3e04bfb, []int{40}
+}
+
+func (m *IndexEntry) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_IndexEntry.Unmarshal(m, b)
+}
+func (m *IndexEntry) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_IndexEntry.Marshal(b, m, deterministic)
+}
+func (m *IndexEntry) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_IndexEntry.Merge(m, src)
+}
+func (m *IndexEntry) XXX_Size() int {
+	return xxx_messageInfo_IndexEntry.Size(m)
+}
+func (m *IndexEntry) XXX_DiscardUnknown() {
+	xxx_messageInfo_IndexEntry.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_IndexEntry proto.InternalMessageInfo
+
+func (m *IndexEntry) GetID() string {
+	if m != nil {
+		return m.ID
+	}
+	return ""
+}
+
+func (m *IndexEntry) GetValue() string {
+	if m != nil {
+		return m.Value
+	}
+	return ""
+}
+
+type QueryIndexResponse struct {
+	Entries              []*IndexEntry `protobuf:"bytes,1,rep,name=entries,proto3" json:"entries,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}      `json:"-"`
+	XXX_unrecognized     []byte        `json:"-"`
+	XXX_sizecache        int32         `json:"-"`
+}
+
+func (m *QueryIndexResponse) Reset()         { *m = QueryIndexResponse{} }
+func (m *QueryIndexResponse) String() string { return proto.CompactTextString(m) }
+func (*QueryIndexResponse) ProtoMessage()    {}
+func (*QueryIndexResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_049c40c6b3e04bfb, []int{41}
+}
+
+func (m *QueryIndexResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_QueryIndexResponse.Unmarshal(m, b)
+}
+func (m *QueryIndexResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_QueryIndexResponse.Marshal(b, m, deterministic)
+}
+func (m *QueryIndexResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_QueryIndexResponse.Merge(m, src)
+}
+func (m *QueryIndexResponse) XXX_Size() int {
+	return xxx_messageInfo_QueryIndexResponse.Size(m)
+}
+func (m *QueryIndexResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_QueryIndexResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_QueryIndexResponse proto.InternalMessageInfo
+
+func (m *QueryIndexResponse) GetEntries() []*IndexEntry {
+	if m != nil {
+		return m.Entries
+	}
+	return nil
+}
+
+type LoginRequest struct {
+	Username             string   `protobuf:"bytes,1,opt,name=username,proto3" json:"username,omitempty"`
+	Password             string   `protobuf:"bytes,2,opt,name=password,proto3" json:"password,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *LoginRequest) Reset()         { *m = LoginRequest{} }
+func (m *LoginRequest) String() string { return proto.CompactTextString(m) }
+func (*LoginRequest) ProtoMessage()    {}
+func (*LoginRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_049c40c6b3e04bfb, []int{42}
+}
+
+func (m *LoginRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_LoginRequest.Unmarshal(m, b)
+}
+func (m *LoginRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_LoginRequest.Marshal(b, m, deterministic)
+}
+func (m *LoginRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_LoginRequest.Merge(m, src)
+}
+func (m *LoginRequest) XXX_Size() int {
+	return xxx_messageInfo_LoginRequest.Size(m)
+}
+func (m *LoginRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_LoginRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_LoginRequest proto.InternalMessageInfo
+
+func (m *LoginRequest) GetUsername() string {
+	if m != nil {
+		return m.Username
+	}
+	return ""
+}
+
+func (m *LoginRequest) GetPassword() string {
+	if m != nil {
+		return m.Password
+	}
+	return ""
+}
+
+type LoginResponse struct {
+	Token string `protobuf:"bytes,1,opt,name=token,proto3" json:"token,omitempty"`
+	// expires is when the token stops being accepted, in Unix nanoseconds
+	Expires              int64    `protobuf:"varint,2,opt,name=expires,proto3" json:"expires,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *LoginResponse) Reset()         { *m = LoginResponse{} }
+func (m *LoginResponse) String() string { return proto.CompactTextString(m) }
+func (*LoginResponse) ProtoMessage()    {}
+func (*LoginResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_049c40c6b3e04bfb, []int{43}
+}
+
+func (m *LoginResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_LoginResponse.Unmarshal(m, b)
+}
+func (m *LoginResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_LoginResponse.Marshal(b, m, deterministic)
+}
+func (m *LoginResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_LoginResponse.Merge(m, src)
+}
+func (m *LoginResponse) XXX_Size() int {
+	return xxx_messageInfo_LoginResponse.Size(m)
+}
+func (m *LoginResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_LoginResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_LoginResponse proto.InternalMessageInfo
+
+func (m *LoginResponse) GetToken() string {
+	if m != nil {
+		return m.Token
+	}
+	return ""
+}
+
+func (m *LoginResponse) GetExpires() int64 {
+	if m != nil {
+		return m.Expires
+	}
+	return 0
+}
+
+type UserRequest struct {
+	Username             string   `protobuf:"bytes,1,opt,name=username,proto3" json:"username,omitempty"`
+	Password             string   `protobuf:"bytes,2,opt,name=password,proto3" json:"password,omitempty"`
+	Roles                []string `protobuf:"bytes,3,rep,name=roles,proto3" json:"roles,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *UserRequest) Reset()         { *m = UserRequest{} }
+func (m *UserRequest) String() string { return proto.CompactTextString(m) }
+func (*UserRequest) ProtoMessage()    {}
+func (*UserRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_049c40c6b3e04bfb, []int{44}
+}
+
+func (m *UserRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_UserRequest.Unmarshal(m, b)
+}
+func (m *UserRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_UserRequest.Marshal(b, m, deterministic)
+}
+func (m *UserRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_UserRequest.Merge(m, src)
+}
+func (m *UserRequest) XXX_Size() int {
+	return xxx_messageInfo_UserRequest.Size(m)
+}
+func (m *UserRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_UserRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_UserRequest proto.InternalMessageInfo
+
+func (m *UserRequest) GetUsername() string {
+	if m != nil {
+		return m.Username
+	}
+	return ""
+}
+
+func (m *UserRequest) GetPassword() string {
+	if m != nil {
+		return m.Password
+	}
+	return ""
+}
+
+func (m *UserRequest) GetRoles() []string {
+	if m != nil {
+		return m.Roles
+	}
+	return nil
+}
+
+type UserInfo struct {
+	Username             string   `protobuf:"bytes,1,opt,name=username,proto3" json:"username,omitempty"`
+	Roles                []string `protobuf:"bytes,2,rep,name=roles,proto3" json:"roles,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *UserInfo) Reset()         { *m = UserInfo{} }
+func (m *UserInfo) String() string { return proto.CompactTextString(m) }
+func (*UserInfo) ProtoMessage()    {}
+func (*UserInfo) Descriptor() ([]byte, []int) {
+	return fileDescriptor_049c40c6b3e04bfb, []int{45}
+}
+
+func (m *UserInfo) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_UserInfo.Unmarshal(m, b)
+}
+func (m *UserInfo) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_UserInfo.Marshal(b, m, deterministic)
+}
+func (m *UserInfo) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_UserInfo.Merge(m, src)
+}
+func (m *UserInfo) XXX_Size() int {
+	return xxx_messageInfo_UserInfo.Size(m)
+}
+func (m *UserInfo) XXX_DiscardUnknown() {
+	xxx_messageInfo_UserInfo.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_UserInfo proto.InternalMessageInfo
+
+func (m *UserInfo) GetUsername() string {
+	if m != nil {
+		return m.Username
+	}
+	return ""
+}
+
+func (m *UserInfo) GetRoles() []string {
+	if m != nil {
+		return m.Roles
+	}
+	return nil
+}
+
+type UsersResponse struct {
+	Users                []*UserInfo `protobuf:"bytes,1,rep,name=users,proto3" json:"users,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}    `json:"-"`
+	XXX_unrecognized     []byte      `json:"-"`
+	XXX_sizecache        int32       `json:"-"`
+}
+
+func (m *UsersResponse) Reset()         { *m = UsersResponse{} }
+func (m *UsersResponse) String() string { return proto.CompactTextString(m) }
+func (*UsersResponse) ProtoMessage()    {}
+func (*UsersResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_049c40c6b3e04bfb, []int{46}
+}
+
+func (m *UsersResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_UsersResponse.Unmarshal(m, b)
+}
+func (m *UsersResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_UsersResponse.Marshal(b, m, deterministic)
+}
+func (m *UsersResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_UsersResponse.Merge(m, src)
+}
+func (m *UsersResponse) XXX_Size() int {
+	return xxx_messageInfo_UsersResponse.Size(m)
+}
+func (m *UsersResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_UsersResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_UsersResponse proto.InternalMessageInfo
+
+func (m *UsersResponse) GetUsers() []*UserInfo {
+	if m != nil {
+		return m.Users
+	}
+	return nil
+}
+
+type Grant struct {
+	// prefix selects the keys the permission applies to, an empty prefix selects every key
+	Prefix               string     `protobuf:"bytes,1,opt,name=prefix,proto3" json:"prefix,omitempty"`
+	Permission           Permission `protobuf:"varint,2,opt,name=permission,proto3,enum=api.Permission" json:"permission,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}   `json:"-"`
+	XXX_unrecognized     []byte     `json:"-"`
+	XXX_sizecache        int32      `json:"-"`
+}
+
+func (m *Grant) Reset()         { *m = Grant{} }
+func (m *Grant) String() string { return proto.CompactTextString(m) }
+func (*Grant) ProtoMessage()    {}
+func (*Grant) Descriptor() ([]byte, []int) {
+	return fileDescriptor_049c40c6b3e04bfb, []int{47}
+}
+
+func (m *Grant) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Grant.Unmarshal(m, b)
+}
+func (m *Grant) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Grant.Marshal(b, m, deterministic)
+}
+func (m *Grant) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Grant.Merge(m, src)
+}
+func (m *Grant) XXX_Size() int {
+	return xxx_messageInfo_Grant.Size(m)
+}
+func (m *Grant) XXX_DiscardUnknown() {
+	xxx_messageInfo_Grant.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Grant proto.InternalMessageInfo
+
+func (m *Grant) GetPrefix() string {
+	if m != nil {
+		return m.Prefix
+	}
+	return ""
+}
+
+func (m *Grant) GetPermission() Permission {
+	if m != nil {
+		return m.Permission
+	}
+	return Permission_NONE
+}
+
+type GrantRequest struct {
+	Role                 string     `protobuf:"bytes,1,opt,name=role,proto3" json:"role,omitempty"`
+	Prefix               string     `protobuf:"bytes,2,opt,name=prefix,proto3" json:"prefix,omitempty"`
+	Permission           Permission `protobuf:"varint,3,opt,name=permission,proto3,enum=api.Permission" json:"permission,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}   `json:"-"`
+	XXX_unrecognized     []byte     `json:"-"`
+	XXX_sizecache        int32      `json:"-"`
+}
+
+func (m *GrantRequest) Reset()         { *m = GrantRequest{} }
+func (m *GrantRequest) String() string { return proto.CompactTextString(m) }
+func (*GrantRequest) ProtoMessage()    {}
+func (*GrantRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_049c40c6b3e04bfb, []int{48}
+}
+
+func (m *GrantRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GrantRequest.Unmarshal(m, b)
+}
+func (m *GrantRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GrantRequest.Marshal(b, m, deterministic)
+}
+func (m *GrantRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GrantRequest.Merge(m, src)
+}
+func (m *GrantRequest) XXX_Size() int {
+	return xxx_messageInfo_GrantRequest.Size(m)
+}
+func (m *GrantRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_GrantRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GrantRequest proto.InternalMessageInfo
+
+func (m *GrantRequest) GetRole() string {
+	if m != nil {
+		return m.Role
+	}
+	return ""
+}
+
+func (m *GrantRequest) GetPrefix() string {
+	if m != nil {
+		return m.Prefix
+	}
+	return ""
+}
+
+func (m *GrantRequest) GetPermission() Permission {
+	if m != nil {
+		return m.Permission
+	}
+	return Permission_NONE
+}
+
+type RoleInfo struct {
+	Name                 string   `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Grants               []*Grant `protobuf:"bytes,2,rep,name=grants,proto3" json:"grants,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *RoleInfo) Reset()         { *m = RoleInfo{} }
+func (m *RoleInfo) String() string { return proto.CompactTextString(m) }
+func (*RoleInfo) ProtoMessage()    {}
+func (*RoleInfo) Descriptor() ([]byte, []int) {
+	return fileDescriptor_049c40c6b3e04bfb, []int{49}
+}
+
+func (m *RoleInfo) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_RoleInfo.Unmarshal(m, b)
+}
+func (m *RoleInfo) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_RoleInfo.Marshal(b, m, deterministic)
+}
+func (m *RoleInfo) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RoleInfo.Merge(m, src)
+}
+func (m *RoleInfo) XXX_Size() int {
+	return xxx_messageInfo_RoleInfo.Size(m)
+}
+func (m *RoleInfo) XXX_DiscardUnknown() {
+	xxx_messageInfo_RoleInfo.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RoleInfo proto.InternalMessageInfo
+
+func (m *RoleInfo) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *RoleInfo) GetGrants() []*Grant {
+	if m != nil {
+		return m.Grants
+	}
+	return nil
+}
+
+type RolesResponse struct {
+	Roles                []*RoleInfo `protobuf:"bytes,1,rep,name=roles,proto3" json:"roles,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}    `json:"-"`
+	XXX_unrecognized     []byte      `json:"-"`
+	XXX_sizecache        int32       `json:"-"`
+}
+
+func (m *RolesResponse) Reset()         { *m = RolesResponse{} }
+func (m *RolesResponse) String() string { return proto.CompactTextString(m) }
+func (*RolesResponse) ProtoMessage()    {}
+func (*RolesResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_049c40c6b3e04bfb, []int{50}
+}
+
+func (m *RolesResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_RolesResponse.Unmarshal(m, b)
+}
+func (m *RolesResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_RolesResponse.Marshal(b, m, deterministic)
+}
+func (m *RolesResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RolesResponse.Merge(m, src)
+}
+func (m *RolesResponse) XXX_Size() int {
+	return xxx_messageInfo_RolesResponse.Size(m)
+}
+func (m *RolesResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_RolesResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RolesResponse proto.InternalMessageInfo
+
+func (m *RolesResponse) GetRoles() []*RoleInfo {
+	if m != nil {
+		return m.Roles
+	}
+	return nil
+}
+
+type NamespaceRequest struct {
+	Name string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	// default_ttl is how long values are kept after they are written, in milliseconds, or zero to keep them
+	DefaultTtl int64 `protobuf:"varint,2,opt,name=default_ttl,json=defaultTtl,proto3" json:"default_ttl,omitempty"`
+	// max_keys and max_bytes limit how much each server stores for the namespace, zero is unlimited
+	MaxKeys  int64 `protobuf:"varint,3,opt,name=max_keys,json=maxKeys,proto3" json:"max_keys,omitempty"`
+	MaxBytes int64 `protobuf:"varint,4,opt,name=max_bytes,json=maxBytes,proto3" json:"max_bytes,omitempty"`
+	// replicas is the number of replicas of each key, zero uses the server's replication factor
+	Replicas uint32 `protobuf:"varint,5,opt,name=replicas,proto3" json:"replicas,omitempty"`
+	// local only applies the request to the receiving server, it is used between nodes
+	Local                bool     `protobuf:"varint,6,opt,name=local,proto3" json:"local,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *NamespaceRequest) Reset()         { *m = NamespaceRequest{} }
+func (m *NamespaceRequest) String() string { return proto.CompactTextString(m) }
+func (*NamespaceRequest) ProtoMessage()    {}
+func (*NamespaceRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_049c40c6b3e04bfb, []int{51}
+}
+
+func (m *NamespaceRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_NamespaceRequest.Unmarshal(m, b)
+}
+func (m *NamespaceRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_NamespaceRequest.Marshal(b, m, deterministic)
+}
+func (m *NamespaceRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_NamespaceRequest.Merge(m, src)
+}
+func (m *NamespaceRequest) XXX_Size() int {
+	return xxx_messageInfo_NamespaceRequest.Size(m)
+}
+func (m *NamespaceRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_NamespaceRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_NamespaceRequest proto.InternalMessageInfo
+
+func (m *NamespaceRequest) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *NamespaceRequest) GetDefaultTtl() int64 {
+	if m != nil {
+		return m.DefaultTtl
+	}
+	return 0
+}
+
+func (m *NamespaceRequest) GetMaxKeys() int64 {
+	if m != nil {
+		return m.MaxKeys
+	}
+	return 0
+}
+
+func (m *NamespaceRequest) GetMaxBytes() int64 {
+	if m != nil {
+		return m.MaxBytes
+	}
+	return 0
+}
+
+func (m *NamespaceRequest) GetReplicas() uint32 {
+	if m != nil {
+		return m.Replicas
+	}
+	return 0
+}
+
+func (m *NamespaceRequest) GetLocal() bool {
+	if m != nil {
+		return m.Local
+	}
+	return false
+}
+
+type NamespaceInfo struct {
+	Name       string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	DefaultTtl int64  `protobuf:"varint,2,opt,name=default_ttl,json=defaultTtl,proto3" json:"default_ttl,omitempty"`
+	MaxKeys    int64  `protobuf:"varint,3,opt,name=max_keys,json=maxKeys,proto3" json:"max_keys,omitempty"`
+	MaxBytes   int64  `protobuf:"varint,4,opt,name=max_bytes,json=maxBytes,proto3" json:"max_bytes,omitempty"`
+	Replicas   uint32 `protobuf:"varint,5,opt,name=replicas,proto3" json:"replicas,omitempty"`
+	// keys and bytes are how much the receiving server stores for the namespace
+	Keys  int64 `protobuf:"varint,6,opt,name=keys,proto3" json:"keys,omitempty"`
+	Bytes int64 `protobuf:"varint,7,opt,name=bytes,proto3" json:"bytes,omitempty"`
+	// stored_bytes is the size of the keys and values after compression, and compressed the number of compressed values
+	StoredBytes int64 `protobuf:"varint,8,opt,name=stored_bytes,json=storedBytes,proto3" json:"stored_bytes,omitempty"`
+	Compressed  int64 `protobuf:"varint,9,opt,name=compressed,proto3" json:"compressed,omitempty"`
+	// compression_ratio is how many times larger the keys and values are than the space they are stored in
+	CompressionRatio     float64  `protobuf:"fixed64,10,opt,name=compression_ratio,json=compressionRatio,proto3" json:"compression_ratio,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *NamespaceInfo) Reset()         { *m = NamespaceInfo{} }
+func (m *NamespaceInfo) String() string { return proto.CompactTextString(m) }
+func (*NamespaceInfo) ProtoMessage()    {}
+func (*NamespaceInfo) Descriptor() ([]byte, []int) {
+	return fileDescriptor_049c40c6b3e04bfb, []int{52}
+}
+
+func (m *NamespaceInfo) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_NamespaceInfo.Unmarshal(m, b)
+}
+func (m *NamespaceInfo) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_NamespaceInfo.Marshal(b, m, deterministic)
+}
+func (m *NamespaceInfo) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_NamespaceInfo.Merge(m, src)
+}
+func (m *NamespaceInfo) XXX_Size() int {
+	return xxx_messageInfo_NamespaceInfo.Size(m)
+}
+func (m *NamespaceInfo) XXX_DiscardUnknown() {
+	xxx_messageInfo_NamespaceInfo.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_NamespaceInfo proto.InternalMessageInfo
+
+func (m *NamespaceInfo) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *NamespaceInfo) GetDefaultTtl() int64 {
+	if m != nil {
+		return m.DefaultTtl
+	}
+	return 0
+}
+
+func (m *NamespaceInfo) GetMaxKeys() int64 {
+	if m != nil {
+		return m.MaxKeys
+	}
+	return 0
+}
+
+func (m *NamespaceInfo) GetMaxBytes() int64 {
+	if m != nil {
+		return m.MaxBytes
+	}
+	return 0
+}
+
+func (m *NamespaceInfo) GetReplicas() uint32 {
+	if m != nil {
+		return m.Replicas
+	}
+	return 0
+}
+
+func (m *NamespaceInfo) GetKeys() int64 {
+	if m != nil {
+		return m.Keys
+	}
+	return 0
+}
+
+func (m *NamespaceInfo) GetBytes() int64 {
+	if m != nil {
+		return m.Bytes
+	}
+	return 0
+}
+
+func (m *NamespaceInfo) GetStoredBytes() int64 {
+	if m != nil {
+		return m.StoredBytes
+	}
+	return 0
+}
+
+func (m *NamespaceInfo) GetCompressed() int64 {
+	if m != nil {
+		return m.Compressed
+	}
+	return 0
+}
+
+func (m *NamespaceInfo) GetCompressionRatio() float64 {
+	if m != nil {
+		return m.CompressionRatio
+	}
+	return 0
+}
+
+type NamespacesResponse struct {
+	Namespaces           []*NamespaceInfo `protobuf:"bytes,1,rep,name=namespaces,proto3" json:"namespaces,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}         `json:"-"`
+	XXX_unrecognized     []byte           `json:"-"`
+	XXX_sizecache        int32            `json:"-"`
+}
+
+func (m *NamespacesResponse) Reset()         { *m = NamespacesResponse{} }
+func (m *NamespacesResponse) String() string { return proto.CompactTextString(m) }
+func (*NamespacesResponse) ProtoMessage()    {}
+func (*NamespacesResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_049c40c6b3e04bfb, []int{53}
+}
+
+func (m *NamespacesResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_NamespacesResponse.Unmarshal(m, b)
+}
+func (m *NamespacesResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_NamespacesResponse.Marshal(b, m, deterministic)
+}
+func (m *NamespacesResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_NamespacesResponse.Merge(m, src)
+}
+func (m *NamespacesResponse) XXX_Size() int {
+	return xxx_messageInfo_NamespacesResponse.Size(m)
+}
+func (m *NamespacesResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_NamespacesResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_NamespacesResponse proto.InternalMessageInfo
+
+func (m *NamespacesResponse) GetNamespaces() []*NamespaceInfo {
+	if m != nil {
+		return m.Namespaces
+	}
+	return nil
+}
+
+// RateLimit is the rate of a token bucket, zero is unlimited.  Bursts of up to one second's worth are allowed.
+type RateLimit struct {
+	OpsPerSecond         float64  `protobuf:"fixed64,1,opt,name=ops_per_second,json=opsPerSecond,proto3" json:"ops_per_second,omitempty"`
+	BytesPerSecond       float64  `protobuf:"fixed64,2,opt,name=bytes_per_second,json=bytesPerSecond,proto3" json:"bytes_per_second,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *RateLimit) Reset()         { *m = RateLimit{} }
+func (m *RateLimit) String() string { return proto.CompactTextString(m) }
+func (*RateLimit) ProtoMessage()    {}
+func (*RateLimit) Descriptor() ([]byte, []int) {
+	return fileDescriptor_049c40c6b3e04bfb, []int{54}
+}
+
+func (m *RateLimit) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_RateLimit.Unmarshal(m, b)
+}
+func (m *RateLimit) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_RateLimit.Marshal(b, m, deterministic)
+}
+func (m *RateLimit) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RateLimit.Merge(m, src)
+}
+func (m *RateLimit) XXX_Size() int {
+	return xxx_messageInfo_RateLimit.Size(m)
+}
+func (m *RateLimit) XXX_DiscardUnknown() {
+	xxx_messageInfo_RateLimit.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RateLimit proto.InternalMessageInfo
+
+func (m *RateLimit) GetOpsPerSecond() float64 {
+	if m != nil {
+		return m.OpsPerSecond
+	}
+	return 0
+}
+
+func (m *RateLimit) GetBytesPerSecond() float64 {
+	if m != nil {
+		return m.BytesPerSecond
+	}
+	return 0
+}
+
+type LimitsRequest struct {
+	// client is the user, or the address of a client that has not authenticated, that the rate limit applies to.
+	// "*" applies it to every client that has no limit of its own.
+	Client string `protobuf:"bytes,1,opt,name=client,proto3" json:"client,omitempty"`
+	// namespace is the namespace the rate limit and storage quota apply to when client is empty
+	Namespace string     `protobuf:"bytes,2,opt,name=namespace,proto3" json:"namespace,omitempty"`
+	Rate      *RateLimit `protobuf:"bytes,3,opt,name=rate,proto3" json:"rate,omitempty"`
+	// max_keys and max_bytes replace the namespace's storage quota, zero is unlimited
+	MaxKeys  int64 `protobuf:"varint,4,opt,name=max_keys,json=maxKeys,proto3" json:"max_keys,omitempty"`
+	MaxBytes int64 `protobuf:"varint,5,opt,name=max_bytes,json=maxBytes,proto3" json:"max_bytes,omitempty"`
+	// local only tells the receiving server to read the limits again, it is used between nodes
+	Local                bool     `protobuf:"varint,6,opt,name=local,proto3" json:"local,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *LimitsRequest) Reset()         { *m = LimitsRequest{} }
+func (m *LimitsRequest) String() string { return proto.CompactTextString(m) }
+func (*LimitsRequest) ProtoMessage()    {}
+func (*LimitsRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_049c40c6b3e04bfb, []int{55}
+}
+
+func (m *LimitsRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_LimitsRequest.Unmarshal(m, b)
+}
+func (m *LimitsRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_LimitsRequest.Marshal(b, m, deterministic)
+}
+func (m *LimitsRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_LimitsRequest.Merge(m, src)
+}
+func (m *LimitsRequest) XXX_Size() int {
+	return xxx_messageInfo_LimitsRequest.Size(m)
+}
+func (m *LimitsRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_LimitsRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_LimitsRequest proto.InternalMessageInfo
+
+func (m *LimitsRequest) GetClient() string {
+	if m != nil {
+		return m.Client
+	}
+	return ""
+}
+
+func (m *LimitsRequest) GetNamespace() string {
+	if m != nil {
+		return m.Namespace
+	}
+	return ""
+}
+
+func (m *LimitsRequest) GetRate() *RateLimit {
+	if m != nil {
+		return m.Rate
+	}
+	return nil
+}
+
+func (m *LimitsRequest) GetMaxKeys() int64 {
+	if m != nil {
+		return m.MaxKeys
+	}
+	return 0
+}
+
+func (m *LimitsRequest) GetMaxBytes() int64 {
+	if m != nil {
+		return m.MaxBytes
+	}
+	return 0
+}
+
+func (m *LimitsRequest) GetLocal() bool {
+	if m != nil {
+		return m.Local
+	}
+	return false
+}
+
+type LimitsResponse struct {
+	DefaultClient        *RateLimit            `protobuf:"bytes,1,opt,name=default_client,json=defaultClient,proto3" json:"default_client,omitempty"`
+	Clients              map[string]*RateLimit `protobuf:"bytes,2,rep,name=clients,proto3" json:"clients,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	Namespaces           map[string]*RateLimit `protobuf:"bytes,3,rep,name=namespaces,proto3" json:"namespaces,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	XXX_NoUnkeyedLiteral struct{}              `json:"-"`
+	XXX_unrecognized     []byte                `json:"-"`
+	XXX_sizecache        int32                 `json:"-"`
+}
+
+func (m *LimitsResponse) Reset()         { *m = LimitsResponse{} }
+func (m *LimitsResponse) String() string { return proto.CompactTextString(m) }
+func (*LimitsResponse) ProtoMessage()    {}
+func (*LimitsResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_049c40c6b3e04bfb, []int{56}
+}
+
+func (m *LimitsResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_LimitsResponse.Unmarshal(m, b)
+}
+func (m *LimitsResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_LimitsResponse.Marshal(b, m, deterministic)
+}
+func (m *LimitsResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_LimitsResponse.Merge(m, src)
+}
+func (m *LimitsResponse) XXX_Size() int {
+	return xxx_messageInfo_LimitsResponse.Size(m)
+}
+func (m *LimitsResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_LimitsResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_LimitsResponse proto.InternalMessageInfo
+
+func (m *LimitsResponse) GetDefaultClient() *RateLimit {
+	if m != nil {
+		return m.DefaultClient
+	}
+	return nil
+}
+
+func (m *LimitsResponse) GetClients() map[string]*RateLimit {
+	if m != nil {
+		return m.Clients
+	}
+	return nil
+}
+
+func (m *LimitsResponse) GetNamespaces() map[string]*RateLimit {
+	if m != nil {
+		return m.Namespaces
+	}
+	return nil
+}
+
+type LogLevelRequest struct {
+	// component is the part of the server whose level changes, such as storage or network.  Every component changes when it is empty.
+	Component string `protobuf:"bytes,1,opt,name=component,proto3" json:"component,omitempty"`
+	// level is debug, info, warn or error
+	Level                string   `protobuf:"bytes,2,opt,name=level,proto3" json:"level,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *LogLevelRequest) Reset()         { *m = LogLevelRequest{} }
+func (m *LogLevelRequest) String() string { return proto.CompactTextString(m) }
+func (*LogLevelRequest) ProtoMessage()    {}
+func (*LogLevelRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_049c40c6b3e04bfb, []int{57}
+}
+
+func (m *LogLevelRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_LogLevelRequest.Unmarshal(m, b)
+}
+func (m *LogLevelRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_LogLevelRequest.Marshal(b, m, deterministic)
+}
+func (m *LogLevelRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_LogLevelRequest.Merge(m, src)
+}
+func (m *LogLevelRequest) XXX_Size() int {
+	return xxx_messageInfo_LogLevelRequest.Size(m)
+}
+func (m *LogLevelRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_LogLevelRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_LogLevelRequest proto.InternalMessageInfo
+
+func (m *LogLevelRequest) GetComponent() string {
+	if m != nil {
+		return m.Component
+	}
+	return ""
+}
+
+func (m *LogLevelRequest) GetLevel() string {
+	if m != nil {
+		return m.Level
+	}
+	return ""
+}
+
+type LogLevelsResponse struct {
+	// levels maps each component of the server to its log level
+	Levels               map[string]string `protobuf:"bytes,1,rep,name=levels,proto3" json:"levels,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	XXX_NoUnkeyedLiteral struct{}          `json:"-"`
+	XXX_unrecognized     []byte            `json:"-"`
+	XXX_sizecache        int32             `json:"-"`
+}
+
+func (m *LogLevelsResponse) Reset()         { *m = LogLevelsResponse{} }
+func (m *LogLevelsResponse) String() string { return proto.CompactTextString(m) }
+func (*LogLevelsResponse) ProtoMessage()    {}
+func (*LogLevelsResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_049c40c6b3e04bfb, []int{58}
+}
+
+func (m *LogLevelsResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_LogLevelsResponse.Unmarshal(m, b)
+}
+func (m *LogLevelsResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_LogLevelsResponse.Marshal(b, m, deterministic)
+}
+func (m *LogLevelsResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_LogLevelsResponse.Merge(m, src)
+}
+func (m *LogLevelsResponse) XXX_Size() int {
+	return xxx_messageInfo_LogLevelsResponse.Size(m)
+}
+func (m *LogLevelsResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_LogLevelsResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_LogLevelsResponse proto.InternalMessageInfo
+
+func (m *LogLevelsResponse) GetLevels() map[string]string {
+	if m != nil {
+		return m.Levels
+	}
+	return nil
+}
+
+type AuditQuery struct {
+	// key restricts the entries to those that changed the key in the given namespace
+	Key string `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	// namespace restricts the entries to those in the namespace when no key is given
+	Namespace string `protobuf:"bytes,2,opt,name=namespace,proto3" json:"namespace,omitempty"`
+	// caller restricts the entries to those made by a user, or by the address of a client that has not authenticated
+	Caller string `protobuf:"bytes,3,opt,name=caller,proto3" json:"caller,omitempty"`
+	// operation restricts the entries to one operation, such as Remove or "RESP DEL"
+	Operation string `protobuf:"bytes,4,opt,name=operation,proto3" json:"operation,omitempty"`
+	// since restricts the entries to those recorded from the given time on, in Unix nanoseconds
+	Since int64 `protobuf:"varint,5,opt,name=since,proto3" json:"since,omitempty"`
+	// limit is the maximum number of entries returned, the most recent are kept, or zero for no limit
+	Limit uint32 `protobuf:"varint,6,opt,name=limit,proto3" json:"limit,omitempty"`
+	// local only queries the receiving server, it is used between nodes
+	Local                bool     `protobuf:"varint,7,opt,name=local,proto3" json:"local,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *AuditQuery) Reset()         { *m = AuditQuery{} }
+func (m *AuditQuery) String() string { return proto.CompactTextString(m) }
+func (*AuditQuery) ProtoMessage()    {}
+func (*AuditQuery) Descriptor() ([]byte, []int) {
+	return fileDescriptor_049c40c6b3e04bfb, []int{59}
+}
+
+func (m *AuditQuery) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_AuditQuery.Unmarshal(m, b)
+}
+func (m *AuditQuery) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_AuditQuery.Marshal(b, m, deterministic)
+}
+func (m *AuditQuery) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_AuditQuery.Merge(m, src)
+}
+func (m *AuditQuery) XXX_Size() int {
+	return xxx_messageInfo_AuditQuery.Size(m)
+}
+func (m *AuditQuery) XXX_DiscardUnknown() {
+	xxx_messageInfo_AuditQuery.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_AuditQuery proto.InternalMessageInfo
+
+func (m *AuditQuery) GetKey() string {
+	if m != nil {
+		return m.Key
+	}
+	return ""
+}
+
+func (m *AuditQuery) GetNamespace() string {
+	if m != nil {
+		return m.Namespace
+	}
+	return ""
+}
+
+func (m *AuditQuery) GetCaller() string {
+	if m != nil {
+		return m.Caller
+	}
+	return ""
+}
+
+func (m *AuditQuery) GetOperation() string {
+	if m != nil {
+		return m.Operation
+	}
+	return ""
+}
+
+func (m *AuditQuery) GetSince() int64 {
+	if m != nil {
+		return m.Since
+	}
+	return 0
+}
+
+func (m *AuditQuery) GetLimit() uint32 {
+	if m != nil {
+		return m.Limit
+	}
+	return 0
+}
+
+func (m *AuditQuery) GetLocal() bool {
+	if m != nil {
+		return m.Local
+	}
+	return false
+}
+
+type AuditEntry struct {
+	// time is when the change was made, in Unix nanoseconds
+	Time int64 `protobuf:"varint,1,opt,name=time,proto3" json:"time,omitempty"`
+	// shard is the ID of the server that recorded the change
+	Shard     string `protobuf:"bytes,2,opt,name=shard,proto3" json:"shard,omitempty"`
+	Caller    string `protobuf:"bytes,3,opt,name=caller,proto3" json:"caller,omitempty"`
+	Address   string `protobuf:"bytes,4,opt,name=address,proto3" json:"address,omitempty"`
+	Operation string `protobuf:"bytes,5,opt,name=operation,proto3" json:"operation,omitempty"`
+	Namespace string `protobuf:"bytes,6,opt,name=namespace,proto3" json:"namespace,omitempty"`
+	// keys are the keys changed, or the names of the users, roles, indexes or components changed
+	Keys      []string `protobuf:"bytes,7,rep,name=keys,proto3" json:"keys,omitempty"`
+	RequestId string   `protobuf:"bytes,8,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty"`
+	// code is the gRPC status code of the outcome and error its message when it failed
+	Code                 string   `protobuf:"bytes,9,opt,name=code,proto3" json:"code,omitempty"`
+	Error                string   `protobuf:"bytes,10,opt,name=error,proto3" json:"error,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *AuditEntry) Reset()         { *m = AuditEntry{} }
+func (m *AuditEntry) String() string { return proto.CompactTextString(m) }
+func (*AuditEntry) ProtoMessage()    {}
+func (*AuditEntry) Descriptor() ([]byte, []int) {
+	return fileDescriptor_049c40c6b3e04bfb, []int{60}
+}
+
+func (m *AuditEntry) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_AuditEntry.Unmarshal(m, b)
+}
+func (m *AuditEntry) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_AuditEntry.Marshal(b, m, deterministic)
+}
+func (m *AuditEntry) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_AuditEntry.Merge(m, src)
+}
+func (m *AuditEntry) XXX_Size() int {
+	return xxx_messageInfo_AuditEntry.Size(m)
+}
+func (m *AuditEntry) XXX_DiscardUnknown() {
+	xxx_messageInfo_AuditEntry.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_AuditEntry proto.InternalMessageInfo
+
+func (m *AuditEntry) GetTime() int64 {
+	if m != nil {
+		return m.Time
+	}
+	return 0
+}
+
+func (m *AuditEntry) GetShard() string {
+	if m != nil {
+		return m.Shard
+	}
+	return ""
+}
+
+func (m *AuditEntry) GetCaller() string {
+	if m != nil {
+		return m.Caller
+	}
+	return ""
+}
+
+func (m *AuditEntry) GetAddress() string {
+	if m != nil {
+		return m.Address
+	}
+	return ""
+}
+
+func (m *AuditEntry) GetOperation() string {
+	if m != nil {
+		return m.Operation
+	}
+	return ""
+}
+
+func (m *AuditEntry) GetNamespace() string {
+	if m != nil {
+		return m.Namespace
+	}
+	return ""
+}
+
+func (m *AuditEntry) GetKeys() []string {
+	if m != nil {
+		return m.Keys
+	}
+	return nil
+}
+
+func (m *AuditEntry) GetRequestId() string {
+	if m != nil {
+		return m.RequestId
+	}
+	return ""
+}
+
+func (m *AuditEntry) GetCode() string {
+	if m != nil {
+		return m.Code
+	}
+	return ""
+}
+
+func (m *AuditEntry) GetError() string {
+	if m != nil {
+		return m.Error
+	}
+	return ""
+}
+
+type AuditResponse struct {
+	Entries []*AuditEntry `protobuf:"bytes,1,rep,name=entries,proto3" json:"entries,omitempty"`
+	// unreachable is the number of servers whose entries are missing
+	Unreachable          uint32   `protobuf:"varint,2,opt,name=unreachable,proto3" json:"unreachable,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *AuditResponse) Reset()         { *m = AuditResponse{} }
+func (m *AuditResponse) String() string { return proto.CompactTextString(m) }
+func (*AuditResponse) ProtoMessage()    {}
+func (*AuditResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_049c40c6b3e04bfb, []int{61}
+}
+
+func (m *AuditResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_AuditResponse.Unmarshal(m, b)
+}
+func (m *AuditResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_AuditResponse.Marshal(b, m, deterministic)
+}
+func (m *AuditResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_AuditResponse.Merge(m, src)
+}
+func (m *AuditResponse) XXX_Size() int {
+	return xxx_messageInfo_AuditResponse.Size(m)
+}
+func (m *AuditResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_AuditResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_AuditResponse proto.InternalMessageInfo
+
+func (m *AuditResponse) GetEntries() []*AuditEntry {
+	if m != nil {
+		return m.Entries
+	}
+	return nil
+}
+
+func (m *AuditResponse) GetUnreachable() uint32 {
+	if m != nil {
+		return m.Unreachable
+	}
+	return 0
+}
+
+type SlowQuery struct {
+	// limit is the maximum number of operations returned, the most recent are kept, or zero for no limit
+	Limit uint32 `protobuf:"varint,1,opt,name=limit,proto3" json:"limit,omitempty"`
+	// local only queries the receiving server, it is used between nodes
+	Local                bool     `protobuf:"varint,2,opt,name=local,proto3" json:"local,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *SlowQuery) Reset()         { *m = SlowQuery{} }
+func (m *SlowQuery) String() string { return proto.CompactTextString(m) }
+func (*SlowQuery) ProtoMessage()    {}
+func (*SlowQuery) Descriptor() ([]byte, []int) {
+	return fileDescriptor_049c40c6b3e04bfb, []int{62}
+}
+
+func (m *SlowQuery) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SlowQuery.Unmarshal(m, b)
+}
+func (m *SlowQuery) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SlowQuery.Marshal(b, m, deterministic)
+}
+func (m *SlowQuery) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SlowQuery.Merge(m, src)
+}
+func (m *SlowQuery) XXX_Size() int {
+	return xxx_messageInfo_SlowQuery.Size(m)
+}
+func (m *SlowQuery) XXX_DiscardUnknown() {
+	xxx_messageInfo_SlowQuery.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SlowQuery proto.InternalMessageInfo
+
+func (m *SlowQuery) GetLimit() uint32 {
+	if m != nil {
+		return m.Limit
+	}
+	return 0
+}
+
+func (m *SlowQuery) GetLocal() bool {
+	if m != nil {
+		return m.Local
+	}
+	return false
+}
+
+type SlowOperation struct {
+	// time is when the request finished, in Unix nanoseconds
+	Time int64 `protobuf:"varint,1,opt,name=time,proto3" json:"time,omitempty"`
+	// shard is the ID of the server that handled the request
+	Shard     string   `protobuf:"bytes,2,opt,name=shard,proto3" json:"shard,omitempty"`
+	Caller    string   `protobuf:"bytes,3,opt,name=caller,proto3" json:"caller,omitempty"`
+	Operation string   `protobuf:"bytes,4,opt,name=operation,proto3" json:"operation,omitempty"`
+	Namespace string   `protobuf:"bytes,5,opt,name=namespace,proto3" json:"namespace,omitempty"`
+	Keys      []string `protobuf:"bytes,6,rep,name=keys,proto3" json:"keys,omitempty"`
+	RequestId string   `protobuf:"bytes,7,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty"`
+	Code      string   `protobuf:"bytes,8,opt,name=code,proto3" json:"code,omitempty"`
+	// duration is how long the request took in nanoseconds.
+	// wait and apply break down the time its storage requests spent queued for the storage thread and applying,
+	// and sync is the time spent writing and syncing the transaction log.
+	Duration             int64    `protobuf:"varint,9,opt,name=duration,proto3" json:"duration,omitempty"`
+	Wait                 int64    `protobuf:"varint,10,opt,name=wait,proto3" json:"wait,omitempty"`
+	Apply                int64    `protobuf:"varint,11,opt,name=apply,proto3" json:"apply,omitempty"`
+	Sync                 int64    `protobuf:"varint,12,opt,name=sync,proto3" json:"sync,omitempty"`
+	StorageRequests      uint32   `protobuf:"varint,13,opt,name=storage_requests,json=storageRequests,proto3" json:"storage_requests,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *SlowOperation) Reset()         { *m = SlowOperation{} }
+func (m *SlowOperation) String() string { return proto.CompactTextString(m) }
+func (*SlowOperation) ProtoMessage()    {}
+func (*SlowOperation) Descriptor() ([]byte, []int) {
+	return fileDescriptor_049c40c6b3e04bfb, []int{63}
+}
+
+func (m *SlowOperation) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SlowOperation.Unmarshal(m, b)
+}
+func (m *SlowOperation) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SlowOperation.Marshal(b, m, deterministic)
+}
+func (m *SlowOperation) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SlowOperation.Merge(m, src)
+}
+func (m *SlowOperation) XXX_Size() int {
+	return xxx_messageInfo_SlowOperation.Size(m)
+}
+func (m *SlowOperation) XXX_DiscardUnknown() {
+	xxx_messageInfo_SlowOperation.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SlowOperation proto.InternalMessageInfo
+
+func (m *SlowOperation) GetTime() int64 {
+	if m != nil {
+		return m.Time
+	}
+	return 0
+}
+
+func (m *SlowOperation) GetShard() string {
+	if m != nil {
+		return m.Shard
+	}
+	return ""
+}
+
+func (m *SlowOperation) GetCaller() string {
+	if m != nil {
+		return m.Caller
+	}
+	return ""
+}
+
+func (m *SlowOperation) GetOperation() string {
+	if m != nil {
+		return m.Operation
+	}
+	return ""
+}
+
+func (m *SlowOperation) GetNamespace() string {
+	if m != nil {
+		return m.Namespace
+	}
+	return ""
+}
+
+func (m *SlowOperation) GetKeys() []string {
+	if m != nil {
+		return m.Keys
+	}
+	return nil
+}
+
+func (m *SlowOperation) GetRequestId() string {
+	if m != nil {
+		return m.RequestId
+	}
+	return ""
+}
+
+func (m *SlowOperation) GetCode() string {
+	if m != nil {
+		return m.Code
+	}
+	return ""
+}
+
+func (m *SlowOperation) GetDuration() int64 {
+	if m != nil {
+		return m.Duration
+	}
+	return 0
+}
+
+func (m *SlowOperation) GetWait() int64 {
+	if m != nil {
+		return m.Wait
+	}
+	return 0
+}
+
+func (m *SlowOperation) GetApply() int64 {
+	if m != nil {
+		return m.Apply
+	}
+	return 0
+}
+
+func (m *SlowOperation) GetSync() int64 {
+	if m != nil {
+		return m.Sync
+	}
+	return 0
+}
+
+func (m *SlowOperation) GetStorageRequests() uint32 {
+	if m != nil {
+		return m.StorageRequests
+	}
+	return 0
+}
+
+type SlowResponse struct {
+	Operations []*SlowOperation `protobuf:"bytes,1,rep,name=operations,proto3" json:"operations,omitempty"`
+	// unreachable is the number of servers whose operations are missing
+	Unreachable          uint32   `protobuf:"varint,2,opt,name=unreachable,proto3" json:"unreachable,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *SlowResponse) Reset()         { *m = SlowResponse{} }
+func (m *SlowResponse) String() string { return proto.CompactTextString(m) }
+func (*SlowResponse) ProtoMessage()    {}
+func (*SlowResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_049c40c6b3e04bfb, []int{64}
+}
+
+func (m *SlowResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SlowResponse.Unmarshal(m, b)
+}
+func (m *SlowResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SlowResponse.Marshal(b, m, deterministic)
+}
+func (m *SlowResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SlowResponse.Merge(m, src)
+}
+func (m *SlowResponse) XXX_Size() int {
+	return xxx_messageInfo_SlowResponse.Size(m)
+}
+func (m *SlowResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_SlowResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SlowResponse proto.InternalMessageInfo
+
+func (m *SlowResponse) GetOperations() []*SlowOperation {
+	if m != nil {
+		return m.Operations
+	}
+	return nil
+}
+
+func (m *SlowResponse) GetUnreachable() uint32 {
+	if m != nil {
+		return m.Unreachable
+	}
+	return 0
+}
+
+func init() {
+	proto.RegisterEnum("api.Consistency", Consistency_name, Consistency_value)
+	proto.RegisterEnum("api.MemberState", MemberState_name, MemberState_value)
+	proto.RegisterEnum("api.TxnDecision", TxnDecision_name, TxnDecision_value)
+	proto.RegisterEnum("api.Permission", Permission_name, Permission_value)
+	proto.RegisterType((*EmptyRequest)(nil), "api.EmptyRequest")
+	proto.RegisterType((*Timestamp)(nil), "api.Timestamp")
+	proto.RegisterType((*TimeRequest)(nil), "api.TimeRequest")
+	proto.RegisterType((*IDRequest)(nil), "api.IDRequest")
+	proto.RegisterType((*IDValueRequest)(nil), "api.IDValueRequest")
+	proto.RegisterType((*GetManyRequest)(nil), "api.GetManyRequest")
+	proto.RegisterType((*GetManyResponse)(nil), "api.GetManyResponse")
+	proto.RegisterType((*SetManyRequest)(nil), "api.SetManyRequest")
+	proto.RegisterMapType((map[string]string)(nil), "api.SetManyRequest.ValuesEntry")
+	proto.RegisterType((*VersionVector)(nil), "api.VersionVector")
+	proto.RegisterMapType((map[string]uint64)(nil), "api.VersionVector.ClocksEntry")
+	proto.RegisterType((*Sibling)(nil), "api.Sibling")
+	proto.RegisterType((*Response)(nil), "api.Response")
+	proto.RegisterType((*ScanRequest)(nil), "api.ScanRequest")
+	proto.RegisterType((*ScanResponse)(nil), "api.ScanResponse")
+	proto.RegisterType((*VersionedRequest)(nil), "api.VersionedRequest")
+	proto.RegisterType((*VersionedResponse)(nil), "api.VersionedResponse")
+	proto.RegisterType((*NodeLocator)(nil), "api.NodeLocator")
+	proto.RegisterType((*KeyVersions)(nil), "api.KeyVersions")
+	proto.RegisterType((*DigestResponse)(nil), "api.DigestResponse")
+	proto.RegisterMapType((map[uint32]uint64)(nil), "api.DigestResponse.ChildrenEntry")
+	proto.RegisterType((*Member)(nil), "api.Member")
+	proto.RegisterType((*ShardInfo)(nil), "api.ShardInfo")
+	proto.RegisterType((*ClusterConfiguration)(nil), "api.ClusterConfiguration")
+	proto.RegisterType((*PlacementLoadRequest)(nil), "api.PlacementLoadRequest")
+	proto.RegisterType((*PlacementLoadResponse)(nil), "api.PlacementLoadResponse")
+	proto.RegisterMapType((map[string]uint64)(nil), "api.PlacementLoadResponse.CountsEntry")
+	proto.RegisterType((*TxnOp)(nil), "api.TxnOp")
+	proto.RegisterType((*TxnRequest)(nil), "api.TxnRequest")
+	proto.RegisterType((*TxnResponse)(nil), "api.TxnResponse")
+	proto.RegisterType((*PrepareRequest)(nil), "api.PrepareRequest")
+	proto.RegisterType((*DecideRequest)(nil), "api.DecideRequest")
+	proto.RegisterType((*TxnStatusRequest)(nil), "api.TxnStatusRequest")
+	proto.RegisterType((*TxnStatusResponse)(nil), "api.TxnStatusResponse")
+	proto.RegisterType((*WatchClusterRequest)(nil), "api.WatchClusterRequest")
+	proto.RegisterType((*UpdateClusterRequest)(nil), "api.UpdateClusterRequest")
+	proto.RegisterType((*PingRequest)(nil), "api.PingRequest")
+	proto.RegisterType((*PingResponse)(nil), "api.PingResponse")
+	proto.RegisterType((*MemberEvent)(nil), "api.MemberEvent")
+	proto.RegisterType((*MembersResponse)(nil), "api.MembersResponse")
+	proto.RegisterType((*IndexDefinition)(nil), "api.IndexDefinition")
+	proto.RegisterType((*IndexStatus)(nil), "api.IndexStatus")
+	proto.RegisterType((*IndexesResponse)(nil), "api.IndexesResponse")
+	proto.RegisterType((*QueryIndexRequest)(nil), "api.QueryIndexRequest")
+	proto.RegisterType((*IndexEntry)(nil), "api.IndexEntry")
+	proto.RegisterType((*QueryIndexResponse)(nil), "api.QueryIndexResponse")
+	proto.RegisterType((*LoginRequest)(nil), "api.LoginRequest")
+	proto.RegisterType((*LoginResponse)(nil), "api.LoginResponse")
+	proto.RegisterType((*UserRequest)(nil), "api.UserRequest")
+	proto.RegisterType((*UserInfo)(nil), "api.UserInfo")
+	proto.RegisterType((*UsersResponse)(nil), "api.UsersResponse")
+	proto.RegisterType((*Grant)(nil), "api.Grant")
+	proto.RegisterType((*GrantRequest)(nil), "api.GrantRequest")
+	proto.RegisterType((*RoleInfo)(nil), "api.RoleInfo")
+	proto.RegisterType((*RolesResponse)(nil), "api.RolesResponse")
+	proto.RegisterType((*NamespaceRequest)(nil), "api.NamespaceRequest")
+	proto.RegisterType((*NamespaceInfo)(nil), "api.NamespaceInfo")
+	proto.RegisterType((*NamespacesResponse)(nil), "api.NamespacesResponse")
+	proto.RegisterType((*RateLimit)(nil), "api.RateLimit")
+	proto.RegisterType((*LimitsRequest)(nil), "api.LimitsRequest")
+	proto.RegisterType((*LimitsResponse)(nil), "api.LimitsResponse")
+	proto.RegisterMapType((map[string]*RateLimit)(nil), "api.LimitsResponse.ClientsEntry")
+	proto.RegisterMapType((map[string]*RateLimit)(nil), "api.LimitsResponse.NamespacesEntry")
+	proto.RegisterType((*LogLevelRequest)(nil), "api.LogLevelRequest")
+	proto.RegisterType((*LogLevelsResponse)(nil), "api.LogLevelsResponse")
+	proto.RegisterMapType((map[string]string)(nil), "api.LogLevelsResponse.LevelsEntry")
+	proto.RegisterType((*AuditQuery)(nil), "api.AuditQuery")
+	proto.RegisterType((*AuditEntry)(nil), "api.AuditEntry")
+	proto.RegisterType((*AuditResponse)(nil), "api.AuditResponse")
+	proto.RegisterType((*SlowQuery)(nil), "api.SlowQuery")
+	proto.RegisterType((*SlowOperation)(nil), "api.SlowOperation")
+	proto.RegisterType((*SlowResponse)(nil), "api.SlowResponse")
+}
+
+func init() { proto.RegisterFile("vdb.proto", fileDescriptor_049c40c6b3e04bfb) }
+
+var fileDescriptor_049c40c6b3e04bfb = []byte{
+	// 3299 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xc4, 0x3a, 0x4d, 0x6f, 0x1c, 0xc7,
+	0xb1, 0x9c, 0xfd, 0xde, 0x5a, 0xee, 0x72, 0xd9, 0x92, 0xe5, 0xf5, 0xbe, 0xf7, 0x6c, 0xba, 0x2d,
+	0x0b, 0xb4, 0xa4, 0x47, 0x5b, 0x7c, 0xf2, 0xc7, 0xb3, 0x2d, 0x19, 0x12, 0x77, 0x2d, 0x6f, 0x4c,
+	0x8a, 0x74, 0x93, 0x92, 0x11, 0x38, 0x00, 0x31, 0x9a, 0x69, 0x2e, 0x07, 0x9c, 0x9d, 0x19, 0xcf,
+	0xcc, 0x52, 0x4b, 0xdf, 0x12, 0x20, 0x97, 0x1c, 0x72, 0x48, 0x80, 0xfc, 0x86, 0x00, 0x39, 0x04,
+	0x09, 0x02, 0xf8, 0x17, 0xe4, 0x2f, 0x04, 0xc8, 0x39, 0xd7, 0x5c, 0x02, 0x04, 0xc8, 0x25, 0x87,
+	0xa0, 0xbf, 0x66, 0x7a, 0x76, 0x67, 0x97, 0xa4, 0xed, 0x38, 0xb7, 0xae, 0x9a, 0xaa, 0xea, 0xaa,
+	0xea, 0xea, 0xaa, 0xee, 0xea, 0x81, 0xfa, 0xa9, 0xfd, 0x6c, 0x23, 0x08, 0xfd, 0xd8, 0x47, 0x45,
+	0x33, 0x70, 0x70, 0x0b, 0x96, 0xfb, 0xa3, 0x20, 0x3e, 0x23, 0xf4, 0xcb, 0x31, 0x8d, 0x62, 0xfc,
+	0x00, 0xea, 0x07, 0xce, 0x88, 0x46, 0xb1, 0x39, 0x0a, 0x50, 0x17, 0x6a, 0xc1, 0xf1, 0x59, 0xe4,
+	0x58, 0xa6, 0xdb, 0x31, 0xd6, 0x8c, 0xf5, 0x22, 0x49, 0x60, 0xd4, 0x81, 0xaa, 0xeb, 0x0f, 0xf9,
+	0xa7, 0xc2, 0x9a, 0xb1, 0xde, 0x24, 0x0a, 0xc4, 0x1f, 0x40, 0x83, 0x89, 0x90, 0x12, 0xd1, 0x6d,
+	0xa8, 0xc7, 0x4a, 0x22, 0x97, 0xd2, 0xd8, 0x6c, 0x6d, 0x98, 0x81, 0xb3, 0x91, 0xcc, 0x43, 0x52,
+	0x02, 0xfc, 0x6b, 0x03, 0xea, 0x83, 0x9e, 0xe2, 0x6d, 0x41, 0x61, 0xd0, 0xe3, 0x4c, 0x75, 0x52,
+	0x18, 0xf4, 0xd0, 0x26, 0x34, 0x2c, 0xdf, 0x8b, 0x9c, 0x28, 0xa6, 0x9e, 0x75, 0xc6, 0x27, 0x6e,
+	0x6d, 0xb6, 0xb9, 0xb4, 0xad, 0x14, 0x4f, 0x74, 0x22, 0x74, 0x15, 0xca, 0x34, 0xf0, 0xad, 0xe3,
+	0x4e, 0x71, 0xcd, 0x58, 0x2f, 0x11, 0x01, 0xa0, 0xd7, 0xa0, 0x39, 0x32, 0x27, 0x87, 0x51, 0x6c,
+	0xba, 0xd4, 0xa3, 0x51, 0xd4, 0x29, 0x71, 0xfb, 0x96, 0x47, 0xe6, 0x64, 0x5f, 0xe1, 0xd0, 0x7f,
+	0x43, 0xdd, 0x33, 0x47, 0x34, 0x0a, 0x4c, 0x8b, 0x76, 0xca, 0x5c, 0x8b, 0x14, 0x81, 0xff, 0x69,
+	0x40, 0x6b, 0xd0, 0x7b, 0x6a, 0xba, 0x63, 0x3a, 0x4f, 0xdf, 0xab, 0x50, 0x3e, 0x65, 0xdf, 0xb9,
+	0xa6, 0x75, 0x22, 0x80, 0x69, 0x2b, 0x8a, 0x17, 0xb1, 0xe2, 0x36, 0x54, 0x2d, 0xdf, 0x8b, 0xe9,
+	0x24, 0xe6, 0x9a, 0x36, 0x36, 0x11, 0xa7, 0x7f, 0x4a, 0xc3, 0xc8, 0xf1, 0xbd, 0xa7, 0xd4, 0x8a,
+	0xfd, 0x90, 0x28, 0x92, 0xd4, 0xe6, 0xb2, 0x6e, 0x73, 0xc6, 0x9c, 0xca, 0x94, 0x39, 0x6c, 0x41,
+	0xe9, 0x24, 0x70, 0x42, 0x1a, 0x75, 0xaa, 0xdc, 0x17, 0x0a, 0x64, 0xd2, 0x8e, 0x5c, 0x73, 0x18,
+	0x75, 0x6a, 0x7c, 0xa1, 0x05, 0x80, 0x7f, 0x6b, 0x40, 0xeb, 0x11, 0x8d, 0x77, 0x4c, 0x4f, 0x05,
+	0x0f, 0x6a, 0x43, 0x71, 0xd0, 0x8b, 0x3a, 0xc6, 0x5a, 0x71, 0xbd, 0x4e, 0xd8, 0xf0, 0x3b, 0x5c,
+	0xb0, 0x8c, 0xf2, 0xa5, 0x69, 0xe5, 0x67, 0x96, 0xb3, 0x3c, 0xbb, 0x9c, 0xf8, 0x3e, 0xac, 0x24,
+	0x0a, 0x47, 0x81, 0xef, 0x45, 0x14, 0xdd, 0x82, 0x7a, 0x28, 0xc7, 0x42, 0xef, 0xc6, 0x66, 0x93,
+	0x6b, 0xa7, 0x28, 0x48, 0xfa, 0x1d, 0xff, 0xd5, 0x80, 0xd6, 0x7e, 0xd6, 0xe2, 0x77, 0xa1, 0xc2,
+	0xd7, 0x54, 0x31, 0xbf, 0xc2, 0x99, 0xb3, 0x44, 0x1b, 0x3c, 0x44, 0xa2, 0xbe, 0x17, 0x87, 0x67,
+	0x44, 0x92, 0x7f, 0x5f, 0x8e, 0xe9, 0xfe, 0x3f, 0x34, 0xb4, 0xe9, 0xd9, 0x0a, 0x9d, 0xd0, 0x33,
+	0x19, 0xa1, 0x6c, 0x98, 0x1f, 0xa2, 0xef, 0x17, 0xde, 0x33, 0xf0, 0x4f, 0x0c, 0x68, 0x66, 0xe2,
+	0x0b, 0xbd, 0x03, 0x15, 0xcb, 0xf5, 0xad, 0x13, 0x65, 0xed, 0xcb, 0xb3, 0x31, 0xb8, 0xb1, 0xc5,
+	0x09, 0xa4, 0xb1, 0x82, 0x9a, 0x29, 0xa1, 0xa1, 0xcf, 0x53, 0xa2, 0xa4, 0x2b, 0xf1, 0x47, 0x03,
+	0xaa, 0xfb, 0xce, 0x33, 0xd7, 0xf1, 0x86, 0x29, 0x95, 0xa1, 0xef, 0xa6, 0xdb, 0x50, 0x3d, 0x15,
+	0x1a, 0x74, 0x0a, 0xf3, 0x77, 0x86, 0x24, 0x61, 0x51, 0x6e, 0x53, 0x97, 0xc6, 0xd4, 0xe6, 0x5e,
+	0xac, 0x11, 0x05, 0x66, 0xf3, 0x54, 0xe9, 0x9c, 0x3c, 0xa5, 0xef, 0x96, 0xf2, 0x9c, 0xdd, 0x52,
+	0xd1, 0x77, 0xcb, 0x9f, 0x0c, 0xa8, 0x25, 0x51, 0x97, 0x6f, 0xc8, 0x3a, 0xd4, 0x22, 0x61, 0x69,
+	0xd4, 0x29, 0x70, 0xff, 0x2e, 0x8b, 0x68, 0x12, 0x48, 0x92, 0x7c, 0xd5, 0x93, 0x41, 0xf1, 0xfc,
+	0x64, 0xf0, 0xef, 0x35, 0xec, 0x87, 0xd0, 0xd8, 0xb7, 0x4c, 0x4f, 0x6d, 0x88, 0x4c, 0x34, 0x1a,
+	0xd3, 0xdb, 0xf4, 0x1a, 0x54, 0xac, 0x71, 0x18, 0xf9, 0xa1, 0xac, 0x19, 0x12, 0x62, 0xa2, 0x2d,
+	0x7f, 0xec, 0x09, 0x73, 0x9a, 0x44, 0x00, 0xf8, 0x2e, 0x2c, 0x0b, 0xd1, 0xd2, 0x6d, 0xb3, 0xe9,
+	0x05, 0x41, 0xc9, 0x63, 0x5e, 0x10, 0xd2, 0xf8, 0x18, 0x6f, 0x43, 0x5b, 0x3a, 0x82, 0xda, 0xf3,
+	0xf2, 0xf2, 0x85, 0x5d, 0x8d, 0x77, 0x60, 0x55, 0x93, 0x26, 0x15, 0xf9, 0xe6, 0xe2, 0x86, 0xd0,
+	0x78, 0xec, 0xdb, 0x74, 0xdb, 0xb7, 0x4c, 0xb6, 0xa1, 0x52, 0x41, 0x4d, 0x55, 0x2f, 0x9e, 0x9d,
+	0xc5, 0x34, 0x92, 0x06, 0x09, 0x20, 0xeb, 0xd3, 0xe2, 0xb4, 0x4f, 0xaf, 0x42, 0x39, 0x3a, 0x36,
+	0x43, 0x5b, 0xee, 0x7d, 0x01, 0x60, 0x0b, 0x1a, 0x9f, 0xd2, 0x33, 0xa9, 0x7a, 0xf4, 0xcd, 0x35,
+	0x66, 0x67, 0x00, 0x3a, 0x09, 0xa8, 0xa5, 0x76, 0x4c, 0x89, 0x24, 0x30, 0xfe, 0xbb, 0x01, 0xad,
+	0x9e, 0x33, 0xa4, 0x51, 0x9c, 0xb8, 0xe6, 0x3a, 0x94, 0x3c, 0xdf, 0xa6, 0xb2, 0xd0, 0x8b, 0x84,
+	0xa6, 0x59, 0x4c, 0xf8, 0x57, 0x16, 0x07, 0x36, 0xe7, 0x93, 0x1b, 0x5e, 0x42, 0xe8, 0x1e, 0xd4,
+	0xac, 0x63, 0xc7, 0xb5, 0x43, 0xea, 0x75, 0x8a, 0x5c, 0xad, 0x57, 0xb9, 0x84, 0xec, 0x24, 0x1b,
+	0x5b, 0x92, 0x46, 0x64, 0x99, 0x84, 0x05, 0xad, 0x27, 0xd9, 0xb8, 0xb4, 0x56, 0x4c, 0xa6, 0xd7,
+	0xfc, 0xa0, 0xd2, 0x6f, 0xf7, 0x03, 0x68, 0x66, 0x84, 0xe8, 0x39, 0xa9, 0x79, 0x5e, 0x4e, 0xfa,
+	0x95, 0x01, 0x95, 0x1d, 0x3a, 0x7a, 0x46, 0xc3, 0x19, 0xbf, 0x76, 0xa0, 0x6a, 0xda, 0x76, 0x48,
+	0xa3, 0x48, 0xe6, 0x53, 0x05, 0xa2, 0x1b, 0x50, 0x8e, 0x62, 0x33, 0xa6, 0x99, 0x72, 0x2f, 0xa4,
+	0xec, 0x33, 0x3c, 0x11, 0x9f, 0xd1, 0x1a, 0x34, 0x1c, 0xcf, 0x32, 0x43, 0xcf, 0x8c, 0x59, 0x4a,
+	0x2b, 0xf1, 0xc9, 0x75, 0x14, 0x9b, 0x63, 0x1c, 0xd8, 0x26, 0x5b, 0x10, 0xb9, 0x43, 0x25, 0x88,
+	0x77, 0xa0, 0xbe, 0xcf, 0x56, 0x7f, 0xe0, 0x1d, 0xf9, 0x97, 0x50, 0xed, 0x1a, 0x54, 0x9e, 0x53,
+	0x67, 0x78, 0xac, 0xb6, 0x9f, 0x84, 0xf0, 0x6f, 0x0c, 0xb8, 0xba, 0xe5, 0x8e, 0xa3, 0x98, 0x86,
+	0x5b, 0xbe, 0x77, 0xe4, 0x0c, 0xc7, 0xa1, 0xd0, 0x20, 0x29, 0x44, 0x86, 0x5e, 0x88, 0x10, 0x94,
+	0x22, 0xe7, 0x2b, 0xaa, 0x36, 0x23, 0x1b, 0xa3, 0x1b, 0x50, 0xe1, 0xf1, 0x18, 0xc9, 0xe5, 0x14,
+	0x89, 0x27, 0x51, 0x92, 0xc8, 0xaf, 0x2c, 0xc4, 0x03, 0xd7, 0xb4, 0xe8, 0x88, 0x7a, 0xb1, 0x2a,
+	0x62, 0x09, 0x82, 0x55, 0xf7, 0x53, 0x27, 0x8c, 0xc7, 0xa6, 0x7b, 0xc8, 0xc2, 0x47, 0x64, 0xa6,
+	0x26, 0x59, 0x96, 0x48, 0x16, 0x5f, 0x11, 0xfe, 0xa9, 0x01, 0x57, 0xf7, 0x14, 0xcb, 0xb6, 0x6f,
+	0xda, 0x5a, 0x4a, 0x4a, 0x65, 0x1b, 0xe7, 0xca, 0x2e, 0xcc, 0xca, 0x66, 0xa6, 0x9d, 0xd0, 0x33,
+	0x61, 0x44, 0x9d, 0xf0, 0x31, 0xf3, 0x5a, 0x64, 0x8e, 0x02, 0x57, 0x14, 0xdd, 0x26, 0x91, 0x10,
+	0xfe, 0x9b, 0x01, 0x2f, 0x4c, 0xe9, 0x21, 0xf7, 0xc6, 0x62, 0x45, 0xd4, 0x1c, 0x22, 0xdc, 0xc4,
+	0x1c, 0xf7, 0xa1, 0xc2, 0x53, 0xa1, 0x72, 0xdf, 0x0d, 0xee, 0xbe, 0x5c, 0xe9, 0x1b, 0x5b, 0x9c,
+	0x50, 0x15, 0x5e, 0x0e, 0xf0, 0x25, 0x39, 0xa1, 0xcf, 0xb9, 0x86, 0x06, 0xe1, 0x63, 0xf4, 0x22,
+	0x54, 0xa3, 0xd8, 0x3e, 0xb4, 0xe9, 0x29, 0x77, 0xa3, 0x41, 0x2a, 0x51, 0x6c, 0xf7, 0xe8, 0x29,
+	0xaf, 0xd2, 0xa9, 0x8c, 0x4b, 0x55, 0xe9, 0x3e, 0x94, 0x0f, 0x26, 0xde, 0x6e, 0x70, 0xc1, 0x03,
+	0x30, 0xdb, 0xfe, 0xbc, 0xea, 0xca, 0x1a, 0x2c, 0x21, 0xfc, 0x0b, 0x03, 0xe0, 0x60, 0xa2, 0xd5,
+	0x92, 0xa2, 0x1f, 0xa8, 0xb3, 0x06, 0x88, 0x92, 0xc5, 0x66, 0x21, 0x0c, 0xfd, 0x7d, 0x9d, 0xa0,
+	0xf0, 0x2e, 0x34, 0xb8, 0x4e, 0x72, 0x11, 0x11, 0x94, 0xe2, 0x89, 0x63, 0x4b, 0x1b, 0xf9, 0x18,
+	0xdd, 0x86, 0x9a, 0x4d, 0x2d, 0x27, 0x39, 0x83, 0x28, 0x3d, 0x0e, 0x26, 0x5e, 0x4f, 0xe2, 0x49,
+	0x42, 0x81, 0x43, 0x68, 0xed, 0x85, 0x34, 0x30, 0xc3, 0xe4, 0xda, 0x90, 0x27, 0x73, 0x8d, 0x99,
+	0xe7, 0x87, 0xb6, 0xe3, 0xb1, 0xbc, 0x29, 0xfd, 0xa7, 0xa3, 0xd0, 0x4d, 0xa8, 0x3a, 0x5e, 0x4c,
+	0xd3, 0xe8, 0x98, 0x4d, 0x77, 0x8a, 0x00, 0x7f, 0x06, 0x4d, 0xa6, 0x89, 0xbd, 0x70, 0xca, 0xcb,
+	0x99, 0x71, 0x03, 0xda, 0x07, 0x13, 0x8f, 0xe5, 0xae, 0x71, 0xb4, 0x40, 0x2a, 0x7e, 0x02, 0xab,
+	0x1a, 0xdd, 0x77, 0xe6, 0xc5, 0x5b, 0x70, 0xe5, 0x73, 0x33, 0xb6, 0x8e, 0x65, 0x82, 0x52, 0x1a,
+	0xe4, 0xa6, 0x26, 0xfc, 0x73, 0x03, 0xae, 0x3e, 0xe1, 0x49, 0x72, 0x8a, 0xfc, 0x75, 0x68, 0xa9,
+	0x6a, 0x76, 0xa8, 0xf3, 0x35, 0x15, 0xb6, 0xcf, 0x90, 0xe8, 0x0e, 0xdb, 0x87, 0x2c, 0x03, 0xca,
+	0x23, 0xe6, 0x4b, 0x22, 0xcc, 0x72, 0x72, 0x23, 0x91, 0x84, 0x2c, 0xa8, 0x8e, 0xfc, 0xf0, 0xb9,
+	0x19, 0xda, 0xc9, 0x51, 0x33, 0x45, 0xe0, 0x5f, 0x1a, 0xd0, 0xd8, 0x63, 0x85, 0x36, 0x75, 0xdc,
+	0x51, 0xe8, 0x8f, 0x94, 0x3f, 0xd8, 0x98, 0xed, 0x92, 0xd8, 0x0c, 0x87, 0x34, 0x96, 0x8b, 0x2f,
+	0x21, 0xf4, 0x3a, 0x54, 0x47, 0xbc, 0x6e, 0xa8, 0x75, 0x6f, 0x68, 0xb5, 0x84, 0xa8, 0x6f, 0x9a,
+	0xce, 0xa5, 0x0b, 0xea, 0x8c, 0xbf, 0x82, 0x65, 0xa1, 0x54, 0x7a, 0xe0, 0x32, 0xad, 0x13, 0xae,
+	0x54, 0x8d, 0xb0, 0xa1, 0x3e, 0x77, 0xe1, 0x42, 0x73, 0x17, 0x2f, 0x3a, 0x37, 0xf3, 0x88, 0x10,
+	0xd3, 0x3f, 0x95, 0xe9, 0x90, 0x1d, 0x4a, 0x65, 0xdf, 0x81, 0x8f, 0x65, 0x76, 0x29, 0xe4, 0x95,
+	0xb4, 0x62, 0xb6, 0xa4, 0x5d, 0x97, 0xfe, 0x2c, 0xcd, 0x29, 0xb6, 0xc2, 0xc3, 0x6b, 0x50, 0x88,
+	0xfd, 0x4e, 0x79, 0x0e, 0x4d, 0x21, 0xf6, 0xf1, 0x04, 0x56, 0x04, 0x2a, 0x0d, 0x5d, 0xcd, 0x05,
+	0xc6, 0x02, 0x17, 0xac, 0x43, 0x85, 0x9e, 0xf2, 0xcd, 0x59, 0xd0, 0x36, 0xa7, 0x66, 0x21, 0x91,
+	0xdf, 0xf3, 0x93, 0x12, 0xde, 0x85, 0x95, 0x81, 0x67, 0xd3, 0x49, 0x8f, 0x1e, 0x39, 0x9e, 0xc3,
+	0xcb, 0x2e, 0x3b, 0xed, 0x9a, 0x23, 0x75, 0xac, 0xe6, 0x63, 0x86, 0x0b, 0xcc, 0xf8, 0x58, 0x3a,
+	0x85, 0x8f, 0x99, 0x40, 0xd7, 0x67, 0x8d, 0x19, 0x11, 0x76, 0x02, 0xc0, 0x3f, 0x36, 0xa0, 0xc1,
+	0x25, 0x8a, 0xad, 0x78, 0x19, 0x69, 0x21, 0x35, 0xed, 0x33, 0x25, 0x8d, 0x03, 0xbc, 0x35, 0x14,
+	0xfa, 0xc3, 0x50, 0xb5, 0x4e, 0x0c, 0x92, 0xc0, 0x6c, 0x59, 0xa8, 0x17, 0x87, 0x8e, 0x2c, 0xd4,
+	0x25, 0xa2, 0x40, 0x7c, 0x4f, 0x1a, 0x45, 0x53, 0x77, 0xf2, 0x2c, 0xc6, 0x51, 0x1d, 0x43, 0x73,
+	0x94, 0xa6, 0x29, 0x51, 0x04, 0xf8, 0x1f, 0x06, 0xac, 0x7e, 0x36, 0xa6, 0xe1, 0x19, 0xff, 0xaa,
+	0x6d, 0x79, 0x4e, 0xa0, 0x6e, 0x53, 0x1c, 0x60, 0x58, 0xfa, 0xe5, 0x58, 0x76, 0xa7, 0xea, 0x44,
+	0x00, 0x2c, 0xa2, 0x47, 0x8e, 0x27, 0xa3, 0x85, 0x0d, 0x39, 0xc6, 0x9c, 0xc8, 0xb4, 0xcf, 0x86,
+	0xbc, 0x97, 0xe0, 0x78, 0x87, 0x74, 0x62, 0xb9, 0xe3, 0xc8, 0x39, 0x15, 0x9d, 0x9f, 0x1a, 0x59,
+	0x1e, 0x39, 0x5e, 0x5f, 0xe1, 0x54, 0xc3, 0x21, 0x25, 0xaa, 0x48, 0x22, 0x73, 0x92, 0x12, 0xb1,
+	0x85, 0x70, 0x46, 0x4e, 0xcc, 0x1b, 0x2a, 0x4d, 0x22, 0x80, 0x74, 0x79, 0x6a, 0xda, 0xf2, 0x64,
+	0x8b, 0x50, 0x7d, 0xba, 0x08, 0x6d, 0x02, 0x70, 0x9b, 0x45, 0x69, 0xbe, 0x50, 0x95, 0xc5, 0x1f,
+	0x01, 0xd2, 0x9d, 0x25, 0xfd, 0xfd, 0x46, 0xba, 0x38, 0xc2, 0xdf, 0x2b, 0xa9, 0xbf, 0xb9, 0xf4,
+	0x74, 0xb5, 0x3e, 0x86, 0xe5, 0x6d, 0x7f, 0xe8, 0x24, 0xf5, 0xb8, 0x0b, 0xb5, 0x71, 0x44, 0x43,
+	0x2d, 0x6a, 0x12, 0x98, 0x7d, 0x0b, 0xcc, 0x28, 0x7a, 0xee, 0x87, 0xb6, 0xd4, 0x22, 0x81, 0xf1,
+	0x47, 0xd0, 0x94, 0x72, 0xd2, 0xfb, 0x6f, 0xec, 0x9f, 0x50, 0x4f, 0xad, 0x18, 0x07, 0xf4, 0x9b,
+	0x67, 0x21, 0x73, 0xf3, 0xc4, 0x5f, 0x40, 0xe3, 0x49, 0x44, 0xc3, 0x6f, 0xa9, 0x07, 0x8f, 0x64,
+	0xdf, 0xa5, 0xea, 0x18, 0x27, 0x00, 0xfc, 0x21, 0xd4, 0x98, 0x70, 0x7e, 0x66, 0x5e, 0x24, 0x39,
+	0xe1, 0x2e, 0xe8, 0xdc, 0x77, 0xa1, 0xc9, 0xb8, 0xd3, 0x78, 0x7e, 0x0d, 0xca, 0x8c, 0x25, 0xdb,
+	0x4d, 0x52, 0x13, 0x10, 0xf1, 0x0d, 0xef, 0x41, 0xf9, 0x51, 0x68, 0x7a, 0x31, 0xcb, 0xf1, 0x41,
+	0x48, 0x8f, 0x1c, 0x15, 0xbc, 0x12, 0x42, 0x6f, 0x02, 0x04, 0x34, 0x1c, 0x39, 0x91, 0x56, 0x0d,
+	0xc5, 0x42, 0xed, 0x25, 0x68, 0xa2, 0x91, 0xe0, 0x13, 0x58, 0xe6, 0x12, 0xb5, 0x82, 0xc2, 0x14,
+	0x54, 0xbb, 0x9b, 0x8d, 0xb5, 0xc9, 0x0a, 0x0b, 0x26, 0x2b, 0x9e, 0x3f, 0xd9, 0x43, 0xa8, 0x11,
+	0xdf, 0xa5, 0xdc, 0x65, 0x79, 0x69, 0x04, 0x43, 0x65, 0xc8, 0x94, 0x51, 0xb9, 0x4f, 0x9c, 0xdd,
+	0x84, 0x7e, 0xf2, 0x0b, 0x73, 0x1c, 0x93, 0x91, 0x71, 0x9c, 0xf0, 0x6f, 0xa6, 0x0d, 0x27, 0xa7,
+	0x51, 0xee, 0xfe, 0x9d, 0x01, 0xed, 0xc7, 0x6a, 0x57, 0x68, 0xb6, 0xce, 0xa8, 0xf0, 0x0a, 0x34,
+	0x6c, 0x7a, 0x64, 0x8e, 0xdd, 0xf8, 0x30, 0x8e, 0x5d, 0x19, 0x50, 0x20, 0x51, 0x07, 0xb1, 0x8b,
+	0x5e, 0x82, 0x1a, 0xdb, 0xc0, 0xf2, 0x58, 0xcf, 0xc3, 0x6d, 0x64, 0x4e, 0x3e, 0x65, 0xa7, 0xee,
+	0xff, 0x82, 0x3a, 0xfb, 0x24, 0x6e, 0xe2, 0xa2, 0x2f, 0xcc, 0x68, 0x1f, 0x32, 0x98, 0x85, 0x48,
+	0x48, 0x03, 0xd7, 0xb1, 0x4c, 0x75, 0x0d, 0x49, 0xe0, 0x74, 0x67, 0x57, 0xf4, 0xc4, 0xfb, 0xfb,
+	0x02, 0x34, 0x13, 0x9d, 0xe7, 0xfa, 0xec, 0x3f, 0xa2, 0xb0, 0xba, 0x73, 0x54, 0x44, 0x91, 0x65,
+	0xe3, 0xb4, 0x07, 0x21, 0xba, 0xc0, 0x02, 0x40, 0xaf, 0xc2, 0x72, 0x14, 0xfb, 0x21, 0xb5, 0xe5,
+	0x2c, 0x35, 0xfe, 0xb1, 0x21, 0x70, 0x62, 0xa2, 0x97, 0x01, 0x2c, 0x7f, 0x14, 0x84, 0x34, 0x8a,
+	0xa8, 0xcd, 0x53, 0x58, 0x91, 0x68, 0x18, 0x74, 0x0b, 0x56, 0x15, 0xe4, 0xf8, 0xde, 0x21, 0xaf,
+	0xff, 0x1d, 0xe0, 0xb5, 0xa3, 0xad, 0x7d, 0x20, 0x0c, 0x8f, 0x3f, 0x01, 0x94, 0xf8, 0x2c, 0x8d,
+	0x91, 0x4d, 0x80, 0x24, 0x27, 0xaa, 0x40, 0x11, 0xbd, 0xaf, 0x8c, 0x83, 0x89, 0x46, 0x85, 0xbf,
+	0x80, 0x3a, 0x31, 0x63, 0xba, 0xcd, 0x73, 0xef, 0x75, 0x68, 0xf9, 0x41, 0x74, 0x18, 0xd0, 0xf0,
+	0x30, 0xa2, 0x96, 0xef, 0x89, 0x13, 0xa8, 0x41, 0x96, 0xfd, 0x20, 0xda, 0xa3, 0xe1, 0x3e, 0xc7,
+	0xa1, 0x75, 0x68, 0x73, 0x2b, 0x75, 0xba, 0x02, 0xa7, 0x6b, 0x71, 0x7c, 0x42, 0x89, 0xbf, 0x36,
+	0xa0, 0xc9, 0x25, 0x27, 0x47, 0x60, 0xd6, 0xe2, 0x72, 0x9d, 0xf4, 0x86, 0x27, 0xa1, 0x6c, 0x7e,
+	0x2f, 0x4c, 0x37, 0x71, 0x30, 0x94, 0x42, 0xd5, 0x1c, 0x50, 0xb7, 0xe4, 0x44, 0x6b, 0xc2, 0xbf,
+	0x65, 0x02, 0xa0, 0xb4, 0x20, 0x00, 0xca, 0x53, 0x01, 0x90, 0x1f, 0x95, 0x7f, 0x29, 0x40, 0x4b,
+	0x69, 0x2e, 0xbd, 0xfb, 0x36, 0xb4, 0x54, 0x08, 0x6a, 0x26, 0xcc, 0xaa, 0xd3, 0x94, 0x54, 0x5b,
+	0xc2, 0xb2, 0xf7, 0xa1, 0x2a, 0xc8, 0xd5, 0x76, 0x5f, 0xe3, 0xf4, 0x59, 0xe1, 0x1b, 0x82, 0x58,
+	0xde, 0x4f, 0x15, 0x03, 0xda, 0xca, 0x2c, 0xa8, 0x38, 0xce, 0xbe, 0x96, 0xc7, 0x9e, 0x06, 0x83,
+	0x90, 0xa0, 0xb1, 0x75, 0x7f, 0x00, 0xcb, 0xba, 0xf4, 0x9c, 0x9b, 0xeb, 0x75, 0xbd, 0x40, 0xce,
+	0x1a, 0x94, 0xde, 0x64, 0xbb, 0x3b, 0xb0, 0x32, 0x35, 0xd5, 0xb7, 0x11, 0x87, 0xfb, 0xb0, 0xb2,
+	0xed, 0x0f, 0xb7, 0xe9, 0x29, 0x75, 0xb5, 0x76, 0x04, 0x8b, 0x76, 0xdf, 0xd3, 0xba, 0x00, 0x09,
+	0x82, 0x2f, 0x16, 0xa3, 0x56, 0xa5, 0x9c, 0x03, 0xf8, 0x67, 0x06, 0xac, 0x2a, 0x39, 0xe9, 0x7a,
+	0xbd, 0x0f, 0x15, 0xfe, 0x59, 0xed, 0x04, 0x2c, 0x1c, 0x37, 0x4d, 0xb7, 0x21, 0x40, 0xd9, 0x19,
+	0x10, 0x1c, 0xec, 0xb2, 0xaf, 0xa1, 0x2f, 0xf5, 0x2e, 0xf0, 0x07, 0x03, 0xe0, 0xc1, 0xd8, 0x76,
+	0x62, 0x7e, 0xba, 0xc8, 0x61, 0x5d, 0x1c, 0xea, 0x6c, 0x83, 0x98, 0xae, 0x4b, 0x43, 0x79, 0x0a,
+	0x93, 0x10, 0xe3, 0xf2, 0x03, 0x1a, 0xa6, 0x6d, 0xaf, 0x3a, 0x49, 0x11, 0x4c, 0x9d, 0xc8, 0xf1,
+	0xe4, 0x33, 0x5c, 0x91, 0x08, 0x20, 0x3d, 0x60, 0x55, 0x72, 0x0f, 0x58, 0xd5, 0xcc, 0xf9, 0xb7,
+	0x20, 0xd5, 0x16, 0x16, 0xe7, 0xdd, 0x2f, 0x92, 0x56, 0x6a, 0x41, 0x6b, 0xa5, 0xce, 0x55, 0x58,
+	0xbb, 0x7d, 0x94, 0xb2, 0xb7, 0x8f, 0x8c, 0x29, 0xe5, 0x69, 0x53, 0x16, 0x3f, 0xc3, 0xa9, 0x94,
+	0x5c, 0xd5, 0x5a, 0x4d, 0xff, 0x03, 0x10, 0x8a, 0xe8, 0x39, 0x74, 0x6c, 0x9e, 0x7a, 0xeb, 0xa4,
+	0x2e, 0x31, 0x03, 0x9b, 0xb1, 0x58, 0xac, 0xe7, 0x2a, 0x4e, 0x8d, 0x7c, 0xcc, 0x4c, 0xa1, 0x61,
+	0xe8, 0x87, 0x1d, 0x90, 0xc7, 0x5f, 0x06, 0xe0, 0x1f, 0x41, 0x93, 0xbb, 0xe0, 0xbc, 0xd3, 0x60,
+	0xea, 0xa7, 0xe4, 0x34, 0xc8, 0x1a, 0x12, 0x63, 0x2f, 0xa4, 0xa6, 0x75, 0x6c, 0x3e, 0x73, 0x55,
+	0x97, 0x4f, 0x47, 0xe1, 0x77, 0xa1, 0xbe, 0xef, 0xfa, 0xcf, 0x45, 0x58, 0x24, 0x4b, 0x63, 0xe4,
+	0x2e, 0x4d, 0x41, 0x5f, 0x9a, 0x3f, 0x17, 0xa0, 0xc9, 0x38, 0x77, 0x13, 0x1f, 0x7d, 0xfb, 0xd5,
+	0x59, 0x1c, 0x4e, 0x0b, 0x5f, 0x76, 0xb5, 0xb2, 0x38, 0x6f, 0x0d, 0xaa, 0xf3, 0xd6, 0xa0, 0xa6,
+	0xad, 0x41, 0x17, 0x6a, 0xb6, 0xbc, 0xe6, 0xca, 0x72, 0x98, 0xc0, 0x8c, 0xfe, 0xb9, 0xe9, 0xc4,
+	0x7c, 0x79, 0x8a, 0x84, 0x8f, 0x99, 0x81, 0x66, 0x10, 0xb8, 0x67, 0x9d, 0x86, 0x88, 0x71, 0x0e,
+	0x30, 0xca, 0xe8, 0xcc, 0xb3, 0x3a, 0xcb, 0x82, 0x92, 0x8d, 0xd1, 0x1b, 0xd0, 0x66, 0x95, 0xd7,
+	0x1c, 0xd2, 0x43, 0xa9, 0x42, 0xd4, 0x69, 0x72, 0x3f, 0xaf, 0x48, 0xbc, 0xcc, 0x36, 0x11, 0xb6,
+	0x61, 0x99, 0xb9, 0x56, 0x2f, 0xa1, 0x89, 0x1b, 0xb2, 0x25, 0x34, 0xb3, 0x02, 0x44, 0xa3, 0x3a,
+	0x7f, 0xe9, 0x6f, 0xbe, 0xc7, 0x7a, 0x87, 0x69, 0x9f, 0xad, 0x01, 0xd5, 0x5e, 0xff, 0xe3, 0x07,
+	0x4f, 0xb6, 0x0f, 0xda, 0x4b, 0xa8, 0x0a, 0xc5, 0xdd, 0xc7, 0xfd, 0xb6, 0x81, 0x00, 0x2a, 0x9f,
+	0x3d, 0xd9, 0x25, 0x4f, 0x76, 0xda, 0x05, 0x86, 0x7c, 0xb0, 0xbd, 0xdd, 0x2e, 0xde, 0x7c, 0x53,
+	0x5d, 0xfb, 0xf9, 0xa5, 0x1b, 0xd5, 0xa1, 0xfc, 0x60, 0x7b, 0xf0, 0xb4, 0xdf, 0x5e, 0x62, 0x42,
+	0xf6, 0x9f, 0xec, 0xef, 0xf5, 0xb7, 0x0e, 0xda, 0x06, 0xaa, 0x41, 0xa9, 0xd7, 0x7f, 0xd0, 0x6b,
+	0x17, 0x6e, 0xde, 0x81, 0x86, 0xd6, 0x11, 0x62, 0x54, 0x7b, 0xfd, 0xc7, 0xbd, 0xc1, 0xe3, 0x47,
+	0xed, 0x25, 0x36, 0xc3, 0xd6, 0xee, 0xce, 0xce, 0x80, 0x71, 0x30, 0x49, 0x0f, 0x77, 0xc9, 0x41,
+	0xbb, 0x70, 0xf3, 0x1d, 0x80, 0xf4, 0x24, 0xcb, 0x44, 0x3d, 0x66, 0x0a, 0x2d, 0xb1, 0x11, 0x61,
+	0x42, 0x39, 0xf1, 0xe7, 0x64, 0x70, 0xd0, 0x6f, 0x17, 0x38, 0x5f, 0x6f, 0x67, 0xf0, 0xb8, 0x5d,
+	0xdc, 0xfc, 0x7a, 0x15, 0x6a, 0x3d, 0x33, 0x36, 0x9f, 0x99, 0x7c, 0xab, 0x94, 0xd8, 0x83, 0x19,
+	0x6a, 0x27, 0x6f, 0x67, 0xd2, 0xc7, 0xdd, 0xec, 0x8b, 0x31, 0x5e, 0x42, 0x37, 0xa0, 0xf8, 0x88,
+	0xc6, 0x48, 0xd4, 0x85, 0x41, 0x6f, 0x2e, 0xdd, 0x2d, 0x28, 0xee, 0xd3, 0x18, 0x5d, 0x91, 0x74,
+	0xfa, 0xaf, 0x04, 0xb3, 0xc4, 0x6f, 0x40, 0x85, 0xd0, 0x91, 0x7f, 0x4a, 0xcf, 0x97, 0xfb, 0x0e,
+	0x54, 0xe5, 0x43, 0xb7, 0x94, 0x9d, 0x7d, 0xa7, 0xef, 0x5e, 0xcd, 0x22, 0x13, 0xbe, 0x37, 0xa1,
+	0xba, 0x9f, 0xe1, 0xcb, 0x3e, 0x64, 0xe7, 0x4d, 0x04, 0x44, 0x9c, 0x25, 0xf3, 0xec, 0xbd, 0xa6,
+	0xbf, 0x4a, 0xa6, 0xcf, 0x67, 0x78, 0x09, 0xdd, 0x4b, 0xf8, 0x98, 0xfd, 0x2f, 0x4c, 0xd3, 0x9d,
+	0xc7, 0x7e, 0x17, 0x1a, 0x8a, 0xdd, 0x32, 0x3d, 0xb9, 0x22, 0xda, 0x2b, 0x64, 0x77, 0x55, 0xc3,
+	0x24, 0x5c, 0x77, 0xa0, 0x22, 0xde, 0x91, 0xd0, 0xcc, 0xb3, 0x54, 0xf7, 0x4a, 0xce, 0x33, 0x13,
+	0x5e, 0x42, 0xff, 0x0b, 0x25, 0xd6, 0x10, 0x93, 0x0c, 0x5a, 0xc3, 0xae, 0xbb, 0xaa, 0x61, 0x34,
+	0xbd, 0xaa, 0xb2, 0x5b, 0x84, 0xc4, 0x77, 0xfd, 0xd7, 0x1a, 0xe9, 0xf5, 0xa9, 0x76, 0x12, 0x5e,
+	0x42, 0x0f, 0xa1, 0xfd, 0x88, 0xc6, 0x99, 0xe6, 0x58, 0x1e, 0xfb, 0xfc, 0x1e, 0x1a, 0x5e, 0x42,
+	0x3b, 0x80, 0xf4, 0x6e, 0xa8, 0x94, 0xd2, 0xe1, 0x2c, 0x39, 0x6d, 0xd2, 0x85, 0xc2, 0xde, 0x32,
+	0xd0, 0x0e, 0x5c, 0xc9, 0xb4, 0x4b, 0xa5, 0x3c, 0xc1, 0x95, 0xd7, 0x48, 0x5d, 0xac, 0xdd, 0x27,
+	0xd0, 0xcc, 0xbc, 0x59, 0x48, 0x41, 0x79, 0xaf, 0x35, 0xdd, 0xee, 0xfc, 0x27, 0x0e, 0xbc, 0x84,
+	0x6e, 0x42, 0xf1, 0x60, 0xe2, 0xa1, 0x15, 0xd5, 0x18, 0x56, 0x5c, 0xed, 0x14, 0x91, 0xd0, 0xbe,
+	0x07, 0x55, 0xd9, 0x67, 0x97, 0xd1, 0x9c, 0xed, 0xba, 0xcb, 0xf8, 0x9a, 0xe9, 0x4d, 0xf3, 0xb0,
+	0xae, 0x88, 0x6e, 0x39, 0x12, 0x99, 0x31, 0xd3, 0x3a, 0x5f, 0xc0, 0xf7, 0x21, 0xd4, 0x13, 0xb4,
+	0x8c, 0xea, 0xe9, 0x16, 0xf9, 0x02, 0xee, 0x77, 0xa1, 0xb1, 0x15, 0x52, 0x33, 0xa6, 0x03, 0xd1,
+	0xc0, 0x4a, 0xfb, 0x32, 0x69, 0x0f, 0xb0, 0x3b, 0xd3, 0x1d, 0xc3, 0x4b, 0xe8, 0x6d, 0xa8, 0xf7,
+	0x42, 0x3f, 0xb8, 0x2c, 0xdb, 0x5d, 0xa8, 0x72, 0x04, 0x5d, 0x10, 0xad, 0x53, 0xdd, 0x3a, 0xbc,
+	0x84, 0x3e, 0x02, 0x48, 0xbb, 0x4a, 0x48, 0x58, 0x33, 0xd3, 0x93, 0xeb, 0xbe, 0x38, 0x83, 0x4f,
+	0x04, 0xbc, 0x05, 0x65, 0xde, 0x0d, 0x92, 0x93, 0xea, 0x1d, 0xa6, 0x2e, 0xd2, 0x51, 0x09, 0xc7,
+	0x6d, 0xa8, 0x3e, 0xb0, 0x6d, 0xd6, 0x43, 0x91, 0x1b, 0x51, 0x6b, 0x06, 0x75, 0xb3, 0x0d, 0x16,
+	0x9e, 0xc4, 0x40, 0xe4, 0xc9, 0x8b, 0x32, 0xbc, 0x05, 0x65, 0x06, 0xe5, 0x7a, 0x01, 0x25, 0xc4,
+	0x51, 0x26, 0x4f, 0xd6, 0x45, 0x33, 0x83, 0x75, 0x55, 0x56, 0xb5, 0xe6, 0x46, 0x36, 0x4f, 0xca,
+	0xde, 0x05, 0x9f, 0x02, 0x08, 0x3d, 0xf5, 0x4f, 0xe8, 0x25, 0x38, 0xca, 0x0c, 0x5a, 0xa0, 0x54,
+	0xa6, 0x7b, 0x82, 0x97, 0xd0, 0x7d, 0x58, 0x11, 0xe1, 0x93, 0xdc, 0x5f, 0x64, 0x08, 0x4e, 0xf7,
+	0x4b, 0xba, 0x39, 0x37, 0x66, 0x1e, 0xbc, 0x4d, 0x16, 0x45, 0xdf, 0x90, 0xfb, 0x3e, 0xbb, 0x4d,
+	0x46, 0x71, 0x82, 0xce, 0x55, 0xfc, 0xc5, 0x2c, 0x6b, 0x76, 0xcb, 0xd5, 0xf7, 0x69, 0x2c, 0x2e,
+	0x7d, 0x72, 0xd7, 0x65, 0xee, 0xd5, 0xdd, 0x2b, 0x19, 0x5c, 0xc2, 0xb7, 0x09, 0x15, 0xc9, 0x94,
+	0x33, 0xdf, 0x1c, 0x9e, 0x7b, 0xd0, 0x60, 0x73, 0xc9, 0x7b, 0x92, 0xdc, 0x31, 0x53, 0xd7, 0xb4,
+	0xee, 0xb5, 0x0c, 0x36, 0xca, 0xe4, 0x95, 0x7a, 0x82, 0xce, 0x9b, 0x75, 0x3e, 0xe7, 0x1d, 0xa8,
+	0xf1, 0x93, 0xf5, 0xb6, 0x3f, 0x44, 0xda, 0x41, 0x9b, 0x6f, 0x93, 0x2e, 0x4a, 0x11, 0x1a, 0xcb,
+	0xdb, 0xd0, 0xca, 0x9c, 0xcb, 0x22, 0x59, 0x65, 0x93, 0x83, 0xb6, 0xaa, 0x75, 0xda, 0x19, 0x0f,
+	0x2f, 0x3d, 0xab, 0xf0, 0x5f, 0x3c, 0xff, 0xef, 0x5f, 0x03, 0x00, 0xfe, 0x68, 0x0f, 0x21, 0xef,
+	0x29, 0x00, 0x00,
+}
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ context.Context
+var _ grpc.ClientConnInterface
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+const _ = grpc.SupportPackageIsVersion6
+
+// DatabaseClient is the client API for Database service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type DatabaseClient interface {
+	// Time returns the server's hybrid logical clock, first advancing it past the caller's timestamp if one is given
+	Time(ctx context.Context, in *TimeRequest, opts ...grpc.CallOption) (*Response, error)
+	Get(ctx context.Context, in *IDRequest, opts ...grpc.CallOption) (*Response, error)
+	Set(ctx context.Context, in *IDValueRequest, opts ...grpc.CallOption) (*Response, error)
+	Remove(ctx context.Context, in *IDRequest, opts ...grpc.CallOption) (*Response, error)
+	// GetMany and SetMany read and write many keys in one request.  Clients send each shard the keys it owns.
+	GetMany(ctx context.Context, in *GetManyRequest, opts ...grpc.CallOption) (*GetManyResponse, error)
+	SetMany(ctx context.Context, in *SetManyRequest, opts ...grpc.CallOption) (*Response, error)
+	// ReplicaGet and ReplicaSet are used between nodes to read and write a single replica
+	ReplicaGet(ctx context.Context, in *IDRequest, opts ...grpc.CallOption) (*VersionedResponse, error)
+	ReplicaSet(ctx context.Context, in *VersionedRequest, opts ...grpc.CallOption) (*VersionedResponse, error)
+	// ReplicaScan is used between nodes to list a page of the keys stored on a single node
+	ReplicaScan(ctx context.Context, in *ScanRequest, opts ...grpc.CallOption) (*ScanResponse, error)
+	// Digest returns the Merkle digest of a node in the storage tree and of its children
+	Digest(ctx context.Context, in *NodeLocator, opts ...grpc.CallOption) (*DigestResponse, error)
+	// Ping is used by the gossip protocol to probe members and disseminate membership changes
+	Ping(ctx context.Context, in *PingRequest, opts ...grpc.CallOption) (*PingResponse, error)
+	// Members returns the cluster membership as seen by the server
+	Members(ctx context.Context, in *EmptyRequest, opts ...grpc.CallOption) (*MembersResponse, error)
+	// GetClusterConfig returns the cluster configuration known to the server and WatchClusterConfig streams every newer one
+	GetClusterConfig(ctx context.Context, in *EmptyRequest, opts ...grpc.CallOption) (*ClusterConfiguration, error)
+	WatchClusterConfig(ctx context.Context, in *WatchClusterRequest, opts ...grpc.CallOption) (Database_WatchClusterConfigClient, error)
+	// UpdateClusterConfig replaces the cluster configuration if the expected epoch is still current
+	UpdateClusterConfig(ctx context.Context, in *UpdateClusterRequest, opts ...grpc.CallOption) (*ClusterConfiguration, error)
+	// PlacementLoad reports how evenly the current placement, or another one, spreads a sample of keys over the shards
+	PlacementLoad(ctx context.Context, in *PlacementLoadRequest, opts ...grpc.CallOption) (*PlacementLoadResponse, error)
+	// Txn atomically applies a set of writes that may span several shards
+	Txn(ctx context.Context, in *TxnRequest, opts ...grpc.CallOption) (*TxnResponse, error)
+	// Prepare, Decide and TxnStatus are used between nodes to run two-phase commit
+	Prepare(ctx context.Context, in *PrepareRequest, opts ...grpc.CallOption) (*TxnStatusResponse, error)
+	Decide(ctx context.Context, in *DecideRequest, opts ...grpc.CallOption) (*TxnStatusResponse, error)
+	TxnStatus(ctx context.Context, in *TxnStatusRequest, opts ...grpc.CallOption) (*TxnStatusResponse, error)
+	// CreateIndex and DropIndex declare and remove a secondary index on every shard
+	CreateIndex(ctx context.Context, in *IndexDefinition, opts ...grpc.CallOption) (*IndexStatus, error)
+	DropIndex(ctx context.Context, in *IndexDefinition, opts ...grpc.CallOption) (*IndexStatus, error)
+	// Indexes returns the secondary indexes of the server and how far their builds have progressed
+	Indexes(ctx context.Context, in *EmptyRequest, opts ...grpc.CallOption) (*IndexesResponse, error)
+	// QueryIndex returns the keys whose indexed field matches the query
+	QueryIndex(ctx context.Context, in *QueryIndexRequest, opts ...grpc.CallOption) (*QueryIndexResponse, error)
+	// Login exchanges a username and password for a token, which is sent as "authorization: Bearer <token>" metadata
+	Login(ctx context.Context, in *LoginRequest, opts ...grpc.CallOption) (*LoginResponse, error)
+	// AddUser creates or replaces a user and RemoveUser deletes one
+	AddUser(ctx context.Context, in *UserRequest, opts ...grpc.CallOption) (*UserInfo, error)
+	RemoveUser(ctx context.Context, in *UserRequest, opts ...grpc.CallOption) (*UserInfo, error)
+	Users(ctx context.Context, in *EmptyRequest, opts ...grpc.CallOption) (*UsersResponse, error)
+	// GrantRole gives a role a permission on every key starting with a prefix and RevokeRole takes it away
+	GrantRole(ctx context.Context, in *GrantRequest, opts ...grpc.CallOption) (*RoleInfo, error)
+	RevokeRole(ctx context.Context, in *GrantRequest, opts ...grpc.CallOption) (*RoleInfo, error)
+	Roles(ctx context.Context, in *EmptyRequest, opts ...grpc.CallOption) (*RolesResponse, error)
+	// CreateNamespace creates a namespace, which holds its own keys with its own settings, and DropNamespace deletes one along with its keys
+	CreateNamespace(ctx context.Context, in *NamespaceRequest, opts ...grpc.CallOption) (*NamespaceInfo, error)
+	DropNamespace(ctx context.Context, in *NamespaceRequest, opts ...grpc.CallOption) (*NamespaceInfo, error)
+	ListNamespaces(ctx context.Context, in *EmptyRequest, opts ...grpc.CallOption) (*NamespacesResponse, error)
+	// SetLimits replaces the rate limit of a client, or the rate limit and storage quota of a namespace
+	SetLimits(ctx context.Context, in *LimitsRequest, opts ...grpc.CallOption) (*LimitsResponse, error)
+	Limits(ctx context.Context, in *EmptyRequest, opts ...grpc.CallOption) (*LimitsResponse, error)
+	// SetLogLevel changes the log level of a component of the server it is sent to, or of every component when none is given
+	SetLogLevel(ctx context.Context, in *LogLevelRequest, opts ...grpc.CallOption) (*LogLevelsResponse, error)
+	LogLevels(ctx context.Context, in *EmptyRequest, opts ...grpc.CallOption) (*LogLevelsResponse, error)
+	// AuditLog returns the changes recorded in the audit logs of every server that match the query, oldest first
+	AuditLog(ctx context.Context, in *AuditQuery, opts ...grpc.CallOption) (*AuditResponse, error)
+	// SlowOperations returns the requests recorded in the slow operation logs of every server, oldest first
+	SlowOperations(ctx context.Context, in *SlowQuery, opts ...grpc.CallOption) (*SlowResponse, error)
+}
+
+type databaseClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewDatabaseClient(cc grpc.ClientConnInterface) DatabaseClient {
+	return &databaseClient{cc}
+}
+
+func (c *databaseClient) Time(ctx context.Context, in *TimeRequest, opts ...grpc.CallOption) (*Response, error) {
+	out := new(Response)
+	err := c.cc.Invoke(ctx, "/api.Database/Time", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *databaseClient) Get(ctx context.Context, in *IDRequest, opts ...grpc.CallOption) (*Response, error) {
+	out := new(Response)
+	err := c.cc.Invoke(ctx, "/api.Database/Get", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *databaseClient) Set(ctx context.Context, in *IDValueRequest, opts ...grpc.CallOption) (*Response, error) {
+	out := new(Response)
+	err := c.cc.Invoke(ctx, "/api.Database/Set", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *databaseClient) Remove(ctx context.Context, in *IDRequest, opts ...grpc.CallOption) (*Response, error) {
+	out := new(Response)
+	err := c.cc.Invoke(ctx, "/api.Database/Remove", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *databaseClient) GetMany(ctx context.Context, in *GetManyRequest, opts ...grpc.CallOption) (*GetManyResponse, error) {
+	out := new(GetManyResponse)
+	err := c.cc.Invoke(ctx, "/api.Database/GetMany", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *databaseClient) SetMany(ctx context.Context, in *SetManyRequest, opts ...grpc.CallOption) (*Response, error) {
+	out := new(Response)
+	err := c.cc.Invoke(ctx, "/api.Database/SetMany", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *databaseClient) ReplicaGet(ctx context.Context, in *IDRequest, opts ...grpc.CallOption) (*VersionedResponse, error) {
+	out := new(VersionedResponse)
+	err := c.cc.Invoke(ctx, "/api.Database/ReplicaGet", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *databaseClient) ReplicaSet(ctx context.Context, in *VersionedRequest, opts ...grpc.CallOption) (*VersionedResponse, error) {
+	out := new(VersionedResponse)
+	err := c.cc.Invoke(ctx, "/api.Database/ReplicaSet", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *databaseClient) ReplicaScan(ctx context.Context, in *ScanRequest, opts ...grpc.CallOption) (*ScanResponse, error) {
+	out := new(ScanResponse)
+	err := c.cc.Invoke(ctx, "/api.Database/ReplicaScan", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *databaseClient) Digest(ctx context.Context, in *NodeLocator, opts ...grpc.CallOption) (*DigestResponse, error) {
+	out := new(DigestResponse)
+	err := c.cc.Invoke(ctx, "/api.Database/Digest", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *databaseClient) Ping(ctx context.Context, in *PingRequest, opts ...grpc.CallOption) (*PingResponse, error) {
+	out := new(PingResponse)
+	err := c.cc.Invoke(ctx, "/api.Database/Ping", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *databaseClient) Members(ctx context.Context, in *EmptyRequest, opts ...grpc.CallOption) (*MembersResponse, error) {
+	out := new(MembersResponse)
+	err := c.cc.Invoke(ctx, "/api.Database/Members", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *databaseClient) GetClusterConfig(ctx context.Context, in *EmptyRequest, opts ...grpc.CallOption) (*ClusterConfiguration, error) {
+	out := new(ClusterConfiguration)
+	err := c.cc.Invoke(ctx, "/api.Database/GetClusterConfig", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *databaseClient) WatchClusterConfig(ctx context.Context, in *WatchClusterRequest, opts ...grpc.CallOption) (Database_WatchClusterConfigClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_Database_serviceDesc.Streams[0], "/api.Database/WatchClusterConfig", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &databaseWatchClusterConfigClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Database_WatchClusterConfigClient interface {
+	Recv() (*ClusterConfiguration, error)
+	grpc.ClientStream
+}
+
+type databaseWatchClusterConfigClient struct {
+	grpc.ClientStream
+}
+
+func (x *databaseWatchClusterConfigClient) Recv() (*ClusterConfiguration, error) {
+	m := new(ClusterConfiguration)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *databaseClient) UpdateClusterConfig(ctx context.Context, in *UpdateClusterRequest, opts ...grpc.CallOption) (*ClusterConfiguration, error) {
+	out := new(ClusterConfiguration)
+	err := c.cc.Invoke(ctx, "/api.Database/UpdateClusterConfig", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *databaseClient) PlacementLoad(ctx context.Context, in *PlacementLoadRequest, opts ...grpc.CallOption) (*PlacementLoadResponse, error) {
+	out := new(PlacementLoadResponse)
+	err := c.cc.Invoke(ctx, "/api.Database/PlacementLoad", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *databaseClient) Txn(ctx context.Context, in *TxnRequest, opts ...grpc.CallOption) (*TxnResponse, error) {
+	out := new(TxnResponse)
+	err := c.cc.Invoke(ctx, "/api.Database/Txn", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *databaseClient) Prepare(ctx context.Context, in *PrepareRequest, opts ...grpc.CallOption) (*TxnStatusResponse, error) {
+	out := new(TxnStatusResponse)
+	err := c.cc.Invoke(ctx, "/api.Database/Prepare", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *databaseClient) Decide(ctx context.Context, in *DecideRequest, opts ...grpc.CallOption) (*TxnStatusResponse, error) {
+	out := new(TxnStatusResponse)
+	err := c.cc.Invoke(ctx, "/api.Database/Decide", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *databaseClient) TxnStatus(ctx context.Context, in *TxnStatusRequest, opts ...grpc.CallOption) (*TxnStatusResponse, error) {
+	out := new(TxnStatusResponse)
+	err := c.cc.Invoke(ctx, "/api.Database/TxnStatus", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *databaseClient) CreateIndex(ctx context.Context, in *IndexDefinition, opts ...grpc.CallOption) (*IndexStatus, error) {
+	out := new(IndexStatus)
+	err := c.cc.Invoke(ctx, "/api.Database/CreateIndex", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *databaseClient) DropIndex(ctx context.Context, in *IndexDefinition, opts ...grpc.CallOption) (*IndexStatus, error) {
+	out := new(IndexStatus)
+	err := c.cc.Invoke(ctx, "/api.Database/DropIndex", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *databaseClient) Indexes(ctx context.Context, in *EmptyRequest, opts ...grpc.CallOption) (*IndexesResponse, error) {
+	out := new(IndexesResponse)
+	err := c.cc.Invoke(ctx, "/api.Database/Indexes", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *databaseClient) QueryIndex(ctx context.Context, in *QueryIndexRequest, opts ...grpc.CallOption) (*QueryIndexResponse, error) {
+	out := new(QueryIndexResponse)
+	err := c.cc.Invoke(ctx, "/api.Database/QueryIndex", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *databaseClient) Login(ctx context.Context, in *LoginRequest, opts ...grpc.CallOption) (*LoginResponse, error) {
+	out := new(LoginResponse)
+	err := c.cc.Invoke(ctx, "/api.Database/Login", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *databaseClient) AddUser(ctx context.Context, in *UserRequest, opts ...grpc.CallOption) (*UserInfo, error) {
+	out := new(UserInfo)
+	err := c.cc.Invoke(ctx, "/api.Database/AddUser", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *databaseClient) RemoveUser(ctx context.Context, in *UserRequest, opts ...grpc.CallOption) (*UserInfo, error) {
+	out := new(UserInfo)
+	err := c.cc.Invoke(ctx, "/api.Database/RemoveUser", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *databaseClient) Users(ctx context.Context, in *EmptyRequest, opts ...grpc.CallOption) (*UsersResponse, error) {
+	out := new(UsersResponse)
+	err := c.cc.Invoke(ctx, "/api.Database/Users", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *databaseClient) GrantRole(ctx context.Context, in *GrantRequest, opts ...grpc.CallOption) (*RoleInfo, error) {
+	out := new(RoleInfo)
+	err := c.cc.Invoke(ctx, "/api.Database/GrantRole", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *databaseClient) RevokeRole(ctx context.Context, in *GrantRequest, opts ...grpc.CallOption) (*RoleInfo, error) {
+	out := new(RoleInfo)
+	err := c.cc.Invoke(ctx, "/api.Database/RevokeRole", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *databaseClient) Roles(ctx context.Context, in *EmptyRequest, opts ...grpc.CallOption) (*RolesResponse, error) {
+	out := new(RolesResponse)
+	err := c.cc.Invoke(ctx, "/api.Database/Roles", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *databaseClient) CreateNamespace(ctx context.Context, in *NamespaceRequest, opts ...grpc.CallOption) (*NamespaceInfo, error) {
+	out := new(NamespaceInfo)
+	err := c.cc.Invoke(ctx, "/api.Database/CreateNamespace", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *databaseClient) DropNamespace(ctx context.Context, in *NamespaceRequest, opts ...grpc.CallOption) (*NamespaceInfo, error) {
+	out := new(NamespaceInfo)
+	err := c.cc.Invoke(ctx, "/api.Database/DropNamespace", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *databaseClient) ListNamespaces(ctx context.Context, in *EmptyRequest, opts ...grpc.CallOption) (*NamespacesResponse, error) {
+	out := new(NamespacesResponse)
+	err := c.cc.Invoke(ctx, "/api.Database/ListNamespaces", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *databaseClient) SetLimits(ctx context.Context, in *LimitsRequest, opts ...grpc.CallOption) (*LimitsResponse, error) {
+	out := new(LimitsResponse)
+	err := c.cc.Invoke(ctx, "/api.Database/SetLimits", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *databaseClient) Limits(ctx context.Context, in *EmptyRequest, opts ...grpc.CallOption) (*LimitsResponse, error) {
+	out := new(LimitsResponse)
+	err := c.cc.Invoke(ctx, "/api.Database/Limits", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *databaseClient) SetLogLevel(ctx context.Context, in *LogLevelRequest, opts ...grpc.CallOption) (*LogLevelsResponse, error) {
+	out := new(LogLevelsResponse)
+	err := c.cc.Invoke(ctx, "/api.Database/SetLogLevel", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *databaseClient) LogLevels(ctx context.Context, in *EmptyRequest, opts ...grpc.CallOption) (*LogLevelsResponse, error) {
+	out := new(LogLevelsResponse)
+	err := c.cc.Invoke(ctx, "/api.Database/LogLevels", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *databaseClient) AuditLog(ctx context.Context, in *AuditQuery, opts ...grpc.CallOption) (*AuditResponse, error) {
+	out := new(AuditResponse)
+	err := c.cc.Invoke(ctx, "/api.Database/AuditLog", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *databaseClient) SlowOperations(ctx context.Context, in *SlowQuery, opts ...grpc.CallOption) (*SlowResponse, error) {
+	out := new(SlowResponse)
+	err := c.cc.Invoke(ctx, "/api.Database/SlowOperations", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// DatabaseServer is the server API for Database service.
+type DatabaseServer interface {
+	// Time returns the server's hybrid logical clock, first advancing it past the caller's timestamp if one is given
+	Time(context.Context, *TimeRequest) (*Response, error)
+	Get(context.Context, *IDRequest) (*Response, error)
+	Set(context.Context, *IDValueRequest) (*Response, error)
+	Remove(context.Context, *IDRequest) (*Response, error)
+	// GetMany and SetMany read and write many keys in one request.  Clients send each shard the keys it owns.
+	GetMany(context.Context, *GetManyRequest) (*GetManyResponse, error)
+	SetMany(context.Context, *SetManyRequest) (*Response, error)
+	// ReplicaGet and ReplicaSet are used between nodes to read and write a single replica
+	ReplicaGet(context.Context, *IDRequest) (*VersionedResponse, error)
+	ReplicaSet(context.Context, *VersionedRequest) (*VersionedResponse, error)
+	// ReplicaScan is used between nodes to list a page of the keys stored on a single node
+	ReplicaScan(context.Context, *ScanRequest) (*ScanResponse, error)
+	// Digest returns the Merkle digest of a node in the storage tree and of its children
+	Digest(context.Context, *NodeLocator) (*DigestResponse, error)
+	// Ping is used by the gossip protocol to probe members and disseminate membership changes
+	Ping(context.Context, *PingRequest) (*PingResponse, error)
+	// Members returns the cluster membership as seen by the server
+	Members(context.Context, *EmptyRequest) (*MembersResponse, error)
+	// GetClusterConfig returns the cluster configuration known to the server and WatchClusterConfig streams every newer one
+	GetClusterConfig(context.Context, *EmptyRequest) (*ClusterConfiguration, error)
+	WatchClusterConfig(*WatchClusterRequest, Database_WatchClusterConfigServer) error
+	// UpdateClusterConfig replaces the cluster configuration if the expected epoch is still current
+	UpdateClusterConfig(context.Context, *UpdateClusterRequest) (*ClusterConfiguration, error)
+	// PlacementLoad reports how evenly the current placement, or another one, spreads a sample of keys over the shards
+	PlacementLoad(context.Context, *PlacementLoadRequest) (*PlacementLoadResponse, error)
+	// Txn atomically applies a set of writes that may span several shards
+	Txn(context.Context, *TxnRequest) (*TxnResponse, error)
+	// Prepare, Decide and TxnStatus are used between nodes to run two-phase commit
+	Prepare(context.Context, *PrepareRequest) (*TxnStatusResponse, error)
+	Decide(context.Context, *DecideRequest) (*TxnStatusResponse, error)
+	TxnStatus(context.Context, *TxnStatusRequest) (*TxnStatusResponse, error)
+	// CreateIndex and DropIndex declare and remove a secondary index on every shard
+	CreateIndex(context.Context, *IndexDefinition) (*IndexStatus, error)
+	DropIndex(context.Context, *IndexDefinition) (*IndexStatus, error)
+	// Indexes returns the secondary indexes of the server and how far their builds have progressed
+	Indexes(context.Context, *EmptyRequest) (*IndexesResponse, error)
+	// QueryIndex returns the keys whose indexed field matches the query
+	QueryIndex(context.Context, *QueryIndexRequest) (*QueryIndexResponse, error)
+	// Login exchanges a username and password for a token, which is sent as "authorization: Bearer <token>" metadata
+	Login(context.Context, *LoginRequest) (*LoginResponse, error)
+	// AddUser creates or replaces a user and RemoveUser deletes one
+	AddUser(context.Context, *UserRequest) (*UserInfo, error)
+	RemoveUser(context.Context, *UserRequest) (*UserInfo, error)
+	Users(context.Context, *EmptyRequest) (*UsersResponse, error)
+	// GrantRole gives a role a permission on every key starting with a prefix and RevokeRole takes it away
+	GrantRole(context.Context, *GrantRequest) (*RoleInfo, error)
+	RevokeRole(context.Context, *GrantRequest) (*RoleInfo, error)
+	Roles(context.Context, *EmptyRequest) (*RolesResponse, error)
+	// CreateNamespace creates a namespace, which holds its own keys with its own settings, and DropNamespace deletes one along with its keys
+	CreateNamespace(context.Context, *NamespaceRequest) (*NamespaceInfo, error)
+	DropNamespace(context.Context, *NamespaceRequest) (*NamespaceInfo, error)
+	ListNamespaces(context.Context, *EmptyRequest) (*NamespacesResponse, error)
+	// SetLimits replaces the rate limit of a client, or the rate limit and storage quota of a namespace
+	SetLimits(context.Context, *LimitsRequest) (*LimitsResponse, error)
+	Limits(context.Context, *EmptyRequest) (*LimitsResponse, error)
+	// SetLogLevel changes the log level of a component of the server it is sent to, or of every component when none is given
+	SetLogLevel(context.Context, *LogLevelRequest) (*LogLevelsResponse, error)
+	LogLevels(context.Context, *EmptyRequest) (*LogLevelsResponse, error)
+	// AuditLog returns the changes recorded in the audit logs of every server that match the query, oldest first
+	AuditLog(context.Context, *AuditQuery) (*AuditResponse, error)
+	// SlowOperations returns the requests recorded in the slow operation logs of every server, oldest first
+	SlowOperations(context.Context, *SlowQuery) (*SlowResponse, error)
+}
+
+// UnimplementedDatabaseServer can be embedded to have forward compatible implementations.
+type UnimplementedDatabaseServer struct {
+}
+
+func (*UnimplementedDatabaseServer) Time(ctx context.Context, req *TimeRequest) (*Response, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Time not implemented")
+}
+func (*UnimplementedDatabaseServer) Get(ctx context.Context, req *IDRequest) (*Response, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Get not implemented")
+}
+func (*UnimplementedDatabaseServer) Set(ctx context.Context, req *IDValueRequest) (*Response, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Set not implemented")
+}
+func (*UnimplementedDatabaseServer) Remove(ctx context.Context, req *IDRequest) (*Response, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Remove not implemented")
+}
+func (*UnimplementedDatabaseServer) GetMany(ctx context.Context, req *GetManyRequest) (*GetManyResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetMany not implemented")
+}
+func (*UnimplementedDatabaseServer) SetMany(ctx context.Context, req *SetManyRequest) (*Response, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetMany not implemented")
+}
+func (*UnimplementedDatabaseServer) ReplicaGet(ctx context.Context, req *IDRequest) (*VersionedResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReplicaGet not implemented")
+}
+func (*UnimplementedDatabaseServer) ReplicaSet(ctx context.Context, req *VersionedRequest) (*VersionedResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReplicaSet not implemented")
+}
+func (*UnimplementedDatabaseServer) ReplicaScan(ctx context.Context, req *ScanRequest) (*ScanResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReplicaScan not implemented")
+}
+func (*UnimplementedDatabaseServer) Digest(ctx context.Context, req *NodeLocator) (*DigestResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Digest not implemented")
+}
+func (*UnimplementedDatabaseServer) Ping(ctx context.Context, req *PingRequest) (*PingResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Ping not implemented")
+}
+func (*UnimplementedDatabaseServer) Members(ctx context.Context, req *EmptyRequest) (*MembersResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Members not implemented")
+}
+func (*UnimplementedDatabaseServer) GetClusterConfig(ctx context.Context, req *EmptyRequest) (*ClusterConfiguration, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetClusterConfig not implemented")
+}
+func (*UnimplementedDatabaseServer) WatchClusterConfig(req *WatchClusterRequest, srv Database_WatchClusterConfigServer) error {
+	return status.Errorf(codes.Unimplemented, "method WatchClusterConfig not implemented")
+}
+func (*UnimplementedDatabaseServer) UpdateClusterConfig(ctx context.Context, req *UpdateClusterRequest) (*ClusterConfiguration, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateClusterConfig not implemented")
+}
+func (*UnimplementedDatabaseServer) PlacementLoad(ctx context.Context, req *PlacementLoadRequest) (*PlacementLoadResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PlacementLoad not implemented")
+}
+func (*UnimplementedDatabaseServer) Txn(ctx context.Context, req *TxnRequest) (*TxnResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Txn not implemented")
+}
+func (*UnimplementedDatabaseServer) Prepare(ctx context.Context, req *PrepareRequest) (*TxnStatusResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Prepare not implemented")
+}
+func (*UnimplementedDatabaseServer) Decide(ctx context.Context, req *DecideRequest) (*TxnStatusResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Decide not implemented")
+}
+func (*UnimplementedDatabaseServer) TxnStatus(ctx context.Context, req *TxnStatusRequest) (*TxnStatusResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method TxnStatus not implemented")
+}
+func (*UnimplementedDatabaseServer) CreateIndex(ctx context.Context, req *IndexDefinition) (*IndexStatus, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateIndex not implemented")
+}
+func (*UnimplementedDatabaseServer) DropIndex(ctx context.Context, req *IndexDefinition) (*IndexStatus, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DropIndex not implemented")
+}
+func (*UnimplementedDatabaseServer) Indexes(ctx context.Context, req *EmptyRequest) (*IndexesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Indexes not implemented")
+}
+func (*UnimplementedDatabaseServer) QueryIndex(ctx context.Context, req *QueryIndexRequest) (*QueryIndexResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method QueryIndex not implemented")
+}
+func (*UnimplementedDatabaseServer) Login(ctx context.Context, req *LoginRequest) (*LoginResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Login not implemented")
+}
+func (*UnimplementedDatabaseServer) AddUser(ctx context.Context, req *UserRequest) (*UserInfo, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AddUser not implemented")
+}
+func (*UnimplementedDatabaseServer) RemoveUser(ctx context.Context, req *UserRequest) (*UserInfo, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RemoveUser not implemented")
+}
+func (*UnimplementedDatabaseServer) Users(ctx context.Context, req *EmptyRequest) (*UsersResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Users not implemented")
+}
+func (*UnimplementedDatabaseServer) GrantRole(ctx context.Context, req *GrantRequest) (*RoleInfo, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GrantRole not implemented")
+}
+func (*UnimplementedDatabaseServer) RevokeRole(ctx context.Context, req *GrantRequest) (*RoleInfo, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RevokeRole not implemented")
+}
+func (*UnimplementedDatabaseServer) Roles(ctx context.Context, req *EmptyRequest) (*RolesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Roles not implemented")
+}
+func (*UnimplementedDatabaseServer) CreateNamespace(ctx context.Context, req *NamespaceRequest) (*NamespaceInfo, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateNamespace not implemented")
+}
+func (*UnimplementedDatabaseServer) DropNamespace(ctx context.Context, req *NamespaceRequest) (*NamespaceInfo, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DropNamespace not implemented")
+}
+func (*UnimplementedDatabaseServer) ListNamespaces(ctx context.Context, req *EmptyRequest) (*NamespacesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListNamespaces not implemented")
+}
+func (*UnimplementedDatabaseServer) SetLimits(ctx context.Context, req *LimitsRequest) (*LimitsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetLimits not implemented")
+}
+func (*UnimplementedDatabaseServer) Limits(ctx context.Context, req *EmptyRequest) (*LimitsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Limits not implemented")
+}
+func (*UnimplementedDatabaseServer) SetLogLevel(ctx context.Context, req *LogLevelRequest) (*LogLevelsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetLogLevel not implemented")
+}
+func (*UnimplementedDatabaseServer) LogLevels(ctx context.Context, req *EmptyRequest) (*LogLevelsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method LogLevels not implemented")
+}
+func (*UnimplementedDatabaseServer) AuditLog(ctx context.Context, req *AuditQuery) (*AuditResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AuditLog not implemented")
+}
+func (*UnimplementedDatabaseServer) SlowOperations(ctx context.Context, req *SlowQuery) (*SlowResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SlowOperations not implemented")
+}
+
+func RegisterDatabaseServer(s *grpc.Server, srv DatabaseServer) {
+	s.RegisterService(&_Database_serviceDesc, srv)
+}
+
+func _Database_Time_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TimeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DatabaseServer).Time(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/api.Database/Time",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DatabaseServer).Time(ctx, req.(*TimeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Database_Get_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(IDRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DatabaseServer).Get(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/api.Database/Get",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DatabaseServer).Get(ctx, req.(*IDRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Database_Set_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(IDValueRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DatabaseServer).Set(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/api.Database/Set",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DatabaseServer).Set(ctx, req.(*IDValueRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Database_Remove_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(IDRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DatabaseServer).Remove(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/api.Database/Remove",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DatabaseServer).Remove(ctx, req.(*IDRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Database_GetMany_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetManyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DatabaseServer).GetMany(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/api.Database/GetMany",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DatabaseServer).GetMany(ctx, req.(*GetManyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Database_SetMany_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetManyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DatabaseServer).SetMany(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/api.Database/SetMany",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DatabaseServer).SetMany(ctx, req.(*SetManyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Database_ReplicaGet_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(IDRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DatabaseServer).ReplicaGet(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/api.Database/ReplicaGet",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DatabaseServer).ReplicaGet(ctx, req.(*IDRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Database_ReplicaSet_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(VersionedRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DatabaseServer).ReplicaSet(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/api.Database/ReplicaSet",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DatabaseServer).ReplicaSet(ctx, req.(*VersionedRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Database_ReplicaScan_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ScanRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DatabaseServer).ReplicaScan(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/api.Database/ReplicaScan",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DatabaseServer).ReplicaScan(ctx, req.(*ScanRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Database_Digest_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(NodeLocator)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DatabaseServer).Digest(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/api.Database/Digest",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DatabaseServer).Digest(ctx, req.(*NodeLocator))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Database_Ping_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PingRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DatabaseServer).Ping(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/api.Database/Ping",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DatabaseServer).Ping(ctx, req.(*PingRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Database_Members_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(EmptyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DatabaseServer).Members(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/api.Database/Members",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DatabaseServer).Members(ctx, req.(*EmptyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Database_GetClusterConfig_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(EmptyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DatabaseServer).GetClusterConfig(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/api.Database/GetClusterConfig",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DatabaseServer).GetClusterConfig(ctx, req.(*EmptyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Database_WatchClusterConfig_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchClusterRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(DatabaseServer).WatchClusterConfig(m, &databaseWatchClusterConfigServer{stream})
+}
+
+type Database_WatchClusterConfigServer interface {
+	Send(*ClusterConfiguration) error
+	grpc.ServerStream
+}
+
+type databaseWatchClusterConfigServer struct {
+	grpc.ServerStream
+}
+
+func (x *databaseWatchClusterConfigServer) Send(m *ClusterConfiguration) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Database_UpdateClusterConfig_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateClusterRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DatabaseServer).UpdateClusterConfig(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/api.Database/UpdateClusterConfig",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DatabaseServer).UpdateClusterConfig(ctx, req.(*UpdateClusterRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Database_PlacementLoad_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PlacementLoadRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DatabaseServer).PlacementLoad(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/api.Database/PlacementLoad",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DatabaseServer).PlacementLoad(ctx, req.(*PlacementLoadRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Database_Txn_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TxnRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DatabaseServer).Txn(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/api.Database/Txn",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DatabaseServer).Txn(ctx, req.(*TxnRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Database_Prepare_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PrepareRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DatabaseServer).Prepare(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/api.Database/Prepare",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DatabaseServer).Prepare(ctx, req.(*PrepareRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Database_Decide_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DecideRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DatabaseServer).Decide(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/api.Database/Decide",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DatabaseServer).Decide(ctx, req.(*DecideRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Database_TxnStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TxnStatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DatabaseServer).TxnStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/api.Database/TxnStatus",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DatabaseServer).TxnStatus(ctx, req.(*TxnStatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Database_CreateIndex_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(IndexDefinition)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DatabaseServer).CreateIndex(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/api.Database/CreateIndex",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DatabaseServer).CreateIndex(ctx, req.(*IndexDefinition))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Database_DropIndex_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(IndexDefinition)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DatabaseServer).DropIndex(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/api.Database/DropIndex",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DatabaseServer).DropIndex(ctx, req.(*IndexDefinition))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Database_Indexes_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(EmptyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DatabaseServer).Indexes(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/api.Database/Indexes",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DatabaseServer).Indexes(ctx, req.(*EmptyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Database_QueryIndex_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(QueryIndexRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DatabaseServer).QueryIndex(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/api.Database/QueryIndex",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DatabaseServer).QueryIndex(ctx, req.(*QueryIndexRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Database_Login_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(LoginRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DatabaseServer).Login(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/api.Database/Login",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DatabaseServer).Login(ctx, req.(*LoginRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-func (m *Response) Reset()                    { *m = Response{} }
-func (m *Response) String() string            { return proto.CompactTextString(m) }
-func (*Response) ProtoMessage()               {}
-func (*Response) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{3} }
-
-func (m *Response) GetValue() string {
-	if m != nil {
-		return m.Value
+func _Database_AddUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UserRequest)
+	if err := dec(in); err != nil {
+		return nil, err
 	}
-	return ""
+	if interceptor == nil {
+		return srv.(DatabaseServer).AddUser(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/api.Database/AddUser",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DatabaseServer).AddUser(ctx, req.(*UserRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-func init() {
-	proto.RegisterType((*EmptyRequest)(nil), "api.EmptyRequest")
-	proto.RegisterType((*IDRequest)(nil), "api.IDRequest")
-	proto.RegisterType((*IDValueRequest)(nil), "api.IDValueRequest")
-	proto.RegisterType((*Response)(nil), "api.Response")
+func _Database_RemoveUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UserRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DatabaseServer).RemoveUser(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/api.Database/RemoveUser",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DatabaseServer).RemoveUser(ctx, req.(*UserRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-// Reference imports to suppress errors if they are not otherwise used.
-var _ context.Context
-var _ grpc.ClientConn
-
-// This is a compile-time assertion to ensure that this generated file
-// is compatible with the grpc package it is being compiled against.
-const _ = grpc.SupportPackageIsVersion4
-
-// Client API for Database service
-
-type DatabaseClient interface {
-	Time(ctx context.Context, in *EmptyRequest, opts ...grpc.CallOption) (*Response, error)
-	Get(ctx context.Context, in *IDRequest, opts ...grpc.CallOption) (*Response, error)
-	Set(ctx context.Context, in *IDValueRequest, opts ...grpc.CallOption) (*Response, error)
-	Remove(ctx context.Context, in *IDRequest, opts ...grpc.CallOption) (*Response, error)
+func _Database_Users_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(EmptyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DatabaseServer).Users(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/api.Database/Users",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DatabaseServer).Users(ctx, req.(*EmptyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-type databaseClient struct {
-	cc *grpc.ClientConn
+func _Database_GrantRole_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GrantRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DatabaseServer).GrantRole(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/api.Database/GrantRole",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DatabaseServer).GrantRole(ctx, req.(*GrantRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-func NewDatabaseClient(cc *grpc.ClientConn) DatabaseClient {
-	return &databaseClient{cc}
+func _Database_RevokeRole_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GrantRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DatabaseServer).RevokeRole(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/api.Database/RevokeRole",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DatabaseServer).RevokeRole(ctx, req.(*GrantRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-func (c *databaseClient) Time(ctx context.Context, in *EmptyRequest, opts ...grpc.CallOption) (*Response, error) {
-	out := new(Response)
-	err := grpc.Invoke(ctx, "/api.Database/Time", in, out, c.cc, opts...)
-	if err != nil {
+func _Database_Roles_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(EmptyRequest)
+	if err := dec(in); err != nil {
 		return nil, err
 	}
-	return out, nil
+	if interceptor == nil {
+		return srv.(DatabaseServer).Roles(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/api.Database/Roles",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DatabaseServer).Roles(ctx, req.(*EmptyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-func (c *databaseClient) Get(ctx context.Context, in *IDRequest, opts ...grpc.CallOption) (*Response, error) {
-	out := new(Response)
-	err := grpc.Invoke(ctx, "/api.Database/Get", in, out, c.cc, opts...)
-	if err != nil {
+func _Database_CreateNamespace_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(NamespaceRequest)
+	if err := dec(in); err != nil {
 		return nil, err
 	}
-	return out, nil
+	if interceptor == nil {
+		return srv.(DatabaseServer).CreateNamespace(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/api.Database/CreateNamespace",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DatabaseServer).CreateNamespace(ctx, req.(*NamespaceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-func (c *databaseClient) Set(ctx context.Context, in *IDValueRequest, opts ...grpc.CallOption) (*Response, error) {
-	out := new(Response)
-	err := grpc.Invoke(ctx, "/api.Database/Set", in, out, c.cc, opts...)
-	if err != nil {
+func _Database_DropNamespace_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(NamespaceRequest)
+	if err := dec(in); err != nil {
 		return nil, err
 	}
-	return out, nil
+	if interceptor == nil {
+		return srv.(DatabaseServer).DropNamespace(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/api.Database/DropNamespace",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DatabaseServer).DropNamespace(ctx, req.(*NamespaceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-func (c *databaseClient) Remove(ctx context.Context, in *IDRequest, opts ...grpc.CallOption) (*Response, error) {
-	out := new(Response)
-	err := grpc.Invoke(ctx, "/api.Database/Remove", in, out, c.cc, opts...)
-	if err != nil {
+func _Database_ListNamespaces_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(EmptyRequest)
+	if err := dec(in); err != nil {
 		return nil, err
 	}
-	return out, nil
+	if interceptor == nil {
+		return srv.(DatabaseServer).ListNamespaces(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/api.Database/ListNamespaces",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DatabaseServer).ListNamespaces(ctx, req.(*EmptyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-// Server API for Database service
-
-type DatabaseServer interface {
-	Time(context.Context, *EmptyRequest) (*Response, error)
-	Get(context.Context, *IDRequest) (*Response, error)
-	Set(context.Context, *IDValueRequest) (*Response, error)
-	Remove(context.Context, *IDRequest) (*Response, error)
+func _Database_SetLimits_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(LimitsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DatabaseServer).SetLimits(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/api.Database/SetLimits",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DatabaseServer).SetLimits(ctx, req.(*LimitsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-func RegisterDatabaseServer(s *grpc.Server, srv DatabaseServer) {
-	s.RegisterService(&_Database_serviceDesc, srv)
+func _Database_Limits_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(EmptyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DatabaseServer).Limits(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/api.Database/Limits",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DatabaseServer).Limits(ctx, req.(*EmptyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-func _Database_Time_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(EmptyRequest)
+func _Database_SetLogLevel_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(LogLevelRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(DatabaseServer).Time(ctx, in)
+		return srv.(DatabaseServer).SetLogLevel(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: "/api.Database/Time",
+		FullMethod: "/api.Database/SetLogLevel",
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(DatabaseServer).Time(ctx, req.(*EmptyRequest))
+		return srv.(DatabaseServer).SetLogLevel(ctx, req.(*LogLevelRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _Database_Get_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(IDRequest)
+func _Database_LogLevels_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(EmptyRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(DatabaseServer).Get(ctx, in)
+		return srv.(DatabaseServer).LogLevels(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: "/api.Database/Get",
+		FullMethod: "/api.Database/LogLevels",
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(DatabaseServer).Get(ctx, req.(*IDRequest))
+		return srv.(DatabaseServer).LogLevels(ctx, req.(*EmptyRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _Database_Set_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(IDValueRequest)
+func _Database_AuditLog_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AuditQuery)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(DatabaseServer).Set(ctx, in)
+		return srv.(DatabaseServer).AuditLog(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: "/api.Database/Set",
+		FullMethod: "/api.Database/AuditLog",
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(DatabaseServer).Set(ctx, req.(*IDValueRequest))
+		return srv.(DatabaseServer).AuditLog(ctx, req.(*AuditQuery))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _Database_Remove_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(IDRequest)
+func _Database_SlowOperations_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SlowQuery)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(DatabaseServer).Remove(ctx, in)
+		return srv.(DatabaseServer).SlowOperations(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: "/api.Database/Remove",
+		FullMethod: "/api.Database/SlowOperations",
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(DatabaseServer).Remove(ctx, req.(*IDRequest))
+		return srv.(DatabaseServer).SlowOperations(ctx, req.(*SlowQuery))
 	}
 	return interceptor(ctx, in, info, handler)
 }
@@ -272,26 +5749,153 @@ var _Database_serviceDesc = grpc.ServiceDesc{
 			MethodName: "Remove",
 			Handler:    _Database_Remove_Handler,
 		},
+		{
+			MethodName: "GetMany",
+			Handler:    _Database_GetMany_Handler,
+		},
+		{
+			MethodName: "SetMany",
+			Handler:    _Database_SetMany_Handler,
+		},
+		{
+			MethodName: "ReplicaGet",
+			Handler:    _Database_ReplicaGet_Handler,
+		},
+		{
+			MethodName: "ReplicaSet",
+			Handler:    _Database_ReplicaSet_Handler,
+		},
+		{
+			MethodName: "ReplicaScan",
+			Handler:    _Database_ReplicaScan_Handler,
+		},
+		{
+			MethodName: "Digest",
+			Handler:    _Database_Digest_Handler,
+		},
+		{
+			MethodName: "Ping",
+			Handler:    _Database_Ping_Handler,
+		},
+		{
+			MethodName: "Members",
+			Handler:    _Database_Members_Handler,
+		},
+		{
+			MethodName: "GetClusterConfig",
+			Handler:    _Database_GetClusterConfig_Handler,
+		},
+		{
+			MethodName: "UpdateClusterConfig",
+			Handler:    _Database_UpdateClusterConfig_Handler,
+		},
+		{
+			MethodName: "PlacementLoad",
+			Handler:    _Database_PlacementLoad_Handler,
+		},
+		{
+			MethodName: "Txn",
+			Handler:    _Database_Txn_Handler,
+		},
+		{
+			MethodName: "Prepare",
+			Handler:    _Database_Prepare_Handler,
+		},
+		{
+			MethodName: "Decide",
+			Handler:    _Database_Decide_Handler,
+		},
+		{
+			MethodName: "TxnStatus",
+			Handler:    _Database_TxnStatus_Handler,
+		},
+		{
+			MethodName: "CreateIndex",
+			Handler:    _Database_CreateIndex_Handler,
+		},
+		{
+			MethodName: "DropIndex",
+			Handler:    _Database_DropIndex_Handler,
+		},
+		{
+			MethodName: "Indexes",
+			Handler:    _Database_Indexes_Handler,
+		},
+		{
+			MethodName: "QueryIndex",
+			Handler:    _Database_QueryIndex_Handler,
+		},
+		{
+			MethodName: "Login",
+			Handler:    _Database_Login_Handler,
+		},
+		{
+			MethodName: "AddUser",
+			Handler:    _Database_AddUser_Handler,
+		},
+		{
+			MethodName: "RemoveUser",
+			Handler:    _Database_RemoveUser_Handler,
+		},
+		{
+			MethodName: "Users",
+			Handler:    _Database_Users_Handler,
+		},
+		{
+			MethodName: "GrantRole",
+			Handler:    _Database_GrantRole_Handler,
+		},
+		{
+			MethodName: "RevokeRole",
+			Handler:    _Database_RevokeRole_Handler,
+		},
+		{
+			MethodName: "Roles",
+			Handler:    _Database_Roles_Handler,
+		},
+		{
+			MethodName: "CreateNamespace",
+			Handler:    _Database_CreateNamespace_Handler,
+		},
+		{
+			MethodName: "DropNamespace",
+			Handler:    _Database_DropNamespace_Handler,
+		},
+		{
+			MethodName: "ListNamespaces",
+			Handler:    _Database_ListNamespaces_Handler,
+		},
+		{
+			MethodName: "SetLimits",
+			Handler:    _Database_SetLimits_Handler,
+		},
+		{
+			MethodName: "Limits",
+			Handler:    _Database_Limits_Handler,
+		},
+		{
+			MethodName: "SetLogLevel",
+			Handler:    _Database_SetLogLevel_Handler,
+		},
+		{
+			MethodName: "LogLevels",
+			Handler:    _Database_LogLevels_Handler,
+		},
+		{
+			MethodName: "AuditLog",
+			Handler:    _Database_AuditLog_Handler,
+		},
+		{
+			MethodName: "SlowOperations",
+			Handler:    _Database_SlowOperations_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchClusterConfig",
+			Handler:       _Database_WatchClusterConfig_Handler,
+			ServerStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "vdb.proto",
 }
-
-func init() { proto.RegisterFile("vdb.proto", fileDescriptor0) }
-
-var fileDescriptor0 = []byte{
-	// 208 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0xe2, 0xe2, 0x2c, 0x4b, 0x49, 0xd2,
-	0x2b, 0x28, 0xca, 0x2f, 0xc9, 0x17, 0x62, 0x4e, 0x2c, 0xc8, 0x54, 0xe2, 0xe3, 0xe2, 0x71, 0xcd,
-	0x2d, 0x28, 0xa9, 0x0c, 0x4a, 0x2d, 0x2c, 0x4d, 0x2d, 0x2e, 0x51, 0x92, 0xe6, 0xe2, 0xf4, 0x74,
-	0x81, 0x72, 0x84, 0xf8, 0xb8, 0x98, 0x3c, 0x5d, 0x24, 0x18, 0x15, 0x18, 0x35, 0x38, 0x83, 0x98,
-	0x32, 0x5d, 0x94, 0xcc, 0xb8, 0xf8, 0x3c, 0x5d, 0xc2, 0x12, 0x73, 0x4a, 0x53, 0x71, 0xa8, 0x10,
-	0x12, 0xe1, 0x62, 0x2d, 0x03, 0xc9, 0x4b, 0x30, 0x81, 0x85, 0x20, 0x1c, 0x25, 0x05, 0x2e, 0x8e,
-	0xa0, 0xd4, 0xe2, 0x82, 0xfc, 0xbc, 0xe2, 0x54, 0x84, 0x0a, 0x46, 0x24, 0x15, 0x46, 0xdb, 0x18,
-	0xb9, 0x38, 0x5c, 0x12, 0x4b, 0x12, 0x93, 0x12, 0x8b, 0x53, 0x85, 0xb4, 0xb8, 0x58, 0x42, 0x32,
-	0x73, 0x53, 0x85, 0x04, 0xf5, 0x12, 0x0b, 0x32, 0xf5, 0x90, 0x9d, 0x27, 0xc5, 0x0b, 0x16, 0x82,
-	0x19, 0xa6, 0xc4, 0x20, 0xa4, 0xc6, 0xc5, 0xec, 0x9e, 0x5a, 0x22, 0xc4, 0x07, 0x16, 0x87, 0xbb,
-	0x1c, 0x53, 0x9d, 0x36, 0x17, 0x73, 0x70, 0x6a, 0x89, 0x90, 0x30, 0x54, 0x1d, 0xb2, 0x27, 0x30,
-	0x15, 0x6b, 0x72, 0xb1, 0x05, 0xa5, 0xe6, 0xe6, 0x97, 0xa5, 0x12, 0x34, 0x37, 0x89, 0x0d, 0x1c,
-	0x96, 0xc6, 0x80, 0x00, 0x00, 0x00, 0xff, 0xff, 0x05, 0x03, 0x79, 0x46, 0x58, 0x01, 0x00, 0x00,
-}
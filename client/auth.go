@@ -0,0 +1,117 @@
+/******
+This file is part of Vaelen/DB.
+
+Copyright 2017, Andrew Young <andrew@vaelen.org>
+
+    Vaelen/DB is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+    Vaelen/DB is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+    You should have received a copy of the GNU General Public License
+along with Vaelen/DB.  If not, see <http://www.gnu.org/licenses/>.
+******/
+
+package client
+
+import (
+	"context"
+	"encoding/base64"
+	"sync"
+	"time"
+
+	"github.com/vaelen/db/api"
+)
+
+// callCredentials sends the client's password or token with every request.
+// They are sent over plain connections too, so TLS should be used whenever the server requires authentication.
+type callCredentials struct {
+	sync.RWMutex
+	authorization string
+}
+
+// GetRequestMetadata returns the authorization metadata for a request
+func (c *callCredentials) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	c.RLock()
+	defer c.RUnlock()
+	if c.authorization == "" {
+		return nil, nil
+	}
+	return map[string]string{"authorization": c.authorization}, nil
+}
+
+// RequireTransportSecurity returns false so that credentials can be used without TLS
+func (c *callCredentials) RequireTransportSecurity() bool {
+	return false
+}
+
+func (c *callCredentials) set(authorization string) {
+	c.Lock()
+	defer c.Unlock()
+	c.authorization = authorization
+}
+
+// SetPassword makes the client send a username and password with every request
+func (c *DBClient) SetPassword(username string, password string) {
+	c.credentials.set("Basic " + base64.StdEncoding.EncodeToString([]byte(username+":"+password)))
+}
+
+// SetToken makes the client send a token returned by Login with every request
+func (c *DBClient) SetToken(token string) {
+	c.credentials.set("Bearer " + token)
+}
+
+// Login exchanges a username and password for a token, which the client sends with every following request.
+// It returns the token and when it expires, and fetches the cluster configuration again now that the client is authenticated.
+func (c *DBClient) Login(username string, password string) (string, time.Time, error) {
+	response, err := c.client.Login(context.Background(), &api.LoginRequest{Username: username, Password: password})
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	c.SetToken(response.Token)
+	if err := c.Refresh(); err != nil {
+		c.Logger.Warn("Could not fetch cluster configuration", "error", err)
+	}
+	return response.Token, time.Unix(0, response.Expires), nil
+}
+
+// AddUser creates a user or replaces its password and roles
+func (c *DBClient) AddUser(username string, password string, roles ...string) error {
+	_, err := c.client.AddUser(context.Background(), &api.UserRequest{Username: username, Password: password, Roles: roles})
+	return err
+}
+
+// RemoveUser deletes a user
+func (c *DBClient) RemoveUser(username string) error {
+	_, err := c.client.RemoveUser(context.Background(), &api.UserRequest{Username: username})
+	return err
+}
+
+// Users returns every user and their roles
+func (c *DBClient) Users() ([]*api.UserInfo, error) {
+	response, err := c.client.Users(context.Background(), &api.EmptyRequest{})
+	return response.GetUsers(), err
+}
+
+// GrantRole gives a role a permission on every key starting with the given prefix
+func (c *DBClient) GrantRole(role string, prefix string, permission api.Permission) error {
+	_, err := c.client.GrantRole(context.Background(), &api.GrantRequest{Role: role, Prefix: prefix, Permission: permission})
+	return err
+}
+
+// RevokeRole takes away a role's permission on the given prefix
+func (c *DBClient) RevokeRole(role string, prefix string) error {
+	_, err := c.client.RevokeRole(context.Background(), &api.GrantRequest{Role: role, Prefix: prefix})
+	return err
+}
+
+// Roles returns every role and its permissions
+func (c *DBClient) Roles() ([]*api.RoleInfo, error) {
+	response, err := c.client.Roles(context.Background(), &api.EmptyRequest{})
+	return response.GetRoles(), err
+}
@@ -20,45 +20,118 @@ along with Vaelen/DB.  If not, see <http://www.gnu.org/licenses/>.
 package client
 
 import (
-	"io"
-	"log"
+	"crypto/tls"
+	"log/slog"
 
+	"context"
 	"github.com/vaelen/db/api"
+	"github.com/vaelen/db/cluster"
+	"github.com/vaelen/db/logging"
+	"github.com/vaelen/db/trace"
 	"google.golang.org/grpc"
-	"context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/encoding/gzip"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 )
 
+// DefaultCompression is the compressor clients use for requests unless they are told otherwise
+const DefaultCompression = gzip.Name
+
 // DBClient is an instance of the database client
 type DBClient struct {
-	Logger *log.Logger
-	conn   *grpc.ClientConn
-	client api.DatabaseClient
+	Logger *slog.Logger
+	// Consistency is sent with every request to control how many replicas must respond
+	Consistency api.Consistency
+	// Namespace is the namespace of the keys read and written by the client, the default namespace is empty
+	Namespace string
+	// Replicas is the number of replicas the cluster keeps of each key, it limits the shards a stale read can go to
+	Replicas int
+	// TLS secures connections to servers when it is set.  The configuration returned by server.Certificates.ClientTLS picks up renewed certificates.
+	TLS *tls.Config
+	// Compression is the name of the compressor requests are sent with, servers answer with the same one.  Requests are not compressed when it is empty.
+	Compression string
+	// Tracer records a span for every request and sends its span context to the server, so the request can be followed through the cluster.  Nothing is traced when it is nil.
+	Tracer      *trace.Tracer
+	conn        *grpc.ClientConn
+	client      api.DatabaseClient
+	routes      *routes
+	credentials *callCredentials
+}
+
+// Versioned holds every version of a value returned by a replicated server
+type Versioned struct {
+	// Values holds one value per sibling.  There is more than one value when concurrent writes could not be resolved.
+	Values []string
+	// Context should be passed to SetVersioned so that the new value replaces every sibling that was read
+	Context *api.VersionVector
 }
 
 // New creates a new DBClient instance
-func New(logWriter io.Writer) *DBClient {
+func New(logger *slog.Logger) *DBClient {
 	return &DBClient{
-		Logger: log.New(logWriter, "[CLIENT] ", log.LstdFlags),
+		Logger:      logger,
+		Replicas:    cluster.DefaultReplicas,
+		Compression: DefaultCompression,
+		credentials: &callCredentials{},
+		routes: &routes{
+			conns:  make(map[string]*grpc.ClientConn),
+			health: make(map[string]*shardHealth),
+		},
 	}
 }
 
-// Connect to a database server
+// Connect to a database server.
+// If the server is part of a cluster its configuration is fetched and requests are sent straight to the shard that owns each key.
 func (c *DBClient) Connect(address string) error {
-	if c.conn != nil {
-		c.conn.Close()
-		c.conn = nil
-	}
-	conn, err := grpc.Dial(address, grpc.WithInsecure())
+	c.Close()
+	conn, err := grpc.Dial(address, c.dialOptions()...)
 	if err != nil {
 		return err
 	}
 	c.conn = conn
 	c.client = api.NewDatabaseClient(c.conn)
+	err = c.Refresh()
+	if c.Compression != "" && status.Code(err) == codes.Unimplemented {
+		// Servers that can not decompress requests say so before they look at them
+		c.Logger.Warn("Server does not support compression, sending requests uncompressed", "compression", c.Compression, "error", err)
+		c.Compression = ""
+		return c.Connect(address)
+	}
+	if err != nil {
+		c.Logger.Warn("Could not fetch cluster configuration, sending every request to the connected server", "address", address, "error", err)
+	}
 	return nil
 }
 
+// dialOptions returns the options used to connect to servers
+func (c *DBClient) dialOptions() []grpc.DialOption {
+	transport := grpc.WithInsecure()
+	if c.TLS != nil {
+		transport = grpc.WithTransportCredentials(credentials.NewTLS(c.TLS))
+	}
+	options := []grpc.DialOption{transport, grpc.WithPerRPCCredentials(c.credentials), grpc.WithUnaryInterceptor(c.intercept)}
+	if c.Compression != "" {
+		options = append(options, grpc.WithDefaultCallOptions(grpc.UseCompressor(c.Compression)))
+	}
+	return options
+}
+
+// intercept sends a new request ID with each request, so that the server's log lines for it can be found, and records a span for it when tracing is enabled
+func (c *DBClient) intercept(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	ctx = logging.WithRequestID(ctx, logging.NewRequestID())
+	ctx = metadata.AppendToOutgoingContext(ctx, logging.RequestIDHeader, logging.RequestID(ctx))
+	err := c.Tracer.UnaryClientInterceptor(ctx, method, req, reply, cc, invoker, opts...)
+	if err != nil {
+		c.Logger.DebugContext(ctx, "Request failed", "method", method, "address", cc.Target(), "error", err)
+	}
+	return err
+}
+
 // Close disconnects from database server
 func (c *DBClient) Close() {
+	c.closeRoutes()
 	if c.conn != nil {
 		c.conn.Close()
 		c.conn = nil
@@ -67,26 +140,175 @@ func (c *DBClient) Close() {
 
 // Time returns the server's current timestamp
 func (c *DBClient) Time() (string, error) {
-	response, err := c.client.Time(context.Background(), &api.EmptyRequest{})
-	return response.Value, err
+	response, err := c.client.Time(context.Background(), &api.TimeRequest{})
+	return response.GetValue(), err
 }
 
 // Get returns a value from the server
 func (c *DBClient) Get(id string) (string, error) {
-	response, err := c.client.Get(context.Background(), &api.IDRequest{ ID: id })
-	return response.Value, err
+	var response *api.Response
+	err := c.do(id, func(client api.DatabaseClient, epoch uint64) error {
+		var err error
+		response, err = client.Get(context.Background(), &api.IDRequest{ID: id, Namespace: c.Namespace, Consistency: c.Consistency, Epoch: epoch})
+		return err
+	})
+	return response.GetValue(), err
+}
+
+// GetVersioned returns every sibling of a value from the server along with its version context
+func (c *DBClient) GetVersioned(id string) (Versioned, error) {
+	var response *api.Response
+	err := c.do(id, func(client api.DatabaseClient, epoch uint64) error {
+		var err error
+		response, err = client.Get(context.Background(), &api.IDRequest{ID: id, Namespace: c.Namespace, Consistency: c.Consistency, Epoch: epoch})
+		return err
+	})
+	if err != nil {
+		return Versioned{}, err
+	}
+	v := Versioned{
+		Values:  make([]string, 0, len(response.Siblings)),
+		Context: response.Context,
+	}
+	for _, s := range response.Siblings {
+		v.Values = append(v.Values, s.Value)
+	}
+	if len(response.Siblings) == 0 && response.Value != "" {
+		// The server is not replicated and does not track versions
+		v.Values = append(v.Values, response.Value)
+	}
+	return v, nil
 }
 
 // Set sets a value on the server
 func (c *DBClient) Set(id string, value string) error {
-	_, err := c.client.Set(context.Background(), &api.IDValueRequest{ ID: id, Value: value })
-	return err
+	return c.SetVersioned(id, value, nil)
+}
+
+// SetVersioned sets a value on the server, replacing the versions described by the given context
+func (c *DBClient) SetVersioned(id string, value string, version *api.VersionVector) error {
+	return c.do(id, func(client api.DatabaseClient, epoch uint64) error {
+		_, err := client.Set(context.Background(), &api.IDValueRequest{ID: id, Namespace: c.Namespace, Value: value, Consistency: c.Consistency, Context: version, Epoch: epoch})
+		return err
+	})
 }
 
 // Remove removes a value from the database server
 func (c *DBClient) Remove(id string) (string, error) {
-	response, err := c.client.Remove(context.Background(), &api.IDRequest{ ID: id })
-	return response.Value, err
+	var response *api.Response
+	err := c.do(id, func(client api.DatabaseClient, epoch uint64) error {
+		var err error
+		response, err = client.Remove(context.Background(), &api.IDRequest{ID: id, Namespace: c.Namespace, Consistency: c.Consistency, Epoch: epoch})
+		return err
+	})
+	return response.GetValue(), err
 }
 
+// Txn atomically applies the given operations, which may touch keys on any number of shards.
+// It returns the ID of the committed transaction.
+func (c *DBClient) Txn(ops []*api.TxnOp) (string, error) {
+	if len(ops) == 0 {
+		return "", nil
+	}
+	var response *api.TxnResponse
+	err := c.do(ops[0].ID, func(client api.DatabaseClient, epoch uint64) error {
+		var err error
+		response, err = client.Txn(context.Background(), &api.TxnRequest{Ops: ops, Namespace: c.Namespace, Consistency: c.Consistency, Epoch: epoch})
+		return err
+	})
+	return response.GetTxid(), err
+}
+
+// Members returns the cluster membership as seen by the server
+func (c *DBClient) Members() (*api.MembersResponse, error) {
+	return c.client.Members(context.Background(), &api.EmptyRequest{})
+}
+
+// ClusterConfig returns the cluster configuration known to the server
+func (c *DBClient) ClusterConfig() (*api.ClusterConfiguration, error) {
+	return c.client.GetClusterConfig(context.Background(), &api.EmptyRequest{})
+}
+
+// UpdateClusterConfig replaces the cluster configuration if its epoch is still the expected epoch
+func (c *DBClient) UpdateClusterConfig(expected uint64, config *api.ClusterConfiguration) (*api.ClusterConfiguration, error) {
+	return c.client.UpdateClusterConfig(context.Background(), &api.UpdateClusterRequest{ExpectedEpoch: expected, Config: config})
+}
+
+// CreateIndex declares a secondary index over the field at the given path of JSON values on every shard
+func (c *DBClient) CreateIndex(name string, path string) (*api.IndexStatus, error) {
+	return c.client.CreateIndex(context.Background(), &api.IndexDefinition{Name: name, Path: path})
+}
+
+// DropIndex removes a secondary index from every shard
+func (c *DBClient) DropIndex(name string) error {
+	_, err := c.client.DropIndex(context.Background(), &api.IndexDefinition{Name: name})
+	return err
+}
+
+// Indexes returns the secondary indexes of the connected server and how far their builds have progressed
+func (c *DBClient) Indexes() ([]*api.IndexStatus, error) {
+	response, err := c.client.Indexes(context.Background(), &api.EmptyRequest{})
+	return response.GetIndexes(), err
+}
+
+// QueryIndex returns the keys whose indexed field matches the given query, sorted by the value of the field.
+// Only keys in the query's namespace are returned.
+func (c *DBClient) QueryIndex(query *api.QueryIndexRequest) ([]*api.IndexEntry, error) {
+	response, err := c.client.QueryIndex(context.Background(), query)
+	return response.GetEntries(), err
+}
+
+// CreateNamespace creates a namespace with the settings in the given request
+func (c *DBClient) CreateNamespace(request *api.NamespaceRequest) (*api.NamespaceInfo, error) {
+	return c.client.CreateNamespace(context.Background(), request)
+}
 
+// DropNamespace deletes a namespace and every key in it
+func (c *DBClient) DropNamespace(name string) error {
+	_, err := c.client.DropNamespace(context.Background(), &api.NamespaceRequest{Name: name})
+	return err
+}
+
+// ListNamespaces returns every namespace along with how much the connected server stores for it
+func (c *DBClient) ListNamespaces() ([]*api.NamespaceInfo, error) {
+	response, err := c.client.ListNamespaces(context.Background(), &api.EmptyRequest{})
+	return response.GetNamespaces(), err
+}
+
+// SetLimits changes the rate limits of a client or namespace, and the storage quotas of a namespace, on every shard
+func (c *DBClient) SetLimits(request *api.LimitsRequest) (*api.LimitsResponse, error) {
+	return c.client.SetLimits(context.Background(), request)
+}
+
+// SetLogLevel changes the log level of a component of the connected server, or of every component when component is empty
+func (c *DBClient) SetLogLevel(component string, level string) (map[string]string, error) {
+	response, err := c.client.SetLogLevel(context.Background(), &api.LogLevelRequest{Component: component, Level: level})
+	return response.GetLevels(), err
+}
+
+// LogLevels returns the log level of every component of the connected server
+func (c *DBClient) LogLevels() (map[string]string, error) {
+	response, err := c.client.LogLevels(context.Background(), &api.EmptyRequest{})
+	return response.GetLevels(), err
+}
+
+// Limits returns the rate limits and quotas in effect on the cluster
+func (c *DBClient) Limits() (*api.LimitsResponse, error) {
+	return c.client.Limits(context.Background(), &api.EmptyRequest{})
+}
+
+// PlacementLoad reports how evenly a placement spreads a sample of keys over the shards of the cluster
+func (c *DBClient) PlacementLoad(request *api.PlacementLoadRequest) (*api.PlacementLoadResponse, error) {
+	return c.client.PlacementLoad(context.Background(), request)
+}
+
+// AuditLog returns the changes recorded in the audit logs of the cluster that match the given query, oldest first
+func (c *DBClient) AuditLog(query *api.AuditQuery) (*api.AuditResponse, error) {
+	return c.client.AuditLog(context.Background(), query)
+}
+
+// SlowOperations returns the most recent requests recorded in the slow operation logs of the cluster, oldest first.
+// Every recorded request is returned when limit is zero.
+func (c *DBClient) SlowOperations(limit uint32) (*api.SlowResponse, error) {
+	return c.client.SlowOperations(context.Background(), &api.SlowQuery{Limit: limit})
+}
@@ -22,6 +22,7 @@ package client
 import (
 	"os"
 	"github.com/vaelen/db/server"
+	"github.com/vaelen/db/logging"
 	"testing"
 	"fmt"
 	"net"
@@ -30,11 +31,14 @@ import (
 	"github.com/vaelen/db/api"
 )
 
+// testLogger is the logger of the clients used by the tests
+var testLogger = logging.Text(os.Stderr).Logger("client")
+
 func TestDBClient(t *testing.T) {
 	testPort := 30000
 	address := fmt.Sprintf("localhost:%d", testPort)
 
-	s := server.New(os.Stdout, "")
+	s := server.New(logging.Text(os.Stdout), "")
 	defer func() { s.Stop() }()
 
 	lis, err := net.Listen("tcp", address)
@@ -46,7 +50,7 @@ func TestDBClient(t *testing.T) {
 	go grpcServer.Serve(lis)
 	defer func() { grpcServer.Stop() }()
 
-	c := New(os.Stderr)
+	c := New(testLogger)
 	err = c.Connect(address)
 	if err != nil {
 		t.Fatalf("Connect Error: %s\n", err.Error())
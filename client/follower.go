@@ -0,0 +1,137 @@
+/******
+This file is part of Vaelen/DB.
+
+Copyright 2017, Andrew Young <andrew@vaelen.org>
+
+    Vaelen/DB is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+    Vaelen/DB is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+    You should have received a copy of the GNU General Public License
+along with Vaelen/DB.  If not, see <http://www.gnu.org/licenses/>.
+******/
+
+package client
+
+import (
+	"context"
+	"time"
+
+	"github.com/vaelen/db/api"
+	"github.com/vaelen/db/storage"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// unhealthyFor is how long a shard that failed a request is avoided by stale reads
+const unhealthyFor = 10 * time.Second
+
+// ReadOptions control how a read may be served
+type ReadOptions struct {
+	// MaxStaleness lets the nearest healthy replica of the key answer from its own copy if it has synchronized with the
+	// other replicas of the key within this duration.  Zero reads from a quorum through the shard that owns the key.
+	MaxStaleness time.Duration
+}
+
+// milliseconds returns MaxStaleness in the milliseconds the API takes, rounding a positive bound up to one
+func (options ReadOptions) milliseconds() int64 {
+	if options.MaxStaleness <= 0 {
+		return 0
+	}
+	if maxStaleness := int64(options.MaxStaleness / time.Millisecond); maxStaleness > 0 {
+		return maxStaleness
+	}
+	return 1
+}
+
+// shardHealth tracks how quickly a shard answers and when it last failed
+type shardHealth struct {
+	// latency is a moving average of the shard's response time
+	latency time.Duration
+	failed  time.Time
+}
+
+// timed runs a request against the shard at the given address and records how it went
+func (c *DBClient) timed(address string, f func() error) error {
+	start := time.Now()
+	err := f()
+	if address == "" {
+		return err
+	}
+	elapsed := time.Since(start)
+	c.routes.Lock()
+	defer c.routes.Unlock()
+	h, ok := c.routes.health[address]
+	if !ok {
+		h = &shardHealth{latency: elapsed}
+		c.routes.health[address] = h
+	}
+	if status.Code(err) == codes.Unavailable || status.Code(err) == codes.DeadlineExceeded {
+		h.failed = time.Now()
+		return err
+	}
+	h.latency = (h.latency*3 + elapsed) / 4
+	return err
+}
+
+// nearest returns the client for the replica of the given key with the lowest latency that has not failed recently.
+// Replicas that have not been measured yet are tried first so that every replica is measured.
+func (c *DBClient) nearest(key string) (api.DatabaseClient, string, uint64) {
+	c.routes.Lock()
+	defer c.routes.Unlock()
+	if c.routes.placement == nil {
+		return c.client, "", 0
+	}
+	replicas := c.routes.placement.Replicas(storage.NamespaceKey(c.Namespace, key), c.Replicas)
+	if len(replicas) == 0 {
+		return c.client, "", 0
+	}
+	best := replicas[0].Address
+	var bestLatency time.Duration = -1
+	for _, replica := range replicas {
+		h, ok := c.routes.health[replica.Address]
+		if !ok {
+			best = replica.Address
+			break
+		}
+		if time.Since(h.failed) < unhealthyFor {
+			continue
+		}
+		if bestLatency < 0 || h.latency < bestLatency {
+			best, bestLatency = replica.Address, h.latency
+		}
+	}
+	return c.connect(best)
+}
+
+// GetWithOptions returns a value from the server using the given read options.
+// If the nearest replica cannot answer the read is sent to the shard that owns the key instead.
+func (c *DBClient) GetWithOptions(id string, options ReadOptions) (string, error) {
+	if options.MaxStaleness <= 0 {
+		return c.Get(id)
+	}
+	maxStaleness := options.milliseconds()
+	client, address, epoch := c.nearest(id)
+	var response *api.Response
+	err := c.timed(address, func() error {
+		var err error
+		response, err = client.Get(context.Background(), &api.IDRequest{ID: id, Namespace: c.Namespace, Consistency: c.Consistency, Epoch: epoch, MaxStaleness: maxStaleness})
+		return err
+	})
+	if err != nil && address != "" {
+		return c.Get(id)
+	}
+	return response.GetValue(), err
+}
+
+// GetManyWithOptions returns the values of the given keys using the given read options.
+// Each key is read from the shard that owns it, which answers from its own copy if it is within MaxStaleness.
+func (c *DBClient) GetManyWithOptions(ids []string, options ReadOptions) (map[string]string, error) {
+	return c.getMany(ids, options.milliseconds())
+}
@@ -0,0 +1,91 @@
+/******
+This file is part of Vaelen/DB.
+
+Copyright 2017, Andrew Young <andrew@vaelen.org>
+
+    Vaelen/DB is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+    Vaelen/DB is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+    You should have received a copy of the GNU General Public License
+along with Vaelen/DB.  If not, see <http://www.gnu.org/licenses/>.
+******/
+
+package client
+
+import (
+	"testing"
+	"time"
+
+	"github.com/vaelen/db/api"
+)
+
+// TestFollowerRead tests that stale reads are answered by a replica once it has synchronized with the cluster
+func TestFollowerRead(t *testing.T) {
+	counter := newRequestCounter()
+	_, servers, stop := startServers(t, 30240, 3, counter)
+	defer stop()
+
+	c := New(testLogger)
+	defer c.Close()
+	c.Consistency = api.Consistency_ALL
+	if err := c.Connect(servers[0].Self.Address); err != nil {
+		t.Fatalf("Connect Error: %s\n", err.Error())
+	}
+	if err := c.Set("foo", "bar"); err != nil {
+		t.Fatalf("Set Error: %s\n", err.Error())
+	}
+
+	followerReads := func() uint64 {
+		total := uint64(0)
+		for _, s := range servers {
+			total += s.FollowerReads()
+		}
+		return total
+	}
+
+	// No replica has synchronized yet so the read goes to a quorum
+	value, err := c.GetWithOptions("foo", ReadOptions{MaxStaleness: time.Minute})
+	if err != nil || value != "bar" {
+		t.Fatalf("GetWithOptions - Value: %s, Error: %v\n", value, err)
+	}
+	if followerReads() != 0 {
+		t.Fatalf("Replica answered before it synchronized\n")
+	}
+
+	for _, s := range servers {
+		s.AntiEntropy()
+	}
+	for i := 0; i < 5; i++ {
+		value, err = c.GetWithOptions("foo", ReadOptions{MaxStaleness: time.Minute})
+		if err != nil || value != "bar" {
+			t.Fatalf("GetWithOptions - Value: %s, Error: %v\n", value, err)
+		}
+	}
+	if followerReads() != 5 {
+		t.Fatalf("Follower Reads: %d, Expected: 5\n", followerReads())
+	}
+	values, err := c.GetManyWithOptions([]string{"foo"}, ReadOptions{MaxStaleness: time.Minute})
+	if err != nil || values["foo"] != "bar" {
+		t.Fatalf("GetManyWithOptions - Values: %v, Error: %v\n", values, err)
+	}
+	if followerReads() != 6 {
+		t.Fatalf("Follower Reads: %d, Expected: 6\n", followerReads())
+	}
+
+	// A bound tighter than the time since the last synchronization requires a quorum read
+	time.Sleep(20 * time.Millisecond)
+	value, err = c.GetWithOptions("foo", ReadOptions{MaxStaleness: time.Millisecond})
+	if err != nil || value != "bar" {
+		t.Fatalf("GetWithOptions - Value: %s, Error: %v\n", value, err)
+	}
+	if followerReads() != 6 {
+		t.Fatalf("Replica answered outside the staleness bound\n")
+	}
+}
@@ -0,0 +1,247 @@
+/******
+This file is part of Vaelen/DB.
+
+Copyright 2017, Andrew Young <andrew@vaelen.org>
+
+    Vaelen/DB is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+    Vaelen/DB is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+    You should have received a copy of the GNU General Public License
+along with Vaelen/DB.  If not, see <http://www.gnu.org/licenses/>.
+******/
+
+package client
+
+import (
+	"context"
+	"sync"
+
+	"github.com/vaelen/db/api"
+	"github.com/vaelen/db/cluster"
+	"github.com/vaelen/db/storage"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// routes holds the cluster configuration used to send requests straight to the shard that owns a key
+type routes struct {
+	sync.RWMutex
+	cluster   *cluster.Config
+	placement cluster.Placement
+	// conns holds one connection per shard, by address
+	conns map[string]*grpc.ClientConn
+	// health holds the observed latency and failures of each shard, by address
+	health map[string]*shardHealth
+}
+
+// Refresh fetches the cluster configuration from the connected server.
+// Requests are sent to the connected server when it is not part of a cluster.
+func (c *DBClient) Refresh() error {
+	response, err := c.client.GetClusterConfig(context.Background(), &api.EmptyRequest{})
+	if err != nil {
+		return err
+	}
+	config, err := cluster.FromConfiguration(response)
+	if err != nil {
+		return err
+	}
+	var placement cluster.Placement
+	if len(config.Shards) > 0 {
+		placement, err = cluster.NewPlacement(config)
+		if err != nil {
+			return err
+		}
+	}
+	c.routes.Lock()
+	defer c.routes.Unlock()
+	if c.routes.cluster != nil && config.Epoch < c.routes.cluster.Epoch {
+		// Another refresh already found a newer configuration
+		return nil
+	}
+	c.routes.cluster = config
+	c.routes.placement = placement
+	return nil
+}
+
+// Epoch returns the epoch of the cluster configuration the client is routing with
+func (c *DBClient) Epoch() uint64 {
+	c.routes.RLock()
+	defer c.routes.RUnlock()
+	if c.routes.cluster == nil {
+		return 0
+	}
+	return c.routes.cluster.Epoch
+}
+
+// Owner returns the address of the shard requests for the given key are sent to, or an empty string if they go to the connected server
+func (c *DBClient) Owner(key string) string {
+	c.routes.RLock()
+	defer c.routes.RUnlock()
+	if c.routes.placement == nil {
+		return ""
+	}
+	replicas := c.routes.placement.Replicas(storage.NamespaceKey(c.Namespace, key), 1)
+	if len(replicas) == 0 {
+		return ""
+	}
+	return replicas[0].Address
+}
+
+// route returns the client for the shard that owns the given key, its address and the epoch of the configuration used to choose it.
+// The address is empty when the request goes to the connected server.
+func (c *DBClient) route(key string) (api.DatabaseClient, string, uint64) {
+	c.routes.Lock()
+	defer c.routes.Unlock()
+	if c.routes.placement == nil {
+		return c.client, "", 0
+	}
+	replicas := c.routes.placement.Replicas(storage.NamespaceKey(c.Namespace, key), 1)
+	if len(replicas) == 0 {
+		return c.client, "", 0
+	}
+	return c.connect(replicas[0].Address)
+}
+
+// connect returns a client for the shard at the given address.  The caller must hold the routes lock.
+func (c *DBClient) connect(address string) (api.DatabaseClient, string, uint64) {
+	conn, ok := c.routes.conns[address]
+	if !ok {
+		var err error
+		conn, err = grpc.Dial(address, c.dialOptions()...)
+		if err != nil {
+			c.Logger.Warn("Could not connect to shard, using the connected server", "address", address, "error", err)
+			return c.client, "", 0
+		}
+		c.routes.conns[address] = conn
+	}
+	return api.NewDatabaseClient(conn), address, c.routes.cluster.Epoch
+}
+
+// closeRoutes closes every shard connection and forgets the cluster configuration
+func (c *DBClient) closeRoutes() {
+	c.routes.Lock()
+	defer c.routes.Unlock()
+	for _, conn := range c.routes.conns {
+		conn.Close()
+	}
+	c.routes.conns = make(map[string]*grpc.ClientConn)
+	c.routes.health = make(map[string]*shardHealth)
+	c.routes.cluster = nil
+	c.routes.placement = nil
+}
+
+// do sends a request for the given key to the shard that owns it.
+// If the shard says the client's configuration is stale the configuration is refreshed and the request is sent again.
+func (c *DBClient) do(key string, f func(client api.DatabaseClient, epoch uint64) error) error {
+	client, address, epoch := c.route(key)
+	err := c.timed(address, func() error { return f(client, epoch) })
+	if epoch != 0 && status.Code(err) == codes.FailedPrecondition {
+		if refreshErr := c.Refresh(); refreshErr != nil {
+			return err
+		}
+		client, address, epoch = c.route(key)
+		err = c.timed(address, func() error { return f(client, epoch) })
+	}
+	return err
+}
+
+// shardBatch holds the keys of a batch that are sent to one shard
+type shardBatch struct {
+	client  api.DatabaseClient
+	address string
+	epoch   uint64
+	keys    []string
+}
+
+// batch groups the given keys by the shard that owns them and calls f once for each shard with its keys, running the shards in parallel.
+// If a shard says the client's configuration is stale the configuration is refreshed and the whole batch is sent again.
+// It returns the first error encountered.
+func (c *DBClient) batch(keys []string, f func(client api.DatabaseClient, epoch uint64, keys []string) error) error {
+	err := c.sendBatch(keys, f)
+	if c.Epoch() != 0 && status.Code(err) == codes.FailedPrecondition {
+		if refreshErr := c.Refresh(); refreshErr != nil {
+			return err
+		}
+		err = c.sendBatch(keys, f)
+	}
+	return err
+}
+
+// sendBatch sends each shard the given keys it owns
+func (c *DBClient) sendBatch(keys []string, f func(client api.DatabaseClient, epoch uint64, keys []string) error) error {
+	batches := make(map[string]*shardBatch)
+	for _, key := range keys {
+		client, address, epoch := c.route(key)
+		b, ok := batches[address]
+		if !ok {
+			b = &shardBatch{client: client, address: address, epoch: epoch}
+			batches[address] = b
+		}
+		b.keys = append(b.keys, key)
+	}
+	var wg sync.WaitGroup
+	errors := make(chan error, len(batches))
+	for _, b := range batches {
+		wg.Add(1)
+		go func(b *shardBatch) {
+			defer wg.Done()
+			if err := c.timed(b.address, func() error { return f(b.client, b.epoch, b.keys) }); err != nil {
+				errors <- err
+			}
+		}(b)
+	}
+	wg.Wait()
+	close(errors)
+	return <-errors
+}
+
+// GetMany returns the values of the given keys, sending one request to each shard in parallel
+func (c *DBClient) GetMany(ids []string) (map[string]string, error) {
+	return c.getMany(ids, 0)
+}
+
+// getMany returns the values of the given keys, letting each shard answer from its own copy within maxStaleness milliseconds
+func (c *DBClient) getMany(ids []string, maxStaleness int64) (map[string]string, error) {
+	var lock sync.Mutex
+	values := make(map[string]string)
+	err := c.batch(ids, func(client api.DatabaseClient, epoch uint64, keys []string) error {
+		request := &api.GetManyRequest{IDs: keys, Namespace: c.Namespace, Consistency: c.Consistency, Epoch: epoch, MaxStaleness: maxStaleness}
+		response, err := client.GetMany(context.Background(), request)
+		if err != nil {
+			return err
+		}
+		lock.Lock()
+		defer lock.Unlock()
+		for i, r := range response.Responses {
+			if i < len(keys) {
+				values[keys[i]] = r.GetValue()
+			}
+		}
+		return nil
+	})
+	return values, err
+}
+
+// SetMany sets the given values, sending one request to each shard in parallel
+func (c *DBClient) SetMany(values map[string]string) error {
+	ids := make([]string, 0, len(values))
+	for id := range values {
+		ids = append(ids, id)
+	}
+	return c.batch(ids, func(client api.DatabaseClient, epoch uint64, keys []string) error {
+		batch := make(map[string]string, len(keys))
+		for _, key := range keys {
+			batch[key] = values[key]
+		}
+		_, err := client.SetMany(context.Background(), &api.SetManyRequest{Values: batch, Namespace: c.Namespace, Consistency: c.Consistency, Epoch: epoch})
+		return err
+	})
+}
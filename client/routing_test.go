@@ -0,0 +1,179 @@
+/******
+This file is part of Vaelen/DB.
+
+Copyright 2017, Andrew Young <andrew@vaelen.org>
+
+    Vaelen/DB is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+    Vaelen/DB is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+    You should have received a copy of the GNU General Public License
+along with Vaelen/DB.  If not, see <http://www.gnu.org/licenses/>.
+******/
+
+package client
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/satori/go.uuid"
+	"github.com/vaelen/db/api"
+	"github.com/vaelen/db/cluster"
+	"github.com/vaelen/db/logging"
+	"github.com/vaelen/db/server"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+)
+
+// requestCounter counts the keys clients write to each server and the batches they are sent in
+type requestCounter struct {
+	sync.Mutex
+	counts  map[string]int
+	batches map[string]int
+}
+
+func newRequestCounter() *requestCounter {
+	return &requestCounter{counts: make(map[string]int), batches: make(map[string]int)}
+}
+
+func (r *requestCounter) interceptor(address string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		r.Lock()
+		switch request := req.(type) {
+		case *api.IDValueRequest:
+			r.counts[address]++
+		case *api.SetManyRequest:
+			r.counts[address] += len(request.Values)
+			r.batches[address]++
+		}
+		r.Unlock()
+		return handler(ctx, req)
+	}
+}
+
+// startServers starts a cluster of servers listening on consecutive ports, counting the client requests they receive
+func startServers(t *testing.T, basePort int, count int, counter *requestCounter) (*cluster.Config, []*server.DBServer, func()) {
+	config := &cluster.Config{Size: cluster.SmallCluster}
+	for i := 0; i < count; i++ {
+		id, _ := uuid.FromString(fmt.Sprintf("00000000-0000-0000-0000-%012d", i+1))
+		config.Shards = append(config.Shards, cluster.Shard{ID: id, Address: fmt.Sprintf("localhost:%d", basePort+i)})
+	}
+	servers := make([]*server.DBServer, 0)
+	stops := make([]func(), 0)
+	for _, shard := range config.Shards {
+		s := server.New(logging.Text(os.Stderr), "")
+		s.Self = shard
+		s.Cluster = config
+		lis, err := net.Listen("tcp", shard.Address)
+		if err != nil {
+			t.Fatalf("failed to listen: %v", err)
+		}
+		grpcServer := grpc.NewServer(grpc.UnaryInterceptor(counter.interceptor(shard.Address)))
+		api.RegisterDatabaseServer(grpcServer, s)
+		go grpcServer.Serve(lis)
+		stops = append(stops, func() {
+			grpcServer.Stop()
+			s.Stop()
+		})
+		servers = append(servers, s)
+	}
+	return config, servers, func() {
+		for _, stop := range stops {
+			stop()
+		}
+	}
+}
+
+// TestRouting tests that requests go straight to the owning shard and that a stale configuration is refreshed
+func TestRouting(t *testing.T) {
+	counter := newRequestCounter()
+	config, servers, stop := startServers(t, 30190, 3, counter)
+	defer stop()
+
+	c := New(testLogger)
+	defer c.Close()
+	if err := c.Connect(config.Shards[0].Address); err != nil {
+		t.Fatalf("Connect Error: %s\n", err.Error())
+	}
+	if _, err := c.UpdateClusterConfig(0, cluster.ToConfiguration(config)); err != nil {
+		t.Fatalf("UpdateClusterConfig Error: %s\n", err.Error())
+	}
+	if err := c.Refresh(); err != nil {
+		t.Fatalf("Refresh Error: %s\n", err.Error())
+	}
+	if c.Epoch() != 1 {
+		t.Fatalf("Epoch: %d, Expected: 1\n", c.Epoch())
+	}
+
+	values := make(map[string]string)
+	expected := make(map[string]int)
+	for i := 0; i < 30; i++ {
+		key := fmt.Sprintf("key-%d", i)
+		values[key] = fmt.Sprintf("value-%d", i)
+		expected[c.Owner(key)]++
+	}
+	if err := c.SetMany(values); err != nil {
+		t.Fatalf("SetMany Error: %s\n", err.Error())
+	}
+	counter.Lock()
+	for address, count := range expected {
+		if counter.counts[address] != count || counter.batches[address] != 1 {
+			t.Errorf("Address: %s, Keys: %d, Batches: %d, Expected: %d keys in 1 batch\n", address, counter.counts[address], counter.batches[address], count)
+		}
+	}
+	counter.Unlock()
+
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	read, err := c.GetMany(keys)
+	if err != nil {
+		t.Fatalf("GetMany Error: %s\n", err.Error())
+	}
+	for key, value := range values {
+		if read[key] != value {
+			t.Errorf("Key: %s, Value: %s, Expected: %s\n", key, read[key], value)
+		}
+	}
+
+	// Wait for the bootstrapped configuration to reach every shard
+	for _, s := range servers {
+		for i := 0; i < 500; i++ {
+			if config, _ := s.GetClusterConfig(context.Background(), &api.EmptyRequest{}); config.Epoch == 1 {
+				break
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	// Move the cluster to ring placement behind the client's back
+	ring := *config
+	ring.Strategy = cluster.RingStrategy
+	for _, s := range servers {
+		if _, err := s.CompareAndSetClusterConfig(1, &ring); err != nil {
+			t.Fatalf("CompareAndSetClusterConfig Error: %s\n", err.Error())
+		}
+	}
+	if err := c.Set("foo", "bar"); err != nil {
+		t.Fatalf("Set Error: %s\n", err.Error())
+	}
+	if c.Epoch() != 2 {
+		t.Fatalf("Client did not refresh its configuration. Epoch: %d\n", c.Epoch())
+	}
+	if value, err := c.Get("foo"); err != nil || value != "bar" {
+		t.Fatalf("Get - Value: %s, Error: %v\n", value, err)
+	}
+}
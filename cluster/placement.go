@@ -0,0 +1,307 @@
+/******
+This file is part of Vaelen/DB.
+
+Copyright 2017, Andrew Young <andrew@vaelen.org>
+
+    Vaelen/DB is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+    Vaelen/DB is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+    You should have received a copy of the GNU General Public License
+along with Vaelen/DB.  If not, see <http://www.gnu.org/licenses/>.
+******/
+
+package cluster
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/satori/go.uuid"
+)
+
+const (
+	// ChunkStrategy places keys by splitting the hash space into a fixed number of chunks
+	ChunkStrategy = "chunk"
+	// RingStrategy places keys on a consistent hash ring with virtual nodes
+	RingStrategy = "ring"
+)
+
+// DefaultVirtualNodes is the number of points each unit of shard weight gets on the hash ring
+const DefaultVirtualNodes = 64
+
+const (
+	// MaxVirtualNodes is the largest number of ring points per unit of shard weight a placement accepts
+	MaxVirtualNodes = 1024
+	// MaxWeight is the largest shard weight a placement accepts
+	MaxWeight = 100
+)
+
+// maxComparedChunks is the number of chunks Gained compares.
+// Chunk placements repeat every len(shards) chunks, so larger clusters only compare their first chunks.
+const maxComparedChunks = 1 << 16
+
+// Placement decides which shards are responsible for a key
+type Placement interface {
+	// Strategy returns the name of the placement strategy
+	Strategy() string
+	// Replicas returns up to n distinct shards responsible for the key, in order of preference
+	Replicas(key string, n int) []Shard
+}
+
+// NewPlacement builds the placement described by the given cluster configuration
+func NewPlacement(config *Config) (Placement, error) {
+	for _, shard := range config.Shards {
+		if shard.Weight > MaxWeight {
+			return nil, fmt.Errorf("shard %s has weight %d, the largest weight is %d", shard.ID, shard.Weight, MaxWeight)
+		}
+	}
+	switch config.Strategy {
+	case "", ChunkStrategy:
+		if config.Size < SmallCluster || config.Size > HugeCluster {
+			return nil, fmt.Errorf("invalid cluster size: %d", config.Size)
+		}
+		return NewChunkPlacement(config), nil
+	case RingStrategy:
+		if config.VirtualNodes > MaxVirtualNodes {
+			return nil, fmt.Errorf("%d virtual nodes per unit of weight, the most is %d", config.VirtualNodes, MaxVirtualNodes)
+		}
+		return NewRingPlacement(config.Shards, config.VirtualNodes), nil
+	}
+	return nil, fmt.Errorf("unknown placement strategy: %s", config.Strategy)
+}
+
+// ChunkPlacement assigns the chunks returned by Config.Chunk to shards round robin.
+// The number of chunks only changes when the cluster size does, so growth happens in 256x steps.
+type ChunkPlacement struct {
+	config *Config
+}
+
+// NewChunkPlacement builds a chunk placement for the given cluster configuration
+func NewChunkPlacement(config *Config) *ChunkPlacement {
+	return &ChunkPlacement{config: config}
+}
+
+// Strategy returns the name of the placement strategy
+func (p *ChunkPlacement) Strategy() string {
+	return ChunkStrategy
+}
+
+// Replicas returns the shard owning the key's chunk followed by the next shards in the shard list
+func (p *ChunkPlacement) Replicas(key string, n int) []Shard {
+	return p.replicasOf(Hash(key), n)
+}
+
+// replicasOf returns the replicas of the keys with the given hash
+func (p *ChunkPlacement) replicasOf(h uint32, n int) []Shard {
+	shards := p.config.Shards
+	if n > len(shards) {
+		n = len(shards)
+	}
+	replicas := make([]Shard, 0, n)
+	if n < 1 {
+		return replicas
+	}
+	start := int(p.config.chunkOf(h) % uint32(len(shards)))
+	for i := 0; i < n; i++ {
+		replicas = append(replicas, shards[(start+i)%len(shards)])
+	}
+	return replicas
+}
+
+// boundaries returns the first hash of each chunk, up to maxComparedChunks of them
+func (p *ChunkPlacement) boundaries() []uint32 {
+	bits := uint(p.config.Size) * 8
+	count := uint64(1) << bits
+	if count > maxComparedChunks {
+		count = maxComparedChunks
+	}
+	hashes := make([]uint32, 0, count)
+	for chunk := uint64(0); chunk < count; chunk++ {
+		hashes = append(hashes, uint32(chunk<<(32-bits)))
+	}
+	return hashes
+}
+
+// ringPoint is a single virtual node on the hash ring
+type ringPoint struct {
+	hash  uint32
+	shard int
+}
+
+// RingPlacement places keys on a consistent hash ring.
+// Each shard gets VirtualNodes points on the ring for every unit of weight, so adding a shard only moves the keys it takes over.
+type RingPlacement struct {
+	shards []Shard
+	points []ringPoint
+}
+
+// NewRingPlacement builds a hash ring for the given shards
+func NewRingPlacement(shards []Shard, virtualNodes int) *RingPlacement {
+	if virtualNodes < 1 {
+		virtualNodes = DefaultVirtualNodes
+	}
+	p := &RingPlacement{
+		shards: shards,
+		points: make([]ringPoint, 0),
+	}
+	for i, shard := range shards {
+		for v := 0; v < virtualNodes*shard.EffectiveWeight(); v++ {
+			p.points = append(p.points, ringPoint{
+				hash:  mix(Hash(fmt.Sprintf("%s#%d", shard.ID, v))),
+				shard: i,
+			})
+		}
+	}
+	sort.Slice(p.points, func(i, j int) bool {
+		return p.points[i].hash < p.points[j].hash
+	})
+	return p
+}
+
+// Strategy returns the name of the placement strategy
+func (p *RingPlacement) Strategy() string {
+	return RingStrategy
+}
+
+// Replicas walks the ring clockwise from the key's position and returns the first n distinct shards
+func (p *RingPlacement) Replicas(key string, n int) []Shard {
+	return p.replicasOf(mix(Hash(key)), n)
+}
+
+// replicasOf returns the replicas of the keys at the given position on the ring
+func (p *RingPlacement) replicasOf(h uint32, n int) []Shard {
+	if n > len(p.shards) {
+		n = len(p.shards)
+	}
+	replicas := make([]Shard, 0, n)
+	if n < 1 || len(p.points) == 0 {
+		return replicas
+	}
+	start := sort.Search(len(p.points), func(i int) bool {
+		return p.points[i].hash >= h
+	})
+	seen := make(map[int]bool)
+	for i := 0; i < len(p.points) && len(replicas) < n; i++ {
+		point := p.points[(start+i)%len(p.points)]
+		if !seen[point.shard] {
+			seen[point.shard] = true
+			replicas = append(replicas, p.shards[point.shard])
+		}
+	}
+	return replicas
+}
+
+// boundaries returns the position of every point on the ring.
+// Each point owns the keys between the previous point and itself.
+func (p *RingPlacement) boundaries() []uint32 {
+	hashes := make([]uint32, 0, len(p.points))
+	for _, point := range p.points {
+		hashes = append(hashes, point.hash)
+	}
+	return hashes
+}
+
+// Gained returns true if the shard with the given ID is one of the first n replicas of keys under placement p
+// that it was not a replica of under the old placement. Every range of keys that either placement places as a whole is compared.
+// Placements with different strategies hash keys differently, so the shard is assumed to gain keys when the strategy changes.
+func Gained(old Placement, p Placement, id uuid.UUID, n int) bool {
+	switch o := old.(type) {
+	case *ChunkPlacement:
+		if c, ok := p.(*ChunkPlacement); ok {
+			return gained(o.replicasOf, c.replicasOf, append(o.boundaries(), c.boundaries()...), id, n)
+		}
+	case *RingPlacement:
+		if r, ok := p.(*RingPlacement); ok {
+			return gained(o.replicasOf, r.replicasOf, append(o.boundaries(), r.boundaries()...), id, n)
+		}
+	}
+	return true
+}
+
+// gained compares the replicas of the ranges starting or ending at each of the given hashes
+func gained(old, replicas func(uint32, int) []Shard, hashes []uint32, id uuid.UUID, n int) bool {
+	for _, h := range hashes {
+		if holds(replicas(h, n), id) && !holds(old(h, n), id) {
+			return true
+		}
+	}
+	return false
+}
+
+// holds returns true if the shard with the given ID is in the list
+func holds(shards []Shard, id uuid.UUID) bool {
+	for _, shard := range shards {
+		if uuid.Equal(shard.ID, id) {
+			return true
+		}
+	}
+	return false
+}
+
+// mix spreads the bits of an FNV hash so that similar inputs land far apart on the ring
+func mix(h uint32) uint32 {
+	h ^= h >> 16
+	h *= 0x85ebca6b
+	h ^= h >> 13
+	h *= 0xc2b2ae35
+	h ^= h >> 16
+	return h
+}
+
+// LoadReport describes how evenly a placement spreads a sample of keys
+type LoadReport struct {
+	// Keys is the number of keys in the sample
+	Keys int
+	// Counts is the number of keys owned by each shard, by shard ID
+	Counts map[string]int
+	// Skew is the load of the busiest shard relative to its fair share, taking weights into account.
+	// A perfectly balanced placement has a skew of 1.
+	Skew float64
+	// StdDev is the standard deviation of each shard's load relative to its fair share
+	StdDev float64
+}
+
+// MeasureLoad reports how the given keys are spread over the shards by their primary replica
+func MeasureLoad(p Placement, shards []Shard, keys []string) LoadReport {
+	report := LoadReport{
+		Keys:   len(keys),
+		Counts: make(map[string]int),
+	}
+	if len(shards) == 0 || len(keys) == 0 {
+		return report
+	}
+	for _, shard := range shards {
+		report.Counts[shard.ID.String()] = 0
+	}
+	for _, key := range keys {
+		if replicas := p.Replicas(key, 1); len(replicas) > 0 {
+			report.Counts[replicas[0].ID.String()]++
+		}
+	}
+
+	totalWeight := 0
+	for _, shard := range shards {
+		totalWeight += shard.EffectiveWeight()
+	}
+	sum, sumSquares := 0.0, 0.0
+	for _, shard := range shards {
+		fair := float64(len(keys)) * float64(shard.EffectiveWeight()) / float64(totalWeight)
+		load := float64(report.Counts[shard.ID.String()]) / fair
+		if load > report.Skew {
+			report.Skew = load
+		}
+		sum += load
+		sumSquares += load * load
+	}
+	mean := sum / float64(len(shards))
+	report.StdDev = math.Sqrt(sumSquares/float64(len(shards)) - mean*mean)
+	return report
+}
@@ -0,0 +1,224 @@
+/******
+This file is part of Vaelen/DB.
+
+Copyright 2017, Andrew Young <andrew@vaelen.org>
+
+    Vaelen/DB is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+    Vaelen/DB is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+    You should have received a copy of the GNU General Public License
+along with Vaelen/DB.  If not, see <http://www.gnu.org/licenses/>.
+******/
+
+package cluster
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/satori/go.uuid"
+	"github.com/vaelen/db/api"
+)
+
+// testConfig returns a configuration with the given number of shards
+func testConfig(count int) *Config {
+	config := &Config{Size: SmallCluster}
+	for i := 0; i < count; i++ {
+		id, _ := uuid.FromString(fmt.Sprintf("00000000-0000-0000-0000-%012d", i+1))
+		config.Shards = append(config.Shards, Shard{
+			ID:      id,
+			Address: fmt.Sprintf("localhost:%d", i),
+		})
+	}
+	return config
+}
+
+// sampleKeys returns the given number of distinct keys
+func sampleKeys(count int) []string {
+	keys := make([]string, 0, count)
+	for i := 0; i < count; i++ {
+		keys = append(keys, fmt.Sprintf("key-%d", i))
+	}
+	return keys
+}
+
+// TestChunk tests that chunks are taken from the top of the hash
+func TestChunk(t *testing.T) {
+	key := "foo"
+	h := Hash(key)
+	for size, expected := range map[ClusterSize]uint32{
+		SmallCluster:  h >> 24,
+		MediumCluster: h >> 16,
+		LargeCluster:  h >> 8,
+		HugeCluster:   h,
+	} {
+		config := &Config{Size: size}
+		if chunk := config.Chunk(key); chunk != expected {
+			t.Errorf("Size: %d, Chunk: %d, Expected: %d\n", size, chunk, expected)
+		}
+	}
+}
+
+// TestRingPlacement tests that the ring returns distinct replicas and spreads keys evenly
+func TestRingPlacement(t *testing.T) {
+	config := testConfig(5)
+	config.Strategy = RingStrategy
+	p, err := NewPlacement(config)
+	if err != nil {
+		t.Fatalf("NewPlacement Error: %s\n", err.Error())
+	}
+	if p.Strategy() != RingStrategy {
+		t.Fatalf("Strategy: %s, Expected: %s\n", p.Strategy(), RingStrategy)
+	}
+
+	keys := sampleKeys(10000)
+	for _, key := range keys[:100] {
+		replicas := p.Replicas(key, 3)
+		if len(replicas) != 3 {
+			t.Fatalf("Key: %s, Replicas: %d, Expected: 3\n", key, len(replicas))
+		}
+		seen := make(map[string]bool)
+		for _, r := range replicas {
+			if seen[r.ID.String()] {
+				t.Fatalf("Key: %s, Duplicate replica: %s\n", key, r.ID)
+			}
+			seen[r.ID.String()] = true
+		}
+	}
+
+	report := MeasureLoad(p, config.Shards, keys)
+	if report.Skew > 1.3 {
+		t.Errorf("Ring skew too high: %f, Counts: %v\n", report.Skew, report.Counts)
+	}
+	t.Logf("Ring Skew: %f, StdDev: %f\n", report.Skew, report.StdDev)
+}
+
+// TestRingWeights tests that a shard with twice the weight receives about twice the keys
+func TestRingWeights(t *testing.T) {
+	config := testConfig(3)
+	config.Shards[0].Weight = 2
+	p := NewRingPlacement(config.Shards, DefaultVirtualNodes)
+	report := MeasureLoad(p, config.Shards, sampleKeys(10000))
+	heavy := report.Counts[config.Shards[0].ID.String()]
+	light := report.Counts[config.Shards[1].ID.String()]
+	ratio := float64(heavy) / float64(light)
+	if ratio < 1.5 || ratio > 2.5 {
+		t.Errorf("Weighted shard ratio: %f, Counts: %v\n", ratio, report.Counts)
+	}
+}
+
+// TestRingGrowth tests that adding a shard to the ring only moves the keys it takes over
+func TestRingGrowth(t *testing.T) {
+	before := testConfig(4)
+	after := testConfig(5)
+	added := after.Shards[4].ID
+	oldRing := NewRingPlacement(before.Shards, DefaultVirtualNodes)
+	newRing := NewRingPlacement(after.Shards, DefaultVirtualNodes)
+	oldChunks := NewChunkPlacement(before)
+	newChunks := NewChunkPlacement(after)
+
+	keys := sampleKeys(10000)
+	ringMoved, chunkMoved := 0, 0
+	for _, key := range keys {
+		from := oldRing.Replicas(key, 1)[0]
+		to := newRing.Replicas(key, 1)[0]
+		if !uuid.Equal(from.ID, to.ID) {
+			ringMoved++
+			if !uuid.Equal(to.ID, added) {
+				t.Fatalf("Key %s moved from %s to %s instead of the new shard\n", key, from.ID, to.ID)
+			}
+		}
+		if !uuid.Equal(oldChunks.Replicas(key, 1)[0].ID, newChunks.Replicas(key, 1)[0].ID) {
+			chunkMoved++
+		}
+	}
+	if ringMoved > len(keys)*3/10 {
+		t.Errorf("Too many keys moved on the ring: %d of %d\n", ringMoved, len(keys))
+	}
+	if ringMoved >= chunkMoved {
+		t.Errorf("Ring moved %d keys, chunk placement moved %d\n", ringMoved, chunkMoved)
+	}
+}
+
+// TestGained tests which shards gain keys when a shard is added or removed
+func TestGained(t *testing.T) {
+	before := testConfig(4)
+	after := testConfig(5)
+	oldRing := NewRingPlacement(before.Shards, DefaultVirtualNodes)
+	newRing := NewRingPlacement(after.Shards, DefaultVirtualNodes)
+	for _, n := range []int{1, 2, 3} {
+		if !Gained(oldRing, newRing, after.Shards[4].ID, n) {
+			t.Errorf("Expected the new shard to gain keys with %d replicas\n", n)
+		}
+		for _, shard := range before.Shards {
+			if Gained(oldRing, newRing, shard.ID, n) {
+				t.Errorf("Expected shard %s not to gain keys from a new shard with %d replicas\n", shard.ID, n)
+			}
+		}
+	}
+	if !Gained(newRing, oldRing, before.Shards[0].ID, 1) {
+		t.Errorf("Expected a shard to gain keys when another shard is removed\n")
+	}
+	if Gained(oldRing, NewRingPlacement(before.Shards, DefaultVirtualNodes), before.Shards[0].ID, 1) {
+		t.Errorf("Expected no shard to gain keys from the same ring\n")
+	}
+
+	oldChunks := NewChunkPlacement(before)
+	if Gained(oldChunks, NewChunkPlacement(testConfig(4)), before.Shards[0].ID, 2) {
+		t.Errorf("Expected no shard to gain keys from the same chunks\n")
+	}
+	if !Gained(oldChunks, NewChunkPlacement(after), before.Shards[0].ID, 1) {
+		t.Errorf("Expected the chunks of the first shard to change\n")
+	}
+	if !Gained(oldChunks, newRing, before.Shards[0].ID, 1) {
+		t.Errorf("Expected a new strategy to gain keys\n")
+	}
+}
+
+// TestPlacementConfig tests that the placement strategy survives conversion to the API representation
+func TestPlacementConfig(t *testing.T) {
+	shards, err := ParseShards("00000000-0000-0000-0000-000000000001=localhost:1/3,00000000-0000-0000-0000-000000000002=localhost:2")
+	if err != nil {
+		t.Fatalf("ParseShards Error: %s\n", err.Error())
+	}
+	if shards[0].Address != "localhost:1" || shards[0].Weight != 3 || shards[1].Weight != 0 {
+		t.Fatalf("Unexpected shards: %v\n", shards)
+	}
+	if _, err := ParseShards("00000000-0000-0000-0000-000000000001=localhost:1/x"); err == nil {
+		t.Fatalf("Expected an error for an invalid weight\n")
+	}
+
+	config := &Config{Size: SmallCluster, Shards: shards, Strategy: RingStrategy, VirtualNodes: 16}
+	converted, err := FromConfiguration(ToConfiguration(config))
+	if err != nil {
+		t.Fatalf("FromConfiguration Error: %s\n", err.Error())
+	}
+	if converted.Strategy != RingStrategy || converted.VirtualNodes != 16 || converted.Shards[0].Weight != 3 {
+		t.Fatalf("Unexpected configuration: %v\n", converted)
+	}
+
+	if _, err := NewPlacement(&Config{Strategy: "bogus"}); err == nil {
+		t.Fatalf("Expected an error for an unknown strategy\n")
+	}
+	for _, size := range []ClusterSize{0, HugeCluster + 1} {
+		if _, err := NewPlacement(&Config{Size: size, Shards: shards}); err == nil {
+			t.Fatalf("Expected an error for cluster size %d\n", size)
+		}
+	}
+	if _, err := NewPlacement(&Config{Shards: shards, Strategy: RingStrategy, VirtualNodes: MaxVirtualNodes + 1}); err == nil {
+		t.Fatalf("Expected an error for too many virtual nodes\n")
+	}
+	if _, err := NewPlacement(&Config{Size: SmallCluster, Shards: []Shard{{ID: shards[0].ID, Weight: MaxWeight + 1}}}); err == nil {
+		t.Fatalf("Expected an error for too large a weight\n")
+	}
+	if _, err := FromConfiguration(&api.ClusterConfiguration{Size: 257}); err == nil {
+		t.Fatalf("Expected an error for a cluster size that does not fit\n")
+	}
+}
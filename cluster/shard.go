@@ -0,0 +1,200 @@
+/******
+This file is part of Vaelen/DB.
+
+Copyright 2017, Andrew Young <andrew@vaelen.org>
+
+    Vaelen/DB is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+    Vaelen/DB is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+    You should have received a copy of the GNU General Public License
+along with Vaelen/DB.  If not, see <http://www.gnu.org/licenses/>.
+******/
+
+package cluster
+
+//noinspection GoRedundantImportAlias
+import (
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"strings"
+
+	"github.com/satori/go.uuid"
+	"github.com/vaelen/db/api"
+)
+
+// Shard represents a single shard in the system that can contain some set of chunks
+type Shard struct {
+	ID      uuid.UUID
+	Address string
+	// Weight is the share of the keys the shard receives relative to other shards under ring placement.
+	// A weight of zero is treated as one.
+	Weight int
+}
+
+// EffectiveWeight returns the shard's weight, treating zero as one
+func (shard Shard) EffectiveWeight() int {
+	if shard.Weight < 1 {
+		return 1
+	}
+	return shard.Weight
+}
+
+// ParseShards parses a comma separated list of shards, each given as <id>=<address> or <id>=<address>/<weight>
+func ParseShards(s string) ([]Shard, error) {
+	shards := make([]Shard, 0)
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		parts := strings.SplitN(entry, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid shard: %s", entry)
+		}
+		id, err := uuid.FromString(parts[0])
+		if err != nil {
+			return nil, fmt.Errorf("invalid shard ID: %s", parts[0])
+		}
+		shard := Shard{ID: id, Address: parts[1]}
+		if i := strings.LastIndex(shard.Address, "/"); i >= 0 {
+			weight, err := strconv.Atoi(shard.Address[i+1:])
+			if err != nil || weight < 1 || weight > MaxWeight {
+				return nil, fmt.Errorf("invalid shard weight: %s", entry)
+			}
+			shard.Address, shard.Weight = shard.Address[:i], weight
+		}
+		shards = append(shards, shard)
+	}
+	return shards, nil
+}
+
+// Chunk represents a single chunk in the system
+type Chunk struct {
+}
+
+// ClusterSize is an enumeration for keeping track of how large a cluster is
+type ClusterSize uint8
+
+// noinspection GoUnusedGlobalVariable
+var (
+	// SmallCluster is for a cluster of up to 256 shards
+	SmallCluster ClusterSize = 1
+	// MediumCluster is for a cluster of up to 65,536 shards
+	MediumCluster ClusterSize = 2
+	// LargeCluster is for a cluster of up to 1,6777,216 shards
+	LargeCluster ClusterSize = 3
+	// HugeCluster is for a cluster of up to 4,294,967,296 shards
+	HugeCluster ClusterSize = 4
+)
+
+// DefaultReplicas is the number of replicas each key is written to unless the servers are configured otherwise
+const DefaultReplicas = 3
+
+// Config holds the current cluster configuration
+type Config struct {
+	// Epoch is incremented every time the configuration changes
+	Epoch uint64
+	Size  ClusterSize
+	// Chunks is the list of all chunks in the system
+	Chunks []Chunk
+	// Shards is the list of shards in the system
+	Shards []Shard
+	// Strategy is the name of the placement strategy used to assign keys to shards. The chunk strategy is used when it is empty.
+	Strategy string
+	// VirtualNodes is the number of ring points per unit of shard weight when the ring strategy is used
+	VirtualNodes int
+}
+
+// Chunk returns the chunk number for the given key, which is the top Size bytes of the key's hash.
+// Releases before placement strategies were added shifted the hash right by Size bytes instead, which puts keys on different shards.
+func (config *Config) Chunk(s string) uint32 {
+	return config.chunkOf(Hash(s))
+}
+
+// chunkOf returns the chunk number for the given key hash
+func (config *Config) chunkOf(h uint32) uint32 {
+	return uint32(uint64(h) >> (32 - uint(config.Size)*8))
+}
+
+// Replicas returns the preference list for the given key under the configured placement strategy.
+// It builds the placement on every call, DBServer caches it instead.
+func (config *Config) Replicas(s string, n int) []Shard {
+	p, err := NewPlacement(config)
+	if err != nil {
+		p = NewChunkPlacement(config)
+	}
+	return p.Replicas(s, n)
+}
+
+// Shard returns the shard with the given ID
+func (config *Config) Shard(id uuid.UUID) (Shard, bool) {
+	for _, shard := range config.Shards {
+		if uuid.Equal(shard.ID, id) {
+			return shard, true
+		}
+	}
+	return Shard{}, false
+}
+
+// ConfigShard returns the shard that serializes changes to the cluster configuration, which is the first shard in the list
+func (config *Config) ConfigShard() (Shard, bool) {
+	if config == nil || len(config.Shards) == 0 {
+		return Shard{}, false
+	}
+	return config.Shards[0], true
+}
+
+// ToConfiguration converts a cluster configuration to its API representation
+func ToConfiguration(config *Config) *api.ClusterConfiguration {
+	if config == nil {
+		return nil
+	}
+	c := &api.ClusterConfiguration{
+		Epoch:        config.Epoch,
+		Size:         uint32(config.Size),
+		Shards:       make([]*api.ShardInfo, 0, len(config.Shards)),
+		Placement:    config.Strategy,
+		VirtualNodes: uint32(config.VirtualNodes),
+	}
+	for _, shard := range config.Shards {
+		c.Shards = append(c.Shards, &api.ShardInfo{ID: shard.ID.String(), Address: shard.Address, Weight: uint32(shard.Weight)})
+	}
+	return c
+}
+
+// FromConfiguration converts an API cluster configuration to a cluster configuration
+func FromConfiguration(c *api.ClusterConfiguration) (*Config, error) {
+	if c.Size > uint32(HugeCluster) {
+		return nil, fmt.Errorf("invalid cluster size: %d", c.Size)
+	}
+	config := &Config{
+		Epoch:        c.Epoch,
+		Size:         ClusterSize(c.Size),
+		Shards:       make([]Shard, 0, len(c.Shards)),
+		Strategy:     c.Placement,
+		VirtualNodes: int(c.VirtualNodes),
+	}
+	for _, shard := range c.Shards {
+		id, err := uuid.FromString(shard.ID)
+		if err != nil {
+			return nil, fmt.Errorf("invalid shard ID: %s", shard.ID)
+		}
+		config.Shards = append(config.Shards, Shard{ID: id, Address: shard.Address, Weight: int(shard.Weight)})
+	}
+	return config, nil
+}
+
+// Hash returns the 32bit hash for a given key
+func Hash(s string) uint32 {
+	h := fnv.New32()
+	h.Write([]byte(s))
+	return h.Sum32()
+}
@@ -20,18 +20,93 @@ along with Vaelen/DB.  If not, see <http://www.gnu.org/licenses/>.
 package main
 
 import (
+	"crypto/tls"
+	"flag"
 	"fmt"
+	"net"
+	"net/http"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/satori/go.uuid"
+	"github.com/vaelen/db/api"
+	"github.com/vaelen/db/cluster"
+	"github.com/vaelen/db/logging"
 	"github.com/vaelen/db/server"
+	"github.com/vaelen/db/storage"
+	"github.com/vaelen/db/trace"
 	"google.golang.org/grpc"
-	"net"
-	"log"
-	"os/signal"
-	"github.com/vaelen/db/api"
+	"google.golang.org/grpc/credentials"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+)
+
+var (
+	listenAddress = flag.String("listen", ":5555", "address to listen on")
+	shardID       = flag.String("id", "", "ID of this server's shard when running in a cluster")
+	shardList     = flag.String("shards", "", "comma separated list of the shards in the cluster, each given as <id>=<address> or <id>=<address>/<weight>. Ignored once a configuration has been saved.")
+	placement     = flag.String("placement", cluster.ChunkStrategy, "how keys are assigned to shards, either chunk or ring")
+	virtualNodes  = flag.Int("vnodes", cluster.DefaultVirtualNodes, "number of ring points per unit of shard weight when using ring placement")
+	replicas      = flag.Int("n", server.DefaultReplication.N, "number of replicas for each key")
+	readQuorum    = flag.Int("r", server.DefaultReplication.R, "number of replicas that must respond to a read")
+	writeQuorum   = flag.Int("w", server.DefaultReplication.W, "number of replicas that must acknowledge a write")
+	antiEntropy   = flag.Duration("anti-entropy", time.Minute, "how often to compare replicas with the rest of the cluster, or 0 to disable")
+	hintInterval  = flag.Duration("hint-interval", 10*time.Second, "how often to replay hints to replicas that were down")
+	hintWindow    = flag.Duration("hint-window", server.DefaultHintWindow, "how long to keep hints for replicas that are down")
+	maxHints      = flag.Int("max-hints", server.DefaultMaxHints, "maximum number of hints to keep for replicas that are down")
+	readRepair    = flag.Bool("read-repair", true, "repair stale replicas found during reads")
+	gossipRate    = flag.Duration("gossip-interval", server.DefaultGossip.Interval, "how often to probe another member of the cluster")
+	suspectAfter  = flag.Duration("suspect-timeout", server.DefaultGossip.SuspectTimeout, "how long a member can be suspected before it is declared dead")
+	seeds         = flag.String("join", "", "comma separated list of addresses to contact when joining the cluster")
+	txnRecovery   = flag.Duration("txn-recovery", 10*time.Second, "how often to resolve transactions that are waiting for a decision")
+	inDoubt       = flag.Duration("in-doubt-timeout", server.DefaultInDoubtTimeout, "how long a prepared transaction waits before its coordinator is asked for the decision")
+	maxOffset     = flag.Duration("max-clock-offset", server.DefaultMaxClockOffset, "how far ahead of the local clock another node's clock may be")
+	bootstrap     = flag.Bool("bootstrap", false, "form a new cluster from the shard list and save its configuration")
+	redisAddress  = flag.String("redis", "", "address to serve the Redis protocol on, or empty to disable it")
+	memcacheAddr  = flag.String("memcached", "", "address to serve the memcached text protocol on, or empty to disable it")
+	httpAddress   = flag.String("http", "", "address to serve the HTTP/JSON API on, or empty to disable it")
+	metricsAddr   = flag.String("metrics", "", "address to serve Prometheus metrics on at /metrics, or empty to disable them")
+	tlsCert       = flag.String("tls-cert", "", "PEM encoded certificate to serve gRPC and HTTP over TLS with and to present to other shards")
+	tlsKey        = flag.String("tls-key", "", "PEM encoded private key of the certificate")
+	tlsCA         = flag.String("tls-ca", "", "PEM encoded CA certificates to verify clients and other shards with")
+	clientAuth    = flag.Bool("tls-client-auth", false, "require clients to present a certificate signed by the CA")
+	tlsReload     = flag.Duration("tls-reload", server.DefaultCertReloadInterval, "how often to check the certificate files for changes, or 0 to disable")
+	authEnabled   = flag.Bool("auth", false, "require clients to authenticate and check their permissions")
+	clusterSecret = flag.String("cluster-secret", os.Getenv("VDB_CLUSTER_SECRET"), "secret shared by every node to authenticate requests between them, defaults to $VDB_CLUSTER_SECRET")
+	adminPassword = flag.String("admin-password", os.Getenv("VDB_ADMIN_PASSWORD"), "password the admin user can log in with until it is added as a user, defaults to $VDB_ADMIN_PASSWORD")
+	tokenTTL      = flag.Duration("token-ttl", server.DefaultTokenTTL, "how long login tokens are accepted")
+	compressAt    = flag.Int("compress-threshold", storage.CompressionThreshold, "size in bytes from which values are compressed in storage, or 0 to disable compression")
+	denseAt       = flag.Int("dense-threshold", storage.DenseCompressionThreshold, "size in bytes from which values are compressed with the slower codec that compresses better")
+	traceOTLP     = flag.String("trace-otlp", "", "URL of an OpenTelemetry collector to export trace spans to over OTLP/HTTP, usually ending in /v1/traces")
+	traceFile     = flag.String("trace-file", "", "file to write trace spans to as JSON lines, instead of exporting them to a collector")
+	traceSample   = flag.Float64("trace-sample", 1, "fraction of the traces started by this server that are exported, traces started by clients follow their decision")
+	logFormat     = flag.String("log-format", logging.TextFormat, "format of log lines, either text or json")
+	logLevel      = flag.String("log-level", "info", "lowest level of log lines that are written, one of debug, info, warn or error. It can be changed while the server runs with the SetLogLevel RPC.")
+	auditLog      = flag.String("audit-log", "audit.log", "file to record the changes clients make in, relative to the data directory, or empty to disable the audit log. Its entries are encrypted like the data directory when -master-key is given.")
+	auditMaxSize  = flag.Int64("audit-max-size", server.DefaultAuditMaxSize>>20, "size in megabytes at which the audit log is rotated")
+	auditMaxFiles = flag.Int("audit-max-files", server.DefaultAuditMaxFiles, "number of rotated audit logs to keep")
+	slowThreshold = flag.Duration("slow-threshold", server.DefaultSlowThreshold, "how long a request may take before it is recorded in the slow operation log, or 0 to disable it")
+	masterKey     = flag.String("master-key", "", "file holding the 32 byte master key, raw or hex encoded, that encrypts the data directory. Send SIGHUP to rotate the data key and rewrap it with the key in the file.")
 )
 
 func main() {
+	flag.Parse()
+
+	level, err := logging.ParseLevel(*logLevel)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid log level: %s\n", err.Error())
+		os.Exit(15)
+	}
+	logs, err := logging.New(os.Stderr, *logFormat, level)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Couldn't create logger: %s\n", err.Error())
+		os.Exit(15)
+	}
+
 	wd, err := os.Getwd()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Couldn't get working directory: %s\n", err.Error())
@@ -55,35 +130,298 @@ func main() {
 		os.Exit(4)
 	}
 
-	s := server.New(os.Stderr, dbPath)
+	var certs *server.Certificates
+	if *tlsCert != "" || *tlsKey != "" {
+		certs, err = server.LoadCertificates(logs.Logger("tls"), *tlsCert, *tlsKey, *tlsCA)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Couldn't load certificates: %s\n", err.Error())
+			os.Exit(11)
+		}
+		if *tlsReload > 0 {
+			certs.Watch(*tlsReload)
+		}
+	} else if *clientAuth {
+		fmt.Fprintf(os.Stderr, "A certificate is required to verify clients\n")
+		os.Exit(11)
+	}
+
+	storage.CompressionThreshold = *compressAt
+	storage.DenseCompressionThreshold = *denseAt
 
-	lis, err := net.Listen("tcp", ":5555")
+	var keyring *storage.Keyring
+	if *masterKey != "" {
+		master, err := storage.LoadMasterKey(*masterKey)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Couldn't load master key: %s\n", err.Error())
+			os.Exit(13)
+		}
+		keyring, err = storage.OpenKeyring(dbPath, master)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Couldn't open keyring: %s\n", err.Error())
+			os.Exit(13)
+		}
+		// Refuse to start rather than replace files that can not be decrypted
+		if err := keyring.Check(dbPath); err != nil {
+			fmt.Fprintf(os.Stderr, "Couldn't decrypt data directory: %s\n", err.Error())
+			os.Exit(13)
+		}
+	}
+
+	s := server.NewEncrypted(logs, dbPath, keyring)
+	if certs != nil {
+		s.UseTLS(certs)
+	}
+	if *traceOTLP != "" && *traceFile != "" {
+		fmt.Fprintf(os.Stderr, "Trace spans can either be exported to a collector or written to a file, not both\n")
+		os.Exit(14)
+	}
+	if *traceOTLP != "" {
+		s.Tracer = trace.NewTracer(logs.Logger("trace"), "vdb-server", trace.NewOTLPExporter(*traceOTLP))
+	} else if *traceFile != "" {
+		f, err := os.OpenFile(*traceFile, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0660)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Couldn't open trace file: %s\n", err.Error())
+			os.Exit(14)
+		}
+		defer f.Close()
+		s.Tracer = trace.NewTracer(logs.Logger("trace"), "vdb-server", trace.NewLogExporter(f))
+	}
+	if s.Tracer != nil {
+		s.Tracer.SampleRate = *traceSample
+	}
+	if *auditLog != "" {
+		path := *auditLog
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dbPath, path)
+		}
+		s.Audit, err = server.OpenAuditLog(path, *auditMaxSize<<20, *auditMaxFiles)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Couldn't open audit log: %s\n", err.Error())
+			os.Exit(16)
+		}
+		s.Audit.Keyring = keyring
+		// Refuse to start rather than drop entries that can not be decrypted when the log is encrypted again
+		if err := s.Audit.Check(); err != nil {
+			fmt.Fprintf(os.Stderr, "Couldn't decrypt audit log: %s\n", err.Error())
+			os.Exit(13)
+		}
+	}
+	if keyring != nil {
+		// Encrypt any files written before encryption was enabled once they have been loaded
+		go func() {
+			if err := s.Reencrypt(); err != nil {
+				logging.Fatal(s.Logger, "Couldn't encrypt data directory", "error", err)
+			}
+		}()
+	}
+	s.SlowThreshold = *slowThreshold
+	s.Replication.N = *replicas
+	s.Replication.R = *readQuorum
+	s.Replication.W = *writeQuorum
+	s.Replication.ReadRepair = *readRepair
+	s.Hints.Window = *hintWindow
+	s.Hints.Max = *maxHints
+	s.Txns.InDoubt = *inDoubt
+	s.Clock.MaxOffset = *maxOffset
+	if *authEnabled {
+		if *memcacheAddr != "" {
+			fmt.Fprintf(os.Stderr, "The memcached protocol can not be served when authentication is enabled\n")
+			os.Exit(12)
+		}
+		if *shardID != "" && *clusterSecret == "" {
+			fmt.Fprintf(os.Stderr, "A cluster secret is required when authentication is enabled in a cluster\n")
+			os.Exit(12)
+		}
+		s.Auth = server.NewAuth(*clusterSecret)
+		s.Auth.AdminPassword = *adminPassword
+		s.Auth.TokenTTL = *tokenTTL
+	}
+
+	s.StartTxnRecovery(*txnRecovery)
+
+	if *bootstrap {
+		if s.Cluster != nil {
+			fmt.Fprintf(os.Stderr, "A cluster configuration already exists, epoch %d\n", s.Cluster.Epoch)
+			os.Exit(9)
+		}
+		if *shardList == "" {
+			fmt.Fprintf(os.Stderr, "A shard list is required to bootstrap a cluster\n")
+			os.Exit(10)
+		}
+	}
+
+	if s.Cluster == nil && *shardList != "" {
+		shards, err := cluster.ParseShards(*shardList)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Couldn't parse shard list: %s\n", err.Error())
+			os.Exit(5)
+		}
+		config := &cluster.Config{
+			Size:         cluster.SmallCluster,
+			Shards:       shards,
+			Strategy:     *placement,
+			VirtualNodes: *virtualNodes,
+		}
+		if *bootstrap {
+			_, err = s.CompareAndSetClusterConfig(0, config)
+		} else {
+			_, err = cluster.NewPlacement(config)
+			s.Cluster = config
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid cluster configuration: %s\n", err.Error())
+			os.Exit(8)
+		}
+	}
+
+	if s.Cluster != nil && *shardID == "" {
+		fmt.Fprintf(os.Stderr, "A shard ID is required when running in a cluster\n")
+		os.Exit(6)
+	}
+
+	if *shardID != "" {
+		id, err := uuid.FromString(*shardID)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid shard ID: %s\n", *shardID)
+			os.Exit(6)
+		}
+		// Without a configuration the server waits for one to be bootstrapped or gossiped to it
+		s.Self = cluster.Shard{ID: id}
+		if s.Cluster != nil {
+			self, ok := s.Cluster.Shard(id)
+			if !ok {
+				fmt.Fprintf(os.Stderr, "Shard %s is not in the shard list\n", id)
+				os.Exit(7)
+			}
+			s.Self = self
+		}
+		if *antiEntropy > 0 {
+			s.StartAntiEntropy(*antiEntropy)
+		}
+		s.StartHintedHandoff(*hintInterval)
+		s.Gossip.Interval = *gossipRate
+		s.Gossip.SuspectTimeout = *suspectAfter
+		joinAddresses := make([]string, 0)
+		for _, seed := range strings.Split(*seeds, ",") {
+			if seed = strings.TrimSpace(seed); seed != "" {
+				joinAddresses = append(joinAddresses, seed)
+			}
+		}
+		s.StartGossip(joinAddresses)
+	}
+
+	lis, err := net.Listen("tcp", *listenAddress)
 	if err != nil {
-		log.Fatalf("failed to listen: %v", err)
+		logging.Fatal(s.Logger, "Failed to listen", "address", *listenAddress, "error", err)
+	}
+	options := []grpc.ServerOption{grpc.UnaryInterceptor(s.UnaryInterceptor), grpc.StreamInterceptor(s.StreamInterceptor)}
+	if certs != nil {
+		options = append(options, grpc.Creds(credentials.NewTLS(certs.ServerTLS(*clientAuth))))
 	}
-	grpcServer := grpc.NewServer()
+	grpcServer := grpc.NewServer(options...)
 	api.RegisterDatabaseServer(grpcServer, s)
+	// The health service reports NOT_SERVING until the server has loaded, so it can be asked while the data is loading
+	healthpb.RegisterHealthServer(grpcServer, s.Health())
+	reflection.Register(grpcServer)
+
+	var redisServer *server.RESPServer
+	if *redisAddress != "" {
+		redisListener, err := net.Listen("tcp", *redisAddress)
+		if err != nil {
+			logging.Fatal(s.Logger, "Failed to listen", "address", *redisAddress, "error", err)
+		}
+		redisServer = server.NewRESPServer(s)
+		go redisServer.Serve(redisListener)
+	}
+
+	var memcacheServer *server.MemcacheServer
+	if *memcacheAddr != "" {
+		memcacheListener, err := net.Listen("tcp", *memcacheAddr)
+		if err != nil {
+			logging.Fatal(s.Logger, "Failed to listen", "address", *memcacheAddr, "error", err)
+		}
+		memcacheServer = server.NewMemcacheServer(s)
+		go memcacheServer.Serve(memcacheListener)
+	}
+
+	var httpServer *http.Server
+	if *httpAddress != "" {
+		httpListener, err := net.Listen("tcp", *httpAddress)
+		if err != nil {
+			logging.Fatal(s.Logger, "Failed to listen", "address", *httpAddress, "error", err)
+		}
+		if certs != nil {
+			httpListener = tls.NewListener(httpListener, certs.ServerTLS(*clientAuth))
+		}
+		httpServer = &http.Server{Handler: server.NewHTTPGateway(s)}
+		go httpServer.Serve(httpListener)
+	}
+
+	var metricsServer *http.Server
+	if *metricsAddr != "" {
+		metricsListener, err := net.Listen("tcp", *metricsAddr)
+		if err != nil {
+			logging.Fatal(s.Logger, "Failed to listen", "address", *metricsAddr, "error", err)
+		}
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", s.MetricsHandler())
+		metricsServer = &http.Server{Handler: mux}
+		go metricsServer.Serve(metricsListener)
+	}
+
+	shutdown := func() {
+		if redisServer != nil {
+			redisServer.Close()
+		}
+		if memcacheServer != nil {
+			memcacheServer.Close()
+		}
+		if httpServer != nil {
+			httpServer.Close()
+		}
+		if metricsServer != nil {
+			metricsServer.Close()
+		}
+		if certs != nil {
+			certs.StopWatching()
+		}
+		s.Stop()
+		// Spans must be flushed before the gRPC server stops, main returns as soon as it does
+		s.Tracer.Close()
+		s.Audit.Close()
+		grpcServer.Stop()
+	}
 
 	// Handle signals nicely
-	signalHandler := make(chan os.Signal)
-	signal.Notify(signalHandler, os.Interrupt, os.Kill)
+	signalHandler := make(chan os.Signal, 1)
+	signal.Notify(signalHandler, os.Interrupt, os.Kill, syscall.SIGHUP)
 	go func(s *server.DBServer) {
 		for {
 			select {
 			case sig := <-signalHandler:
 				// Handle signal
-				s.Logger.Printf("Caught Signal: %s\n", sig)
+				s.Logger.Info("Caught signal", "signal", sig.String())
 				switch sig {
 				case os.Interrupt:
-					s.Stop()
-					grpcServer.Stop()
+					shutdown()
 					break
 				case os.Kill:
-					s.Stop()
-					grpcServer.Stop()
+					shutdown()
 					break
+				case syscall.SIGHUP:
+					if *masterKey == "" {
+						s.Logger.Info("Signal ignored")
+						break
+					}
+					master, err := storage.LoadMasterKey(*masterKey)
+					if err == nil {
+						err = s.RotateKey(master)
+					}
+					if err != nil {
+						s.Logger.Error("Could not rotate data key", "error", err)
+					}
 				default:
-					s.Logger.Printf("Signal Ignored\n")
+					s.Logger.Info("Signal ignored")
 				}
 			}
 		}
@@ -20,15 +20,74 @@ along with Vaelen/DB.  If not, see <http://www.gnu.org/licenses/>.
 package main
 
 import (
+	"flag"
+	"fmt"
 	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
 	"github.com/abiosoft/ishell"
+	"github.com/vaelen/db/api"
 	"github.com/vaelen/db/client"
+	"github.com/vaelen/db/cluster"
+	"github.com/vaelen/db/logging"
+	"github.com/vaelen/db/server"
+	"github.com/vaelen/db/trace"
+)
+
+var (
+	useTLS     = flag.Bool("tls", false, "connect using TLS, implied by the other TLS options")
+	tlsCert    = flag.String("tls-cert", "", "PEM encoded client certificate to present to servers that require one")
+	tlsKey     = flag.String("tls-key", "", "PEM encoded private key of the client certificate")
+	tlsCA      = flag.String("tls-ca", "", "PEM encoded CA certificates to verify servers with instead of the system roots")
+	serverName = flag.String("tls-server-name", "", "name the server's certificate must match, defaults to the host being connected to")
+	username   = flag.String("user", "", "username to send with every request to servers that require authentication")
+	password   = flag.String("password", "", "password of the user")
+	namespace  = flag.String("namespace", "", "namespace of the keys to read and write, defaults to the default namespace")
+	logLevel   = flag.String("log-level", "info", "lowest level of the client's log lines that are written, one of debug, info, warn or error")
+	compressor = flag.String("compression", client.DefaultCompression, "compression used for requests and responses, or empty to disable it")
+	traceOTLP  = flag.String("trace-otlp", "", "URL of an OpenTelemetry collector to export a trace span for every request to, usually ending in /v1/traces")
+	traceFile  = flag.String("trace-file", "", "file to write a trace span for every request to as JSON lines")
 )
 
 func Start() {
-	db := client.New(os.Stderr)
+	level, err := logging.ParseLevel(*logLevel)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid log level: %s\n", err.Error())
+		os.Exit(1)
+	}
+	logs, _ := logging.New(os.Stderr, logging.TextFormat, level)
+	db := client.New(logs.Logger("client"))
 	defer db.Close()
 
+	if *useTLS || *tlsCert != "" || *tlsKey != "" || *tlsCA != "" || *serverName != "" {
+		certs, err := server.LoadCertificates(logs.Logger("tls"), *tlsCert, *tlsKey, *tlsCA)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Couldn't load certificates: %s\n", err.Error())
+			os.Exit(1)
+		}
+		db.TLS = certs.ClientTLS(*serverName)
+	}
+	if *username != "" {
+		db.SetPassword(*username, *password)
+	}
+	db.Namespace = *namespace
+	db.Compression = *compressor
+	if *traceOTLP != "" {
+		db.Tracer = trace.NewTracer(logs.Logger("trace"), "vdb", trace.NewOTLPExporter(*traceOTLP))
+	} else if *traceFile != "" {
+		f, err := os.OpenFile(*traceFile, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0660)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Couldn't open trace file: %s\n", err.Error())
+			os.Exit(1)
+		}
+		defer f.Close()
+		db.Tracer = trace.NewTracer(logs.Logger("trace"), "vdb", trace.NewLogExporter(f))
+	}
+	defer db.Tracer.Close()
+
 	shell := ishell.New()
 
 	// display welcome info.
@@ -74,12 +133,40 @@ func Start() {
 				c.Println("Usage: get <key>")
 				return
 			}
-			v, err := db.Get(c.Args[0])
+			v, err := db.GetVersioned(c.Args[0])
 			if err != nil {
 				c.Printf("Error: %s\n", err)
 				return
 			}
-			c.Printf("Value: %s\n", v)
+			switch len(v.Values) {
+			case 0:
+				c.Printf("Value: \n")
+			case 1:
+				c.Printf("Value: %s\n", v.Values[0])
+			default:
+				c.Printf("Siblings: %d\n", len(v.Values))
+				for i, value := range v.Values {
+					c.Printf("Value %d: %s\n", i+1, value)
+				}
+			}
+		},
+	})
+
+	shell.AddCmd(&ishell.Cmd{
+		Name: "consistency",
+		Help: "sets the number of replicas that must respond to requests. usage: consistency <default|one|quorum|all>",
+		Func: func(c *ishell.Context) {
+			if len(c.Args) < 1 {
+				c.Printf("Consistency: %s\n", strings.ToLower(db.Consistency.String()))
+				return
+			}
+			consistency, ok := api.Consistency_value[strings.ToUpper(c.Args[0])]
+			if !ok {
+				c.Println("Usage: consistency <default|one|quorum|all>")
+				return
+			}
+			db.Consistency = api.Consistency(consistency)
+			c.Printf("Consistency: %s\n", strings.ToLower(db.Consistency.String()))
 		},
 	})
 
@@ -117,14 +204,606 @@ func Start() {
 		},
 	})
 
+	shell.AddCmd(&ishell.Cmd{
+		Name: "txn",
+		Help: "atomically sets and removes several keys. usage: txn set <key> <value> | remove <key> ...",
+		Func: func(c *ishell.Context) {
+			ops := make([]*api.TxnOp, 0)
+			for i := 0; i < len(c.Args); {
+				switch {
+				case c.Args[i] == "set" && i+2 < len(c.Args):
+					ops = append(ops, &api.TxnOp{ID: c.Args[i+1], Value: c.Args[i+2]})
+					i += 3
+				case c.Args[i] == "remove" && i+1 < len(c.Args):
+					ops = append(ops, &api.TxnOp{ID: c.Args[i+1], Delete: true})
+					i += 2
+				default:
+					c.Println("Usage: txn set <key> <value> | remove <key> ...")
+					return
+				}
+			}
+			if len(ops) == 0 {
+				c.Println("Usage: txn set <key> <value> | remove <key> ...")
+				return
+			}
+			txid, err := db.Txn(ops)
+			if err != nil {
+				c.Printf("Error: %s\n", err)
+				return
+			}
+			c.Printf("Transaction Committed: %s\n", txid)
+		},
+	})
+
+	shell.AddCmd(&ishell.Cmd{
+		Name: "members",
+		Help: "lists the members of the cluster and recent membership changes. usage: members",
+		Func: func(c *ishell.Context) {
+			response, err := db.Members()
+			if err != nil {
+				c.Printf("Error: %s\n", err)
+				return
+			}
+			c.Printf("Cluster Epoch: %d\n", response.Epoch)
+			for _, m := range response.Members {
+				c.Printf("%s  %-21s  %-7s  incarnation %d\n", m.ID, m.Address, m.State, m.Incarnation)
+			}
+			if len(response.Events) > 0 {
+				c.Println("Recent Changes:")
+			}
+			for _, e := range response.Events {
+				c.Printf("%s  %s  %s -> %s\n", time.Unix(0, e.Time).Format(time.RFC3339), e.ID, e.From, e.To)
+			}
+		},
+	})
+
+	shell.AddCmd(&ishell.Cmd{
+		Name: "cluster",
+		Help: "shows the cluster configuration. usage: cluster",
+		Func: func(c *ishell.Context) {
+			config, err := db.ClusterConfig()
+			if err != nil {
+				c.Printf("Error: %s\n", err)
+				return
+			}
+			placement := config.Placement
+			if placement == "" {
+				placement = "chunk"
+			}
+			c.Printf("Epoch: %d, Placement: %s, Shards: %d\n", config.Epoch, placement, len(config.Shards))
+			for _, shard := range config.Shards {
+				c.Printf("%s  %-21s  weight %d\n", shard.ID, shard.Address, shard.Weight)
+			}
+		},
+	})
+
+	shell.AddCmd(&ishell.Cmd{
+		Name: "load",
+		Help: "reports how evenly a placement spreads a sample of keys over the shards. usage: load [chunk|ring] [virtual nodes] [sample]",
+		Func: func(c *ishell.Context) {
+			usage := "Usage: load [chunk|ring] [virtual nodes] [sample]"
+			if len(c.Args) > 3 {
+				c.Println(usage)
+				return
+			}
+			request := &api.PlacementLoadRequest{}
+			if len(c.Args) > 0 {
+				request.Placement = c.Args[0]
+			}
+			for i, field := range []*uint32{&request.VirtualNodes, &request.Sample} {
+				if len(c.Args) > i+1 {
+					n, err := strconv.ParseUint(c.Args[i+1], 10, 32)
+					if err != nil {
+						c.Println(usage)
+						return
+					}
+					*field = uint32(n)
+				}
+			}
+			response, err := db.PlacementLoad(request)
+			if err != nil {
+				c.Printf("Error: %s\n", err)
+				return
+			}
+			c.Printf("Placement: %s, Keys: %d, Skew: %.3f, Standard Deviation: %.3f\n", response.Placement, response.Keys, response.Skew, response.StdDev)
+			shards := make([]string, 0, len(response.Counts))
+			for id := range response.Counts {
+				shards = append(shards, id)
+			}
+			sort.Strings(shards)
+			for _, id := range shards {
+				c.Printf("%s  %d\n", id, response.Counts[id])
+			}
+		},
+	})
+
+	shell.AddCmd(&ishell.Cmd{
+		Name: "bootstrap",
+		Help: "forms a new cluster from the given shards. usage: bootstrap <id>=<address>[/<weight>],... [chunk|ring]",
+		Func: func(c *ishell.Context) {
+			if len(c.Args) < 1 || len(c.Args) > 2 {
+				c.Println("Usage: bootstrap <id>=<address>[/<weight>],... [chunk|ring]")
+				return
+			}
+			shards, err := cluster.ParseShards(c.Args[0])
+			if err != nil {
+				c.Printf("Error: %s\n", err)
+				return
+			}
+			config := &cluster.Config{Size: cluster.SmallCluster, Shards: shards}
+			if len(c.Args) > 1 {
+				config.Strategy = c.Args[1]
+			}
+			updated, err := db.UpdateClusterConfig(0, cluster.ToConfiguration(config))
+			if err != nil {
+				c.Printf("Error: %s\n", err)
+				return
+			}
+			c.Printf("Cluster Formed. Epoch: %d\n", updated.Epoch)
+		},
+	})
+
+	shell.AddCmd(&ishell.Cmd{
+		Name: "index",
+		Help: "declares or drops a secondary index on a field of JSON values. usage: index create <name> <path> | drop <name>",
+		Func: func(c *ishell.Context) {
+			var err error
+			switch {
+			case len(c.Args) == 3 && c.Args[0] == "create":
+				_, err = db.CreateIndex(c.Args[1], c.Args[2])
+			case len(c.Args) == 2 && c.Args[0] == "drop":
+				err = db.DropIndex(c.Args[1])
+			default:
+				c.Println("Usage: index create <name> <path> | drop <name>")
+				return
+			}
+			if err != nil {
+				c.Printf("Error: %s\n", err)
+				return
+			}
+			c.Println("Done")
+		},
+	})
+
+	shell.AddCmd(&ishell.Cmd{
+		Name: "indexes",
+		Help: "lists the secondary indexes of the server. usage: indexes",
+		Func: func(c *ishell.Context) {
+			indexes, err := db.Indexes()
+			if err != nil {
+				c.Printf("Error: %s\n", err)
+				return
+			}
+			for _, i := range indexes {
+				c.Printf("%-20s  %-30s  %3.0f%%  entries %d\n", i.Name, i.Path, i.Progress*100, i.Entries)
+			}
+		},
+	})
+
+	shell.AddCmd(&ishell.Cmd{
+		Name: "query",
+		Help: "returns the keys whose indexed field matches. values are JSON. usage: query <index> = <value> | query <index> <min|*> <max|*> [limit]",
+		Func: func(c *ishell.Context) {
+			usage := "Usage: query <index> = <value> | query <index> <min|*> <max|*> [limit]"
+			if len(c.Args) < 3 || len(c.Args) > 4 {
+				c.Println(usage)
+				return
+			}
+			query := &api.QueryIndexRequest{Index: c.Args[0], Namespace: db.Namespace}
+			if c.Args[1] == "=" {
+				query.Equal = c.Args[2]
+			} else {
+				if c.Args[1] != "*" {
+					query.Min = c.Args[1]
+				}
+				if c.Args[2] != "*" {
+					query.Max = c.Args[2]
+				}
+			}
+			if len(c.Args) == 4 {
+				limit, err := strconv.ParseUint(c.Args[3], 10, 32)
+				if err != nil {
+					c.Println(usage)
+					return
+				}
+				query.Limit = uint32(limit)
+			}
+			entries, err := db.QueryIndex(query)
+			if err != nil {
+				c.Printf("Error: %s\n", err)
+				return
+			}
+			for _, e := range entries {
+				c.Printf("%s  %s\n", e.ID, e.Value)
+			}
+			c.Printf("Keys: %d\n", len(entries))
+		},
+	})
+
+	shell.AddCmd(&ishell.Cmd{
+		Name: "login",
+		Help: "logs in to a server that requires authentication. usage: login <username> [password]",
+		Func: func(c *ishell.Context) {
+			if len(c.Args) < 1 || len(c.Args) > 2 {
+				c.Println("Usage: login <username> [password]")
+				return
+			}
+			password := ""
+			if len(c.Args) == 2 {
+				password = c.Args[1]
+			} else {
+				c.Print("Password: ")
+				password = c.ReadPassword()
+			}
+			_, expires, err := db.Login(c.Args[0], password)
+			if err != nil {
+				c.Printf("Error: %s\n", err)
+				return
+			}
+			c.Printf("Logged in until %s\n", expires.Format(time.RFC3339))
+		},
+	})
+
+	shell.AddCmd(&ishell.Cmd{
+		Name: "user",
+		Help: "manages the users of the server. usage: user add <name> <password> [role...] | remove <name> | list",
+		Func: func(c *ishell.Context) {
+			var err error
+			switch {
+			case len(c.Args) >= 3 && c.Args[0] == "add":
+				err = db.AddUser(c.Args[1], c.Args[2], c.Args[3:]...)
+			case len(c.Args) == 2 && c.Args[0] == "remove":
+				err = db.RemoveUser(c.Args[1])
+			case len(c.Args) == 1 && c.Args[0] == "list":
+				var users []*api.UserInfo
+				users, err = db.Users()
+				for _, u := range users {
+					c.Printf("%-20s  %s\n", u.Username, strings.Join(u.Roles, ", "))
+				}
+				if err == nil {
+					return
+				}
+			default:
+				c.Println("Usage: user add <name> <password> [role...] | remove <name> | list")
+				return
+			}
+			if err != nil {
+				c.Printf("Error: %s\n", err)
+				return
+			}
+			c.Println("Done")
+		},
+	})
+
+	shell.AddCmd(&ishell.Cmd{
+		Name: "role",
+		Help: "manages the permissions roles have on key prefixes. a prefix of * is every key. usage: role grant <role> <prefix> <read|write|admin> | revoke <role> <prefix> | list",
+		Func: func(c *ishell.Context) {
+			usage := "Usage: role grant <role> <prefix> <read|write|admin> | revoke <role> <prefix> | list"
+			prefix := func(p string) string {
+				if p == "*" {
+					return ""
+				}
+				return p
+			}
+			var err error
+			switch {
+			case len(c.Args) == 4 && c.Args[0] == "grant":
+				permission, ok := api.Permission_value[strings.ToUpper(c.Args[3])]
+				if !ok || permission == int32(api.Permission_NONE) {
+					c.Println(usage)
+					return
+				}
+				err = db.GrantRole(c.Args[1], prefix(c.Args[2]), api.Permission(permission))
+			case len(c.Args) == 3 && c.Args[0] == "revoke":
+				err = db.RevokeRole(c.Args[1], prefix(c.Args[2]))
+			case len(c.Args) == 1 && c.Args[0] == "list":
+				var roles []*api.RoleInfo
+				roles, err = db.Roles()
+				for _, r := range roles {
+					for _, g := range r.Grants {
+						p := g.Prefix
+						if p == "" {
+							p = "*"
+						}
+						c.Printf("%-20s  %-30s  %s\n", r.Name, p, strings.ToLower(g.Permission.String()))
+					}
+				}
+				if err == nil {
+					return
+				}
+			default:
+				c.Println(usage)
+				return
+			}
+			if err != nil {
+				c.Printf("Error: %s\n", err)
+				return
+			}
+			c.Println("Done")
+		},
+	})
+
+	shell.AddCmd(&ishell.Cmd{
+		Name: "namespace",
+		Help: "manages namespaces and selects the one keys are read from and written to. usage: namespace create <name> [ttl=<duration>] [keys=<n>] [bytes=<n>] [replicas=<n>] | drop <name> | list | use [name]",
+		Func: func(c *ishell.Context) {
+			usage := "Usage: namespace create <name> [ttl=<duration>] [keys=<n>] [bytes=<n>] [replicas=<n>] | drop <name> | list | use [name]"
+			var err error
+			switch {
+			case len(c.Args) >= 2 && c.Args[0] == "create":
+				request := &api.NamespaceRequest{Name: c.Args[1]}
+				for _, setting := range c.Args[2:] {
+					parts := strings.SplitN(setting, "=", 2)
+					if len(parts) != 2 {
+						c.Println(usage)
+						return
+					}
+					var n int64
+					if parts[0] == "ttl" {
+						var ttl time.Duration
+						ttl, err = time.ParseDuration(parts[1])
+						n = int64(ttl / time.Millisecond)
+					} else {
+						n, err = strconv.ParseInt(parts[1], 10, 64)
+					}
+					if err != nil {
+						c.Println(usage)
+						return
+					}
+					switch parts[0] {
+					case "ttl":
+						request.DefaultTtl = n
+					case "keys":
+						request.MaxKeys = n
+					case "bytes":
+						request.MaxBytes = n
+					case "replicas":
+						request.Replicas = uint32(n)
+					default:
+						c.Println(usage)
+						return
+					}
+				}
+				_, err = db.CreateNamespace(request)
+			case len(c.Args) == 2 && c.Args[0] == "drop":
+				err = db.DropNamespace(c.Args[1])
+			case len(c.Args) == 1 && c.Args[0] == "list":
+				var namespaces []*api.NamespaceInfo
+				namespaces, err = db.ListNamespaces()
+				for _, n := range namespaces {
+					ttl := time.Duration(n.DefaultTtl) * time.Millisecond
+					c.Printf("%-20s  ttl %-8s  keys %d/%d  bytes %d/%d  stored %d (%.1fx)  replicas %d\n", n.Name, ttl, n.Keys, n.MaxKeys, n.Bytes, n.MaxBytes, n.StoredBytes, n.CompressionRatio, n.Replicas)
+				}
+				if err == nil {
+					return
+				}
+			case len(c.Args) <= 2 && len(c.Args) > 0 && c.Args[0] == "use":
+				db.Namespace = ""
+				if len(c.Args) == 2 {
+					db.Namespace = c.Args[1]
+				}
+			default:
+				c.Println(usage)
+				return
+			}
+			if err != nil {
+				c.Printf("Error: %s\n", err)
+				return
+			}
+			c.Println("Done")
+		},
+	})
+
+	shell.AddCmd(&ishell.Cmd{
+		Name: "limit",
+		Help: "sets a rate limit, zero removes it. usage: limit client <name|*> <ops/s> <bytes/s> | limit namespace <name> <ops/s> <bytes/s> [max-keys] [max-bytes]",
+		Func: func(c *ishell.Context) {
+			usage := "Usage: limit client <name|*> <ops/s> <bytes/s> | limit namespace <name> <ops/s> <bytes/s> [max-keys] [max-bytes]"
+			if len(c.Args) < 4 || (c.Args[0] == "client" && len(c.Args) != 4) || (c.Args[0] == "namespace" && len(c.Args) > 6) {
+				c.Println(usage)
+				return
+			}
+			request := &api.LimitsRequest{Rate: &api.RateLimit{}}
+			switch c.Args[0] {
+			case "client":
+				request.Client = c.Args[1]
+			case "namespace":
+				request.Namespace = c.Args[1]
+			default:
+				c.Println(usage)
+				return
+			}
+			var err error
+			if request.Rate.OpsPerSecond, err = strconv.ParseFloat(c.Args[2], 64); err != nil {
+				c.Println(usage)
+				return
+			}
+			if request.Rate.BytesPerSecond, err = strconv.ParseFloat(c.Args[3], 64); err != nil {
+				c.Println(usage)
+				return
+			}
+			if len(c.Args) > 4 {
+				if request.MaxKeys, err = strconv.ParseInt(c.Args[4], 10, 64); err != nil {
+					c.Println(usage)
+					return
+				}
+			}
+			if len(c.Args) > 5 {
+				if request.MaxBytes, err = strconv.ParseInt(c.Args[5], 10, 64); err != nil {
+					c.Println(usage)
+					return
+				}
+			}
+			if _, err := db.SetLimits(request); err != nil {
+				c.Printf("Error: %s\n", err)
+				return
+			}
+			c.Println("Done")
+		},
+	})
+
+	shell.AddCmd(&ishell.Cmd{
+		Name: "limits",
+		Help: "lists the rate limits of clients and namespaces",
+		Func: func(c *ishell.Context) {
+			limits, err := db.Limits()
+			if err != nil {
+				c.Printf("Error: %s\n", err)
+				return
+			}
+			c.Printf("client    %-20s  ops %g/s  bytes %g/s\n", "*", limits.DefaultClient.GetOpsPerSecond(), limits.DefaultClient.GetBytesPerSecond())
+			for name, r := range limits.Clients {
+				c.Printf("client    %-20s  ops %g/s  bytes %g/s\n", name, r.OpsPerSecond, r.BytesPerSecond)
+			}
+			for name, r := range limits.Namespaces {
+				c.Printf("namespace %-20s  ops %g/s  bytes %g/s\n", name, r.OpsPerSecond, r.BytesPerSecond)
+			}
+		},
+	})
+
+	shell.AddCmd(&ishell.Cmd{
+		Name: "loglevel",
+		Help: "changes the log level of a component of the connected server, or of every component. usage: loglevel [component] <debug|info|warn|error>",
+		Func: func(c *ishell.Context) {
+			var component, level string
+			switch len(c.Args) {
+			case 1:
+				level = c.Args[0]
+			case 2:
+				component, level = c.Args[0], c.Args[1]
+			default:
+				c.Println("Usage: loglevel [component] <debug|info|warn|error>")
+				return
+			}
+			levels, err := db.SetLogLevel(component, level)
+			if err != nil {
+				c.Printf("Error: %s\n", err)
+				return
+			}
+			printLogLevels(c, levels)
+		},
+	})
+
+	shell.AddCmd(&ishell.Cmd{
+		Name: "loglevels",
+		Help: "lists the log level of every component of the connected server",
+		Func: func(c *ishell.Context) {
+			levels, err := db.LogLevels()
+			if err != nil {
+				c.Printf("Error: %s\n", err)
+				return
+			}
+			printLogLevels(c, levels)
+		},
+	})
+
+	shell.AddCmd(&ishell.Cmd{
+		Name: "audit",
+		Help: "lists the changes recorded in the audit log, the key is in the current namespace. usage: audit [key=<id>] [namespace=<name>] [caller=<name>] [op=<operation>] [since=<duration>] [limit=<n>]",
+		Func: func(c *ishell.Context) {
+			usage := "Usage: audit [key=<id>] [namespace=<name>] [caller=<name>] [op=<operation>] [since=<duration>] [limit=<n>]"
+			query := &api.AuditQuery{Limit: 20}
+			for _, setting := range c.Args {
+				parts := strings.SplitN(setting, "=", 2)
+				if len(parts) != 2 {
+					c.Println(usage)
+					return
+				}
+				switch parts[0] {
+				case "key":
+					query.Key, query.Namespace = parts[1], db.Namespace
+				case "namespace":
+					query.Namespace = parts[1]
+				case "caller":
+					query.Caller = parts[1]
+				case "op":
+					query.Operation = parts[1]
+				case "since":
+					since, err := time.ParseDuration(parts[1])
+					if err != nil {
+						c.Println(usage)
+						return
+					}
+					query.Since = time.Now().Add(-since).UnixNano()
+				case "limit":
+					limit, err := strconv.ParseUint(parts[1], 10, 32)
+					if err != nil {
+						c.Println(usage)
+						return
+					}
+					query.Limit = uint32(limit)
+				default:
+					c.Println(usage)
+					return
+				}
+			}
+			response, err := db.AuditLog(query)
+			if err != nil {
+				c.Printf("Error: %s\n", err)
+				return
+			}
+			for _, e := range response.Entries {
+				outcome := e.Code
+				if e.Error != "" {
+					outcome += ": " + e.Error
+				}
+				keys := strings.Join(e.Keys, ",")
+				if e.Namespace != "" {
+					keys = e.Namespace + "/" + keys
+				}
+				c.Printf("%s  %-12s  %-16s  %-20s  %-30s  %s  [%s]\n", time.Unix(0, e.Time).Format(time.RFC3339), e.Shard, e.Caller, e.Operation, keys, outcome, e.RequestId)
+			}
+			if response.Unreachable > 0 {
+				c.Printf("Warning: %d servers could not be reached\n", response.Unreachable)
+			}
+		},
+	})
+
+	shell.AddCmd(&ishell.Cmd{
+		Name: "slowops",
+		Help: "lists the most recent requests recorded in the slow operation log. usage: slowops [limit]",
+		Func: func(c *ishell.Context) {
+			limit := uint64(20)
+			if len(c.Args) > 1 {
+				c.Println("Usage: slowops [limit]")
+				return
+			}
+			if len(c.Args) == 1 {
+				var err error
+				if limit, err = strconv.ParseUint(c.Args[0], 10, 32); err != nil {
+					c.Println("Usage: slowops [limit]")
+					return
+				}
+			}
+			response, err := db.SlowOperations(uint32(limit))
+			if err != nil {
+				c.Printf("Error: %s\n", err)
+				return
+			}
+			for _, op := range response.Operations {
+				keys := strings.Join(op.Keys, ",")
+				if op.Namespace != "" {
+					keys = op.Namespace + "/" + keys
+				}
+				c.Printf("%s  %-12s  %-16s  %-20s  %-30s  %s  %s (wait %s, apply %s, sync %s, %d storage requests)  [%s]\n",
+					time.Unix(0, op.Time).Format(time.RFC3339), op.Shard, op.Caller, op.Operation, keys, op.Code, time.Duration(op.Duration),
+					time.Duration(op.Wait), time.Duration(op.Apply), time.Duration(op.Sync), op.StorageRequests, op.RequestId)
+			}
+			if response.Unreachable > 0 {
+				c.Printf("Warning: %d servers could not be reached\n", response.Unreachable)
+			}
+		},
+	})
+
 	// initial connection
 	address := "localhost:5555"
-	if len(os.Args) > 1 {
-		address = os.Args[1]
+	if flag.NArg() > 0 {
+		address = flag.Arg(0)
 	}
 
 	shell.Printf("Connecting to %s...\n", address)
-	err := db.Connect(address)
+	err = db.Connect(address)
 	if err != nil {
 		shell.Printf("Error: %s\n", err.Error())
 	}
@@ -134,6 +813,19 @@ func Start() {
 	shell.Run()
 }
 
+// printLogLevels prints the log level of each component in order
+func printLogLevels(c *ishell.Context, levels map[string]string) {
+	components := make([]string, 0, len(levels))
+	for component := range levels {
+		components = append(components, component)
+	}
+	sort.Strings(components)
+	for _, component := range components {
+		c.Printf("%-10s %s\n", component, levels[component])
+	}
+}
+
 func main() {
+	flag.Parse()
 	Start()
 }
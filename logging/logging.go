@@ -0,0 +1,194 @@
+/******
+This file is part of Vaelen/DB.
+
+Copyright 2017, Andrew Young <andrew@vaelen.org>
+
+    Vaelen/DB is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+    Vaelen/DB is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+    You should have received a copy of the GNU General Public License
+along with Vaelen/DB.  If not, see <http://www.gnu.org/licenses/>.
+******/
+
+// Package logging builds the structured, leveled loggers used by every package.
+// Each component of a process logs through its own logger, tagged with the component's name, and the level of each component can be changed while the process runs.
+// Log lines written for a request carry the request's ID when the logger is given the request's context.
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+)
+
+const (
+	// TextFormat writes log lines as key=value pairs
+	TextFormat = "text"
+	// JSONFormat writes log lines as JSON objects
+	JSONFormat = "json"
+)
+
+const (
+	// ComponentKey is the key of the attribute naming the component that wrote a log line
+	ComponentKey = "component"
+	// RequestIDKey is the key of the attribute holding the ID of the request a log line was written for
+	RequestIDKey = "request_id"
+	// RequestIDHeader is the metadata key request IDs are sent in
+	RequestIDHeader = "x-request-id"
+)
+
+// Root writes the log lines of every component of a process and holds their levels
+type Root struct {
+	handler slog.Handler
+	lock    sync.Mutex
+	// level is the level of components that have not been given one of their own
+	level  slog.Level
+	levels map[string]*slog.LevelVar
+}
+
+// New creates a root that writes log lines in the given format, logging every component at the given level
+func New(w io.Writer, format string, level slog.Level) (*Root, error) {
+	// Levels are checked by each component, so the handler itself lets everything through
+	options := &slog.HandlerOptions{Level: slog.Level(-128)}
+	var handler slog.Handler
+	switch format {
+	case TextFormat, "":
+		handler = slog.NewTextHandler(w, options)
+	case JSONFormat:
+		handler = slog.NewJSONHandler(w, options)
+	default:
+		return nil, fmt.Errorf("unknown log format: %s", format)
+	}
+	return &Root{
+		handler: handler,
+		level:   level,
+		levels:  make(map[string]*slog.LevelVar),
+	}, nil
+}
+
+// Text creates a root that writes log lines as text at the info level
+func Text(w io.Writer) *Root {
+	root, _ := New(w, TextFormat, slog.LevelInfo)
+	return root
+}
+
+// ParseLevel parses the name of a log level, such as debug, info, warn or error
+func ParseLevel(name string) (slog.Level, error) {
+	var level slog.Level
+	err := level.UnmarshalText([]byte(strings.TrimSpace(name)))
+	return level, err
+}
+
+// levelVar returns the level of a component, adding it at the default level if it has not logged before
+func (r *Root) levelVar(component string) *slog.LevelVar {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	level, ok := r.levels[component]
+	if !ok {
+		level = &slog.LevelVar{}
+		level.Set(r.level)
+		r.levels[component] = level
+	}
+	return level
+}
+
+// Logger returns the logger of a component
+func (r *Root) Logger(component string) *slog.Logger {
+	return slog.New(&handler{inner: r.handler, level: r.levelVar(component)}).With(ComponentKey, component)
+}
+
+// SetLevel changes the level of a component.  When component is empty every component changes, including those that have not logged yet.
+func (r *Root) SetLevel(component string, level slog.Level) {
+	if component != "" {
+		r.levelVar(component).Set(level)
+		return
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.level = level
+	for _, l := range r.levels {
+		l.Set(level)
+	}
+}
+
+// Levels returns the level of every component that has a logger
+func (r *Root) Levels() map[string]slog.Level {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	levels := make(map[string]slog.Level, len(r.levels))
+	for component, level := range r.levels {
+		levels[component] = level.Level()
+	}
+	return levels
+}
+
+// handler filters log lines by the level of its component and adds the ID of the request they were written for
+type handler struct {
+	inner slog.Handler
+	level *slog.LevelVar
+}
+
+func (h *handler) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+func (h *handler) Handle(ctx context.Context, record slog.Record) error {
+	if id := RequestID(ctx); id != "" {
+		record = record.Clone()
+		record.AddAttrs(slog.String(RequestIDKey, id))
+	}
+	return h.inner.Handle(ctx, record)
+}
+
+func (h *handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &handler{inner: h.inner.WithAttrs(attrs), level: h.level}
+}
+
+func (h *handler) WithGroup(name string) slog.Handler {
+	return &handler{inner: h.inner.WithGroup(name), level: h.level}
+}
+
+// Fatal logs an error and exits the process
+func Fatal(logger *slog.Logger, msg string, args ...interface{}) {
+	logger.Error(msg, args...)
+	os.Exit(1)
+}
+
+type requestIDKey struct{}
+
+// NewRequestID returns a new random request ID
+func NewRequestID() string {
+	var b [8]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// WithRequestID returns a copy of ctx whose log lines carry the given request ID
+func WithRequestID(ctx context.Context, id string) context.Context {
+	if id == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID returns the ID of the request ctx belongs to, or an empty string if it has none
+func RequestID(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
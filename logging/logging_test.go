@@ -0,0 +1,86 @@
+/******
+This file is part of Vaelen/DB.
+
+Copyright 2017, Andrew Young <andrew@vaelen.org>
+
+    Vaelen/DB is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+    Vaelen/DB is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+    You should have received a copy of the GNU General Public License
+along with Vaelen/DB.  If not, see <http://www.gnu.org/licenses/>.
+******/
+
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+// TestLevels tests that each component logs at its own level
+func TestLevels(t *testing.T) {
+	var b bytes.Buffer
+	root := Text(&b)
+	storage := root.Logger("storage")
+	network := root.Logger("network")
+
+	storage.Debug("hidden")
+	root.SetLevel("storage", slog.LevelDebug)
+	storage.Debug("shown", "key", "foo")
+	network.Debug("hidden")
+	if lines := strings.Split(strings.TrimSpace(b.String()), "\n"); len(lines) != 1 || !strings.Contains(lines[0], "level=DEBUG msg=shown component=storage key=foo") {
+		t.Fatalf("Unexpected log lines: %q\n", b.String())
+	}
+
+	root.SetLevel("", slog.LevelError)
+	b.Reset()
+	storage.Warn("hidden")
+	root.Logger("http").Warn("hidden")
+	if b.Len() != 0 {
+		t.Fatalf("Expected every component to log at the error level, got %q\n", b.String())
+	}
+	levels := root.Levels()
+	if len(levels) != 3 || levels["storage"] != slog.LevelError || levels["http"] != slog.LevelError {
+		t.Errorf("Unexpected levels: %v\n", levels)
+	}
+
+	if _, err := ParseLevel("verbose"); err == nil {
+		t.Errorf("Expected an unknown level to be rejected\n")
+	}
+	if _, err := New(&b, "xml", slog.LevelInfo); err == nil {
+		t.Errorf("Expected an unknown format to be rejected\n")
+	}
+}
+
+// TestRequestID tests that log lines written for a request carry its ID
+func TestRequestID(t *testing.T) {
+	var b bytes.Buffer
+	root, err := New(&b, JSONFormat, slog.LevelInfo)
+	if err != nil {
+		t.Fatalf("New Error: %s\n", err.Error())
+	}
+	ctx := WithRequestID(context.Background(), "abc")
+	root.Logger("network").With("shard", "1").InfoContext(ctx, "Replica write failed")
+
+	var line map[string]interface{}
+	if err := json.Unmarshal(b.Bytes(), &line); err != nil {
+		t.Fatalf("Unmarshal Error: %s\n", err.Error())
+	}
+	if line[ComponentKey] != "network" || line[RequestIDKey] != "abc" || line["shard"] != "1" || line["msg"] != "Replica write failed" {
+		t.Errorf("Unexpected log line: %s\n", b.String())
+	}
+	if RequestID(context.Background()) != "" || len(NewRequestID()) != 16 {
+		t.Errorf("Unexpected request IDs\n")
+	}
+}
@@ -0,0 +1,418 @@
+/******
+This file is part of Vaelen/DB.
+
+Copyright 2017, Andrew Young <andrew@vaelen.org>
+
+    Vaelen/DB is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+    Vaelen/DB is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+    You should have received a copy of the GNU General Public License
+along with Vaelen/DB.  If not, see <http://www.gnu.org/licenses/>.
+******/
+
+package server
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/satori/go.uuid"
+	"github.com/vaelen/db/api"
+	"github.com/vaelen/db/cluster"
+	"github.com/vaelen/db/storage"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// AntiEntropyStats reports the work done by the anti-entropy job
+type AntiEntropyStats struct {
+	// Runs is the number of completed runs
+	Runs uint64
+	// LastRun is the time the last run started
+	LastRun time.Time
+	// LastRunDuration is how long the last run took
+	LastRunDuration time.Duration
+	// LastRunNodesCompared is the number of tree nodes compared with other replicas during the last run
+	LastRunNodesCompared int
+	// LastRunKeysRepaired is the number of keys repaired during the last run.
+	// A key is counted once for each replica it was exchanged with.
+	LastRunKeysRepaired int
+	// TotalKeysRepaired is the number of keys repaired by every run
+	TotalKeysRepaired uint64
+	// LastSync is the start of the last run that compared this node with every other shard without an error.
+	// Every write accepted by the cluster before then has reached this node.
+	LastSync time.Time
+	// PeerSync holds the start of the last run that compared this node with each other shard without an error, by shard ID
+	PeerSync map[string]time.Time
+}
+
+// antiEntropy holds the state of the anti-entropy job
+type antiEntropy struct {
+	sync.Mutex
+	stats AntiEntropyStats
+	stop  chan bool
+	// grouping is held while the digest groups of storage are rebuilt from placement and replicas
+	grouping  sync.Mutex
+	placement cluster.Placement
+	replicas  map[string]int
+}
+
+// StartAntiEntropy starts comparing this node's replicas with the rest of the cluster at the given interval
+func (s *DBServer) StartAntiEntropy(interval time.Duration) {
+	s.antiEntropy.Lock()
+	defer s.antiEntropy.Unlock()
+	if s.antiEntropy.stop != nil {
+		return
+	}
+	stop := make(chan bool)
+	s.antiEntropy.stop = stop
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				s.AntiEntropy()
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
+
+// stopAntiEntropy stops the anti-entropy job if it is running
+func (s *DBServer) stopAntiEntropy() {
+	s.antiEntropy.Lock()
+	defer s.antiEntropy.Unlock()
+	if s.antiEntropy.stop != nil {
+		close(s.antiEntropy.stop)
+		s.antiEntropy.stop = nil
+	}
+}
+
+// antiEntropyRunning returns true if the anti-entropy job is running
+func (s *DBServer) antiEntropyRunning() bool {
+	s.antiEntropy.Lock()
+	defer s.antiEntropy.Unlock()
+	return s.antiEntropy.stop != nil
+}
+
+// AntiEntropyStats returns statistics about the anti-entropy job
+func (s *DBServer) AntiEntropyStats() AntiEntropyStats {
+	s.antiEntropy.Lock()
+	defer s.antiEntropy.Unlock()
+	stats := s.antiEntropy.stats
+	stats.PeerSync = make(map[string]time.Time, len(s.antiEntropy.stats.PeerSync))
+	for id, synced := range s.antiEntropy.stats.PeerSync {
+		stats.PeerSync[id] = synced
+	}
+	return stats
+}
+
+// peerSync returns the start of the last run that compared this node with the given shard without an error
+func (s *DBServer) peerSync(shard cluster.Shard) time.Time {
+	s.antiEntropy.Lock()
+	defer s.antiEntropy.Unlock()
+	return s.antiEntropy.stats.PeerSync[shard.ID.String()]
+}
+
+// AntiEntropy compares this node's storage tree with every other shard in the cluster and repairs the keys that differ.
+// It returns the number of keys that were repaired, counting a key once for each replica it was exchanged with.
+func (s *DBServer) AntiEntropy() int {
+	if !s.replicated() {
+		return 0
+	}
+	start := time.Now()
+	s.dropRemovedNamespaces()
+	s.updateDigestGroups()
+	compared, repaired := 0, 0
+	synced := true
+	for _, shard := range s.cluster().Shards {
+		if s.isSelf(shard) {
+			continue
+		}
+		c, r, err := s.repairWith(shard)
+		compared += c
+		repaired += r
+		if err != nil {
+			synced = false
+			s.Logger.Warn("Anti-entropy failed", "shard", shard.ID.String(), "error", err)
+			continue
+		}
+		s.antiEntropy.Lock()
+		if s.antiEntropy.stats.PeerSync == nil {
+			s.antiEntropy.stats.PeerSync = make(map[string]time.Time)
+		}
+		s.antiEntropy.stats.PeerSync[shard.ID.String()] = start
+		s.antiEntropy.Unlock()
+	}
+
+	s.antiEntropy.Lock()
+	s.antiEntropy.stats.Runs++
+	s.antiEntropy.stats.LastRun = start
+	s.antiEntropy.stats.LastRunDuration = time.Since(start)
+	s.antiEntropy.stats.LastRunNodesCompared = compared
+	s.antiEntropy.stats.LastRunKeysRepaired = repaired
+	s.antiEntropy.stats.TotalKeysRepaired += uint64(repaired)
+	if synced {
+		s.antiEntropy.stats.LastSync = start
+	}
+	s.antiEntropy.Unlock()
+	s.checkMigration()
+
+	s.Logger.Info("Anti-entropy finished", "nodes_compared", compared, "keys_repaired", repaired, "duration", time.Since(start))
+	return repaired
+}
+
+// repairWith compares the storage tree of every namespace on this node and the given shard
+func (s *DBServer) repairWith(shard cluster.Shard) (int, int, error) {
+	c, err := s.peers.client(shard)
+	if err != nil {
+		return 0, 0, err
+	}
+	compared, repaired := 0, 0
+	for _, namespace := range s.namespaceNames() {
+		nc, nr, err := s.repairTree(c, shard, namespace)
+		compared += nc
+		repaired += nr
+		if err != nil {
+			return compared, repaired, err
+		}
+	}
+	return compared, repaired, nil
+}
+
+// repairTree walks the storage tree of a namespace on this node and the given shard from the root, descending only into nodes whose digests differ.
+// Both sides only include the keys they both replicate in their digests, so the trees match once those keys agree.
+func (s *DBServer) repairTree(c api.DatabaseClient, shard cluster.Shard, namespace string) (int, int, error) {
+	compared, repaired := 0, 0
+	shared := sharedWith(s.Self.ID, shard.ID)
+	pending := []storage.NodeLocator{{Namespace: namespace}}
+	for len(pending) > 0 {
+		id := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+
+		local := s.Storage.SharedDigest(id, shared)
+		ctx, cancel := context.WithTimeout(context.Background(), s.Replication.Timeout)
+		response, err := c.Digest(ctx, &api.NodeLocator{ID: id.ID, Bytes: uint32(id.Bytes), Namespace: id.Namespace, Shard: s.Self.ID.String()})
+		cancel()
+		if err != nil {
+			return compared, repaired, err
+		}
+		remote := FromDigestResponse(response)
+		compared++
+
+		if local.Digest == remote.Digest {
+			continue
+		}
+
+		if id.Bytes >= storage.LeafDepth {
+			r, err := s.repairLeaf(c, shard, local, remote)
+			repaired += r
+			if err != nil {
+				return compared, repaired, err
+			}
+			continue
+		}
+
+		for i := 0; i < 256; i++ {
+			b := byte(i)
+			if local.Children[b] != remote.Children[b] {
+				pending = append(pending, id.Child(b))
+			}
+		}
+	}
+	return compared, repaired, nil
+}
+
+// repairLeaf exchanges the versions of every key in a leaf that differs between this node and the given shard.
+// Keys that the shard is not a replica for are ignored.
+func (s *DBServer) repairLeaf(c api.DatabaseClient, shard cluster.Shard, local storage.NodeDigest, remote storage.NodeDigest) (int, error) {
+	localVersions := groupVersions(local.Values)
+	remoteVersions := groupVersions(remote.Values)
+	keys := make(map[string]bool)
+	for key := range localVersions {
+		keys[key] = true
+	}
+	for key := range remoteVersions {
+		keys[key] = true
+	}
+
+	repaired := 0
+	for key := range keys {
+		if storage.DigestVersions(localVersions[key]) == storage.DigestVersions(remoteVersions[key]) {
+			continue
+		}
+		if !s.sharesKey(shard, key) {
+			continue
+		}
+		if len(remoteVersions[key]) > 0 {
+			s.Storage.PutVersions(key, remoteVersions[key])
+		}
+		if len(localVersions[key]) > 0 {
+			ctx, cancel := context.WithTimeout(context.Background(), s.Replication.Timeout)
+			_, err := c.ReplicaSet(ctx, &api.VersionedRequest{ID: key, Siblings: ToSiblings(localVersions[key])})
+			cancel()
+			if err != nil {
+				return repaired, err
+			}
+		}
+		repaired++
+	}
+	return repaired, nil
+}
+
+// sharesKey returns true if both this node and the given shard are replicas for the given key
+func (s *DBServer) sharesKey(shard cluster.Shard, key string) bool {
+	self, other := false, false
+	for _, replica := range s.replicas(key) {
+		if s.isSelf(replica) {
+			self = true
+		}
+		if uuid.Equal(replica.ID, shard.ID) {
+			other = true
+		}
+	}
+	return self && other
+}
+
+// updateDigestGroups groups the keys in storage by the shards that replicate them so that two shards can compare just the keys they share.
+// The digests are only recalculated when the placement or a replication factor has changed since they were last grouped.
+func (s *DBServer) updateDigestGroups() {
+	if !s.replicated() {
+		return
+	}
+	p := s.placement()
+	replicas := map[string]int{"": s.Replication.N}
+	if configs, err := s.namespaceConfigs(false); err == nil {
+		for name, config := range configs {
+			replicas[name] = s.Replication.N
+			if config.Replicas > 0 {
+				replicas[name] = config.Replicas
+			}
+		}
+	}
+	s.antiEntropy.grouping.Lock()
+	defer s.antiEntropy.grouping.Unlock()
+	if s.antiEntropy.placement == p && reflect.DeepEqual(s.antiEntropy.replicas, replicas) {
+		return
+	}
+	s.Storage.SetDigestGroups(replicaSets(p, replicas))
+	s.antiEntropy.placement = p
+	s.antiEntropy.replicas = replicas
+}
+
+// replicaSets groups keys by the shards that replicate them.  A group is named by the sorted IDs of its shards.
+// Keys in namespaces missing from replicas use the replication factor of the default namespace.
+func replicaSets(p cluster.Placement, replicas map[string]int) storage.DigestGroups {
+	var lock sync.Mutex
+	names := make(map[string]string)
+	return func(key string) string {
+		namespace, _ := storage.SplitNamespace(key)
+		n, ok := replicas[namespace]
+		if !ok {
+			n = replicas[""]
+		}
+		shards := p.Replicas(key, n)
+		ids := make([]string, len(shards))
+		for i, shard := range shards {
+			ids[i] = shard.ID.String()
+		}
+		sort.Strings(ids)
+		name := strings.Join(ids, ",")
+		// Every node of the tree keeps the digests of its groups by name, so each name is only stored once
+		lock.Lock()
+		defer lock.Unlock()
+		if interned, ok := names[name]; ok {
+			return interned
+		}
+		names[name] = name
+		return name
+	}
+}
+
+// sharedWith selects the digest groups of the keys that both of the given shards replicate
+func sharedWith(a uuid.UUID, b uuid.UUID) storage.SharedGroups {
+	x, y := a.String(), b.String()
+	shared := make(map[string]bool)
+	return func(group string) bool {
+		ok, seen := shared[group]
+		if !seen {
+			ok = strings.Contains(group, x) && strings.Contains(group, y)
+			shared[group] = ok
+		}
+		return ok
+	}
+}
+
+func groupVersions(versions []storage.NodeKeyValuePair) map[string][]storage.NodeKeyValuePair {
+	grouped := make(map[string][]storage.NodeKeyValuePair)
+	for _, v := range versions {
+		grouped[v.Key] = append(grouped[v.Key], v)
+	}
+	return grouped
+}
+
+// Digest returns the digest of a node in this server's storage tree.
+// When the request names the shard asking for it, only the keys that shard also replicates are included.
+func (s *DBServer) Digest(ctx context.Context, request *api.NodeLocator) (*api.DigestResponse, error) {
+	var shared storage.SharedGroups
+	if request.Shard != "" && s.replicated() {
+		peer, err := uuid.FromString(request.Shard)
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid shard ID: %s", request.Shard)
+		}
+		s.updateDigestGroups()
+		shared = sharedWith(s.Self.ID, peer)
+	}
+	digest := s.Storage.SharedDigest(storage.NodeLocator{ID: request.ID, Bytes: byte(request.Bytes), Namespace: request.Namespace}, shared)
+	return ToDigestResponse(digest), nil
+}
+
+// ToDigestResponse converts a node digest to its API representation
+func ToDigestResponse(digest storage.NodeDigest) *api.DigestResponse {
+	response := &api.DigestResponse{
+		Node:     &api.NodeLocator{ID: digest.ID.ID, Bytes: uint32(digest.ID.Bytes), Namespace: digest.ID.Namespace},
+		Digest:   digest.Digest,
+		Children: make(map[uint32]uint64),
+		Values:   make([]*api.KeyVersions, 0),
+	}
+	for b, d := range digest.Children {
+		response.Children[uint32(b)] = d
+	}
+	for key, versions := range groupVersions(digest.Values) {
+		response.Values = append(response.Values, &api.KeyVersions{ID: key, Siblings: ToSiblings(versions)})
+	}
+	return response
+}
+
+// FromDigestResponse converts an API digest response to a node digest
+func FromDigestResponse(response *api.DigestResponse) storage.NodeDigest {
+	digest := storage.NodeDigest{
+		Digest:   response.Digest,
+		Children: make(map[byte]uint64),
+		Values:   make([]storage.NodeKeyValuePair, 0),
+	}
+	if response.Node != nil {
+		digest.ID = storage.NodeLocator{ID: response.Node.ID, Bytes: byte(response.Node.Bytes), Namespace: response.Node.Namespace}
+	}
+	for b, d := range response.Children {
+		digest.Children[byte(b)] = d
+	}
+	for _, v := range response.Values {
+		digest.Values = append(digest.Values, FromSiblings(v.ID, v.Siblings)...)
+	}
+	return digest
+}
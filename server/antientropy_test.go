@@ -0,0 +1,126 @@
+/******
+This file is part of Vaelen/DB.
+
+Copyright 2017, Andrew Young <andrew@vaelen.org>
+
+    Vaelen/DB is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+    Vaelen/DB is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+    You should have received a copy of the GNU General Public License
+along with Vaelen/DB.  If not, see <http://www.gnu.org/licenses/>.
+******/
+
+package server
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/satori/go.uuid"
+	"github.com/vaelen/db/api"
+	"github.com/vaelen/db/storage"
+
+	"golang.org/x/net/context"
+)
+
+// TestAntiEntropy tests that replicas which missed writes are repaired
+func TestAntiEntropy(t *testing.T) {
+	servers, stop := startCluster(t, 30130, 3)
+	defer stop()
+
+	// Write keys to a single replica, bypassing replication
+	for i := 0; i < 50; i++ {
+		key := fmt.Sprintf("key%d", i)
+		pair := storage.NodeKeyValuePair{
+			Key:     key,
+			Value:   key,
+			Version: storage.VersionVector{servers[0].Self.ID.String(): 1},
+		}
+		servers[0].Storage.PutVersions(key, []storage.NodeKeyValuePair{pair})
+	}
+
+	// The keys are pulled from the first replica and then pushed to the third
+	repaired := servers[1].AntiEntropy()
+	if repaired != 100 {
+		t.Errorf("Expected 100 keys to be repaired, got %d\n", repaired)
+	}
+	repaired = servers[2].AntiEntropy()
+	if repaired != 0 {
+		t.Errorf("Expected nothing to repair, got %d\n", repaired)
+	}
+
+	for i, s := range servers {
+		if s.Storage.Digest(storage.NodeLocator{}).Digest != servers[0].Storage.Digest(storage.NodeLocator{}).Digest {
+			t.Errorf("Replica %d does not match after repair\n", i)
+		}
+	}
+
+	if repaired := servers[0].AntiEntropy(); repaired != 0 {
+		t.Errorf("Expected nothing to repair, got %d\n", repaired)
+	}
+
+	stats := servers[0].AntiEntropyStats()
+	if stats.Runs != 1 || stats.LastRunKeysRepaired != 0 || stats.LastRunNodesCompared != 2 {
+		t.Errorf("Unexpected stats: %+v\n", stats)
+	}
+	if stats := servers[1].AntiEntropyStats(); stats.TotalKeysRepaired != 100 {
+		t.Errorf("Unexpected stats: %+v\n", stats)
+	}
+}
+
+// TestAntiEntropySharedKeys tests that shards which only share some of their keys compare digests over just those keys
+func TestAntiEntropySharedKeys(t *testing.T) {
+	servers, stop := startCluster(t, 30373, 4)
+	defer stop()
+	for _, s := range servers {
+		s.Replication.N = 2
+	}
+
+	ctx := context.Background()
+	for i := 0; i < 50; i++ {
+		key := fmt.Sprintf("key%d", i)
+		if _, err := servers[0].Set(ctx, &api.IDValueRequest{ID: key, Value: key, Consistency: api.Consistency_ALL}); err != nil {
+			t.Fatalf("Set Error: %s\n", err.Error())
+		}
+	}
+
+	// Replicas that agree on the keys they share only compare the roots of their trees
+	for i, s := range servers {
+		if repaired := s.AntiEntropy(); repaired != 0 {
+			t.Errorf("Server %d: expected nothing to repair, got %d\n", i, repaired)
+		}
+		if stats := s.AntiEntropyStats(); stats.LastRunNodesCompared != 3 {
+			t.Errorf("Server %d: expected 3 nodes to be compared, got %d\n", i, stats.LastRunNodesCompared)
+		}
+	}
+
+	// A key missing from one replica is found by descending a single path
+	replicas := servers[0].replicas("missing")
+	var primary, secondary *DBServer
+	for _, s := range servers {
+		if uuid.Equal(s.Self.ID, replicas[0].ID) {
+			primary = s
+		}
+		if uuid.Equal(s.Self.ID, replicas[1].ID) {
+			secondary = s
+		}
+	}
+	pair := storage.NodeKeyValuePair{Key: "missing", Value: "found", Version: storage.VersionVector{primary.Self.ID.String(): 1}}
+	primary.Storage.PutVersions("missing", []storage.NodeKeyValuePair{pair})
+	if repaired := secondary.AntiEntropy(); repaired != 1 {
+		t.Errorf("Expected 1 key to be repaired, got %d\n", repaired)
+	}
+	if stats := secondary.AntiEntropyStats(); stats.LastRunNodesCompared != 3+storage.LeafDepth {
+		t.Errorf("Expected %d nodes to be compared, got %d\n", 3+storage.LeafDepth, stats.LastRunNodesCompared)
+	}
+	if v := secondary.Storage.Get("missing"); v != "found" {
+		t.Errorf("Value: %q, Expected: found\n", v)
+	}
+}
@@ -0,0 +1,619 @@
+/******
+This file is part of Vaelen/DB.
+
+Copyright 2017, Andrew Young <andrew@vaelen.org>
+
+    Vaelen/DB is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+    Vaelen/DB is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+    You should have received a copy of the GNU General Public License
+along with Vaelen/DB.  If not, see <http://www.gnu.org/licenses/>.
+******/
+
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vaelen/db/api"
+	"github.com/vaelen/db/logging"
+	"github.com/vaelen/db/storage"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// DefaultAuditMaxSize is the size at which the audit log is rotated
+var DefaultAuditMaxSize int64 = 64 << 20
+
+// DefaultAuditMaxFiles is the number of rotated audit logs that are kept
+var DefaultAuditMaxFiles = 5
+
+// maxAuditLine is the longest audit entry that is read back
+const maxAuditLine = 1 << 20
+
+// auditedMethods are the methods that change data or configuration, which are recorded in the audit log
+var auditedMethods = map[string]bool{
+	"/api.Database/Set":                 true,
+	"/api.Database/Remove":              true,
+	"/api.Database/SetMany":             true,
+	"/api.Database/Txn":                 true,
+	"/api.Database/UpdateClusterConfig": true,
+	"/api.Database/CreateIndex":         true,
+	"/api.Database/DropIndex":           true,
+	"/api.Database/AddUser":             true,
+	"/api.Database/RemoveUser":          true,
+	"/api.Database/GrantRole":           true,
+	"/api.Database/RevokeRole":          true,
+	"/api.Database/CreateNamespace":     true,
+	"/api.Database/DropNamespace":       true,
+	"/api.Database/SetLimits":           true,
+	"/api.Database/SetLogLevel":         true,
+}
+
+// AuditEntry records a change made by a client
+type AuditEntry struct {
+	Time time.Time `json:"time"`
+	// Shard is the ID of the server that recorded the change
+	Shard string `json:"shard,omitempty"`
+	// Caller is the user that made the change, or the address of a client that has not authenticated
+	Caller    string `json:"caller"`
+	Address   string `json:"address,omitempty"`
+	Operation string `json:"operation"`
+	Namespace string `json:"namespace,omitempty"`
+	// Keys are the keys changed, or the names of the users, roles, indexes or components changed
+	Keys      []string `json:"keys,omitempty"`
+	RequestID string   `json:"request_id,omitempty"`
+	// Code is the gRPC status code of the outcome and Error its message when the change failed
+	Code  string `json:"code"`
+	Error string `json:"error,omitempty"`
+}
+
+// AuditQuery selects audit entries.  Empty fields match every entry.
+type AuditQuery struct {
+	// Key matches the entries that changed the key in Namespace
+	Key string
+	// Namespace matches the entries in the namespace when Key is empty
+	Namespace string
+	Caller    string
+	Operation string
+	// Since matches the entries recorded from the given time on
+	Since time.Time
+	// Limit is the maximum number of entries returned, the most recent are kept, or zero for no limit
+	Limit int
+}
+
+// matches returns true if the entry is selected by the query
+func (q AuditQuery) matches(e AuditEntry) bool {
+	if q.Key != "" || q.Namespace != "" {
+		if e.Namespace != q.Namespace {
+			return false
+		}
+	}
+	if q.Key != "" && !contains(e.Keys, q.Key) {
+		return false
+	}
+	if q.Caller != "" && e.Caller != q.Caller {
+		return false
+	}
+	if q.Operation != "" && !strings.EqualFold(e.Operation, q.Operation) {
+		return false
+	}
+	return !e.Time.Before(q.Since)
+}
+
+// contains returns true if the list holds the value
+func contains(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// AuditLog appends audit entries to a file as JSON lines.
+// Once the file grows past MaxSize it is renamed with the suffix .1, the older files move up by one and only MaxFiles of them are kept.
+type AuditLog struct {
+	sync.Mutex
+	Path     string
+	MaxSize  int64
+	MaxFiles int
+	// Keyring encrypts each entry, which is then written as a line of base64.  Entries are not encrypted when it is nil.
+	Keyring *storage.Keyring
+	file    *os.File
+	size    int64
+}
+
+// OpenAuditLog opens the audit log at the given path, creating it if needed
+func OpenAuditLog(path string, maxSize int64, maxFiles int) (*AuditLog, error) {
+	a := &AuditLog{
+		Path:     path,
+		MaxSize:  maxSize,
+		MaxFiles: maxFiles,
+	}
+	if err := a.open(); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// open opens the current file for appending.  The caller must hold the lock.
+// A line torn by a crash is cut off first, so that only the last line of a file can ever be torn.
+func (a *AuditLog) open() error {
+	file, err := os.OpenFile(a.Path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err == nil {
+		a.size, err = trimTorn(file, info.Size())
+	}
+	if err != nil {
+		file.Close()
+		return err
+	}
+	a.file = file
+	return nil
+}
+
+// trimTorn cuts a partial last line off the given file and returns its new size
+func trimTorn(file *os.File, size int64) (int64, error) {
+	buf := make([]byte, 4096)
+	for end := size; end > 0; {
+		start := end - int64(len(buf))
+		if start < 0 {
+			start = 0
+		}
+		n, err := file.ReadAt(buf[:end-start], start)
+		if err != nil {
+			return 0, err
+		}
+		if i := bytes.LastIndexByte(buf[:n], '\n'); i >= 0 {
+			end = start + int64(i) + 1
+			if end == size {
+				return size, nil
+			}
+			return end, file.Truncate(end)
+		}
+		end = start
+	}
+	return 0, file.Truncate(0)
+}
+
+// rotated returns the name of a rotated file, the newest being 1
+func (a *AuditLog) rotated(i int) string {
+	return fmt.Sprintf("%s.%d", a.Path, i)
+}
+
+// rotate renames the current file and starts a new one.  The caller must hold the lock.
+func (a *AuditLog) rotate() error {
+	if err := a.file.Close(); err != nil {
+		return err
+	}
+	a.file = nil
+	if a.MaxFiles > 0 {
+		os.Remove(a.rotated(a.MaxFiles))
+		for i := a.MaxFiles - 1; i > 0; i-- {
+			os.Rename(a.rotated(i), a.rotated(i+1))
+		}
+		if err := os.Rename(a.Path, a.rotated(1)); err != nil {
+			return err
+		}
+	} else if err := os.Remove(a.Path); err != nil {
+		return err
+	}
+	return a.open()
+}
+
+// encode returns the line an entry is written as
+func (a *AuditLog) encode(entry AuditEntry) ([]byte, error) {
+	b, err := json.Marshal(entry)
+	if err != nil || a.Keyring == nil {
+		return append(b, '\n'), err
+	}
+	sealed, err := a.Keyring.Seal(b)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(base64.StdEncoding.EncodeToString(sealed) + "\n"), nil
+}
+
+// decode returns the entry written as the given line.  Lines written before encryption was enabled are plain JSON.
+func (a *AuditLog) decode(line []byte) (AuditEntry, error) {
+	var entry AuditEntry
+	if !bytes.HasPrefix(line, []byte("{")) {
+		sealed, err := base64.StdEncoding.DecodeString(string(line))
+		if err != nil {
+			return entry, err
+		}
+		if line, err = a.Keyring.Open(sealed); err != nil {
+			return entry, err
+		}
+	}
+	err := json.Unmarshal(line, &entry)
+	return entry, err
+}
+
+// Record appends an entry to the log, rotating it first if the entry would take it past MaxSize
+func (a *AuditLog) Record(entry AuditEntry) error {
+	b, err := a.encode(entry)
+	if err != nil {
+		return err
+	}
+	a.Lock()
+	defer a.Unlock()
+	if a.file == nil {
+		if err := a.open(); err != nil {
+			return err
+		}
+	}
+	if a.MaxSize > 0 && a.size > 0 && a.size+int64(len(b)) > a.MaxSize {
+		if err := a.rotate(); err != nil {
+			return err
+		}
+	}
+	n, err := a.file.Write(b)
+	a.size += int64(n)
+	return err
+}
+
+// Query returns the entries selected by the query, oldest first.
+// Entries may be missed or repeated if the log is rotated while it is read.
+func (a *AuditLog) Query(query AuditQuery) ([]AuditEntry, error) {
+	var entries []AuditEntry
+	for i := a.MaxFiles; i >= 0; i-- {
+		name := a.Path
+		if i > 0 {
+			name = a.rotated(i)
+		}
+		file, err := os.Open(name)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		scanner := bufio.NewScanner(file)
+		scanner.Buffer(nil, maxAuditLine)
+		for scanner.Scan() {
+			// A line torn by a crash is skipped
+			entry, err := a.decode(scanner.Bytes())
+			if err != nil || !query.matches(entry) {
+				continue
+			}
+			entries = append(entries, entry)
+			if query.Limit > 0 && len(entries) > query.Limit {
+				entries = entries[1:]
+			}
+		}
+		err = scanner.Err()
+		file.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return entries, nil
+}
+
+// Check makes sure that the keyring has the data key of every file of the log, so that a server given the wrong key refuses
+// to start instead of dropping the entries it can not read when the log is encrypted again.
+// Only the first encrypted line of each file is read.
+func (a *AuditLog) Check() error {
+	if a == nil {
+		return nil
+	}
+	for i := 0; i <= a.MaxFiles; i++ {
+		name := a.Path
+		if i > 0 {
+			name = a.rotated(i)
+		}
+		if err := a.checkFile(name); err != nil {
+			return fmt.Errorf("%s: %s", name, err)
+		}
+	}
+	return nil
+}
+
+// checkFile decrypts the first encrypted line of the named file
+func (a *AuditLog) checkFile(name string) error {
+	file, err := os.Open(name)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, maxAuditLine)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 || bytes.HasPrefix(line, []byte("{")) {
+			continue
+		}
+		if _, err := a.decode(line); err == storage.ErrUnknownKey || err == storage.ErrNoKeyring {
+			return err
+		}
+		return nil
+	}
+	return scanner.Err()
+}
+
+// Reencrypt writes every file of the log again so that its entries are encrypted with the active data key.
+// Entries written before encryption was enabled are encrypted by it.  A line torn by a crash at the end of a file is dropped,
+// any other line that can not be read stops the log from being written again.
+func (a *AuditLog) Reencrypt() error {
+	if a == nil || a.Keyring == nil {
+		return nil
+	}
+	a.Lock()
+	defer a.Unlock()
+	if a.file != nil {
+		a.file.Close()
+		a.file = nil
+	}
+	for i := 0; i <= a.MaxFiles; i++ {
+		name := a.Path
+		if i > 0 {
+			name = a.rotated(i)
+		}
+		b, err := ioutil.ReadFile(name)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		var encrypted bytes.Buffer
+		lines := bytes.Split(b, []byte("\n"))
+		for j, line := range lines {
+			if len(line) == 0 {
+				continue
+			}
+			entry, err := a.decode(line)
+			if err == storage.ErrUnknownKey || err == storage.ErrNoKeyring {
+				return fmt.Errorf("%s: %s", name, err)
+			}
+			if err != nil {
+				// Only the last line can have been torn by a crash
+				if j == len(lines)-1 {
+					continue
+				}
+				return fmt.Errorf("%s: line %d: %s", name, j+1, err)
+			}
+			line, err = a.encode(entry)
+			if err != nil {
+				return err
+			}
+			encrypted.Write(line)
+		}
+		if err := ioutil.WriteFile(name+".tmp", encrypted.Bytes(), 0600); err != nil {
+			return err
+		}
+		if err := os.Rename(name+".tmp", name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close closes the current file.  Recording another entry opens it again.
+func (a *AuditLog) Close() error {
+	if a == nil {
+		return nil
+	}
+	a.Lock()
+	defer a.Unlock()
+	if a.file == nil {
+		return nil
+	}
+	err := a.file.Close()
+	a.file = nil
+	return err
+}
+
+// gatewayKey is the context key of the command received by a gateway
+type gatewayKey struct{}
+
+// gatewayCommand describes a command received by one of the gateways, which do not pass through UnaryInterceptor
+type gatewayCommand struct {
+	operation string
+	address   string
+}
+
+// withGatewayCommand returns a context for a command received by a gateway from the client at the given address
+func withGatewayCommand(ctx context.Context, operation string, address string) context.Context {
+	return context.WithValue(ctx, gatewayKey{}, gatewayCommand{operation: operation, address: address})
+}
+
+// callerAddress returns the address of the client that sent the request in ctx
+func callerAddress(ctx context.Context) string {
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		return p.Addr.String()
+	}
+	command, _ := ctx.Value(gatewayKey{}).(gatewayCommand)
+	return command.address
+}
+
+// callerName returns the name of the caller used by the audit and slow operation logs
+func callerName(ctx context.Context) string {
+	id := callerIdentity(ctx)
+	if id != nil && id.Node {
+		return "node"
+	}
+	return clientName(id, callerAddress(ctx))
+}
+
+// requestTarget returns the namespace and the keys, or the names of other things, that a request applies to
+func requestTarget(req interface{}) (string, []string) {
+	switch r := req.(type) {
+	case *api.IDRequest:
+		return r.Namespace, []string{r.ID}
+	case *api.IDValueRequest:
+		return r.Namespace, []string{r.ID}
+	case *api.SetManyRequest:
+		return r.Namespace, sortedIDs(r.Values)
+	case *api.VersionedRequest:
+		namespace, key := storage.SplitNamespace(r.ID)
+		return namespace, []string{key}
+	case *api.TxnRequest:
+		keys := make([]string, 0, len(r.Ops))
+		for _, op := range r.Ops {
+			keys = append(keys, op.ID)
+		}
+		return r.Namespace, keys
+	case *api.IndexDefinition:
+		return "", []string{r.Name}
+	case *api.UserRequest:
+		return "", []string{r.Username}
+	case *api.GrantRequest:
+		return "", []string{r.Role}
+	case *api.NamespaceRequest:
+		return r.Name, nil
+	case *api.LimitsRequest:
+		if r.Client != "" {
+			return "", []string{r.Client}
+		}
+		return r.Namespace, nil
+	case *api.LogLevelRequest:
+		return "", []string{r.Component}
+	}
+	return "", nil
+}
+
+// audit records a request in the audit log if it may have changed data or configuration.
+// Requests sent between nodes on behalf of another request are left out, the node that received the original request records it.
+func (s *DBServer) audit(ctx context.Context, method string, req interface{}, err error) {
+	if s.Audit == nil || !auditedMethods[method] {
+		return
+	}
+	if id := callerIdentity(ctx); id != nil && id.Node {
+		return
+	}
+	if r, ok := req.(interface{ GetLocal() bool }); ok && r.GetLocal() {
+		return
+	}
+	namespace, keys := requestTarget(req)
+	s.recordAudit(ctx, strings.TrimPrefix(method, "/api.Database/"), namespace, keys, err)
+}
+
+// auditCommand records a change to the given stored keys made by a command received by one of the gateways
+func (s *DBServer) auditCommand(ctx context.Context, keys []string, err error) {
+	command, ok := ctx.Value(gatewayKey{}).(gatewayCommand)
+	if s.Audit == nil || !ok {
+		return
+	}
+	namespace := ""
+	ids := make([]string, 0, len(keys))
+	for _, key := range keys {
+		var id string
+		namespace, id = storage.SplitNamespace(key)
+		ids = append(ids, id)
+	}
+	s.recordAudit(ctx, command.operation, namespace, ids, err)
+}
+
+// recordAudit writes an entry to the audit log
+func (s *DBServer) recordAudit(ctx context.Context, operation string, namespace string, keys []string, err error) {
+	entry := AuditEntry{
+		Time:      time.Now(),
+		Shard:     s.Self.ID.String(),
+		Caller:    callerName(ctx),
+		Address:   callerAddress(ctx),
+		Operation: operation,
+		Namespace: namespace,
+		Keys:      keys,
+		RequestID: logging.RequestID(ctx),
+		Code:      status.Code(err).String(),
+	}
+	if err != nil {
+		entry.Error = status.Convert(err).Message()
+	}
+	if err := s.Audit.Record(entry); err != nil {
+		s.Logger.ErrorContext(ctx, "Could not write audit log", "file", s.Audit.Path, "error", err)
+	}
+}
+
+// toAuditEntry converts an audit entry to its API representation
+func toAuditEntry(e AuditEntry) *api.AuditEntry {
+	return &api.AuditEntry{
+		Time:      e.Time.UnixNano(),
+		Shard:     e.Shard,
+		Caller:    e.Caller,
+		Address:   e.Address,
+		Operation: e.Operation,
+		Namespace: e.Namespace,
+		Keys:      e.Keys,
+		RequestId: e.RequestID,
+		Code:      e.Code,
+		Error:     e.Error,
+	}
+}
+
+// AuditLog returns the entries of the audit logs of every server that match the query, oldest first.
+// Servers that do not keep an audit log have no entries.
+func (s *DBServer) AuditLog(ctx context.Context, request *api.AuditQuery) (*api.AuditResponse, error) {
+	query := AuditQuery{
+		Key:       request.Key,
+		Namespace: request.Namespace,
+		Caller:    request.Caller,
+		Operation: request.Operation,
+		Limit:     int(request.Limit),
+	}
+	if request.Since != 0 {
+		query.Since = time.Unix(0, request.Since)
+	}
+	response := &api.AuditResponse{}
+	if s.Audit != nil {
+		entries, err := s.Audit.Query(query)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "could not read audit log: %s", err)
+		}
+		for _, e := range entries {
+			response.Entries = append(response.Entries, toAuditEntry(e))
+		}
+	}
+	if !request.Local && s.replicated() {
+		var lock sync.Mutex
+		failed := s.broadcast(ctx, codes.OK, func(ctx context.Context, c api.DatabaseClient) error {
+			peerRequest := *request
+			peerRequest.Local = true
+			remote, err := c.AuditLog(ctx, &peerRequest)
+			if err != nil {
+				return err
+			}
+			lock.Lock()
+			defer lock.Unlock()
+			response.Entries = append(response.Entries, remote.Entries...)
+			return nil
+		})
+		response.Unreachable = uint32(failed)
+		sort.SliceStable(response.Entries, func(i, j int) bool {
+			return response.Entries[i].Time < response.Entries[j].Time
+		})
+		if request.Limit > 0 && len(response.Entries) > int(request.Limit) {
+			response.Entries = response.Entries[len(response.Entries)-int(request.Limit):]
+		}
+	}
+	return response, nil
+}
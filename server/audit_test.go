@@ -0,0 +1,216 @@
+/******
+This file is part of Vaelen/DB.
+
+Copyright 2017, Andrew Young <andrew@vaelen.org>
+
+    Vaelen/DB is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+    Vaelen/DB is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+    You should have received a copy of the GNU General Public License
+along with Vaelen/DB.  If not, see <http://www.gnu.org/licenses/>.
+******/
+
+package server
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/vaelen/db/api"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+// TestAuditLog tests rotating and querying an audit log
+func TestAuditLog(t *testing.T) {
+	dir, err := ioutil.TempDir("", "vdb-audit")
+	if err != nil {
+		t.Fatalf("TempDir Error: %s\n", err.Error())
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "audit.log")
+	a, err := OpenAuditLog(path, 1024, 2)
+	if err != nil {
+		t.Fatalf("OpenAuditLog Error: %s\n", err.Error())
+	}
+	defer a.Close()
+
+	start := time.Now()
+	for i := 0; i < 30; i++ {
+		err := a.Record(AuditEntry{
+			Time:      start.Add(time.Duration(i) * time.Second),
+			Caller:    fmt.Sprintf("user%d", i%2),
+			Operation: "Set",
+			Keys:      []string{fmt.Sprintf("key%d", i)},
+			Code:      codes.OK.String(),
+		})
+		if err != nil {
+			t.Fatalf("Record Error: %s\n", err.Error())
+		}
+	}
+	for _, name := range []string{path, path + ".1", path + ".2"} {
+		info, err := os.Stat(name)
+		if err != nil {
+			t.Fatalf("Expected %s to exist: %s\n", name, err.Error())
+		}
+		if info.Size() > 1024 {
+			t.Errorf("%s is larger than the maximum size: %d\n", name, info.Size())
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("Expected only two rotated files to be kept\n")
+	}
+
+	entries, err := a.Query(AuditQuery{})
+	if err != nil {
+		t.Fatalf("Query Error: %s\n", err.Error())
+	}
+	if len(entries) == 0 || len(entries) >= 30 || entries[len(entries)-1].Keys[0] != "key29" {
+		t.Fatalf("Expected the newest entries to be kept, got %d\n", len(entries))
+	}
+	for i := 1; i < len(entries); i++ {
+		if !entries[i].Time.After(entries[i-1].Time) {
+			t.Fatalf("Entries are out of order: %v\n", entries)
+		}
+	}
+
+	entries, err = a.Query(AuditQuery{Caller: "user1", Limit: 2})
+	if err != nil {
+		t.Fatalf("Query Error: %s\n", err.Error())
+	}
+	if len(entries) != 2 || entries[0].Keys[0] != "key27" || entries[1].Keys[0] != "key29" {
+		t.Fatalf("Unexpected entries: %v\n", entries)
+	}
+	entries, err = a.Query(AuditQuery{Key: "key28", Since: start.Add(28 * time.Second)})
+	if err != nil {
+		t.Fatalf("Query Error: %s\n", err.Error())
+	}
+	if len(entries) != 1 || entries[0].Caller != "user0" {
+		t.Fatalf("Unexpected entries: %v\n", entries)
+	}
+
+	// A line torn by a crash is cut off when the log is opened again
+	a.Close()
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		t.Fatalf("OpenFile Error: %s\n", err.Error())
+	}
+	f.Write([]byte(`{"Operation":"Se`))
+	f.Close()
+	reopened, err := OpenAuditLog(path, 1024, 2)
+	if err != nil {
+		t.Fatalf("OpenAuditLog Error: %s\n", err.Error())
+	}
+	defer reopened.Close()
+	if err := reopened.Record(AuditEntry{Time: start.Add(30 * time.Second), Operation: "Get", Keys: []string{"key30"}}); err != nil {
+		t.Fatalf("Record Error: %s\n", err.Error())
+	}
+	entries, err = reopened.Query(AuditQuery{Since: start.Add(29 * time.Second)})
+	if err != nil {
+		t.Fatalf("Query Error: %s\n", err.Error())
+	}
+	if len(entries) != 2 || entries[0].Keys[0] != "key29" || entries[1].Keys[0] != "key30" {
+		t.Fatalf("Unexpected entries after a torn line: %v\n", entries)
+	}
+}
+
+// TestAudit tests that changes made through gRPC and the gateways are recorded and can be queried
+func TestAudit(t *testing.T) {
+	dir, err := ioutil.TempDir("", "vdb-audit")
+	if err != nil {
+		t.Fatalf("TempDir Error: %s\n", err.Error())
+	}
+	defer os.RemoveAll(dir)
+	s := New(testLogs, "")
+	defer s.Stop()
+	if s.Audit, err = OpenAuditLog(filepath.Join(dir, "audit.log"), DefaultAuditMaxSize, DefaultAuditMaxFiles); err != nil {
+		t.Fatalf("OpenAuditLog Error: %s\n", err.Error())
+	}
+	defer s.Audit.Close()
+
+	lis, err := net.Listen("tcp", "localhost:30370")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	grpcServer := grpc.NewServer(grpc.UnaryInterceptor(s.UnaryInterceptor))
+	api.RegisterDatabaseServer(grpcServer, s)
+	go grpcServer.Serve(lis)
+	defer grpcServer.Stop()
+
+	conn, err := grpc.Dial("localhost:30370", grpc.WithInsecure())
+	if err != nil {
+		t.Fatalf("Dial Error: %s\n", err.Error())
+	}
+	defer conn.Close()
+	c := api.NewDatabaseClient(conn)
+	ctx := context.Background()
+
+	if _, err := c.Set(ctx, &api.IDValueRequest{ID: "foo", Value: "bar"}); err != nil {
+		t.Fatalf("Set Error: %s\n", err.Error())
+	}
+	if _, err := c.Get(ctx, &api.IDRequest{ID: "foo"}); err != nil {
+		t.Fatalf("Get Error: %s\n", err.Error())
+	}
+	if _, err := c.Set(ctx, &api.IDValueRequest{ID: "foo", Value: "bar", Namespace: "missing"}); err == nil {
+		t.Fatalf("Expected a write to a missing namespace to fail\n")
+	}
+
+	ts := httptest.NewServer(NewHTTPGateway(s))
+	defer ts.Close()
+	request, _ := http.NewRequest("DELETE", ts.URL+"/v1/keys/foo", nil)
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		t.Fatalf("DELETE Error: %s\n", err.Error())
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusNoContent {
+		t.Fatalf("DELETE Status: %d\n", response.StatusCode)
+	}
+
+	audit, err := c.AuditLog(ctx, &api.AuditQuery{Key: "foo"})
+	if err != nil {
+		t.Fatalf("AuditLog Error: %s\n", err.Error())
+	}
+	if len(audit.Entries) != 2 {
+		t.Fatalf("Expected the write and the delete to be recorded, got %v\n", audit.Entries)
+	}
+	set, remove := audit.Entries[0], audit.Entries[1]
+	if set.Operation != "Set" || set.Caller != "127.0.0.1" || set.Code != codes.OK.String() || set.RequestId == "" {
+		t.Errorf("Unexpected entry: %v\n", set)
+	}
+	if remove.Operation != "HTTP DELETE" || remove.Caller != "127.0.0.1" || remove.Time < set.Time {
+		t.Errorf("Unexpected entry: %v\n", remove)
+	}
+
+	audit, err = c.AuditLog(ctx, &api.AuditQuery{Namespace: "missing"})
+	if err != nil {
+		t.Fatalf("AuditLog Error: %s\n", err.Error())
+	}
+	if len(audit.Entries) != 1 || audit.Entries[0].Code == codes.OK.String() || audit.Entries[0].Error == "" {
+		t.Fatalf("Expected the failed write to be recorded, got %v\n", audit.Entries)
+	}
+
+	audit, err = c.AuditLog(ctx, &api.AuditQuery{Operation: "get"})
+	if err != nil {
+		t.Fatalf("AuditLog Error: %s\n", err.Error())
+	}
+	if len(audit.Entries) != 0 {
+		t.Fatalf("Expected reads not to be recorded, got %v\n", audit.Entries)
+	}
+}
@@ -0,0 +1,692 @@
+/******
+This file is part of Vaelen/DB.
+
+Copyright 2017, Andrew Young <andrew@vaelen.org>
+
+    Vaelen/DB is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+    Vaelen/DB is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+    You should have received a copy of the GNU General Public License
+along with Vaelen/DB.  If not, see <http://www.gnu.org/licenses/>.
+******/
+
+package server
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vaelen/db/api"
+	"github.com/vaelen/db/storage"
+	"github.com/vaelen/db/trace"
+
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// AdminRole has every permission on every key.  It is built in and can not be changed.
+	AdminRole = "admin"
+	// AdminUser can log in with Auth.AdminPassword until a user with the same name is added
+	AdminUser = "admin"
+	usersKey  = ReservedPrefix + "users"
+	rolesKey  = ReservedPrefix + "roles"
+	// tokensPrefix is followed by the digest of each token returned by Login
+	tokensPrefix = ReservedPrefix + "tokens/"
+	// authorizationHeader is the metadata key credentials are sent in
+	authorizationHeader = "authorization"
+	loginMethod         = "/api.Database/Login"
+)
+
+var (
+	// DefaultTokenTTL is how long the tokens returned by Login are accepted
+	DefaultTokenTTL = 24 * time.Hour
+	// DefaultAuthCacheTTL is how long users, roles and verified credentials are cached
+	DefaultAuthCacheTTL = 5 * time.Second
+)
+
+// methodPermissions is the permission needed to call each method, on every key in the request or on every key when the request has none.
+// Methods that are not listed may only be called by other nodes.  Login may be called by anyone.
+var methodPermissions = map[string]api.Permission{
+	"/api.Database/Time":                api.Permission_NONE,
+	"/api.Database/Get":                 api.Permission_READ,
+	"/api.Database/Set":                 api.Permission_WRITE,
+	"/api.Database/Remove":              api.Permission_WRITE,
+	"/api.Database/GetMany":             api.Permission_READ,
+	"/api.Database/SetMany":             api.Permission_WRITE,
+	"/api.Database/Txn":                 api.Permission_WRITE,
+	"/api.Database/Members":             api.Permission_NONE,
+	"/api.Database/GetClusterConfig":    api.Permission_NONE,
+	"/api.Database/WatchClusterConfig":  api.Permission_NONE,
+	"/api.Database/UpdateClusterConfig": api.Permission_ADMIN,
+	"/api.Database/CreateIndex":         api.Permission_ADMIN,
+	"/api.Database/DropIndex":           api.Permission_ADMIN,
+	"/api.Database/Indexes":             api.Permission_NONE,
+	// QueryIndex only returns the keys the caller can read
+	"/api.Database/QueryIndex":      api.Permission_NONE,
+	"/api.Database/AddUser":         api.Permission_ADMIN,
+	"/api.Database/RemoveUser":      api.Permission_ADMIN,
+	"/api.Database/Users":           api.Permission_ADMIN,
+	"/api.Database/GrantRole":       api.Permission_ADMIN,
+	"/api.Database/RevokeRole":      api.Permission_ADMIN,
+	"/api.Database/Roles":           api.Permission_ADMIN,
+	"/api.Database/CreateNamespace": api.Permission_ADMIN,
+	"/api.Database/DropNamespace":   api.Permission_ADMIN,
+	"/api.Database/ListNamespaces":  api.Permission_NONE,
+	"/api.Database/SetLimits":       api.Permission_ADMIN,
+	"/api.Database/Limits":          api.Permission_ADMIN,
+	"/api.Database/SetLogLevel":     api.Permission_ADMIN,
+	"/api.Database/LogLevels":       api.Permission_ADMIN,
+	"/api.Database/AuditLog":        api.Permission_ADMIN,
+	"/api.Database/SlowOperations":  api.Permission_ADMIN,
+	"/api.Database/PlacementLoad":   api.Permission_ADMIN,
+}
+
+// Auth authenticates callers and checks their permissions against the users and roles kept under ReservedPrefix.
+// Users send "Basic" credentials or a "Bearer" token returned by Login in the authorization metadata.
+// Other nodes send "Node" followed by the cluster secret.
+// Changes made through another node are seen once the cache expires.
+type Auth struct {
+	// ClusterSecret is shared by every node in the cluster and authenticates the requests they send each other
+	ClusterSecret string
+	// AdminPassword lets AdminUser log in until a user with that name is added, so that the first users can be created.
+	// It is ignored when empty.
+	AdminPassword string
+	// TokenTTL is how long the tokens returned by Login are accepted
+	TokenTTL time.Duration
+	// CacheTTL is how long users, roles and verified credentials are cached
+	CacheTTL time.Duration
+	lock     sync.Mutex
+	users    map[string]*User
+	roles    map[string][]Grant
+	loaded   time.Time
+	// sessions holds verified credentials by their digest
+	sessions map[string]session
+}
+
+// User is an account that can log in
+type User struct {
+	// Password is the bcrypt hash of the user's password
+	Password []byte   `json:"password"`
+	Roles    []string `json:"roles"`
+}
+
+// Grant gives a permission on every key that starts with a prefix
+type Grant struct {
+	Prefix     string         `json:"prefix"`
+	Permission api.Permission `json:"permission"`
+}
+
+// Identity is an authenticated caller
+type Identity struct {
+	User string
+	// Node is true for other nodes in the cluster, which may call any method
+	Node   bool
+	grants []Grant
+}
+
+// session is a cached identity
+type session struct {
+	identity *Identity
+	expires  time.Time
+}
+
+// token is the record kept for each token returned by Login
+type token struct {
+	User string `json:"user"`
+	// Expires is in Unix nanoseconds
+	Expires int64 `json:"expires"`
+}
+
+// NewAuth creates an authenticator that accepts the given cluster secret from other nodes
+func NewAuth(clusterSecret string) *Auth {
+	return &Auth{
+		ClusterSecret: clusterSecret,
+		TokenTTL:      DefaultTokenTTL,
+		CacheTTL:      DefaultAuthCacheTTL,
+		sessions:      make(map[string]session),
+	}
+}
+
+// Can returns true if the identity has the given permission on a key
+func (id *Identity) Can(p api.Permission, key string) bool {
+	if id.Node {
+		return true
+	}
+	if strings.HasPrefix(key, ReservedPrefix) {
+		return false
+	}
+	if p == api.Permission_NONE {
+		return true
+	}
+	for _, g := range id.grants {
+		if g.Permission >= p && strings.HasPrefix(key, g.Prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// identityKey is the context key the caller's identity is stored under
+type identityKey struct{}
+
+// withIdentity returns a context carrying the caller's identity
+func withIdentity(ctx context.Context, id *Identity) context.Context {
+	return context.WithValue(ctx, identityKey{}, id)
+}
+
+// callerIdentity returns the caller's identity, or nil if the request was not authenticated
+func callerIdentity(ctx context.Context) *Identity {
+	id, _ := ctx.Value(identityKey{}).(*Identity)
+	return id
+}
+
+// digest returns a hex encoded SHA-256 digest of a credential
+func digest(credential string) string {
+	sum := sha256.Sum256([]byte(credential))
+	return hex.EncodeToString(sum[:])
+}
+
+// forget drops every cached user, role and credential
+func (a *Auth) forget() {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	a.users = nil
+	a.roles = nil
+	a.sessions = make(map[string]session)
+}
+
+// cached returns the identity cached for a credential digest
+func (a *Auth) cached(d string) *Identity {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	cached, ok := a.sessions[d]
+	if !ok {
+		return nil
+	}
+	if time.Now().After(cached.expires) {
+		delete(a.sessions, d)
+		return nil
+	}
+	return cached.identity
+}
+
+// cache remembers the identity verified for a credential digest
+func (a *Auth) cache(d string, id *Identity, expires time.Time) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	a.sessions[d] = session{identity: id, expires: expires}
+}
+
+// updateAuth updates the users or roles and drops everything cached from them
+func (s *DBServer) updateAuth(key string, v interface{}, f func() error) error {
+	if err := s.updateReserved(key, v, f); err != nil {
+		return err
+	}
+	s.Auth.forget()
+	return nil
+}
+
+// authData returns the users and roles, reading them again once the cache has expired
+func (s *DBServer) authData() (map[string]*User, map[string][]Grant, error) {
+	a := s.Auth
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	if a.users != nil && time.Since(a.loaded) < a.CacheTTL {
+		return a.users, a.roles, nil
+	}
+	users := make(map[string]*User)
+	if _, err := s.readReserved(usersKey, &users); err != nil {
+		return nil, nil, err
+	}
+	roles := make(map[string][]Grant)
+	if _, err := s.readReserved(rolesKey, &roles); err != nil {
+		return nil, nil, err
+	}
+	a.users = users
+	a.roles = roles
+	a.loaded = time.Now()
+	return users, roles, nil
+}
+
+// identity returns the identity of a user
+func (s *DBServer) identity(name string) (*Identity, error) {
+	users, roles, err := s.authData()
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	if user, ok := users[name]; ok {
+		names = user.Roles
+	} else if name == AdminUser && s.Auth.AdminPassword != "" {
+		names = []string{AdminRole}
+	} else {
+		return nil, status.Errorf(codes.Unauthenticated, "unknown user: %s", name)
+	}
+	id := &Identity{User: name}
+	for _, role := range names {
+		if role == AdminRole {
+			id.grants = append(id.grants, Grant{Permission: api.Permission_ADMIN})
+			continue
+		}
+		id.grants = append(id.grants, roles[role]...)
+	}
+	return id, nil
+}
+
+// checkPassword returns the identity of a user if the password is correct
+func (s *DBServer) checkPassword(name string, password string) (*Identity, error) {
+	users, _, err := s.authData()
+	if err != nil {
+		return nil, err
+	}
+	invalid := status.Errorf(codes.Unauthenticated, "invalid username or password")
+	user, ok := users[name]
+	switch {
+	case ok:
+		if bcrypt.CompareHashAndPassword(user.Password, []byte(password)) != nil {
+			return nil, invalid
+		}
+	case name == AdminUser && s.Auth.AdminPassword != "":
+		if subtle.ConstantTimeCompare([]byte(password), []byte(s.Auth.AdminPassword)) != 1 {
+			return nil, invalid
+		}
+	default:
+		return nil, invalid
+	}
+	return s.identity(name)
+}
+
+// checkToken returns the identity of the user a token was issued to, along with when the token expires
+func (s *DBServer) checkToken(t string) (*Identity, time.Time, error) {
+	key := tokensPrefix + digest(t)
+	var record token
+	if _, err := s.readReserved(key, &record); err != nil {
+		return nil, time.Time{}, err
+	}
+	expires := time.Unix(0, record.Expires)
+	if record.User == "" || time.Now().After(expires) {
+		return nil, time.Time{}, status.Errorf(codes.Unauthenticated, "invalid or expired token")
+	}
+	id, err := s.identity(record.User)
+	return id, expires, err
+}
+
+// authenticate returns the identity of a caller that sent the given authorization metadata
+func (s *DBServer) authenticate(authorization string) (*Identity, error) {
+	a := s.Auth
+	scheme, credential := authorization, ""
+	if i := strings.IndexByte(authorization, ' '); i >= 0 {
+		scheme, credential = authorization[:i], strings.TrimSpace(authorization[i+1:])
+	}
+	switch strings.ToLower(scheme) {
+	case "":
+		return nil, status.Errorf(codes.Unauthenticated, "authentication required")
+	case "node":
+		if a.ClusterSecret == "" || subtle.ConstantTimeCompare([]byte(credential), []byte(a.ClusterSecret)) != 1 {
+			return nil, status.Errorf(codes.Unauthenticated, "invalid cluster secret")
+		}
+		return &Identity{Node: true}, nil
+	case "basic", "bearer":
+	default:
+		return nil, status.Errorf(codes.Unauthenticated, "unsupported authorization scheme: %s", scheme)
+	}
+
+	d := digest(authorization)
+	if id := a.cached(d); id != nil {
+		return id, nil
+	}
+	expires := time.Now().Add(a.CacheTTL)
+	var id *Identity
+	var err error
+	if strings.EqualFold(scheme, "basic") {
+		b, decodeErr := base64.StdEncoding.DecodeString(credential)
+		pair := strings.SplitN(string(b), ":", 2)
+		if decodeErr != nil || len(pair) != 2 {
+			return nil, status.Errorf(codes.Unauthenticated, "invalid basic credentials")
+		}
+		id, err = s.checkPassword(pair[0], pair[1])
+	} else {
+		var tokenExpires time.Time
+		id, tokenExpires, err = s.checkToken(credential)
+		if tokenExpires.Before(expires) {
+			expires = tokenExpires
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	a.cache(d, id, expires)
+	return id, nil
+}
+
+// authorizeIdentity returns an error unless the identity has the given permission on every key, or on every key when none are given.
+// It always succeeds when authentication is disabled.
+func (s *DBServer) authorizeIdentity(id *Identity, p api.Permission, keys ...string) error {
+	if s.Auth == nil {
+		return nil
+	}
+	if id == nil {
+		return status.Errorf(codes.Unauthenticated, "authentication required")
+	}
+	if len(keys) == 0 {
+		if !id.Can(p, "") {
+			return status.Errorf(codes.PermissionDenied, "%s does not have %s permission", id.User, p)
+		}
+		return nil
+	}
+	for _, key := range keys {
+		if !id.Can(p, key) {
+			return status.Errorf(codes.PermissionDenied, "%s does not have %s permission on %s", id.User, p, key)
+		}
+	}
+	return nil
+}
+
+// authorize checks the permissions of the caller whose identity is in the context
+func (s *DBServer) authorize(ctx context.Context, p api.Permission, keys ...string) error {
+	return s.authorizeIdentity(callerIdentity(ctx), p, keys...)
+}
+
+// requestKeys returns the internal keys a request touches, so that a grant on "__ns/<name>/" covers a whole namespace
+func requestKeys(req interface{}) []string {
+	switch r := req.(type) {
+	case *api.IDRequest:
+		return []string{storage.NamespaceKey(r.Namespace, r.ID)}
+	case *api.IDValueRequest:
+		return []string{storage.NamespaceKey(r.Namespace, r.ID)}
+	case *api.GetManyRequest:
+		keys := make([]string, 0, len(r.IDs))
+		for _, id := range r.IDs {
+			keys = append(keys, storage.NamespaceKey(r.Namespace, id))
+		}
+		return keys
+	case *api.SetManyRequest:
+		keys := make([]string, 0, len(r.Values))
+		for id := range r.Values {
+			keys = append(keys, storage.NamespaceKey(r.Namespace, id))
+		}
+		return keys
+	case *api.TxnRequest:
+		keys := make([]string, 0, len(r.Ops))
+		for _, op := range r.Ops {
+			keys = append(keys, storage.NamespaceKey(r.Namespace, op.ID))
+		}
+		return keys
+	}
+	return nil
+}
+
+// authorizeCall authenticates the caller of a method and checks its permissions.
+// It returns a context carrying the caller's identity.
+func (s *DBServer) authorizeCall(ctx context.Context, method string, keys []string) (context.Context, error) {
+	if method == loginMethod {
+		return ctx, nil
+	}
+	authorization := ""
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md[authorizationHeader]; len(values) > 0 {
+			authorization = values[0]
+		}
+	}
+	id, err := s.authenticate(authorization)
+	if err != nil {
+		return ctx, err
+	}
+	p, ok := methodPermissions[method]
+	if !ok && !id.Node {
+		return ctx, status.Errorf(codes.PermissionDenied, "%s may only be called by other nodes", method)
+	}
+	return withIdentity(ctx, id), s.authorizeIdentity(id, p, keys...)
+}
+
+// authStream is a server stream carrying the caller's identity
+type authStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (a *authStream) Context() context.Context {
+	return a.ctx
+}
+
+// StreamInterceptor authenticates the callers of streaming methods when authentication is enabled, records how long the streams took and traces them when tracing is enabled.
+// Streams to the health and reflection services are passed straight through.
+func (s *DBServer) StreamInterceptor(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if !isDatabaseMethod(info.FullMethod) {
+		return handler(srv, stream)
+	}
+	start := time.Now()
+	ctx, span := s.Tracer.StartServer(receiveRequestID(stream.Context()), info.FullMethod)
+	defer span.Finish()
+	stream = &authStream{ServerStream: stream, ctx: ctx}
+	err := s.interceptStream(srv, stream, info, handler)
+	trace.Status(span, err)
+	s.metrics.observe(info.FullMethod, status.Code(err), time.Since(start))
+	return err
+}
+
+// interceptStream waits for the server to load and authorizes a streaming request
+func (s *DBServer) interceptStream(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if err := s.waitLoaded(stream.Context()); err != nil {
+		return err
+	}
+	if s.Auth == nil {
+		return handler(srv, stream)
+	}
+	ctx, err := s.authorizeCall(stream.Context(), info.FullMethod, nil)
+	if err != nil {
+		return err
+	}
+	return handler(srv, &authStream{ServerStream: stream, ctx: ctx})
+}
+
+// nodeCredentials attaches the cluster secret to a request sent to another node
+func (s *DBServer) nodeCredentials(ctx context.Context) context.Context {
+	if s.Auth == nil || s.Auth.ClusterSecret == "" {
+		return ctx
+	}
+	return metadata.AppendToOutgoingContext(ctx, authorizationHeader, "Node "+s.Auth.ClusterSecret)
+}
+
+// requireAuth returns an error if authentication is disabled
+func (s *DBServer) requireAuth() error {
+	if s.Auth == nil {
+		return status.Errorf(codes.FailedPrecondition, "authentication is not enabled")
+	}
+	return nil
+}
+
+// Login checks a user's password and returns a token the user can authenticate with until it expires
+func (s *DBServer) Login(ctx context.Context, request *api.LoginRequest) (*api.LoginResponse, error) {
+	if err := s.requireAuth(); err != nil {
+		return nil, err
+	}
+	if _, err := s.checkPassword(request.Username, request.Password); err != nil {
+		return nil, err
+	}
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	t := hex.EncodeToString(b)
+	expires := time.Now().Add(s.Auth.TokenTTL)
+	// The record expires with the token, so that the records of tokens that are no longer used do not pile up
+	if err := s.writeReservedUntil(tokensPrefix+digest(t), token{User: request.Username, Expires: expires.UnixNano()}, nil, expires); err != nil {
+		return nil, err
+	}
+	return &api.LoginResponse{Token: t, Expires: expires.UnixNano()}, nil
+}
+
+// AddUser creates a user or replaces its password and roles
+func (s *DBServer) AddUser(ctx context.Context, request *api.UserRequest) (*api.UserInfo, error) {
+	if err := s.requireAuth(); err != nil {
+		return nil, err
+	}
+	if request.Username == "" || strings.ContainsAny(request.Username, ": ") {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid username: %q", request.Username)
+	}
+	if request.Password == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "a password is required")
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(request.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid password: %s", err)
+	}
+	users := make(map[string]*User)
+	err = s.updateAuth(usersKey, &users, func() error {
+		users[request.Username] = &User{Password: hash, Roles: request.Roles}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.Logger.InfoContext(ctx, "User added", "user", request.Username, "roles", request.Roles)
+	return &api.UserInfo{Username: request.Username, Roles: request.Roles}, nil
+}
+
+// RemoveUser deletes a user.  Tokens the user already has stop working once they leave the cache.
+func (s *DBServer) RemoveUser(ctx context.Context, request *api.UserRequest) (*api.UserInfo, error) {
+	if err := s.requireAuth(); err != nil {
+		return nil, err
+	}
+	var removed *User
+	users := make(map[string]*User)
+	err := s.updateAuth(usersKey, &users, func() error {
+		var ok bool
+		if removed, ok = users[request.Username]; !ok {
+			return status.Errorf(codes.NotFound, "unknown user: %s", request.Username)
+		}
+		delete(users, request.Username)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.Logger.InfoContext(ctx, "User removed", "user", request.Username)
+	return &api.UserInfo{Username: request.Username, Roles: removed.Roles}, nil
+}
+
+// Users returns every user and their roles
+func (s *DBServer) Users(ctx context.Context, request *api.EmptyRequest) (*api.UsersResponse, error) {
+	if err := s.requireAuth(); err != nil {
+		return nil, err
+	}
+	users, _, err := s.authData()
+	if err != nil {
+		return nil, err
+	}
+	response := &api.UsersResponse{Users: make([]*api.UserInfo, 0, len(users))}
+	for name, user := range users {
+		response.Users = append(response.Users, &api.UserInfo{Username: name, Roles: user.Roles})
+	}
+	sort.Slice(response.Users, func(i, j int) bool { return response.Users[i].Username < response.Users[j].Username })
+	return response, nil
+}
+
+// toRoleInfo converts a role to its API representation
+func toRoleInfo(name string, grants []Grant) *api.RoleInfo {
+	info := &api.RoleInfo{Name: name, Grants: make([]*api.Grant, 0, len(grants))}
+	for _, g := range grants {
+		info.Grants = append(info.Grants, &api.Grant{Prefix: g.Prefix, Permission: g.Permission})
+	}
+	return info
+}
+
+// GrantRole gives a role a permission on every key starting with a prefix, replacing any permission it had on the same prefix
+func (s *DBServer) GrantRole(ctx context.Context, request *api.GrantRequest) (*api.RoleInfo, error) {
+	if err := s.requireAuth(); err != nil {
+		return nil, err
+	}
+	if request.Role == "" || request.Role == AdminRole {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid role: %q", request.Role)
+	}
+	if request.Permission < api.Permission_READ || request.Permission > api.Permission_ADMIN {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid permission: %s", request.Permission)
+	}
+	roles := make(map[string][]Grant)
+	err := s.updateAuth(rolesKey, &roles, func() error {
+		grants := make([]Grant, 0, len(roles[request.Role])+1)
+		for _, g := range roles[request.Role] {
+			if g.Prefix != request.Prefix {
+				grants = append(grants, g)
+			}
+		}
+		roles[request.Role] = append(grants, Grant{Prefix: request.Prefix, Permission: request.Permission})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.Logger.InfoContext(ctx, "Role granted", "role", request.Role, "prefix", request.Prefix, "permission", request.Permission.String())
+	return toRoleInfo(request.Role, roles[request.Role]), nil
+}
+
+// RevokeRole takes away a role's permission on a prefix.  The role is deleted when it has no permissions left.
+func (s *DBServer) RevokeRole(ctx context.Context, request *api.GrantRequest) (*api.RoleInfo, error) {
+	if err := s.requireAuth(); err != nil {
+		return nil, err
+	}
+	roles := make(map[string][]Grant)
+	err := s.updateAuth(rolesKey, &roles, func() error {
+		grants := make([]Grant, 0, len(roles[request.Role]))
+		for _, g := range roles[request.Role] {
+			if g.Prefix != request.Prefix {
+				grants = append(grants, g)
+			}
+		}
+		if len(grants) == len(roles[request.Role]) {
+			return status.Errorf(codes.NotFound, "role %s has no permission on %q", request.Role, request.Prefix)
+		}
+		if len(grants) == 0 {
+			delete(roles, request.Role)
+		} else {
+			roles[request.Role] = grants
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.Logger.InfoContext(ctx, "Role revoked", "role", request.Role, "prefix", request.Prefix)
+	return toRoleInfo(request.Role, roles[request.Role]), nil
+}
+
+// Roles returns every role and its permissions, starting with the built in admin role
+func (s *DBServer) Roles(ctx context.Context, request *api.EmptyRequest) (*api.RolesResponse, error) {
+	if err := s.requireAuth(); err != nil {
+		return nil, err
+	}
+	_, roles, err := s.authData()
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(roles))
+	for name := range roles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	response := &api.RolesResponse{Roles: []*api.RoleInfo{toRoleInfo(AdminRole, []Grant{{Permission: api.Permission_ADMIN}})}}
+	for _, name := range names {
+		response.Roles = append(response.Roles, toRoleInfo(name, roles[name]))
+	}
+	return response, nil
+}
@@ -0,0 +1,284 @@
+/******
+This file is part of Vaelen/DB.
+
+Copyright 2017, Andrew Young <andrew@vaelen.org>
+
+    Vaelen/DB is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+    Vaelen/DB is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+    You should have received a copy of the GNU General Public License
+along with Vaelen/DB.  If not, see <http://www.gnu.org/licenses/>.
+******/
+
+package server
+
+import (
+	"encoding/base64"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vaelen/db/api"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// basic returns the authorization metadata for a username and password
+func basic(username string, password string) string {
+	return "Basic " + base64.StdEncoding.EncodeToString([]byte(username+":"+password))
+}
+
+// as returns a context that authenticates requests with the given authorization metadata
+func as(authorization string) context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), authorizationHeader, authorization)
+}
+
+// newAuthServer creates a server that requires authentication, with an admin user and a user named alice
+// who can write keys starting with "app/" and read keys starting with "public/"
+func newAuthServer(t *testing.T) *DBServer {
+	s := New(testLogs, "")
+	s.Auth = NewAuth("cluster")
+	s.Auth.AdminPassword = "secret"
+	ctx := context.Background()
+	if _, err := s.AddUser(ctx, &api.UserRequest{Username: "alice", Password: "password", Roles: []string{"app"}}); err != nil {
+		t.Fatalf("AddUser Error: %s\n", err.Error())
+	}
+	for prefix, p := range map[string]api.Permission{"app/": api.Permission_WRITE, "public/": api.Permission_READ} {
+		if _, err := s.GrantRole(ctx, &api.GrantRequest{Role: "app", Prefix: prefix, Permission: p}); err != nil {
+			t.Fatalf("GrantRole Error: %s\n", err.Error())
+		}
+	}
+	return s
+}
+
+// TestAuth tests authentication and permissions on the gRPC service
+func TestAuth(t *testing.T) {
+	s := newAuthServer(t)
+	defer s.Stop()
+	lis, err := net.Listen("tcp", "localhost:30300")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	grpcServer := grpc.NewServer(grpc.UnaryInterceptor(s.UnaryInterceptor), grpc.StreamInterceptor(s.StreamInterceptor))
+	api.RegisterDatabaseServer(grpcServer, s)
+	go grpcServer.Serve(lis)
+	defer grpcServer.Stop()
+	conn, err := grpc.Dial("localhost:30300", grpc.WithInsecure())
+	if err != nil {
+		t.Fatalf("Dial Error: %s\n", err.Error())
+	}
+	defer conn.Close()
+	c := api.NewDatabaseClient(conn)
+
+	expect := func(name string, err error, code codes.Code) {
+		if status.Code(err) != code {
+			t.Errorf("%s - Code: %s, Expected: %s, Error: %v\n", name, status.Code(err), code, err)
+		}
+	}
+
+	_, err = c.Get(context.Background(), &api.IDRequest{ID: "public/a"})
+	expect("Anonymous Get", err, codes.Unauthenticated)
+	_, err = c.Get(as(basic("alice", "wrong")), &api.IDRequest{ID: "public/a"})
+	expect("Wrong Password", err, codes.Unauthenticated)
+	_, err = c.Get(as("Bearer nope"), &api.IDRequest{ID: "public/a"})
+	expect("Unknown Token", err, codes.Unauthenticated)
+
+	login, err := c.Login(context.Background(), &api.LoginRequest{Username: "alice", Password: "password"})
+	if err != nil {
+		t.Fatalf("Login Error: %s\n", err.Error())
+	}
+	alice := as("Bearer " + login.Token)
+	// The token's record is removed once the token expires
+	if expires := s.Storage.Lookup(tokensPrefix + digest(login.Token)).Expires; expires.UnixNano()/1e6 != login.Expires/1e6 {
+		t.Errorf("Token Record Expires: %v, Expected: %v\n", expires, time.Unix(0, login.Expires))
+	}
+	_, err = c.Set(alice, &api.IDValueRequest{ID: "app/a", Value: "1"})
+	expect("Write Granted", err, codes.OK)
+	_, err = c.Get(alice, &api.IDRequest{ID: "app/a"})
+	expect("Read Through Write", err, codes.OK)
+	_, err = c.Get(alice, &api.IDRequest{ID: "public/a"})
+	expect("Read Granted", err, codes.OK)
+	_, err = c.Set(alice, &api.IDValueRequest{ID: "public/a", Value: "1"})
+	expect("Write Without Permission", err, codes.PermissionDenied)
+	_, err = c.Get(alice, &api.IDRequest{ID: "private/a"})
+	expect("Read Without Permission", err, codes.PermissionDenied)
+	_, err = c.Txn(alice, &api.TxnRequest{Ops: []*api.TxnOp{{ID: "app/b", Value: "2"}, {ID: "public/b", Value: "2"}}})
+	expect("Txn Without Permission", err, codes.PermissionDenied)
+	_, err = c.AddUser(alice, &api.UserRequest{Username: "mallory", Password: "password"})
+	expect("Admin Without Permission", err, codes.PermissionDenied)
+	_, err = c.ReplicaGet(alice, &api.IDRequest{ID: "app/a"})
+	expect("Node Method", err, codes.PermissionDenied)
+	_, err = c.Time(alice, &api.TimeRequest{})
+	expect("Authenticated Method", err, codes.OK)
+
+	admin := as(basic(AdminUser, "secret"))
+	_, err = c.Get(admin, &api.IDRequest{ID: usersKey})
+	expect("Reserved Key", err, codes.PermissionDenied)
+	_, err = c.ReplicaGet(as("Node cluster"), &api.IDRequest{ID: usersKey})
+	expect("Node Secret", err, codes.OK)
+	_, err = c.ReplicaGet(as("Node wrong"), &api.IDRequest{ID: usersKey})
+	expect("Wrong Node Secret", err, codes.Unauthenticated)
+
+	roles, err := c.Roles(admin, &api.EmptyRequest{})
+	if err != nil {
+		t.Fatalf("Roles Error: %s\n", err.Error())
+	}
+	if len(roles.Roles) != 2 || roles.Roles[0].Name != AdminRole || roles.Roles[1].Name != "app" || len(roles.Roles[1].Grants) != 2 {
+		t.Errorf("Roles: %v\n", roles.Roles)
+	}
+
+	// Revoking a permission applies straight away on the node that made the change
+	_, err = c.RevokeRole(admin, &api.GrantRequest{Role: "app", Prefix: "public/"})
+	expect("RevokeRole", err, codes.OK)
+	_, err = c.Get(alice, &api.IDRequest{ID: "public/a"})
+	expect("Read After Revoke", err, codes.PermissionDenied)
+
+	// Storing the admin user replaces the admin password
+	_, err = c.AddUser(admin, &api.UserRequest{Username: AdminUser, Password: "changed", Roles: []string{AdminRole}})
+	expect("AddUser", err, codes.OK)
+	_, err = c.Users(admin, &api.EmptyRequest{})
+	expect("Old Admin Password", err, codes.Unauthenticated)
+	admin = as(basic(AdminUser, "changed"))
+	users, err := c.Users(admin, &api.EmptyRequest{})
+	if err != nil || len(users.Users) != 2 {
+		t.Errorf("Users: %v, Error: %v\n", users.GetUsers(), err)
+	}
+
+	_, err = c.RemoveUser(admin, &api.UserRequest{Username: "alice"})
+	expect("RemoveUser", err, codes.OK)
+	_, err = c.Get(alice, &api.IDRequest{ID: "app/a"})
+	expect("Removed User", err, codes.Unauthenticated)
+
+	watch, err := c.WatchClusterConfig(context.Background(), &api.WatchClusterRequest{})
+	if err == nil {
+		_, err = watch.Recv()
+	}
+	expect("Anonymous Stream", err, codes.Unauthenticated)
+}
+
+// TestAuthGateways tests that the Redis and HTTP listeners check the same users and permissions
+func TestAuthGateways(t *testing.T) {
+	s := newAuthServer(t)
+	defer s.Stop()
+	s.Storage.Set("public/a", "1")
+	s.Storage.Set("private/a", "2")
+
+	lis, err := net.Listen("tcp", "localhost:30301")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	r := NewRESPServer(s)
+	go r.Serve(lis)
+	defer r.Close()
+	c := dialRESP(t, "localhost:30301")
+	defer c.conn.Close()
+	if reply, ok := c.do(t, "GET", "public/a").(respError); !ok || !strings.HasPrefix(string(reply), "NOAUTH") {
+		t.Errorf("GET before AUTH: %v\n", reply)
+	}
+	if reply, ok := c.do(t, "AUTH", "alice", "wrong").(respError); !ok || !strings.HasPrefix(string(reply), "WRONGPASS") {
+		t.Errorf("AUTH with wrong password: %v\n", reply)
+	}
+	if reply := c.do(t, "AUTH", "alice", "password"); reply != "OK" {
+		t.Fatalf("AUTH: %v\n", reply)
+	}
+	if reply := c.do(t, "GET", "public/a"); reply != "1" {
+		t.Errorf("GET: %v\n", reply)
+	}
+	if reply, ok := c.do(t, "MGET", "public/a", "private/a").(respError); !ok || !strings.HasPrefix(string(reply), "NOPERM") {
+		t.Errorf("MGET without permission: %v\n", reply)
+	}
+	if reply, ok := c.do(t, "MSET", "app/a", "1", "public/a", "2").(respError); !ok || !strings.HasPrefix(string(reply), "NOPERM") {
+		t.Errorf("MSET without permission: %v\n", reply)
+	}
+	if reply, ok := c.do(t, "SCAN", "0", "COUNT", "1000").([]interface{}); !ok || len(reply) != 2 || len(reply[1].([]interface{})) != 1 {
+		t.Errorf("SCAN: %v\n", reply)
+	}
+
+	g := httptest.NewServer(NewHTTPGateway(s))
+	defer g.Close()
+	get := func(key string, authorization string) int {
+		request, _ := http.NewRequest(http.MethodGet, g.URL+"/v1/keys/"+key, nil)
+		if authorization != "" {
+			request.Header.Set("Authorization", authorization)
+		}
+		response, err := http.DefaultClient.Do(request)
+		if err != nil {
+			t.Fatalf("GET Error: %s\n", err.Error())
+		}
+		response.Body.Close()
+		return response.StatusCode
+	}
+	if code := get("public/a", ""); code != http.StatusUnauthorized {
+		t.Errorf("Anonymous GET: %d\n", code)
+	}
+	if code := get("public/a", basic("alice", "password")); code != http.StatusOK {
+		t.Errorf("GET: %d\n", code)
+	}
+	if code := get("private/a", basic("alice", "password")); code != http.StatusForbidden {
+		t.Errorf("GET without permission: %d\n", code)
+	}
+}
+
+// TestAuthCluster tests that users added through one node can log in through another and that nodes authenticate each other
+func TestAuthCluster(t *testing.T) {
+	config := newCluster(30310, 2)
+	servers := make([]*DBServer, 0)
+	for _, shard := range config.Shards {
+		s := New(testLogs, "")
+		s.Self = shard
+		s.Cluster = config
+		s.Replication = ReplicationConfig{N: 2, R: 2, W: 2, Timeout: 2 * time.Second}
+		s.Auth = NewAuth("cluster")
+		lis, err := net.Listen("tcp", shard.Address)
+		if err != nil {
+			t.Fatalf("failed to listen: %v", err)
+		}
+		grpcServer := grpc.NewServer(grpc.UnaryInterceptor(s.UnaryInterceptor), grpc.StreamInterceptor(s.StreamInterceptor))
+		api.RegisterDatabaseServer(grpcServer, s)
+		go grpcServer.Serve(lis)
+		defer s.Stop()
+		defer grpcServer.Stop()
+		servers = append(servers, s)
+	}
+
+	ctx := context.Background()
+	if _, err := servers[0].AddUser(ctx, &api.UserRequest{Username: "alice", Password: "password", Roles: []string{"app"}}); err != nil {
+		t.Fatalf("AddUser Error: %s\n", err.Error())
+	}
+	if _, err := servers[0].GrantRole(ctx, &api.GrantRequest{Role: "app", Prefix: "app/", Permission: api.Permission_WRITE}); err != nil {
+		t.Fatalf("GrantRole Error: %s\n", err.Error())
+	}
+
+	conn, err := grpc.Dial(config.Shards[1].Address, grpc.WithInsecure())
+	if err != nil {
+		t.Fatalf("Dial Error: %s\n", err.Error())
+	}
+	defer conn.Close()
+	c := api.NewDatabaseClient(conn)
+	login, err := c.Login(ctx, &api.LoginRequest{Username: "alice", Password: "password"})
+	if err != nil {
+		t.Fatalf("Login Error: %s\n", err.Error())
+	}
+	if _, err := c.Set(as("Bearer "+login.Token), &api.IDValueRequest{ID: "app/a", Value: "1"}); err != nil {
+		t.Fatalf("Set Error: %s\n", err.Error())
+	}
+	for _, s := range servers {
+		if v := s.Storage.Get("app/a"); v != "1" {
+			t.Errorf("Shard: %s, Value: %s\n", s.Self.ID, v)
+		}
+	}
+}
@@ -0,0 +1,235 @@
+/******
+This file is part of Vaelen/DB.
+
+Copyright 2017, Andrew Young <andrew@vaelen.org>
+
+    Vaelen/DB is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+    Vaelen/DB is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+    You should have received a copy of the GNU General Public License
+along with Vaelen/DB.  If not, see <http://www.gnu.org/licenses/>.
+******/
+
+package server
+
+import (
+	"math/rand"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/vaelen/db/api"
+	"github.com/vaelen/db/storage"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// cacheRetries is how many times a read-modify-write command is tried again when the key changes between its read and its write
+	cacheRetries = 5
+	// cacheRetryDelay is the longest wait before the first retry, it doubles for each one after that
+	cacheRetryDelay = 10 * time.Millisecond
+)
+
+// keyLocks serializes the read-modify-write requests made through this server one key at a time, so that they do not make each other fail.
+// Conflicts with requests made through other servers are caught by the replicas, see conditionalWrite.
+type keyLocks struct {
+	sync.Mutex
+	locks map[string]*keyLock
+}
+
+// keyLock is the lock of a single key along with the number of requests holding or waiting for it
+type keyLock struct {
+	sync.Mutex
+	waiters int
+}
+
+func newKeyLocks() *keyLocks {
+	return &keyLocks{
+		locks: make(map[string]*keyLock),
+	}
+}
+
+// lock locks a key and returns the function that unlocks it.  The lock is forgotten once nothing holds or waits for it.
+func (l *keyLocks) lock(key string) func() {
+	l.Lock()
+	k, ok := l.locks[key]
+	if !ok {
+		k = &keyLock{}
+		l.locks[key] = k
+	}
+	k.waiters++
+	l.Unlock()
+	k.Lock()
+	return func() {
+		k.Unlock()
+		l.Lock()
+		defer l.Unlock()
+		k.waiters--
+		if k.waiters == 0 {
+			delete(l.locks, key)
+		}
+	}
+}
+
+// cacheItem is a value read by a cache protocol
+type cacheItem struct {
+	Value string
+	Flags uint32
+	// Expires is when the value expires, it never does when it is zero
+	Expires time.Time
+	// Version is the version of the value
+	Version *api.VersionVector
+	// Digest identifies the versions the item was read from, including tombstones, so it is set even if the key does not exist
+	Digest uint64
+}
+
+// cacheRead reads a key for a cache protocol.  Empty values are treated as missing.
+func (s *DBServer) cacheRead(ctx context.Context, key string) (cacheItem, bool, error) {
+	key, err := s.key("", key)
+	if err != nil {
+		return cacheItem{}, false, err
+	}
+	versions, err := s.readVersions(ctx, key, api.Consistency_DEFAULT)
+	if err != nil {
+		return cacheItem{}, false, err
+	}
+	response := versionedResponse(versions)
+	item := cacheItem{
+		Value:   response.Value,
+		Flags:   response.Flags,
+		Expires: FromExpires(response.Expires),
+		Version: response.Context,
+		Digest:  storage.DigestVersions(versions),
+	}
+	return item, item.Value != "", nil
+}
+
+// cacheWrite writes a key for a cache protocol along with its flags.
+// The value expires at the given time, it never does if expires is zero.
+// If read is given the write only succeeds if the key has not changed since the item was read, otherwise it fails with Aborted.
+// The check is made by every replica of the key when the write is prepared, see commit.
+func (s *DBServer) cacheWrite(ctx context.Context, key string, value string, flags uint32, expires time.Time, read *cacheItem) (err error) {
+	defer func() { s.auditCommand(ctx, []string{key}, err) }()
+	if read == nil {
+		request := &api.IDValueRequest{ID: key, Value: value, Flags: flags, Expires: ToExpires(expires)}
+		if expires.IsZero() {
+			request.Expires = -1
+		}
+		_, err = s.Set(ctx, request)
+		return err
+	}
+	internal, err := s.key("", key)
+	if err != nil {
+		return err
+	}
+	pair := storage.NodeKeyValuePair{Key: internal, Value: value, Expires: expires, Flags: flags}
+	return s.conditionalWrite(ctx, pair, FromVersionVector(read.Version), read.Digest, api.Consistency_DEFAULT)
+}
+
+// cacheUpdate reads a key and writes the item returned by update if the key has not changed in between.
+// When it has, the key is read and update is called again, up to cacheRetries more times.
+// Nothing is written if update returns false.
+func (s *DBServer) cacheUpdate(ctx context.Context, key string, update func(item cacheItem, exists bool) (cacheItem, bool)) error {
+	defer s.locks.lock(key)()
+	for attempt := 0; ; attempt++ {
+		item, exists, err := s.cacheRead(ctx, key)
+		if err != nil {
+			return err
+		}
+		next, write := update(item, exists)
+		if !write {
+			return nil
+		}
+		err = s.cacheWrite(ctx, key, next.Value, next.Flags, next.Expires, &item)
+		if status.Code(err) != codes.Aborted || attempt == cacheRetries {
+			return err
+		}
+		// Writers that collided back off for a random time so that they do not collide again
+		time.Sleep(time.Duration(rand.Int63n(int64(cacheRetryDelay << uint(attempt)))))
+	}
+}
+
+// cacheRemove removes a key for a cache protocol.
+// It returns true if the key existed.
+func (s *DBServer) cacheRemove(ctx context.Context, key string) (bool, error) {
+	response, err := s.Remove(ctx, &api.IDRequest{ID: key})
+	s.auditCommand(ctx, []string{key}, err)
+	if err != nil {
+		return false, err
+	}
+	return response.Value != "", nil
+}
+
+// connTracker tracks the listeners and connections of a protocol server so that they can all be closed
+type connTracker struct {
+	lock      sync.Mutex
+	listeners map[net.Listener]bool
+	conns     map[net.Conn]bool
+	closed    bool
+}
+
+// serve accepts connections on the given listener and handles each of them in its own goroutine until the tracker is closed
+func (t *connTracker) serve(lis net.Listener, handle func(conn net.Conn)) error {
+	t.lock.Lock()
+	if t.closed {
+		t.lock.Unlock()
+		lis.Close()
+		return nil
+	}
+	if t.listeners == nil {
+		t.listeners = make(map[net.Listener]bool)
+		t.conns = make(map[net.Conn]bool)
+	}
+	t.listeners[lis] = true
+	t.lock.Unlock()
+	for {
+		conn, err := lis.Accept()
+		if err != nil {
+			t.lock.Lock()
+			closed := t.closed
+			t.lock.Unlock()
+			if closed {
+				return nil
+			}
+			return err
+		}
+		t.lock.Lock()
+		if t.closed {
+			t.lock.Unlock()
+			conn.Close()
+			return nil
+		}
+		t.conns[conn] = true
+		t.lock.Unlock()
+		go func() {
+			handle(conn)
+			t.lock.Lock()
+			delete(t.conns, conn)
+			t.lock.Unlock()
+			conn.Close()
+		}()
+	}
+}
+
+// close stops every listener and closes every connection
+func (t *connTracker) close() {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.closed = true
+	for lis := range t.listeners {
+		lis.Close()
+	}
+	for conn := range t.conns {
+		conn.Close()
+	}
+}
@@ -0,0 +1,97 @@
+/******
+This file is part of Vaelen/DB.
+
+Copyright 2017, Andrew Young <andrew@vaelen.org>
+
+    Vaelen/DB is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+    Vaelen/DB is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+    You should have received a copy of the GNU General Public License
+along with Vaelen/DB.  If not, see <http://www.gnu.org/licenses/>.
+******/
+
+package server
+
+import (
+	"errors"
+	"sync"
+)
+
+// ErrNotEncrypted is returned when a key is rotated on a server that does not encrypt its files
+var ErrNotEncrypted = errors.New("encryption at rest is not enabled")
+
+// keyRotation tracks the key rotation that is encrypting files again in the background
+type keyRotation struct {
+	sync.Mutex
+	// done is closed once the last rotation has encrypted every file again, it is nil before the first rotation
+	done chan struct{}
+}
+
+// wait returns once the last rotation has encrypted every file again
+func (r *keyRotation) wait() {
+	r.Lock()
+	done := r.done
+	r.Unlock()
+	if done != nil {
+		<-done
+	}
+}
+
+// RotateKey creates a new data key and encrypts every file the server has written with it in the background.
+// If a master key is given the data keys are wrapped with it from now on.
+// The old data keys are forgotten once every file has been encrypted again.
+// A rotation waits for the previous one to finish.
+func (s *DBServer) RotateKey(master []byte) error {
+	if s.Keyring == nil {
+		return ErrNotEncrypted
+	}
+	s.rotation.Lock()
+	defer s.rotation.Unlock()
+	if s.rotation.done != nil {
+		<-s.rotation.done
+	}
+	if err := s.Keyring.Rotate(master); err != nil {
+		return err
+	}
+	s.Logger.Info("Data key rotated", "key", s.Keyring.Active())
+	done := make(chan struct{})
+	s.rotation.done = done
+	go func() {
+		defer close(done)
+		if err := s.Reencrypt(); err != nil {
+			s.Logger.Error("Could not encrypt files with the new data key, old keys are kept", "error", err)
+			return
+		}
+		if err := s.Keyring.Retire(); err != nil {
+			s.Logger.Error("Could not retire old data keys", "error", err)
+			return
+		}
+		s.Logger.Info("Files encrypted with the new data key", "key", s.Keyring.Active())
+	}()
+	return nil
+}
+
+// Reencrypt writes every file the server keeps again so that it is encrypted with the active data key.
+// Files written before encryption was enabled are encrypted by it.
+func (s *DBServer) Reencrypt() error {
+	if err := s.Storage.Reencrypt(); err != nil {
+		return err
+	}
+	if err := s.Hints.Reencrypt(); err != nil {
+		return err
+	}
+	if err := s.reencryptCluster(); err != nil {
+		return err
+	}
+	if err := s.Audit.Reencrypt(); err != nil {
+		return err
+	}
+	return s.Txns.Reencrypt()
+}
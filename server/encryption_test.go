@@ -0,0 +1,121 @@
+/******
+This file is part of Vaelen/DB.
+
+Copyright 2017, Andrew Young <andrew@vaelen.org>
+
+    Vaelen/DB is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+    Vaelen/DB is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+    You should have received a copy of the GNU General Public License
+along with Vaelen/DB.  If not, see <http://www.gnu.org/licenses/>.
+******/
+
+package server
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/vaelen/db/storage"
+)
+
+// TestRotateKey tests that rotating the data key encrypts every file again so that the old key can be forgotten
+func TestRotateKey(t *testing.T) {
+	dir, err := ioutil.TempDir("", "vdb-encryption")
+	if err != nil {
+		t.Fatalf("TempDir Error: %s\n", err.Error())
+	}
+	defer os.RemoveAll(dir)
+	master := bytes.Repeat([]byte{1}, storage.KeySize)
+	keyring, err := storage.OpenKeyring(dir, master)
+	if err != nil {
+		t.Fatalf("OpenKeyring Error: %s\n", err.Error())
+	}
+
+	s := NewEncrypted(testLogs, dir, keyring)
+	defer s.Stop()
+	s.Hints.Add(Hint{Target: "a", Key: "secret-key", Created: time.Now()})
+	s.Hints.Flush()
+	filename := filepath.Join(dir, "hints.gob")
+	if b, _ := ioutil.ReadFile(filename); len(b) == 0 || bytes.Contains(b, []byte("secret-key")) {
+		t.Fatalf("Hints were not encrypted\n")
+	}
+	if _, err := s.CompareAndSetClusterConfig(0, newCluster(30000, 2)); err != nil {
+		t.Fatalf("CompareAndSetClusterConfig Error: %s\n", err.Error())
+	}
+	// Entries recorded before encryption was enabled are encrypted by the rotation
+	auditFile := filepath.Join(dir, "audit.log")
+	if s.Audit, err = OpenAuditLog(auditFile, DefaultAuditMaxSize, DefaultAuditMaxFiles); err != nil {
+		t.Fatalf("OpenAuditLog Error: %s\n", err.Error())
+	}
+	s.Audit.Record(AuditEntry{Operation: "Set", Keys: []string{"plain-key"}})
+	s.Audit.Keyring = keyring
+	s.Audit.Record(AuditEntry{Operation: "Set", Keys: []string{"secret-key"}})
+	if b, _ := ioutil.ReadFile(auditFile); bytes.Contains(b, []byte("secret-key")) {
+		t.Fatalf("Audit entry was not encrypted\n")
+	}
+	clusterFile := filepath.Join(dir, "cluster.pb")
+	if b, _ := ioutil.ReadFile(clusterFile); len(b) == 0 || bytes.Contains(b, []byte("localhost")) {
+		t.Fatalf("Cluster configuration was not encrypted\n")
+	}
+
+	// A keyring opened before the rotation only knows the old data key
+	before, err := storage.OpenKeyring(dir, master)
+	if err != nil {
+		t.Fatalf("OpenKeyring Error: %s\n", err.Error())
+	}
+	if err := s.RotateKey(nil); err != nil {
+		t.Fatalf("RotateKey Error: %s\n", err.Error())
+	}
+	// Wait for the files to be encrypted again
+	s.rotation.wait()
+	if _, err := before.ReadFile(filename); err != storage.ErrUnknownKey {
+		t.Fatalf("Expected ErrUnknownKey, got %v\n", err)
+	}
+	if _, err := before.ReadFile(clusterFile); err != storage.ErrUnknownKey {
+		t.Fatalf("Expected ErrUnknownKey for the cluster configuration, got %v\n", err)
+	}
+	if b, _ := ioutil.ReadFile(auditFile); bytes.Contains(b, []byte("plain-key")) {
+		t.Fatalf("Audit log was not encrypted again\n")
+	}
+	if entries, err := s.Audit.Query(AuditQuery{}); err != nil || len(entries) != 2 {
+		t.Fatalf("Audit entries: %v, Error: %v\n", entries, err)
+	}
+	after, err := storage.OpenKeyring(dir, master)
+	if err != nil {
+		t.Fatalf("OpenKeyring Error: %s\n", err.Error())
+	}
+	if err := after.Check(dir); err != nil {
+		t.Fatalf("Check Error: %s\n", err.Error())
+	}
+
+	// An audit log that the keyring can not decrypt is refused instead of being written again without its entries
+	stale := &AuditLog{Path: auditFile, MaxFiles: DefaultAuditMaxFiles, Keyring: before}
+	if err := stale.Check(); err == nil {
+		t.Fatalf("Expected an error checking the audit log without the new data key\n")
+	}
+	if err := (&AuditLog{Path: auditFile}).Check(); err == nil {
+		t.Fatalf("Expected an error checking the encrypted audit log without a keyring\n")
+	}
+	contents, _ := ioutil.ReadFile(auditFile)
+	if err := stale.Reencrypt(); err == nil {
+		t.Fatalf("Expected an error encrypting the audit log again without the new data key\n")
+	}
+	if b, _ := ioutil.ReadFile(auditFile); !bytes.Equal(b, contents) {
+		t.Fatalf("Audit log was written again without the entries it could not decrypt\n")
+	}
+	if err := (&DBServer{}).RotateKey(nil); err != ErrNotEncrypted {
+		t.Fatalf("Expected ErrNotEncrypted, got %v\n", err)
+	}
+}
@@ -0,0 +1,65 @@
+/******
+This file is part of Vaelen/DB.
+
+Copyright 2017, Andrew Young <andrew@vaelen.org>
+
+    Vaelen/DB is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+    Vaelen/DB is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+    You should have received a copy of the GNU General Public License
+along with Vaelen/DB.  If not, see <http://www.gnu.org/licenses/>.
+******/
+
+package server
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/vaelen/db/api"
+
+	"golang.org/x/net/context"
+)
+
+// followerRead answers a Get from this node's own copy of the key when the caller accepts stale data.
+// It returns false if the read has to be coordinated across a quorum instead.
+func (s *DBServer) followerRead(ctx context.Context, key string, maxStaleness int64) (*api.Response, bool) {
+	if !s.fresh(key, maxStaleness) {
+		return nil, false
+	}
+	atomic.AddUint64(&s.followerReads, 1)
+	defer s.storageSpan(ctx, "GetVersions", key).Finish()
+	return versionedResponse(s.localStorage(ctx).GetVersions(key)), true
+}
+
+// fresh returns true if this node's copy of the key is within the given staleness bound in milliseconds.
+// The node must be a replica for the key and must have synchronized with every other replica of the key within the bound.
+func (s *DBServer) fresh(key string, maxStaleness int64) bool {
+	if maxStaleness <= 0 {
+		return false
+	}
+	bound := time.Duration(maxStaleness) * time.Millisecond
+	replica := false
+	for _, shard := range s.replicas(key) {
+		if s.isSelf(shard) {
+			replica = true
+			continue
+		}
+		if synced := s.peerSync(shard); synced.IsZero() || time.Since(synced) > bound {
+			return false
+		}
+	}
+	return replica
+}
+
+// FollowerReads returns the number of reads this node answered from its own copy
+func (s *DBServer) FollowerReads() uint64 {
+	return atomic.LoadUint64(&s.followerReads)
+}
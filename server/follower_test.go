@@ -0,0 +1,93 @@
+/******
+This file is part of Vaelen/DB.
+
+Copyright 2017, Andrew Young <andrew@vaelen.org>
+
+    Vaelen/DB is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+    Vaelen/DB is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+    You should have received a copy of the GNU General Public License
+along with Vaelen/DB.  If not, see <http://www.gnu.org/licenses/>.
+******/
+
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/vaelen/db/api"
+	"github.com/vaelen/db/cluster"
+
+	"golang.org/x/net/context"
+)
+
+// TestFollowerRead tests that only a synchronized replica of a key answers stale reads from its own copy.
+// A replica only has to have synchronized with the other replicas of the key.
+func TestFollowerRead(t *testing.T) {
+	servers, stop := startCluster(t, 30230, 3)
+	defer stop()
+	servers[0].cluster().Strategy = cluster.RingStrategy
+	for _, s := range servers {
+		s.Replication.N = 1
+		s.Replication.R = 1
+		s.Replication.W = 1
+	}
+	ctx := context.Background()
+	key := "foo"
+	if _, err := servers[0].Set(ctx, &api.IDValueRequest{ID: key, Value: "bar"}); err != nil {
+		t.Fatalf("Set Error: %s\n", err.Error())
+	}
+
+	for _, s := range servers {
+		s.AntiEntropy()
+	}
+	for _, s := range servers {
+		response, err := s.Get(ctx, &api.IDRequest{ID: key, MaxStaleness: 60000})
+		if err != nil {
+			t.Fatalf("Get Error: %s\n", err.Error())
+		}
+		if response.Value != "bar" {
+			t.Errorf("Shard: %s, Value: %s\n", s.Self.ID, response.Value)
+		}
+		replica := s.isSelf(s.replicas(key)[0])
+		if followed := s.FollowerReads() == 1; followed != replica {
+			t.Errorf("Shard: %s, Replica: %t, Follower Reads: %d\n", s.Self.ID, replica, s.FollowerReads())
+		}
+	}
+
+	for _, s := range servers {
+		s.Replication.N = 2
+	}
+	replicas := servers[0].replicas(key)
+	var replica *DBServer
+	for _, s := range servers {
+		if s.isSelf(replicas[0]) {
+			replica = s
+		}
+	}
+	replica.antiEntropy.Lock()
+	replica.antiEntropy.stats.PeerSync = map[string]time.Time{}
+	for _, shard := range replica.cluster().Shards {
+		if shard.ID != replicas[1].ID {
+			replica.antiEntropy.stats.PeerSync[shard.ID.String()] = time.Now()
+		}
+	}
+	replica.antiEntropy.Unlock()
+	if replica.fresh(key, 60000) {
+		t.Fatalf("Replica is fresh without synchronizing with the other replica of the key\n")
+	}
+	replica.antiEntropy.Lock()
+	replica.antiEntropy.stats.PeerSync[replicas[1].ID.String()] = time.Now()
+	replica.antiEntropy.Unlock()
+	if !replica.fresh(key, 60000) {
+		t.Fatalf("Replica is not fresh after synchronizing with the other replica of the key\n")
+	}
+}
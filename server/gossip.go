@@ -0,0 +1,496 @@
+/******
+This file is part of Vaelen/DB.
+
+Copyright 2017, Andrew Young <andrew@vaelen.org>
+
+    Vaelen/DB is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+    Vaelen/DB is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+    You should have received a copy of the GNU General Public License
+along with Vaelen/DB.  If not, see <http://www.gnu.org/licenses/>.
+******/
+
+package server
+
+import (
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/satori/go.uuid"
+	"github.com/vaelen/db/api"
+	"github.com/vaelen/db/cluster"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// GossipConfig controls the SWIM failure detector
+type GossipConfig struct {
+	// Interval is how often a member is probed
+	Interval time.Duration
+	// Timeout is how long to wait for a probe to be acknowledged
+	Timeout time.Duration
+	// IndirectProbes is the number of members asked to probe a member that did not answer a direct probe
+	IndirectProbes int
+	// SuspectTimeout is how long a member can be suspected before it is declared dead
+	SuspectTimeout time.Duration
+}
+
+// DefaultGossip is the gossip configuration used by new servers
+var DefaultGossip = GossipConfig{
+	Interval:       time.Second,
+	Timeout:        500 * time.Millisecond,
+	IndirectProbes: 3,
+	SuspectTimeout: 5 * time.Second,
+}
+
+// maxMemberEvents is the number of membership changes that are remembered
+const maxMemberEvents = 100
+
+var errMemberDead = status.Errorf(codes.Unavailable, "member is dead")
+
+// Member is the state of a single member of the cluster as seen by this server
+type Member struct {
+	Shard cluster.Shard
+	State api.MemberState
+	// Incarnation is incremented by a member to refute suspicion about itself
+	Incarnation uint64
+	// Updated is when the state last changed
+	Updated time.Time
+}
+
+// MemberEvent records a change in a member's state
+type MemberEvent struct {
+	Time  time.Time
+	Shard cluster.Shard
+	From  api.MemberState
+	To    api.MemberState
+}
+
+// membership holds the state of the gossip protocol
+type membership struct {
+	sync.Mutex
+	members map[string]*Member
+	events  []MemberEvent
+	// probes is the order in which members will be probed
+	probes []string
+	stop   chan bool
+}
+
+func newMembership() *membership {
+	return &membership{
+		members: make(map[string]*Member),
+		events:  make([]MemberEvent, 0),
+		probes:  make([]string, 0),
+	}
+}
+
+// supersedes returns true if the update should replace the current state of a member.
+// This follows the precedence rules used by SWIM.
+func supersedes(update Member, current Member) bool {
+	switch update.State {
+	case api.MemberState_ALIVE:
+		return update.Incarnation > current.Incarnation
+	case api.MemberState_SUSPECT:
+		switch current.State {
+		case api.MemberState_ALIVE:
+			return update.Incarnation >= current.Incarnation
+		case api.MemberState_SUSPECT:
+			return update.Incarnation > current.Incarnation
+		}
+		return false
+	case api.MemberState_DEAD:
+		if current.State == api.MemberState_DEAD {
+			return false
+		}
+		return update.Incarnation >= current.Incarnation
+	}
+	return false
+}
+
+// record adds a membership event.  The caller must hold the lock.
+func (m *membership) record(event MemberEvent) {
+	m.events = append(m.events, event)
+	if len(m.events) > maxMemberEvents {
+		m.events = m.events[len(m.events)-maxMemberEvents:]
+	}
+}
+
+// StartGossip starts probing the other members of the cluster.
+// The given seed addresses are pinged first so that they learn about this server.
+func (s *DBServer) StartGossip(seeds []string) {
+	s.membership.Lock()
+	if s.membership.stop != nil {
+		s.membership.Unlock()
+		return
+	}
+	stop := make(chan bool)
+	s.membership.stop = stop
+	now := time.Now()
+	if _, ok := s.membership.members[s.Self.ID.String()]; !ok {
+		s.membership.members[s.Self.ID.String()] = &Member{Shard: s.Self, State: api.MemberState_ALIVE, Updated: now}
+	}
+	s.membership.Unlock()
+
+	if config := s.cluster(); config != nil {
+		s.addShards(config.Shards)
+	}
+
+	go func() {
+		for _, seed := range seeds {
+			s.ping(seed, "")
+		}
+		ticker := time.NewTicker(s.Gossip.Interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				s.gossipRound()
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
+
+// stopGossip stops the gossip protocol if it is running
+func (s *DBServer) stopGossip() {
+	s.membership.Lock()
+	defer s.membership.Unlock()
+	if s.membership.stop != nil {
+		close(s.membership.stop)
+		s.membership.stop = nil
+	}
+}
+
+// addShards adds any shards that are not already known as alive members
+func (s *DBServer) addShards(shards []cluster.Shard) {
+	s.membership.Lock()
+	defer s.membership.Unlock()
+	now := time.Now()
+	for _, shard := range shards {
+		if _, ok := s.membership.members[shard.ID.String()]; !ok {
+			s.membership.members[shard.ID.String()] = &Member{Shard: shard, State: api.MemberState_ALIVE, Updated: now}
+		}
+	}
+}
+
+// MemberList returns the members known to this server, ordered by ID
+func (s *DBServer) MemberList() []Member {
+	s.membership.Lock()
+	defer s.membership.Unlock()
+	members := make([]Member, 0, len(s.membership.members))
+	for _, m := range s.membership.members {
+		members = append(members, *m)
+	}
+	sort.Slice(members, func(i, j int) bool {
+		return members[i].Shard.ID.String() < members[j].Shard.ID.String()
+	})
+	return members
+}
+
+// MemberEvents returns the most recent membership changes, oldest first
+func (s *DBServer) MemberEvents() []MemberEvent {
+	s.membership.Lock()
+	defer s.membership.Unlock()
+	events := make([]MemberEvent, len(s.membership.events))
+	copy(events, s.membership.events)
+	return events
+}
+
+// memberState returns the state of the member with the given ID.  Unknown members are assumed to be alive.
+func (s *DBServer) memberState(id uuid.UUID) api.MemberState {
+	s.membership.Lock()
+	defer s.membership.Unlock()
+	if m, ok := s.membership.members[id.String()]; ok {
+		return m.State
+	}
+	return api.MemberState_ALIVE
+}
+
+// isDead returns true if the gossip protocol has declared the given shard dead
+func (s *DBServer) isDead(shard cluster.Shard) bool {
+	return s.memberState(shard.ID) == api.MemberState_DEAD
+}
+
+// applyMembers merges membership updates received from another member
+func (s *DBServer) applyMembers(updates []Member) {
+	revived := false
+	s.membership.Lock()
+	now := time.Now()
+	for _, update := range updates {
+		id := update.Shard.ID.String()
+		current, ok := s.membership.members[id]
+
+		if uuid.Equal(update.Shard.ID, s.Self.ID) {
+			// Refute any suspicion about ourselves
+			if ok && update.State != api.MemberState_ALIVE && update.Incarnation >= current.Incarnation {
+				current.Incarnation = update.Incarnation + 1
+				current.Updated = now
+				s.Logger.Info("Refuting suspicion", "state", update.State.String(), "incarnation", current.Incarnation)
+			}
+			continue
+		}
+
+		if !ok {
+			m := update
+			m.Updated = now
+			s.membership.members[id] = &m
+			s.membership.record(MemberEvent{Time: now, Shard: update.Shard, From: update.State, To: update.State})
+			s.Logger.Info("Member joined", "id", id, "address", update.Shard.Address, "state", update.State.String())
+			continue
+		}
+
+		if !supersedes(update, *current) {
+			continue
+		}
+		previous := current.State
+		current.Shard.Address = update.Shard.Address
+		current.Incarnation = update.Incarnation
+		if previous != update.State {
+			current.State = update.State
+			current.Updated = now
+			s.membership.record(MemberEvent{Time: now, Shard: current.Shard, From: previous, To: update.State})
+			s.Logger.Info("Member changed state", "id", id, "from", previous.String(), "to", update.State.String())
+			if update.State == api.MemberState_ALIVE {
+				revived = true
+			}
+		}
+	}
+	s.membership.Unlock()
+
+	if revived && len(s.Hints.Targets()) > 0 {
+		go s.ReplayHints()
+	}
+}
+
+// gossipRound probes the next member and checks whether any suspected members should be declared dead
+func (s *DBServer) gossipRound() {
+	if target, ok := s.nextProbe(); ok {
+		if !s.ping(target.Shard.Address, "") && !s.indirectPing(target) {
+			s.suspect(target)
+		}
+	}
+	s.expireSuspects(time.Now())
+}
+
+// nextProbe returns the next member to probe.  Members are probed in a random order, each once per round.
+func (s *DBServer) nextProbe() (Member, bool) {
+	s.membership.Lock()
+	defer s.membership.Unlock()
+	for {
+		if len(s.membership.probes) == 0 {
+			for id, m := range s.membership.members {
+				if !uuid.Equal(m.Shard.ID, s.Self.ID) && m.State != api.MemberState_DEAD {
+					s.membership.probes = append(s.membership.probes, id)
+				}
+			}
+			if len(s.membership.probes) == 0 {
+				return Member{}, false
+			}
+			rand.Shuffle(len(s.membership.probes), func(i, j int) {
+				s.membership.probes[i], s.membership.probes[j] = s.membership.probes[j], s.membership.probes[i]
+			})
+		}
+		id := s.membership.probes[0]
+		s.membership.probes = s.membership.probes[1:]
+		if m, ok := s.membership.members[id]; ok && m.State != api.MemberState_DEAD {
+			return *m, true
+		}
+	}
+}
+
+// pingRequest builds a ping carrying this server's view of the cluster
+func (s *DBServer) pingRequest(target string) *api.PingRequest {
+	return &api.PingRequest{
+		From:    s.Self.ID.String(),
+		Target:  target,
+		Members: ToMembers(s.MemberList()),
+		Config:  cluster.ToConfiguration(s.cluster()),
+	}
+}
+
+// ping sends a ping to the given address, optionally asking it to probe another member.
+// It returns true if the ping was acknowledged.
+func (s *DBServer) ping(address string, target string) bool {
+	c, err := s.peers.dial(address)
+	if err != nil {
+		return false
+	}
+	timeout := s.Gossip.Timeout
+	if target != "" {
+		// Leave time for the indirect probe
+		timeout *= 2
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	response, err := c.Ping(ctx, s.pingRequest(target))
+	if err != nil {
+		return false
+	}
+	s.applyPing(response.Members, response.Config)
+	return response.Ack
+}
+
+// indirectPing asks other members to probe a member that did not answer.
+// It returns true if any of them received an acknowledgement.
+func (s *DBServer) indirectPing(target Member) bool {
+	helpers := make([]Member, 0)
+	for _, m := range s.MemberList() {
+		if m.State == api.MemberState_ALIVE && !uuid.Equal(m.Shard.ID, s.Self.ID) && !uuid.Equal(m.Shard.ID, target.Shard.ID) {
+			helpers = append(helpers, m)
+		}
+	}
+	rand.Shuffle(len(helpers), func(i, j int) { helpers[i], helpers[j] = helpers[j], helpers[i] })
+	if len(helpers) > s.Gossip.IndirectProbes {
+		helpers = helpers[:s.Gossip.IndirectProbes]
+	}
+	if len(helpers) == 0 {
+		return false
+	}
+
+	acks := make(chan bool, len(helpers))
+	for _, helper := range helpers {
+		go func(helper Member) {
+			acks <- s.ping(helper.Shard.Address, target.Shard.ID.String())
+		}(helper)
+	}
+	acked := false
+	for range helpers {
+		if <-acks {
+			acked = true
+		}
+	}
+	return acked
+}
+
+// suspect marks a member as suspected of having failed
+func (s *DBServer) suspect(target Member) {
+	s.applyMembers([]Member{{
+		Shard:       target.Shard,
+		State:       api.MemberState_SUSPECT,
+		Incarnation: target.Incarnation,
+	}})
+}
+
+// expireSuspects declares members dead that have been suspected for longer than the suspect timeout
+func (s *DBServer) expireSuspects(now time.Time) {
+	dead := make([]Member, 0)
+	s.membership.Lock()
+	for _, m := range s.membership.members {
+		if m.State == api.MemberState_SUSPECT && now.Sub(m.Updated) > s.Gossip.SuspectTimeout {
+			dead = append(dead, Member{Shard: m.Shard, State: api.MemberState_DEAD, Incarnation: m.Incarnation})
+		}
+	}
+	s.membership.Unlock()
+	if len(dead) > 0 {
+		s.applyMembers(dead)
+	}
+}
+
+// applyPing merges the membership and cluster configuration carried by a ping or its acknowledgement
+func (s *DBServer) applyPing(members []*api.Member, config *api.ClusterConfiguration) {
+	s.applyMembers(FromMembers(members))
+	if config == nil {
+		return
+	}
+	current := s.cluster()
+	if current != nil && config.Epoch <= current.Epoch {
+		return
+	}
+	c, err := cluster.FromConfiguration(config)
+	if err != nil {
+		s.Logger.Warn("Ignoring invalid cluster configuration", "error", err)
+		return
+	}
+	if s.SetClusterConfig(c) {
+		s.addShards(c.Shards)
+	}
+}
+
+// Ping handles a gossip probe.  If a target is given the target is probed on the sender's behalf.
+func (s *DBServer) Ping(ctx context.Context, request *api.PingRequest) (*api.PingResponse, error) {
+	s.applyPing(request.Members, request.Config)
+	ack := true
+	if request.Target != "" {
+		ack = false
+		id, err := uuid.FromString(request.Target)
+		if err == nil {
+			s.membership.Lock()
+			m, ok := s.membership.members[id.String()]
+			s.membership.Unlock()
+			if ok {
+				ack = s.ping(m.Shard.Address, "")
+			}
+		}
+	}
+	return &api.PingResponse{
+		Ack:     ack,
+		Members: ToMembers(s.MemberList()),
+		Config:  cluster.ToConfiguration(s.cluster()),
+	}, nil
+}
+
+// Members returns the cluster membership as seen by this server
+func (s *DBServer) Members(ctx context.Context, request *api.EmptyRequest) (*api.MembersResponse, error) {
+	response := &api.MembersResponse{
+		Members: ToMembers(s.MemberList()),
+		Events:  make([]*api.MemberEvent, 0),
+	}
+	for _, e := range s.MemberEvents() {
+		response.Events = append(response.Events, &api.MemberEvent{
+			Time:    e.Time.UnixNano(),
+			ID:      e.Shard.ID.String(),
+			Address: e.Shard.Address,
+			From:    e.From,
+			To:      e.To,
+		})
+	}
+	if config := s.cluster(); config != nil {
+		response.Epoch = config.Epoch
+	}
+	return response, nil
+}
+
+// ToMembers converts members to their API representation
+func ToMembers(members []Member) []*api.Member {
+	result := make([]*api.Member, 0, len(members))
+	for _, m := range members {
+		result = append(result, &api.Member{
+			ID:          m.Shard.ID.String(),
+			Address:     m.Shard.Address,
+			State:       m.State,
+			Incarnation: m.Incarnation,
+			Updated:     m.Updated.UnixNano(),
+		})
+	}
+	return result
+}
+
+// FromMembers converts API members to members.  Members with invalid IDs are skipped.
+func FromMembers(members []*api.Member) []Member {
+	result := make([]Member, 0, len(members))
+	for _, m := range members {
+		id, err := uuid.FromString(m.ID)
+		if err != nil {
+			continue
+		}
+		result = append(result, Member{
+			Shard:       cluster.Shard{ID: id, Address: m.Address},
+			State:       m.State,
+			Incarnation: m.Incarnation,
+			Updated:     time.Unix(0, m.Updated),
+		})
+	}
+	return result
+}
@@ -0,0 +1,133 @@
+/******
+This file is part of Vaelen/DB.
+
+Copyright 2017, Andrew Young <andrew@vaelen.org>
+
+    Vaelen/DB is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+    Vaelen/DB is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+    You should have received a copy of the GNU General Public License
+along with Vaelen/DB.  If not, see <http://www.gnu.org/licenses/>.
+******/
+
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/vaelen/db/api"
+	"github.com/vaelen/db/cluster"
+)
+
+var testGossip = GossipConfig{
+	Interval:       20 * time.Millisecond,
+	Timeout:        100 * time.Millisecond,
+	IndirectProbes: 1,
+	SuspectTimeout: 200 * time.Millisecond,
+}
+
+// waitFor polls the given condition until it is true or the timeout passes
+func waitFor(timeout time.Duration, condition func() bool) bool {
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if condition() {
+			return true
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return condition()
+}
+
+// TestSupersedes tests the precedence of membership updates
+func TestSupersedes(t *testing.T) {
+	alive, suspect, dead := api.MemberState_ALIVE, api.MemberState_SUSPECT, api.MemberState_DEAD
+	tests := []struct {
+		update   Member
+		current  Member
+		expected bool
+	}{
+		{Member{State: alive, Incarnation: 1}, Member{State: alive, Incarnation: 0}, true},
+		{Member{State: alive, Incarnation: 0}, Member{State: alive, Incarnation: 0}, false},
+		{Member{State: alive, Incarnation: 1}, Member{State: suspect, Incarnation: 0}, true},
+		{Member{State: alive, Incarnation: 0}, Member{State: suspect, Incarnation: 0}, false},
+		{Member{State: suspect, Incarnation: 0}, Member{State: alive, Incarnation: 0}, true},
+		{Member{State: suspect, Incarnation: 0}, Member{State: alive, Incarnation: 1}, false},
+		{Member{State: suspect, Incarnation: 0}, Member{State: suspect, Incarnation: 0}, false},
+		{Member{State: dead, Incarnation: 0}, Member{State: suspect, Incarnation: 0}, true},
+		{Member{State: dead, Incarnation: 0}, Member{State: alive, Incarnation: 1}, false},
+		{Member{State: suspect, Incarnation: 5}, Member{State: dead, Incarnation: 0}, false},
+		{Member{State: alive, Incarnation: 1}, Member{State: dead, Incarnation: 0}, true},
+	}
+	for i, test := range tests {
+		if actual := supersedes(test.update, test.current); actual != test.expected {
+			t.Errorf("Test %d: expected %v, got %v\n", i, test.expected, actual)
+		}
+	}
+}
+
+// TestGossip tests failure detection, recovery and configuration propagation
+func TestGossip(t *testing.T) {
+	config := newCluster(30160, 3)
+	servers := make([]*DBServer, 0, 3)
+	stops := make([]func(), 0, 3)
+	for _, shard := range config.Shards {
+		s, stop := startServer(t, config, shard)
+		s.Gossip = testGossip
+		s.StartGossip(nil)
+		servers = append(servers, s)
+		stops = append(stops, stop)
+	}
+	defer func() {
+		for _, stop := range stops {
+			stop()
+		}
+	}()
+
+	a, c := servers[0], servers[2]
+	state := func(s *DBServer, shard cluster.Shard) api.MemberState {
+		for _, m := range s.MemberList() {
+			if m.Shard.ID == shard.ID {
+				return m.State
+			}
+		}
+		return -1
+	}
+
+	stops[2]()
+	stops[2] = func() {}
+	if !waitFor(5*time.Second, func() bool { return state(a, c.Self) == api.MemberState_DEAD }) {
+		t.Fatalf("Expected stopped member to be declared dead, got %s\n", state(a, c.Self))
+	}
+
+	// A restarted member has to refute its death with a new incarnation
+	restarted, stop := startServer(t, config, config.Shards[2])
+	stops[2] = stop
+	restarted.Gossip = testGossip
+	restarted.StartGossip([]string{a.Self.Address})
+	if !waitFor(5*time.Second, func() bool { return state(a, c.Self) == api.MemberState_ALIVE }) {
+		t.Fatalf("Expected restarted member to be alive, got %s\n", state(a, c.Self))
+	}
+
+	events := a.MemberEvents()
+	if len(events) < 2 || events[len(events)-1].To != api.MemberState_ALIVE {
+		t.Errorf("Unexpected membership events: %v\n", events)
+	}
+
+	// Configuration changes spread to every member
+	updated := *config
+	updated.Epoch = config.Epoch + 1
+	a.SetClusterConfig(&updated)
+	for i, s := range []*DBServer{servers[1], restarted} {
+		if !waitFor(5*time.Second, func() bool { return s.cluster().Epoch == updated.Epoch }) {
+			t.Errorf("Server %d did not receive the new configuration\n", i)
+		}
+	}
+}
@@ -0,0 +1,209 @@
+/******
+This file is part of Vaelen/DB.
+
+Copyright 2017, Andrew Young <andrew@vaelen.org>
+
+    Vaelen/DB is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+    Vaelen/DB is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+    You should have received a copy of the GNU General Public License
+along with Vaelen/DB.  If not, see <http://www.gnu.org/licenses/>.
+******/
+
+package server
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vaelen/db/cluster"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+// HealthService is the name the health service reports the readiness of the Database service under.
+// The empty name, which stands for the whole server, reports the same status.
+const HealthService = "api.Database"
+
+// MigrationTimeout is how long a shard that gained keys in a configuration change waits for anti-entropy to reach
+// every other shard before it serves requests again
+var MigrationTimeout = 10 * time.Minute
+
+// readiness tracks what keeps the server from serving requests and reports it through the gRPC health service
+type readiness struct {
+	sync.Mutex
+	health *health.Server
+	// loaded is closed once storage has been loaded and the transaction log replayed
+	loaded chan bool
+	ready  bool
+	// migrating is when a configuration change last moved keys to this shard.  It is zero once anti-entropy has caught up.
+	migrating time.Time
+}
+
+func newReadiness() *readiness {
+	r := &readiness{
+		health: health.NewServer(),
+		loaded: make(chan bool),
+	}
+	r.update()
+	return r
+}
+
+// update reports the current status to the health service.  The caller must hold the lock.
+func (r *readiness) update() {
+	state := healthpb.HealthCheckResponse_NOT_SERVING
+	if r.ready && r.migrating.IsZero() {
+		state = healthpb.HealthCheckResponse_SERVING
+	}
+	r.health.SetServingStatus("", state)
+	r.health.SetServingStatus(HealthService, state)
+}
+
+// setLoaded marks the server as loaded
+func (r *readiness) setLoaded() {
+	r.Lock()
+	defer r.Unlock()
+	r.ready = true
+	close(r.loaded)
+	r.update()
+}
+
+// setMigrating marks the shard as migrating from the given time on
+func (r *readiness) setMigrating(since time.Time) {
+	r.Lock()
+	defer r.Unlock()
+	r.migrating = since
+	r.update()
+}
+
+// migratingSince returns when the current migration started, or the zero time if the shard is not migrating
+func (r *readiness) migratingSince() time.Time {
+	r.Lock()
+	defer r.Unlock()
+	return r.migrating
+}
+
+// synced clears the migration if it started before the given anti-entropy run
+func (r *readiness) synced(run time.Time) {
+	r.Lock()
+	defer r.Unlock()
+	if r.migrating.IsZero() || r.migrating.After(run) {
+		return
+	}
+	r.migrating = time.Time{}
+	r.update()
+}
+
+// Health returns the gRPC health service, which should be registered on the gRPC server hosting this server.
+// It reports NOT_SERVING until storage has been loaded and the transaction log replayed, while keys moved to this shard
+// by a configuration change are being copied by anti-entropy, and once the server has stopped.
+func (s *DBServer) Health() *health.Server {
+	return s.readiness.health
+}
+
+// Loaded returns a channel that is closed once storage has been loaded and the transaction log replayed
+func (s *DBServer) Loaded() <-chan bool {
+	return s.readiness.loaded
+}
+
+// load waits for storage to be loaded and replays the intents of prepared transactions before marking the server as loaded
+func (s *DBServer) load() {
+	<-s.Storage.Loaded()
+	s.restoreIntents()
+	s.readiness.setLoaded()
+	s.Logger.Info("Loaded")
+}
+
+// waitLoaded waits until the server is loaded or the request in ctx is cancelled
+func (s *DBServer) waitLoaded(ctx context.Context) error {
+	select {
+	case <-s.readiness.loaded:
+		return nil
+	case <-ctx.Done():
+		return status.Errorf(codes.Unavailable, "the server is still loading")
+	}
+}
+
+// isDatabaseMethod returns true if the method belongs to the Database service rather than to the health or reflection services
+func isDatabaseMethod(method string) bool {
+	return strings.HasPrefix(method, "/"+HealthService+"/")
+}
+
+// checkMigration ends the migration once anti-entropy has compared this node with every shard that gossip has not declared dead
+// since the migration started, or once the migration has lasted MigrationTimeout
+func (s *DBServer) checkMigration() {
+	since := s.readiness.migratingSince()
+	if since.IsZero() {
+		return
+	}
+	if time.Since(since) >= MigrationTimeout {
+		s.Logger.Warn("Serving before anti-entropy has reached every shard since the configuration change", "since", since, "timeout", MigrationTimeout)
+		s.readiness.synced(since)
+		return
+	}
+	for _, shard := range s.others() {
+		if !s.isDead(shard) && s.peerSync(shard).Before(since) {
+			return
+		}
+	}
+	s.readiness.synced(since)
+}
+
+// gainsKeys returns true if this shard becomes a replica of keys under the new configuration that it was not a replica of before,
+// for the server's replication factor or that of any cached namespace configuration
+func (s *DBServer) gainsKeys(old *cluster.Config, config *cluster.Config) bool {
+	if old == nil {
+		return true
+	}
+	if !movesKeys(old, config) {
+		return false
+	}
+	previous, err := cluster.NewPlacement(old)
+	if err != nil {
+		return true
+	}
+	placement, err := cluster.NewPlacement(config)
+	if err != nil {
+		return true
+	}
+	factors := map[int]bool{s.Replication.N: true}
+	s.namespaces.Lock()
+	for _, namespace := range s.namespaces.configs {
+		if namespace.Replicas > 0 {
+			factors[namespace.Replicas] = true
+		}
+	}
+	s.namespaces.Unlock()
+	for n := range factors {
+		if cluster.Gained(previous, placement, s.Self.ID, n) {
+			return true
+		}
+	}
+	return false
+}
+
+// movesKeys returns true if keys may be assigned to different shards under the new configuration
+func movesKeys(old *cluster.Config, config *cluster.Config) bool {
+	if old == nil || old.Size != config.Size || len(old.Chunks) != len(config.Chunks) || old.Strategy != config.Strategy ||
+		old.VirtualNodes != config.VirtualNodes || len(old.Shards) != len(config.Shards) {
+		return true
+	}
+	for i, shard := range old.Shards {
+		if shard.ID != config.Shards[i].ID || shard.EffectiveWeight() != config.Shards[i].EffectiveWeight() {
+			return true
+		}
+	}
+	return false
+}
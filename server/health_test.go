@@ -0,0 +1,187 @@
+/******
+This file is part of Vaelen/DB.
+
+Copyright 2017, Andrew Young <andrew@vaelen.org>
+
+    Vaelen/DB is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+    Vaelen/DB is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+    You should have received a copy of the GNU General Public License
+along with Vaelen/DB.  If not, see <http://www.gnu.org/licenses/>.
+******/
+
+package server
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/satori/go.uuid"
+	"github.com/vaelen/db/api"
+	"github.com/vaelen/db/cluster"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1"
+)
+
+// TestReadiness tests the status reported while the server loads and while keys move after a configuration change
+func TestReadiness(t *testing.T) {
+	r := newReadiness()
+	check := func(expected healthpb.HealthCheckResponse_ServingStatus) {
+		for _, service := range []string{"", HealthService} {
+			response, err := r.health.Check(context.Background(), &healthpb.HealthCheckRequest{Service: service})
+			if err != nil {
+				t.Fatalf("Check Error: %s\n", err.Error())
+			}
+			if response.Status != expected {
+				t.Fatalf("Service %q: expected %s, got %s\n", service, expected, response.Status)
+			}
+		}
+	}
+	check(healthpb.HealthCheckResponse_NOT_SERVING)
+	r.setLoaded()
+	check(healthpb.HealthCheckResponse_SERVING)
+
+	changed := time.Now()
+	r.setMigrating(changed)
+	check(healthpb.HealthCheckResponse_NOT_SERVING)
+	r.synced(changed.Add(-time.Second))
+	check(healthpb.HealthCheckResponse_NOT_SERVING)
+	r.synced(changed.Add(time.Second))
+	check(healthpb.HealthCheckResponse_SERVING)
+
+	a, _ := uuid.FromString("00000000-0000-0000-0000-000000000001")
+	b, _ := uuid.FromString("00000000-0000-0000-0000-000000000002")
+	config := &cluster.Config{Shards: []cluster.Shard{{ID: a}, {ID: b}}}
+	if !movesKeys(nil, config) {
+		t.Errorf("Expected the first configuration to move keys\n")
+	}
+	if movesKeys(config, &cluster.Config{Shards: []cluster.Shard{{ID: a, Address: "a:5555", Weight: 1}, {ID: b}}}) {
+		t.Errorf("Expected a new address not to move keys\n")
+	}
+	if !movesKeys(config, &cluster.Config{Shards: []cluster.Shard{{ID: a, Weight: 2}, {ID: b}}}) {
+		t.Errorf("Expected a new weight to move keys\n")
+	}
+	if !movesKeys(config, &cluster.Config{Shards: []cluster.Shard{{ID: a}}}) {
+		t.Errorf("Expected a removed shard to move keys\n")
+	}
+
+	c, _ := uuid.FromString("00000000-0000-0000-0000-000000000003")
+	s := New(testLogs, "")
+	s.Self = cluster.Shard{ID: a}
+	s.Replication.N = 1
+	ring := &cluster.Config{Strategy: cluster.RingStrategy, Shards: []cluster.Shard{{ID: a}, {ID: b}}}
+	grown := &cluster.Config{Strategy: cluster.RingStrategy, Shards: []cluster.Shard{{ID: a}, {ID: b}, {ID: c}}}
+	if s.gainsKeys(ring, grown) {
+		t.Errorf("Expected an existing shard not to gain keys from a new shard\n")
+	}
+	if !s.gainsKeys(grown, ring) {
+		t.Errorf("Expected an existing shard to gain keys from a removed shard\n")
+	}
+
+	s.Cluster = grown
+	s.Self = cluster.Shard{ID: c}
+	changed = time.Now()
+	s.readiness.setMigrating(changed)
+	s.antiEntropy.stats.PeerSync = map[string]time.Time{a.String(): changed}
+	s.checkMigration()
+	if s.readiness.migratingSince().IsZero() {
+		t.Fatalf("Expected the migration to wait for every peer\n")
+	}
+	s.antiEntropy.stats.PeerSync[b.String()] = changed.Add(time.Second)
+	s.checkMigration()
+	if !s.readiness.migratingSince().IsZero() {
+		t.Fatalf("Expected the migration to end once every peer synced\n")
+	}
+
+	s.readiness.setMigrating(changed.Add(-MigrationTimeout))
+	s.antiEntropy.stats.PeerSync = nil
+	s.checkMigration()
+	if !s.readiness.migratingSince().IsZero() {
+		t.Fatalf("Expected the migration to end after the timeout\n")
+	}
+}
+
+// TestHealth tests the health and reflection services, which answer without credentials
+func TestHealth(t *testing.T) {
+	s := New(testLogs, "")
+	s.Auth = NewAuth("secret")
+	lis, err := net.Listen("tcp", "localhost:30372")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	grpcServer := grpc.NewServer(grpc.UnaryInterceptor(s.UnaryInterceptor), grpc.StreamInterceptor(s.StreamInterceptor))
+	api.RegisterDatabaseServer(grpcServer, s)
+	healthpb.RegisterHealthServer(grpcServer, s.Health())
+	reflection.Register(grpcServer)
+	go grpcServer.Serve(lis)
+	defer grpcServer.Stop()
+
+	conn, err := grpc.Dial("localhost:30372", grpc.WithInsecure())
+	if err != nil {
+		t.Fatalf("Dial Error: %s\n", err.Error())
+	}
+	defer conn.Close()
+	ctx := context.Background()
+
+	<-s.Loaded()
+	health := healthpb.NewHealthClient(conn)
+	response, err := health.Check(ctx, &healthpb.HealthCheckRequest{Service: HealthService})
+	if err != nil {
+		t.Fatalf("Check Error: %s\n", err.Error())
+	}
+	if response.Status != healthpb.HealthCheckResponse_SERVING {
+		t.Fatalf("Expected the loaded server to be serving, got %s\n", response.Status)
+	}
+	if _, err := api.NewDatabaseClient(conn).Get(ctx, &api.IDRequest{ID: "foo"}); err == nil {
+		t.Fatalf("Expected the Database service to still require credentials\n")
+	}
+
+	stream, err := reflectionpb.NewServerReflectionClient(conn).ServerReflectionInfo(ctx)
+	if err != nil {
+		t.Fatalf("ServerReflectionInfo Error: %s\n", err.Error())
+	}
+	if err := stream.Send(&reflectionpb.ServerReflectionRequest{MessageRequest: &reflectionpb.ServerReflectionRequest_ListServices{}}); err != nil {
+		t.Fatalf("Send Error: %s\n", err.Error())
+	}
+	reply, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("Recv Error: %s\n", err.Error())
+	}
+	services := make(map[string]bool)
+	for _, service := range reply.GetListServicesResponse().GetService() {
+		services[service.Name] = true
+	}
+	if !services[HealthService] || !services["grpc.health.v1.Health"] {
+		t.Fatalf("Unexpected services: %v\n", services)
+	}
+	if err := stream.Send(&reflectionpb.ServerReflectionRequest{MessageRequest: &reflectionpb.ServerReflectionRequest_FileContainingSymbol{FileContainingSymbol: HealthService}}); err != nil {
+		t.Fatalf("Send Error: %s\n", err.Error())
+	}
+	if reply, err = stream.Recv(); err != nil {
+		t.Fatalf("Recv Error: %s\n", err.Error())
+	}
+	if len(reply.GetFileDescriptorResponse().GetFileDescriptorProto()) == 0 {
+		t.Fatalf("Expected the descriptor of the Database service, got %v\n", reply)
+	}
+	stream.CloseSend()
+
+	s.Stop()
+	if response, err = health.Check(ctx, &healthpb.HealthCheckRequest{Service: HealthService}); err != nil {
+		t.Fatalf("Check Error: %s\n", err.Error())
+	}
+	if response.Status != healthpb.HealthCheckResponse_NOT_SERVING {
+		t.Fatalf("Expected the stopped server not to be serving, got %s\n", response.Status)
+	}
+}
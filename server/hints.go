@@ -0,0 +1,407 @@
+/******
+This file is part of Vaelen/DB.
+
+Copyright 2017, Andrew Young <andrew@vaelen.org>
+
+    Vaelen/DB is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+    Vaelen/DB is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+    You should have received a copy of the GNU General Public License
+along with Vaelen/DB.  If not, see <http://www.gnu.org/licenses/>.
+******/
+
+package server
+
+import (
+	"bytes"
+	"encoding/gob"
+	"log/slog"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/satori/go.uuid"
+	"github.com/vaelen/db/api"
+	"github.com/vaelen/db/logging"
+	"github.com/vaelen/db/storage"
+
+	"golang.org/x/net/context"
+)
+
+var (
+	// DefaultHintWindow is how long hints are kept before they expire
+	DefaultHintWindow = 3 * time.Hour
+	// DefaultMaxHints is the maximum number of hints a server will keep
+	DefaultMaxHints = 100000
+	// DefaultHintFlushInterval is how long changes to the hints may wait before they are saved
+	DefaultHintFlushInterval = time.Second
+)
+
+// Hint is a write that could not be delivered to one of a key's replicas.
+// Hints are also kept for writes that failed because too few replicas acknowledged them,
+// so a write reported as Unavailable may still reach every replica later.
+type Hint struct {
+	// Target is the ID of the shard the write was meant for
+	Target   string
+	Key      string
+	Versions []storage.NodeKeyValuePair
+	// Created is when the first undelivered write for this key was stored
+	Created time.Time
+}
+
+// HintStats reports the activity of a hint store
+type HintStats struct {
+	// Pending is the number of hints waiting to be delivered
+	Pending int
+	// Stored is the number of hints that have been stored
+	Stored uint64
+	// Replayed is the number of hints that have been delivered
+	Replayed uint64
+	// Expired is the number of hints that were discarded because they were older than the hint window
+	Expired uint64
+	// Dropped is the number of hints that were discarded because the store was full
+	Dropped uint64
+}
+
+// hintID identifies the hint for a key on a target
+type hintID struct {
+	target string
+	key    string
+}
+
+// HintStore holds writes for replicas that are down until they can be replayed.
+// Changes are saved in batches at most once every FlushInterval, so hints stored just before a crash may be lost.
+type HintStore struct {
+	sync.Mutex
+	// Logger is the logger used by the hint store
+	Logger *slog.Logger
+	// Path is the directory the hints are saved in.  Hints are only kept in memory when it is empty.
+	Path string
+	// Keyring encrypts the saved hints.  They are not encrypted when it is nil.
+	Keyring *storage.Keyring
+	// Window is how long a hint is kept before it expires
+	Window time.Duration
+	// Max is the maximum number of hints that will be kept
+	Max int
+	// FlushInterval is how long changes may wait before they are saved
+	FlushInterval time.Duration
+	hints         []*Hint
+	index         map[hintID]*Hint
+	stats         HintStats
+	// flush is the timer that saves the pending changes, it is nil when there are none
+	flush *time.Timer
+}
+
+// NewHintStore creates a hint store and loads any hints saved in the given directory
+func NewHintStore(logger *slog.Logger, dbPath string, keyring *storage.Keyring, window time.Duration, max int) *HintStore {
+	h := &HintStore{
+		Logger:        logger,
+		Path:          dbPath,
+		Keyring:       keyring,
+		Window:        window,
+		Max:           max,
+		FlushInterval: DefaultHintFlushInterval,
+		hints:         make([]*Hint, 0),
+		index:         make(map[hintID]*Hint),
+	}
+	h.load()
+	return h
+}
+
+// Add stores a hint.  If a hint for the same key and target already exists the versions are reconciled with it.
+func (h *HintStore) Add(hint Hint) {
+	h.Lock()
+	defer h.Unlock()
+	h.add(hint)
+	h.stats.Stored++
+	h.changed()
+}
+
+// Restore puts back hints that were taken but could not be delivered
+func (h *HintStore) Restore(hints []Hint) {
+	h.Lock()
+	defer h.Unlock()
+	for _, hint := range hints {
+		h.add(hint)
+	}
+	h.changed()
+}
+
+// add stores a hint.  The caller must hold the lock.
+func (h *HintStore) add(hint Hint) {
+	id := hintID{target: hint.Target, key: hint.Key}
+	if existing, ok := h.index[id]; ok {
+		for _, v := range hint.Versions {
+			existing.Versions, _ = storage.Reconcile(existing.Versions, v)
+		}
+		if hint.Created.Before(existing.Created) {
+			existing.Created = hint.Created
+		}
+		return
+	}
+
+	if h.Max > 0 && len(h.hints) >= h.Max {
+		// Drop the oldest hint to make room
+		oldest := h.hints[0]
+		h.Logger.Warn("Hint store full, dropping hint", "target", oldest.Target, "key", oldest.Key)
+		delete(h.index, hintID{target: oldest.Target, key: oldest.Key})
+		h.hints[0] = nil
+		h.hints = h.hints[1:]
+		h.stats.Dropped++
+	}
+	h.hints = append(h.hints, &hint)
+	h.index[id] = &hint
+}
+
+// keep keeps only the hints for which the given function returns true and returns the others.  The caller must hold the lock.
+func (h *HintStore) keep(f func(hint *Hint) bool) []Hint {
+	removed := make([]Hint, 0)
+	remaining := make([]*Hint, 0, len(h.hints))
+	for _, hint := range h.hints {
+		if f(hint) {
+			remaining = append(remaining, hint)
+			continue
+		}
+		removed = append(removed, *hint)
+		delete(h.index, hintID{target: hint.Target, key: hint.Key})
+	}
+	h.hints = remaining
+	return removed
+}
+
+// Take removes and returns every hint for the given target
+func (h *HintStore) Take(target string) []Hint {
+	h.Lock()
+	defer h.Unlock()
+	taken := h.keep(func(hint *Hint) bool {
+		return hint.Target != target
+	})
+	if len(taken) > 0 {
+		h.changed()
+	}
+	return taken
+}
+
+// Targets returns the IDs of every shard that has pending hints
+func (h *HintStore) Targets() []string {
+	h.Lock()
+	defer h.Unlock()
+	seen := make(map[string]bool)
+	targets := make([]string, 0)
+	for _, hint := range h.hints {
+		if !seen[hint.Target] {
+			seen[hint.Target] = true
+			targets = append(targets, hint.Target)
+		}
+	}
+	return targets
+}
+
+// Replayed records that the given number of hints were delivered
+func (h *HintStore) Replayed(count int) {
+	h.Lock()
+	defer h.Unlock()
+	h.stats.Replayed += uint64(count)
+}
+
+// Expire discards hints that are older than the hint window and returns the number discarded
+func (h *HintStore) Expire(now time.Time) int {
+	h.Lock()
+	defer h.Unlock()
+	if h.Window <= 0 {
+		return 0
+	}
+	expired := len(h.keep(func(hint *Hint) bool {
+		return now.Sub(hint.Created) <= h.Window
+	}))
+	if expired > 0 {
+		h.stats.Expired += uint64(expired)
+		h.changed()
+	}
+	return expired
+}
+
+// Stats returns statistics about the hint store
+func (h *HintStore) Stats() HintStats {
+	h.Lock()
+	defer h.Unlock()
+	stats := h.stats
+	stats.Pending = len(h.hints)
+	return stats
+}
+
+func (h *HintStore) filename() string {
+	return filepath.Join(h.Path, "hints.gob")
+}
+
+// changed schedules the hints to be saved unless a save is already pending.  The caller must hold the lock.
+func (h *HintStore) changed() {
+	if h.Path == "" || h.flush != nil {
+		return
+	}
+	h.flush = time.AfterFunc(h.FlushInterval, func() {
+		h.Flush()
+	})
+}
+
+// Flush saves any changes that are waiting to be saved
+func (h *HintStore) Flush() error {
+	h.Lock()
+	defer h.Unlock()
+	if h.flush == nil {
+		return nil
+	}
+	h.flush.Stop()
+	h.flush = nil
+	return h.save()
+}
+
+// save writes the hints to disk.  The caller must hold the lock.
+func (h *HintStore) save() error {
+	if h.Path == "" {
+		return nil
+	}
+	filename := h.filename()
+	var b bytes.Buffer
+	err := gob.NewEncoder(&b).Encode(h.hints)
+	if err == nil {
+		err = h.Keyring.WriteFile(filename, b.Bytes())
+	}
+	if err != nil {
+		h.Logger.Error("Could not save hints", "file", filename, "error", err)
+	}
+	return err
+}
+
+// Reencrypt writes the hints to disk again so that they are encrypted with the active data key
+func (h *HintStore) Reencrypt() error {
+	h.Lock()
+	defer h.Unlock()
+	return h.save()
+}
+
+func (h *HintStore) load() {
+	if h.Path == "" {
+		return
+	}
+	filename := h.filename()
+	b, err := h.Keyring.ReadFile(filename)
+	if storage.IsKeyError(err) {
+		// Saving over hints that could not be read would lose them
+		logging.Fatal(h.Logger, "Could not decrypt hints", "file", filename, "error", err)
+	}
+	if err != nil {
+		return
+	}
+	hints := make([]*Hint, 0)
+	err = gob.NewDecoder(bytes.NewReader(b)).Decode(&hints)
+	if err != nil {
+		h.Logger.Warn("Could not load hints", "file", filename, "error", err)
+		return
+	}
+	for _, hint := range hints {
+		h.add(*hint)
+	}
+	h.Logger.Info("Hints loaded", "file", filename, "pending", len(h.hints))
+}
+
+// handoff holds the state of the hint replay job
+type handoff struct {
+	sync.Mutex
+	stop chan bool
+}
+
+// StartHintedHandoff starts replaying hints to shards that have come back at the given interval
+func (s *DBServer) StartHintedHandoff(interval time.Duration) {
+	s.handoff.Lock()
+	defer s.handoff.Unlock()
+	if s.handoff.stop != nil {
+		return
+	}
+	stop := make(chan bool)
+	s.handoff.stop = stop
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				s.ReplayHints()
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
+
+// stopHintedHandoff stops the hint replay job if it is running
+func (s *DBServer) stopHintedHandoff() {
+	s.handoff.Lock()
+	defer s.handoff.Unlock()
+	if s.handoff.stop != nil {
+		close(s.handoff.stop)
+		s.handoff.stop = nil
+	}
+}
+
+// HintStats returns statistics about the hints held by this server
+func (s *DBServer) HintStats() HintStats {
+	return s.Hints.Stats()
+}
+
+// ReplayHints expires old hints and delivers the rest to their targets.
+// Delivery to a target stops at the first failure and the remaining hints are kept for the next attempt.
+// Targets that gossip has declared dead are skipped until they come back.
+// It returns the number of hints delivered.
+func (s *DBServer) ReplayHints() int {
+	expired := s.Hints.Expire(time.Now())
+	if expired > 0 {
+		s.Logger.Info("Hints expired", "count", expired)
+	}
+	if !s.replicated() {
+		return 0
+	}
+
+	delivered := 0
+	for _, target := range s.Hints.Targets() {
+		id, err := uuid.FromString(target)
+		if err != nil {
+			continue
+		}
+		shard, ok := s.cluster().Shard(id)
+		if !ok {
+			// The shard has left the cluster, its hints will expire
+			continue
+		}
+		if s.isDead(shard) {
+			continue
+		}
+		c, err := s.peers.client(shard)
+		if err != nil {
+			continue
+		}
+		hints := s.Hints.Take(target)
+		for i, hint := range hints {
+			ctx, cancel := context.WithTimeout(context.Background(), s.Replication.Timeout)
+			_, err = c.ReplicaSet(ctx, &api.VersionedRequest{ID: hint.Key, Siblings: ToSiblings(hint.Versions)})
+			cancel()
+			if err != nil {
+				s.Hints.Restore(hints[i:])
+				break
+			}
+			delivered++
+		}
+	}
+	if delivered > 0 {
+		s.Hints.Replayed(delivered)
+		s.Logger.Info("Hints replayed", "count", delivered)
+	}
+	return delivered
+}
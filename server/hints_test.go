@@ -0,0 +1,173 @@
+/******
+This file is part of Vaelen/DB.
+
+Copyright 2017, Andrew Young <andrew@vaelen.org>
+
+    Vaelen/DB is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+    Vaelen/DB is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+    You should have received a copy of the GNU General Public License
+along with Vaelen/DB.  If not, see <http://www.gnu.org/licenses/>.
+******/
+
+package server
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/vaelen/db/api"
+	"github.com/vaelen/db/storage"
+
+	"golang.org/x/net/context"
+)
+
+// TestHintStore tests that hints are bounded, expire and survive a restart
+func TestHintStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "vdb-hints")
+	if err != nil {
+		t.Fatalf("TempDir Error: %s\n", err.Error())
+	}
+	defer os.RemoveAll(dir)
+
+	logger := testLogs.Logger("hints")
+	h := NewHintStore(logger, dir, nil, time.Hour, 2)
+	h.FlushInterval = time.Hour
+	now := time.Now()
+	h.Add(Hint{Target: "a", Key: "1", Created: now.Add(-2 * time.Hour)})
+	h.Add(Hint{Target: "a", Key: "2", Created: now})
+	h.Add(Hint{Target: "b", Key: "3", Created: now})
+
+	stats := h.Stats()
+	if stats.Pending != 2 || stats.Dropped != 1 {
+		t.Fatalf("Expected oldest hint to be dropped, got %+v\n", stats)
+	}
+
+	h.Add(Hint{Target: "a", Key: "2", Created: now.Add(-2 * time.Hour)})
+	if expired := h.Expire(now); expired != 1 {
+		t.Fatalf("Expected 1 expired hint, got %d\n", expired)
+	}
+
+	// Changes are only saved once they are flushed
+	if _, err := os.Stat(filepath.Join(dir, "hints.gob")); !os.IsNotExist(err) {
+		t.Fatalf("Expected the hints to wait for a flush, got %v\n", err)
+	}
+	if err := h.Flush(); err != nil {
+		t.Fatalf("Flush Error: %s\n", err.Error())
+	}
+
+	loaded := NewHintStore(logger, dir, nil, time.Hour, 2)
+	targets := loaded.Targets()
+	if len(targets) != 1 || targets[0] != "b" {
+		t.Fatalf("Expected hints for b to be loaded, got %v\n", targets)
+	}
+	if hints := loaded.Take("b"); len(hints) != 1 || hints[0].Key != "3" {
+		t.Fatalf("Unexpected hints: %v\n", hints)
+	}
+	if stats := loaded.Stats(); stats.Pending != 0 {
+		t.Errorf("Expected no pending hints, got %+v\n", stats)
+	}
+}
+
+// TestHintedHandoff tests that writes to a replica that is down are delivered when it comes back
+func TestHintedHandoff(t *testing.T) {
+	config := newCluster(30140, 3)
+	a, stopA := startServer(t, config, config.Shards[0])
+	defer stopA()
+	_, stopB := startServer(t, config, config.Shards[1])
+	defer stopB()
+
+	ctx := context.Background()
+	_, err := a.Set(ctx, &api.IDValueRequest{ID: "foo", Value: "bar", Consistency: api.Consistency_QUORUM})
+	if err != nil {
+		t.Fatalf("Set Error: %s\n", err.Error())
+	}
+
+	// The hint is stored after the write to the missing replica fails, which may be after Set returns
+	deadline := time.Now().Add(5 * time.Second)
+	for a.HintStats().Pending != 1 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if stats := a.HintStats(); stats.Pending != 1 {
+		t.Fatalf("Expected 1 pending hint, got %+v\n", stats)
+	}
+
+	if delivered := a.ReplayHints(); delivered != 0 {
+		t.Fatalf("Expected no hints to be delivered while the replica is down, got %d\n", delivered)
+	}
+
+	// Hints for a replica that gossip has declared dead are not attempted
+	a.applyMembers([]Member{{Shard: config.Shards[2], State: api.MemberState_DEAD, Incarnation: 1}})
+	started := time.Now()
+	if delivered := a.ReplayHints(); delivered != 0 || time.Since(started) >= a.Replication.Timeout {
+		t.Fatalf("Expected hints for a dead replica to be skipped, delivered %d in %s\n", delivered, time.Since(started))
+	}
+	if stats := a.HintStats(); stats.Pending != 1 {
+		t.Fatalf("Expected the hint to be kept for a dead replica, got %+v\n", stats)
+	}
+	a.membership.Lock()
+	delete(a.membership.members, config.Shards[2].ID.String())
+	a.membership.Unlock()
+
+	c, stopC := startServer(t, config, config.Shards[2])
+	defer stopC()
+
+	// The connection to the replica may still be waiting to reconnect
+	delivered := 0
+	deadline = time.Now().Add(10 * time.Second)
+	for delivered == 0 && time.Now().Before(deadline) {
+		delivered = a.ReplayHints()
+		time.Sleep(50 * time.Millisecond)
+	}
+	if delivered != 1 {
+		t.Fatalf("Expected 1 hint to be delivered, got %d\n", delivered)
+	}
+	if v := c.Storage.Get("foo"); v != "bar" {
+		t.Errorf("Expected replayed value bar, got %q\n", v)
+	}
+	if stats := a.HintStats(); stats.Pending != 0 || stats.Replayed != 1 {
+		t.Errorf("Unexpected hint stats: %+v\n", stats)
+	}
+}
+
+// TestReadRepair tests that stale replicas are repaired after a read
+func TestReadRepair(t *testing.T) {
+	servers, stop := startCluster(t, 30150, 3)
+	defer stop()
+
+	key := "foo"
+	pair := storage.NodeKeyValuePair{
+		Key:     key,
+		Value:   "bar",
+		Version: storage.VersionVector{servers[0].Self.ID.String(): 1},
+	}
+	servers[0].Storage.PutVersions(key, []storage.NodeKeyValuePair{pair})
+
+	_, err := servers[1].Get(context.Background(), &api.IDRequest{ID: key, Consistency: api.Consistency_ALL})
+	if err != nil {
+		t.Fatalf("Get Error: %s\n", err.Error())
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for servers[1].ReadRepairs() < 2 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if repairs := servers[1].ReadRepairs(); repairs != 2 {
+		t.Fatalf("Expected 2 read repairs, got %d\n", repairs)
+	}
+	for i, s := range servers {
+		if v := s.Storage.Get(key); v != "bar" {
+			t.Errorf("Replica %d has value %q, expected bar\n", i, v)
+		}
+	}
+}
@@ -0,0 +1,215 @@
+/******
+This file is part of Vaelen/DB.
+
+Copyright 2017, Andrew Young <andrew@vaelen.org>
+
+    Vaelen/DB is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+    Vaelen/DB is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+    You should have received a copy of the GNU General Public License
+along with Vaelen/DB.  If not, see <http://www.gnu.org/licenses/>.
+******/
+
+package server
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/vaelen/db/api"
+	"github.com/vaelen/db/storage"
+	"github.com/vaelen/db/trace"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// DefaultMaxClockOffset is how far ahead of the local clock a remote timestamp may be before it is rejected
+var DefaultMaxClockOffset = 500 * time.Millisecond
+
+// clockHeader is the metadata key used to carry the sender's hybrid logical clock on inter-node RPCs
+const clockHeader = "vdb-hlc"
+
+// HLC is a hybrid logical clock.
+// It follows the local wall clock but never goes backwards, and it moves past every timestamp it receives from other nodes
+// so that an event that causes another always has a smaller timestamp.
+type HLC struct {
+	sync.Mutex
+	// MaxOffset is how far ahead of the local clock a remote timestamp may be.  Zero disables the check.
+	MaxOffset time.Duration
+	now       func() time.Time
+	last      storage.Timestamp
+}
+
+// NewHLC creates a hybrid logical clock that follows the system clock
+func NewHLC(maxOffset time.Duration) *HLC {
+	return &HLC{
+		MaxOffset: maxOffset,
+		now:       time.Now,
+	}
+}
+
+// Now returns a timestamp that is greater than every timestamp the clock has returned or received
+func (c *HLC) Now() storage.Timestamp {
+	c.Lock()
+	defer c.Unlock()
+	physical := c.now().UnixNano()
+	if physical > c.last.Physical {
+		c.last = storage.Timestamp{Physical: physical}
+	} else {
+		c.last.Logical++
+	}
+	return c.last
+}
+
+// Update moves the clock past a timestamp received from another node and returns the new time.
+// The clock is left alone and an error is returned if the timestamp is further ahead of the local clock than MaxOffset.
+func (c *HLC) Update(remote storage.Timestamp) (storage.Timestamp, error) {
+	c.Lock()
+	defer c.Unlock()
+	physical := c.now().UnixNano()
+	if c.MaxOffset > 0 && remote.Physical-physical > int64(c.MaxOffset) {
+		return c.last, status.Errorf(codes.OutOfRange, "clock skew of %s exceeds the maximum of %s", time.Duration(remote.Physical-physical), c.MaxOffset)
+	}
+	switch {
+	case physical > c.last.Physical && physical > remote.Physical:
+		c.last = storage.Timestamp{Physical: physical}
+	case c.last.Physical == remote.Physical:
+		logical := c.last.Logical
+		if remote.Logical > logical {
+			logical = remote.Logical
+		}
+		c.last.Logical = logical + 1
+	case c.last.Physical > remote.Physical:
+		c.last.Logical++
+	default:
+		c.last = storage.Timestamp{Physical: remote.Physical, Logical: remote.Logical + 1}
+	}
+	return c.last, nil
+}
+
+// ToTimestamp converts a timestamp to its API representation
+func ToTimestamp(t storage.Timestamp) *api.Timestamp {
+	return &api.Timestamp{Physical: t.Physical, Logical: t.Logical}
+}
+
+// FromTimestamp converts an API timestamp to its storage representation
+func FromTimestamp(t *api.Timestamp) storage.Timestamp {
+	if t == nil {
+		return storage.Timestamp{}
+	}
+	return storage.Timestamp{Physical: t.Physical, Logical: t.Logical}
+}
+
+func formatClockHeader(t storage.Timestamp) string {
+	return fmt.Sprintf("%d.%d", t.Physical, t.Logical)
+}
+
+func parseClockHeader(s string) (storage.Timestamp, error) {
+	var t storage.Timestamp
+	_, err := fmt.Sscanf(s, "%d.%d", &t.Physical, &t.Logical)
+	return t, err
+}
+
+// receiveClock updates the clock from the timestamp carried in the given metadata, if there is one
+func (s *DBServer) receiveClock(md metadata.MD) error {
+	values := md.Get(clockHeader)
+	if len(values) == 0 {
+		return nil
+	}
+	remote, err := parseClockHeader(values[0])
+	if err != nil {
+		return status.Errorf(codes.InvalidArgument, "invalid clock header: %s", values[0])
+	}
+	_, err = s.Clock.Update(remote)
+	return err
+}
+
+// UnaryInterceptor should be installed on the gRPC server hosting this server.
+// It authenticates the caller when authentication is enabled, advances the clock past the timestamp sent by the calling node and sends this node's clock back.
+// The number and latency of requests are recorded for the metrics and a span is recorded for each request when tracing is enabled.
+// Changes are recorded in the audit log and requests that take longer than SlowThreshold in the slow operation log.
+// Requests wait for the server to load.  Those to the health and reflection services are passed straight through.
+func (s *DBServer) UnaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if !isDatabaseMethod(info.FullMethod) {
+		return handler(ctx, req)
+	}
+	start := time.Now()
+	ctx = receiveRequestID(ctx)
+	ctx, _ = withTimer(ctx)
+	ctx, span := s.Tracer.StartServer(ctx, info.FullMethod)
+	defer span.Finish()
+	ctx, response, err := s.intercept(ctx, req, info, handler)
+	trace.Status(span, err)
+	elapsed := time.Since(start)
+	s.metrics.observe(info.FullMethod, status.Code(err), elapsed)
+	s.audit(ctx, info.FullMethod, req, err)
+	s.slowOperation(ctx, info.FullMethod, req, err, elapsed)
+	return response, err
+}
+
+// intercept waits for the server to load, authorizes and rate limits a request and keeps the clock in step with the caller's.
+// It returns the context the request was handled with, which carries the caller's identity.
+func (s *DBServer) intercept(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (context.Context, interface{}, error) {
+	if err := s.waitLoaded(ctx); err != nil {
+		return ctx, nil, err
+	}
+	if s.Auth != nil {
+		var err error
+		if ctx, err = s.authorizeCall(ctx, info.FullMethod, requestKeys(req)); err != nil {
+			return ctx, nil, err
+		}
+	}
+	if err := s.limitCall(ctx, info.FullMethod, req); err != nil {
+		return ctx, nil, err
+	}
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if err := s.receiveClock(md); err != nil {
+			return ctx, nil, err
+		}
+	}
+	response, err := handler(ctx, req)
+	grpc.SetHeader(ctx, metadata.Pairs(clockHeader, formatClockHeader(s.Clock.Now())))
+	return ctx, response, err
+}
+
+// sendClock attaches this node's clock and credentials to requests sent to other nodes and advances the clock past the timestamp they reply with
+func (s *DBServer) sendClock(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	ctx = metadata.AppendToOutgoingContext(s.nodeCredentials(ctx), clockHeader, formatClockHeader(s.Clock.Now()))
+	var header metadata.MD
+	err := invoker(ctx, method, req, reply, cc, append(opts, grpc.Header(&header))...)
+	if clockErr := s.receiveClock(header); clockErr != nil {
+		s.Logger.WarnContext(ctx, "Ignoring clock", "address", cc.Target(), "error", clockErr)
+	}
+	return err
+}
+
+// Time returns the server's hybrid logical clock.
+// If the caller sends its own timestamp the clock is first moved past it, and the request fails if the caller's clock is too far ahead.
+func (s *DBServer) Time(ctx context.Context, request *api.TimeRequest) (*api.Response, error) {
+	var now storage.Timestamp
+	if request.Timestamp != nil {
+		var err error
+		now, err = s.Clock.Update(FromTimestamp(request.Timestamp))
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		now = s.Clock.Now()
+	}
+	return &api.Response{
+		Value:     now.String(),
+		Timestamp: ToTimestamp(now),
+	}, nil
+}
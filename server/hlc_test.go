@@ -0,0 +1,126 @@
+/******
+This file is part of Vaelen/DB.
+
+Copyright 2017, Andrew Young <andrew@vaelen.org>
+
+    Vaelen/DB is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+    Vaelen/DB is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+    You should have received a copy of the GNU General Public License
+along with Vaelen/DB.  If not, see <http://www.gnu.org/licenses/>.
+******/
+
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/vaelen/db/api"
+	"github.com/vaelen/db/storage"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// TestHLC tests that the clock never goes backwards and moves past remote timestamps
+func TestHLC(t *testing.T) {
+	wall := time.Unix(1000, 0)
+	c := NewHLC(time.Second)
+	c.now = func() time.Time { return wall }
+
+	a := c.Now()
+	b := c.Now()
+	if !a.Before(b) || b.Physical != wall.UnixNano() || b.Logical != 1 {
+		t.Fatalf("Clock did not advance. a: %v, b: %v\n", a, b)
+	}
+
+	// The wall clock going backwards must not move the clock backwards
+	wall = wall.Add(-time.Minute)
+	if d := c.Now(); !b.Before(d) {
+		t.Fatalf("Clock went backwards. b: %v, d: %v\n", b, d)
+	}
+
+	// A remote timestamp ahead of the local clock moves it forward
+	remote := storage.Timestamp{Physical: time.Unix(1000, 0).Add(500 * time.Millisecond).UnixNano(), Logical: 7}
+	wall = time.Unix(1000, 0)
+	updated, err := c.Update(remote)
+	if err != nil {
+		t.Fatalf("Update Error: %s\n", err.Error())
+	}
+	if updated.Physical != remote.Physical || updated.Logical != 8 {
+		t.Fatalf("Unexpected timestamp after update: %v\n", updated)
+	}
+	if next := c.Now(); !updated.Before(next) {
+		t.Fatalf("Clock went backwards after update. updated: %v, next: %v\n", updated, next)
+	}
+
+	// A remote timestamp too far ahead is rejected and leaves the clock alone
+	before := c.Now()
+	_, err = c.Update(storage.Timestamp{Physical: wall.Add(time.Hour).UnixNano()})
+	if status.Code(err) != codes.OutOfRange {
+		t.Fatalf("Expected a clock skew error, got: %v\n", err)
+	}
+	if after := c.Now(); after.Physical != before.Physical {
+		t.Fatalf("Rejected timestamp moved the clock. before: %v, after: %v\n", before, after)
+	}
+}
+
+// TestClockPropagation tests that clocks are carried on inter-node RPCs and used to stamp writes
+func TestClockPropagation(t *testing.T) {
+	servers, stop := startCluster(t, 30220, 2)
+	defer stop()
+	ahead := time.Now().Add(300 * time.Millisecond)
+	servers[1].Clock.Lock()
+	servers[1].Clock.now = func() time.Time { return ahead }
+	servers[1].Clock.Unlock()
+	ctx := context.Background()
+
+	_, err := servers[0].Set(ctx, &api.IDValueRequest{ID: "foo", Value: "bar", Consistency: api.Consistency_ALL})
+	if err != nil {
+		t.Fatalf("Set Error: %s\n", err.Error())
+	}
+	if now := servers[0].Clock.Now(); now.Physical < ahead.UnixNano() {
+		t.Fatalf("Clock was not carried back from the replica. Local: %v, Remote: %v\n", now, ahead)
+	}
+	versions := servers[1].Storage.GetVersions("foo")
+	if len(versions) != 1 || versions[0].Timestamp.IsZero() {
+		t.Fatalf("Write was not stamped: %v\n", versions)
+	}
+
+	response, err := servers[0].Time(ctx, &api.TimeRequest{})
+	if err != nil {
+		t.Fatalf("Time Error: %s\n", err.Error())
+	}
+	if !versions[0].Timestamp.Before(FromTimestamp(response.Timestamp)) {
+		t.Fatalf("Time went backwards. Write: %v, Time: %v\n", versions[0].Timestamp, response.Value)
+	}
+
+	skewed := ToTimestamp(storage.Timestamp{Physical: time.Now().Add(time.Hour).UnixNano()})
+	if _, err := servers[0].Time(ctx, &api.TimeRequest{Timestamp: skewed}); status.Code(err) != codes.OutOfRange {
+		t.Fatalf("Expected a clock skew error, got: %v\n", err)
+	}
+}
+
+// TestLatestSibling tests that the newest concurrent sibling is returned as the value
+func TestLatestSibling(t *testing.T) {
+	versions := []storage.NodeKeyValuePair{
+		{Key: "foo", Value: "new", Version: storage.VersionVector{"b": 1}, Timestamp: storage.Timestamp{Physical: 2}},
+		{Key: "foo", Value: "old", Version: storage.VersionVector{"a": 1}, Timestamp: storage.Timestamp{Physical: 1}},
+	}
+	if response := versionedResponse(versions); response.Value != "new" || len(response.Siblings) != 2 {
+		t.Fatalf("Unexpected response: %v\n", response)
+	}
+	versions[0], versions[1] = versions[1], versions[0]
+	if response := versionedResponse(versions); response.Value != "new" {
+		t.Fatalf("Unexpected response: %v\n", response)
+	}
+}
@@ -0,0 +1,599 @@
+/******
+This file is part of Vaelen/DB.
+
+Copyright 2017, Andrew Young <andrew@vaelen.org>
+
+    Vaelen/DB is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+    Vaelen/DB is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+    You should have received a copy of the GNU General Public License
+along with Vaelen/DB.  If not, see <http://www.gnu.org/licenses/>.
+******/
+
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log/slog"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/vaelen/db/api"
+	"github.com/vaelen/db/logging"
+	"github.com/vaelen/db/storage"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// maxHTTPBody is the largest request body the gateway accepts
+	maxHTTPBody = 64 * 1024 * 1024
+	// defaultHTTPScanPage is the number of keys fetched from storage at a time when streaming a scan
+	defaultHTTPScanPage = 100
+)
+
+// HTTPGateway serves an HTTP/JSON API that calls the database server's gRPC methods.
+//
+//	GET    /v1/time          returns the server's clock
+//	GET    /v1/keys/{key}    returns a value and its ETag
+//	PUT    /v1/keys/{key}    sets a value from the request body, honouring If-Match and If-None-Match
+//	DELETE /v1/keys/{key}    removes a value, honouring If-Match
+//	POST   /v1/batch         atomically applies sets and deletes, then reads keys
+//	GET    /v1/keys          streams the keys held by the cluster and their values as NDJSON
+//
+// Every endpoint under /v1/keys and /v1/batch takes an optional namespace query parameter.
+// GET requests under /v1/keys take an optional max_staleness in milliseconds, which lets a replica answer from its own copy.
+// Errors are returned as JSON objects with the gRPC code and message.
+// When authentication is enabled requests carry the same Basic or Bearer Authorization header as gRPC requests.
+type HTTPGateway struct {
+	Logger *slog.Logger
+	db     *DBServer
+	mux    *http.ServeMux
+}
+
+// NewHTTPGateway creates an HTTP gateway for the given database server
+func NewHTTPGateway(s *DBServer) *HTTPGateway {
+	g := &HTTPGateway{
+		Logger: s.logs.Logger("http"),
+		db:     s,
+		mux:    http.NewServeMux(),
+	}
+	g.mux.HandleFunc("/v1/time", g.time)
+	g.mux.HandleFunc("/v1/keys/", g.key)
+	g.mux.HandleFunc("/v1/keys", g.scan)
+	g.mux.HandleFunc("/v1/batch", g.batch)
+	g.mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, status.Errorf(codes.NotFound, "no such endpoint: %s", r.URL.Path))
+	})
+	return g
+}
+
+// ServeHTTP handles a request
+func (g *HTTPGateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	id := r.Header.Get(logging.RequestIDHeader)
+	if id == "" {
+		id = logging.NewRequestID()
+	}
+	w.Header().Set(logging.RequestIDHeader, id)
+	r = r.WithContext(withGatewayCommand(logging.WithRequestID(r.Context(), id), "HTTP "+r.Method, r.RemoteAddr))
+	if err := g.db.waitLoaded(r.Context()); err != nil {
+		writeError(w, err)
+		return
+	}
+	if g.db.Auth != nil {
+		id, err := g.db.authenticate(r.Header.Get("Authorization"))
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", `Basic realm="vdb"`)
+			writeError(w, err)
+			return
+		}
+		r = r.WithContext(withIdentity(r.Context(), id))
+	}
+	bytes := len(r.URL.Path)
+	if r.ContentLength > 0 {
+		bytes += int(r.ContentLength)
+	}
+	if err := g.db.limit(clientName(callerIdentity(r.Context()), r.RemoteAddr), r.URL.Query().Get("namespace"), 1, bytes); err != nil {
+		writeError(w, err)
+		return
+	}
+	g.mux.ServeHTTP(w, r)
+}
+
+// httpStatus maps a gRPC code to the closest HTTP status
+func httpStatus(code codes.Code) int {
+	switch code {
+	case codes.OK:
+		return http.StatusOK
+	case codes.Canceled:
+		return 499
+	case codes.InvalidArgument, codes.OutOfRange:
+		return http.StatusBadRequest
+	case codes.DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.AlreadyExists, codes.Aborted:
+		return http.StatusConflict
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case codes.FailedPrecondition:
+		return http.StatusPreconditionFailed
+	case codes.Unimplemented:
+		return http.StatusNotImplemented
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusInternalServerError
+}
+
+// httpError is the body of an error response
+type httpError struct {
+	Error struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// newHTTPError converts an error into the body of an error response.
+// The code is the name of the gRPC code in upper snake case, such as NOT_FOUND.
+func newHTTPError(err error) httpError {
+	s := status.Convert(err)
+	var name []rune
+	previous := ' '
+	for _, c := range s.Code().String() {
+		if unicode.IsUpper(c) && unicode.IsLower(previous) {
+			name = append(name, '_')
+		}
+		name = append(name, unicode.ToUpper(c))
+		previous = c
+	}
+	var body httpError
+	body.Error.Code = string(name)
+	body.Error.Message = s.Message()
+	return body
+}
+
+// writeError writes an error as JSON with the HTTP status matching its gRPC code.
+// Errors that tell the caller when to retry set the Retry-After header.
+func writeError(w http.ResponseWriter, err error) {
+	if wait, ok := RetryDelay(err); ok {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	}
+	writeJSON(w, httpStatus(status.Code(err)), newHTTPError(err))
+}
+
+func writeJSON(w http.ResponseWriter, code int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(body)
+}
+
+// allow rejects requests that do not use one of the given methods with 405 Method Not Allowed
+func allow(w http.ResponseWriter, r *http.Request, methods ...string) bool {
+	for _, m := range methods {
+		if r.Method == m {
+			return true
+		}
+	}
+	w.Header().Set("Allow", strings.Join(methods, ", "))
+	writeJSON(w, http.StatusMethodNotAllowed, newHTTPError(status.Errorf(codes.Unimplemented, "method %s is not allowed", r.Method)))
+	return false
+}
+
+// consistency parses the consistency query parameter
+func consistency(r *http.Request) (api.Consistency, error) {
+	name := r.URL.Query().Get("consistency")
+	if name == "" {
+		return api.Consistency_DEFAULT, nil
+	}
+	c, ok := api.Consistency_value[strings.ToUpper(name)]
+	if !ok {
+		return api.Consistency_DEFAULT, status.Errorf(codes.InvalidArgument, "unknown consistency: %s", name)
+	}
+	return api.Consistency(c), nil
+}
+
+// staleness parses the max_staleness query parameter, which is given in milliseconds
+func staleness(r *http.Request) (int64, error) {
+	s := r.URL.Query().Get("max_staleness")
+	if s == "" {
+		return 0, nil
+	}
+	maxStaleness, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || maxStaleness < 0 {
+		return 0, status.Errorf(codes.InvalidArgument, "invalid max_staleness: %s", s)
+	}
+	return maxStaleness, nil
+}
+
+// etag returns the entity tag of a value, which changes whenever the value or its version does
+func etag(value string, version *api.VersionVector) string {
+	pair := storage.NodeKeyValuePair{Value: value, Version: FromVersionVector(version)}
+	return fmt.Sprintf(`"%x"`, storage.DigestVersions([]storage.NodeKeyValuePair{pair}))
+}
+
+// timeResponse is the body returned by /v1/time
+type timeResponse struct {
+	Time     string `json:"time"`
+	Physical int64  `json:"physical"`
+	Logical  uint32 `json:"logical"`
+}
+
+func (g *HTTPGateway) time(w http.ResponseWriter, r *http.Request) {
+	if !allow(w, r, http.MethodGet) {
+		return
+	}
+	response, err := g.db.Time(r.Context(), &api.TimeRequest{})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, timeResponse{
+		Time:     response.Value,
+		Physical: response.Timestamp.GetPhysical(),
+		Logical:  response.Timestamp.GetLogical(),
+	})
+}
+
+// keyValue is a key and its value as returned by the gateway
+type keyValue struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+	// Siblings holds every concurrent value when they could not be resolved
+	Siblings []string `json:"siblings,omitempty"`
+}
+
+// current reads a key in a namespace, returning a nil response if it does not exist
+func (g *HTTPGateway) current(ctx context.Context, namespace string, id string, c api.Consistency) (*api.Response, error) {
+	return g.read(ctx, namespace, id, c, 0)
+}
+
+// read returns the value of a key like current, but lets this server answer from its own copy if it is within maxStaleness milliseconds
+func (g *HTTPGateway) read(ctx context.Context, namespace string, id string, c api.Consistency, maxStaleness int64) (*api.Response, error) {
+	response, err := g.db.Get(ctx, &api.IDRequest{ID: id, Namespace: namespace, Consistency: c, MaxStaleness: maxStaleness})
+	if err != nil || response.Value == "" {
+		return nil, err
+	}
+	return response, nil
+}
+
+// checkPreconditions compares the If-Match and If-None-Match headers with the current value of a key
+func checkPreconditions(r *http.Request, current *api.Response) error {
+	if match := r.Header.Get("If-Match"); match != "" {
+		if current == nil || (match != "*" && !matchesETag(match, etag(current.Value, current.Context))) {
+			return status.Errorf(codes.FailedPrecondition, "the value does not match If-Match")
+		}
+	}
+	if noneMatch := r.Header.Get("If-None-Match"); noneMatch != "" && current != nil {
+		if noneMatch == "*" || matchesETag(noneMatch, etag(current.Value, current.Context)) {
+			return status.Errorf(codes.FailedPrecondition, "the value matches If-None-Match")
+		}
+	}
+	return nil
+}
+
+// matchesETag returns true if the header lists the given entity tag
+func matchesETag(header string, tag string) bool {
+	for _, t := range strings.Split(header, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(t), "W/") == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// key handles GET, PUT and DELETE on /v1/keys/{key}
+func (g *HTTPGateway) key(w http.ResponseWriter, r *http.Request) {
+	if !allow(w, r, http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete) {
+		return
+	}
+	id, err := url.PathUnescape(strings.TrimPrefix(r.URL.EscapedPath(), "/v1/keys/"))
+	if err != nil || id == "" {
+		writeError(w, status.Errorf(codes.InvalidArgument, "invalid key"))
+		return
+	}
+	namespace := r.URL.Query().Get("namespace")
+	key, err := g.db.key(namespace, id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	c, err := consistency(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	ctx := r.Context()
+	permission := api.Permission_WRITE
+	if r.Method == http.MethodGet || r.Method == http.MethodHead {
+		permission = api.Permission_READ
+	}
+	if err := g.db.authorize(ctx, permission, key); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	if r.Method == http.MethodGet || r.Method == http.MethodHead {
+		maxStaleness, err := staleness(r)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		response, err := g.read(ctx, namespace, id, c, maxStaleness)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		if response == nil {
+			writeError(w, status.Errorf(codes.NotFound, "key not found: %s", id))
+			return
+		}
+		body := keyValue{Key: id, Value: response.Value}
+		if len(response.Siblings) > 1 {
+			for _, sibling := range response.Siblings {
+				body.Siblings = append(body.Siblings, sibling.Value)
+			}
+		}
+		w.Header().Set("ETag", etag(response.Value, response.Context))
+		writeJSON(w, http.StatusOK, body)
+		return
+	}
+
+	var value []byte
+	if r.Method == http.MethodPut {
+		value, err = ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxHTTPBody))
+		if err != nil {
+			writeError(w, status.Errorf(codes.InvalidArgument, "could not read the request body: %s", err))
+			return
+		}
+	}
+
+	switch {
+	case r.Header.Get("If-Match") != "" || r.Header.Get("If-None-Match") != "":
+		err = g.conditionalWrite(ctx, r, key, string(value), c)
+	case r.Method == http.MethodDelete:
+		var previous *api.Response
+		previous, err = g.db.Remove(ctx, &api.IDRequest{ID: id, Namespace: namespace, Consistency: c})
+		if err == nil && previous.Value == "" {
+			err = status.Errorf(codes.NotFound, "key not found: %s", id)
+		}
+	default:
+		_, err = g.db.Set(ctx, &api.IDValueRequest{ID: id, Namespace: namespace, Value: string(value), Consistency: c})
+	}
+	g.db.auditCommand(ctx, []string{key}, err)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// conditionalWrite applies a PUT or DELETE that has preconditions.  They are checked against the versions read from the key's
+// replicas, and the write only succeeds if enough of the replicas still hold those versions when it is applied.
+func (g *HTTPGateway) conditionalWrite(ctx context.Context, r *http.Request, key string, value string, c api.Consistency) error {
+	defer g.db.locks.lock(key)()
+	versions, err := g.db.readVersions(ctx, key, c)
+	if err != nil {
+		return err
+	}
+	current := versionedResponse(versions)
+	if current.Value == "" {
+		current = nil
+	}
+	if err := checkPreconditions(r, current); err != nil {
+		return err
+	}
+	pair := storage.NodeKeyValuePair{Key: key, Value: value}
+	if r.Method == http.MethodDelete {
+		if current == nil {
+			_, id := storage.SplitNamespace(key)
+			return status.Errorf(codes.NotFound, "key not found: %s", id)
+		}
+		pair.Deleted = true
+	} else {
+		pair.Expires = g.db.expiry(key, 0)
+	}
+	err = g.db.conditionalWrite(ctx, pair, MergeVersions(versions), storage.DigestVersions(versions), c)
+	if status.Code(err) == codes.Aborted {
+		return status.Errorf(codes.FailedPrecondition, "the value changed while the request was being applied")
+	}
+	return err
+}
+
+// batchRequest is the body of a request to /v1/batch
+type batchRequest struct {
+	Get    []string          `json:"get"`
+	Set    map[string]string `json:"set"`
+	Delete []string          `json:"delete"`
+}
+
+// batchResponse is the body returned by /v1/batch
+type batchResponse struct {
+	// Txid is the transaction that applied the writes, it is empty when there were none
+	Txid string `json:"txid,omitempty"`
+	// Values holds the value of every key that was read and exists
+	Values map[string]string `json:"values"`
+}
+
+// batch applies the sets and deletes in a single transaction and then reads the requested keys
+func (g *HTTPGateway) batch(w http.ResponseWriter, r *http.Request) {
+	if !allow(w, r, http.MethodPost) {
+		return
+	}
+	c, err := consistency(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	var request batchRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxHTTPBody)).Decode(&request); err != nil {
+		writeError(w, status.Errorf(codes.InvalidArgument, "invalid batch request: %s", err))
+		return
+	}
+	ctx := r.Context()
+	response := batchResponse{Values: make(map[string]string)}
+
+	ops := make([]*api.TxnOp, 0, len(request.Set)+len(request.Delete))
+	for key, value := range request.Set {
+		ops = append(ops, &api.TxnOp{ID: key, Value: value})
+	}
+	for _, key := range request.Delete {
+		ops = append(ops, &api.TxnOp{ID: key, Delete: true})
+	}
+	namespace := r.URL.Query().Get("namespace")
+	keys := make([]string, 0, len(ops))
+	for _, op := range ops {
+		key, err := g.db.key(namespace, op.ID)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		if err := g.db.authorize(ctx, api.Permission_WRITE, key); err != nil {
+			writeError(w, err)
+			return
+		}
+		keys = append(keys, key)
+	}
+	for _, id := range request.Get {
+		key, err := g.db.key(namespace, id)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		if err := g.db.authorize(ctx, api.Permission_READ, key); err != nil {
+			writeError(w, err)
+			return
+		}
+	}
+	if len(ops) > 0 {
+		txn, err := g.db.Txn(ctx, &api.TxnRequest{Ops: ops, Namespace: namespace, Consistency: c})
+		g.db.auditCommand(ctx, keys, err)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		if txn.Decision != api.TxnDecision_COMMIT {
+			writeError(w, status.Errorf(codes.Aborted, "transaction %s was aborted", txn.Txid))
+			return
+		}
+		response.Txid = txn.Txid
+	}
+
+	for _, id := range request.Get {
+		current, err := g.current(ctx, namespace, id, c)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		if current != nil {
+			response.Values[id] = current.Value
+		}
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+// scan streams the keys held by the cluster as NDJSON, one keyValue per line.  The shards are listed one after the other.
+// The match parameter filters keys with a glob pattern and limit stops the scan after that many keys.
+// Only the keys the caller can read are returned.
+func (g *HTTPGateway) scan(w http.ResponseWriter, r *http.Request) {
+	if !allow(w, r, http.MethodGet) {
+		return
+	}
+	c, err := consistency(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	maxStaleness, err := staleness(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	query := r.URL.Query()
+	namespace := query.Get("namespace")
+	if _, err := g.db.namespace(namespace); err != nil {
+		writeError(w, err)
+		return
+	}
+	pattern := query.Get("match")
+	limit := 0
+	if l := query.Get("limit"); l != "" {
+		if limit, err = strconv.Atoi(l); err != nil || limit < 0 {
+			writeError(w, status.Errorf(codes.InvalidArgument, "invalid limit: %s", l))
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+	flusher, _ := w.(http.Flusher)
+	enc := json.NewEncoder(w)
+	ctx := r.Context()
+	sent := 0
+	for _, shard := range g.db.scanShards() {
+		if g.db.isDead(shard) {
+			// Its keys are listed by their other replicas
+			continue
+		}
+		cursor := uint32(0)
+		for {
+			keys, next, err := g.db.replicaScan(ctx, shard, namespace, cursor, defaultHTTPScanPage)
+			if err != nil {
+				// The status has already been sent, so the error is reported in the stream
+				enc.Encode(newHTTPError(err))
+				return
+			}
+			for _, key := range keys {
+				_, id := storage.SplitNamespace(key)
+				if (pattern != "" && !globMatch(pattern, id)) || !g.db.scannedFrom(shard, key) || g.db.authorize(ctx, api.Permission_READ, key) != nil {
+					continue
+				}
+				current, err := g.read(ctx, namespace, id, c, maxStaleness)
+				if err != nil {
+					enc.Encode(newHTTPError(err))
+					return
+				}
+				if current == nil {
+					continue
+				}
+				if err := enc.Encode(keyValue{Key: id, Value: current.Value}); err != nil {
+					return
+				}
+				sent++
+				if limit > 0 && sent >= limit {
+					return
+				}
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+			if ctx.Err() != nil {
+				return
+			}
+			if next == 0 {
+				break
+			}
+			cursor = next
+		}
+	}
+}
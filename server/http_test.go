@@ -0,0 +1,245 @@
+/******
+This file is part of Vaelen/DB.
+
+Copyright 2017, Andrew Young <andrew@vaelen.org>
+
+    Vaelen/DB is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+    Vaelen/DB is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+    You should have received a copy of the GNU General Public License
+along with Vaelen/DB.  If not, see <http://www.gnu.org/licenses/>.
+******/
+
+package server
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vaelen/db/api"
+	"github.com/vaelen/db/storage"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// TestHTTPGateway tests the HTTP/JSON gateway against a standalone server
+func TestHTTPGateway(t *testing.T) {
+	s := New(testLogs, "")
+	defer s.Stop()
+	ts := httptest.NewServer(NewHTTPGateway(s))
+	defer ts.Close()
+
+	do := func(method string, path string, body string, headers map[string]string) *http.Response {
+		request, err := http.NewRequest(method, ts.URL+path, strings.NewReader(body))
+		if err != nil {
+			t.Fatalf("Request Error: %s\n", err.Error())
+		}
+		for k, v := range headers {
+			request.Header.Set(k, v)
+		}
+		response, err := http.DefaultClient.Do(request)
+		if err != nil {
+			t.Fatalf("%s %s Error: %s\n", method, path, err.Error())
+		}
+		return response
+	}
+	expect := func(response *http.Response, code int) {
+		if response.StatusCode != code {
+			b, _ := ioutil.ReadAll(response.Body)
+			t.Fatalf("%s %s Status: %d, Expected: %d, Body: %s\n", response.Request.Method, response.Request.URL.Path, response.StatusCode, code, b)
+		}
+	}
+
+	response := do("GET", "/v1/keys/foo", "", nil)
+	expect(response, http.StatusNotFound)
+	var e httpError
+	json.NewDecoder(response.Body).Decode(&e)
+	if e.Error.Code != "NOT_FOUND" {
+		t.Errorf("Error Code: %s\n", e.Error.Code)
+	}
+
+	expect(do("PUT", "/v1/keys/foo", "bar", nil), http.StatusNoContent)
+	expect(do("PUT", "/v1/keys/a%2Fb", "slash", nil), http.StatusNoContent)
+	response = do("GET", "/v1/keys/foo", "", nil)
+	expect(response, http.StatusOK)
+	var kv keyValue
+	json.NewDecoder(response.Body).Decode(&kv)
+	if kv.Key != "foo" || kv.Value != "bar" {
+		t.Errorf("Unexpected value: %+v\n", kv)
+	}
+	tag := response.Header.Get("ETag")
+	if tag == "" {
+		t.Fatalf("No ETag\n")
+	}
+	response = do("GET", "/v1/keys/a%2Fb", "", nil)
+	expect(response, http.StatusOK)
+	json.NewDecoder(response.Body).Decode(&kv)
+	if kv.Key != "a/b" || kv.Value != "slash" {
+		t.Errorf("Unexpected value: %+v\n", kv)
+	}
+
+	expect(do("PUT", "/v1/keys/foo", "baz", map[string]string{"If-Match": `"0"`}), http.StatusPreconditionFailed)
+	expect(do("PUT", "/v1/keys/foo", "baz", map[string]string{"If-None-Match": "*"}), http.StatusPreconditionFailed)
+	expect(do("PUT", "/v1/keys/foo", "baz", map[string]string{"If-Match": tag}), http.StatusNoContent)
+	expect(do("PUT", "/v1/keys/foo", "qux", map[string]string{"If-Match": tag}), http.StatusPreconditionFailed)
+	expect(do("PUT", "/v1/keys/new", "value", map[string]string{"If-None-Match": "*"}), http.StatusNoContent)
+	expect(do("GET", "/v1/keys/foo?consistency=sometimes", "", nil), http.StatusBadRequest)
+	expect(do("GET", "/v1/keys/foo?max_staleness=soon", "", nil), http.StatusBadRequest)
+	expect(do("GET", "/v1/keys?max_staleness=-1", "", nil), http.StatusBadRequest)
+	response = do("POST", "/v1/keys/foo", "", nil)
+	expect(response, http.StatusMethodNotAllowed)
+	if allowed := response.Header.Get("Allow"); allowed != "GET, HEAD, PUT, DELETE" {
+		t.Errorf("Allow: %q\n", allowed)
+	}
+
+	expect(do("DELETE", "/v1/keys/new", "", nil), http.StatusNoContent)
+	expect(do("DELETE", "/v1/keys/new", "", nil), http.StatusNotFound)
+
+	response = do("POST", "/v1/batch", `{"set": {"x": "1", "y": "2"}, "delete": ["foo"], "get": ["x", "y", "foo"]}`, nil)
+	expect(response, http.StatusOK)
+	var batch batchResponse
+	json.NewDecoder(response.Body).Decode(&batch)
+	if batch.Txid == "" || len(batch.Values) != 2 || batch.Values["x"] != "1" || batch.Values["y"] != "2" {
+		t.Errorf("Unexpected batch response: %+v\n", batch)
+	}
+
+	for i := 0; i < 250; i++ {
+		s.Storage.Set(fmt.Sprintf("scan-%d", i), "v")
+	}
+	response = do("GET", "/v1/keys?match=scan-*", "", nil)
+	expect(response, http.StatusOK)
+	if ct := response.Header.Get("Content-Type"); ct != "application/x-ndjson" {
+		t.Errorf("Content-Type: %s\n", ct)
+	}
+	lines := 0
+	scanner := bufio.NewScanner(response.Body)
+	for scanner.Scan() {
+		var kv keyValue
+		if err := json.Unmarshal(scanner.Bytes(), &kv); err != nil || !strings.HasPrefix(kv.Key, "scan-") || kv.Value != "v" {
+			t.Errorf("Unexpected line: %s\n", scanner.Text())
+		}
+		lines++
+	}
+	if lines != 250 {
+		t.Errorf("Scanned: %d, Expected: 250\n", lines)
+	}
+	response = do("GET", "/v1/keys?limit=10", "", nil)
+	b, _ := ioutil.ReadAll(response.Body)
+	if n := strings.Count(string(b), "\n"); n != 10 {
+		t.Errorf("Scanned with limit: %d, Expected: 10\n", n)
+	}
+
+	if _, err := s.CreateNamespace(context.Background(), &api.NamespaceRequest{Name: "team"}); err != nil {
+		t.Fatalf("CreateNamespace Error: %s\n", err.Error())
+	}
+	expect(do("PUT", "/v1/keys/x?namespace=team", "in-team", nil), http.StatusNoContent)
+	response = do("GET", "/v1/keys/x?namespace=team", "", nil)
+	expect(response, http.StatusOK)
+	json.NewDecoder(response.Body).Decode(&kv)
+	if kv.Key != "x" || kv.Value != "in-team" {
+		t.Errorf("Unexpected value: %+v\n", kv)
+	}
+	expect(do("GET", "/v1/keys/__ns%2Fteam%2Fx", "", nil), http.StatusBadRequest)
+
+	response = do("GET", "/v1/time", "", nil)
+	expect(response, http.StatusOK)
+	var now timeResponse
+	json.NewDecoder(response.Body).Decode(&now)
+	if now.Time == "" || now.Physical == 0 {
+		t.Errorf("Unexpected time: %+v\n", now)
+	}
+	expect(do("GET", "/v2/anything", "", nil), http.StatusNotFound)
+}
+
+// TestHTTPCluster tests conditional requests and scans through the HTTP gateway of a cluster
+func TestHTTPCluster(t *testing.T) {
+	servers, stop := startCluster(t, 30376, 3)
+	defer stop()
+	for _, s := range servers {
+		s.Replication = ReplicationConfig{N: 2, R: 2, W: 2, Timeout: 2 * time.Second}
+	}
+	gateways := make([]*httptest.Server, 0, len(servers))
+	for _, s := range servers {
+		ts := httptest.NewServer(NewHTTPGateway(s))
+		defer ts.Close()
+		gateways = append(gateways, ts)
+	}
+	do := func(ts *httptest.Server, method string, path string, body string, headers map[string]string) *http.Response {
+		request, _ := http.NewRequest(method, ts.URL+path, strings.NewReader(body))
+		for k, v := range headers {
+			request.Header.Set(k, v)
+		}
+		response, err := http.DefaultClient.Do(request)
+		if err != nil {
+			t.Fatalf("%s %s Error: %s\n", method, path, err.Error())
+		}
+		return response
+	}
+	ctx := context.Background()
+
+	if response := do(gateways[0], "PUT", "/v1/keys/foo", "bar", nil); response.StatusCode != http.StatusNoContent {
+		t.Fatalf("PUT Status: %d\n", response.StatusCode)
+	}
+	// An entity tag returned by one server is accepted by another
+	tag := do(gateways[1], "GET", "/v1/keys/foo", "", nil).Header.Get("ETag")
+	if response := do(gateways[2], "PUT", "/v1/keys/foo", "baz", map[string]string{"If-Match": tag}); response.StatusCode != http.StatusNoContent {
+		t.Fatalf("Conditional PUT Status: %d\n", response.StatusCode)
+	}
+	if response := do(gateways[0], "PUT", "/v1/keys/foo", "stale", map[string]string{"If-Match": tag}); response.StatusCode != http.StatusPreconditionFailed {
+		t.Fatalf("Stale PUT Status: %d\n", response.StatusCode)
+	}
+
+	// The replicas reject a write based on versions that changed after they were read
+	versions, err := servers[0].readVersions(ctx, "foo", api.Consistency_DEFAULT)
+	if err != nil {
+		t.Fatalf("Read Error: %s\n", err.Error())
+	}
+	if _, err := servers[1].Set(ctx, &api.IDValueRequest{ID: "foo", Value: "qux"}); err != nil {
+		t.Fatalf("Set Error: %s\n", err.Error())
+	}
+	pair := storage.NodeKeyValuePair{Key: "foo", Value: "stale"}
+	if err := servers[0].conditionalWrite(ctx, pair, MergeVersions(versions), storage.DigestVersions(versions), api.Consistency_DEFAULT); status.Code(err) != codes.Aborted {
+		t.Fatalf("Expected Aborted, got %v\n", err)
+	}
+	response, err := servers[2].Get(ctx, &api.IDRequest{ID: "foo", Consistency: api.Consistency_ALL})
+	if err != nil || response.Value != "qux" {
+		t.Fatalf("Get: %v, Error: %v\n", response, err)
+	}
+
+	// Every key is listed once, whichever servers hold it
+	for i := 0; i < 30; i++ {
+		if _, err := servers[i%3].Set(ctx, &api.IDValueRequest{ID: fmt.Sprintf("scan-%d", i), Value: "v"}); err != nil {
+			t.Fatalf("Set Error: %s\n", err.Error())
+		}
+	}
+	for i, ts := range gateways {
+		seen := make(map[string]bool)
+		scanner := bufio.NewScanner(do(ts, "GET", "/v1/keys?match=scan-*", "", nil).Body)
+		for scanner.Scan() {
+			var kv keyValue
+			if err := json.Unmarshal(scanner.Bytes(), &kv); err != nil || seen[kv.Key] {
+				t.Errorf("Server %d, Unexpected line: %s\n", i, scanner.Text())
+			}
+			seen[kv.Key] = true
+		}
+		if len(seen) != 30 {
+			t.Errorf("Server %d, Scanned: %d, Expected: 30\n", i, len(seen))
+		}
+	}
+}
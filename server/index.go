@@ -0,0 +1,215 @@
+/******
+This file is part of Vaelen/DB.
+
+Copyright 2017, Andrew Young <andrew@vaelen.org>
+
+    Vaelen/DB is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+    Vaelen/DB is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+    You should have received a copy of the GNU General Public License
+along with Vaelen/DB.  If not, see <http://www.gnu.org/licenses/>.
+******/
+
+package server
+
+import (
+	"github.com/vaelen/db/api"
+	"github.com/vaelen/db/cluster"
+	"github.com/vaelen/db/storage"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// indexError converts an error returned by a storage index into a gRPC status
+func indexError(err error) error {
+	switch err {
+	case nil:
+		return nil
+	case storage.ErrIndexExists:
+		return status.Error(codes.AlreadyExists, err.Error())
+	case storage.ErrUnknownIndex:
+		return status.Error(codes.NotFound, err.Error())
+	case storage.ErrIndexBuilding:
+		return status.Error(codes.FailedPrecondition, err.Error())
+	}
+	return status.Error(codes.InvalidArgument, err.Error())
+}
+
+// toIndexStatus converts a storage index status into its API representation
+func toIndexStatus(s storage.IndexStatus) *api.IndexStatus {
+	return &api.IndexStatus{
+		Name:     s.Name,
+		Path:     s.Path,
+		Ready:    s.Ready,
+		Progress: s.Progress,
+		Entries:  uint64(s.Entries),
+	}
+}
+
+// others returns every shard in the cluster except this one
+func (s *DBServer) others() []cluster.Shard {
+	config := s.cluster()
+	if config == nil {
+		return nil
+	}
+	shards := make([]cluster.Shard, 0, len(config.Shards))
+	for _, shard := range config.Shards {
+		if !s.isSelf(shard) {
+			shards = append(shards, shard)
+		}
+	}
+	return shards
+}
+
+// broadcast calls f on every other shard in parallel on behalf of the request in ctx and returns the number of shards that failed.
+// Errors with the ignored code are not counted as failures.
+func (s *DBServer) broadcast(ctx context.Context, ignored codes.Code, f func(ctx context.Context, c api.DatabaseClient) error) int {
+	shards := s.others()
+	ctx, cancel := context.WithTimeout(detach(ctx), s.Replication.Timeout)
+	defer cancel()
+	errors := make(chan error, len(shards))
+	for _, shard := range shards {
+		go func(shard cluster.Shard) {
+			if s.isDead(shard) {
+				errors <- errMemberDead
+				return
+			}
+			c, err := s.peers.client(shard)
+			if err == nil {
+				err = f(ctx, c)
+			}
+			if err != nil && status.Code(err) != ignored {
+				s.Logger.WarnContext(ctx, "Broadcast request failed", "shard", shard.ID.String(), "error", err)
+				errors <- err
+				return
+			}
+			errors <- nil
+		}(shard)
+	}
+	failed := 0
+	for range shards {
+		if <-errors != nil {
+			failed++
+		}
+	}
+	return failed
+}
+
+// CreateIndex declares a secondary index on every shard.  Each shard builds its index from the values it holds in the background.
+func (s *DBServer) CreateIndex(ctx context.Context, request *api.IndexDefinition) (*api.IndexStatus, error) {
+	created, err := s.Storage.CreateIndex(storage.IndexDefinition{Name: request.Name, Path: request.Path})
+	if err != nil && err != storage.ErrIndexExists {
+		return nil, indexError(err)
+	}
+	if !request.Local && s.replicated() {
+		// Declare the index on the other shards even if it already exists here, so that retrying a partial failure completes it
+		failed := s.broadcast(ctx, codes.AlreadyExists, func(ctx context.Context, c api.DatabaseClient) error {
+			_, err := c.CreateIndex(ctx, &api.IndexDefinition{Name: request.Name, Path: request.Path, Local: true})
+			return err
+		})
+		if failed > 0 {
+			return nil, status.Errorf(codes.Unavailable, "index could not be declared on %d shards", failed)
+		}
+	}
+	if err != nil {
+		return nil, indexError(err)
+	}
+	return toIndexStatus(created), nil
+}
+
+// DropIndex removes a secondary index from every shard
+func (s *DBServer) DropIndex(ctx context.Context, request *api.IndexDefinition) (*api.IndexStatus, error) {
+	err := s.Storage.DropIndex(request.Name)
+	if err != nil && err != storage.ErrUnknownIndex {
+		return nil, indexError(err)
+	}
+	if !request.Local && s.replicated() {
+		failed := s.broadcast(ctx, codes.NotFound, func(ctx context.Context, c api.DatabaseClient) error {
+			_, err := c.DropIndex(ctx, &api.IndexDefinition{Name: request.Name, Local: true})
+			return err
+		})
+		if failed > 0 {
+			return nil, status.Errorf(codes.Unavailable, "index could not be dropped on %d shards", failed)
+		}
+	}
+	if err != nil {
+		return nil, indexError(err)
+	}
+	return &api.IndexStatus{Name: request.Name}, nil
+}
+
+// Indexes returns the secondary indexes of this server and how far their builds have progressed
+func (s *DBServer) Indexes(ctx context.Context, request *api.EmptyRequest) (*api.IndexesResponse, error) {
+	response := &api.IndexesResponse{}
+	for _, index := range s.Storage.Indexes() {
+		response.Indexes = append(response.Indexes, toIndexStatus(index))
+	}
+	return response, nil
+}
+
+// QueryIndex queries a secondary index on every shard and merges the results.
+// Every key is held by N shards, so the query succeeds as long as fewer than N shards fail to answer.
+// Each shard answers from its own replicas, so a key may be returned for a value that has since been overwritten elsewhere.
+// Only the keys the caller can read are returned, so fewer than limit entries may be returned even when more match.
+func (s *DBServer) QueryIndex(ctx context.Context, request *api.QueryIndexRequest) (*api.QueryIndexResponse, error) {
+	query := storage.IndexQuery{
+		Index:        request.Index,
+		Equal:        request.Equal,
+		Min:          request.Min,
+		Max:          request.Max,
+		MinExclusive: request.MinExclusive,
+		MaxExclusive: request.MaxExclusive,
+		Namespace:    request.Namespace,
+		Limit:        int(request.Limit),
+	}
+	local, err := s.Storage.QueryIndex(query)
+	if err != nil {
+		return nil, indexError(err)
+	}
+	results := [][]storage.IndexEntry{local}
+	if !request.Local && s.replicated() {
+		remote := make(chan []storage.IndexEntry, len(s.others()))
+		failed := s.broadcast(ctx, codes.OK, func(ctx context.Context, c api.DatabaseClient) error {
+			peerRequest := *request
+			peerRequest.Local = true
+			response, err := c.QueryIndex(ctx, &peerRequest)
+			if err != nil {
+				return err
+			}
+			entries := make([]storage.IndexEntry, 0, len(response.Entries))
+			for _, e := range response.Entries {
+				entries = append(entries, storage.IndexEntry{Key: e.ID, Value: e.Value})
+			}
+			remote <- entries
+			return nil
+		})
+		close(remote)
+		if failed >= s.Replication.N {
+			return nil, status.Errorf(codes.Unavailable, "%d shards did not answer the query", failed)
+		}
+		for entries := range remote {
+			results = append(results, entries)
+		}
+	}
+	response := &api.QueryIndexResponse{}
+	for _, e := range storage.MergeIndexEntries(results, query.Limit) {
+		if s.authorize(ctx, api.Permission_READ, e.Key) != nil {
+			continue
+		}
+		id := e.Key
+		if !request.Local {
+			_, id = storage.SplitNamespace(e.Key)
+		}
+		response.Entries = append(response.Entries, &api.IndexEntry{ID: id, Value: e.Value})
+	}
+	return response, nil
+}
@@ -0,0 +1,92 @@
+/******
+This file is part of Vaelen/DB.
+
+Copyright 2017, Andrew Young <andrew@vaelen.org>
+
+    Vaelen/DB is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+    Vaelen/DB is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+    You should have received a copy of the GNU General Public License
+along with Vaelen/DB.  If not, see <http://www.gnu.org/licenses/>.
+******/
+
+package server
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/vaelen/db/api"
+	"github.com/vaelen/db/cluster"
+
+	"golang.org/x/net/context"
+)
+
+// TestQueryIndex tests that an index declared on one server is built on every shard and queried across the cluster
+func TestQueryIndex(t *testing.T) {
+	servers, stop := startCluster(t, 30250, 3)
+	defer stop()
+	servers[0].cluster().Strategy = cluster.RingStrategy
+	ctx := context.Background()
+
+	for i := 0; i < 30; i++ {
+		value := fmt.Sprintf(`{"name": "user %d", "group": %d}`, i, i%3)
+		if _, err := servers[0].Set(ctx, &api.IDValueRequest{ID: fmt.Sprintf("user-%d", i), Value: value, Consistency: api.Consistency_ALL}); err != nil {
+			t.Fatalf("Set Error: %s\n", err.Error())
+		}
+	}
+
+	if _, err := servers[1].CreateIndex(ctx, &api.IndexDefinition{Name: "group", Path: "group"}); err != nil {
+		t.Fatalf("CreateIndex Error: %s\n", err.Error())
+	}
+	ready := waitFor(5*time.Second, func() bool {
+		for _, s := range servers {
+			response, _ := s.Indexes(ctx, &api.EmptyRequest{})
+			if len(response.Indexes) != 1 || !response.Indexes[0].Ready {
+				return false
+			}
+		}
+		return true
+	})
+	if !ready {
+		t.Fatalf("Index was not built on every shard\n")
+	}
+
+	response, err := servers[2].QueryIndex(ctx, &api.QueryIndexRequest{Index: "group", Equal: "1"})
+	if err != nil {
+		t.Fatalf("QueryIndex Error: %s\n", err.Error())
+	}
+	if len(response.Entries) != 10 {
+		t.Fatalf("Entries: %d, Expected: 10\n", len(response.Entries))
+	}
+	for _, e := range response.Entries {
+		if e.Value != "1" {
+			t.Errorf("Key: %s, Value: %s\n", e.ID, e.Value)
+		}
+	}
+
+	response, err = servers[0].QueryIndex(ctx, &api.QueryIndexRequest{Index: "group", Min: "1", Limit: 15})
+	if err != nil {
+		t.Fatalf("QueryIndex Error: %s\n", err.Error())
+	}
+	if len(response.Entries) != 15 || response.Entries[0].Value != "1" || response.Entries[14].Value != "2" {
+		t.Fatalf("Unexpected range results: %v\n", response.Entries)
+	}
+
+	if _, err := servers[0].DropIndex(ctx, &api.IndexDefinition{Name: "group"}); err != nil {
+		t.Fatalf("DropIndex Error: %s\n", err.Error())
+	}
+	for _, s := range servers {
+		if response, _ := s.Indexes(ctx, &api.EmptyRequest{}); len(response.Indexes) != 0 {
+			t.Errorf("Index was not dropped on shard %s\n", s.Self.ID)
+		}
+	}
+}
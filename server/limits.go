@@ -0,0 +1,422 @@
+/******
+This file is part of Vaelen/DB.
+
+Copyright 2017, Andrew Young <andrew@vaelen.org>
+
+    Vaelen/DB is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+    Vaelen/DB is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+    You should have received a copy of the GNU General Public License
+along with Vaelen/DB.  If not, see <http://www.gnu.org/licenses/>.
+******/
+
+package server
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/vaelen/db/api"
+
+	"golang.org/x/net/context"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// limitsKey holds the rate limits of clients and namespaces
+const limitsKey = ReservedPrefix + "limits"
+
+// AllClients is the client name that sets the rate limit of every client without a limit of its own
+const AllClients = "*"
+
+var (
+	// LimitsCacheTTL is how long rate limits are cached before they are read again
+	LimitsCacheTTL = 5 * time.Second
+	// bucketIdleTime is how long a token bucket is kept after it was last used
+	bucketIdleTime = time.Minute
+)
+
+// RateLimit is the rate of a token bucket, zero is unlimited.  Bursts of up to one second's worth are allowed.
+type RateLimit struct {
+	OpsPerSecond   float64
+	BytesPerSecond float64
+}
+
+// LimitsConfig holds the rate limits of clients and namespaces.
+// Each server keeps its own token buckets, so a client spreading its requests over several servers gets each server's rate.
+type LimitsConfig struct {
+	// DefaultClient limits every client that is not in Clients
+	DefaultClient RateLimit
+	// Clients holds the limits of clients by user, or by address for clients that have not authenticated
+	Clients map[string]RateLimit
+	// Namespaces holds the limits of namespaces, the default namespace is ""
+	Namespaces map[string]RateLimit
+}
+
+// bucket is a token bucket
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// refill adds the tokens earned since the bucket was last used, up to one second's worth
+func (b *bucket) refill(rate float64, now time.Time) {
+	if b.last.IsZero() {
+		b.tokens = rate
+	} else {
+		b.tokens = math.Min(rate, b.tokens+now.Sub(b.last).Seconds()*rate)
+	}
+	b.last = now
+}
+
+// wait returns how long it will be until n tokens can be taken.
+// Costs larger than a burst are allowed once the bucket is full and leave it in debt.
+func (b *bucket) wait(rate float64, n float64) time.Duration {
+	need := math.Min(n, rate)
+	if b.tokens >= need {
+		return 0
+	}
+	return time.Duration((need - b.tokens) / rate * float64(time.Second))
+}
+
+// limiter holds the cached rate limits and the token buckets of this server
+type limiter struct {
+	sync.Mutex
+	config  *LimitsConfig
+	loaded  time.Time
+	buckets map[string]*bucket
+	pruned  time.Time
+}
+
+func newLimiter() *limiter {
+	return &limiter{buckets: make(map[string]*bucket)}
+}
+
+// limitsConfig returns the rate limits, reading them again once the cache has expired.
+// The cached limits are used if they can not be read, and no limits apply if they never could be.
+// Either way the result is cached, so that requests do not each read the limits again while they can not be read.
+func (s *DBServer) limitsConfig() *LimitsConfig {
+	s.limits.Lock()
+	config, age := s.limits.config, time.Since(s.limits.loaded)
+	s.limits.Unlock()
+	if config != nil && age < LimitsCacheTTL {
+		return config
+	}
+	loaded, err := s.loadLimits()
+	if err != nil {
+		s.Logger.Error("Could not read rate limits", "error", err)
+		if config == nil {
+			config = &LimitsConfig{}
+		}
+		s.limits.Lock()
+		defer s.limits.Unlock()
+		s.limits.config = config
+		s.limits.loaded = time.Now()
+		return config
+	}
+	return loaded
+}
+
+// loadLimits reads the rate limits and caches them
+func (s *DBServer) loadLimits() (*LimitsConfig, error) {
+	config := &LimitsConfig{}
+	if _, err := s.readReserved(limitsKey, config); err != nil {
+		return nil, err
+	}
+	s.limits.Lock()
+	defer s.limits.Unlock()
+	s.limits.config = config
+	s.limits.loaded = time.Now()
+	return config, nil
+}
+
+// forgetLimits drops the cached rate limits
+func (s *DBServer) forgetLimits() {
+	s.limits.Lock()
+	defer s.limits.Unlock()
+	s.limits.config = nil
+}
+
+// limitCheck is a cost taken from one token bucket
+type limitCheck struct {
+	bucket  string
+	rate    float64
+	cost    float64
+	subject string
+}
+
+// limit takes the cost of a request from the rate limits of its client and namespace.
+// It returns ResourceExhausted with a retry delay if either is over its limit, in which case nothing is taken.
+// Requests from other nodes, which have an empty client name, are never limited.
+func (s *DBServer) limit(client string, namespace string, ops int, bytes int) error {
+	if client == "" {
+		return nil
+	}
+	config := s.limitsConfig()
+	if config == nil {
+		return nil
+	}
+	clientRate, ok := config.Clients[client]
+	if !ok {
+		clientRate = config.DefaultClient
+	}
+	namespaceRate := config.Namespaces[namespace]
+	checks := make([]limitCheck, 0, 4)
+	for _, c := range []limitCheck{
+		{"client/ops/" + client, clientRate.OpsPerSecond, float64(ops), "operations of client " + client},
+		{"client/bytes/" + client, clientRate.BytesPerSecond, float64(bytes), "bytes of client " + client},
+		{"namespace/ops/" + namespace, namespaceRate.OpsPerSecond, float64(ops), fmt.Sprintf("operations in namespace %q", namespace)},
+		{"namespace/bytes/" + namespace, namespaceRate.BytesPerSecond, float64(bytes), fmt.Sprintf("bytes in namespace %q", namespace)},
+	} {
+		if c.rate > 0 && c.cost > 0 {
+			checks = append(checks, c)
+		}
+	}
+	if len(checks) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	s.limits.Lock()
+	defer s.limits.Unlock()
+	s.limits.prune(now)
+	var wait time.Duration
+	subject := ""
+	for _, c := range checks {
+		b, ok := s.limits.buckets[c.bucket]
+		if !ok {
+			b = &bucket{}
+			s.limits.buckets[c.bucket] = b
+		}
+		b.refill(c.rate, now)
+		if d := b.wait(c.rate, c.cost); d > wait {
+			wait, subject = d, c.subject
+		}
+	}
+	if wait > 0 {
+		return rateLimited(subject, wait)
+	}
+	for _, c := range checks {
+		s.limits.buckets[c.bucket].tokens -= c.cost
+	}
+	return nil
+}
+
+// prune drops the buckets that have not been used recently.  The caller must hold the lock.
+func (l *limiter) prune(now time.Time) {
+	if now.Sub(l.pruned) < bucketIdleTime {
+		return
+	}
+	l.pruned = now
+	for name, b := range l.buckets {
+		if now.Sub(b.last) > bucketIdleTime {
+			delete(l.buckets, name)
+		}
+	}
+}
+
+// rateLimited returns a ResourceExhausted error telling the caller when to retry
+func rateLimited(subject string, wait time.Duration) error {
+	st := status.New(codes.ResourceExhausted, fmt.Sprintf("rate limit exceeded for %s, retry in %s", subject, wait))
+	if detailed, err := st.WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(wait)}); err == nil {
+		st = detailed
+	}
+	return st.Err()
+}
+
+// quotaExceeded returns a ResourceExhausted error describing the storage quota a write would exceed
+func quotaExceeded(namespace string, format string, args ...interface{}) error {
+	description := fmt.Sprintf(format, args...)
+	st := status.New(codes.ResourceExhausted, description+", remove keys or raise the quota before retrying")
+	violation := &errdetails.QuotaFailure_Violation{Subject: "namespace:" + namespace, Description: description}
+	if detailed, err := st.WithDetails(&errdetails.QuotaFailure{Violations: []*errdetails.QuotaFailure_Violation{violation}}); err == nil {
+		st = detailed
+	}
+	return st.Err()
+}
+
+// RetryDelay returns how long the caller was told to wait before retrying a request that failed with the given error
+func RetryDelay(err error) (time.Duration, bool) {
+	for _, detail := range status.Convert(err).Details() {
+		if info, ok := detail.(*errdetails.RetryInfo); ok {
+			return info.RetryDelay.AsDuration(), true
+		}
+	}
+	return 0, false
+}
+
+// clientName returns the name a caller's rate limits are tracked under: its user, or the host of its address when it has not authenticated.
+// Other nodes get an empty name because they are never limited.
+func clientName(id *Identity, addr string) string {
+	if id != nil {
+		if id.Node {
+			return ""
+		}
+		return id.User
+	}
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+	return addr
+}
+
+// limitedMethods are the methods whose requests are rate limited
+var limitedMethods = map[string]bool{
+	"/api.Database/Get":        true,
+	"/api.Database/Set":        true,
+	"/api.Database/Remove":     true,
+	"/api.Database/GetMany":    true,
+	"/api.Database/SetMany":    true,
+	"/api.Database/Txn":        true,
+	"/api.Database/QueryIndex": true,
+}
+
+// limitCall takes the cost of a gRPC request from the rate limits of its caller and namespace
+func (s *DBServer) limitCall(ctx context.Context, method string, req interface{}) error {
+	if !limitedMethods[method] {
+		return nil
+	}
+	namespace, ops, bytes := "", 0, 0
+	switch r := req.(type) {
+	case *api.IDRequest:
+		namespace, ops, bytes = r.Namespace, 1, len(r.ID)
+	case *api.IDValueRequest:
+		namespace, ops, bytes = r.Namespace, 1, len(r.ID)+len(r.Value)
+	case *api.GetManyRequest:
+		namespace, ops = r.Namespace, len(r.IDs)
+		for _, id := range r.IDs {
+			bytes += len(id)
+		}
+	case *api.SetManyRequest:
+		namespace, ops = r.Namespace, len(r.Values)
+		for id, value := range r.Values {
+			bytes += len(id) + len(value)
+		}
+	case *api.TxnRequest:
+		namespace, ops = r.Namespace, len(r.Ops)
+		for _, op := range r.Ops {
+			bytes += len(op.ID) + len(op.Value)
+		}
+	case *api.QueryIndexRequest:
+		if r.Local {
+			return nil
+		}
+		namespace, ops = r.Namespace, 1
+	default:
+		return nil
+	}
+	return s.limit(clientName(callerIdentity(ctx), callerAddress(ctx)), namespace, ops, bytes)
+}
+
+// toRateLimit converts a rate limit to its API representation
+func toRateLimit(r RateLimit) *api.RateLimit {
+	return &api.RateLimit{OpsPerSecond: r.OpsPerSecond, BytesPerSecond: r.BytesPerSecond}
+}
+
+// toLimitsResponse converts the rate limits to their API representation
+func toLimitsResponse(config *LimitsConfig) *api.LimitsResponse {
+	response := &api.LimitsResponse{
+		DefaultClient: toRateLimit(config.DefaultClient),
+		Clients:       make(map[string]*api.RateLimit),
+		Namespaces:    make(map[string]*api.RateLimit),
+	}
+	for name, r := range config.Clients {
+		response.Clients[name] = toRateLimit(r)
+	}
+	for name, r := range config.Namespaces {
+		response.Namespaces[name] = toRateLimit(r)
+	}
+	return response
+}
+
+// SetLimits replaces the rate limit of a client, or the rate limit and storage quota of a namespace.
+// The other shards are told to read the limits again so that the change applies everywhere right away.
+func (s *DBServer) SetLimits(ctx context.Context, request *api.LimitsRequest) (*api.LimitsResponse, error) {
+	if request.Local {
+		s.forgetLimits()
+		s.forgetNamespaces()
+		return &api.LimitsResponse{}, nil
+	}
+	rate := RateLimit{OpsPerSecond: request.Rate.GetOpsPerSecond(), BytesPerSecond: request.Rate.GetBytesPerSecond()}
+	if rate.OpsPerSecond < 0 || rate.BytesPerSecond < 0 || request.MaxKeys < 0 || request.MaxBytes < 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "limits can not be negative")
+	}
+	if request.Client != "" && (request.Namespace != "" || request.MaxKeys != 0 || request.MaxBytes != 0) {
+		return nil, status.Errorf(codes.InvalidArgument, "clients only have rate limits")
+	}
+	if request.Client == "" && request.Namespace == "" && (request.MaxKeys != 0 || request.MaxBytes != 0) {
+		return nil, status.Errorf(codes.InvalidArgument, "the default namespace has no storage quota")
+	}
+	if request.Client == "" && request.Namespace != "" {
+		configs := make(map[string]NamespaceConfig)
+		err := s.updateReserved(namespacesKey, &configs, func() error {
+			config, ok := configs[request.Namespace]
+			if !ok {
+				return status.Errorf(codes.NotFound, "unknown namespace: %s", request.Namespace)
+			}
+			config.MaxKeys = request.MaxKeys
+			config.MaxBytes = request.MaxBytes
+			configs[request.Namespace] = config
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	config := &LimitsConfig{}
+	err := s.updateReserved(limitsKey, config, func() error {
+		limits := &config.Namespaces
+		name := request.Namespace
+		if request.Client == AllClients {
+			config.DefaultClient = rate
+			return nil
+		} else if request.Client != "" {
+			limits, name = &config.Clients, request.Client
+		}
+		if *limits == nil {
+			*limits = make(map[string]RateLimit)
+		}
+		if rate == (RateLimit{}) {
+			delete(*limits, name)
+		} else {
+			(*limits)[name] = rate
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.forgetLimits()
+	s.forgetNamespaces()
+	if s.replicated() {
+		// Shards that miss this pick up the change once their cached limits expire
+		s.broadcast(ctx, codes.OK, func(ctx context.Context, c api.DatabaseClient) error {
+			_, err := c.SetLimits(ctx, &api.LimitsRequest{Local: true})
+			return err
+		})
+	}
+	s.Logger.InfoContext(ctx, "Limits changed", "client", request.Client, "namespace", request.Namespace, "ops_per_second", rate.OpsPerSecond, "bytes_per_second", rate.BytesPerSecond)
+	return toLimitsResponse(config), nil
+}
+
+// Limits returns the rate limits of every client and namespace.  Storage quotas are returned by Namespaces.
+func (s *DBServer) Limits(ctx context.Context, request *api.EmptyRequest) (*api.LimitsResponse, error) {
+	config, err := s.loadLimits()
+	if err != nil {
+		return nil, err
+	}
+	return toLimitsResponse(config), nil
+}
@@ -0,0 +1,133 @@
+/******
+This file is part of Vaelen/DB.
+
+Copyright 2017, Andrew Young <andrew@vaelen.org>
+
+    Vaelen/DB is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+    Vaelen/DB is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+    You should have received a copy of the GNU General Public License
+along with Vaelen/DB.  If not, see <http://www.gnu.org/licenses/>.
+******/
+
+package server
+
+import (
+	"testing"
+
+	"github.com/vaelen/db/api"
+
+	"golang.org/x/net/context"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// TestLimits tests that rate limits and quotas set on one node are enforced by every node
+func TestLimits(t *testing.T) {
+	servers, stop := startCluster(t, 30330, 2)
+	defer stop()
+	ctx := context.Background()
+
+	if _, err := servers[0].SetLimits(ctx, &api.LimitsRequest{Client: "alice", MaxKeys: 1}); status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("Expected InvalidArgument, got %v\n", err)
+	}
+	if _, err := servers[0].SetLimits(ctx, &api.LimitsRequest{Client: "alice", Rate: &api.RateLimit{OpsPerSecond: 2}}); err != nil {
+		t.Fatalf("SetLimits Error: %s\n", err.Error())
+	}
+	for i := 0; i < 2; i++ {
+		if err := servers[1].limit("alice", "", 1, 0); err != nil {
+			t.Fatalf("Request %d was limited: %s\n", i, err.Error())
+		}
+	}
+	err := servers[1].limit("alice", "", 1, 0)
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("Expected ResourceExhausted, got %v\n", err)
+	}
+	if wait, ok := RetryDelay(err); !ok || wait <= 0 {
+		t.Fatalf("Expected a retry delay, got %s\n", wait)
+	}
+	if err := servers[1].limit("bob", "", 1, 0); err != nil {
+		t.Fatalf("Other clients were limited: %s\n", err.Error())
+	}
+	if err := servers[1].limit("", "", 100, 0); err != nil {
+		t.Fatalf("Nodes were limited: %s\n", err.Error())
+	}
+
+	// Namespace quotas are changed at runtime through SetLimits
+	if _, err := servers[0].CreateNamespace(ctx, &api.NamespaceRequest{Name: "team"}); err != nil {
+		t.Fatalf("CreateNamespace Error: %s\n", err.Error())
+	}
+	if _, err := servers[0].SetLimits(ctx, &api.LimitsRequest{Namespace: "team", Rate: &api.RateLimit{BytesPerSecond: 10}, MaxKeys: 1}); err != nil {
+		t.Fatalf("SetLimits Error: %s\n", err.Error())
+	}
+	if err := servers[1].limit("bob", "team", 1, 8); err != nil {
+		t.Fatalf("Request was limited: %s\n", err.Error())
+	}
+	if err := servers[1].limit("bob", "team", 1, 8); status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("Expected ResourceExhausted, got %v\n", err)
+	}
+	set := func(key string) error {
+		_, err := servers[1].Set(ctx, &api.IDValueRequest{Namespace: "team", ID: key, Value: "v", Consistency: api.Consistency_ALL})
+		return err
+	}
+	if err := set("a"); err != nil {
+		t.Fatalf("Set Error: %s\n", err.Error())
+	}
+	err = set("b")
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("Expected ResourceExhausted, got %v\n", err)
+	}
+	quota := false
+	for _, detail := range status.Convert(err).Details() {
+		_, ok := detail.(*errdetails.QuotaFailure)
+		quota = quota || ok
+	}
+	if !quota {
+		t.Fatalf("Expected a quota failure, got %v\n", status.Convert(err).Details())
+	}
+
+	limits, err := servers[1].Limits(ctx, &api.EmptyRequest{})
+	if err != nil {
+		t.Fatalf("Limits Error: %s\n", err.Error())
+	}
+	if limits.Clients["alice"].GetOpsPerSecond() != 2 || limits.Namespaces["team"].GetBytesPerSecond() != 10 {
+		t.Fatalf("Unexpected limits: %v\n", limits)
+	}
+	if _, err := servers[0].SetLimits(ctx, &api.LimitsRequest{Client: "alice"}); err != nil {
+		t.Fatalf("SetLimits Error: %s\n", err.Error())
+	}
+	if err := servers[1].limit("alice", "", 1, 0); err != nil {
+		t.Fatalf("Removed limit was still applied: %s\n", err.Error())
+	}
+}
+
+// TestLimitsUnavailable tests that limits that can not be read are not read again by every request
+func TestLimitsUnavailable(t *testing.T) {
+	config := newCluster(30379, 3)
+	s, stop := startServer(t, config, config.Shards[0])
+	defer stop()
+
+	first := s.limitsConfig()
+	if first == nil {
+		t.Fatalf("Expected no limits to be cached while they can not be read\n")
+	}
+	s.limits.Lock()
+	loaded := s.limits.loaded
+	s.limits.Unlock()
+	if second := s.limitsConfig(); second != first {
+		t.Fatalf("Expected the cached limits to be used, got %v\n", second)
+	}
+	s.limits.Lock()
+	defer s.limits.Unlock()
+	if !s.limits.loaded.Equal(loaded) {
+		t.Fatalf("Limits were read again before the cache expired\n")
+	}
+}
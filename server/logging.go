@@ -0,0 +1,89 @@
+/******
+This file is part of Vaelen/DB.
+
+Copyright 2017, Andrew Young <andrew@vaelen.org>
+
+    Vaelen/DB is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+    Vaelen/DB is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+    You should have received a copy of the GNU General Public License
+along with Vaelen/DB.  If not, see <http://www.gnu.org/licenses/>.
+******/
+
+package server
+
+import (
+	"github.com/vaelen/db/api"
+	"github.com/vaelen/db/logging"
+	"github.com/vaelen/db/trace"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// detach returns a background context that keeps the trace, request ID and timer of ctx, for work that outlives the request
+func detach(ctx context.Context) context.Context {
+	detached := logging.WithRequestID(trace.Detach(ctx), logging.RequestID(ctx))
+	if timer := requestTimer(ctx); timer != nil {
+		detached = context.WithValue(detached, timerKey{}, timer)
+	}
+	return detached
+}
+
+// receiveRequestID attaches the request ID sent by the caller to ctx, or a new one if the caller did not send one, and sends it back
+func receiveRequestID(ctx context.Context) context.Context {
+	id := ""
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(logging.RequestIDHeader); len(values) > 0 {
+			id = values[0]
+		}
+	}
+	if id == "" {
+		id = logging.NewRequestID()
+	}
+	grpc.SetHeader(ctx, metadata.Pairs(logging.RequestIDHeader, id))
+	return logging.WithRequestID(ctx, id)
+}
+
+// sendRequestID sends the ID of the request being handled along with requests made to other nodes on its behalf
+func sendRequestID(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	if id := logging.RequestID(ctx); id != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, logging.RequestIDHeader, id)
+	}
+	return invoker(ctx, method, req, reply, cc, opts...)
+}
+
+// toLogLevelsResponse converts the level of every component to its API representation
+func (s *DBServer) toLogLevelsResponse() *api.LogLevelsResponse {
+	response := &api.LogLevelsResponse{Levels: make(map[string]string)}
+	for component, level := range s.logs.Levels() {
+		response.Levels[component] = level.String()
+	}
+	return response
+}
+
+// SetLogLevel changes the log level of a component of this server, or of every component when none is given
+func (s *DBServer) SetLogLevel(ctx context.Context, request *api.LogLevelRequest) (*api.LogLevelsResponse, error) {
+	level, err := logging.ParseLevel(request.Level)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "%s", err)
+	}
+	s.logs.SetLevel(request.Component, level)
+	s.Logger.InfoContext(ctx, "Log level changed", "target", request.Component, "level", level)
+	return s.toLogLevelsResponse(), nil
+}
+
+// LogLevels returns the log level of every component of this server
+func (s *DBServer) LogLevels(ctx context.Context, request *api.EmptyRequest) (*api.LogLevelsResponse, error) {
+	return s.toLogLevelsResponse(), nil
+}
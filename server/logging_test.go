@@ -0,0 +1,114 @@
+/******
+This file is part of Vaelen/DB.
+
+Copyright 2017, Andrew Young <andrew@vaelen.org>
+
+    Vaelen/DB is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+    Vaelen/DB is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+    You should have received a copy of the GNU General Public License
+along with Vaelen/DB.  If not, see <http://www.gnu.org/licenses/>.
+******/
+
+package server
+
+import (
+	"bytes"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/vaelen/db/api"
+	"github.com/vaelen/db/logging"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// syncBuffer is a buffer that can be written to by several goroutines
+type syncBuffer struct {
+	sync.Mutex
+	b bytes.Buffer
+}
+
+func (s *syncBuffer) Write(p []byte) (int, error) {
+	s.Lock()
+	defer s.Unlock()
+	return s.b.Write(p)
+}
+
+func (s *syncBuffer) String() string {
+	s.Lock()
+	defer s.Unlock()
+	return s.b.String()
+}
+
+// TestSetLogLevel tests changing log levels at runtime and that log lines carry the caller's request ID
+func TestSetLogLevel(t *testing.T) {
+	var b syncBuffer
+	s := New(logging.Text(&b), "")
+	defer s.Stop()
+	lis, err := net.Listen("tcp", "localhost:30360")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	grpcServer := grpc.NewServer(grpc.UnaryInterceptor(s.UnaryInterceptor))
+	api.RegisterDatabaseServer(grpcServer, s)
+	go grpcServer.Serve(lis)
+	defer grpcServer.Stop()
+
+	conn, err := grpc.Dial("localhost:30360", grpc.WithInsecure())
+	if err != nil {
+		t.Fatalf("Dial Error: %s\n", err.Error())
+	}
+	defer conn.Close()
+	c := api.NewDatabaseClient(conn)
+
+	if _, err := c.SetLogLevel(context.Background(), &api.LogLevelRequest{Level: "verbose"}); err == nil {
+		t.Fatalf("Expected an unknown level to be rejected\n")
+	}
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), logging.RequestIDHeader, "abc")
+	var header metadata.MD
+	response, err := c.SetLogLevel(ctx, &api.LogLevelRequest{Component: "storage", Level: "debug"}, grpc.Header(&header))
+	if err != nil {
+		t.Fatalf("SetLogLevel Error: %s\n", err.Error())
+	}
+	if response.Levels["storage"] != "DEBUG" || response.Levels["network"] != "INFO" {
+		t.Fatalf("Unexpected levels: %v\n", response.Levels)
+	}
+	if ids := header.Get(logging.RequestIDHeader); len(ids) != 1 || ids[0] != "abc" {
+		t.Errorf("Expected the request ID to be sent back, got %v\n", ids)
+	}
+	if !strings.Contains(b.String(), `msg="Log level changed" component=network target=storage level=DEBUG request_id=abc`) {
+		t.Errorf("Expected the change to be logged with the request ID, got %q\n", b.String())
+	}
+
+	if _, err := c.Set(context.Background(), &api.IDValueRequest{ID: "foo", Value: "bar"}, grpc.Header(&header)); err != nil {
+		t.Fatalf("Set Error: %s\n", err.Error())
+	}
+	ids := header.Get(logging.RequestIDHeader)
+	if len(ids) != 1 || ids[0] == "" || ids[0] == "abc" {
+		t.Fatalf("Expected a new request ID to be sent back, got %v\n", ids)
+	}
+	if !strings.Contains(b.String(), "level=DEBUG msg=Set component=storage key=foo size=3 request_id="+ids[0]) {
+		t.Errorf("Expected storage debug lines to be logged with the request ID, got %q\n", b.String())
+	}
+
+	levels, err := c.LogLevels(context.Background(), &api.EmptyRequest{})
+	if err != nil {
+		t.Fatalf("LogLevels Error: %s\n", err.Error())
+	}
+	if levels.Levels["storage"] != "DEBUG" {
+		t.Errorf("Unexpected levels: %v\n", levels.Levels)
+	}
+}
@@ -0,0 +1,429 @@
+/******
+This file is part of Vaelen/DB.
+
+Copyright 2017, Andrew Young <andrew@vaelen.org>
+
+    Vaelen/DB is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+    Vaelen/DB is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+    You should have received a copy of the GNU General Public License
+along with Vaelen/DB.  If not, see <http://www.gnu.org/licenses/>.
+******/
+
+package server
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log/slog"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vaelen/db/logging"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// maxMemcacheKey is the longest key memcached clients may use
+	maxMemcacheKey = 250
+	// maxMemcacheItem is the largest value that can be stored
+	maxMemcacheItem = 1024 * 1024
+	// maxRelativeExptime is the largest exptime that is relative to now, larger ones are Unix times
+	maxRelativeExptime = 60 * 60 * 24 * 30
+)
+
+// MemcacheServer serves the memcached text protocol on top of a database server.
+// Commands go through the same operations as the gRPC service, so values are routed, replicated and kept like any other.
+// Flags and expirations are stored and replicated with the values.
+// Commands that read a key before they write it, like cas, add and incr, only write it if no other write got in between.
+// Empty values are treated as missing.
+// The text protocol has no authentication, so it bypasses the server's users and roles.
+type MemcacheServer struct {
+	Logger  *slog.Logger
+	db      *DBServer
+	tracker connTracker
+}
+
+// NewMemcacheServer creates a memcached protocol server for the given database server
+func NewMemcacheServer(s *DBServer) *MemcacheServer {
+	return &MemcacheServer{
+		Logger: s.logs.Logger("memcache"),
+		db:     s,
+	}
+}
+
+// Serve accepts connections on the given listener until the server is closed
+func (m *MemcacheServer) Serve(lis net.Listener) error {
+	m.Logger.Info("Listening", "address", lis.Addr().String())
+	return m.tracker.serve(lis, m.serveConn)
+}
+
+// Close stops every listener and closes every connection
+func (m *MemcacheServer) Close() {
+	m.tracker.close()
+}
+
+// memcacheConn is a client connection
+type memcacheConn struct {
+	*bufio.Writer
+	reader *bufio.Reader
+	// noreply is set when the current command asked not to be answered
+	noreply bool
+	quit    bool
+	// addr is the client's address
+	addr string
+	// ctx carries the ID of the command being run
+	ctx context.Context
+}
+
+// reply writes a line unless the command asked not to be answered
+func (c *memcacheConn) reply(format string, args ...interface{}) {
+	if !c.noreply {
+		fmt.Fprintf(c, format+"\r\n", args...)
+	}
+}
+
+// serverError writes an error returned by the database server
+func (c *memcacheConn) serverError(err error) {
+	c.reply("SERVER_ERROR %s", status.Convert(err).Message())
+}
+
+func (m *MemcacheServer) serveConn(conn net.Conn) {
+	c := &memcacheConn{
+		Writer: bufio.NewWriter(conn),
+		reader: bufio.NewReader(conn),
+		addr:   conn.RemoteAddr().String(),
+	}
+	// Commands are read once the server has loaded
+	<-m.db.Loaded()
+	for !c.quit {
+		line, err := readLine(c.reader)
+		if err != nil {
+			return
+		}
+		c.noreply = false
+		m.dispatch(c, strings.Fields(line))
+		if c.reader.Buffered() == 0 || c.quit {
+			if err := c.Flush(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// dispatch runs a command and writes its reply
+func (m *MemcacheServer) dispatch(c *memcacheConn, fields []string) {
+	c.ctx = logging.WithRequestID(context.Background(), logging.NewRequestID())
+	if len(fields) == 0 {
+		c.reply("ERROR")
+		return
+	}
+	c.ctx = withGatewayCommand(c.ctx, "memcache "+fields[0], c.addr)
+	args := fields[1:]
+	switch fields[0] {
+	case "get", "gets", "delete", "incr", "decr", "touch":
+		// Stored values are limited once their data has been read
+		ops := 1
+		if fields[0] == "get" || fields[0] == "gets" {
+			ops = len(args)
+		}
+		if err := m.db.limit(clientName(nil, c.addr), "", ops, len(strings.Join(args, ""))); err != nil {
+			c.serverError(err)
+			return
+		}
+	}
+	switch fields[0] {
+	case "get":
+		m.get(c, args, false)
+	case "gets":
+		m.get(c, args, true)
+	case "set", "add", "replace", "cas":
+		m.store(c, fields[0], args)
+	case "delete":
+		m.delete(c, args)
+	case "incr", "decr":
+		m.incr(c, fields[0] == "decr", args)
+	case "touch":
+		m.touch(c, args)
+	case "version":
+		c.reply("VERSION 0.1")
+	case "quit":
+		c.quit = true
+	default:
+		c.reply("ERROR")
+	}
+}
+
+// validKey returns true if the key can be used by memcached clients
+func validKey(key string) bool {
+	if len(key) == 0 || len(key) > maxMemcacheKey {
+		return false
+	}
+	for i := 0; i < len(key); i++ {
+		if key[i] <= ' ' || key[i] == 0x7f {
+			return false
+		}
+	}
+	return true
+}
+
+// parseNoreply strips a trailing noreply argument
+func (c *memcacheConn) parseNoreply(args []string) []string {
+	if len(args) > 0 && args[len(args)-1] == "noreply" {
+		c.noreply = true
+		return args[:len(args)-1]
+	}
+	return args
+}
+
+// memcacheDeadline converts an exptime into a deadline.  Zero never expires, up to 30 days is relative and larger values are Unix times.
+// It returns true if the time has already passed.
+func memcacheDeadline(exptime int64, now time.Time) (time.Time, bool) {
+	switch {
+	case exptime == 0:
+		return time.Time{}, false
+	case exptime < 0:
+		return time.Time{}, true
+	case exptime <= maxRelativeExptime:
+		return now.Add(time.Duration(exptime) * time.Second), false
+	}
+	deadline := time.Unix(exptime, 0)
+	return deadline, !now.Before(deadline)
+}
+
+// casUnique identifies the current version of an item.  It changes whenever the value, its flags, its expiration or its version do.
+func casUnique(item cacheItem) uint64 {
+	if item.Digest == 0 {
+		return 1
+	}
+	return item.Digest
+}
+
+func (m *MemcacheServer) get(c *memcacheConn, keys []string, withCAS bool) {
+	if len(keys) == 0 {
+		c.reply("ERROR")
+		return
+	}
+	for _, key := range keys {
+		if !validKey(key) {
+			c.reply("CLIENT_ERROR bad command line format")
+			return
+		}
+	}
+	for _, key := range keys {
+		item, ok, err := m.db.cacheRead(c.ctx, key)
+		if err != nil {
+			c.serverError(err)
+			return
+		}
+		if !ok {
+			continue
+		}
+		if withCAS {
+			c.reply("VALUE %s %d %d %d", key, item.Flags, len(item.Value), casUnique(item))
+		} else {
+			c.reply("VALUE %s %d %d", key, item.Flags, len(item.Value))
+		}
+		c.reply("%s", item.Value)
+	}
+	c.reply("END")
+}
+
+// store handles set, add, replace and cas: <command> <key> <flags> <exptime> <bytes> [<cas unique>] [noreply]
+func (m *MemcacheServer) store(c *memcacheConn, command string, args []string) {
+	args = c.parseNoreply(args)
+	expected := 4
+	if command == "cas" {
+		expected = 5
+	}
+	if len(args) != expected {
+		c.reply("ERROR")
+		return
+	}
+	key := args[0]
+	flags, flagsErr := strconv.ParseUint(args[1], 10, 32)
+	exptime, exptimeErr := strconv.ParseInt(args[2], 10, 64)
+	length, lengthErr := strconv.Atoi(args[3])
+	var unique uint64
+	var uniqueErr error
+	if command == "cas" {
+		unique, uniqueErr = strconv.ParseUint(args[4], 10, 64)
+	}
+	if lengthErr != nil || length < 0 {
+		c.reply("CLIENT_ERROR bad command line format")
+		return
+	}
+	if !validKey(key) || flagsErr != nil || exptimeErr != nil || uniqueErr != nil || length > maxMemcacheItem {
+		// Skip the data so that the connection stays in sync
+		io.CopyN(ioutil.Discard, c.reader, int64(length)+2)
+		if length > maxMemcacheItem {
+			c.reply("SERVER_ERROR object too large for cache")
+		} else {
+			c.reply("CLIENT_ERROR bad command line format")
+		}
+		return
+	}
+	data := make([]byte, length+2)
+	if _, err := io.ReadFull(c.reader, data); err != nil {
+		c.quit = true
+		return
+	}
+	if data[length] != '\r' || data[length+1] != '\n' {
+		// Skip the rest of the line that was sent as data
+		readLine(c.reader)
+		c.reply("CLIENT_ERROR bad data chunk")
+		return
+	}
+	value := string(data[:length])
+	if err := m.db.limit(clientName(nil, c.addr), "", 1, len(key)+len(value)); err != nil {
+		c.serverError(err)
+		return
+	}
+
+	now := time.Now()
+	deadline, expired := memcacheDeadline(exptime, now)
+	if expired {
+		// The value is stored already expired so that it replaces the current one just like any other value
+		deadline = now
+	}
+	if command == "set" {
+		if err := m.db.cacheWrite(c.ctx, key, value, uint32(flags), deadline, nil); err != nil {
+			c.serverError(err)
+			return
+		}
+		c.reply("STORED")
+		return
+	}
+	reply := "STORED"
+	err := m.db.cacheUpdate(c.ctx, key, func(item cacheItem, ok bool) (cacheItem, bool) {
+		switch {
+		case command == "add" && ok, command == "replace" && !ok:
+			reply = "NOT_STORED"
+		case command == "cas" && !ok:
+			reply = "NOT_FOUND"
+		case command == "cas" && casUnique(item) != unique:
+			reply = "EXISTS"
+		default:
+			reply = "STORED"
+			return cacheItem{Value: value, Flags: uint32(flags), Expires: deadline}, true
+		}
+		return item, false
+	})
+	if err != nil {
+		c.serverError(err)
+		return
+	}
+	c.reply("%s", reply)
+}
+
+// delete handles: delete <key> [0] [noreply]
+func (m *MemcacheServer) delete(c *memcacheConn, args []string) {
+	args = c.parseNoreply(args)
+	if len(args) == 2 && args[1] == "0" {
+		args = args[:1]
+	}
+	if len(args) != 1 || !validKey(args[0]) {
+		c.reply("CLIENT_ERROR bad command line format")
+		return
+	}
+	existed, err := m.db.cacheRemove(c.ctx, args[0])
+	switch {
+	case err != nil:
+		c.serverError(err)
+	case existed:
+		c.reply("DELETED")
+	default:
+		c.reply("NOT_FOUND")
+	}
+}
+
+// incr handles incr and decr: <command> <key> <delta> [noreply].
+// Increments wrap around at 64 bits and decrements stop at zero.
+func (m *MemcacheServer) incr(c *memcacheConn, decr bool, args []string) {
+	args = c.parseNoreply(args)
+	if len(args) != 2 || !validKey(args[0]) {
+		c.reply("ERROR")
+		return
+	}
+	delta, err := strconv.ParseUint(args[1], 10, 64)
+	if err != nil {
+		c.reply("CLIENT_ERROR invalid numeric delta argument")
+		return
+	}
+	var reply string
+	err = m.db.cacheUpdate(c.ctx, args[0], func(item cacheItem, ok bool) (cacheItem, bool) {
+		if !ok {
+			reply = "NOT_FOUND"
+			return item, false
+		}
+		n, err := strconv.ParseUint(item.Value, 10, 64)
+		if err != nil {
+			reply = "CLIENT_ERROR cannot increment or decrement non-numeric value"
+			return item, false
+		}
+		switch {
+		case !decr:
+			n += delta
+		case delta > n:
+			n = 0
+		default:
+			n -= delta
+		}
+		reply = strconv.FormatUint(n, 10)
+		item.Value = reply
+		return item, true
+	})
+	if err != nil {
+		c.serverError(err)
+		return
+	}
+	c.reply("%s", reply)
+}
+
+// touch handles: touch <key> <exptime> [noreply]
+func (m *MemcacheServer) touch(c *memcacheConn, args []string) {
+	args = c.parseNoreply(args)
+	if len(args) != 2 || !validKey(args[0]) {
+		c.reply("ERROR")
+		return
+	}
+	exptime, err := strconv.ParseInt(args[1], 10, 64)
+	if err != nil {
+		c.reply("CLIENT_ERROR invalid exptime argument")
+		return
+	}
+	now := time.Now()
+	deadline, expired := memcacheDeadline(exptime, now)
+	if expired {
+		deadline = now
+	}
+	reply := "TOUCHED"
+	// The expiration is stored with the value, so the value is written again along with it
+	err = m.db.cacheUpdate(c.ctx, args[0], func(item cacheItem, ok bool) (cacheItem, bool) {
+		if !ok {
+			reply = "NOT_FOUND"
+			return item, false
+		}
+		reply = "TOUCHED"
+		item.Expires = deadline
+		return item, true
+	})
+	if err != nil {
+		c.serverError(err)
+		return
+	}
+	c.reply("%s", reply)
+}
@@ -0,0 +1,193 @@
+/******
+This file is part of Vaelen/DB.
+
+Copyright 2017, Andrew Young <andrew@vaelen.org>
+
+    Vaelen/DB is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+    Vaelen/DB is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+    You should have received a copy of the GNU General Public License
+along with Vaelen/DB.  If not, see <http://www.gnu.org/licenses/>.
+******/
+
+package server
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// memcacheClient is a minimal memcached text protocol client
+type memcacheClient struct {
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+// send writes a command line followed by an optional data block
+func (c *memcacheClient) send(line string, data ...string) {
+	fmt.Fprintf(c.conn, "%s\r\n", line)
+	for _, d := range data {
+		fmt.Fprintf(c.conn, "%s\r\n", d)
+	}
+}
+
+// readReply reads lines until one that ends a reply
+func (c *memcacheClient) readReply(t *testing.T) []string {
+	lines := make([]string, 0)
+	for {
+		line, err := c.reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("Read Error: %s\n", err.Error())
+		}
+		line = strings.TrimSuffix(line, "\r\n")
+		lines = append(lines, line)
+		if !strings.HasPrefix(line, "VALUE ") && (len(lines) < 2 || !strings.HasPrefix(lines[len(lines)-2], "VALUE ")) {
+			return lines
+		}
+	}
+}
+
+// TestMemcache drives the memcached protocol listener with a hand written client
+func TestMemcache(t *testing.T) {
+	s := New(testLogs, "")
+	defer s.Stop()
+	m := NewMemcacheServer(s)
+	defer m.Close()
+	lis, err := net.Listen("tcp", "localhost:30270")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	go m.Serve(lis)
+
+	conn, err := net.Dial("tcp", "localhost:30270")
+	if err != nil {
+		t.Fatalf("Dial Error: %s\n", err.Error())
+	}
+	defer conn.Close()
+	c := &memcacheClient{conn: conn, reader: bufio.NewReader(conn)}
+
+	expect := func(expected string, line string, data ...string) []string {
+		c.send(line, data...)
+		reply := c.readReply(t)
+		if strings.Join(reply, "|") != expected {
+			t.Errorf("Command: %s, Reply: %s, Expected: %s\n", line, strings.Join(reply, "|"), expected)
+		}
+		return reply
+	}
+
+	expect("STORED", "set foo 42 0 3", "bar")
+	expect("VALUE foo 42 3|bar|END", "get foo missing")
+	expect("NOT_STORED", "add foo 0 0 1", "x")
+	expect("NOT_STORED", "replace missing 0 0 1", "x")
+	expect("STORED", "add new 1 0 1", "x")
+	expect("STORED", "replace new 2 0 1", "y")
+	expect("VALUE new 2 1|y|END", "get new")
+
+	c.send("gets foo")
+	reply := c.readReply(t)
+	var flags, length int
+	var unique uint64
+	if len(reply) != 3 {
+		t.Fatalf("Unexpected gets reply: %v\n", reply)
+	}
+	fmt.Sscanf(reply[0], "VALUE foo %d %d %d", &flags, &length, &unique)
+	expect("EXISTS", fmt.Sprintf("cas foo 0 0 3 %d", unique+1), "baz")
+	expect("STORED", fmt.Sprintf("cas foo 0 0 3 %d", unique), "baz")
+	expect("EXISTS", fmt.Sprintf("cas foo 0 0 3 %d", unique), "qux")
+	expect("NOT_FOUND", "cas missing 0 0 1 1", "x")
+
+	expect("STORED", "set counter 0 0 2", "10")
+	expect("15", "incr counter 5")
+	expect("0", "decr counter 20")
+	expect("CLIENT_ERROR cannot increment or decrement non-numeric value", "incr foo 1")
+	expect("NOT_FOUND", "incr missing 1")
+
+	expect("DELETED", "delete foo")
+	expect("NOT_FOUND", "delete foo")
+	expect("END", "get foo")
+
+	expect("STORED", "set temp 0 1 1", "x")
+	expect("TOUCHED", "touch temp -1")
+	expect("END", "get temp")
+	expect("NOT_FOUND", "touch temp 10")
+	expect("STORED", "set temp 0 -1 1", "x")
+	expect("END", "get temp")
+	expect("STORED", fmt.Sprintf("set abs 0 %d 1", time.Now().Add(time.Hour).Unix()), "x")
+	expect("VALUE abs 0 1|x|END", "get abs")
+
+	c.send("set quiet 0 0 1 noreply", "q")
+	expect("VALUE quiet 0 1|q|END", "get quiet")
+	expect("CLIENT_ERROR bad data chunk", "set bad 0 0 1", "toolong")
+	expect("ERROR", "flush_all")
+	expect("CLIENT_ERROR bad command line format", "set "+strings.Repeat("k", 251)+" 0 0 1", "x")
+}
+
+// TestCacheReplication tests that flags are replicated with the values and that a stale conditional write is rejected by the replicas
+func TestCacheReplication(t *testing.T) {
+	servers, stop := startCluster(t, 30374, 2)
+	defer stop()
+	ctx := context.Background()
+
+	if err := servers[0].cacheWrite(ctx, "foo", "bar", 42, time.Time{}, nil); err != nil {
+		t.Fatalf("Write Error: %s\n", err.Error())
+	}
+	item, ok, err := servers[1].cacheRead(ctx, "foo")
+	if err != nil || !ok {
+		t.Fatalf("Read Error: %v, found: %v\n", err, ok)
+	}
+	if item.Value != "bar" || item.Flags != 42 {
+		t.Fatalf("Unexpected item: %+v\n", item)
+	}
+
+	// Another coordinator changes the key between the read and the conditional write
+	if err := servers[1].cacheWrite(ctx, "foo", "baz", 0, time.Time{}, nil); err != nil {
+		t.Fatalf("Write Error: %s\n", err.Error())
+	}
+	if err := servers[0].cacheWrite(ctx, "foo", "stale", 0, time.Time{}, &item); status.Code(err) != codes.Aborted {
+		t.Fatalf("Expected Aborted, got %v\n", err)
+	}
+	for i, s := range servers {
+		if v := s.Storage.Get("foo"); v != "baz" {
+			t.Errorf("Replica %d has %q, expected baz\n", i, v)
+		}
+	}
+
+	// Concurrent increments through different coordinators are all applied
+	servers[0].cacheWrite(ctx, "counter", "0", 0, time.Time{}, nil)
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(s *DBServer) {
+			defer wg.Done()
+			err := s.cacheUpdate(ctx, "counter", func(item cacheItem, ok bool) (cacheItem, bool) {
+				n, _ := strconv.Atoi(item.Value)
+				item.Value = strconv.Itoa(n + 1)
+				return item, true
+			})
+			if err != nil {
+				t.Errorf("Update Error: %s\n", err.Error())
+			}
+		}(servers[i%2])
+	}
+	wg.Wait()
+	if item, _, _ := servers[0].cacheRead(ctx, "counter"); item.Value != "4" {
+		t.Errorf("Counter: %s, Expected: 4\n", item.Value)
+	}
+}
@@ -0,0 +1,242 @@
+/******
+This file is part of Vaelen/DB.
+
+Copyright 2017, Andrew Young <andrew@vaelen.org>
+
+    Vaelen/DB is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+    Vaelen/DB is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+    You should have received a copy of the GNU General Public License
+along with Vaelen/DB.  If not, see <http://www.gnu.org/licenses/>.
+******/
+
+package server
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/vaelen/db/api"
+	"github.com/vaelen/db/cluster"
+	"github.com/vaelen/db/storage"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ClusterCache keeps a copy of the cluster configuration on disk so that a restarted server can route requests before it reconnects
+type ClusterCache struct {
+	// Logger is the logger used by the cache
+	Logger *slog.Logger
+	// Path is the directory the configuration is saved in.  Nothing is saved when it is empty.
+	Path string
+	// Keyring encrypts the saved configuration.  It is not encrypted when it is nil.
+	Keyring *storage.Keyring
+}
+
+// NewClusterCache creates a cluster configuration cache in the given directory
+func NewClusterCache(logger *slog.Logger, dbPath string, keyring *storage.Keyring) *ClusterCache {
+	return &ClusterCache{
+		Logger:  logger,
+		Path:    dbPath,
+		Keyring: keyring,
+	}
+}
+
+func (c *ClusterCache) filename() string {
+	return filepath.Join(c.Path, "cluster.pb")
+}
+
+// Save writes the given configuration to disk
+func (c *ClusterCache) Save(config *cluster.Config) error {
+	if c.Path == "" || config == nil {
+		return nil
+	}
+	b, err := proto.Marshal(cluster.ToConfiguration(config))
+	if err != nil {
+		return err
+	}
+	return c.Keyring.WriteFile(c.filename(), b)
+}
+
+// Load reads the saved configuration from disk.  It returns nil if no configuration has been saved.
+func (c *ClusterCache) Load() (*cluster.Config, error) {
+	if c.Path == "" {
+		return nil, nil
+	}
+	b, err := c.Keyring.ReadFile(c.filename())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	config := &api.ClusterConfiguration{}
+	err = proto.Unmarshal(b, config)
+	if err != nil {
+		return nil, err
+	}
+	return cluster.FromConfiguration(config)
+}
+
+// configEpoch returns the epoch of the configuration, or zero if there is none
+func configEpoch(config *cluster.Config) uint64 {
+	if config == nil {
+		return 0
+	}
+	return config.Epoch
+}
+
+// setClusterConfig replaces the cluster configuration, saves it and wakes up any watchers.  The caller must hold the cluster lock.
+func (s *DBServer) setClusterConfig(config *cluster.Config) {
+	old := s.Cluster
+	s.Cluster = config
+	s.Logger.Info("Cluster configuration updated", "epoch", config.Epoch, "shards", len(config.Shards))
+	if len(config.Shards) > 1 && s.antiEntropyRunning() && s.gainsKeys(old, config) {
+		s.Logger.Info("Not ready until anti-entropy has copied the keys moved to this shard by the configuration change", "epoch", config.Epoch)
+		s.readiness.setMigrating(time.Now())
+	}
+	if err := s.ClusterCache.Save(config); err != nil {
+		s.Logger.Error("Could not save cluster configuration", "error", err)
+	}
+	close(s.clusterChanged)
+	s.clusterChanged = make(chan bool)
+}
+
+// watchCluster returns the current configuration and a channel that is closed when it changes
+func (s *DBServer) watchCluster() (*cluster.Config, chan bool) {
+	s.clusterLock.RLock()
+	defer s.clusterLock.RUnlock()
+	return s.Cluster, s.clusterChanged
+}
+
+// CompareAndSetClusterConfig replaces the cluster configuration if its epoch still matches the expected epoch.
+// The new configuration is given the next epoch and is returned.  An epoch of zero is expected when there is no configuration yet.
+func (s *DBServer) CompareAndSetClusterConfig(expected uint64, config *cluster.Config) (*cluster.Config, error) {
+	if _, err := cluster.NewPlacement(config); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "%s", err)
+	}
+	s.clusterLock.Lock()
+	defer s.clusterLock.Unlock()
+	if current := configEpoch(s.Cluster); current != expected {
+		return nil, status.Errorf(codes.FailedPrecondition, "stale epoch: expected %d, current %d", expected, current)
+	}
+	updated := *config
+	updated.Epoch = expected + 1
+	s.setClusterConfig(&updated)
+	return &updated, nil
+}
+
+// checkEpoch rejects a request that was routed with an older cluster configuration so that the client refreshes its copy.
+// An epoch of zero means the client did not route the request itself.
+func (s *DBServer) checkEpoch(epoch uint64) error {
+	if current := configEpoch(s.cluster()); epoch != 0 && epoch < current {
+		return status.Errorf(codes.FailedPrecondition, "stale epoch: request %d, current %d", epoch, current)
+	}
+	return nil
+}
+
+// GetClusterConfig returns the cluster configuration known to this server.
+// A server that is not part of a cluster returns an empty configuration.
+func (s *DBServer) GetClusterConfig(ctx context.Context, request *api.EmptyRequest) (*api.ClusterConfiguration, error) {
+	if config := cluster.ToConfiguration(s.cluster()); config != nil {
+		return config, nil
+	}
+	return &api.ClusterConfiguration{}, nil
+}
+
+// WatchClusterConfig sends every configuration newer than the given epoch until the caller goes away
+func (s *DBServer) WatchClusterConfig(request *api.WatchClusterRequest, stream api.Database_WatchClusterConfigServer) error {
+	epoch := request.Epoch
+	for {
+		config, changed := s.watchCluster()
+		if config != nil && config.Epoch > epoch {
+			err := stream.Send(cluster.ToConfiguration(config))
+			if err != nil {
+				return err
+			}
+			epoch = config.Epoch
+		}
+		select {
+		case <-changed:
+		case <-stream.Context().Done():
+			return nil
+		}
+	}
+}
+
+// UpdateClusterConfig applies a compare-and-set update to the cluster configuration.
+// Updates are forwarded to the config shard so that they are applied in order, which then pushes the result to every other shard.
+// A forwarded update that reaches a shard which is not the config shard in its own configuration is rejected rather than forwarded again.
+// The update succeeds once a majority of the shards in the new configuration hold it.  Gossip carries it to any shard the push misses.
+func (s *DBServer) UpdateClusterConfig(ctx context.Context, request *api.UpdateClusterRequest) (*api.ClusterConfiguration, error) {
+	if request.Config == nil {
+		return nil, status.Errorf(codes.InvalidArgument, "missing configuration")
+	}
+	if owner, ok := s.cluster().ConfigShard(); ok && !s.isSelf(owner) {
+		if request.Forwarded {
+			return nil, status.Errorf(codes.FailedPrecondition, "not the config shard: shard %s is", owner.ID)
+		}
+		c, err := s.peers.client(owner)
+		if err != nil {
+			return nil, status.Errorf(codes.Unavailable, "config shard unavailable: %s", err)
+		}
+		forwarded := *request
+		forwarded.Forwarded = true
+		return c.UpdateClusterConfig(ctx, &forwarded)
+	}
+
+	config, err := cluster.FromConfiguration(request.Config)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "%s", err)
+	}
+	updated, err := s.CompareAndSetClusterConfig(request.ExpectedEpoch, config)
+	if err != nil {
+		return nil, err
+	}
+	s.addShards(updated.Shards)
+	if held, total := s.pushClusterConfig(updated); held <= total/2 {
+		return nil, status.Errorf(codes.Unavailable, "configuration epoch %d is held by %d of %d shards", updated.Epoch, held, total)
+	}
+	return cluster.ToConfiguration(updated), nil
+}
+
+// pushClusterConfig sends the given configuration to every other shard by pinging them in parallel.
+// It returns how many shards, including this one, acknowledged it and how many shards there are.
+func (s *DBServer) pushClusterConfig(config *cluster.Config) (int, int) {
+	acks := make(chan bool, len(config.Shards))
+	for _, shard := range config.Shards {
+		if s.isSelf(shard) {
+			acks <- true
+			continue
+		}
+		go func(address string) {
+			acks <- s.ping(address, "")
+		}(shard.Address)
+	}
+	held := 0
+	for range config.Shards {
+		if <-acks {
+			held++
+		}
+	}
+	return held, len(config.Shards)
+}
+
+// reencryptCluster saves the cluster configuration again so that it is encrypted with the active data key
+func (s *DBServer) reencryptCluster() error {
+	s.clusterLock.RLock()
+	defer s.clusterLock.RUnlock()
+	return s.ClusterCache.Save(s.Cluster)
+}
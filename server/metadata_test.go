@@ -0,0 +1,168 @@
+/******
+This file is part of Vaelen/DB.
+
+Copyright 2017, Andrew Young <andrew@vaelen.org>
+
+    Vaelen/DB is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+    Vaelen/DB is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+    You should have received a copy of the GNU General Public License
+along with Vaelen/DB.  If not, see <http://www.gnu.org/licenses/>.
+******/
+
+package server
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/vaelen/db/api"
+	"github.com/vaelen/db/cluster"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// TestClusterCache tests that a restarted server starts with the configuration it saved
+func TestClusterCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "vdb-cluster")
+	if err != nil {
+		t.Fatalf("TempDir Error: %s\n", err.Error())
+	}
+	defer os.RemoveAll(dir)
+
+	s := New(testLogs, dir)
+	if s.Cluster != nil {
+		t.Fatalf("Unexpected configuration: %v\n", s.Cluster)
+	}
+	config := newCluster(0, 3)
+	config.Strategy = cluster.RingStrategy
+	if _, err := s.CompareAndSetClusterConfig(0, config); err != nil {
+		t.Fatalf("CompareAndSetClusterConfig Error: %s\n", err.Error())
+	}
+	s.Stop()
+
+	restarted := New(testLogs, dir)
+	defer restarted.Stop()
+	if restarted.Cluster == nil {
+		t.Fatalf("Configuration was not loaded\n")
+	}
+	if restarted.Cluster.Epoch != 1 || len(restarted.Cluster.Shards) != 3 || restarted.Cluster.Strategy != cluster.RingStrategy {
+		t.Fatalf("Unexpected configuration: %v\n", restarted.Cluster)
+	}
+}
+
+// TestCompareAndSetClusterConfig tests that updates are only applied to the expected epoch
+func TestCompareAndSetClusterConfig(t *testing.T) {
+	s := New(testLogs, "")
+	defer s.Stop()
+
+	updated, err := s.CompareAndSetClusterConfig(0, newCluster(0, 2))
+	if err != nil {
+		t.Fatalf("CompareAndSetClusterConfig Error: %s\n", err.Error())
+	}
+	if updated.Epoch != 1 || s.cluster().Epoch != 1 {
+		t.Fatalf("Epoch: %d, Expected: 1\n", updated.Epoch)
+	}
+
+	_, err = s.CompareAndSetClusterConfig(0, newCluster(0, 3))
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Fatalf("Expected a stale epoch error, got: %v\n", err)
+	}
+	if len(s.cluster().Shards) != 2 {
+		t.Fatalf("Stale update was applied\n")
+	}
+
+	if _, err = s.CompareAndSetClusterConfig(1, &cluster.Config{Strategy: "bogus"}); status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("Expected an invalid argument error, got: %v\n", err)
+	}
+	unsized := newCluster(0, 3)
+	unsized.Size = 0
+	if _, err = s.CompareAndSetClusterConfig(1, unsized); status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("Expected an invalid argument error for a cluster without a size, got: %v\n", err)
+	}
+
+	if _, err = s.CompareAndSetClusterConfig(1, newCluster(0, 3)); err != nil {
+		t.Fatalf("CompareAndSetClusterConfig Error: %s\n", err.Error())
+	}
+	if s.cluster().Epoch != 2 || len(s.cluster().Shards) != 3 {
+		t.Fatalf("Unexpected configuration: %v\n", s.cluster())
+	}
+}
+
+// TestUpdateClusterConfig tests that updates are forwarded to the config shard and reach every shard and watcher.
+// A forwarded update that reaches a shard which is not the config shard is rejected instead of being forwarded again.
+func TestUpdateClusterConfig(t *testing.T) {
+	servers, stop := startCluster(t, 30180, 3)
+	defer stop()
+
+	conn, err := grpc.Dial(servers[2].Self.Address, grpc.WithInsecure())
+	if err != nil {
+		t.Fatalf("Dial Error: %s\n", err.Error())
+	}
+	defer conn.Close()
+	c := api.NewDatabaseClient(conn)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	watch, err := c.WatchClusterConfig(ctx, &api.WatchClusterRequest{Epoch: 0})
+	if err != nil {
+		t.Fatalf("WatchClusterConfig Error: %s\n", err.Error())
+	}
+
+	config := cluster.ToConfiguration(servers[0].cluster())
+	config.Placement = cluster.RingStrategy
+	updated, err := c.UpdateClusterConfig(ctx, &api.UpdateClusterRequest{ExpectedEpoch: 0, Config: config})
+	if err != nil {
+		t.Fatalf("UpdateClusterConfig Error: %s\n", err.Error())
+	}
+	if updated.Epoch != 1 {
+		t.Fatalf("Epoch: %d, Expected: 1\n", updated.Epoch)
+	}
+
+	_, err = c.UpdateClusterConfig(ctx, &api.UpdateClusterRequest{ExpectedEpoch: 0, Config: config})
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Fatalf("Expected a stale epoch error, got: %v\n", err)
+	}
+
+	_, err = c.UpdateClusterConfig(ctx, &api.UpdateClusterRequest{ExpectedEpoch: 1, Config: config, Forwarded: true})
+	if status.Code(err) != codes.FailedPrecondition || servers[0].cluster().Epoch != 1 {
+		t.Fatalf("Expected a forwarded update to be rejected, got: %v\n", err)
+	}
+
+	for i, s := range servers {
+		if !waitFor(5*time.Second, func() bool { return s.cluster().Epoch == 1 }) {
+			t.Fatalf("Server %d did not receive the new configuration\n", i)
+		}
+		if s.placement().Strategy() != cluster.RingStrategy {
+			t.Errorf("Server %d is using %s placement\n", i, s.placement().Strategy())
+		}
+	}
+
+	received, err := watch.Recv()
+	if err != nil {
+		t.Fatalf("Watch Error: %s\n", err.Error())
+	}
+	if received.Epoch != 1 || received.Placement != cluster.RingStrategy {
+		t.Fatalf("Unexpected configuration from watch: %v\n", received)
+	}
+
+	current, err := c.GetClusterConfig(ctx, &api.EmptyRequest{})
+	if err != nil {
+		t.Fatalf("GetClusterConfig Error: %s\n", err.Error())
+	}
+	if current.Epoch != 1 || len(current.Shards) != 3 {
+		t.Fatalf("Unexpected configuration: %v\n", current)
+	}
+}
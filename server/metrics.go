@@ -0,0 +1,262 @@
+/******
+This file is part of Vaelen/DB.
+
+Copyright 2017, Andrew Young <andrew@vaelen.org>
+
+    Vaelen/DB is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+    Vaelen/DB is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+    You should have received a copy of the GNU General Public License
+along with Vaelen/DB.  If not, see <http://www.gnu.org/licenses/>.
+******/
+
+package server
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vaelen/db/api"
+	"google.golang.org/grpc/codes"
+)
+
+// LatencyBuckets are the upper bounds, in seconds, of the buckets of every latency histogram
+var LatencyBuckets = []float64{.0001, .0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// histogram counts observations by the latency bucket they fall in, the way a Prometheus histogram does
+type histogram struct {
+	sync.Mutex
+	// counts holds the number of observations in each bucket, the last one is for observations larger than every bound
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+func newHistogram() *histogram {
+	return &histogram{counts: make([]uint64, len(LatencyBuckets)+1)}
+}
+
+// observe records a latency
+func (h *histogram) observe(d time.Duration) {
+	seconds := d.Seconds()
+	i := sort.SearchFloat64s(LatencyBuckets, seconds)
+	h.Lock()
+	defer h.Unlock()
+	h.counts[i]++
+	h.count++
+	h.sum += seconds
+}
+
+// requestLabels identifies the requests a latency histogram is kept for
+type requestLabels struct {
+	method string
+	code   codes.Code
+}
+
+// requestMetrics holds the latency of the gRPC requests handled by a server
+type requestMetrics struct {
+	sync.Mutex
+	latency map[requestLabels]*histogram
+}
+
+func newRequestMetrics() *requestMetrics {
+	return &requestMetrics{latency: make(map[requestLabels]*histogram)}
+}
+
+// observe records a request to the given method that finished with the given code
+func (m *requestMetrics) observe(fullMethod string, code codes.Code, d time.Duration) {
+	labels := requestLabels{method: fullMethod[strings.LastIndex(fullMethod, "/")+1:], code: code}
+	m.Lock()
+	h, ok := m.latency[labels]
+	if !ok {
+		h = newHistogram()
+		m.latency[labels] = h
+	}
+	m.Unlock()
+	h.observe(d)
+}
+
+// metricsWriter writes metrics in the Prometheus text format
+type metricsWriter struct {
+	*bufio.Writer
+}
+
+// labelEscaper escapes label values the way the Prometheus text format requires
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// labels formats the given names and values as a label set
+func labels(pairs ...string) string {
+	if len(pairs) == 0 {
+		return ""
+	}
+	parts := make([]string, 0, len(pairs)/2)
+	for i := 0; i+1 < len(pairs); i += 2 {
+		parts = append(parts, pairs[i]+`="`+labelEscaper.Replace(pairs[i+1])+`"`)
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+// header writes the help and type of a metric
+func (w metricsWriter) header(name string, kind string, help string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+// sample writes one value of a metric
+func (w metricsWriter) sample(name string, labels string, value float64) {
+	fmt.Fprintf(w, "%s%s %s\n", name, labels, strconv.FormatFloat(value, 'g', -1, 64))
+}
+
+// metric writes a metric that has a single value
+func (w metricsWriter) metric(name string, kind string, help string, value float64) {
+	w.header(name, kind, help)
+	w.sample(name, "", value)
+}
+
+// histogram writes the buckets, sum and count of a histogram with the given labels
+func (w metricsWriter) histogram(name string, h *histogram, pairs ...string) {
+	h.Lock()
+	counts := append([]uint64{}, h.counts...)
+	count, sum := h.count, h.sum
+	h.Unlock()
+	cumulative := uint64(0)
+	for i, c := range counts {
+		cumulative += c
+		le := "+Inf"
+		if i < len(LatencyBuckets) {
+			le = strconv.FormatFloat(LatencyBuckets[i], 'g', -1, 64)
+		}
+		bucket := append(append([]string{}, pairs...), "le", le)
+		w.sample(name+"_bucket", labels(bucket...), float64(cumulative))
+	}
+	w.sample(name+"_sum", labels(pairs...), sum)
+	w.sample(name+"_count", labels(pairs...), float64(count))
+}
+
+// timestamp returns a time as seconds since the epoch, or zero for the zero time
+func timestamp(t time.Time) float64 {
+	if t.IsZero() {
+		return 0
+	}
+	return float64(t.UnixNano()) / 1e9
+}
+
+// WriteMetrics writes the server's metrics in the Prometheus text format
+func (s *DBServer) WriteMetrics(out io.Writer) error {
+	w := metricsWriter{bufio.NewWriter(out)}
+
+	// Requests
+	type request struct {
+		requestLabels
+		latency *histogram
+	}
+	s.metrics.Lock()
+	requests := make([]request, 0, len(s.metrics.latency))
+	for labels, h := range s.metrics.latency {
+		requests = append(requests, request{labels, h})
+	}
+	s.metrics.Unlock()
+	sort.Slice(requests, func(i, j int) bool {
+		if requests[i].method != requests[j].method {
+			return requests[i].method < requests[j].method
+		}
+		return requests[i].code < requests[j].code
+	})
+	w.header("vdb_requests_total", "counter", "Number of gRPC requests handled, by method and status code.")
+	for _, r := range requests {
+		r.latency.Lock()
+		count := r.latency.count
+		r.latency.Unlock()
+		w.sample("vdb_requests_total", labels("method", r.method, "code", r.code.String()), float64(count))
+	}
+	w.header("vdb_request_duration_seconds", "histogram", "Time taken to handle gRPC requests, by method and status code.")
+	for _, r := range requests {
+		w.histogram("vdb_request_duration_seconds", r.latency, "method", r.method, "code", r.code.String())
+	}
+
+	// Storage
+	w.metric("vdb_storage_queue_depth", "gauge", "Number of requests waiting for the storage thread.", float64(s.Storage.QueueDepth()))
+	usage := s.Storage.Namespaces()
+	names := make([]string, 0, len(usage))
+	for name := range usage {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	w.header("vdb_storage_keys", "gauge", "Number of live keys stored, by namespace.")
+	for _, name := range names {
+		w.sample("vdb_storage_keys", labels("namespace", name), float64(usage[name].Keys))
+	}
+	w.header("vdb_storage_nodes", "gauge", "Number of nodes in the storage tree, by namespace.")
+	for _, name := range names {
+		w.sample("vdb_storage_nodes", labels("namespace", name), float64(usage[name].Nodes))
+	}
+	w.header("vdb_storage_bytes", "gauge", "Size of the live keys and values as written, by namespace.")
+	for _, name := range names {
+		w.sample("vdb_storage_bytes", labels("namespace", name), float64(usage[name].Bytes))
+	}
+	w.header("vdb_storage_stored_bytes", "gauge", "Size of the live keys and values after compression, by namespace.")
+	for _, name := range names {
+		w.sample("vdb_storage_stored_bytes", labels("namespace", name), float64(usage[name].Stored))
+	}
+	definitions := s.Storage.IndexDefinitionWrites()
+	w.metric("vdb_index_definition_writes_total", "counter", "Number of times the index definitions were written to disk.", float64(definitions.Count))
+	w.metric("vdb_index_definition_write_seconds_total", "counter", "Time spent writing the index definitions to disk.", definitions.Total.Seconds())
+	w.metric("vdb_index_definition_last_write_seconds", "gauge", "Time taken by the last write of the index definitions.", definitions.Last.Seconds())
+	w.header("vdb_txn_log_write_seconds", "histogram", "Time taken to write and sync the transaction log.")
+	w.histogram("vdb_txn_log_write_seconds", s.Txns.writes)
+
+	// Cluster
+	epoch, shards := uint64(0), 0
+	if config := s.cluster(); config != nil {
+		epoch, shards = config.Epoch, len(config.Shards)
+	}
+	w.metric("vdb_cluster_epoch", "gauge", "Epoch of the cluster configuration.", float64(epoch))
+	w.metric("vdb_cluster_shards", "gauge", "Number of shards in the cluster configuration.", float64(shards))
+	states := make(map[api.MemberState]int)
+	for _, m := range s.MemberList() {
+		states[m.State]++
+	}
+	w.header("vdb_cluster_members", "gauge", "Number of cluster members known through gossip, by state.")
+	for _, state := range []api.MemberState{api.MemberState_ALIVE, api.MemberState_SUSPECT, api.MemberState_DEAD} {
+		w.sample("vdb_cluster_members", labels("state", strings.ToLower(state.String())), float64(states[state]))
+	}
+	w.metric("vdb_replication_factor", "gauge", "Number of replicas kept of each key in the default namespace.", float64(s.Replication.N))
+	hints := s.HintStats()
+	w.metric("vdb_hints_pending", "gauge", "Number of hints waiting to be replayed.", float64(hints.Pending))
+	w.metric("vdb_hints_stored_total", "counter", "Number of hints stored for replicas that were down.", float64(hints.Stored))
+	w.metric("vdb_hints_replayed_total", "counter", "Number of hints delivered to replicas.", float64(hints.Replayed))
+	w.metric("vdb_hints_expired_total", "counter", "Number of hints discarded because they were older than the hint window.", float64(hints.Expired))
+	w.metric("vdb_hints_dropped_total", "counter", "Number of hints discarded because the hint store was full.", float64(hints.Dropped))
+	w.metric("vdb_read_repairs_total", "counter", "Number of stale replicas repaired after a read.", float64(s.ReadRepairs()))
+	w.metric("vdb_follower_reads_total", "counter", "Number of reads answered from this node's own copy.", float64(s.FollowerReads()))
+	antiEntropy := s.AntiEntropyStats()
+	w.metric("vdb_anti_entropy_runs_total", "counter", "Number of completed anti-entropy runs.", float64(antiEntropy.Runs))
+	w.metric("vdb_anti_entropy_keys_repaired_total", "counter", "Number of keys repaired by anti-entropy.", float64(antiEntropy.TotalKeysRepaired))
+	w.metric("vdb_anti_entropy_last_run_duration_seconds", "gauge", "Time taken by the last anti-entropy run.", antiEntropy.LastRunDuration.Seconds())
+	w.metric("vdb_anti_entropy_last_sync_timestamp_seconds", "gauge", "Start of the last anti-entropy run that reached every shard, in seconds since the epoch.", timestamp(antiEntropy.LastSync))
+
+	return w.Flush()
+}
+
+// MetricsHandler returns an HTTP handler that serves the server's metrics in the Prometheus text format
+func (s *DBServer) MetricsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		if err := s.WriteMetrics(w); err != nil {
+			s.Logger.WarnContext(r.Context(), "Could not write metrics", "error", err)
+		}
+	})
+}
@@ -0,0 +1,110 @@
+/******
+This file is part of Vaelen/DB.
+
+Copyright 2017, Andrew Young <andrew@vaelen.org>
+
+    Vaelen/DB is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+    Vaelen/DB is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+    You should have received a copy of the GNU General Public License
+along with Vaelen/DB.  If not, see <http://www.gnu.org/licenses/>.
+******/
+
+package server
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/vaelen/db/api"
+
+	"golang.org/x/net/context"
+)
+
+// metricNames documents every metric served on /metrics along with its type.
+// Dashboards and alerts depend on these names, so they must not change.
+var metricNames = map[string]string{
+	"vdb_requests_total":                           "counter",
+	"vdb_request_duration_seconds":                 "histogram",
+	"vdb_storage_queue_depth":                      "gauge",
+	"vdb_storage_keys":                             "gauge",
+	"vdb_storage_nodes":                            "gauge",
+	"vdb_storage_bytes":                            "gauge",
+	"vdb_storage_stored_bytes":                     "gauge",
+	"vdb_index_definition_writes_total":            "counter",
+	"vdb_index_definition_write_seconds_total":     "counter",
+	"vdb_index_definition_last_write_seconds":      "gauge",
+	"vdb_txn_log_write_seconds":                    "histogram",
+	"vdb_cluster_epoch":                            "gauge",
+	"vdb_cluster_shards":                           "gauge",
+	"vdb_cluster_members":                          "gauge",
+	"vdb_replication_factor":                       "gauge",
+	"vdb_hints_pending":                            "gauge",
+	"vdb_hints_stored_total":                       "counter",
+	"vdb_hints_replayed_total":                     "counter",
+	"vdb_hints_expired_total":                      "counter",
+	"vdb_hints_dropped_total":                      "counter",
+	"vdb_read_repairs_total":                       "counter",
+	"vdb_follower_reads_total":                     "counter",
+	"vdb_anti_entropy_runs_total":                  "counter",
+	"vdb_anti_entropy_keys_repaired_total":         "counter",
+	"vdb_anti_entropy_last_run_duration_seconds":   "gauge",
+	"vdb_anti_entropy_last_sync_timestamp_seconds": "gauge",
+}
+
+// TestMetrics tests that every documented metric is served and that requests are counted
+func TestMetrics(t *testing.T) {
+	servers, stop := startCluster(t, 30340, 2)
+	defer stop()
+
+	if _, err := servers[0].Set(context.Background(), &api.IDValueRequest{ID: "a", Value: "1", Consistency: api.Consistency_ALL}); err != nil {
+		t.Fatalf("Set Error: %s\n", err.Error())
+	}
+
+	recorder := httptest.NewRecorder()
+	servers[1].MetricsHandler().ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	if !strings.HasPrefix(recorder.Header().Get("Content-Type"), "text/plain") {
+		t.Fatalf("Unexpected content type: %s\n", recorder.Header().Get("Content-Type"))
+	}
+	body := recorder.Body.String()
+	served := make(map[string]string)
+	for _, line := range strings.Split(body, "\n") {
+		if fields := strings.Fields(line); len(fields) == 4 && fields[1] == "TYPE" {
+			served[fields[2]] = fields[3]
+		}
+	}
+	for name, kind := range metricNames {
+		if served[name] != kind {
+			t.Errorf("Metric %s should be a %s, got %q\n", name, kind, served[name])
+		}
+	}
+	for name := range served {
+		if _, ok := metricNames[name]; !ok {
+			t.Errorf("Metric %s is not documented\n", name)
+		}
+	}
+
+	for _, sample := range []string{
+		`vdb_requests_total{method="ReplicaSet",code="OK"} 1`,
+		`vdb_request_duration_seconds_bucket{method="ReplicaSet",code="OK",le="+Inf"} 1`,
+		`vdb_request_duration_seconds_count{method="ReplicaSet",code="OK"} 1`,
+		`vdb_storage_keys{namespace=""} 1`,
+		`vdb_cluster_shards 2`,
+		`vdb_cluster_members{state="dead"} 0`,
+	} {
+		if !strings.Contains(body, sample+"\n") {
+			t.Errorf("Missing sample: %s\n", sample)
+		}
+	}
+	if t.Failed() {
+		t.Logf("Metrics:\n%s", body)
+	}
+}
@@ -0,0 +1,353 @@
+/******
+This file is part of Vaelen/DB.
+
+Copyright 2017, Andrew Young <andrew@vaelen.org>
+
+    Vaelen/DB is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+    Vaelen/DB is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+    You should have received a copy of the GNU General Public License
+along with Vaelen/DB.  If not, see <http://www.gnu.org/licenses/>.
+******/
+
+package server
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vaelen/db/api"
+	"github.com/vaelen/db/storage"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// namespacesKey holds the configuration of every namespace
+const namespacesKey = ReservedPrefix + "namespaces"
+
+var (
+	// NamespaceCacheTTL is how long namespace configurations are cached before they are read again
+	NamespaceCacheTTL = 5 * time.Second
+	// namespaceRefreshInterval limits how often an unknown namespace causes the configurations to be read again
+	namespaceRefreshInterval = time.Second
+)
+
+// NamespaceConfig holds the settings of a namespace.  Its keys are stored apart from every other namespace's keys.
+type NamespaceConfig struct {
+	// DefaultTTL is how long values are kept after they are written, or zero to keep them.
+	// The expiration is stored and replicated with each value, so it only applies to values written after it is set.
+	DefaultTTL time.Duration
+	// MaxKeys and MaxBytes limit how much each server stores for the namespace, zero is unlimited.
+	// Writes to a namespace with a quota go through a transaction, and each replica checks the quota against what it stores
+	// when it prepares the write.
+	MaxKeys  int64
+	MaxBytes int64
+	// Replicas is the number of replicas of each key, zero uses the server's replication factor
+	Replicas int
+}
+
+// namespaceCache holds the namespace configurations read from namespacesKey
+type namespaceCache struct {
+	sync.Mutex
+	configs map[string]NamespaceConfig
+	loaded  time.Time
+}
+
+// loadNamespaces reads the namespace configurations and caches them
+func (s *DBServer) loadNamespaces() (map[string]NamespaceConfig, error) {
+	configs := make(map[string]NamespaceConfig)
+	if _, err := s.readReserved(namespacesKey, &configs); err != nil {
+		return nil, err
+	}
+	s.Storage.SetQuotas(quotas(configs))
+	s.namespaces.Lock()
+	defer s.namespaces.Unlock()
+	s.namespaces.configs = configs
+	s.namespaces.loaded = time.Now()
+	return configs, nil
+}
+
+// namespaceConfigs returns the namespace configurations, reading them again once the cache has expired.
+// If refresh is true they are read again unless they were read very recently.
+// The cached configurations are used if they can not be read.
+func (s *DBServer) namespaceConfigs(refresh bool) (map[string]NamespaceConfig, error) {
+	s.namespaces.Lock()
+	configs, age := s.namespaces.configs, time.Since(s.namespaces.loaded)
+	s.namespaces.Unlock()
+	if configs != nil && (age < namespaceRefreshInterval || (!refresh && age < NamespaceCacheTTL)) {
+		return configs, nil
+	}
+	loaded, err := s.loadNamespaces()
+	if err != nil {
+		if configs != nil {
+			s.Logger.Warn("Could not read namespaces, using cached configuration", "error", err)
+			return configs, nil
+		}
+		return nil, err
+	}
+	return loaded, nil
+}
+
+// forgetNamespaces drops the cached namespace configurations
+func (s *DBServer) forgetNamespaces() {
+	s.namespaces.Lock()
+	defer s.namespaces.Unlock()
+	s.namespaces.configs = nil
+}
+
+// namespace returns the configuration of a namespace.  The default namespace always exists and has no settings.
+func (s *DBServer) namespace(name string) (NamespaceConfig, error) {
+	if name == "" {
+		return NamespaceConfig{}, nil
+	}
+	configs, err := s.namespaceConfigs(false)
+	if err != nil {
+		return NamespaceConfig{}, err
+	}
+	config, ok := configs[name]
+	if !ok {
+		// The namespace may have been created through another server since the configurations were read
+		if configs, err = s.namespaceConfigs(true); err != nil {
+			return NamespaceConfig{}, err
+		}
+		if config, ok = configs[name]; !ok {
+			return NamespaceConfig{}, status.Errorf(codes.NotFound, "unknown namespace: %s", name)
+		}
+	}
+	return config, nil
+}
+
+// key returns the internal key of a key in a namespace, checking that the namespace exists.
+// Keys in the default namespace cannot start with storage.NamespacePrefix, since they would be the keys of another namespace.
+func (s *DBServer) key(namespace string, id string) (string, error) {
+	if namespace == "" {
+		if strings.HasPrefix(id, storage.NamespacePrefix) {
+			return "", status.Errorf(codes.InvalidArgument, "keys in the default namespace cannot start with %s", storage.NamespacePrefix)
+		}
+		return id, nil
+	}
+	if _, err := s.namespace(namespace); err != nil {
+		return "", err
+	}
+	return storage.NamespaceKey(namespace, id), nil
+}
+
+// replicationFactor returns the number of replicas kept of a key
+func (s *DBServer) replicationFactor(key string) int {
+	namespace, _ := storage.SplitNamespace(key)
+	if namespace == "" {
+		return s.Replication.N
+	}
+	config, err := s.namespace(namespace)
+	if err != nil || config.Replicas <= 0 {
+		return s.Replication.N
+	}
+	return config.Replicas
+}
+
+// limited returns true if writes to a key have to be checked against its namespace's quota
+func (s *DBServer) limited(key string) (bool, error) {
+	namespace, _ := storage.SplitNamespace(key)
+	config, err := s.namespace(namespace)
+	if err != nil {
+		return false, err
+	}
+	return config.MaxKeys > 0 || config.MaxBytes > 0, nil
+}
+
+// refreshQuotas reads the namespace configurations again if they have expired from the cache, so that the quotas of the
+// namespaces the given versions are written to are current when this server checks them
+func (s *DBServer) refreshQuotas(versions []storage.NodeKeyValuePair) {
+	for _, v := range versions {
+		if namespace, _ := storage.SplitNamespace(v.Key); namespace != "" {
+			s.namespaceConfigs(false)
+			return
+		}
+	}
+}
+
+// quotas returns the storage quotas of the given namespace configurations
+func quotas(configs map[string]NamespaceConfig) map[string]storage.Quota {
+	quotas := make(map[string]storage.Quota)
+	for name, config := range configs {
+		if config.MaxKeys > 0 || config.MaxBytes > 0 {
+			quotas[name] = storage.Quota{MaxKeys: config.MaxKeys, MaxBytes: config.MaxBytes}
+		}
+	}
+	return quotas
+}
+
+// expiry returns when a value written to a key expires given the expiration sent with the write, which follows IDValueRequest.Expires.
+// It is zero if the value does not expire.
+func (s *DBServer) expiry(key string, expires int64) time.Time {
+	switch {
+	case expires > 0:
+		return FromExpires(expires)
+	case expires < 0:
+		return time.Time{}
+	}
+	namespace, _ := storage.SplitNamespace(key)
+	if namespace == "" {
+		return time.Time{}
+	}
+	config, err := s.namespace(namespace)
+	if err != nil || config.DefaultTTL <= 0 {
+		return time.Time{}
+	}
+	// Replicas compare expirations, so they are rounded to what the API can carry
+	return FromExpires(ToExpires(time.Now().Add(config.DefaultTTL)))
+}
+
+// dropNamespace discards this server's keys of a namespace
+func (s *DBServer) dropNamespace(ctx context.Context, name string) storage.Usage {
+	usage := s.Storage.DropNamespace(name)
+	s.Logger.InfoContext(ctx, "Namespace dropped", "namespace", name, "keys", usage.Keys, "bytes", usage.Bytes)
+	return usage
+}
+
+// dropRemovedNamespaces discards the keys this server still holds for namespaces that no longer exist
+func (s *DBServer) dropRemovedNamespaces() {
+	// The local namespaces are listed first so that a namespace created while the configurations are read is not dropped
+	local := s.Storage.Namespaces()
+	configs, err := s.loadNamespaces()
+	if err != nil {
+		s.Logger.Error("Could not read namespaces", "error", err)
+		return
+	}
+	for name := range local {
+		if _, ok := configs[name]; name != "" && !ok {
+			s.dropNamespace(context.Background(), name)
+		}
+	}
+}
+
+// namespaceNames returns the names of the namespaces whose keys are replicated, starting with the default namespace
+func (s *DBServer) namespaceNames() []string {
+	names := []string{""}
+	configs, err := s.namespaceConfigs(false)
+	if err != nil {
+		return names
+	}
+	for name := range configs {
+		names = append(names, name)
+	}
+	sort.Strings(names[1:])
+	return names
+}
+
+// toNamespaceInfo converts a namespace to its API representation
+func toNamespaceInfo(name string, config NamespaceConfig, usage storage.Usage) *api.NamespaceInfo {
+	return &api.NamespaceInfo{
+		Name:             name,
+		DefaultTtl:       int64(config.DefaultTTL / time.Millisecond),
+		MaxKeys:          config.MaxKeys,
+		MaxBytes:         config.MaxBytes,
+		Replicas:         uint32(config.Replicas),
+		Keys:             usage.Keys,
+		Bytes:            usage.Bytes,
+		StoredBytes:      usage.Stored,
+		Compressed:       usage.Compressed,
+		CompressionRatio: usage.Ratio(),
+	}
+}
+
+// CreateNamespace creates a namespace with the given settings.
+// The other shards are told to read the namespaces again so that the namespace can be used through them right away.
+func (s *DBServer) CreateNamespace(ctx context.Context, request *api.NamespaceRequest) (*api.NamespaceInfo, error) {
+	if request.Local {
+		s.forgetNamespaces()
+		return &api.NamespaceInfo{Name: request.Name}, nil
+	}
+	if !storage.ValidNamespace(request.Name) {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid namespace name: %q", request.Name)
+	}
+	if request.DefaultTtl < 0 || request.MaxKeys < 0 || request.MaxBytes < 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "namespace settings can not be negative")
+	}
+	config := NamespaceConfig{
+		DefaultTTL: time.Duration(request.DefaultTtl) * time.Millisecond,
+		MaxKeys:    request.MaxKeys,
+		MaxBytes:   request.MaxBytes,
+		Replicas:   int(request.Replicas),
+	}
+	configs := make(map[string]NamespaceConfig)
+	err := s.updateReserved(namespacesKey, &configs, func() error {
+		if _, ok := configs[request.Name]; ok {
+			return status.Errorf(codes.AlreadyExists, "namespace already exists: %s", request.Name)
+		}
+		configs[request.Name] = config
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.forgetNamespaces()
+	if s.replicated() {
+		// Shards that miss this read the namespaces again when they are first asked for the namespace
+		s.broadcast(ctx, codes.OK, func(ctx context.Context, c api.DatabaseClient) error {
+			_, err := c.CreateNamespace(ctx, &api.NamespaceRequest{Name: request.Name, Local: true})
+			return err
+		})
+	}
+	s.Logger.InfoContext(ctx, "Namespace created", "namespace", request.Name)
+	return toNamespaceInfo(request.Name, config, storage.Usage{}), nil
+}
+
+// DropNamespace deletes a namespace and discards its keys on every shard.
+// Each shard drops the namespace's storage tree as a whole instead of removing its keys one at a time.
+func (s *DBServer) DropNamespace(ctx context.Context, request *api.NamespaceRequest) (*api.NamespaceInfo, error) {
+	if !storage.ValidNamespace(request.Name) {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid namespace name: %q", request.Name)
+	}
+	var config NamespaceConfig
+	if !request.Local {
+		configs := make(map[string]NamespaceConfig)
+		err := s.updateReserved(namespacesKey, &configs, func() error {
+			config = configs[request.Name]
+			delete(configs, request.Name)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	s.forgetNamespaces()
+	usage := s.dropNamespace(ctx, request.Name)
+	if !request.Local && s.replicated() {
+		failed := s.broadcast(ctx, codes.OK, func(ctx context.Context, c api.DatabaseClient) error {
+			_, err := c.DropNamespace(ctx, &api.NamespaceRequest{Name: request.Name, Local: true})
+			return err
+		})
+		if failed > 0 {
+			// Anti-entropy drops the namespace on these shards once they can be reached
+			return nil, status.Errorf(codes.Unavailable, "namespace could not be dropped on %d shards", failed)
+		}
+	}
+	return toNamespaceInfo(request.Name, config, usage), nil
+}
+
+// ListNamespaces returns every namespace along with how much this server stores for it
+func (s *DBServer) ListNamespaces(ctx context.Context, request *api.EmptyRequest) (*api.NamespacesResponse, error) {
+	configs, err := s.namespaceConfigs(true)
+	if err != nil {
+		return nil, err
+	}
+	usage := s.Storage.Namespaces()
+	response := &api.NamespacesResponse{Namespaces: make([]*api.NamespaceInfo, 0, len(configs))}
+	for name, config := range configs {
+		response.Namespaces = append(response.Namespaces, toNamespaceInfo(name, config, usage[name]))
+	}
+	sort.Slice(response.Namespaces, func(i, j int) bool { return response.Namespaces[i].Name < response.Namespaces[j].Name })
+	return response, nil
+}
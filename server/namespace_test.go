@@ -0,0 +1,176 @@
+/******
+This file is part of Vaelen/DB.
+
+Copyright 2017, Andrew Young <andrew@vaelen.org>
+
+    Vaelen/DB is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+    Vaelen/DB is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+    You should have received a copy of the GNU General Public License
+along with Vaelen/DB.  If not, see <http://www.gnu.org/licenses/>.
+******/
+
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/vaelen/db/api"
+	"github.com/vaelen/db/storage"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// TestNamespaces tests that namespaces keep their keys apart and apply their settings across the cluster
+func TestNamespaces(t *testing.T) {
+	servers, stop := startCluster(t, 30320, 2)
+	defer stop()
+	ctx := context.Background()
+
+	if _, err := servers[0].CreateNamespace(ctx, &api.NamespaceRequest{Name: "team", MaxKeys: 2}); err != nil {
+		t.Fatalf("CreateNamespace Error: %s\n", err.Error())
+	}
+	if _, err := servers[1].CreateNamespace(ctx, &api.NamespaceRequest{Name: "team"}); status.Code(err) != codes.AlreadyExists {
+		t.Fatalf("Expected AlreadyExists, got %v\n", err)
+	}
+	if _, err := servers[0].CreateNamespace(ctx, &api.NamespaceRequest{Name: "a/b"}); status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("Expected InvalidArgument, got %v\n", err)
+	}
+
+	set := func(s *DBServer, namespace string, key string, value string) error {
+		_, err := s.Set(ctx, &api.IDValueRequest{Namespace: namespace, ID: key, Value: value, Consistency: api.Consistency_ALL})
+		return err
+	}
+	if err := set(servers[0], "team", "a", "1"); err != nil {
+		t.Fatalf("Set Error: %s\n", err.Error())
+	}
+	if err := set(servers[0], "", "a", "default"); err != nil {
+		t.Fatalf("Set Error: %s\n", err.Error())
+	}
+	// The default namespace cannot reach the keys of other namespaces
+	if err := set(servers[0], "", storage.NamespaceKey("team", "a"), "2"); status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("Expected InvalidArgument, got %v\n", err)
+	}
+	if _, err := servers[0].Get(ctx, &api.IDRequest{ID: storage.NamespaceKey("team", "a")}); status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("Expected InvalidArgument, got %v\n", err)
+	}
+	for i, s := range servers {
+		if v := s.Storage.Get(storage.NamespaceKey("team", "a")); v != "1" {
+			t.Errorf("Replica %d has value %q, expected 1\n", i, v)
+		}
+	}
+	response, err := servers[1].Get(ctx, &api.IDRequest{Namespace: "team", ID: "a"})
+	if err != nil || response.Value != "1" {
+		t.Fatalf("Unexpected Get response: %v, Error: %v\n", response, err)
+	}
+	if response, _ := servers[1].Get(ctx, &api.IDRequest{ID: "a"}); response.Value != "default" {
+		t.Fatalf("Namespaces are not isolated: %v\n", response)
+	}
+	if _, err := servers[1].Get(ctx, &api.IDRequest{Namespace: "missing", ID: "a"}); status.Code(err) != codes.NotFound {
+		t.Fatalf("Expected NotFound, got %v\n", err)
+	}
+
+	// The quota counts keys, so replacing a value is allowed once it is full
+	if err := set(servers[0], "team", "b", "2"); err != nil {
+		t.Fatalf("Set Error: %s\n", err.Error())
+	}
+	if err := set(servers[0], "team", "c", "3"); status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("Expected ResourceExhausted, got %v\n", err)
+	}
+	if err := set(servers[0], "team", "a", "4"); err != nil {
+		t.Fatalf("Set Error: %s\n", err.Error())
+	}
+	// The replicas check the quota, so no server can write past it
+	if err := set(servers[1], "team", "c", "3"); status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("Expected ResourceExhausted, got %v\n", err)
+	}
+	if _, err := servers[1].Txn(ctx, &api.TxnRequest{Namespace: "team", Ops: []*api.TxnOp{{ID: "d", Value: "5"}}}); status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("Expected ResourceExhausted, got %v\n", err)
+	}
+	for i, s := range servers {
+		if s.Storage.Get(storage.NamespaceKey("team", "c")) != "" || s.Storage.Get(storage.NamespaceKey("team", "d")) != "" {
+			t.Errorf("Replica %d stored a key over the quota\n", i)
+		}
+	}
+	namespaces, err := servers[1].ListNamespaces(ctx, &api.EmptyRequest{})
+	if err != nil || len(namespaces.Namespaces) != 1 || namespaces.Namespaces[0].Keys != 2 || namespaces.Namespaces[0].MaxKeys != 2 {
+		t.Fatalf("Unexpected namespaces: %v, Error: %v\n", namespaces, err)
+	}
+	if info := namespaces.Namespaces[0]; info.CompressionRatio != float64(info.Bytes)/float64(info.StoredBytes) {
+		t.Fatalf("Compression Ratio: %f, Bytes: %d, Stored: %d\n", info.CompressionRatio, info.Bytes, info.StoredBytes)
+	}
+
+	// A namespace can keep fewer replicas than the rest of the cluster
+	if _, err := servers[1].CreateNamespace(ctx, &api.NamespaceRequest{Name: "single", Replicas: 1}); err != nil {
+		t.Fatalf("CreateNamespace Error: %s\n", err.Error())
+	}
+	if err := set(servers[0], "single", "x", "y"); err != nil {
+		t.Fatalf("Set Error: %s\n", err.Error())
+	}
+	copies := 0
+	for _, s := range servers {
+		if s.Storage.Get(storage.NamespaceKey("single", "x")) == "y" {
+			copies++
+		}
+	}
+	if copies != 1 {
+		t.Errorf("Copies: %d, Expected: 1\n", copies)
+	}
+
+	// Values in a namespace with a default TTL expire
+	if _, err := servers[0].CreateNamespace(ctx, &api.NamespaceRequest{Name: "short", DefaultTtl: 100}); err != nil {
+		t.Fatalf("CreateNamespace Error: %s\n", err.Error())
+	}
+	if err := set(servers[0], "short", "x", "y"); err != nil {
+		t.Fatalf("Set Error: %s\n", err.Error())
+	}
+	// The expiration is stored with the value on every replica
+	for i, s := range servers {
+		versions := s.Storage.GetVersions(storage.NamespaceKey("short", "x"))
+		if len(versions) != 1 || versions[0].Expires.IsZero() {
+			t.Fatalf("Replica %d does not have the expiration: %v\n", i, versions)
+		}
+	}
+	if response, _ := servers[1].Get(ctx, &api.IDRequest{Namespace: "short", ID: "x"}); response.Expires == 0 {
+		t.Fatalf("Expiration was not returned: %v\n", response)
+	}
+	time.Sleep(200 * time.Millisecond)
+	if response, _ := servers[0].Get(ctx, &api.IDRequest{Namespace: "short", ID: "x"}); response.Value != "" {
+		t.Fatalf("Value did not expire: %v\n", response)
+	}
+
+	if _, err := servers[1].DropNamespace(ctx, &api.NamespaceRequest{Name: "team"}); err != nil {
+		t.Fatalf("DropNamespace Error: %s\n", err.Error())
+	}
+	for i, s := range servers {
+		if _, ok := s.Storage.Namespaces()["team"]; ok {
+			t.Errorf("Replica %d still has the dropped namespace\n", i)
+		}
+	}
+	if _, err := servers[0].Get(ctx, &api.IDRequest{Namespace: "team", ID: "a"}); status.Code(err) != codes.NotFound {
+		t.Fatalf("Expected NotFound, got %v\n", err)
+	}
+	if response, _ := servers[0].Get(ctx, &api.IDRequest{ID: "a"}); response.Value != "default" {
+		t.Fatalf("Default namespace was affected by the drop: %v\n", response)
+	}
+
+	// Anti-entropy drops namespaces that a server missed being told about
+	servers[1].Storage.Set(storage.NamespaceKey("gone", "x"), "y")
+	servers[1].AntiEntropy()
+	if _, ok := servers[1].Storage.Namespaces()["gone"]; ok {
+		t.Errorf("Anti-entropy did not drop the removed namespace\n")
+	}
+	if servers[0].Storage.Get(storage.NamespaceKey("single", "x"))+servers[1].Storage.Get(storage.NamespaceKey("single", "x")) != "y" {
+		t.Errorf("Anti-entropy copied a key to a server that is not its replica\n")
+	}
+}
@@ -20,65 +20,303 @@ along with Vaelen/DB.  If not, see <http://www.gnu.org/licenses/>.
 package server
 
 import (
-	"io"
-	"log"
+	"log/slog"
+	"sort"
+	"sync"
 	"time"
 
-	"github.com/vaelen/db/storage"
 	"github.com/vaelen/db/api"
+	"github.com/vaelen/db/cluster"
+	"github.com/vaelen/db/logging"
+	"github.com/vaelen/db/storage"
+	"github.com/vaelen/db/trace"
 
 	"golang.org/x/net/context"
+	// Registering the gzip compressor lets clients compress requests, responses are compressed the same way
+	_ "google.golang.org/grpc/encoding/gzip"
 )
 
 // DBServer is an instance of the database server
 type DBServer struct {
-	Logger        *log.Logger
-	Storage       *storage.Instance
-	logWriter     io.Writer
+	Logger  *slog.Logger
+	Storage *storage.Instance
+	// Keyring encrypts the files the server writes.  They are not encrypted when it is nil.
+	Keyring *storage.Keyring
+	// Self is the shard this server represents in the cluster
+	Self cluster.Shard
+	// Cluster is the cluster this server belongs to. Requests are only handled locally when it is nil.
+	// It should only be set directly before the server starts, use SetClusterConfig afterwards.
+	Cluster     *cluster.Config
+	clusterLock sync.RWMutex
+	// ClusterCache keeps the cluster configuration on disk across restarts
+	ClusterCache *ClusterCache
+	// clusterChanged is closed and replaced whenever the cluster configuration changes
+	clusterChanged chan bool
+	// currentPlacement is the placement built for the configuration in placementFor
+	currentPlacement cluster.Placement
+	placementFor     *cluster.Config
+	// Replication controls how keys are replicated across the cluster
+	Replication ReplicationConfig
+	// Hints holds writes for replicas that could not be reached
+	Hints *HintStore
+	// Clock is the hybrid logical clock used to stamp writes
+	Clock *HLC
+	// Txns records transaction decisions and prepared intents
+	Txns *TxnLog
+	// Gossip controls how cluster members are probed
+	Gossip GossipConfig
+	// Auth authenticates callers and checks their permissions.  Every caller may do anything when it is nil.
+	Auth *Auth
+	// Tracer records spans for requests handled by the server and continues the traces of the callers that send one.  Nothing is traced when it is nil.
+	Tracer *trace.Tracer
+	// Audit records the changes made by clients.  Nothing is recorded when it is nil.
+	Audit *AuditLog
+	// SlowThreshold is how long a request may take before it is recorded in the slow operation log, zero disables the log
+	SlowThreshold time.Duration
+	// reserved serializes changes to reserved keys made through this server
+	reserved sync.Mutex
+	// rotation tracks the key rotation running in the background, so that a rotation never retires a key another one is still writing with
+	rotation      keyRotation
+	namespaces    *namespaceCache
+	limits        *limiter
+	metrics       *requestMetrics
+	slowOps       *slowLog
+	readiness     *readiness
+	membership    *membership
+	peers         *peerPool
+	antiEntropy   *antiEntropy
+	handoff       *handoff
+	txnRecovery   *txnRecovery
+	locks         *keyLocks
+	readRepairs   uint64
+	followerReads uint64
+	// logs builds the loggers of the server's components and changes their levels
+	logs *logging.Root
+}
+
+// New creates a new instance of the database server.
+// Each component of the server logs through its own logger built from logs.
+func New(logs *logging.Root, dbPath string) *DBServer {
+	return NewEncrypted(logs, dbPath, nil)
+}
+
+// NewEncrypted creates a new instance of the database server whose files are encrypted with the given keyring
+func NewEncrypted(logs *logging.Root, dbPath string, keyring *storage.Keyring) *DBServer {
+	logger := logs.Logger("network")
+	s := &DBServer{
+		Logger:         logger,
+		Storage:        storage.NewEncrypted(logs.Logger("storage"), dbPath, keyring),
+		Keyring:        keyring,
+		ClusterCache:   NewClusterCache(logs.Logger("cluster"), dbPath, keyring),
+		clusterChanged: make(chan bool),
+		Replication:    DefaultReplication,
+		Hints:          NewHintStore(logs.Logger("hints"), dbPath, keyring, DefaultHintWindow, DefaultMaxHints),
+		Txns:           NewTxnLog(logs.Logger("txns"), dbPath, keyring),
+		Clock:          NewHLC(DefaultMaxClockOffset),
+		Gossip:         DefaultGossip,
+		membership:     newMembership(),
+		antiEntropy:    &antiEntropy{},
+		handoff:        &handoff{},
+		txnRecovery:    &txnRecovery{},
+		locks:          newKeyLocks(),
+		namespaces:     &namespaceCache{},
+		limits:         newLimiter(),
+		metrics:        newRequestMetrics(),
+		SlowThreshold:  DefaultSlowThreshold,
+		slowOps:        newSlowLog(DefaultMaxSlowOperations),
+		readiness:      newReadiness(),
+		logs:           logs,
+	}
+	s.peers = newPeerPool(s.peerInterceptors())
+	config, err := s.ClusterCache.Load()
+	if err != nil {
+		logger.Error("Could not load cluster configuration", "error", err)
+	} else if config != nil {
+		s.Cluster = config
+		logger.Info("Cluster configuration loaded", "epoch", config.Epoch, "shards", len(config.Shards))
+	}
+	go s.load()
+	return s
+}
+
+// cluster returns the current cluster configuration
+func (s *DBServer) cluster() *cluster.Config {
+	s.clusterLock.RLock()
+	defer s.clusterLock.RUnlock()
+	return s.Cluster
 }
 
-// New creates a new instance of the database server
-func New(logWriter io.Writer, dbPath string) *DBServer {
-	return &DBServer{
-		Logger:        log.New(logWriter, "[NETWORK] ", log.LstdFlags),
-		Storage:       storage.New(logWriter, dbPath),
-		logWriter:     logWriter,
+// SetClusterConfig replaces the cluster configuration if the given configuration has a newer epoch.
+// It returns true if the configuration was replaced.
+func (s *DBServer) SetClusterConfig(config *cluster.Config) bool {
+	s.clusterLock.Lock()
+	defer s.clusterLock.Unlock()
+	if s.Cluster != nil && config.Epoch <= s.Cluster.Epoch {
+		return false
 	}
+	s.setClusterConfig(config)
+	return true
 }
 
 // Stop shuts down the database server
 func (s *DBServer) Stop() {
+	s.readiness.health.Shutdown()
+	s.stopGossip()
+	s.stopAntiEntropy()
+	s.stopHintedHandoff()
+	s.Hints.Flush()
+	s.stopTxnRecovery()
+	s.peers.Close()
 	if s.Storage != nil {
 		s.Storage.Shutdown <- true
 	}
 }
 
-// Time returns the current time
-func (s *DBServer) Time(ctx context.Context, request *api.EmptyRequest) (*api.Response, error) {
-	return &api.Response{
-		Value: time.Now().Format(time.RFC3339),
-	}, nil
-}
-
 // Get returns a value for a given key
 func (s *DBServer) Get(ctx context.Context, request *api.IDRequest) (*api.Response, error) {
+	if err := s.checkEpoch(request.Epoch); err != nil {
+		return nil, err
+	}
+	key, err := s.key(request.Namespace, request.ID)
+	if err != nil {
+		return nil, err
+	}
+	if s.replicated() {
+		if response, ok := s.followerRead(ctx, key, request.MaxStaleness); ok {
+			return response, nil
+		}
+		versions, err := s.quorumGet(ctx, key, request.Consistency)
+		if err != nil {
+			return nil, err
+		}
+		return versionedResponse(versions), nil
+	}
+	defer s.storageSpan(ctx, "Get", key).Finish()
+	result := s.localStorage(ctx).Lookup(key)
 	return &api.Response{
-		Value: s.Storage.Get(request.ID),
+		Value:   result.Value,
+		Context: ToVersionVector(result.Version),
+		Expires: ToExpires(result.Expires),
+		Flags:   result.Flags,
 	}, nil
 }
 
 // Set sets a value for a given key
 func (s *DBServer) Set(ctx context.Context, request *api.IDValueRequest) (*api.Response, error) {
+	if err := s.checkEpoch(request.Epoch); err != nil {
+		return nil, err
+	}
+	key, err := s.key(request.Namespace, request.ID)
+	if err != nil {
+		return nil, err
+	}
+	limited, err := s.limited(key)
+	if err != nil {
+		return nil, err
+	}
+	expires := s.expiry(key, request.Expires)
+	if limited || s.replicated() {
+		pair := storage.NodeKeyValuePair{Key: key, Value: request.Value, Expires: expires, Flags: request.Flags}
+		if limited {
+			err = s.limitedWrite(ctx, pair, request.Consistency, request.Context)
+		} else {
+			_, err = s.replicatedWrite(ctx, pair, request.Consistency, request.Context)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return &api.Response{
+			Value:   request.Value,
+			Expires: ToExpires(expires),
+			Flags:   request.Flags,
+		}, nil
+	}
+	defer s.storageSpan(ctx, "Set", key).Finish()
 	return &api.Response{
-		Value: s.Storage.Set(request.ID, request.Value),
+		Value:   s.localStorage(ctx).SetItem(key, request.Value, request.Flags, expires),
+		Expires: ToExpires(expires),
+		Flags:   request.Flags,
 	}, nil
 }
 
 // Remove removes a given key
 func (s *DBServer) Remove(ctx context.Context, request *api.IDRequest) (*api.Response, error) {
+	if err := s.checkEpoch(request.Epoch); err != nil {
+		return nil, err
+	}
+	key, err := s.key(request.Namespace, request.ID)
+	if err != nil {
+		return nil, err
+	}
+	if s.replicated() {
+		previous, err := s.replicatedWrite(ctx, storage.NodeKeyValuePair{Key: key, Deleted: true}, request.Consistency, nil)
+		if err != nil {
+			return nil, err
+		}
+		return versionedResponse(previous), nil
+	}
+	defer s.storageSpan(ctx, "Remove", key).Finish()
 	return &api.Response{
-		Value: s.Storage.Remove(request.ID),
+		Value: s.localStorage(ctx).Remove(key),
 	}, nil
 }
 
+// GetMany returns the values of the given keys, reading them in parallel
+func (s *DBServer) GetMany(ctx context.Context, request *api.GetManyRequest) (*api.GetManyResponse, error) {
+	if err := s.checkEpoch(request.Epoch); err != nil {
+		return nil, err
+	}
+	response := &api.GetManyResponse{Responses: make([]*api.Response, len(request.IDs))}
+	err := parallel(len(request.IDs), func(i int) error {
+		var err error
+		response.Responses[i], err = s.Get(ctx, &api.IDRequest{ID: request.IDs[i], Namespace: request.Namespace, Consistency: request.Consistency, MaxStaleness: request.MaxStaleness})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// SetMany sets the given values, writing them in parallel
+func (s *DBServer) SetMany(ctx context.Context, request *api.SetManyRequest) (*api.Response, error) {
+	if err := s.checkEpoch(request.Epoch); err != nil {
+		return nil, err
+	}
+	ids := sortedIDs(request.Values)
+	err := parallel(len(ids), func(i int) error {
+		_, err := s.Set(ctx, &api.IDValueRequest{ID: ids[i], Namespace: request.Namespace, Value: request.Values[ids[i]], Consistency: request.Consistency})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &api.Response{}, nil
+}
 
+// parallel calls f for every index up to n at the same time and returns the first error
+func parallel(n int, f func(i int) error) error {
+	errors := make(chan error, n)
+	for i := 0; i < n; i++ {
+		go func(i int) {
+			errors <- f(i)
+		}(i)
+	}
+	var first error
+	for i := 0; i < n; i++ {
+		if err := <-errors; err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// sortedIDs returns the IDs of the given values in order
+func sortedIDs(values map[string]string) []string {
+	ids := make([]string, 0, len(values))
+	for id := range values {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
@@ -0,0 +1,103 @@
+/******
+This file is part of Vaelen/DB.
+
+Copyright 2017, Andrew Young <andrew@vaelen.org>
+
+    Vaelen/DB is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+    Vaelen/DB is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+    You should have received a copy of the GNU General Public License
+along with Vaelen/DB.  If not, see <http://www.gnu.org/licenses/>.
+******/
+
+package server
+
+import (
+	"fmt"
+
+	"github.com/vaelen/db/api"
+	"github.com/vaelen/db/cluster"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// DefaultLoadSample is the number of generated keys PlacementLoad places when it is not given any
+const DefaultLoadSample = 10000
+
+// placement returns the placement for the current cluster configuration, building it when the configuration changes
+func (s *DBServer) placement() cluster.Placement {
+	s.clusterLock.RLock()
+	if s.placementFor == s.Cluster && s.currentPlacement != nil {
+		p := s.currentPlacement
+		s.clusterLock.RUnlock()
+		return p
+	}
+	s.clusterLock.RUnlock()
+
+	s.clusterLock.Lock()
+	defer s.clusterLock.Unlock()
+	p, err := cluster.NewPlacement(s.Cluster)
+	if err != nil {
+		s.Logger.Warn("Falling back to chunk placement", "error", err)
+		p = cluster.NewChunkPlacement(s.Cluster)
+	}
+	s.currentPlacement = p
+	s.placementFor = s.Cluster
+	return p
+}
+
+// replicas returns the preference list for a key under the current cluster configuration
+func (s *DBServer) replicas(key string) []cluster.Shard {
+	return s.placement().Replicas(key, s.replicationFactor(key))
+}
+
+// PlacementLoad reports how evenly a placement spreads a sample of keys over the shards of the cluster.
+// Another strategy can be measured before the cluster configuration is changed to use it.
+func (s *DBServer) PlacementLoad(ctx context.Context, request *api.PlacementLoadRequest) (*api.PlacementLoadResponse, error) {
+	config := s.cluster()
+	if config == nil {
+		return nil, status.Errorf(codes.FailedPrecondition, "the server is not part of a cluster")
+	}
+	p := s.placement()
+	if request.Placement != "" {
+		candidate := *config
+		candidate.Strategy = request.Placement
+		candidate.VirtualNodes = int(request.VirtualNodes)
+		var err error
+		if p, err = cluster.NewPlacement(&candidate); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "%s", err.Error())
+		}
+	}
+	keys := request.Keys
+	if len(keys) == 0 {
+		sample := int(request.Sample)
+		if sample <= 0 {
+			sample = DefaultLoadSample
+		}
+		keys = make([]string, sample)
+		for i := range keys {
+			keys[i] = fmt.Sprintf("sample-%d", i)
+		}
+	}
+	report := cluster.MeasureLoad(p, config.Shards, keys)
+	response := &api.PlacementLoadResponse{
+		Placement: p.Strategy(),
+		Keys:      uint64(report.Keys),
+		Counts:    make(map[string]uint64),
+		Skew:      report.Skew,
+		StdDev:    report.StdDev,
+	}
+	for id, count := range report.Counts {
+		response.Counts[id] = uint64(count)
+	}
+	return response, nil
+}
@@ -0,0 +1,109 @@
+/******
+This file is part of Vaelen/DB.
+
+Copyright 2017, Andrew Young <andrew@vaelen.org>
+
+    Vaelen/DB is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+    Vaelen/DB is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+    You should have received a copy of the GNU General Public License
+along with Vaelen/DB.  If not, see <http://www.gnu.org/licenses/>.
+******/
+
+package server
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/satori/go.uuid"
+	"github.com/vaelen/db/api"
+	"github.com/vaelen/db/cluster"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// TestRingReplication tests quorum reads and writes on a cluster using ring placement
+func TestRingReplication(t *testing.T) {
+	config := newCluster(30170, 4)
+	config.Strategy = cluster.RingStrategy
+	servers := make([]*DBServer, 0)
+	for _, shard := range config.Shards {
+		s, stop := startServer(t, config, shard)
+		defer stop()
+		servers = append(servers, s)
+	}
+
+	ctx := context.Background()
+	for i := 0; i < 20; i++ {
+		key := fmt.Sprintf("key-%d", i)
+		if _, err := servers[0].Set(ctx, &api.IDValueRequest{ID: key, Value: key, Consistency: api.Consistency_ALL}); err != nil {
+			t.Fatalf("Set Error: %s\n", err.Error())
+		}
+		replicas := servers[0].replicas(key)
+		if len(replicas) != 3 {
+			t.Fatalf("Key: %s, Replicas: %d, Expected: 3\n", key, len(replicas))
+		}
+		for _, s := range servers {
+			held := s.Storage.Get(key) == key
+			expected := false
+			for _, r := range replicas {
+				expected = expected || uuid.Equal(r.ID, s.Self.ID)
+			}
+			if held != expected {
+				t.Errorf("Key: %s, Shard: %s, Holds: %t, Expected: %t\n", key, s.Self.ID, held, expected)
+			}
+		}
+		response, err := servers[3].Get(ctx, &api.IDRequest{ID: key})
+		if err != nil {
+			t.Fatalf("Get Error: %s\n", err.Error())
+		}
+		if response.Value != key {
+			t.Errorf("Key: %s, Value: %s\n", key, response.Value)
+		}
+	}
+}
+
+// TestPlacementLoad tests measuring the current placement and a candidate one through the API
+func TestPlacementLoad(t *testing.T) {
+	s := New(testLogs, "")
+	defer s.Stop()
+	ctx := context.Background()
+	if _, err := s.PlacementLoad(ctx, &api.PlacementLoadRequest{}); status.Code(err) != codes.FailedPrecondition {
+		t.Fatalf("Expected FailedPrecondition without a cluster, got %v\n", err)
+	}
+	s.Cluster = newCluster(0, 5)
+
+	response, err := s.PlacementLoad(ctx, &api.PlacementLoadRequest{Keys: []string{"a", "b", "c"}})
+	if err != nil {
+		t.Fatalf("PlacementLoad Error: %s\n", err.Error())
+	}
+	total := uint64(0)
+	for _, count := range response.Counts {
+		total += count
+	}
+	if response.Placement != cluster.ChunkStrategy || response.Keys != 3 || total != 3 || len(response.Counts) != 5 {
+		t.Fatalf("Unexpected response: %v\n", response)
+	}
+
+	response, err = s.PlacementLoad(ctx, &api.PlacementLoadRequest{Placement: cluster.RingStrategy, VirtualNodes: 256})
+	if err != nil {
+		t.Fatalf("PlacementLoad Error: %s\n", err.Error())
+	}
+	if response.Placement != cluster.RingStrategy || response.Keys != uint64(DefaultLoadSample) || response.Skew < 1 || response.Skew > 1.25 {
+		t.Fatalf("Unexpected response: %v\n", response)
+	}
+
+	if _, err := s.PlacementLoad(ctx, &api.PlacementLoadRequest{Placement: "unknown"}); status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("Expected InvalidArgument for an unknown strategy, got %v\n", err)
+	}
+}
@@ -0,0 +1,501 @@
+/******
+This file is part of Vaelen/DB.
+
+Copyright 2017, Andrew Young <andrew@vaelen.org>
+
+    Vaelen/DB is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+    Vaelen/DB is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+    You should have received a copy of the GNU General Public License
+along with Vaelen/DB.  If not, see <http://www.gnu.org/licenses/>.
+******/
+
+package server
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/satori/go.uuid"
+	"github.com/vaelen/db/api"
+	"github.com/vaelen/db/cluster"
+	"github.com/vaelen/db/storage"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ReplicationConfig controls how many replicas hold each key and how many must respond to a request
+type ReplicationConfig struct {
+	// N is the number of replicas each key is written to
+	N int
+	// R is the number of replicas that must respond to a read when no consistency is requested
+	R int
+	// W is the number of replicas that must acknowledge a write when no consistency is requested
+	W int
+	// Timeout is how long to wait for replicas to respond
+	Timeout time.Duration
+	// ReadRepair controls whether stale replicas found during reads are repaired
+	ReadRepair bool
+}
+
+// DefaultReplication is the replication configuration used by new servers
+var DefaultReplication = ReplicationConfig{
+	N:          3,
+	R:          2,
+	W:          2,
+	Timeout:    5 * time.Second,
+	ReadRepair: true,
+}
+
+// required returns the number of replies needed for the given consistency level
+func (r ReplicationConfig) required(consistency api.Consistency, defaultRequired int, replicas int) int {
+	required := defaultRequired
+	switch consistency {
+	case api.Consistency_ONE:
+		required = 1
+	case api.Consistency_QUORUM:
+		required = replicas/2 + 1
+	case api.Consistency_ALL:
+		required = replicas
+	}
+	if required > replicas {
+		required = replicas
+	}
+	if required < 1 {
+		required = 1
+	}
+	return required
+}
+
+// peerPool keeps one connection open to each address in the cluster
+type peerPool struct {
+	sync.Mutex
+	conns   map[string]*grpc.ClientConn
+	options []grpc.DialOption
+}
+
+func newPeerPool(options ...grpc.DialOption) *peerPool {
+	return &peerPool{
+		conns:   make(map[string]*grpc.ClientConn),
+		options: append([]grpc.DialOption{grpc.WithInsecure()}, options...),
+	}
+}
+
+// client returns a client for the given shard, connecting if necessary
+func (p *peerPool) client(shard cluster.Shard) (api.DatabaseClient, error) {
+	return p.dial(shard.Address)
+}
+
+// dial returns a client for the given address, connecting if necessary
+func (p *peerPool) dial(address string) (api.DatabaseClient, error) {
+	p.Lock()
+	defer p.Unlock()
+	conn, ok := p.conns[address]
+	if !ok {
+		var err error
+		conn, err = grpc.Dial(address, p.options...)
+		if err != nil {
+			return nil, err
+		}
+		p.conns[address] = conn
+	}
+	return api.NewDatabaseClient(conn), nil
+}
+
+// Close closes all peer connections
+func (p *peerPool) Close() {
+	p.Lock()
+	defer p.Unlock()
+	for address, conn := range p.conns {
+		conn.Close()
+		delete(p.conns, address)
+	}
+}
+
+// replicaResponse holds the reply from a single replica
+type replicaResponse struct {
+	Shard    cluster.Shard
+	Versions []storage.NodeKeyValuePair
+	Err      error
+}
+
+// replicated returns true if requests should be coordinated across the cluster
+func (s *DBServer) replicated() bool {
+	config := s.cluster()
+	return config != nil && len(config.Shards) > 0
+}
+
+func (s *DBServer) isSelf(shard cluster.Shard) bool {
+	return uuid.Equal(shard.ID, s.Self.ID)
+}
+
+// replicaGet reads every version of a key from a single replica
+func (s *DBServer) replicaGet(ctx context.Context, shard cluster.Shard, key string) ([]storage.NodeKeyValuePair, error) {
+	if s.isSelf(shard) {
+		defer s.storageSpan(ctx, "GetVersions", key).Finish()
+		return s.localStorage(ctx).GetVersions(key), nil
+	}
+	if s.isDead(shard) {
+		return nil, errMemberDead
+	}
+	c, err := s.peers.client(shard)
+	if err != nil {
+		return nil, err
+	}
+	response, err := c.ReplicaGet(ctx, &api.IDRequest{ID: key})
+	if err != nil {
+		return nil, err
+	}
+	return FromSiblings(key, response.Siblings), nil
+}
+
+// replicaScan lists a page of the keys stored on a single replica
+func (s *DBServer) replicaScan(ctx context.Context, shard cluster.Shard, namespace string, cursor uint32, count int) ([]string, uint32, error) {
+	if s.isSelf(shard) {
+		keys, next := s.Storage.ScanNamespace(namespace, cursor, count)
+		return keys, next, nil
+	}
+	if s.isDead(shard) {
+		return nil, 0, errMemberDead
+	}
+	c, err := s.peers.client(shard)
+	if err != nil {
+		return nil, 0, err
+	}
+	response, err := c.ReplicaScan(ctx, &api.ScanRequest{Namespace: namespace, Cursor: cursor, Count: uint32(count)})
+	if err != nil {
+		return nil, 0, err
+	}
+	return response.IDs, response.Next, nil
+}
+
+// scanShards returns the shards whose keys make up the whole database
+func (s *DBServer) scanShards() []cluster.Shard {
+	if !s.replicated() {
+		return []cluster.Shard{s.Self}
+	}
+	return s.cluster().Shards
+}
+
+// scannedFrom returns true if a key found on the given shard should be listed by a scan of every shard.
+// Each key is listed by the first of its replicas that is alive, so that it is listed once.
+func (s *DBServer) scannedFrom(shard cluster.Shard, key string) bool {
+	if !s.replicated() {
+		return true
+	}
+	for _, replica := range s.replicas(key) {
+		if s.isDead(replica) {
+			continue
+		}
+		return uuid.Equal(replica.ID, shard.ID)
+	}
+	return false
+}
+
+// replicaSet writes versions of a key to a single replica
+func (s *DBServer) replicaSet(ctx context.Context, shard cluster.Shard, key string, versions []storage.NodeKeyValuePair) ([]storage.NodeKeyValuePair, error) {
+	if s.isSelf(shard) {
+		defer s.storageSpan(ctx, "PutVersions", key).Finish()
+		return s.localStorage(ctx).PutVersions(key, versions), nil
+	}
+	if s.isDead(shard) {
+		return nil, errMemberDead
+	}
+	c, err := s.peers.client(shard)
+	if err != nil {
+		return nil, err
+	}
+	response, err := c.ReplicaSet(ctx, &api.VersionedRequest{ID: key, Siblings: ToSiblings(versions)})
+	if err != nil {
+		return nil, err
+	}
+	return FromSiblings(key, response.Siblings), nil
+}
+
+// quorumGet reads a key from its replicas and returns the reconciled versions once enough replicas have replied.
+// Replicas that returned stale versions are repaired in the background once every replica has replied.
+func (s *DBServer) quorumGet(ctx context.Context, key string, consistency api.Consistency) ([]storage.NodeKeyValuePair, error) {
+	replicas := s.replicas(key)
+	required := s.Replication.required(consistency, s.Replication.R, len(replicas))
+
+	// Requests are not tied to the caller's context so that slow replicas still finish, they only stay part of its trace
+	ctx, cancel := context.WithTimeout(detach(ctx), s.Replication.Timeout)
+	responses := make(chan replicaResponse, len(replicas))
+	for _, shard := range replicas {
+		go func(shard cluster.Shard) {
+			versions, err := s.replicaGet(ctx, shard, key)
+			responses <- replicaResponse{Shard: shard, Versions: versions, Err: err}
+		}(shard)
+	}
+
+	merged := make([]storage.NodeKeyValuePair, 0)
+	replied := make([]replicaResponse, 0, len(replicas))
+	received := 0
+	for ; received < len(replicas) && len(replied) < required; received++ {
+		response := <-responses
+		if response.Err != nil {
+			s.Logger.WarnContext(ctx, "Replica read failed", "shard", response.Shard.ID.String(), "key", key, "error", response.Err)
+			continue
+		}
+		replied = append(replied, response)
+		for _, v := range response.Versions {
+			merged, _ = storage.Reconcile(merged, v)
+		}
+	}
+	succeeded := len(replied)
+
+	go func(received int, replied []replicaResponse) {
+		defer cancel()
+		// Wait for the remaining replicas to answer
+		for ; received < len(replicas); received++ {
+			response := <-responses
+			if response.Err == nil {
+				replied = append(replied, response)
+			}
+		}
+		if s.Replication.ReadRepair {
+			s.readRepair(ctx, key, replied)
+		}
+	}(received, replied)
+
+	if succeeded < required {
+		return nil, status.Errorf(codes.Unavailable, "only %d of %d required replicas responded", succeeded, required)
+	}
+	return merged, nil
+}
+
+// readVersions reads every version of a key, from its replicas when the server is replicated
+func (s *DBServer) readVersions(ctx context.Context, key string, consistency api.Consistency) ([]storage.NodeKeyValuePair, error) {
+	if s.replicated() {
+		return s.quorumGet(ctx, key, consistency)
+	}
+	defer s.storageSpan(ctx, "GetVersions", key).Finish()
+	return s.localStorage(ctx).GetVersions(key), nil
+}
+
+// readRepair writes the reconciled versions of a key to every replica that returned something different
+func (s *DBServer) readRepair(ctx context.Context, key string, replied []replicaResponse) {
+	merged := make([]storage.NodeKeyValuePair, 0)
+	for _, response := range replied {
+		for _, v := range response.Versions {
+			merged, _ = storage.Reconcile(merged, v)
+		}
+	}
+	digest := storage.DigestVersions(merged)
+	for _, response := range replied {
+		if storage.DigestVersions(response.Versions) == digest {
+			continue
+		}
+		_, err := s.replicaSet(ctx, response.Shard, key, merged)
+		if err != nil {
+			s.Logger.WarnContext(ctx, "Read repair failed", "shard", response.Shard.ID.String(), "key", key, "error", err)
+			continue
+		}
+		atomic.AddUint64(&s.readRepairs, 1)
+	}
+}
+
+// ReadRepairs returns the number of stale replicas that have been repaired after a read
+func (s *DBServer) ReadRepairs() uint64 {
+	return atomic.LoadUint64(&s.readRepairs)
+}
+
+// quorumPut writes a version of a key to its replicas and returns once enough replicas have acknowledged it.
+// Writes that fail are kept as hints and replayed when the replica comes back, even when too few replicas acknowledged the write.
+// The replicas that did accept it keep it as well, so a write that fails with Unavailable may still show up later.
+func (s *DBServer) quorumPut(ctx context.Context, pair storage.NodeKeyValuePair, consistency api.Consistency) error {
+	replicas := s.replicas(pair.Key)
+	required := s.Replication.required(consistency, s.Replication.W, len(replicas))
+
+	ctx, cancel := context.WithTimeout(detach(ctx), s.Replication.Timeout)
+	responses := make(chan replicaResponse, len(replicas))
+	for _, shard := range replicas {
+		go func(shard cluster.Shard) {
+			versions, err := s.replicaSet(ctx, shard, pair.Key, []storage.NodeKeyValuePair{pair})
+			if err != nil && !s.isSelf(shard) {
+				s.Hints.Add(Hint{
+					Target:   shard.ID.String(),
+					Key:      pair.Key,
+					Versions: []storage.NodeKeyValuePair{pair},
+					Created:  time.Now(),
+				})
+			}
+			responses <- replicaResponse{Shard: shard, Versions: versions, Err: err}
+		}(shard)
+	}
+
+	succeeded, received := 0, 0
+	for ; received < len(replicas) && succeeded < required; received++ {
+		response := <-responses
+		if response.Err != nil {
+			s.Logger.WarnContext(ctx, "Replica write failed", "shard", response.Shard.ID.String(), "key", pair.Key, "error", response.Err)
+			continue
+		}
+		succeeded++
+	}
+	go func(received int) {
+		for ; received < len(replicas); received++ {
+			<-responses
+		}
+		cancel()
+	}(received)
+
+	if succeeded < required {
+		return status.Errorf(codes.Unavailable, "only %d of %d required replicas acknowledged the write", succeeded, required)
+	}
+	return nil
+}
+
+// replicatedWrite writes the given pair as a new version of its key that descends from the given context.
+// Only the key, value, tombstone marker, expiration and flags of the pair are used, its version and timestamp are set here.
+// If no context is given, the versions currently stored on the replicas are used.
+// The versions the write was based on are returned.
+func (s *DBServer) replicatedWrite(ctx context.Context, pair storage.NodeKeyValuePair, consistency api.Consistency, causal *api.VersionVector) ([]storage.NodeKeyValuePair, error) {
+	var previous []storage.NodeKeyValuePair
+	version := FromVersionVector(causal)
+	if causal == nil || pair.Deleted {
+		var err error
+		previous, err = s.quorumGet(ctx, pair.Key, consistency)
+		if err != nil {
+			return nil, err
+		}
+		if causal == nil {
+			version = MergeVersions(previous)
+		}
+	}
+	pair.Version = version.Increment(s.Self.ID.String())
+	pair.Timestamp = s.Clock.Now()
+	return previous, s.quorumPut(ctx, pair, consistency)
+}
+
+// ReplicaGet returns every version of a key stored on this node
+func (s *DBServer) ReplicaGet(ctx context.Context, request *api.IDRequest) (*api.VersionedResponse, error) {
+	defer s.storageSpan(ctx, "GetVersions", request.ID).Finish()
+	return &api.VersionedResponse{
+		ID:       request.ID,
+		Siblings: ToSiblings(s.localStorage(ctx).GetVersions(request.ID)),
+	}, nil
+}
+
+// ReplicaScan lists a page of the keys stored on this node
+func (s *DBServer) ReplicaScan(ctx context.Context, request *api.ScanRequest) (*api.ScanResponse, error) {
+	keys, next := s.Storage.ScanNamespace(request.Namespace, request.Cursor, int(request.Count))
+	return &api.ScanResponse{IDs: keys, Next: next}, nil
+}
+
+// ReplicaSet reconciles the given versions of a key with the versions stored on this node
+func (s *DBServer) ReplicaSet(ctx context.Context, request *api.VersionedRequest) (*api.VersionedResponse, error) {
+	defer s.storageSpan(ctx, "PutVersions", request.ID).Finish()
+	versions := s.localStorage(ctx).PutVersions(request.ID, FromSiblings(request.ID, request.Siblings))
+	return &api.VersionedResponse{
+		ID:       request.ID,
+		Siblings: ToSiblings(versions),
+	}, nil
+}
+
+// versionedResponse builds a client response from a set of reconciled versions.
+// When there are concurrent siblings the value is the one with the newest timestamp.
+// Versions that have expired are left out like tombstones.
+func versionedResponse(versions []storage.NodeKeyValuePair) *api.Response {
+	response := &api.Response{
+		Siblings: make([]*api.Sibling, 0),
+		Context:  ToVersionVector(MergeVersions(versions)),
+	}
+	now := time.Now()
+	var latest storage.Timestamp
+	for _, v := range versions {
+		if !v.Live(now) {
+			continue
+		}
+		if len(response.Siblings) == 0 || latest.Before(v.Timestamp) {
+			response.Value = v.Value
+			response.Expires = ToExpires(v.Expires)
+			response.Flags = v.Flags
+			latest = v.Timestamp
+		}
+		response.Siblings = append(response.Siblings, &api.Sibling{Value: v.Value, Version: ToVersionVector(v.Version), Timestamp: ToTimestamp(v.Timestamp), Expires: ToExpires(v.Expires), Flags: v.Flags})
+	}
+	return response
+}
+
+// MergeVersions returns a version vector that descends from every given version
+func MergeVersions(versions []storage.NodeKeyValuePair) storage.VersionVector {
+	merged := make(storage.VersionVector)
+	for _, v := range versions {
+		merged = merged.Merge(v.Version)
+	}
+	return merged
+}
+
+// ToVersionVector converts a storage version vector to its API representation
+func ToVersionVector(v storage.VersionVector) *api.VersionVector {
+	return &api.VersionVector{Clocks: v.Copy()}
+}
+
+// FromVersionVector converts an API version vector to its storage representation
+func FromVersionVector(v *api.VersionVector) storage.VersionVector {
+	if v == nil {
+		return make(storage.VersionVector)
+	}
+	return storage.VersionVector(v.Clocks).Copy()
+}
+
+// ToSiblings converts stored versions to their API representation
+func ToSiblings(versions []storage.NodeKeyValuePair) []*api.Sibling {
+	siblings := make([]*api.Sibling, 0, len(versions))
+	for _, v := range versions {
+		siblings = append(siblings, &api.Sibling{
+			Value:     v.Value,
+			Version:   ToVersionVector(v.Version),
+			Deleted:   v.Deleted,
+			Timestamp: ToTimestamp(v.Timestamp),
+			Expires:   ToExpires(v.Expires),
+			Flags:     v.Flags,
+		})
+	}
+	return siblings
+}
+
+// FromSiblings converts API siblings of the given key to their storage representation
+func FromSiblings(key string, siblings []*api.Sibling) []storage.NodeKeyValuePair {
+	versions := make([]storage.NodeKeyValuePair, 0, len(siblings))
+	for _, s := range siblings {
+		versions = append(versions, storage.NodeKeyValuePair{
+			Key:       key,
+			Value:     s.Value,
+			Version:   FromVersionVector(s.Version),
+			Deleted:   s.Deleted,
+			Timestamp: FromTimestamp(s.Timestamp),
+			Expires:   FromExpires(s.Expires),
+			Flags:     s.Flags,
+		})
+	}
+	return versions
+}
+
+// ToExpires converts an expiration to milliseconds since the Unix epoch, which is zero if there is none
+func ToExpires(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixMilli()
+}
+
+// FromExpires converts milliseconds since the Unix epoch to an expiration, which is zero if there is none
+func FromExpires(ms int64) time.Time {
+	if ms <= 0 {
+		return time.Time{}
+	}
+	return time.UnixMilli(ms)
+}
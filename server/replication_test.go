@@ -0,0 +1,205 @@
+/******
+This file is part of Vaelen/DB.
+
+Copyright 2017, Andrew Young <andrew@vaelen.org>
+
+    Vaelen/DB is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+    Vaelen/DB is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+    You should have received a copy of the GNU General Public License
+along with Vaelen/DB.  If not, see <http://www.gnu.org/licenses/>.
+******/
+
+package server
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"testing"
+
+	"github.com/satori/go.uuid"
+	"github.com/vaelen/db/api"
+	"github.com/vaelen/db/cluster"
+	"github.com/vaelen/db/logging"
+	"github.com/vaelen/db/storage"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+)
+
+// testLogs builds the loggers of the servers used by the tests
+var testLogs = logging.Text(os.Stderr)
+
+// newCluster returns a cluster configuration with the given number of shards listening on consecutive ports
+func newCluster(basePort int, count int) *cluster.Config {
+	config := &cluster.Config{Size: cluster.SmallCluster}
+	for i := 0; i < count; i++ {
+		id, _ := uuid.FromString(fmt.Sprintf("00000000-0000-0000-0000-%012d", i+1))
+		config.Shards = append(config.Shards, cluster.Shard{
+			ID:      id,
+			Address: fmt.Sprintf("localhost:%d", basePort+i),
+		})
+	}
+	return config
+}
+
+// startServer starts a server for the given shard
+func startServer(t *testing.T, config *cluster.Config, shard cluster.Shard) (*DBServer, func()) {
+	s := New(testLogs, "")
+	s.Self = shard
+	s.Cluster = config
+	lis, err := net.Listen("tcp", shard.Address)
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	grpcServer := grpc.NewServer(grpc.UnaryInterceptor(s.UnaryInterceptor))
+	api.RegisterDatabaseServer(grpcServer, s)
+	go grpcServer.Serve(lis)
+	return s, func() {
+		grpcServer.Stop()
+		s.Stop()
+	}
+}
+
+// startCluster starts the given number of servers listening on consecutive ports
+func startCluster(t *testing.T, basePort int, count int) ([]*DBServer, func()) {
+	config := newCluster(basePort, count)
+	servers := make([]*DBServer, 0, count)
+	stops := make([]func(), 0, count)
+	for _, shard := range config.Shards {
+		s, stop := startServer(t, config, shard)
+		servers = append(servers, s)
+		stops = append(stops, stop)
+	}
+	return servers, func() {
+		for _, stop := range stops {
+			stop()
+		}
+	}
+}
+
+// TestQuorumReplication tests reading and writing through different coordinators
+func TestQuorumReplication(t *testing.T) {
+	servers, stop := startCluster(t, 30100, 3)
+	defer stop()
+
+	ctx := context.Background()
+	key := "foo"
+
+	_, err := servers[0].Set(ctx, &api.IDValueRequest{ID: key, Value: "bar", Consistency: api.Consistency_ALL})
+	if err != nil {
+		t.Fatalf("Set Error: %s\n", err.Error())
+	}
+
+	for i, s := range servers {
+		if v := s.Storage.Get(key); v != "bar" {
+			t.Errorf("Replica %d has value %q, expected bar\n", i, v)
+		}
+	}
+
+	response, err := servers[1].Get(ctx, &api.IDRequest{ID: key})
+	if err != nil {
+		t.Fatalf("Get Error: %s\n", err.Error())
+	}
+	if response.Value != "bar" || len(response.Siblings) != 1 {
+		t.Fatalf("Unexpected Get response: %v\n", response)
+	}
+
+	// Overwriting through another coordinator should not create siblings
+	_, err = servers[2].Set(ctx, &api.IDValueRequest{ID: key, Value: "baz", Consistency: api.Consistency_ALL})
+	if err != nil {
+		t.Fatalf("Set Error: %s\n", err.Error())
+	}
+	response, err = servers[0].Get(ctx, &api.IDRequest{ID: key, Consistency: api.Consistency_ALL})
+	if err != nil {
+		t.Fatalf("Get Error: %s\n", err.Error())
+	}
+	if response.Value != "baz" || len(response.Siblings) != 1 {
+		t.Fatalf("Unexpected Get response: %v\n", response)
+	}
+
+	removed, err := servers[1].Remove(ctx, &api.IDRequest{ID: key, Consistency: api.Consistency_ALL})
+	if err != nil {
+		t.Fatalf("Remove Error: %s\n", err.Error())
+	}
+	if removed.Value != "baz" {
+		t.Errorf("Remove returned %q, expected baz\n", removed.Value)
+	}
+	response, err = servers[2].Get(ctx, &api.IDRequest{ID: key})
+	if err != nil {
+		t.Fatalf("Get Error: %s\n", err.Error())
+	}
+	if response.Value != "" || len(response.Siblings) != 0 {
+		t.Errorf("Expected key to be removed, got %v\n", response)
+	}
+}
+
+// TestSiblings tests that concurrent writes are returned as siblings and resolved by a later write
+func TestSiblings(t *testing.T) {
+	servers, stop := startCluster(t, 30110, 3)
+	defer stop()
+
+	ctx := context.Background()
+	key := "foo"
+
+	// Simulate two writes that were coordinated concurrently by different nodes
+	for i, value := range []string{"a", "b"} {
+		pair := storage.NodeKeyValuePair{
+			Key:     key,
+			Value:   value,
+			Version: storage.VersionVector{servers[i].Self.ID.String(): 1},
+		}
+		for _, s := range servers {
+			s.Storage.PutVersions(key, []storage.NodeKeyValuePair{pair})
+		}
+	}
+
+	response, err := servers[2].Get(ctx, &api.IDRequest{ID: key, Consistency: api.Consistency_QUORUM})
+	if err != nil {
+		t.Fatalf("Get Error: %s\n", err.Error())
+	}
+	if len(response.Siblings) != 2 {
+		t.Fatalf("Expected 2 siblings, got %v\n", response.Siblings)
+	}
+
+	_, err = servers[2].Set(ctx, &api.IDValueRequest{ID: key, Value: "c", Context: response.Context, Consistency: api.Consistency_ALL})
+	if err != nil {
+		t.Fatalf("Set Error: %s\n", err.Error())
+	}
+
+	response, err = servers[0].Get(ctx, &api.IDRequest{ID: key, Consistency: api.Consistency_ALL})
+	if err != nil {
+		t.Fatalf("Get Error: %s\n", err.Error())
+	}
+	if response.Value != "c" || len(response.Siblings) != 1 {
+		t.Fatalf("Expected siblings to be resolved, got %v\n", response)
+	}
+}
+
+// TestUnavailable tests that requests fail when too few replicas respond
+func TestUnavailable(t *testing.T) {
+	servers, stop := startCluster(t, 30120, 2)
+	defer stop()
+
+	// Add a shard that is not running
+	id, _ := uuid.FromString("00000000-0000-0000-0000-000000000099")
+	servers[0].Cluster.Shards = append(servers[0].Cluster.Shards, cluster.Shard{ID: id, Address: "localhost:30129"})
+
+	ctx := context.Background()
+	_, err := servers[0].Set(ctx, &api.IDValueRequest{ID: "foo", Value: "bar", Consistency: api.Consistency_ALL})
+	if err == nil {
+		t.Errorf("Expected write with consistency ALL to fail\n")
+	}
+	_, err = servers[0].Set(ctx, &api.IDValueRequest{ID: "foo", Value: "bar", Consistency: api.Consistency_QUORUM})
+	if err != nil {
+		t.Errorf("Expected write with consistency QUORUM to succeed: %s\n", err.Error())
+	}
+}
@@ -0,0 +1,81 @@
+/******
+This file is part of Vaelen/DB.
+
+Copyright 2017, Andrew Young <andrew@vaelen.org>
+
+    Vaelen/DB is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+    Vaelen/DB is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+    You should have received a copy of the GNU General Public License
+along with Vaelen/DB.  If not, see <http://www.gnu.org/licenses/>.
+******/
+
+package server
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/vaelen/db/api"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ReservedPrefix starts every key the server keeps its own data in.  Only other nodes may read or write these keys.
+const ReservedPrefix = "__vdb/"
+
+// readReserved decodes the JSON value of a reserved key into v and returns its version
+func (s *DBServer) readReserved(key string, v interface{}) (*api.VersionVector, error) {
+	response, err := s.Get(context.Background(), &api.IDRequest{ID: key})
+	if err != nil {
+		return nil, err
+	}
+	if response.Value != "" {
+		if err := json.Unmarshal([]byte(response.Value), v); err != nil {
+			return nil, status.Errorf(codes.DataLoss, "could not decode %s: %s", key, err)
+		}
+	}
+	return response.Context, nil
+}
+
+// writeReserved stores v as the JSON value of a reserved key, replacing the given version
+func (s *DBServer) writeReserved(key string, v interface{}, version *api.VersionVector) error {
+	return s.writeReservedUntil(key, v, version, time.Time{})
+}
+
+// writeReservedUntil stores v like writeReserved, but the key is removed once it expires.  It never does when expires is zero.
+func (s *DBServer) writeReservedUntil(key string, v interface{}, version *api.VersionVector, expires time.Time) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	request := &api.IDValueRequest{ID: key, Value: string(b), Context: version}
+	if !expires.IsZero() {
+		request.Expires = ToExpires(expires)
+	}
+	_, err = s.Set(context.Background(), request)
+	return err
+}
+
+// updateReserved reads a reserved key into v, which must be a pointer, calls f to change it and writes it back
+func (s *DBServer) updateReserved(key string, v interface{}, f func() error) error {
+	s.reserved.Lock()
+	defer s.reserved.Unlock()
+	version, err := s.readReserved(key, v)
+	if err != nil {
+		return err
+	}
+	if err := f(); err != nil {
+		return err
+	}
+	return s.writeReserved(key, v, version)
+}
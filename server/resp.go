@@ -0,0 +1,760 @@
+/******
+This file is part of Vaelen/DB.
+
+Copyright 2017, Andrew Young <andrew@vaelen.org>
+
+    Vaelen/DB is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+    Vaelen/DB is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+    You should have received a copy of the GNU General Public License
+along with Vaelen/DB.  If not, see <http://www.gnu.org/licenses/>.
+******/
+
+package server
+
+import (
+	"bufio"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/vaelen/db/api"
+	"github.com/vaelen/db/logging"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// defaultScanCount is the number of keys SCAN looks at when no COUNT is given
+	defaultScanCount = 10
+	// maxBulkLength is the longest bulk string a client may send
+	maxBulkLength = 512 * 1024 * 1024
+)
+
+// RESPServer serves the Redis protocol, RESP2 and RESP3, on top of a database server.
+// Commands go through the same operations as the gRPC service, so they are routed and replicated in the same way.
+// Expirations are stored and replicated with the values.
+// SCAN only returns the keys held by this server, like SCAN on a Redis Cluster node.
+// When authentication is enabled clients must send AUTH, or HELLO with AUTH, before any other command.
+type RESPServer struct {
+	Logger  *slog.Logger
+	db      *DBServer
+	tracker connTracker
+	nextID  int64
+}
+
+// NewRESPServer creates a Redis protocol server for the given database server
+func NewRESPServer(s *DBServer) *RESPServer {
+	return &RESPServer{
+		Logger: s.logs.Logger("resp"),
+		db:     s,
+	}
+}
+
+// Serve accepts connections on the given listener until the server is closed
+func (r *RESPServer) Serve(lis net.Listener) error {
+	r.Logger.Info("Listening", "address", lis.Addr().String())
+	return r.tracker.serve(lis, r.serveConn)
+}
+
+// Close stops every listener and closes every connection
+func (r *RESPServer) Close() {
+	r.tracker.close()
+}
+
+// respConn is a client connection
+type respConn struct {
+	*respWriter
+	id     int64
+	reader *bufio.Reader
+	quit   bool
+	// identity is the user the client authenticated as
+	identity *Identity
+	// addr is the client's address
+	addr string
+	// ctx carries the ID of the command being run
+	ctx context.Context
+}
+
+func (r *RESPServer) serveConn(conn net.Conn) {
+	c := &respConn{
+		respWriter: &respWriter{Writer: bufio.NewWriter(conn), proto: 2},
+		id:         atomic.AddInt64(&r.nextID, 1),
+		reader:     bufio.NewReader(conn),
+		addr:       conn.RemoteAddr().String(),
+	}
+	// Commands are read once the server has loaded
+	<-r.db.Loaded()
+	for !c.quit {
+		args, err := readCommand(c.reader)
+		if err != nil {
+			if perr, ok := err.(protocolError); ok {
+				c.error("ERR Protocol error: " + string(perr))
+				c.Flush()
+			}
+			return
+		}
+		if len(args) == 0 {
+			continue
+		}
+		r.dispatch(c, args)
+		if c.reader.Buffered() == 0 || c.quit {
+			// Replies to pipelined commands are sent together
+			if err := c.Flush(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// protocolError is returned when a client sends something that is not valid RESP
+type protocolError string
+
+func (e protocolError) Error() string {
+	return string(e)
+}
+
+// readLine reads a line terminated by CRLF, or by LF alone for inline commands
+func readLine(rd *bufio.Reader) (string, error) {
+	line, err := rd.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r"), nil
+}
+
+// readCommand reads a command sent either as an array of bulk strings or as an inline command
+func readCommand(rd *bufio.Reader) ([]string, error) {
+	line, err := readLine(rd)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(line, "*") {
+		return strings.Fields(line), nil
+	}
+	n, err := strconv.Atoi(line[1:])
+	if err != nil || n > 1024*1024 {
+		return nil, protocolError("invalid multibulk length")
+	}
+	args := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		line, err := readLine(rd)
+		if err != nil {
+			return nil, err
+		}
+		if !strings.HasPrefix(line, "$") {
+			return nil, protocolError(fmt.Sprintf("expected '$', got '%.1s'", line))
+		}
+		length, err := strconv.Atoi(line[1:])
+		if err != nil || length < 0 || length > maxBulkLength {
+			return nil, protocolError("invalid bulk length")
+		}
+		buf := make([]byte, length+2)
+		if _, err := io.ReadFull(rd, buf); err != nil {
+			return nil, err
+		}
+		if buf[length] != '\r' || buf[length+1] != '\n' {
+			return nil, protocolError("bulk string is not terminated by CRLF")
+		}
+		args = append(args, string(buf[:length]))
+	}
+	return args, nil
+}
+
+// respWriter writes replies in the protocol version chosen by the client
+type respWriter struct {
+	*bufio.Writer
+	proto int
+}
+
+func (w *respWriter) simple(s string) {
+	fmt.Fprintf(w, "+%s\r\n", s)
+}
+
+func (w *respWriter) error(msg string) {
+	fmt.Fprintf(w, "-%s\r\n", msg)
+}
+
+func (w *respWriter) integer(n int64) {
+	fmt.Fprintf(w, ":%d\r\n", n)
+}
+
+func (w *respWriter) bulk(s string) {
+	fmt.Fprintf(w, "$%d\r\n%s\r\n", len(s), s)
+}
+
+func (w *respWriter) null() {
+	if w.proto >= 3 {
+		w.WriteString("_\r\n")
+		return
+	}
+	w.WriteString("$-1\r\n")
+}
+
+func (w *respWriter) array(n int) {
+	fmt.Fprintf(w, "*%d\r\n", n)
+}
+
+// mapHeader starts a map of n pairs, which is sent as a flat array under RESP2
+func (w *respWriter) mapHeader(n int) {
+	if w.proto >= 3 {
+		fmt.Fprintf(w, "%%%d\r\n", n)
+		return
+	}
+	w.array(n * 2)
+}
+
+// respCommand describes a command the server understands
+type respCommand struct {
+	// arity is the number of arguments including the command name, or minus the minimum when the number can vary
+	arity int
+	run   func(r *RESPServer, c *respConn, args []string)
+	// permission is needed on the keys the command touches when authentication is enabled
+	permission api.Permission
+	// firstKey and lastKey are the positions of the first and last keys, lastKey is negative when counting from the end.
+	// step is the distance between keys.  Commands without keys have a step of zero.
+	firstKey, lastKey, step int
+}
+
+var respCommands = map[string]respCommand{
+	"ping":   {-1, (*RESPServer).ping, api.Permission_NONE, 0, 0, 0},
+	"hello":  {-1, (*RESPServer).hello, api.Permission_NONE, 0, 0, 0},
+	"auth":   {-2, (*RESPServer).auth, api.Permission_NONE, 0, 0, 0},
+	"quit":   {1, (*RESPServer).quit, api.Permission_NONE, 0, 0, 0},
+	"get":    {2, (*RESPServer).get, api.Permission_READ, 1, 1, 1},
+	"set":    {-3, (*RESPServer).set, api.Permission_WRITE, 1, 1, 1},
+	"del":    {-2, (*RESPServer).del, api.Permission_WRITE, 1, -1, 1},
+	"exists": {-2, (*RESPServer).exists, api.Permission_READ, 1, -1, 1},
+	"mget":   {-2, (*RESPServer).mget, api.Permission_READ, 1, -1, 1},
+	"mset":   {-3, (*RESPServer).mset, api.Permission_WRITE, 1, -1, 2},
+	"incr":   {2, (*RESPServer).incr, api.Permission_WRITE, 1, 1, 1},
+	"expire": {3, (*RESPServer).expire, api.Permission_WRITE, 1, 1, 1},
+	"ttl":    {2, (*RESPServer).ttl, api.Permission_READ, 1, 1, 1},
+	// scan only returns the keys the client can read
+	"scan": {-2, (*RESPServer).scan, api.Permission_NONE, 0, 0, 0},
+}
+
+// keys returns the keys a command touches
+func (command respCommand) keys(args []string) []string {
+	if command.step == 0 {
+		return nil
+	}
+	last := command.lastKey
+	if last < 0 {
+		last += len(args)
+	}
+	keys := make([]string, 0, last-command.firstKey+1)
+	for i := command.firstKey; i <= last && i < len(args); i += command.step {
+		keys = append(keys, args[i])
+	}
+	return keys
+}
+
+// dispatch runs a command and writes its reply
+func (r *RESPServer) dispatch(c *respConn, args []string) {
+	c.ctx = logging.WithRequestID(context.Background(), logging.NewRequestID())
+	name := strings.ToLower(args[0])
+	c.ctx = withGatewayCommand(c.ctx, "RESP "+strings.ToUpper(name), c.addr)
+	if c.identity != nil {
+		c.ctx = withIdentity(c.ctx, c.identity)
+	}
+	command, ok := respCommands[name]
+	if !ok {
+		quoted := make([]string, 0, len(args)-1)
+		for _, arg := range args[1:] {
+			quoted = append(quoted, fmt.Sprintf("'%s' ", arg))
+		}
+		c.error(fmt.Sprintf("ERR unknown command '%s', with args beginning with: %s", args[0], strings.Join(quoted, "")))
+		return
+	}
+	if (command.arity > 0 && len(args) != command.arity) || (command.arity < 0 && len(args) < -command.arity) {
+		c.error(fmt.Sprintf("ERR wrong number of arguments for '%s' command", name))
+		return
+	}
+	if r.db.Auth != nil {
+		if c.identity == nil && name != "auth" && name != "hello" && name != "quit" {
+			c.error("NOAUTH Authentication required.")
+			return
+		}
+		if keys := command.keys(args); len(keys) > 0 {
+			if err := r.db.authorizeIdentity(c.identity, command.permission, keys...); err != nil {
+				c.error("NOPERM " + status.Convert(err).Message())
+				return
+			}
+		}
+	}
+	bytes := 0
+	for _, arg := range args {
+		bytes += len(arg)
+	}
+	if err := r.db.limit(clientName(c.identity, c.addr), "", 1, bytes); err != nil {
+		c.dbError(err)
+		return
+	}
+	command.run(r, c, args)
+}
+
+// dbError writes an error returned by the database server
+func (c *respConn) dbError(err error) {
+	c.error("ERR " + status.Convert(err).Message())
+}
+
+// read returns the value of a key and whether it exists
+func (r *RESPServer) read(ctx context.Context, key string) (string, bool, error) {
+	item, ok, err := r.db.cacheRead(ctx, key)
+	return item.Value, ok, err
+}
+
+func (r *RESPServer) ping(c *respConn, args []string) {
+	switch len(args) {
+	case 1:
+		c.simple("PONG")
+	case 2:
+		c.bulk(args[1])
+	default:
+		c.error("ERR wrong number of arguments for 'ping' command")
+	}
+}
+
+// hello switches the protocol version and describes the server
+func (r *RESPServer) hello(c *respConn, args []string) {
+	proto := c.proto
+	if len(args) > 1 {
+		v, err := strconv.Atoi(args[1])
+		if err != nil {
+			c.error("ERR Protocol version is not an integer or out of range")
+			return
+		}
+		if v != 2 && v != 3 {
+			c.error("NOPROTO unsupported protocol version")
+			return
+		}
+		proto = v
+	}
+	for i := 2; i < len(args); i++ {
+		switch {
+		case strings.EqualFold(args[i], "setname") && i+1 < len(args):
+			i++
+		case strings.EqualFold(args[i], "auth") && i+2 < len(args):
+			if !r.login(c, args[i+1], args[i+2]) {
+				return
+			}
+			i += 2
+		default:
+			c.error("ERR syntax error")
+			return
+		}
+	}
+	c.proto = proto
+	mode := "standalone"
+	if r.db.replicated() {
+		mode = "cluster"
+	}
+	c.mapHeader(7)
+	c.bulk("server")
+	c.bulk("vdb")
+	c.bulk("version")
+	c.bulk("0.1")
+	c.bulk("proto")
+	c.integer(int64(c.proto))
+	c.bulk("id")
+	c.integer(c.id)
+	c.bulk("mode")
+	c.bulk(mode)
+	c.bulk("role")
+	c.bulk("master")
+	c.bulk("modules")
+	c.array(0)
+}
+
+// login authenticates a connection, writing an error and returning false if the credentials are not valid
+func (r *RESPServer) login(c *respConn, username string, password string) bool {
+	if r.db.Auth == nil {
+		c.error("ERR AUTH <password> called without any password configured for the default user. Are you sure your configuration is correct?")
+		return false
+	}
+	id, err := r.db.authenticate("Basic " + base64.StdEncoding.EncodeToString([]byte(username+":"+password)))
+	if err != nil {
+		c.error("WRONGPASS invalid username-password pair or user is disabled.")
+		return false
+	}
+	c.identity = id
+	return true
+}
+
+// auth authenticates the connection.  The username defaults to the admin user when only a password is given.
+func (r *RESPServer) auth(c *respConn, args []string) {
+	username, password := AdminUser, args[1]
+	switch len(args) {
+	case 2:
+	case 3:
+		username, password = args[1], args[2]
+	default:
+		c.error("ERR syntax error")
+		return
+	}
+	if r.login(c, username, password) {
+		c.simple("OK")
+	}
+}
+
+func (r *RESPServer) quit(c *respConn, args []string) {
+	c.simple("OK")
+	c.quit = true
+}
+
+func (r *RESPServer) get(c *respConn, args []string) {
+	value, ok, err := r.read(c.ctx, args[1])
+	switch {
+	case err != nil:
+		c.dbError(err)
+	case !ok:
+		c.null()
+	default:
+		c.bulk(value)
+	}
+}
+
+// set supports the EX, PX, NX, XX and KEEPTTL options
+func (r *RESPServer) set(c *respConn, args []string) {
+	key, value := args[1], args[2]
+	var ttl time.Duration
+	nx, xx, keepTTL := false, false, false
+	for i := 3; i < len(args); i++ {
+		option := strings.ToUpper(args[i])
+		switch {
+		case (option == "EX" || option == "PX") && ttl == 0 && !keepTTL && i+1 < len(args):
+			n, err := strconv.ParseInt(args[i+1], 10, 64)
+			if err != nil {
+				c.error("ERR value is not an integer or out of range")
+				return
+			}
+			if n <= 0 {
+				c.error("ERR invalid expire time in 'set' command")
+				return
+			}
+			unit := time.Second
+			if option == "PX" {
+				unit = time.Millisecond
+			}
+			ttl = time.Duration(n) * unit
+			i++
+		case option == "NX" && !xx:
+			nx = true
+		case option == "XX" && !nx:
+			xx = true
+		case option == "KEEPTTL" && ttl == 0:
+			keepTTL = true
+		default:
+			c.error("ERR syntax error")
+			return
+		}
+	}
+
+	var expires time.Time
+	if ttl > 0 {
+		expires = time.Now().Add(ttl)
+	}
+	if !nx && !xx && !keepTTL {
+		if err := r.db.cacheWrite(c.ctx, key, value, 0, expires, nil); err != nil {
+			c.dbError(err)
+			return
+		}
+		c.simple("OK")
+		return
+	}
+	written := false
+	err := r.db.cacheUpdate(c.ctx, key, func(item cacheItem, exists bool) (cacheItem, bool) {
+		written = !(nx && exists) && !(xx && !exists)
+		if keepTTL {
+			expires = item.Expires
+		}
+		return cacheItem{Value: value, Expires: expires}, written
+	})
+	switch {
+	case err != nil:
+		c.dbError(err)
+	case written:
+		c.simple("OK")
+	default:
+		c.null()
+	}
+}
+
+func (r *RESPServer) del(c *respConn, args []string) {
+	removed := int64(0)
+	for _, key := range args[1:] {
+		existed, err := r.db.cacheRemove(c.ctx, key)
+		if err != nil {
+			c.dbError(err)
+			return
+		}
+		if existed {
+			removed++
+		}
+	}
+	c.integer(removed)
+}
+
+func (r *RESPServer) exists(c *respConn, args []string) {
+	found := int64(0)
+	for _, key := range args[1:] {
+		_, ok, err := r.read(c.ctx, key)
+		if err != nil {
+			c.dbError(err)
+			return
+		}
+		if ok {
+			found++
+		}
+	}
+	c.integer(found)
+}
+
+func (r *RESPServer) mget(c *respConn, args []string) {
+	values := make([]string, 0, len(args)-1)
+	found := make([]bool, 0, len(args)-1)
+	for _, key := range args[1:] {
+		value, ok, err := r.read(c.ctx, key)
+		if err != nil {
+			c.dbError(err)
+			return
+		}
+		values = append(values, value)
+		found = append(found, ok)
+	}
+	c.array(len(values))
+	for i, value := range values {
+		if found[i] {
+			c.bulk(value)
+		} else {
+			c.null()
+		}
+	}
+}
+
+func (r *RESPServer) mset(c *respConn, args []string) {
+	if len(args)%2 != 1 {
+		c.error("ERR wrong number of arguments for 'mset' command")
+		return
+	}
+	for i := 1; i < len(args); i += 2 {
+		if err := r.db.cacheWrite(c.ctx, args[i], args[i+1], 0, time.Time{}, nil); err != nil {
+			c.dbError(err)
+			return
+		}
+	}
+	c.simple("OK")
+}
+
+// incr increments an integer value, keeping its expiration
+func (r *RESPServer) incr(c *respConn, args []string) {
+	var n int64
+	var failure string
+	err := r.db.cacheUpdate(c.ctx, args[1], func(item cacheItem, ok bool) (cacheItem, bool) {
+		n, failure = 0, ""
+		if ok {
+			var err error
+			if n, err = strconv.ParseInt(item.Value, 10, 64); err != nil {
+				failure = "ERR value is not an integer or out of range"
+				return item, false
+			}
+		}
+		if n == math.MaxInt64 {
+			failure = "ERR increment or decrement would overflow"
+			return item, false
+		}
+		n++
+		item.Value = strconv.FormatInt(n, 10)
+		return item, true
+	})
+	switch {
+	case err != nil:
+		c.dbError(err)
+	case failure != "":
+		c.error(failure)
+	default:
+		c.integer(n)
+	}
+}
+
+func (r *RESPServer) expire(c *respConn, args []string) {
+	seconds, err := strconv.ParseInt(args[2], 10, 64)
+	if err != nil {
+		c.error("ERR value is not an integer or out of range")
+		return
+	}
+	// The expiration is stored with the value, so the value is written again along with it.
+	// A deadline that has already passed hides the value straight away.
+	expires := time.Now()
+	if seconds > 0 {
+		expires = expires.Add(time.Duration(seconds) * time.Second)
+	}
+	found := false
+	err = r.db.cacheUpdate(c.ctx, args[1], func(item cacheItem, ok bool) (cacheItem, bool) {
+		found = ok
+		item.Expires = expires
+		return item, ok
+	})
+	switch {
+	case err != nil:
+		c.dbError(err)
+	case found:
+		c.integer(1)
+	default:
+		c.integer(0)
+	}
+}
+
+// ttl returns the remaining time to live in seconds, -1 if the key does not expire and -2 if it does not exist
+func (r *RESPServer) ttl(c *respConn, args []string) {
+	item, ok, err := r.db.cacheRead(c.ctx, args[1])
+	if err != nil {
+		c.dbError(err)
+		return
+	}
+	if !ok {
+		c.integer(-2)
+		return
+	}
+	if item.Expires.IsZero() {
+		c.integer(-1)
+		return
+	}
+	c.integer(int64((time.Until(item.Expires) + 500*time.Millisecond) / time.Second))
+}
+
+// scan supports the MATCH and COUNT options
+func (r *RESPServer) scan(c *respConn, args []string) {
+	cursor, err := strconv.ParseUint(args[1], 10, 32)
+	if err != nil {
+		c.error("ERR invalid cursor")
+		return
+	}
+	pattern, count := "", defaultScanCount
+	for i := 2; i < len(args); i++ {
+		option := strings.ToUpper(args[i])
+		switch {
+		case option == "MATCH" && i+1 < len(args):
+			pattern = args[i+1]
+			i++
+		case option == "COUNT" && i+1 < len(args):
+			count, err = strconv.Atoi(args[i+1])
+			if err != nil {
+				c.error("ERR value is not an integer or out of range")
+				return
+			}
+			if count < 1 {
+				c.error("ERR syntax error")
+				return
+			}
+			i++
+		default:
+			c.error("ERR syntax error")
+			return
+		}
+	}
+	keys, next := r.db.Storage.Scan(uint32(cursor), count)
+	matched := make([]string, 0, len(keys))
+	for _, key := range keys {
+		if (pattern == "" || globMatch(pattern, key)) && r.db.authorizeIdentity(c.identity, api.Permission_READ, key) == nil {
+			matched = append(matched, key)
+		}
+	}
+	c.array(2)
+	c.bulk(strconv.FormatUint(uint64(next), 10))
+	c.array(len(matched))
+	for _, key := range matched {
+		c.bulk(key)
+	}
+}
+
+// globMatch matches a string against a Redis style glob pattern supporting *, ?, [...] and backslash escapes
+func globMatch(pattern, s string) bool {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case '*':
+			pattern = strings.TrimLeft(pattern, "*")
+			if pattern == "" {
+				return true
+			}
+			for i := 0; i <= len(s); i++ {
+				if globMatch(pattern, s[i:]) {
+					return true
+				}
+			}
+			return false
+		case '?':
+			if s == "" {
+				return false
+			}
+			pattern, s = pattern[1:], s[1:]
+		case '[':
+			end := strings.IndexByte(pattern[1:], ']')
+			if end < 0 {
+				// An unterminated class matches a literal bracket
+				if s == "" || s[0] != '[' {
+					return false
+				}
+				pattern, s = pattern[1:], s[1:]
+				continue
+			}
+			if s == "" || !matchClass(pattern[1:end+1], s[0]) {
+				return false
+			}
+			pattern, s = pattern[end+2:], s[1:]
+		case '\\':
+			if len(pattern) > 1 {
+				pattern = pattern[1:]
+			}
+			fallthrough
+		default:
+			if s == "" || s[0] != pattern[0] {
+				return false
+			}
+			pattern, s = pattern[1:], s[1:]
+		}
+	}
+	return s == ""
+}
+
+// matchClass matches a byte against the contents of a [...] class, which may be negated with ^ and contain ranges
+func matchClass(class string, c byte) bool {
+	negate := strings.HasPrefix(class, "^")
+	if negate {
+		class = class[1:]
+	}
+	matched := false
+	for i := 0; i < len(class); i++ {
+		switch {
+		case class[i] == '\\' && i+1 < len(class):
+			i++
+			matched = matched || class[i] == c
+		case i+2 < len(class) && class[i+1] == '-':
+			lo, hi := class[i], class[i+2]
+			if lo > hi {
+				lo, hi = hi, lo
+			}
+			matched = matched || (c >= lo && c <= hi)
+			i += 2
+		default:
+			matched = matched || class[i] == c
+		}
+	}
+	return matched != negate
+}
@@ -0,0 +1,256 @@
+/******
+This file is part of Vaelen/DB.
+
+Copyright 2017, Andrew Young <andrew@vaelen.org>
+
+    Vaelen/DB is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+    Vaelen/DB is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+    You should have received a copy of the GNU General Public License
+along with Vaelen/DB.  If not, see <http://www.gnu.org/licenses/>.
+******/
+
+package server
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// respError is an error reply read by the test client
+type respError string
+
+// respClient is a minimal Redis protocol client
+type respClient struct {
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+func dialRESP(t *testing.T, address string) *respClient {
+	conn, err := net.Dial("tcp", address)
+	if err != nil {
+		t.Fatalf("Dial Error: %s\n", err.Error())
+	}
+	return &respClient{conn: conn, reader: bufio.NewReader(conn)}
+}
+
+// send writes a command as an array of bulk strings
+func (c *respClient) send(args ...string) {
+	fmt.Fprintf(c.conn, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(c.conn, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+}
+
+func (c *respClient) do(t *testing.T, args ...string) interface{} {
+	c.send(args...)
+	reply, err := c.read()
+	if err != nil {
+		t.Fatalf("Read Error: %s, Command: %v\n", err.Error(), args)
+	}
+	return reply
+}
+
+// read reads a single reply.  Arrays are returned as slices and maps as maps with string keys.
+func (c *respClient) read() (interface{}, error) {
+	line, err := c.reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return respError(line[1:]), nil
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '_':
+		return nil, nil
+	case '$':
+		n, _ := strconv.Atoi(line[1:])
+		if n < 0 {
+			return nil, nil
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(c.reader, buf); err != nil {
+			return nil, err
+		}
+		return string(buf[:n]), nil
+	case '*':
+		n, _ := strconv.Atoi(line[1:])
+		if n < 0 {
+			return nil, nil
+		}
+		items := make([]interface{}, 0, n)
+		for i := 0; i < n; i++ {
+			item, err := c.read()
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+		}
+		return items, nil
+	case '%':
+		n, _ := strconv.Atoi(line[1:])
+		items := make(map[string]interface{})
+		for i := 0; i < n; i++ {
+			key, err := c.read()
+			if err != nil {
+				return nil, err
+			}
+			value, err := c.read()
+			if err != nil {
+				return nil, err
+			}
+			items[fmt.Sprint(key)] = value
+		}
+		return items, nil
+	}
+	return nil, fmt.Errorf("unexpected reply: %s", line)
+}
+
+// TestRESP drives the Redis protocol listener with a hand written client
+func TestRESP(t *testing.T) {
+	s := New(testLogs, "")
+	defer s.Stop()
+	r := NewRESPServer(s)
+	defer r.Close()
+	lis, err := net.Listen("tcp", "localhost:30260")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	go r.Serve(lis)
+
+	c := dialRESP(t, "localhost:30260")
+	defer c.conn.Close()
+
+	expect := func(expected interface{}, args ...string) {
+		if reply := c.do(t, args...); !reflect.DeepEqual(reply, expected) {
+			t.Errorf("Command: %v, Reply: %#v, Expected: %#v\n", args, reply, expected)
+		}
+	}
+	expectError := func(prefix string, args ...string) {
+		reply := c.do(t, args...)
+		if e, ok := reply.(respError); !ok || !strings.HasPrefix(string(e), prefix) {
+			t.Errorf("Command: %v, Reply: %#v, Expected an error starting with: %s\n", args, reply, prefix)
+		}
+	}
+
+	expect("PONG", "PING")
+	expect("OK", "SET", "foo", "bar")
+	expect("bar", "get", "foo")
+	expect(nil, "GET", "missing")
+	expect(nil, "SET", "foo", "baz", "NX")
+	expect(nil, "SET", "new", "1", "XX")
+	expect("OK", "SET", "foo", "baz", "XX")
+	expect("baz", "GET", "foo")
+	expectError("ERR syntax error", "SET", "foo", "bar", "NX", "XX")
+	expectError("ERR value is not an integer", "SET", "foo", "bar", "EX", "soon")
+	expectError("ERR invalid expire time", "SET", "foo", "bar", "EX", "0")
+
+	expect("OK", "MSET", "a", "1", "b", "2")
+	expect([]interface{}{"1", "2", nil}, "MGET", "a", "b", "c")
+	expectError("ERR wrong number of arguments for 'mset' command", "MSET", "a", "1", "b")
+	expect(int64(3), "EXISTS", "a", "b", "c", "a")
+
+	expect(int64(2), "INCR", "a")
+	expect(int64(1), "INCR", "counter")
+	expectError("ERR value is not an integer", "INCR", "foo")
+
+	expect(int64(1), "EXPIRE", "a", "100")
+	expect(int64(100), "TTL", "a")
+	expect(int64(-1), "TTL", "b")
+	expect(int64(-2), "TTL", "missing")
+	expect(int64(0), "EXPIRE", "missing", "10")
+	expect(int64(3), "INCR", "a")
+	expect(int64(100), "TTL", "a")
+	expect("OK", "SET", "temp", "value", "PX", "50")
+	time.Sleep(100 * time.Millisecond)
+	expect(nil, "GET", "temp")
+	expect(int64(0), "EXISTS", "temp")
+
+	expect(int64(2), "DEL", "a", "b", "missing")
+	expect(nil, "GET", "a")
+
+	for i := 0; i < 50; i++ {
+		expect("OK", "SET", fmt.Sprintf("key-%d", i), "value")
+	}
+	found := make(map[string]bool)
+	cursor := "0"
+	for {
+		reply, ok := c.do(t, "SCAN", cursor, "MATCH", "key-*", "COUNT", "5").([]interface{})
+		if !ok || len(reply) != 2 {
+			t.Fatalf("Unexpected SCAN reply: %#v\n", reply)
+		}
+		for _, key := range reply[1].([]interface{}) {
+			found[key.(string)] = true
+		}
+		if cursor = reply[0].(string); cursor == "0" {
+			break
+		}
+	}
+	if len(found) != 50 {
+		t.Errorf("SCAN found %d keys, Expected: 50\n", len(found))
+	}
+
+	expectError("ERR unknown command 'FLUSHALL', with args beginning with: 'ASYNC' ", "FLUSHALL", "ASYNC")
+	expectError("ERR wrong number of arguments for 'get' command", "GET")
+
+	// Inline commands
+	fmt.Fprintf(c.conn, "PING hello\r\n")
+	if reply, _ := c.read(); reply != "hello" {
+		t.Errorf("Inline PING Reply: %#v\n", reply)
+	}
+
+	expectError("NOPROTO", "HELLO", "4")
+	hello, ok := c.do(t, "HELLO", "3").(map[string]interface{})
+	if !ok || hello["proto"] != int64(3) || hello["server"] != "vdb" {
+		t.Fatalf("Unexpected HELLO reply: %#v\n", hello)
+	}
+	c.send("GET", "missing")
+	if line, _ := c.reader.ReadString('\n'); line != "_\r\n" {
+		t.Errorf("RESP3 null: %q\n", line)
+	}
+	expect("OK", "QUIT")
+}
+
+// TestGlobMatch tests the patterns used by SCAN MATCH
+func TestGlobMatch(t *testing.T) {
+	tests := []struct {
+		pattern string
+		s       string
+		match   bool
+	}{
+		{"*", "anything", true},
+		{"key-*", "key-12", true},
+		{"key-*", "other", false},
+		{"h?llo", "hello", true},
+		{"h?llo", "hllo", false},
+		{"h[ae]llo", "hallo", true},
+		{"h[^e]llo", "hello", false},
+		{"h[a-c]llo", "hbllo", true},
+		{"a/*/c", "a/b/c", true},
+		{`\*`, "*", true},
+		{`\*`, "a", false},
+	}
+	for _, test := range tests {
+		if globMatch(test.pattern, test.s) != test.match {
+			t.Errorf("Pattern: %s, String: %s, Expected: %t\n", test.pattern, test.s, test.match)
+		}
+	}
+}
@@ -0,0 +1,197 @@
+/******
+This file is part of Vaelen/DB.
+
+Copyright 2017, Andrew Young <andrew@vaelen.org>
+
+    Vaelen/DB is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+    Vaelen/DB is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+    You should have received a copy of the GNU General Public License
+along with Vaelen/DB.  If not, see <http://www.gnu.org/licenses/>.
+******/
+
+package server
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vaelen/db/api"
+	"github.com/vaelen/db/logging"
+	"github.com/vaelen/db/storage"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// DefaultSlowThreshold is how long a request may take before it is recorded in the slow operation log
+var DefaultSlowThreshold = 250 * time.Millisecond
+
+// DefaultMaxSlowOperations is the number of slow operations each server keeps
+var DefaultMaxSlowOperations = 1000
+
+// SlowOperation is a request that took longer than the slow operation threshold
+type SlowOperation struct {
+	// Time is when the request finished
+	Time time.Time
+	// Shard is the ID of the server that handled the request
+	Shard     string
+	Caller    string
+	Operation string
+	Namespace string
+	Keys      []string
+	RequestID string
+	Code      string
+	Duration  time.Duration
+	// Storage breaks down the time the request spent waiting for and in storage, and writing files
+	Storage storage.Timing
+}
+
+// slowLog keeps the most recent slow operations
+type slowLog struct {
+	sync.Mutex
+	max        int
+	operations []SlowOperation
+	// next is where the next operation is written once the log is full
+	next int
+}
+
+func newSlowLog(max int) *slowLog {
+	return &slowLog{max: max}
+}
+
+// add records an operation, replacing the oldest once the log is full
+func (l *slowLog) add(op SlowOperation) {
+	l.Lock()
+	defer l.Unlock()
+	if l.max <= 0 {
+		return
+	}
+	if len(l.operations) < l.max {
+		l.operations = append(l.operations, op)
+		return
+	}
+	l.operations[l.next] = op
+	l.next = (l.next + 1) % l.max
+}
+
+// list returns the operations in the log, oldest first
+func (l *slowLog) list() []SlowOperation {
+	l.Lock()
+	defer l.Unlock()
+	operations := make([]SlowOperation, 0, len(l.operations))
+	operations = append(operations, l.operations[l.next:]...)
+	return append(operations, l.operations[:l.next]...)
+}
+
+// timerKey is the context key of the timer that collects the storage timing of a request
+type timerKey struct{}
+
+// withTimer returns a context that collects the storage timing of the request it carries
+func withTimer(ctx context.Context) (context.Context, *storage.Timer) {
+	timer := &storage.Timer{}
+	return context.WithValue(ctx, timerKey{}, timer), timer
+}
+
+// requestTimer returns the timer of the request in ctx, or nil if its timing is not collected
+func requestTimer(ctx context.Context) *storage.Timer {
+	timer, _ := ctx.Value(timerKey{}).(*storage.Timer)
+	return timer
+}
+
+// localStorage returns the storage instance to use on behalf of the request in ctx, which logs with ctx and records the request's timing
+func (s *DBServer) localStorage(ctx context.Context) *storage.Instance {
+	db := s.Storage.WithContext(ctx)
+	if timer := requestTimer(ctx); timer != nil {
+		return db.Timed(timer)
+	}
+	return db
+}
+
+// logSync writes a log on behalf of the request in ctx and adds the time it took to the request's timing
+func logSync(ctx context.Context, write func()) {
+	start := time.Now()
+	write()
+	requestTimer(ctx).Add(storage.Timing{Sync: time.Since(start)})
+}
+
+// slowOperation records a request in the slow operation log if it took longer than SlowThreshold
+func (s *DBServer) slowOperation(ctx context.Context, method string, req interface{}, err error, elapsed time.Duration) {
+	if s.SlowThreshold <= 0 || elapsed < s.SlowThreshold {
+		return
+	}
+	namespace, keys := requestTarget(req)
+	op := SlowOperation{
+		Time:      time.Now(),
+		Shard:     s.Self.ID.String(),
+		Caller:    callerName(ctx),
+		Operation: strings.TrimPrefix(method, "/api.Database/"),
+		Namespace: namespace,
+		Keys:      keys,
+		RequestID: logging.RequestID(ctx),
+		Code:      status.Code(err).String(),
+		Duration:  elapsed,
+		Storage:   requestTimer(ctx).Timing(),
+	}
+	s.slowOps.add(op)
+	s.Logger.WarnContext(ctx, "Slow operation", "operation", op.Operation, "caller", op.Caller, "keys", len(keys), "duration", elapsed,
+		"wait", op.Storage.Wait, "apply", op.Storage.Apply, "sync", op.Storage.Sync, "storage_requests", op.Storage.Requests)
+}
+
+// toSlowOperation converts a slow operation to its API representation
+func toSlowOperation(op SlowOperation) *api.SlowOperation {
+	return &api.SlowOperation{
+		Time:            op.Time.UnixNano(),
+		Shard:           op.Shard,
+		Caller:          op.Caller,
+		Operation:       op.Operation,
+		Namespace:       op.Namespace,
+		Keys:            op.Keys,
+		RequestId:       op.RequestID,
+		Code:            op.Code,
+		Duration:        int64(op.Duration),
+		Wait:            int64(op.Storage.Wait),
+		Apply:           int64(op.Storage.Apply),
+		Sync:            int64(op.Storage.Sync),
+		StorageRequests: uint32(op.Storage.Requests),
+	}
+}
+
+// SlowOperations returns the operations in the slow operation logs of every server, oldest first
+func (s *DBServer) SlowOperations(ctx context.Context, request *api.SlowQuery) (*api.SlowResponse, error) {
+	response := &api.SlowResponse{}
+	for _, op := range s.slowOps.list() {
+		response.Operations = append(response.Operations, toSlowOperation(op))
+	}
+	if !request.Local && s.replicated() {
+		var lock sync.Mutex
+		failed := s.broadcast(ctx, codes.OK, func(ctx context.Context, c api.DatabaseClient) error {
+			remote, err := c.SlowOperations(ctx, &api.SlowQuery{Local: true})
+			if err != nil {
+				return err
+			}
+			lock.Lock()
+			defer lock.Unlock()
+			response.Operations = append(response.Operations, remote.Operations...)
+			return nil
+		})
+		response.Unreachable = uint32(failed)
+		sort.SliceStable(response.Operations, func(i, j int) bool {
+			return response.Operations[i].Time < response.Operations[j].Time
+		})
+	}
+	if request.Limit > 0 && len(response.Operations) > int(request.Limit) {
+		response.Operations = response.Operations[len(response.Operations)-int(request.Limit):]
+	}
+	return response, nil
+}
@@ -0,0 +1,93 @@
+/******
+This file is part of Vaelen/DB.
+
+Copyright 2017, Andrew Young <andrew@vaelen.org>
+
+    Vaelen/DB is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+    Vaelen/DB is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+    You should have received a copy of the GNU General Public License
+along with Vaelen/DB.  If not, see <http://www.gnu.org/licenses/>.
+******/
+
+package server
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/vaelen/db/api"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+)
+
+// TestSlowLog tests that the slow operation log keeps the most recent operations
+func TestSlowLog(t *testing.T) {
+	l := newSlowLog(3)
+	for _, op := range []string{"a", "b", "c", "d", "e"} {
+		l.add(SlowOperation{Operation: op})
+	}
+	ops := l.list()
+	if len(ops) != 3 || ops[0].Operation != "c" || ops[1].Operation != "d" || ops[2].Operation != "e" {
+		t.Fatalf("Unexpected operations: %v\n", ops)
+	}
+}
+
+// TestSlowOperations tests that requests over the threshold are recorded with their storage timing
+func TestSlowOperations(t *testing.T) {
+	s := New(testLogs, "")
+	defer s.Stop()
+	s.SlowThreshold = time.Nanosecond
+
+	lis, err := net.Listen("tcp", "localhost:30371")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	grpcServer := grpc.NewServer(grpc.UnaryInterceptor(s.UnaryInterceptor))
+	api.RegisterDatabaseServer(grpcServer, s)
+	go grpcServer.Serve(lis)
+	defer grpcServer.Stop()
+
+	conn, err := grpc.Dial("localhost:30371", grpc.WithInsecure())
+	if err != nil {
+		t.Fatalf("Dial Error: %s\n", err.Error())
+	}
+	defer conn.Close()
+	c := api.NewDatabaseClient(conn)
+	ctx := context.Background()
+
+	if _, err := c.Set(ctx, &api.IDValueRequest{ID: "foo", Value: "bar"}); err != nil {
+		t.Fatalf("Set Error: %s\n", err.Error())
+	}
+	if _, err := c.Txn(ctx, &api.TxnRequest{Ops: []*api.TxnOp{{ID: "foo", Value: "baz"}, {ID: "qux", Delete: true}}}); err != nil {
+		t.Fatalf("Txn Error: %s\n", err.Error())
+	}
+
+	response, err := c.SlowOperations(ctx, &api.SlowQuery{Limit: 2})
+	if err != nil {
+		t.Fatalf("SlowOperations Error: %s\n", err.Error())
+	}
+	if len(response.Operations) != 2 {
+		t.Fatalf("Expected two slow operations, got %v\n", response.Operations)
+	}
+	set, txn := response.Operations[0], response.Operations[1]
+	if set.Operation != "Set" || set.Keys[0] != "foo" || set.StorageRequests != 1 || set.Duration <= 0 || set.Apply <= 0 || set.RequestId == "" {
+		t.Errorf("Unexpected operation: %v\n", set)
+	}
+	// The transaction reads both keys, prepares and commits its intents
+	if txn.Operation != "Txn" || len(txn.Keys) != 2 || txn.StorageRequests != 4 || txn.Sync < 0 {
+		t.Errorf("Unexpected operation: %v\n", txn)
+	}
+	if set.Duration < set.Wait+set.Apply {
+		t.Errorf("Expected the storage time to be part of the duration: %v\n", set)
+	}
+}
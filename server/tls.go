@@ -0,0 +1,251 @@
+/******
+This file is part of Vaelen/DB.
+
+Copyright 2017, Andrew Young <andrew@vaelen.org>
+
+    Vaelen/DB is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+    Vaelen/DB is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+    You should have received a copy of the GNU General Public License
+along with Vaelen/DB.  If not, see <http://www.gnu.org/licenses/>.
+******/
+
+package server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+// DefaultCertReloadInterval is how often certificate files are checked for changes
+var DefaultCertReloadInterval = 10 * time.Second
+
+// Certificates holds a certificate and the CA certificates used to verify peers, reloading them when their files change.
+// Connections that are already open keep the certificates they were made with.
+type Certificates struct {
+	Logger *slog.Logger
+	// CertFile and KeyFile hold the PEM encoded certificate and private key.  Both are empty for a client without a certificate.
+	CertFile string
+	KeyFile  string
+	// CAFile holds the PEM encoded CA certificates peers are verified with.  The system roots are used when it is empty.
+	CAFile   string
+	lock     sync.RWMutex
+	cert     *tls.Certificate
+	pool     *x509.CertPool
+	modified map[string]time.Time
+	stop     chan bool
+}
+
+// LoadCertificates loads the given certificate, key and CA files
+func LoadCertificates(logger *slog.Logger, certFile string, keyFile string, caFile string) (*Certificates, error) {
+	if (certFile == "") != (keyFile == "") {
+		return nil, errors.New("a certificate and a key must be given together")
+	}
+	c := &Certificates{
+		Logger:   logger,
+		CertFile: certFile,
+		KeyFile:  keyFile,
+		CAFile:   caFile,
+		modified: make(map[string]time.Time),
+	}
+	if err := c.load(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// files returns the files the certificates are loaded from
+func (c *Certificates) files() []string {
+	files := make([]string, 0, 3)
+	for _, f := range []string{c.CertFile, c.KeyFile, c.CAFile} {
+		if f != "" {
+			files = append(files, f)
+		}
+	}
+	return files
+}
+
+// load reads every file and replaces the certificates if they are all valid
+func (c *Certificates) load() error {
+	modified := make(map[string]time.Time)
+	for _, f := range c.files() {
+		info, err := os.Stat(f)
+		if err != nil {
+			return err
+		}
+		modified[f] = info.ModTime()
+	}
+	var cert *tls.Certificate
+	if c.CertFile != "" {
+		pair, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+		if err != nil {
+			return err
+		}
+		cert = &pair
+	}
+	var pool *x509.CertPool
+	if c.CAFile != "" {
+		b, err := ioutil.ReadFile(c.CAFile)
+		if err != nil {
+			return err
+		}
+		pool = x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(b) {
+			return fmt.Errorf("no certificates found in %s", c.CAFile)
+		}
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.cert = cert
+	c.pool = pool
+	c.modified = modified
+	return nil
+}
+
+// Reload reloads the certificates if any of their files have changed and returns true if they were replaced.
+// The current certificates are kept if the new files can not be loaded.
+func (c *Certificates) Reload() (bool, error) {
+	changed := false
+	c.lock.RLock()
+	for _, f := range c.files() {
+		info, err := os.Stat(f)
+		if err != nil || !info.ModTime().Equal(c.modified[f]) {
+			changed = true
+			break
+		}
+	}
+	c.lock.RUnlock()
+	if !changed {
+		return false, nil
+	}
+	if err := c.load(); err != nil {
+		return false, err
+	}
+	c.Logger.Info("Certificates reloaded")
+	return true, nil
+}
+
+// Watch starts reloading the certificates whenever their files change, checking at the given interval
+func (c *Certificates) Watch(interval time.Duration) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.stop != nil {
+		return
+	}
+	stop := make(chan bool)
+	c.stop = stop
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if _, err := c.Reload(); err != nil {
+					c.Logger.Warn("Could not reload certificates, keeping the current ones", "error", err)
+				}
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
+
+// StopWatching stops reloading the certificates
+func (c *Certificates) StopWatching() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.stop != nil {
+		close(c.stop)
+		c.stop = nil
+	}
+}
+
+// certificate returns the current certificate, or an empty one if there is none
+func (c *Certificates) certificate() *tls.Certificate {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	if c.cert == nil {
+		return &tls.Certificate{}
+	}
+	return c.cert
+}
+
+// verify checks a peer's certificate chain against the current CA certificates
+func (c *Certificates) verify(chain []*x509.Certificate, name string, usage x509.ExtKeyUsage) error {
+	if len(chain) == 0 {
+		return errors.New("no certificate was presented")
+	}
+	c.lock.RLock()
+	pool := c.pool
+	c.lock.RUnlock()
+	intermediates := x509.NewCertPool()
+	for _, cert := range chain[1:] {
+		intermediates.AddCert(cert)
+	}
+	_, err := chain[0].Verify(x509.VerifyOptions{
+		Roots:         pool,
+		Intermediates: intermediates,
+		DNSName:       name,
+		KeyUsages:     []x509.ExtKeyUsage{usage},
+	})
+	return err
+}
+
+// ServerTLS returns a TLS configuration for listeners.  When clientAuth is true clients must present a certificate signed by the CA.
+// Peers are verified by the configuration itself so that reloaded CA certificates are used straight away.
+func (c *Certificates) ServerTLS(clientAuth bool) *tls.Config {
+	config := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			return c.certificate(), nil
+		},
+	}
+	if clientAuth {
+		config.ClientAuth = tls.RequireAnyClientCert
+		config.VerifyConnection = func(state tls.ConnectionState) error {
+			return c.verify(state.PeerCertificates, "", x509.ExtKeyUsageClientAuth)
+		}
+	}
+	return config
+}
+
+// ClientTLS returns a TLS configuration for connecting to servers.
+// The server's certificate must match serverName, or the host being connected to when it is empty.
+func (c *Certificates) ClientTLS(serverName string) *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: serverName,
+		GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			return c.certificate(), nil
+		},
+		// The chain is verified by VerifyConnection against the current CA certificates instead
+		InsecureSkipVerify: true,
+		VerifyConnection: func(state tls.ConnectionState) error {
+			return c.verify(state.PeerCertificates, state.ServerName, x509.ExtKeyUsageServerAuth)
+		},
+	}
+}
+
+// UseTLS makes connections to other shards use TLS, presenting this server's certificate to them.
+// It must be called before the server starts talking to the rest of the cluster.
+func (s *DBServer) UseTLS(certs *Certificates) {
+	s.peers.Close()
+	s.peers = newPeerPool(grpc.WithTransportCredentials(credentials.NewTLS(certs.ClientTLS(""))), s.peerInterceptors())
+}
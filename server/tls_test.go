@@ -0,0 +1,251 @@
+/******
+This file is part of Vaelen/DB.
+
+Copyright 2017, Andrew Young <andrew@vaelen.org>
+
+    Vaelen/DB is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+    Vaelen/DB is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+    You should have received a copy of the GNU General Public License
+along with Vaelen/DB.  If not, see <http://www.gnu.org/licenses/>.
+******/
+
+package server
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/vaelen/db/api"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+// testCA is a throwaway certificate authority
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+var testSerial int64
+
+func newTestCA(t *testing.T) *testCA {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey Error: %s\n", err.Error())
+	}
+	testSerial++
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(testSerial),
+		Subject:               pkix.Name{CommonName: "Test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("CreateCertificate Error: %s\n", err.Error())
+	}
+	cert, _ := x509.ParseCertificate(der)
+	return &testCA{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue returns a PEM encoded certificate for localhost and its key, usable by both servers and clients
+func (ca *testCA) issue(t *testing.T, name string) ([]byte, []byte, *big.Int) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey Error: %s\n", err.Error())
+	}
+	testSerial++
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(testSerial),
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatalf("CreateCertificate Error: %s\n", err.Error())
+	}
+	keyDER, _ := x509.MarshalECPrivateKey(key)
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+		template.SerialNumber
+}
+
+// writeCertificates writes a certificate, key and CA to the given directory under the given prefix and loads them
+func writeCertificates(t *testing.T, dir string, prefix string, ca *testCA, cert []byte, key []byte) *Certificates {
+	files := map[string][]byte{"cert.pem": cert, "key.pem": key, "ca.pem": ca.pem}
+	for name, b := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, prefix+name), b, 0600); err != nil {
+			t.Fatalf("WriteFile Error: %s\n", err.Error())
+		}
+	}
+	certs, err := LoadCertificates(testLogs.Logger("tls"),
+		filepath.Join(dir, prefix+"cert.pem"), filepath.Join(dir, prefix+"key.pem"), filepath.Join(dir, prefix+"ca.pem"))
+	if err != nil {
+		t.Fatalf("LoadCertificates Error: %s\n", err.Error())
+	}
+	return certs
+}
+
+// startTLSServer starts a server that requires client certificates
+func startTLSServer(t *testing.T, s *DBServer, address string, certs *Certificates) func() {
+	lis, err := net.Listen("tcp", address)
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	grpcServer := grpc.NewServer(grpc.Creds(credentials.NewTLS(certs.ServerTLS(true))), grpc.UnaryInterceptor(s.UnaryInterceptor))
+	api.RegisterDatabaseServer(grpcServer, s)
+	go grpcServer.Serve(lis)
+	return grpcServer.Stop
+}
+
+// callTime dials the given address with the given TLS configuration and calls Time
+func callTime(address string, config *tls.Config) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	conn, err := grpc.DialContext(ctx, address, grpc.WithTransportCredentials(credentials.NewTLS(config)), grpc.WithBlock())
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	_, err = api.NewDatabaseClient(conn).Time(ctx, &api.TimeRequest{})
+	return err
+}
+
+// TestMutualTLS tests that clients must present a certificate from the CA and that reloaded certificates are used for new connections
+func TestMutualTLS(t *testing.T) {
+	dir, err := ioutil.TempDir("", "vdb-tls")
+	if err != nil {
+		t.Fatalf("TempDir Error: %s\n", err.Error())
+	}
+	defer os.RemoveAll(dir)
+
+	ca := newTestCA(t)
+	cert, key, _ := ca.issue(t, "server")
+	serverCerts := writeCertificates(t, dir, "server-", ca, cert, key)
+	cert, key, _ = ca.issue(t, "client")
+	clientCerts := writeCertificates(t, dir, "client-", ca, cert, key)
+
+	s := New(testLogs, "")
+	defer s.Stop()
+	address := "localhost:30280"
+	defer startTLSServer(t, s, address, serverCerts)()
+
+	if err := callTime(address, clientCerts.ClientTLS("")); err != nil {
+		t.Fatalf("Client with a certificate could not connect: %s\n", err)
+	}
+
+	anonymous, err := LoadCertificates(clientCerts.Logger, "", "", clientCerts.CAFile)
+	if err != nil {
+		t.Fatalf("LoadCertificates Error: %s\n", err.Error())
+	}
+	if err := callTime(address, anonymous.ClientTLS("")); err == nil {
+		t.Errorf("Client without a certificate was able to connect\n")
+	}
+
+	other := newTestCA(t)
+	cert, key, _ = other.issue(t, "intruder")
+	intruder := writeCertificates(t, dir, "intruder-", other, cert, key)
+	intruder.pool = clientCerts.pool
+	if err := callTime(address, intruder.ClientTLS("")); err == nil {
+		t.Errorf("Client with a certificate from another CA was able to connect\n")
+	}
+
+	// Replace the server's certificate and check that new connections see it
+	cert, key, serial := ca.issue(t, "server")
+	later := time.Now().Add(time.Second)
+	for name, b := range map[string][]byte{"server-cert.pem": cert, "server-key.pem": key} {
+		f := filepath.Join(dir, name)
+		ioutil.WriteFile(f, b, 0600)
+		os.Chtimes(f, later, later)
+	}
+	if reloaded, err := serverCerts.Reload(); err != nil || !reloaded {
+		t.Fatalf("Reload - Reloaded: %t, Error: %v\n", reloaded, err)
+	}
+	if reloaded, _ := serverCerts.Reload(); reloaded {
+		t.Errorf("Certificates were reloaded without changing\n")
+	}
+	conn, err := tls.Dial("tcp", address, clientCerts.ClientTLS(""))
+	if err != nil {
+		t.Fatalf("Dial Error: %s\n", err.Error())
+	}
+	if presented := conn.ConnectionState().PeerCertificates[0].SerialNumber; presented.Cmp(serial) != 0 {
+		t.Errorf("Serial: %s, Expected: %s\n", presented, serial)
+	}
+	conn.Close()
+
+	// A broken file leaves the current certificates in place
+	ioutil.WriteFile(filepath.Join(dir, "server-cert.pem"), []byte("broken"), 0600)
+	os.Chtimes(filepath.Join(dir, "server-cert.pem"), later.Add(time.Second), later.Add(time.Second))
+	if _, err := serverCerts.Reload(); err == nil {
+		t.Errorf("Expected an error loading a broken certificate\n")
+	}
+	if err := callTime(address, clientCerts.ClientTLS("")); err != nil {
+		t.Errorf("Could not connect after a failed reload: %s\n", err)
+	}
+}
+
+// TestTLSReplication tests that shards replicate to each other over mutual TLS
+func TestTLSReplication(t *testing.T) {
+	dir, err := ioutil.TempDir("", "vdb-tls")
+	if err != nil {
+		t.Fatalf("TempDir Error: %s\n", err.Error())
+	}
+	defer os.RemoveAll(dir)
+	ca := newTestCA(t)
+
+	config := newCluster(30290, 2)
+	servers := make([]*DBServer, 0)
+	for _, shard := range config.Shards {
+		cert, key, _ := ca.issue(t, shard.ID.String())
+		certs := writeCertificates(t, dir, shard.ID.String()+"-", ca, cert, key)
+		s := New(testLogs, "")
+		s.Self = shard
+		s.Cluster = config
+		s.Replication = ReplicationConfig{N: 2, R: 2, W: 2, Timeout: 2 * time.Second}
+		s.UseTLS(certs)
+		defer s.Stop()
+		defer startTLSServer(t, s, shard.Address, certs)()
+		servers = append(servers, s)
+	}
+
+	ctx := context.Background()
+	if _, err := servers[0].Set(ctx, &api.IDValueRequest{ID: "foo", Value: "bar"}); err != nil {
+		t.Fatalf("Set Error: %s\n", err.Error())
+	}
+	for _, s := range servers {
+		if v := s.Storage.Get("foo"); v != "bar" {
+			t.Errorf("Shard: %s, Value: %s\n", s.Self.ID, v)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+/******
+This file is part of Vaelen/DB.
+
+Copyright 2017, Andrew Young <andrew@vaelen.org>
+
+    Vaelen/DB is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+    Vaelen/DB is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+    You should have received a copy of the GNU General Public License
+along with Vaelen/DB.  If not, see <http://www.gnu.org/licenses/>.
+******/
+
+package server
+
+import (
+	"github.com/vaelen/db/storage"
+	"github.com/vaelen/db/trace"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+)
+
+// peerInterceptors returns the interceptors installed on connections to other nodes
+func (s *DBServer) peerInterceptors() grpc.DialOption {
+	return grpc.WithChainUnaryInterceptor(s.traceCall, sendRequestID, s.sendClock)
+}
+
+// traceCall records a client span for each request sent to another node and sends its span context along with the request
+func (s *DBServer) traceCall(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	return s.Tracer.UnaryClientInterceptor(ctx, method, req, reply, cc, invoker, opts...)
+}
+
+// storageSpan starts a span for a storage operation done on behalf of the request in ctx
+func (s *DBServer) storageSpan(ctx context.Context, operation string, key string) *trace.Span {
+	_, span := s.Tracer.Start(ctx, "storage."+operation, trace.Internal)
+	span.SetAttribute("db.system", "vdb")
+	span.SetAttribute("db.operation", operation)
+	if namespace, _ := storage.SplitNamespace(key); namespace != "" {
+		span.SetAttribute("db.namespace", namespace)
+	}
+	return span
+}
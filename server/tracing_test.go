@@ -0,0 +1,160 @@
+/******
+This file is part of Vaelen/DB.
+
+Copyright 2017, Andrew Young <andrew@vaelen.org>
+
+    Vaelen/DB is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+    Vaelen/DB is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+    You should have received a copy of the GNU General Public License
+along with Vaelen/DB.  If not, see <http://www.gnu.org/licenses/>.
+******/
+
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/vaelen/db/api"
+	"github.com/vaelen/db/trace"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+)
+
+// collectedSpan is a span received by the collector stand-in
+type collectedSpan struct {
+	Service      string
+	TraceID      string
+	SpanID       string
+	ParentSpanID string
+	Name         string
+	Kind         int
+}
+
+// collector stands in for an OpenTelemetry collector that receives spans over OTLP/HTTP
+type collector struct {
+	sync.Mutex
+	spans []collectedSpan
+}
+
+func (c *collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		ResourceSpans []struct {
+			Resource struct {
+				Attributes []struct {
+					Key   string
+					Value struct{ StringValue string }
+				}
+			}
+			ScopeSpans []struct{ Spans []collectedSpan }
+		}
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	c.Lock()
+	defer c.Unlock()
+	for _, resource := range request.ResourceSpans {
+		for _, scope := range resource.ScopeSpans {
+			for _, span := range scope.Spans {
+				span.Service = resource.Resource.Attributes[0].Value.StringValue
+				c.spans = append(c.spans, span)
+			}
+		}
+	}
+}
+
+// TestTracing tests that a replicated write is traced from the client through the coordinator to every replica
+func TestTracing(t *testing.T) {
+	c := &collector{}
+	endpoint := httptest.NewServer(c)
+	defer endpoint.Close()
+
+	servers, stop := startCluster(t, 30350, 3)
+	defer stop()
+	for _, s := range servers {
+		s.Tracer = trace.NewTracer(testLogs.Logger("trace"), s.Self.ID.String(), trace.NewOTLPExporter(endpoint.URL+"/v1/traces"))
+	}
+	tracer := trace.NewTracer(testLogs.Logger("trace"), "client", trace.NewOTLPExporter(endpoint.URL+"/v1/traces"))
+
+	conn, err := grpc.Dial(servers[0].Self.Address, grpc.WithInsecure(), grpc.WithUnaryInterceptor(tracer.UnaryClientInterceptor))
+	if err != nil {
+		t.Fatalf("Dial Error: %s\n", err.Error())
+	}
+	defer conn.Close()
+	_, err = api.NewDatabaseClient(conn).Set(context.Background(), &api.IDValueRequest{ID: "foo", Value: "bar", Consistency: api.Consistency_ALL})
+	if err != nil {
+		t.Fatalf("Set Error: %s\n", err.Error())
+	}
+
+	tracer.Close()
+	for _, s := range servers {
+		s.Tracer.Close()
+	}
+
+	c.Lock()
+	defer c.Unlock()
+	// Internal reads, such as of the namespace configuration, start traces of their own
+	var root collectedSpan
+	for _, span := range c.spans {
+		if span.Service == "client" {
+			root = span
+		}
+	}
+	if root.ParentSpanID != "" || root.Name != "/api.Database/Set" || root.Kind != int(trace.Client) {
+		t.Fatalf("Unexpected root span: %+v\n", root)
+	}
+	spans := make(map[string]collectedSpan)
+	traced := make([]collectedSpan, 0)
+	for _, span := range c.spans {
+		if span.TraceID == root.TraceID {
+			spans[span.SpanID] = span
+			traced = append(traced, span)
+		}
+	}
+	for _, span := range traced {
+		if _, ok := spans[span.ParentSpanID]; !ok && span.ParentSpanID != "" {
+			t.Errorf("Parent of span %s %s was not exported\n", span.Service, span.Name)
+		}
+	}
+
+	// path returns the names of the spans from the root to the given span
+	path := func(span collectedSpan) []string {
+		names := []string{span.Name}
+		for span.ParentSpanID != "" {
+			span = spans[span.ParentSpanID]
+			names = append([]string{span.Name}, names...)
+		}
+		return names
+	}
+	replicaWrites := 0
+	for _, span := range traced {
+		if span.Name != "storage.PutVersions" {
+			continue
+		}
+		replicaWrites++
+		p := path(span)
+		if p[1] != "/api.Database/Set" {
+			t.Errorf("Unexpected path to storage span: %v\n", p)
+		}
+		if span.Service != servers[0].Self.ID.String() && (len(p) != 5 || p[2] != "/api.Database/ReplicaSet" || p[3] != "/api.Database/ReplicaSet") {
+			t.Errorf("Unexpected path to replica storage span: %v\n", p)
+		}
+	}
+	if replicaWrites != 3 {
+		t.Errorf("Expected 3 replica writes to be traced, got %d\n", replicaWrites)
+	}
+}
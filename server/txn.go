@@ -0,0 +1,487 @@
+/******
+This file is part of Vaelen/DB.
+
+Copyright 2017, Andrew Young <andrew@vaelen.org>
+
+    Vaelen/DB is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+    Vaelen/DB is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+    You should have received a copy of the GNU General Public License
+along with Vaelen/DB.  If not, see <http://www.gnu.org/licenses/>.
+******/
+
+package server
+
+import (
+	"sync"
+	"time"
+
+	"github.com/satori/go.uuid"
+	"github.com/vaelen/db/api"
+	"github.com/vaelen/db/cluster"
+	"github.com/vaelen/db/storage"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// txnRecovery holds the state of the transaction recovery job
+type txnRecovery struct {
+	sync.Mutex
+	stop chan bool
+}
+
+// txnVote is a participant's answer to a prepare request
+type txnVote struct {
+	Shard cluster.Shard
+	Err   error
+}
+
+// Txn atomically applies a set of writes using two-phase commit.
+// Every replica of every key is asked to prepare the writes.  The transaction commits if enough replicas of each key prepared
+// to satisfy the write consistency and none of them had a conflicting intent from another transaction.
+// The same path is used when every key lives on this server, which is then the only participant.
+// Intents only conflict with other transactions, plain writes to a key with an intent are not blocked.
+func (s *DBServer) Txn(ctx context.Context, request *api.TxnRequest) (*api.TxnResponse, error) {
+	if err := s.checkEpoch(request.Epoch); err != nil {
+		return nil, err
+	}
+	if len(request.Ops) == 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "transaction has no operations")
+	}
+
+	txid := s.Txns.Begin(s.Self.ID.String())
+	defer s.Txns.End(txid)
+
+	writes, err := s.txnWrites(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.commit(ctx, txid, writes, nil, request.Consistency); err != nil {
+		return nil, err
+	}
+	return &api.TxnResponse{Txid: txid, Decision: api.TxnDecision_COMMIT}, nil
+}
+
+// commit applies the writes of a transaction that has begun using two-phase commit.
+// A replica only prepares a key in expected if the key's stored versions still have the given digest there.
+// Replicas where they do not are counted like replicas that could not be reached, so the writes are only committed
+// if enough replicas of each key still had the versions the writes were based on.
+func (s *DBServer) commit(ctx context.Context, txid string, writes []storage.NodeKeyValuePair, expected map[string]uint64, consistency api.Consistency) error {
+	// Group the intents by the shard that has to prepare them
+	intents := make(map[string][]storage.NodeKeyValuePair)
+	shards := make(map[string]cluster.Shard)
+	replicas := make(map[string][]cluster.Shard)
+	for _, w := range writes {
+		replicas[w.Key] = s.txnReplicas(w.Key)
+		for _, shard := range replicas[w.Key] {
+			id := shard.ID.String()
+			shards[id] = shard
+			intents[id] = append(intents[id], w)
+		}
+	}
+
+	votes := make(chan txnVote, len(shards))
+	for id, shard := range shards {
+		go func(shard cluster.Shard, versions []storage.NodeKeyValuePair) {
+			votes <- txnVote{Shard: shard, Err: s.prepareOn(ctx, shard, txid, versions, expected)}
+		}(shard, intents[id])
+	}
+	prepared := make(map[string]bool)
+	decision := api.TxnDecision_COMMIT
+	// refused is set when a replica refuses the writes for taking a namespace over its quota
+	var refused error
+	for range shards {
+		vote := <-votes
+		if vote.Err == nil {
+			prepared[vote.Shard.ID.String()] = true
+			continue
+		}
+		s.Logger.WarnContext(ctx, "Prepare failed", "txid", txid, "shard", vote.Shard.ID.String(), "error", vote.Err)
+		switch status.Code(vote.Err) {
+		case codes.Aborted:
+			decision = api.TxnDecision_ABORT
+		case codes.ResourceExhausted:
+			decision = api.TxnDecision_ABORT
+			refused = vote.Err
+		}
+	}
+	for key, keyReplicas := range replicas {
+		count := 0
+		for _, shard := range keyReplicas {
+			if prepared[shard.ID.String()] {
+				count++
+			}
+		}
+		if count < s.Replication.required(consistency, s.Replication.W, len(keyReplicas)) {
+			s.Logger.WarnContext(ctx, "Not enough replicas prepared", "txid", txid, "key", key, "prepared", count)
+			decision = api.TxnDecision_ABORT
+		}
+	}
+
+	record := TxnRecord{
+		ID:           txid,
+		Coordinator:  s.Self.ID.String(),
+		Decision:     decision,
+		Participants: make([]string, 0, len(prepared)),
+		Versions:     writes,
+		Created:      time.Now(),
+	}
+	for id := range prepared {
+		record.Participants = append(record.Participants, id)
+	}
+	if decision == api.TxnDecision_COMMIT {
+		// The decision must be durable before any participant hears about it
+		var err error
+		logSync(ctx, func() { err = s.Txns.Decide(record) })
+		if err != nil {
+			s.Logger.WarnContext(ctx, "Aborting a transaction whose commit decision could not be saved", "txid", txid, "error", err)
+			decision = api.TxnDecision_ABORT
+			record.Decision = decision
+		}
+	}
+	if decision == api.TxnDecision_COMMIT {
+		// Replicas that could not prepare get the writes once they come back
+		for id, shard := range shards {
+			if prepared[id] || s.isSelf(shard) {
+				continue
+			}
+			for _, w := range intents[id] {
+				s.Hints.Add(Hint{Target: id, Key: w.Key, Versions: []storage.NodeKeyValuePair{w}, Created: time.Now()})
+			}
+		}
+	}
+	s.sendDecision(ctx, record, shards)
+
+	if refused != nil {
+		return refused
+	}
+	if decision != api.TxnDecision_COMMIT {
+		return status.Errorf(codes.Aborted, "transaction %s aborted", txid)
+	}
+	return nil
+}
+
+// txnReplicas returns the shards that have to prepare a key, which is just this server when it is not part of a cluster
+func (s *DBServer) txnReplicas(key string) []cluster.Shard {
+	if !s.replicated() {
+		return []cluster.Shard{s.Self}
+	}
+	return s.replicas(key)
+}
+
+// txnWrites builds the new version of every key written by a transaction.
+// If a key is written more than once the last write wins.
+func (s *DBServer) txnWrites(ctx context.Context, request *api.TxnRequest) ([]storage.NodeKeyValuePair, error) {
+	ops := make(map[string]*api.TxnOp)
+	order := make([]string, 0, len(request.Ops))
+	for _, op := range request.Ops {
+		key, err := s.key(request.Namespace, op.ID)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := ops[key]; !ok {
+			order = append(order, key)
+		}
+		ops[key] = op
+	}
+	writes := make([]storage.NodeKeyValuePair, 0, len(order))
+	for _, key := range order {
+		var previous []storage.NodeKeyValuePair
+		if s.replicated() {
+			var err error
+			previous, err = s.quorumGet(ctx, key, request.Consistency)
+			if err != nil {
+				return nil, err
+			}
+		} else {
+			previous = s.localStorage(ctx).GetVersions(key)
+		}
+		writes = append(writes, storage.NodeKeyValuePair{
+			Key:       key,
+			Value:     ops[key].Value,
+			Version:   MergeVersions(previous).Increment(s.Self.ID.String()),
+			Deleted:   ops[key].Delete,
+			Timestamp: s.Clock.Now(),
+		})
+		if !ops[key].Delete {
+			writes[len(writes)-1].Expires = s.expiry(key, 0)
+		}
+	}
+	return writes, nil
+}
+
+// transactionalWrite writes the pair as a new version of its key that descends from the given version through a transaction,
+// so that every replica checks the write before any of them applies it.  The keys in expected are checked as in commit.
+// Only the key, value, tombstone marker, expiration and flags of the pair are used.
+func (s *DBServer) transactionalWrite(ctx context.Context, pair storage.NodeKeyValuePair, version storage.VersionVector, expected map[string]uint64, consistency api.Consistency) error {
+	txid := s.Txns.Begin(s.Self.ID.String())
+	defer s.Txns.End(txid)
+	pair.Version = version.Increment(s.Self.ID.String())
+	pair.Timestamp = s.Clock.Now()
+	// Replicas compare expirations, so they are rounded to what the API can carry
+	pair.Expires = FromExpires(ToExpires(pair.Expires))
+	return s.commit(ctx, txid, []storage.NodeKeyValuePair{pair}, expected, consistency)
+}
+
+// conditionalWrite writes the pair as a new version of its key that descends from the given version, but only if enough
+// replicas of the key still store versions with the given digest.  It fails with Aborted if they do not.
+func (s *DBServer) conditionalWrite(ctx context.Context, pair storage.NodeKeyValuePair, version storage.VersionVector, digest uint64, consistency api.Consistency) error {
+	return s.transactionalWrite(ctx, pair, version, map[string]uint64{pair.Key: digest}, consistency)
+}
+
+// limitedWrite writes the pair like replicatedWrite, but through a transaction so that every replica checks the quota of
+// the key's namespace before any of them applies the write
+func (s *DBServer) limitedWrite(ctx context.Context, pair storage.NodeKeyValuePair, consistency api.Consistency, causal *api.VersionVector) error {
+	version := FromVersionVector(causal)
+	if causal == nil {
+		versions, err := s.readVersions(ctx, pair.Key, consistency)
+		if err != nil {
+			return err
+		}
+		version = MergeVersions(versions)
+	}
+	return s.transactionalWrite(ctx, pair, version, nil, consistency)
+}
+
+// prepareOn asks a single participant to prepare the given intents, checking the digests of the keys in expected first
+func (s *DBServer) prepareOn(ctx context.Context, shard cluster.Shard, txid string, versions []storage.NodeKeyValuePair, expected map[string]uint64) error {
+	if s.isSelf(shard) {
+		return s.prepareLocal(ctx, txid, s.Self.ID.String(), versions, expected)
+	}
+	if s.isDead(shard) {
+		return errMemberDead
+	}
+	c, err := s.peers.client(shard)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(detach(ctx), s.Replication.Timeout)
+	defer cancel()
+	_, err = c.Prepare(ctx, &api.PrepareRequest{
+		Txid:        txid,
+		Coordinator: s.Self.ID.String(),
+		Intents:     toKeyVersions(versions, expected),
+	})
+	return err
+}
+
+// prepareLocal prepares intents in this server's storage and records them in the transaction log.
+// It waits until the intents in the transaction log have been replayed.
+func (s *DBServer) prepareLocal(ctx context.Context, txid string, coordinator string, versions []storage.NodeKeyValuePair, expected map[string]uint64) error {
+	<-s.readiness.loaded
+	s.refreshQuotas(versions)
+	err := s.localStorage(ctx).PrepareIntentIf(txid, versions, expected)
+	if quota, ok := err.(*storage.QuotaError); ok {
+		return quotaExceeded(quota.Namespace, "%s", quota.Description)
+	} else if err == storage.ErrVersionConflict {
+		return status.Errorf(codes.FailedPrecondition, "%s", err)
+	} else if err != nil {
+		return status.Errorf(codes.Aborted, "%s", err)
+	}
+	logSync(ctx, func() {
+		err = s.Txns.Prepare(TxnRecord{
+			ID:          txid,
+			Coordinator: coordinator,
+			Decision:    api.TxnDecision_PENDING,
+			Versions:    versions,
+			Created:     time.Now(),
+		})
+	})
+	if err != nil {
+		// An intent that is not in the transaction log would be lost on restart, so the transaction can not commit here
+		s.localStorage(ctx).AbortIntent(txid)
+		return status.Errorf(codes.Aborted, "could not record the prepared transaction: %s", err)
+	}
+	return nil
+}
+
+// decideLocal applies the decision for a transaction prepared on this server once the transaction log has been replayed
+func (s *DBServer) decideLocal(ctx context.Context, txid string, decision api.TxnDecision) {
+	<-s.readiness.loaded
+	switch decision {
+	case api.TxnDecision_COMMIT:
+		s.localStorage(ctx).CommitIntent(txid)
+	case api.TxnDecision_ABORT:
+		s.localStorage(ctx).AbortIntent(txid)
+	default:
+		return
+	}
+	logSync(ctx, func() { s.Txns.Resolve(txid) })
+}
+
+// sendDecision sends the decision for a transaction to every participant in the record.
+// Participants that acknowledge a commit are removed from the transaction log, the rest are retried by RecoverTxns.
+func (s *DBServer) sendDecision(ctx context.Context, record TxnRecord, shards map[string]cluster.Shard) {
+	var wg sync.WaitGroup
+	for _, id := range record.Participants {
+		shard, ok := shards[id]
+		if !ok {
+			continue
+		}
+		wg.Add(1)
+		go func(id string, shard cluster.Shard) {
+			defer wg.Done()
+			if s.isSelf(shard) {
+				s.decideLocal(ctx, record.ID, record.Decision)
+			} else {
+				c, err := s.peers.client(shard)
+				if err != nil {
+					return
+				}
+				ctx, cancel := context.WithTimeout(detach(ctx), s.Replication.Timeout)
+				_, err = c.Decide(ctx, &api.DecideRequest{Txid: record.ID, Decision: record.Decision})
+				cancel()
+				if err != nil {
+					s.Logger.WarnContext(ctx, "Decision not delivered", "txid", record.ID, "shard", id, "error", err)
+					return
+				}
+			}
+			s.Txns.Acknowledge(record.ID, id)
+		}(id, shard)
+	}
+	wg.Wait()
+}
+
+// Prepare records the intents of a transaction on this server and locks their keys
+func (s *DBServer) Prepare(ctx context.Context, request *api.PrepareRequest) (*api.TxnStatusResponse, error) {
+	versions := make([]storage.NodeKeyValuePair, 0, len(request.Intents))
+	var expected map[string]uint64
+	for _, kv := range request.Intents {
+		versions = append(versions, FromSiblings(kv.ID, kv.Siblings)...)
+		if kv.Expected != 0 {
+			if expected == nil {
+				expected = make(map[string]uint64)
+			}
+			expected[kv.ID] = kv.Expected
+		}
+	}
+	if err := s.prepareLocal(ctx, request.Txid, request.Coordinator, versions, expected); err != nil {
+		return nil, err
+	}
+	return &api.TxnStatusResponse{Txid: request.Txid, Decision: api.TxnDecision_PENDING}, nil
+}
+
+// Decide applies the coordinator's decision for a transaction prepared on this server
+func (s *DBServer) Decide(ctx context.Context, request *api.DecideRequest) (*api.TxnStatusResponse, error) {
+	s.decideLocal(ctx, request.Txid, request.Decision)
+	return &api.TxnStatusResponse{Txid: request.Txid, Decision: request.Decision}, nil
+}
+
+// TxnStatus returns the outcome of a transaction coordinated by this server
+func (s *DBServer) TxnStatus(ctx context.Context, request *api.TxnStatusRequest) (*api.TxnStatusResponse, error) {
+	return &api.TxnStatusResponse{Txid: request.Txid, Decision: s.Txns.Decision(request.Txid)}, nil
+}
+
+// StartTxnRecovery starts resolving in-doubt transactions at the given interval
+func (s *DBServer) StartTxnRecovery(interval time.Duration) {
+	s.txnRecovery.Lock()
+	defer s.txnRecovery.Unlock()
+	if s.txnRecovery.stop != nil {
+		return
+	}
+	stop := make(chan bool)
+	s.txnRecovery.stop = stop
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				s.RecoverTxns()
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
+
+// stopTxnRecovery stops the transaction recovery job if it is running
+func (s *DBServer) stopTxnRecovery() {
+	s.txnRecovery.Lock()
+	defer s.txnRecovery.Unlock()
+	if s.txnRecovery.stop != nil {
+		close(s.txnRecovery.stop)
+		s.txnRecovery.stop = nil
+	}
+}
+
+// RecoverTxns resends commit decisions that participants have not acknowledged and asks coordinators for the outcome of
+// transactions that have been prepared on this server for longer than the in-doubt timeout.
+// It returns the number of prepared transactions that were resolved.
+func (s *DBServer) RecoverTxns() int {
+	for _, record := range s.Txns.Decided() {
+		shards := make(map[string]cluster.Shard)
+		for _, id := range record.Participants {
+			if shard, ok := s.txnShard(id); ok {
+				shards[id] = shard
+			}
+		}
+		s.sendDecision(context.Background(), record, shards)
+	}
+
+	resolved := 0
+	now := time.Now()
+	for _, record := range s.Txns.Prepared() {
+		if now.Sub(record.Created) < s.Txns.InDoubt {
+			continue
+		}
+		decision := api.TxnDecision_PENDING
+		if record.Coordinator == s.Self.ID.String() {
+			decision = s.Txns.Decision(record.ID)
+		} else if shard, ok := s.txnShard(record.Coordinator); ok {
+			if c, err := s.peers.client(shard); err == nil {
+				ctx, cancel := context.WithTimeout(context.Background(), s.Replication.Timeout)
+				response, err := c.TxnStatus(ctx, &api.TxnStatusRequest{Txid: record.ID})
+				cancel()
+				if err == nil {
+					decision = response.Decision
+				}
+			}
+		}
+		if decision == api.TxnDecision_PENDING {
+			continue
+		}
+		s.Logger.Info("Resolved in-doubt transaction", "txid", record.ID, "decision", decision.String())
+		s.decideLocal(context.Background(), record.ID, decision)
+		resolved++
+	}
+	return resolved
+}
+
+// txnShard returns the shard with the given ID
+func (s *DBServer) txnShard(id string) (cluster.Shard, bool) {
+	if id == s.Self.ID.String() {
+		return s.Self, true
+	}
+	shardID, err := uuid.FromString(id)
+	if err != nil || !s.replicated() {
+		return cluster.Shard{}, false
+	}
+	return s.cluster().Shard(shardID)
+}
+
+// restoreIntents prepares the intents of every transaction in the transaction log again after a restart
+func (s *DBServer) restoreIntents() {
+	for _, record := range s.Txns.Prepared() {
+		if err := s.Storage.PrepareIntent(record.ID, record.Versions); err != nil {
+			s.Logger.Error("Could not restore intents", "txid", record.ID, "error", err)
+		}
+	}
+}
+
+func toKeyVersions(versions []storage.NodeKeyValuePair, expected map[string]uint64) []*api.KeyVersions {
+	grouped := make([]*api.KeyVersions, 0, len(versions))
+	for key, v := range groupVersions(versions) {
+		grouped = append(grouped, &api.KeyVersions{ID: key, Siblings: ToSiblings(v), Expected: expected[key]})
+	}
+	return grouped
+}
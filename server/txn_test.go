@@ -0,0 +1,249 @@
+/******
+This file is part of Vaelen/DB.
+
+Copyright 2017, Andrew Young <andrew@vaelen.org>
+
+    Vaelen/DB is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+    Vaelen/DB is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+    You should have received a copy of the GNU General Public License
+along with Vaelen/DB.  If not, see <http://www.gnu.org/licenses/>.
+******/
+
+package server
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/vaelen/db/api"
+	"github.com/vaelen/db/cluster"
+	"github.com/vaelen/db/storage"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// TestLocalTxn tests transactions on a server that is not part of a cluster
+func TestLocalTxn(t *testing.T) {
+	s := New(testLogs, "")
+	defer s.Stop()
+	ctx := context.Background()
+
+	s.Storage.Set("c", "old")
+	_, err := s.Txn(ctx, &api.TxnRequest{Ops: []*api.TxnOp{
+		{ID: "a", Value: "1"},
+		{ID: "b", Value: "2"},
+		{ID: "c", Delete: true},
+	}})
+	if err != nil {
+		t.Fatalf("Txn Error: %s\n", err.Error())
+	}
+	if s.Storage.Get("a") != "1" || s.Storage.Get("b") != "2" || s.Storage.Get("c") != "" {
+		t.Fatalf("Unexpected values. a: %s, b: %s, c: %s\n", s.Storage.Get("a"), s.Storage.Get("b"), s.Storage.Get("c"))
+	}
+	if len(s.Txns.Prepared()) != 0 || len(s.Txns.Decided()) != 0 {
+		t.Fatalf("Transaction log was not cleaned up\n")
+	}
+
+	if _, err := s.Txn(ctx, &api.TxnRequest{}); status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("Expected an invalid argument error, got: %v\n", err)
+	}
+}
+
+// TestCrossShardTxn tests a transaction whose keys live on different shards
+func TestCrossShardTxn(t *testing.T) {
+	servers, stop := startCluster(t, 30200, 3)
+	defer stop()
+	// Similar keys share a chunk, the ring spreads them out
+	servers[0].cluster().Strategy = cluster.RingStrategy
+	for _, s := range servers {
+		s.Replication.N = 1
+		s.Replication.W = 1
+		s.Replication.R = 1
+	}
+	ctx := context.Background()
+
+	ops := make([]*api.TxnOp, 0)
+	owners := make(map[string]bool)
+	for i := 0; i < 10; i++ {
+		key := fmt.Sprintf("key-%d", i)
+		ops = append(ops, &api.TxnOp{ID: key, Value: key})
+		owners[servers[0].replicas(key)[0].ID.String()] = true
+	}
+	if len(owners) < 2 {
+		t.Fatalf("Keys do not span shards\n")
+	}
+
+	response, err := servers[1].Txn(ctx, &api.TxnRequest{Ops: ops})
+	if err != nil {
+		t.Fatalf("Txn Error: %s\n", err.Error())
+	}
+	if response.Decision != api.TxnDecision_COMMIT {
+		t.Fatalf("Decision: %s\n", response.Decision)
+	}
+	for _, op := range ops {
+		r, err := servers[2].Get(ctx, &api.IDRequest{ID: op.ID})
+		if err != nil {
+			t.Fatalf("Get Error: %s\n", err.Error())
+		}
+		if r.Value != op.Value {
+			t.Errorf("Key: %s, Value: %s\n", op.ID, r.Value)
+		}
+	}
+
+	// A conflicting intent on one participant aborts the whole transaction
+	conflict := ops[0].ID
+	owner := servers[0].replicas(conflict)[0]
+	var participant *DBServer
+	for _, s := range servers {
+		if s.isSelf(owner) {
+			participant = s
+		}
+	}
+	err = participant.Storage.PrepareIntent("other", []storage.NodeKeyValuePair{{Key: conflict, Value: "x", Version: storage.VersionVector{"x": 99}}})
+	if err != nil {
+		t.Fatalf("PrepareIntent Error: %s\n", err.Error())
+	}
+	for _, op := range ops {
+		op.Value = "changed"
+	}
+	if _, err := servers[1].Txn(ctx, &api.TxnRequest{Ops: ops}); status.Code(err) != codes.Aborted {
+		t.Fatalf("Expected the transaction to abort, got: %v\n", err)
+	}
+	for _, op := range ops {
+		r, err := servers[2].Get(ctx, &api.IDRequest{ID: op.ID})
+		if err != nil {
+			t.Fatalf("Get Error: %s\n", err.Error())
+		}
+		if r.Value == "changed" {
+			t.Errorf("Aborted write is visible. Key: %s\n", op.ID)
+		}
+	}
+	for _, s := range servers {
+		for _, record := range s.Txns.Prepared() {
+			t.Errorf("Shard %s still has prepared transaction %s\n", s.Self.ID, record.ID)
+		}
+	}
+}
+
+// TestTxnRecovery tests that in-doubt transactions are resolved after a coordinator or participant misses the decision
+func TestTxnRecovery(t *testing.T) {
+	servers, stop := startCluster(t, 30210, 2)
+	defer stop()
+	coordinator, participant := servers[0], servers[1]
+	participant.Txns.InDoubt = 0
+	ctx := context.Background()
+	write := func(key string) []*api.KeyVersions {
+		return toKeyVersions([]storage.NodeKeyValuePair{{Key: key, Value: key, Version: storage.VersionVector{"x": 1}}}, nil)
+	}
+
+	// The coordinator committed but the participant never heard about it
+	committed := coordinator.Txns.Begin(coordinator.Self.ID.String())
+	_, err := participant.Prepare(ctx, &api.PrepareRequest{Txid: committed, Coordinator: coordinator.Self.ID.String(), Intents: write("committed")})
+	if err != nil {
+		t.Fatalf("Prepare Error: %s\n", err.Error())
+	}
+	coordinator.Txns.Decide(TxnRecord{ID: committed, Decision: api.TxnDecision_COMMIT, Participants: []string{participant.Self.ID.String()}})
+	coordinator.Txns.End(committed)
+
+	// The coordinator crashed before deciding
+	abandoned := "abandoned"
+	_, err = participant.Prepare(ctx, &api.PrepareRequest{Txid: abandoned, Coordinator: coordinator.Self.ID.String(), Intents: write("abandoned")})
+	if err != nil {
+		t.Fatalf("Prepare Error: %s\n", err.Error())
+	}
+
+	// A transaction that is still running must be left alone
+	running := coordinator.Txns.Begin(coordinator.Self.ID.String())
+	defer coordinator.Txns.End(running)
+	_, err = participant.Prepare(ctx, &api.PrepareRequest{Txid: running, Coordinator: coordinator.Self.ID.String(), Intents: write("running")})
+	if err != nil {
+		t.Fatalf("Prepare Error: %s\n", err.Error())
+	}
+
+	if resolved := participant.RecoverTxns(); resolved != 2 {
+		t.Fatalf("Resolved: %d, Expected: 2\n", resolved)
+	}
+	if v := participant.Storage.Get("committed"); v != "committed" {
+		t.Errorf("Committed transaction was not applied: %q\n", v)
+	}
+	if v := participant.Storage.Get("abandoned"); v != "" {
+		t.Errorf("Abandoned transaction was applied: %q\n", v)
+	}
+	if prepared := participant.Txns.Prepared(); len(prepared) != 1 || prepared[0].ID != running {
+		t.Errorf("Unexpected prepared transactions: %v\n", prepared)
+	}
+
+	// The coordinator keeps resending the decision until the participant acknowledges it
+	coordinator.RecoverTxns()
+	if decided := coordinator.Txns.Decided(); len(decided) != 0 {
+		t.Errorf("Decision was not acknowledged: %v\n", decided)
+	}
+}
+
+// TestTxnLog tests that prepared transactions and decisions survive a restart
+func TestTxnLog(t *testing.T) {
+	dir, err := ioutil.TempDir("", "vdb-txns")
+	if err != nil {
+		t.Fatalf("TempDir Error: %s\n", err.Error())
+	}
+	defer os.RemoveAll(dir)
+
+	s := New(testLogs, dir)
+	err = s.prepareLocal(context.Background(), "t1", "coordinator", []storage.NodeKeyValuePair{{Key: "foo", Value: "bar", Version: storage.VersionVector{"x": 1}}}, nil)
+	if err != nil {
+		t.Fatalf("Prepare Error: %s\n", err.Error())
+	}
+	s.Txns.Decide(TxnRecord{ID: "t2", Decision: api.TxnDecision_COMMIT, Participants: []string{"p"}, Created: time.Now()})
+	s.Stop()
+
+	restarted := New(testLogs, dir)
+	defer restarted.Stop()
+	if len(restarted.Txns.Prepared()) != 1 || restarted.Txns.Decision("t2") != api.TxnDecision_COMMIT {
+		t.Fatalf("Transaction log was not restored\n")
+	}
+	// The restored intent still holds its lock once the server has loaded
+	<-restarted.Loaded()
+	err = restarted.Storage.PrepareIntent("t3", []storage.NodeKeyValuePair{{Key: "foo", Value: "baz"}})
+	if err != storage.ErrIntentConflict {
+		t.Fatalf("Expected a conflict with the restored intent, got: %v\n", err)
+	}
+	restarted.decideLocal(context.Background(), "t1", api.TxnDecision_COMMIT)
+	if v := restarted.Storage.Get("foo"); v != "bar" {
+		t.Fatalf("Value: %q, Expected: bar\n", v)
+	}
+
+	// Nothing is decided or prepared once the log can no longer be written
+	restarted.Txns.Lock()
+	restarted.Txns.Path = filepath.Join(dir, "missing")
+	restarted.Txns.Unlock()
+	if err := restarted.Txns.Decide(TxnRecord{ID: "t4", Decision: api.TxnDecision_COMMIT, Participants: []string{"p"}}); err == nil {
+		t.Fatalf("Expected an error deciding without a writable log\n")
+	}
+	if decision := restarted.Txns.Decision("t4"); decision != api.TxnDecision_ABORT {
+		t.Fatalf("Decision: %s, Expected: ABORT\n", decision)
+	}
+	err = restarted.prepareLocal(context.Background(), "t5", "coordinator", []storage.NodeKeyValuePair{{Key: "bar", Value: "baz", Version: storage.VersionVector{"x": 1}}}, nil)
+	if status.Code(err) != codes.Aborted {
+		t.Fatalf("Expected an aborted error preparing without a writable log, got: %v\n", err)
+	}
+	if len(restarted.Txns.Prepared()) != 0 {
+		t.Fatalf("Unexpected prepared transactions: %v\n", restarted.Txns.Prepared())
+	}
+	if err := restarted.Storage.PrepareIntent("t6", []storage.NodeKeyValuePair{{Key: "bar", Value: "qux"}}); err != nil {
+		t.Fatalf("Expected the refused intent to be released, got: %v\n", err)
+	}
+}
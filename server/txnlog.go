@@ -0,0 +1,270 @@
+/******
+This file is part of Vaelen/DB.
+
+Copyright 2017, Andrew Young <andrew@vaelen.org>
+
+    Vaelen/DB is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+    Vaelen/DB is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+    You should have received a copy of the GNU General Public License
+along with Vaelen/DB.  If not, see <http://www.gnu.org/licenses/>.
+******/
+
+package server
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/vaelen/db/api"
+	"github.com/vaelen/db/logging"
+	"github.com/vaelen/db/storage"
+)
+
+// DefaultInDoubtTimeout is how long a participant waits for a decision before asking the coordinator
+var DefaultInDoubtTimeout = 10 * time.Second
+
+// TxnRecord describes a transaction in the transaction log
+type TxnRecord struct {
+	ID string
+	// Coordinator is the ID of the shard coordinating the transaction
+	Coordinator string
+	Decision    api.TxnDecision
+	// Participants holds the IDs of the shards that prepared the transaction and have not acknowledged the decision.
+	// It is only used by the coordinator.
+	Participants []string
+	// Versions holds the writes of the transaction.  On a participant it only holds the intents prepared there.
+	Versions []storage.NodeKeyValuePair
+	Created  time.Time
+}
+
+// txnLogFile is the part of the transaction log that is saved to disk
+type txnLogFile struct {
+	Decided  map[string]TxnRecord
+	Prepared map[string]TxnRecord
+}
+
+// TxnLog durably records commit decisions made by this server and the transactions it has prepared.
+// Aborts are never recorded: a coordinator that has no record of a transaction it is not running reports it as aborted.
+type TxnLog struct {
+	sync.Mutex
+	// Logger is the logger used by the transaction log
+	Logger *slog.Logger
+	// Path is the directory the log is saved in.  The log is only kept in memory when it is empty.
+	Path string
+	// Keyring encrypts the saved log.  It is not encrypted when it is nil.
+	Keyring *storage.Keyring
+	// InDoubt is how long a prepared transaction waits for a decision before the coordinator is asked for it
+	InDoubt time.Duration
+	file    txnLogFile
+	// active holds the transactions this server is coordinating that have not been decided yet
+	active map[string]bool
+	next   uint64
+	// writes holds the time taken to write and sync the log
+	writes *histogram
+}
+
+// NewTxnLog creates a transaction log and loads any records saved in the given directory
+func NewTxnLog(logger *slog.Logger, dbPath string, keyring *storage.Keyring) *TxnLog {
+	l := &TxnLog{
+		Logger:  logger,
+		Path:    dbPath,
+		Keyring: keyring,
+		InDoubt: DefaultInDoubtTimeout,
+		file: txnLogFile{
+			Decided:  make(map[string]TxnRecord),
+			Prepared: make(map[string]TxnRecord),
+		},
+		active: make(map[string]bool),
+		writes: newHistogram(),
+	}
+	l.load()
+	return l
+}
+
+// Begin starts a new transaction coordinated by the given shard and returns its ID
+func (l *TxnLog) Begin(coordinator string) string {
+	l.Lock()
+	defer l.Unlock()
+	l.next++
+	txid := fmt.Sprintf("%s-%x-%x", coordinator, time.Now().UnixNano(), l.next)
+	l.active[txid] = true
+	return txid
+}
+
+// End marks a transaction as no longer running on this coordinator
+func (l *TxnLog) End(txid string) {
+	l.Lock()
+	defer l.Unlock()
+	delete(l.active, txid)
+}
+
+// Decide records a commit decision.  The record is kept until every participant has acknowledged it.
+// If the decision can not be saved it is forgotten and the error is returned, the transaction then has to abort.
+func (l *TxnLog) Decide(record TxnRecord) error {
+	l.Lock()
+	defer l.Unlock()
+	if len(record.Participants) == 0 {
+		return nil
+	}
+	l.file.Decided[record.ID] = record
+	if err := l.save(); err != nil {
+		delete(l.file.Decided, record.ID)
+		return err
+	}
+	return nil
+}
+
+// Acknowledge records that a participant has applied the decision for a transaction
+func (l *TxnLog) Acknowledge(txid string, participant string) {
+	l.Lock()
+	defer l.Unlock()
+	record, ok := l.file.Decided[txid]
+	if !ok {
+		return
+	}
+	remaining := make([]string, 0, len(record.Participants))
+	for _, p := range record.Participants {
+		if p != participant {
+			remaining = append(remaining, p)
+		}
+	}
+	if len(remaining) == 0 {
+		delete(l.file.Decided, txid)
+	} else {
+		record.Participants = remaining
+		l.file.Decided[txid] = record
+	}
+	l.save()
+}
+
+// Decision returns the outcome of a transaction coordinated by this server
+func (l *TxnLog) Decision(txid string) api.TxnDecision {
+	l.Lock()
+	defer l.Unlock()
+	if record, ok := l.file.Decided[txid]; ok {
+		return record.Decision
+	}
+	if l.active[txid] {
+		return api.TxnDecision_PENDING
+	}
+	return api.TxnDecision_ABORT
+}
+
+// Decided returns the commit decisions that have not been acknowledged by every participant
+func (l *TxnLog) Decided() []TxnRecord {
+	l.Lock()
+	defer l.Unlock()
+	records := make([]TxnRecord, 0, len(l.file.Decided))
+	for _, record := range l.file.Decided {
+		records = append(records, record)
+	}
+	return records
+}
+
+// Prepare records that a transaction has been prepared on this server.
+// If the record can not be saved the log is left as it was and the error is returned.
+func (l *TxnLog) Prepare(record TxnRecord) error {
+	l.Lock()
+	defer l.Unlock()
+	existing, ok := l.file.Prepared[record.ID]
+	if ok {
+		record.Versions = append(existing.Versions, record.Versions...)
+		record.Created = existing.Created
+	}
+	l.file.Prepared[record.ID] = record
+	if err := l.save(); err != nil {
+		if ok {
+			l.file.Prepared[record.ID] = existing
+		} else {
+			delete(l.file.Prepared, record.ID)
+		}
+		return err
+	}
+	return nil
+}
+
+// Resolve forgets a prepared transaction once its decision has been applied
+func (l *TxnLog) Resolve(txid string) {
+	l.Lock()
+	defer l.Unlock()
+	if _, ok := l.file.Prepared[txid]; !ok {
+		return
+	}
+	delete(l.file.Prepared, txid)
+	l.save()
+}
+
+// Prepared returns the transactions prepared on this server that are waiting for a decision
+func (l *TxnLog) Prepared() []TxnRecord {
+	l.Lock()
+	defer l.Unlock()
+	records := make([]TxnRecord, 0, len(l.file.Prepared))
+	for _, record := range l.file.Prepared {
+		records = append(records, record)
+	}
+	return records
+}
+
+func (l *TxnLog) filename() string {
+	return filepath.Join(l.Path, "txns.gob")
+}
+
+// save writes the log to disk.  The caller must hold the lock.
+func (l *TxnLog) save() error {
+	if l.Path == "" {
+		return nil
+	}
+	filename := l.filename()
+	var b bytes.Buffer
+	err := gob.NewEncoder(&b).Encode(l.file)
+	if err == nil {
+		start := time.Now()
+		err = l.Keyring.WriteFile(filename, b.Bytes())
+		l.writes.observe(time.Since(start))
+	}
+	if err != nil {
+		l.Logger.Error("Could not save transaction log", "file", filename, "error", err)
+	}
+	return err
+}
+
+// Reencrypt writes the log to disk again so that it is encrypted with the active data key
+func (l *TxnLog) Reencrypt() error {
+	l.Lock()
+	defer l.Unlock()
+	return l.save()
+}
+
+func (l *TxnLog) load() {
+	if l.Path == "" {
+		return
+	}
+	filename := l.filename()
+	b, err := l.Keyring.ReadFile(filename)
+	if storage.IsKeyError(err) {
+		// Saving over a log that could not be read would lose the decisions in it
+		logging.Fatal(l.Logger, "Could not decrypt transaction log", "file", filename, "error", err)
+	}
+	if err != nil {
+		return
+	}
+	err = gob.NewDecoder(bytes.NewReader(b)).Decode(&l.file)
+	if err != nil {
+		l.Logger.Warn("Could not load transaction log", "file", filename, "error", err)
+		return
+	}
+	l.Logger.Info("Transaction log loaded", "file", filename, "decided", len(l.file.Decided), "prepared", len(l.file.Prepared))
+}
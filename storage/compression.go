@@ -0,0 +1,126 @@
+/******
+This file is part of Vaelen/DB.
+
+Copyright 2017, Andrew Young <andrew@vaelen.org>
+
+    Vaelen/DB is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+    Vaelen/DB is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+    You should have received a copy of the GNU General Public License
+along with Vaelen/DB.  If not, see <http://www.gnu.org/licenses/>.
+******/
+
+package storage
+
+import (
+	"bytes"
+	"compress/flate"
+	"io/ioutil"
+	"strings"
+	"sync"
+)
+
+var (
+	// CompressionThreshold is the size in bytes from which values are compressed.  Compression is disabled when it is zero.
+	CompressionThreshold = 256
+	// DenseCompressionThreshold is the size in bytes from which values are compressed with a slower codec that compresses better
+	DenseCompressionThreshold = 64 * 1024
+)
+
+// codec marks how a stored value is encoded
+type codec byte
+
+const (
+	// codecNone stores a value as it is
+	codecNone codec = iota
+	// codecFast compresses a value quickly at the expense of its size
+	codecFast
+	// codecDense compresses a value as small as it will go
+	codecDense
+)
+
+// writers holds reusable compressors for each codec
+var writers = map[codec]*sync.Pool{
+	codecFast:  {New: func() interface{} { w, _ := flate.NewWriter(nil, flate.BestSpeed); return w }},
+	codecDense: {New: func() interface{} { w, _ := flate.NewWriter(nil, flate.BestCompression); return w }},
+}
+
+// chooseCodec returns the codec a value of the given size is stored with
+func chooseCodec(size int) codec {
+	switch {
+	case CompressionThreshold <= 0 || size < CompressionThreshold:
+		return codecNone
+	case size < DenseCompressionThreshold:
+		return codecFast
+	}
+	return codecDense
+}
+
+// compress encodes a value with the given codec
+func compress(value string, c codec) string {
+	var b bytes.Buffer
+	w := writers[c].Get().(*flate.Writer)
+	defer writers[c].Put(w)
+	w.Reset(&b)
+	w.Write([]byte(value))
+	w.Close()
+	return b.String()
+}
+
+// decompress decodes a value encoded with the given codec
+func decompress(value string, c codec) string {
+	if c == codecNone {
+		return value
+	}
+	b, err := ioutil.ReadAll(flate.NewReader(strings.NewReader(value)))
+	if err != nil {
+		// Values are compressed in memory, so this can only happen if memory is corrupt
+		panic("could not decompress value: " + err.Error())
+	}
+	return string(b)
+}
+
+// encoded returns the pair with its value compressed if it is large enough to be worth it
+func (pair NodeKeyValuePair) encoded() NodeKeyValuePair {
+	if pair.codec != codecNone {
+		return pair
+	}
+	c := chooseCodec(len(pair.Value))
+	if c == codecNone {
+		return pair
+	}
+	if value := compress(pair.Value, c); len(value) < len(pair.Value) {
+		pair.Value, pair.codec = value, c
+	}
+	return pair
+}
+
+// decoded returns the pair with its value as it was written
+func (pair NodeKeyValuePair) decoded() NodeKeyValuePair {
+	pair.Value, pair.codec = decompress(pair.Value, pair.codec), codecNone
+	return pair
+}
+
+// Ratio returns how many times larger the keys and values are than the space they are stored in
+func (u Usage) Ratio() float64 {
+	if u.Stored == 0 {
+		return 1
+	}
+	return float64(u.Bytes) / float64(u.Stored)
+}
+
+// Usage returns the usage of every namespace together
+func (db *Instance) Usage() Usage {
+	total := Usage{}
+	for _, usage := range db.Namespaces() {
+		total.add(usage)
+	}
+	return total
+}
@@ -0,0 +1,376 @@
+/******
+This file is part of Vaelen/DB.
+
+Copyright 2017, Andrew Young <andrew@vaelen.org>
+
+    Vaelen/DB is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+    Vaelen/DB is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+    You should have received a copy of the GNU General Public License
+along with Vaelen/DB.  If not, see <http://www.gnu.org/licenses/>.
+******/
+
+package storage
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/gob"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// KeySize is the size of master and data keys in bytes, which selects AES-256
+const KeySize = 32
+
+// keyringFilename is the file in the data directory that holds the wrapped data keys
+const keyringFilename = "keys.gob"
+
+// sealedMagic starts every encrypted file so that it can be told apart from files written before encryption was enabled
+var sealedMagic = []byte("VDBENC\x01")
+
+var (
+	// ErrWrongKey is returned when the master key does not unwrap the data keys, or a file does not decrypt
+	ErrWrongKey = errors.New("wrong encryption key or corrupt data")
+	// ErrUnknownKey is returned when a file was encrypted with a data key that is not in the keyring
+	ErrUnknownKey = errors.New("file was encrypted with an unknown data key")
+	// ErrNoKeyring is returned when an encrypted file is read without a keyring
+	ErrNoKeyring = errors.New("file is encrypted but no master key was given")
+)
+
+// IsKeyError returns true if the given error means a file could not be decrypted with the keys at hand
+func IsKeyError(err error) bool {
+	return err == ErrWrongKey || err == ErrUnknownKey || err == ErrNoKeyring
+}
+
+// LoadMasterKey reads a master key from a file holding either 32 raw bytes or 64 hexadecimal characters
+func LoadMasterKey(filename string) ([]byte, error) {
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) == KeySize {
+		return b, nil
+	}
+	key, err := hex.DecodeString(strings.TrimSpace(string(b)))
+	if err != nil || len(key) != KeySize {
+		return nil, fmt.Errorf("master key must be %d bytes or %d hexadecimal characters", KeySize, 2*KeySize)
+	}
+	return key, nil
+}
+
+// keyringFile is the on disk form of a keyring
+type keyringFile struct {
+	Active uint32
+	// Keys holds the data keys sealed by the master key, by ID
+	Keys map[uint32][]byte
+}
+
+// Keyring holds the data keys files are encrypted with.  The data keys are saved wrapped by a master key that is never written to the data directory.
+// New files are always encrypted with the active key, older keys are kept until every file has been encrypted again.
+// A nil keyring reads and writes files unencrypted.
+type Keyring struct {
+	sync.RWMutex
+	// Path is the directory the wrapped data keys are saved in
+	Path   string
+	master cipher.AEAD
+	active uint32
+	keys   map[uint32]cipher.AEAD
+	// raw holds the unwrapped data keys so that they can be wrapped again with a new master key
+	raw map[uint32][]byte
+}
+
+// newAEAD returns an AES-GCM cipher using the given key
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("encryption keys must be %d bytes", KeySize)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// keyID returns the additional data that binds a sealed key or file to the ID of its key
+func keyID(id uint32) []byte {
+	b := make([]byte, 4)
+	binary.LittleEndian.PutUint32(b, id)
+	return b
+}
+
+// seal encrypts the given plaintext with a random nonce, which is prepended to the result
+func seal(aead cipher.AEAD, plaintext []byte, data []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, data), nil
+}
+
+// open decrypts the output of seal
+func open(aead cipher.AEAD, sealed []byte, data []byte) ([]byte, error) {
+	if len(sealed) < aead.NonceSize() {
+		return nil, ErrWrongKey
+	}
+	plaintext, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], data)
+	if err != nil {
+		return nil, ErrWrongKey
+	}
+	return plaintext, nil
+}
+
+// OpenKeyring loads the data keys saved in the given directory, creating the first data key if there are none.
+// It returns ErrWrongKey if the data keys were wrapped by a different master key.
+func OpenKeyring(dbPath string, master []byte) (*Keyring, error) {
+	aead, err := newAEAD(master)
+	if err != nil {
+		return nil, err
+	}
+	k := &Keyring{
+		Path:   dbPath,
+		master: aead,
+		keys:   make(map[uint32]cipher.AEAD),
+		raw:    make(map[uint32][]byte),
+	}
+	b, err := ioutil.ReadFile(k.filename())
+	if os.IsNotExist(err) {
+		k.Lock()
+		defer k.Unlock()
+		return k, k.addKey()
+	}
+	if err != nil {
+		return nil, err
+	}
+	file := keyringFile{}
+	if err := gob.NewDecoder(bytes.NewReader(b)).Decode(&file); err != nil {
+		return nil, err
+	}
+	for id, wrapped := range file.Keys {
+		key, err := open(k.master, wrapped, keyID(id))
+		if err != nil {
+			return nil, err
+		}
+		if k.keys[id], err = newAEAD(key); err != nil {
+			return nil, err
+		}
+		k.raw[id] = key
+	}
+	if _, ok := k.keys[file.Active]; !ok {
+		return nil, ErrUnknownKey
+	}
+	k.active = file.Active
+	return k, nil
+}
+
+func (k *Keyring) filename() string {
+	return filepath.Join(k.Path, keyringFilename)
+}
+
+// addKey creates a new data key, makes it the active key and saves the keyring.  The caller must hold the lock.
+func (k *Keyring) addKey() error {
+	key := make([]byte, KeySize)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return err
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return err
+	}
+	id := k.active + 1
+	for _, ok := k.keys[id]; ok; _, ok = k.keys[id] {
+		id++
+	}
+	k.keys[id] = aead
+	k.raw[id] = key
+	k.active = id
+	return k.save()
+}
+
+// save writes the data keys, wrapped by the master key, to disk.  The caller must hold the lock.
+func (k *Keyring) save() error {
+	file := keyringFile{Active: k.active, Keys: make(map[uint32][]byte)}
+	for id, key := range k.raw {
+		wrapped, err := seal(k.master, key, keyID(id))
+		if err != nil {
+			return err
+		}
+		file.Keys[id] = wrapped
+	}
+	var b bytes.Buffer
+	if err := gob.NewEncoder(&b).Encode(file); err != nil {
+		return err
+	}
+	return writeFile(k.filename(), b.Bytes())
+}
+
+// Rotate creates a new data key that new files are encrypted with.
+// If a master key is given the data keys are wrapped with it from now on.
+// Files encrypted with older keys can still be read until Retire is called.
+func (k *Keyring) Rotate(master []byte) error {
+	k.Lock()
+	defer k.Unlock()
+	if master != nil {
+		aead, err := newAEAD(master)
+		if err != nil {
+			return err
+		}
+		k.master = aead
+	}
+	return k.addKey()
+}
+
+// Retire forgets every data key except the active one.  It must only be called once every file has been encrypted with the active key.
+func (k *Keyring) Retire() error {
+	k.Lock()
+	defer k.Unlock()
+	for id := range k.keys {
+		if id != k.active {
+			delete(k.keys, id)
+			delete(k.raw, id)
+		}
+	}
+	return k.save()
+}
+
+// Active returns the ID of the data key new files are encrypted with
+func (k *Keyring) Active() uint32 {
+	k.RLock()
+	defer k.RUnlock()
+	return k.active
+}
+
+// Seal encrypts the given data with the active data key.  A nil keyring returns the data as it is.
+func (k *Keyring) Seal(plaintext []byte) ([]byte, error) {
+	if k == nil {
+		return plaintext, nil
+	}
+	k.RLock()
+	defer k.RUnlock()
+	header := append(append([]byte{}, sealedMagic...), keyID(k.active)...)
+	sealed, err := seal(k.keys[k.active], plaintext, header)
+	if err != nil {
+		return nil, err
+	}
+	return append(header, sealed...), nil
+}
+
+// Open decrypts data encrypted by Seal with any key in the keyring.
+// Data written before encryption was enabled is returned as it is.
+func (k *Keyring) Open(data []byte) ([]byte, error) {
+	if !bytes.HasPrefix(data, sealedMagic) {
+		return data, nil
+	}
+	if k == nil {
+		return nil, ErrNoKeyring
+	}
+	headerSize := len(sealedMagic) + 4
+	if len(data) < headerSize {
+		return nil, ErrWrongKey
+	}
+	k.RLock()
+	defer k.RUnlock()
+	aead, ok := k.keys[binary.LittleEndian.Uint32(data[len(sealedMagic):headerSize])]
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+	return open(aead, data[headerSize:], data[:headerSize])
+}
+
+// WriteFile encrypts the given data and replaces the named file with it.
+// The data is written to a temporary file first so that a crash never leaves a partial file behind.
+func (k *Keyring) WriteFile(filename string, data []byte) error {
+	sealed, err := k.Seal(data)
+	if err != nil {
+		return err
+	}
+	return writeFile(filename, sealed)
+}
+
+// ReadFile reads and decrypts the named file
+func (k *Keyring) ReadFile(filename string) ([]byte, error) {
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return k.Open(b)
+}
+
+// Check makes sure that the keyring has the data key of every encrypted file in the given directory, so that a server given
+// the wrong key refuses to start instead of replacing data it could not read.  Only the header of each file is read, and files
+// that are not encrypted are skipped.
+func (k *Keyring) Check(dir string) error {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		if f.IsDir() || f.Name() == keyringFilename || strings.HasSuffix(f.Name(), ".tmp") {
+			continue
+		}
+		if err := k.checkFile(filepath.Join(dir, f.Name())); err != nil {
+			return fmt.Errorf("%s: %s", f.Name(), err)
+		}
+	}
+	return nil
+}
+
+// checkFile reads the header of a file and makes sure that the keyring has the data key it was encrypted with
+func (k *Keyring) checkFile(filename string) error {
+	f, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	header := make([]byte, len(sealedMagic)+4)
+	n, err := io.ReadFull(f, header)
+	if !bytes.HasPrefix(header[:n], sealedMagic) {
+		return nil
+	}
+	if err != nil {
+		return ErrWrongKey
+	}
+	if k == nil {
+		return ErrNoKeyring
+	}
+	k.RLock()
+	defer k.RUnlock()
+	if _, ok := k.keys[binary.LittleEndian.Uint32(header[len(sealedMagic):])]; !ok {
+		return ErrUnknownKey
+	}
+	return nil
+}
+
+// writeFile writes the given data to a temporary file, syncs it and renames it over the named file
+func writeFile(filename string, data []byte) error {
+	f, err := os.OpenFile(filename+".tmp", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0640)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if err == nil {
+		err = f.Sync()
+	}
+	f.Close()
+	if err != nil {
+		return err
+	}
+	return os.Rename(filename+".tmp", filename)
+}
@@ -0,0 +1,264 @@
+/******
+This file is part of Vaelen/DB.
+
+Copyright 2017, Andrew Young <andrew@vaelen.org>
+
+    Vaelen/DB is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+    Vaelen/DB is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+    You should have received a copy of the GNU General Public License
+along with Vaelen/DB.  If not, see <http://www.gnu.org/licenses/>.
+******/
+
+package storage
+
+import (
+	"encoding/binary"
+	"hash"
+	"hash/fnv"
+	"sort"
+)
+
+// LeafDepth is the depth of the tree at which nodes hold values
+const LeafDepth = 4
+
+// DigestGroups returns the digest group of a key.
+// The digest of every node is kept for each group separately so that two replicas can compare just the groups they both hold.
+type DigestGroups func(key string) string
+
+// SharedGroups selects the digest groups that are compared.  A nil SharedGroups selects every group.
+type SharedGroups func(group string) bool
+
+// NodeDigest describes the digest of a node in the storage tree and the digests of its children.
+// Values is only filled in for leaf nodes.
+type NodeDigest struct {
+	ID       NodeLocator
+	Digest   uint64
+	Children map[byte]uint64
+	Values   []NodeKeyValuePair
+}
+
+// Digest returns the digest of the node's subtree.  An empty subtree has a digest of zero.
+func (n *Node) Digest() uint64 {
+	return n.GroupDigest(nil)
+}
+
+// GroupDigest returns the digest of the keys in the node's subtree that belong to the selected groups
+func (n *Node) GroupDigest(shared SharedGroups) uint64 {
+	if n == nil {
+		return 0
+	}
+	var digest uint64
+	for group, d := range n.digests {
+		if shared == nil || shared(group) {
+			digest ^= d
+		}
+	}
+	return digest
+}
+
+// groupOf returns the digest group of a key.  Every key is in the group "" when there are no groups.
+func groupOf(groups DigestGroups, key string) string {
+	if groups == nil {
+		return ""
+	}
+	return groups(key)
+}
+
+// setDigest sets the digest of one of the node's groups, forgetting groups that become empty
+func (n *Node) setDigest(group string, digest uint64) {
+	if digest == 0 {
+		delete(n.digests, group)
+		return
+	}
+	if n.digests == nil {
+		n.digests = make(map[string]uint64)
+	}
+	n.digests[group] = digest
+}
+
+// updateDigest recalculates the digests of this node from its values and the digests of its children.
+// Children are combined with XOR so that a parent can be updated when a single child changes without visiting the others.
+func (n *Node) updateDigest(groups DigestGroups) {
+	n.digests = nil
+	if len(n.values) > 0 {
+		grouped := make(map[string][]NodeKeyValuePair)
+		for _, v := range n.decodedValues() {
+			group := groupOf(groups, v.Key)
+			grouped[group] = append(grouped[group], v)
+		}
+		for group, versions := range grouped {
+			n.setDigest(group, DigestVersions(versions))
+		}
+	}
+	for i, child := range n.Children {
+		if child == nil {
+			continue
+		}
+		for group, d := range child.digests {
+			n.setDigest(group, n.digests[group]^childDigest(byte(i), d))
+		}
+	}
+}
+
+// changedGroups returns the digests a node had before it changed, adding a zero digest for each group it has gained
+func changedGroups(old map[string]uint64, node *Node) map[string]uint64 {
+	changed := make(map[string]uint64, len(old))
+	for group, d := range old {
+		changed[group] = d
+	}
+	if node != nil {
+		for group := range node.digests {
+			if _, ok := changed[group]; !ok {
+				changed[group] = 0
+			}
+		}
+	}
+	return changed
+}
+
+// childDigest returns the contribution of a child to its parent's digest
+func childDigest(b byte, digest uint64) uint64 {
+	if digest == 0 {
+		return 0
+	}
+	h := fnv.New64a()
+	h.Write([]byte{b})
+	binary.Write(h, binary.LittleEndian, digest)
+	return h.Sum64()
+}
+
+// DigestVersions returns a digest of a set of versions that does not depend on their order
+func DigestVersions(versions []NodeKeyValuePair) uint64 {
+	h := fnv.New64a()
+	writeVersions(h, versions)
+	return h.Sum64()
+}
+
+func writeVersions(h hash.Hash64, versions []NodeKeyValuePair) {
+	sorted := make([]NodeKeyValuePair, len(versions))
+	copy(sorted, versions)
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Key != sorted[j].Key {
+			return sorted[i].Key < sorted[j].Key
+		}
+		return sorted[i].Value < sorted[j].Value
+	})
+	for _, v := range sorted {
+		h.Write([]byte(v.Key))
+		h.Write([]byte{0})
+		h.Write([]byte(v.Value))
+		h.Write([]byte{0})
+		replicas := make([]string, 0, len(v.Version))
+		for r := range v.Version {
+			replicas = append(replicas, r)
+		}
+		sort.Strings(replicas)
+		for _, r := range replicas {
+			h.Write([]byte(r))
+			binary.Write(h, binary.LittleEndian, v.Version[r])
+		}
+		if v.Deleted {
+			h.Write([]byte{1})
+		} else {
+			h.Write([]byte{0})
+		}
+		// Versions that never expire and have no flags hash the same way they did before either was stored
+		if !v.Expires.IsZero() {
+			binary.Write(h, binary.LittleEndian, v.Expires.UnixNano())
+		}
+		if v.Flags != 0 {
+			h.Write([]byte{2})
+			binary.Write(h, binary.LittleEndian, v.Flags)
+		}
+	}
+}
+
+// Child returns the locator of the given child of this node
+func (id NodeLocator) Child(b byte) NodeLocator {
+	return NodeLocator{
+		ID:        id.ID | uint32(b)<<(8*uint(id.Bytes)),
+		Bytes:     id.Bytes + 1,
+		Namespace: id.Namespace,
+	}
+}
+
+// pathTo returns the nodes on the path to the given node, starting at the root and stopping at the deepest node that exists
+func (db *Hashtable) pathTo(b []byte) []*Node {
+	path := []*Node{db.root}
+	for _, x := range b {
+		next := path[len(path)-1].Children[x]
+		if next == nil {
+			break
+		}
+		path = append(path, next)
+	}
+	return path
+}
+
+// updateDigests recalculates the digests of every node on the path to the given node, from the bottom up.
+// Only the deepest node is fully recalculated, each parent just replaces the contribution of the child that changed.
+func (db *Hashtable) updateDigests(id NodeLocator) {
+	b := id.GetBytes()
+	path := db.pathTo(b)
+	last := path[len(path)-1]
+	old := last.digests
+	last.updateDigest(db.groups)
+	db.propagateDigest(b, path, changedGroups(old, last))
+}
+
+// propagateDigest updates the ancestors of the last node in the path after the digests of the given groups changed from the given values
+func (db *Hashtable) propagateDigest(b []byte, path []*Node, old map[string]uint64) {
+	for i := len(path) - 2; i >= 0; i-- {
+		parent, child := path[i], path[i+1]
+		parentOld := make(map[string]uint64, len(old))
+		for group, d := range old {
+			parentOld[group] = parent.digests[group]
+			parent.setDigest(group, parent.digests[group]^childDigest(b[i], d)^childDigest(b[i], child.digests[group]))
+		}
+		old = parentOld
+	}
+}
+
+// SetGroups changes how keys are grouped and recalculates the digests of the whole tree
+func (db *Hashtable) SetGroups(groups DigestGroups) {
+	db.groups = groups
+	db.root.rehash(groups)
+}
+
+// Digest returns the digest of the root of the tree
+func (db *Hashtable) Digest() uint64 {
+	return db.root.Digest()
+}
+
+// NodeDigest returns the digest of the given node and its children over the keys in the selected groups
+func (db *Hashtable) NodeDigest(id NodeLocator, shared SharedGroups) NodeDigest {
+	result := NodeDigest{
+		ID:       id,
+		Children: make(map[byte]uint64),
+		Values:   make([]NodeKeyValuePair, 0),
+	}
+	node, _ := db.FindNode(id)
+	if node == nil {
+		return result
+	}
+	result.Digest = node.GroupDigest(shared)
+	for i, child := range node.Children {
+		if d := child.GroupDigest(shared); d != 0 {
+			result.Children[byte(i)] = d
+		}
+	}
+	for _, v := range node.decodedValues() {
+		if shared == nil || shared(groupOf(db.groups, v.Key)) {
+			result.Values = append(result.Values, v)
+		}
+	}
+	return result
+}
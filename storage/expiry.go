@@ -0,0 +1,100 @@
+/******
+This file is part of Vaelen/DB.
+
+Copyright 2017, Andrew Young <andrew@vaelen.org>
+
+    Vaelen/DB is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+    Vaelen/DB is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+    You should have received a copy of the GNU General Public License
+along with Vaelen/DB.  If not, see <http://www.gnu.org/licenses/>.
+******/
+
+package storage
+
+import (
+	"time"
+)
+
+// expirySweepInterval is how often versions that have expired are replaced with tombstones
+const expirySweepInterval = time.Second
+
+// track remembers when the first live version of each of the given keys expires.  It must only be called from the storage thread.
+func (db *Instance) track(keys ...string) {
+	for _, key := range keys {
+		var deadline time.Time
+		for _, v := range db.findFor(key).storedVersions(key) {
+			if !v.Deleted && !v.Expires.IsZero() && (deadline.IsZero() || v.Expires.Before(deadline)) {
+				deadline = v.Expires
+			}
+		}
+		if deadline.IsZero() {
+			delete(db.expiring, key)
+		} else {
+			db.expiring[key] = deadline
+		}
+	}
+}
+
+// expire replaces the versions that have expired by the given time with tombstones.
+// Values that were set without a version are removed instead.  It must only be called from the storage thread.
+func (db *Instance) expire(now time.Time) {
+	keys := make([]string, 0)
+	for key, deadline := range db.expiring {
+		if !now.Before(deadline) {
+			keys = append(keys, key)
+		}
+	}
+	if len(keys) == 0 {
+		return
+	}
+	for _, key := range keys {
+		tree := db.findFor(key)
+		for _, v := range tree.GetVersions(key) {
+			if v.Deleted || v.Live(now) {
+				continue
+			}
+			if len(v.Version) == 0 {
+				tree.Remove(key)
+				continue
+			}
+			tree.PutVersion(v.tombstone())
+		}
+	}
+	db.changed(keys...)
+	db.Logger.Debug("Expired", "keys", len(keys))
+	db.save()
+}
+
+// Lookup returns the value of the given key along with its flags and when it expires
+func (db *Instance) Lookup(id string) Result {
+	request := GetRequest{
+		ID:     id,
+		Result: make(chan Result),
+		queued: db.queue(),
+	}
+	db.getChannel <- request
+	return <-request.Result
+}
+
+// SetItem sets the value of the given key along with its flags and when it expires, it never does if expires is zero
+func (db *Instance) SetItem(id string, value string, flags uint32, expires time.Time) string {
+	request := SetRequest{
+		ID:      id,
+		Value:   value,
+		Flags:   flags,
+		Expires: expires,
+		Result:  make(chan Result),
+		queued:  db.queue(),
+	}
+	db.setChannel <- request
+	result := <-request.Result
+	return result.Value
+}
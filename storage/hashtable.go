@@ -20,8 +20,8 @@ along with Vaelen/DB.  If not, see <http://www.gnu.org/licenses/>.
 package storage
 
 import (
-	"bytes"
-	"log"
+	"log/slog"
+	"time"
 
 	"encoding/binary"
 	"hash/fnv"
@@ -31,24 +31,24 @@ import (
 type NodeLocator struct {
 	ID    uint32
 	Bytes byte
+	// Namespace is the namespace whose storage tree the node belongs to
+	Namespace string
 }
 
 // GetNodeLocator returns a NodeLocator for this ID
 func GetNodeLocator(id string) NodeLocator {
+	namespace, _ := SplitNamespace(id)
 	return NodeLocator{
-		ID:    Hash(id),
-		Bytes: 4,
+		ID:        Hash(id),
+		Bytes:     4,
+		Namespace: namespace,
 	}
 }
 
 // GetBytes returns a byte slice representing this node locator
 func (id NodeLocator) GetBytes() []byte {
-	buf := new(bytes.Buffer)
-	err := binary.Write(buf, binary.LittleEndian, id)
-	if err != nil {
-		log.Fatalf("Couldn't convert number to byte array: %s\n", err.Error())
-	}
-	b := buf.Bytes()
+	b := make([]byte, 4)
+	binary.LittleEndian.PutUint32(b, id.ID)
 	return b[:id.Bytes]
 }
 
@@ -56,6 +56,8 @@ func (id NodeLocator) GetBytes() []byte {
 type Node struct {
 	Children [256]*Node
 	values   []NodeKeyValuePair
+	// digests summarize the keys of each group in the node's subtree so that replicas can be compared one level at a time
+	digests map[string]uint64
 }
 
 // NewNode returns a new Node instance
@@ -65,10 +67,33 @@ func NewNode() *Node {
 	}
 }
 
-// NodeKeyValuePair holds a key/value pair
+// NodeKeyValuePair holds a key/value pair.
+// A key can have several pairs when concurrent versions of it exist.
 type NodeKeyValuePair struct {
-	Key   string
-	Value string
+	Key     string
+	Value   string
+	Version VersionVector
+	// Deleted marks a tombstone left behind by a replicated remove
+	Deleted bool
+	// Timestamp is the hybrid logical clock time the version was written at
+	Timestamp Timestamp
+	// Expires is when the version stops being visible, it never does when it is zero
+	Expires time.Time
+	// Flags are opaque flags that clients store with the value, like the flags of memcached items
+	Flags uint32
+	// codec is how the value is encoded while it is stored in a node.  Pairs returned by a node are always decoded.
+	codec codec
+}
+
+// Live returns true if the version holds a value that has not expired by the given time
+func (pair NodeKeyValuePair) Live(now time.Time) bool {
+	return !pair.Deleted && (pair.Expires.IsZero() || now.Before(pair.Expires))
+}
+
+// tombstone returns the tombstone that an expired version is replaced with.
+// It keeps the version's clock so that every replica sweeps the version into the same tombstone.
+func (pair NodeKeyValuePair) tombstone() NodeKeyValuePair {
+	return NodeKeyValuePair{Key: pair.Key, Version: pair.Version, Deleted: true, Timestamp: pair.Timestamp}
 }
 
 // IsLeaf returns true if this node is a leaf node
@@ -78,28 +103,84 @@ func (n *Node) IsLeaf() bool {
 
 // GetValue returns the given value from the node
 func (n *Node) GetValue(key string) string {
+	pair, _ := n.live(key, time.Now())
+	return pair.Value
+}
+
+// live returns the first version of the given key that is live at the given time
+func (n *Node) live(key string, now time.Time) (NodeKeyValuePair, bool) {
+	for _, v := range n.values {
+		if v.Key == key && v.Live(now) {
+			return v.decoded(), true
+		}
+	}
+	return NodeKeyValuePair{}, false
+}
+
+// GetVersions returns every version of the given key stored on the node, including tombstones
+func (n *Node) GetVersions(key string) []NodeKeyValuePair {
+	versions := make([]NodeKeyValuePair, 0)
+	for _, v := range n.values {
+		if v.Key == key {
+			versions = append(versions, v.decoded())
+		}
+	}
+	return versions
+}
+
+// storedVersions returns every version of the given key as it is stored on the node
+func (n *Node) storedVersions(key string) []NodeKeyValuePair {
+	versions := make([]NodeKeyValuePair, 0)
 	for _, v := range n.values {
 		if v.Key == key {
-			return v.Value
+			versions = append(versions, v)
+		}
+	}
+	return versions
+}
+
+// decodedValues returns every version stored on the node
+func (n *Node) decodedValues() []NodeKeyValuePair {
+	values := make([]NodeKeyValuePair, 0, len(n.values))
+	for _, v := range n.values {
+		values = append(values, v.decoded())
+	}
+	return values
+}
+
+// PutVersion reconciles the given version with the versions already stored on the node.
+// It returns true if the stored versions changed.
+func (n *Node) PutVersion(pair NodeKeyValuePair) bool {
+	siblings, changed := Reconcile(n.GetVersions(pair.Key), pair)
+	if changed {
+		n.RemoveValue(pair.Key)
+		for _, s := range siblings {
+			n.values = append(n.values, s.encoded())
 		}
 	}
-	var value string
-	return value
+	return changed
 }
 
 // SetValue sets the given value on the node
 func (n *Node) SetValue(key string, value string) {
-	n.RemoveValue(key)
-	n.values = append(n.values, NodeKeyValuePair{Key: key, Value: value})
+	n.setPair(NodeKeyValuePair{Key: key, Value: value})
+}
+
+// setPair replaces every version of the pair's key with the pair
+func (n *Node) setPair(pair NodeKeyValuePair) {
+	n.RemoveValue(pair.Key)
+	n.values = append(n.values, pair.encoded())
 }
 
 // RemoveValue removes the given value from the node
 func (n *Node) RemoveValue(key string) {
-	for i, v := range n.values {
-		if v.Key == key {
-			n.values = append(n.values[:i], n.values[i+1:]...)
+	values := n.values[:0]
+	for _, v := range n.values {
+		if v.Key != key {
+			values = append(values, v)
 		}
 	}
+	n.values = values
 }
 
 // IsEmpty returns true if this node is empty.  Leaf nodes are empty if they have no value.  Non-leaf nodes are empty if they have no children.
@@ -117,13 +198,30 @@ func (n *Node) IsEmpty() bool {
 // Hashtable implements a tree based hashtable
 type Hashtable struct {
 	root *Node
+	// groups splits the keys into the groups whose digests are kept apart
+	groups DigestGroups
+	// nodes is the number of nodes in the tree, kept up to date as nodes are added and removed
+	nodes int64
 }
 
 // NewHashtable creates a Hashtable instance
 func NewHashtable() *Hashtable {
 	return &Hashtable{
-		root: NewNode(),
+		root:  NewNode(),
+		nodes: 1,
+	}
+}
+
+// count returns the number of nodes in the subtree starting at the node
+func (n *Node) count() int64 {
+	if n == nil {
+		return 0
+	}
+	count := int64(1)
+	for _, child := range n.Children {
+		count += child.count()
 	}
+	return count
 }
 
 // FindNode returns a node and the path to its parent node
@@ -163,18 +261,45 @@ func (db *Hashtable) SetNodeValue(id NodeLocator, key string, value string) *Nod
 
 // SetNode sets a given node in the tree
 func (db *Hashtable) SetNode(id NodeLocator, value *Node) *Node {
-	return db.setNodeRecurse(id.GetBytes(), db.root, value)
+	if value == nil {
+		node := db.setNodeRecurse(id.GetBytes(), db.root, value)
+		db.updateDigests(id)
+		return node
+	}
+
+	// Remember the digests of the node being replaced so that the parents can be updated
+	b := id.GetBytes()
+	var old map[string]uint64
+	if path := db.pathTo(b); len(path) == len(b)+1 {
+		old = path[len(path)-1].digests
+	}
+	value.rehash(db.groups)
+	node := db.setNodeRecurse(b, db.root, value)
+	db.propagateDigest(b, db.pathTo(b), changedGroups(old, value))
+	return node
+}
+
+// rehash recalculates the digests of every node in this node's subtree
+func (n *Node) rehash(groups DigestGroups) {
+	if n == nil {
+		return
+	}
+	for _, child := range n.Children {
+		child.rehash(groups)
+	}
+	n.updateDigest(groups)
 }
 
 func (db *Hashtable) setNodeRecurse(id []byte, parent *Node, child *Node) *Node {
 	if parent == nil || len(id) == 0 {
 		// This should never happen, but just in case...
-		log.Printf("WARNING: setNodeRecurse() called with either no parent or no id.\n")
+		slog.Warn("setNodeRecurse() called with either no parent or no id")
 		return nil
 	}
 
 	if len(id) == 1 {
 		// We need to set the child on this node
+		db.nodes += child.count() - parent.Children[id[0]].count()
 		parent.Children[id[0]] = child
 		return parent.Children[id[0]]
 	}
@@ -182,6 +307,7 @@ func (db *Hashtable) setNodeRecurse(id []byte, parent *Node, child *Node) *Node
 	// Check to see if the next level has already been created and create it if necessary
 	if parent.Children[id[0]] == nil {
 		parent.Children[id[0]] = NewNode()
+		db.nodes++
 	}
 
 	// Recurse
@@ -197,8 +323,10 @@ func (db *Hashtable) RemoveNode(id NodeLocator) {
 	if node != nil {
 		parent := path[len(path)-1]
 		// Node found, remove node and prune tree
+		db.nodes -= node.count()
 		parent.Children[b[len(b)-1]] = nil
 		db.prune(b[:len(b)-1], path[:len(path)-1])
+		db.updateDigests(id)
 	}
 }
 
@@ -211,7 +339,7 @@ func (db *Hashtable) prune(id []byte, path []*Node) {
 
 	if len(id) > 1 && len(path) < 1 {
 		// This shouldn't happen
-		log.Printf("WARNING: prune() error - length of ID is %d but length of path is %d\n", len(id), len(path))
+		slog.Warn("prune() error, the lengths of the ID and path differ", "id", len(id), "path", len(path))
 		return
 	}
 
@@ -223,10 +351,11 @@ func (db *Hashtable) prune(id []byte, path []*Node) {
 	nodeID := id[len(id)-1]
 	node := parent.Children[nodeID]
 
-	if node == nil || node.IsEmpty() {
+	if node != nil && node.IsEmpty() {
 		// Prune node
 		//db.Logger.Printf("Prune: Removed node %X\n", id)
 		parent.Children[nodeID] = nil
+		db.nodes--
 	}
 
 	db.prune(id[:len(id)-1], path[:len(path)-1])
@@ -250,13 +379,61 @@ func (db *Hashtable) Get(key string) string {
 	return ""
 }
 
+// lookup returns the live value of a given key
+func (db *Hashtable) lookup(key string, now time.Time) (NodeKeyValuePair, bool) {
+	node, _ := db.FindNode(GetNodeLocator(key))
+	if node != nil {
+		return node.live(key, now)
+	}
+	return NodeKeyValuePair{}, false
+}
+
 // Set sets the value for a given key
 func (db *Hashtable) Set(key string, value string) {
+	db.SetItem(key, value, 0, time.Time{})
+}
+
+// SetItem sets the value for a given key along with its flags and when it expires
+func (db *Hashtable) SetItem(key string, value string, flags uint32, expires time.Time) {
 	id := GetNodeLocator(key)
 	node := db.SetNodeValue(id, key, value)
 	if node != nil {
-		node.SetValue(key, value)
+		node.setPair(NodeKeyValuePair{Key: key, Value: value, Expires: expires, Flags: flags})
+	}
+	db.updateDigests(id)
+}
+
+// GetVersions returns every version of a given key, including tombstones
+func (db *Hashtable) GetVersions(key string) []NodeKeyValuePair {
+	id := GetNodeLocator(key)
+	node, _ := db.FindNode(id)
+	if node != nil {
+		return node.GetVersions(key)
+	}
+	return make([]NodeKeyValuePair, 0)
+}
+
+// storedVersions returns every version of a given key as it is stored
+func (db *Hashtable) storedVersions(key string) []NodeKeyValuePair {
+	node, _ := db.FindNode(GetNodeLocator(key))
+	if node != nil {
+		return node.storedVersions(key)
+	}
+	return nil
+}
+
+// PutVersion reconciles a version of a given key with the versions already stored
+func (db *Hashtable) PutVersion(pair NodeKeyValuePair) []NodeKeyValuePair {
+	id := GetNodeLocator(pair.Key)
+	node, _ := db.FindNode(id)
+	if node == nil {
+		node = NewNode()
+		db.SetNode(id, node)
+	}
+	if node.PutVersion(pair) {
+		db.updateDigests(id)
 	}
+	return node.GetVersions(pair.Key)
 }
 
 // Remove removes a given key
@@ -266,5 +443,6 @@ func (db *Hashtable) Remove(key string) {
 	if node != nil {
 		node.RemoveValue(key)
 		db.prune(id.GetBytes(), path)
+		db.updateDigests(id)
 	}
 }
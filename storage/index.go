@@ -0,0 +1,667 @@
+/******
+This file is part of Vaelen/DB.
+
+Copyright 2017, Andrew Young <andrew@vaelen.org>
+
+    Vaelen/DB is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+    Vaelen/DB is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+    You should have received a copy of the GNU General Public License
+along with Vaelen/DB.  If not, see <http://www.gnu.org/licenses/>.
+******/
+
+package storage
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vaelen/db/logging"
+)
+
+var (
+	// ErrIndexExists is returned when an index with the same name has already been declared
+	ErrIndexExists = errors.New("index already exists")
+	// ErrUnknownIndex is returned when an index has not been declared
+	ErrUnknownIndex = errors.New("unknown index")
+	// ErrIndexBuilding is returned when an index is queried before it has finished building
+	ErrIndexBuilding = errors.New("index is still building")
+	// errIndexReplaced is returned to a build of an index that has since been dropped or declared again
+	errIndexReplaced = errors.New("index was replaced")
+)
+
+// indexSteps is the number of steps an index build is split into, one for each child of the root of the storage tree
+const indexSteps = 256
+
+// IndexDefinition declares a secondary index over a field of JSON values
+type IndexDefinition struct {
+	Name string
+	// Path is the dot separated path of the indexed field, such as "address.city" or "tags.0".  A leading "$." is ignored.
+	Path string
+}
+
+// IndexStatus describes a secondary index and how far its build has progressed
+type IndexStatus struct {
+	IndexDefinition
+	// Ready is true once every existing value has been indexed
+	Ready bool
+	// Progress is the fraction of the storage tree that has been indexed, between 0 and 1
+	Progress float64
+	// Entries is the number of values in the index
+	Entries int
+}
+
+// IndexQuery selects entries from a secondary index.
+// The values are JSON literals.  If Equal is set only entries equal to it match, otherwise entries between Min and Max match.
+// An empty bound is open, and only entries of the same JSON type as the bounds are matched.
+type IndexQuery struct {
+	Index        string
+	Equal        string
+	Min          string
+	Max          string
+	MinExclusive bool
+	MaxExclusive bool
+	// Namespace restricts the entries to the keys of one namespace
+	Namespace string
+	// Limit is the maximum number of entries returned, or zero for no limit
+	Limit int
+}
+
+// IndexEntry is a key matched by an index query along with the value of its indexed field as a JSON literal
+type IndexEntry struct {
+	Key   string
+	Value string
+}
+
+// indexKind orders values of different JSON types
+type indexKind int
+
+const (
+	nullKind indexKind = iota
+	boolKind
+	numberKind
+	stringKind
+)
+
+// indexValue is an indexed JSON value
+type indexValue struct {
+	kind   indexKind
+	number float64
+	text   string
+}
+
+// newIndexValue converts a decoded JSON value, returning false for objects and arrays
+func newIndexValue(v interface{}) (indexValue, bool) {
+	switch x := v.(type) {
+	case nil:
+		return indexValue{kind: nullKind}, true
+	case bool:
+		if x {
+			return indexValue{kind: boolKind, number: 1}, true
+		}
+		return indexValue{kind: boolKind}, true
+	case float64:
+		return indexValue{kind: numberKind, number: x}, true
+	case string:
+		return indexValue{kind: stringKind, text: x}, true
+	}
+	return indexValue{}, false
+}
+
+// parseIndexValue parses a JSON literal
+func parseIndexValue(literal string) (indexValue, error) {
+	var v interface{}
+	if err := json.Unmarshal([]byte(literal), &v); err != nil {
+		return indexValue{}, fmt.Errorf("invalid index value %q: %s", literal, err)
+	}
+	value, ok := newIndexValue(v)
+	if !ok {
+		return indexValue{}, fmt.Errorf("invalid index value %q: objects and arrays can not be indexed", literal)
+	}
+	return value, nil
+}
+
+// compare returns -1, 0 or 1 if the value sorts before, equal to or after the given value
+func (v indexValue) compare(o indexValue) int {
+	switch {
+	case v.kind != o.kind:
+		if v.kind < o.kind {
+			return -1
+		}
+		return 1
+	case v.kind == stringKind:
+		return strings.Compare(v.text, o.text)
+	case v.number < o.number:
+		return -1
+	case v.number > o.number:
+		return 1
+	}
+	return 0
+}
+
+// String returns the value as a JSON literal
+func (v indexValue) String() string {
+	switch v.kind {
+	case boolKind:
+		return strconv.FormatBool(v.number != 0)
+	case numberKind:
+		return strconv.FormatFloat(v.number, 'g', -1, 64)
+	case stringKind:
+		b, _ := json.Marshal(v.text)
+		return string(b)
+	}
+	return "null"
+}
+
+// indexEntry is a single value in an index
+type indexEntry struct {
+	value indexValue
+	key   string
+}
+
+func (e indexEntry) less(o indexEntry) bool {
+	if c := e.value.compare(o.value); c != 0 {
+		return c < 0
+	}
+	return e.key < o.key
+}
+
+// index is a secondary index.  It must only be used from the storage thread.
+type index struct {
+	IndexDefinition
+	path []string
+	// entries holds the indexed values sorted by value and then by key
+	entries []indexEntry
+	// values holds the values indexed for each key, a key has several when it has siblings
+	values map[string][]indexValue
+	// built is the number of build steps that have completed
+	built int
+	// generation tells this declaration of the index apart from earlier ones with the same name
+	generation uint64
+}
+
+// parseIndexPath splits an index path into its fields
+func parseIndexPath(path string) ([]string, error) {
+	path = strings.TrimPrefix(strings.TrimPrefix(path, "$"), ".")
+	if path == "" {
+		return nil, fmt.Errorf("invalid index path: %q", path)
+	}
+	fields := strings.Split(path, ".")
+	for _, f := range fields {
+		if f == "" {
+			return nil, fmt.Errorf("invalid index path: %q", path)
+		}
+	}
+	return fields, nil
+}
+
+func newIndex(definition IndexDefinition) (*index, error) {
+	if definition.Name == "" {
+		return nil, errors.New("an index must have a name")
+	}
+	path, err := parseIndexPath(definition.Path)
+	if err != nil {
+		return nil, err
+	}
+	return &index{
+		IndexDefinition: definition,
+		path:            path,
+		entries:         make([]indexEntry, 0),
+		values:          make(map[string][]indexValue),
+	}, nil
+}
+
+// extract returns the value of the indexed field, returning false if the value is not JSON or does not have the field
+func (i *index) extract(value string) (indexValue, bool) {
+	var v interface{}
+	if err := json.Unmarshal([]byte(value), &v); err != nil {
+		return indexValue{}, false
+	}
+	for _, field := range i.path {
+		switch x := v.(type) {
+		case map[string]interface{}:
+			var ok bool
+			if v, ok = x[field]; !ok {
+				return indexValue{}, false
+			}
+		case []interface{}:
+			n, err := strconv.Atoi(field)
+			if err != nil || n < 0 || n >= len(x) {
+				return indexValue{}, false
+			}
+			v = x[n]
+		default:
+			return indexValue{}, false
+		}
+	}
+	return newIndexValue(v)
+}
+
+// update replaces the entries for a key with the indexed field of each of its live versions
+func (i *index) update(key string, versions []NodeKeyValuePair) {
+	for _, value := range i.values[key] {
+		i.removeEntry(indexEntry{value: value, key: key})
+	}
+	delete(i.values, key)
+	for _, v := range versions {
+		if v.Deleted {
+			continue
+		}
+		value, ok := i.extract(v.Value)
+		if !ok || i.has(key, value) {
+			continue
+		}
+		i.values[key] = append(i.values[key], value)
+		i.insertEntry(indexEntry{value: value, key: key})
+	}
+}
+
+func (i *index) has(key string, value indexValue) bool {
+	for _, v := range i.values[key] {
+		if v.compare(value) == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func (i *index) search(entry indexEntry) int {
+	return sort.Search(len(i.entries), func(n int) bool {
+		return !i.entries[n].less(entry)
+	})
+}
+
+func (i *index) insertEntry(entry indexEntry) {
+	n := i.search(entry)
+	i.entries = append(i.entries, indexEntry{})
+	copy(i.entries[n+1:], i.entries[n:])
+	i.entries[n] = entry
+}
+
+func (i *index) removeEntry(entry indexEntry) {
+	n := i.search(entry)
+	if n < len(i.entries) && i.entries[n].key == entry.key && i.entries[n].value.compare(entry.value) == 0 {
+		i.entries = append(i.entries[:n], i.entries[n+1:]...)
+	}
+}
+
+func (i *index) status() IndexStatus {
+	return IndexStatus{
+		IndexDefinition: i.IndexDefinition,
+		Ready:           i.built >= indexSteps,
+		Progress:        float64(i.built) / indexSteps,
+		Entries:         len(i.entries),
+	}
+}
+
+// query returns the entries matching the given query
+func (i *index) query(q IndexQuery) ([]IndexEntry, error) {
+	var min, max *indexValue
+	if q.Equal != "" {
+		v, err := parseIndexValue(q.Equal)
+		if err != nil {
+			return nil, err
+		}
+		min, max = &v, &v
+		q.MinExclusive, q.MaxExclusive = false, false
+	} else {
+		if q.Min != "" {
+			v, err := parseIndexValue(q.Min)
+			if err != nil {
+				return nil, err
+			}
+			min = &v
+		}
+		if q.Max != "" {
+			v, err := parseIndexValue(q.Max)
+			if err != nil {
+				return nil, err
+			}
+			max = &v
+		}
+	}
+
+	var kind indexKind
+	switch {
+	case min != nil:
+		kind = min.kind
+	case max != nil:
+		kind = max.kind
+	}
+	if min != nil && max != nil && min.kind != max.kind {
+		return nil, errors.New("index query bounds must have the same type")
+	}
+
+	start := 0
+	if min != nil {
+		start = sort.Search(len(i.entries), func(n int) bool {
+			c := i.entries[n].value.compare(*min)
+			return c > 0 || (c == 0 && !q.MinExclusive)
+		})
+	}
+	results := make([]IndexEntry, 0)
+	for _, e := range i.entries[start:] {
+		if q.Limit > 0 && len(results) >= q.Limit {
+			break
+		}
+		if namespace, _ := SplitNamespace(e.key); namespace != q.Namespace {
+			continue
+		}
+		if max != nil {
+			c := e.value.compare(*max)
+			if c > 0 || (c == 0 && q.MaxExclusive) {
+				break
+			}
+		}
+		if (min != nil || max != nil) && e.value.kind != kind {
+			if e.value.kind > kind {
+				break
+			}
+			continue
+		}
+		results = append(results, IndexEntry{Key: e.key, Value: e.value.String()})
+	}
+	return results, nil
+}
+
+// MergeIndexEntries combines the results of the same query from several storage instances.
+// Each key is returned once and the entries are sorted by value and then by key.
+func MergeIndexEntries(results [][]IndexEntry, limit int) []IndexEntry {
+	seen := make(map[string]bool)
+	entries := make([]indexEntry, 0)
+	for _, result := range results {
+		for _, e := range result {
+			if seen[e.Key] {
+				continue
+			}
+			value, err := parseIndexValue(e.Value)
+			if err != nil {
+				continue
+			}
+			seen[e.Key] = true
+			entries = append(entries, indexEntry{value: value, key: e.Key})
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].less(entries[j])
+	})
+	if limit > 0 && len(entries) > limit {
+		entries = entries[:limit]
+	}
+	merged := make([]IndexEntry, 0, len(entries))
+	for _, e := range entries {
+		merged = append(merged, IndexEntry{Key: e.key, Value: e.value.String()})
+	}
+	return merged
+}
+
+type indexAction int
+
+const (
+	createIndex indexAction = iota
+	dropIndex
+	buildIndex
+	indexStatus
+	queryIndex
+)
+
+// IndexRequest is used to declare, drop, build and query secondary indexes.
+type IndexRequest struct {
+	Definition IndexDefinition
+	Query      IndexQuery
+	action     indexAction
+	step       int
+	generation uint64
+	Result     chan IndexResult
+}
+
+// IndexResult is returned from index requests
+type IndexResult struct {
+	Status     []IndexStatus
+	Entries    []IndexEntry
+	Err        error
+	generation uint64
+}
+
+// handleIndex applies an index request.  It must only be called from the storage thread.
+func (db *Instance) handleIndex(request IndexRequest) IndexResult {
+	switch request.action {
+	case createIndex:
+		if _, ok := db.indexes[request.Definition.Name]; ok {
+			return IndexResult{Err: ErrIndexExists}
+		}
+		i, err := newIndex(request.Definition)
+		if err != nil {
+			return IndexResult{Err: err}
+		}
+		db.indexGeneration++
+		i.generation = db.indexGeneration
+		db.indexes[i.Name] = i
+		db.saveIndexes()
+		return IndexResult{Status: []IndexStatus{i.status()}, generation: i.generation}
+	case dropIndex:
+		i, ok := db.indexes[request.Definition.Name]
+		if !ok {
+			return IndexResult{Err: ErrUnknownIndex}
+		}
+		delete(db.indexes, i.Name)
+		db.saveIndexes()
+		return IndexResult{Status: []IndexStatus{i.status()}}
+	case buildIndex:
+		i, ok := db.indexes[request.Definition.Name]
+		if !ok {
+			return IndexResult{Err: ErrUnknownIndex}
+		}
+		if i.generation != request.generation {
+			return IndexResult{Err: errIndexReplaced}
+		}
+		for _, t := range db.trees() {
+			if request.step == 0 {
+				db.indexNode(i, t.root, false)
+			}
+			db.indexNode(i, t.root.Children[request.step], true)
+		}
+		if request.step+1 > i.built {
+			i.built = request.step + 1
+		}
+		return IndexResult{Status: []IndexStatus{i.status()}}
+	case indexStatus:
+		status := make([]IndexStatus, 0, len(db.indexes))
+		for _, i := range db.indexes {
+			status = append(status, i.status())
+		}
+		sort.Slice(status, func(a, b int) bool {
+			return status[a].Name < status[b].Name
+		})
+		return IndexResult{Status: status}
+	case queryIndex:
+		i, ok := db.indexes[request.Query.Index]
+		if !ok {
+			return IndexResult{Err: ErrUnknownIndex}
+		}
+		if i.built < indexSteps {
+			return IndexResult{Err: ErrIndexBuilding}
+		}
+		entries, err := i.query(request.Query)
+		return IndexResult{Entries: entries, Err: err}
+	}
+	return IndexResult{}
+}
+
+// indexNode adds the values stored on a node, and optionally on its descendants, to an index
+func (db *Instance) indexNode(i *index, n *Node, recurse bool) {
+	if n == nil {
+		return
+	}
+	for _, key := range n.keys() {
+		i.update(key, n.GetVersions(key))
+	}
+	if !recurse {
+		return
+	}
+	for _, child := range n.Children {
+		db.indexNode(i, child, true)
+	}
+}
+
+// reindex updates every index with the current versions of the given keys.  It must only be called from the storage thread.
+func (db *Instance) reindex(keys ...string) {
+	if len(db.indexes) == 0 {
+		return
+	}
+	for _, key := range keys {
+		versions := db.findFor(key).GetVersions(key)
+		for _, i := range db.indexes {
+			i.update(key, versions)
+		}
+	}
+}
+
+// keys returns the distinct keys stored on a node
+func (n *Node) keys() []string {
+	seen := make(map[string]bool)
+	keys := make([]string, 0, len(n.values))
+	for _, v := range n.values {
+		if !seen[v.Key] {
+			seen[v.Key] = true
+			keys = append(keys, v.Key)
+		}
+	}
+	return keys
+}
+
+// subtreeKeys returns the distinct keys stored on a node and its descendants
+func (n *Node) subtreeKeys() []string {
+	if n == nil {
+		return nil
+	}
+	keys := n.keys()
+	for _, child := range n.Children {
+		keys = append(keys, child.subtreeKeys()...)
+	}
+	return keys
+}
+
+func (db *Instance) sendIndex(request IndexRequest) IndexResult {
+	request.Result = make(chan IndexResult)
+	db.indexChannel <- request
+	return <-request.Result
+}
+
+// CreateIndex declares a secondary index and starts building it from the existing values in the background.
+// Values written after this returns are indexed as they are written.
+func (db *Instance) CreateIndex(definition IndexDefinition) (IndexStatus, error) {
+	result := db.sendIndex(IndexRequest{Definition: definition, action: createIndex})
+	if result.Err != nil {
+		return IndexStatus{}, result.Err
+	}
+	go db.build(definition.Name, result.generation)
+	return result.Status[0], nil
+}
+
+// build indexes the existing values one subtree at a time so that other requests are not blocked for long.
+// It stops if the index is dropped or declared again, which starts a build of its own.
+func (db *Instance) build(name string, generation uint64) {
+	db.Logger.Info("Building index", "index", name)
+	for step := 0; step < indexSteps; step++ {
+		result := db.sendIndex(IndexRequest{Definition: IndexDefinition{Name: name}, action: buildIndex, step: step, generation: generation})
+		if result.Err != nil {
+			db.Logger.Warn("Index build stopped", "index", name, "error", result.Err)
+			return
+		}
+	}
+	db.Logger.Info("Index built", "index", name)
+}
+
+// DropIndex removes a secondary index
+func (db *Instance) DropIndex(name string) error {
+	return db.sendIndex(IndexRequest{Definition: IndexDefinition{Name: name}, action: dropIndex}).Err
+}
+
+// Indexes returns the status of every secondary index, sorted by name
+func (db *Instance) Indexes() []IndexStatus {
+	return db.sendIndex(IndexRequest{action: indexStatus}).Status
+}
+
+// QueryIndex returns the keys matching the given query along with their indexed values, sorted by value and then by key.
+// ErrIndexBuilding is returned until the index has finished building.
+func (db *Instance) QueryIndex(query IndexQuery) ([]IndexEntry, error) {
+	result := db.sendIndex(IndexRequest{Query: query, action: queryIndex})
+	return result.Entries, result.Err
+}
+
+func (db *Instance) indexesFilename() string {
+	return filepath.Join(db.Path, "indexes.gob")
+}
+
+// saveIndexes writes the index definitions to disk.  It must only be called from the storage thread.
+func (db *Instance) saveIndexes() error {
+	if db.Path == "" {
+		return nil
+	}
+	defer db.definitionWrites.record(time.Now())
+	definitions := make([]IndexDefinition, 0, len(db.indexes))
+	for _, i := range db.indexes {
+		definitions = append(definitions, i.IndexDefinition)
+	}
+	filename := db.indexesFilename()
+	var b bytes.Buffer
+	err := gob.NewEncoder(&b).Encode(definitions)
+	if err == nil {
+		err = db.Keyring.WriteFile(filename, b.Bytes())
+	}
+	if err != nil {
+		db.Logger.Error("Could not save index definitions", "file", filename, "error", err)
+	}
+	return err
+}
+
+// loadIndexes declares the indexes saved on disk and rebuilds them.  It must only be called from the storage thread.
+func (db *Instance) loadIndexes() {
+	if db.Path == "" {
+		return
+	}
+	filename := db.indexesFilename()
+	b, err := db.Keyring.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return
+	}
+	if IsKeyError(err) {
+		logging.Fatal(db.Logger, "Could not decrypt index definitions", "file", filename, "error", err)
+		return
+	}
+	if err != nil {
+		db.Logger.Warn("Could not open index definitions", "file", filename, "error", err)
+		return
+	}
+	definitions := make([]IndexDefinition, 0)
+	err = gob.NewDecoder(bytes.NewReader(b)).Decode(&definitions)
+	if err != nil {
+		db.Logger.Error("Could not load index definitions", "file", filename, "error", err)
+		return
+	}
+	for _, definition := range definitions {
+		result := db.handleIndex(IndexRequest{Definition: definition, action: createIndex})
+		if result.Err != nil {
+			db.Logger.Error("Could not declare index", "index", definition.Name, "error", result.Err)
+			continue
+		}
+		go db.build(definition.Name, result.generation)
+	}
+}
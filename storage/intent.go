@@ -0,0 +1,150 @@
+/******
+This file is part of Vaelen/DB.
+
+Copyright 2017, Andrew Young <andrew@vaelen.org>
+
+    Vaelen/DB is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+    Vaelen/DB is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+    You should have received a copy of the GNU General Public License
+along with Vaelen/DB.  If not, see <http://www.gnu.org/licenses/>.
+******/
+
+package storage
+
+import (
+	"errors"
+)
+
+// ErrIntentConflict is returned when a key already has an intent from another transaction
+var ErrIntentConflict = errors.New("key has an intent from another transaction")
+
+// ErrVersionConflict is returned when a key's versions are not the ones an intent expects
+var ErrVersionConflict = errors.New("key has changed")
+
+type intentAction int
+
+const (
+	prepareIntent intentAction = iota
+	commitIntent
+	abortIntent
+)
+
+// IntentRequest is used to prepare, commit or abort the intents of a transaction.
+type IntentRequest struct {
+	TxID     string
+	Versions []NodeKeyValuePair
+	// Expected holds the digest that the stored versions of each conditional key must have, see DigestVersions
+	Expected map[string]uint64
+	action   intentAction
+	Result   chan error
+	queued   queued
+}
+
+// intents holds the writes prepared by transactions that have not been committed or aborted
+type intents struct {
+	// writes holds the prepared versions of each transaction
+	writes map[string][]NodeKeyValuePair
+	// locks holds the transaction that has an intent on each key
+	locks map[string]string
+}
+
+func newIntents() *intents {
+	return &intents{
+		writes: make(map[string][]NodeKeyValuePair),
+		locks:  make(map[string]string),
+	}
+}
+
+// handleIntent applies an intent request.  It must only be called from the storage thread.
+func (db *Instance) handleIntent(request IntentRequest) error {
+	switch request.action {
+	case prepareIntent:
+		for _, v := range request.Versions {
+			if owner, ok := db.intents.locks[v.Key]; ok && owner != request.TxID {
+				return ErrIntentConflict
+			}
+		}
+		for _, v := range request.Versions {
+			if expected, ok := request.Expected[v.Key]; ok && DigestVersions(db.findFor(v.Key).GetVersions(v.Key)) != expected {
+				return ErrVersionConflict
+			}
+		}
+		if err := db.checkQuota(request.TxID, request.Versions); err != nil {
+			return err
+		}
+		for _, v := range request.Versions {
+			db.intents.locks[v.Key] = request.TxID
+			db.intents.writes[request.TxID] = replaceIntent(db.intents.writes[request.TxID], v)
+		}
+	case commitIntent:
+		for _, v := range db.intents.writes[request.TxID] {
+			db.treeFor(v.Key).PutVersion(v)
+			db.changed(v.Key)
+		}
+		db.releaseIntent(request.TxID)
+	case abortIntent:
+		db.releaseIntent(request.TxID)
+	}
+	return nil
+}
+
+// replaceIntent replaces the intent for the given version's key
+func replaceIntent(writes []NodeKeyValuePair, v NodeKeyValuePair) []NodeKeyValuePair {
+	replaced := make([]NodeKeyValuePair, 0, len(writes)+1)
+	for _, w := range writes {
+		if w.Key != v.Key {
+			replaced = append(replaced, w)
+		}
+	}
+	return append(replaced, v)
+}
+
+// releaseIntent forgets the intents of a transaction and unlocks its keys
+func (db *Instance) releaseIntent(txid string) {
+	for _, v := range db.intents.writes[txid] {
+		if db.intents.locks[v.Key] == txid {
+			delete(db.intents.locks, v.Key)
+		}
+	}
+	delete(db.intents.writes, txid)
+}
+
+func (db *Instance) sendIntent(request IntentRequest) error {
+	request.Result = make(chan error)
+	request.queued = db.queue()
+	db.intentChannel <- request
+	return <-request.Result
+}
+
+// PrepareIntent records the given versions as the intents of a transaction and locks their keys.
+// Preparing the same transaction again adds to its intents.
+// ErrIntentConflict is returned if another transaction already holds one of the keys,
+// and a QuotaError if the intents would take a namespace over its quota, see SetQuotas.
+func (db *Instance) PrepareIntent(txid string, versions []NodeKeyValuePair) error {
+	return db.PrepareIntentIf(txid, versions, nil)
+}
+
+// PrepareIntentIf is like PrepareIntent, but only prepares the intents if the stored versions of each key in expected
+// still have the given digest.  ErrVersionConflict is returned if one of them does not.
+func (db *Instance) PrepareIntentIf(txid string, versions []NodeKeyValuePair, expected map[string]uint64) error {
+	return db.sendIntent(IntentRequest{TxID: txid, Versions: versions, Expected: expected, action: prepareIntent})
+}
+
+// CommitIntent writes the intents of a transaction to storage and unlocks their keys.
+// Committing an unknown transaction does nothing.
+func (db *Instance) CommitIntent(txid string) {
+	db.sendIntent(IntentRequest{TxID: txid, action: commitIntent})
+}
+
+// AbortIntent discards the intents of a transaction and unlocks their keys
+func (db *Instance) AbortIntent(txid string) {
+	db.sendIntent(IntentRequest{TxID: txid, action: abortIntent})
+}
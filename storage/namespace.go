@@ -0,0 +1,336 @@
+/******
+This file is part of Vaelen/DB.
+
+Copyright 2017, Andrew Young <andrew@vaelen.org>
+
+    Vaelen/DB is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+    Vaelen/DB is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+    You should have received a copy of the GNU General Public License
+along with Vaelen/DB.  If not, see <http://www.gnu.org/licenses/>.
+******/
+
+package storage
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// NamespacePrefix starts the internal keys of every namespace other than the default one.
+// The key "x" in the namespace "team" is stored as "__ns/team/x" in a storage tree of its own.
+const NamespacePrefix = "__ns/"
+
+// validNamespace matches the names that namespaces may have
+var validNamespace = regexp.MustCompile(`^[A-Za-z0-9_.-]{1,64}$`)
+
+// ValidNamespace returns true if the given name can be used for a namespace
+func ValidNamespace(name string) bool {
+	return validNamespace.MatchString(name)
+}
+
+// NamespaceKey returns the internal key of a key in the given namespace.  Keys in the default namespace, "", are unchanged.
+func NamespaceKey(namespace string, key string) string {
+	if namespace == "" {
+		return key
+	}
+	return NamespacePrefix + namespace + "/" + key
+}
+
+// SplitNamespace returns the namespace of an internal key and the key within that namespace
+func SplitNamespace(key string) (string, string) {
+	if !strings.HasPrefix(key, NamespacePrefix) {
+		return "", key
+	}
+	rest := key[len(NamespacePrefix):]
+	i := strings.Index(rest, "/")
+	if i <= 0 {
+		return "", key
+	}
+	return rest[:i], rest[i+1:]
+}
+
+// Usage is the amount of data a namespace stores.  Only live values are counted.
+type Usage struct {
+	Keys int64
+	// Bytes is the size of the keys and values as they were written
+	Bytes int64
+	// Stored is the size of the keys and values after compression
+	Stored int64
+	// Compressed is the number of values that are stored compressed
+	Compressed int64
+	// Nodes is the number of nodes in the namespace's storage tree
+	Nodes int64
+}
+
+// add adds the given usage to the usage
+func (u *Usage) add(o Usage) {
+	u.Keys += o.Keys
+	u.Bytes += o.Bytes
+	u.Stored += o.Stored
+	u.Compressed += o.Compressed
+}
+
+// subtract removes the given usage from the usage
+func (u *Usage) subtract(o Usage) {
+	u.add(Usage{Keys: -o.Keys, Bytes: -o.Bytes, Stored: -o.Stored, Compressed: -o.Compressed})
+}
+
+// namespaceTree is the storage tree of one namespace along with its usage
+type namespaceTree struct {
+	*Hashtable
+	// sizes holds the usage of the live values of each key
+	sizes map[string]Usage
+	usage Usage
+}
+
+func newNamespaceTree() *namespaceTree {
+	return &namespaceTree{
+		Hashtable: NewHashtable(),
+		sizes:     make(map[string]Usage),
+	}
+}
+
+// account updates the usage of the tree with the current versions of the given keys
+func (t *namespaceTree) account(keys ...string) {
+	for _, key := range keys {
+		if size, ok := t.sizes[key]; ok {
+			t.usage.subtract(size)
+			delete(t.sizes, key)
+		}
+		size := Usage{}
+		for _, v := range t.storedVersions(key) {
+			if !v.Deleted {
+				size.Keys = 1
+				size.Bytes += int64(len(v.Key) + len(v.decoded().Value))
+				size.Stored += int64(len(v.Key) + len(v.Value))
+				if v.codec != codecNone {
+					size.Compressed++
+				}
+			}
+		}
+		if size.Keys > 0 {
+			t.sizes[key] = size
+			t.usage.add(size)
+		}
+	}
+}
+
+// current returns the usage of the tree along with its number of nodes
+func (t *namespaceTree) current() Usage {
+	usage := t.usage
+	usage.Nodes = t.nodes
+	return usage
+}
+
+// Quota limits how much a namespace stores, zero is unlimited
+type Quota struct {
+	MaxKeys  int64
+	MaxBytes int64
+}
+
+// QuotaError is returned when preparing intents would take a namespace over its quota
+type QuotaError struct {
+	Namespace   string
+	Description string
+}
+
+func (e *QuotaError) Error() string {
+	return e.Description
+}
+
+// checkQuota returns a QuotaError if the intents of a transaction write a value to a namespace that the intents
+// would take over its quota, once the intents prepared by every other transaction are applied as well.
+// Intents that only remove values are always allowed.  It must only be called from the storage thread.
+func (db *Instance) checkQuota(txid string, versions []NodeKeyValuePair) error {
+	// pending holds the latest intent on each key, with the given versions replacing the transaction's earlier ones
+	pending := make(map[string]NodeKeyValuePair)
+	for id, writes := range db.intents.writes {
+		if id == txid {
+			continue
+		}
+		for _, v := range writes {
+			pending[v.Key] = v
+		}
+	}
+	for _, v := range db.intents.writes[txid] {
+		pending[v.Key] = v
+	}
+	for _, v := range versions {
+		pending[v.Key] = v
+	}
+	checked := make(map[string]bool)
+	for _, v := range versions {
+		namespace, _ := SplitNamespace(v.Key)
+		quota, ok := db.quotas[namespace]
+		if v.Deleted || !ok || checked[namespace] {
+			continue
+		}
+		checked[namespace] = true
+		t := db.find(namespace)
+		usage := t.usage
+		for key, p := range pending {
+			if ns, _ := SplitNamespace(key); ns != namespace {
+				continue
+			}
+			usage.subtract(t.sizes[key])
+			if !p.Deleted {
+				usage.add(Usage{Keys: 1, Bytes: int64(len(p.Key) + len(p.Value))})
+			}
+		}
+		if quota.MaxKeys > 0 && usage.Keys > quota.MaxKeys {
+			return &QuotaError{Namespace: namespace, Description: fmt.Sprintf("namespace %s has reached its limit of %d keys", namespace, quota.MaxKeys)}
+		}
+		if quota.MaxBytes > 0 && usage.Bytes > quota.MaxBytes {
+			return &QuotaError{Namespace: namespace, Description: fmt.Sprintf("namespace %s would exceed its limit of %d bytes", namespace, quota.MaxBytes)}
+		}
+	}
+	return nil
+}
+
+type namespaceAction int
+
+const (
+	namespaceUsage namespaceAction = iota
+	dropNamespace
+	setQuotas
+)
+
+// NamespaceRequest is used to read the usage of namespaces, set their quotas or drop one
+type NamespaceRequest struct {
+	action namespaceAction
+	Name   string
+	Quotas map[string]Quota
+	Result chan map[string]Usage
+}
+
+// tree returns the storage tree of a namespace, creating it if needed.  It must only be called from the storage thread.
+func (db *Instance) tree(namespace string) *namespaceTree {
+	if namespace == "" {
+		return db.storage
+	}
+	t, ok := db.namespaces[namespace]
+	if !ok {
+		t = newNamespaceTree()
+		t.groups = db.groups
+		db.namespaces[namespace] = t
+	}
+	return t
+}
+
+// setGroups changes how the keys of every tree are grouped for their digests.  It must only be called from the storage thread.
+func (db *Instance) setGroups(groups DigestGroups) {
+	db.groups = groups
+	db.empty.groups = groups
+	for _, t := range db.trees() {
+		t.SetGroups(groups)
+	}
+}
+
+// find returns the storage tree of a namespace for reading.  Namespaces without a tree get an empty tree that must not be written to,
+// so that reading a dropped namespace does not bring its tree back.  It must only be called from the storage thread.
+func (db *Instance) find(namespace string) *namespaceTree {
+	if namespace == "" {
+		return db.storage
+	}
+	if t, ok := db.namespaces[namespace]; ok {
+		return t
+	}
+	return db.empty
+}
+
+// treeFor returns the storage tree that holds the given internal key, creating it if needed.  It must only be called from the storage thread.
+func (db *Instance) treeFor(key string) *namespaceTree {
+	namespace, _ := SplitNamespace(key)
+	return db.tree(namespace)
+}
+
+// findFor returns the storage tree that holds the given internal key for reading.  It must only be called from the storage thread.
+func (db *Instance) findFor(key string) *namespaceTree {
+	namespace, _ := SplitNamespace(key)
+	return db.find(namespace)
+}
+
+// trees returns every storage tree, starting with the default namespace.  It must only be called from the storage thread.
+func (db *Instance) trees() []*namespaceTree {
+	names := make([]string, 0, len(db.namespaces))
+	for name := range db.namespaces {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	trees := []*namespaceTree{db.storage}
+	for _, name := range names {
+		trees = append(trees, db.namespaces[name])
+	}
+	return trees
+}
+
+// changed accounts for and reindexes keys whose versions have changed.  It must only be called from the storage thread.
+func (db *Instance) changed(keys ...string) {
+	for _, key := range keys {
+		db.findFor(key).account(key)
+	}
+	db.track(keys...)
+	db.reindex(keys...)
+}
+
+// handleNamespace applies a namespace request.  It must only be called from the storage thread.
+func (db *Instance) handleNamespace(request NamespaceRequest) map[string]Usage {
+	usage := make(map[string]Usage)
+	switch request.action {
+	case namespaceUsage:
+		usage[""] = db.storage.current()
+		for name, t := range db.namespaces {
+			usage[name] = t.current()
+		}
+	case dropNamespace:
+		t, ok := db.namespaces[request.Name]
+		if !ok {
+			break
+		}
+		usage[request.Name] = t.usage
+		// The tree is discarded as a whole, only the indexes have to forget its keys one at a time
+		delete(db.namespaces, request.Name)
+		if len(db.indexes) > 0 {
+			for _, key := range t.root.subtreeKeys() {
+				for _, i := range db.indexes {
+					i.update(key, nil)
+				}
+			}
+		}
+		db.save()
+	case setQuotas:
+		db.quotas = request.Quotas
+	}
+	return usage
+}
+
+func (db *Instance) sendNamespace(request NamespaceRequest) map[string]Usage {
+	request.Result = make(chan map[string]Usage)
+	db.namespaceChannel <- request
+	return <-request.Result
+}
+
+// Namespaces returns the usage of every namespace that has a storage tree, including the default namespace
+func (db *Instance) Namespaces() map[string]Usage {
+	return db.sendNamespace(NamespaceRequest{action: namespaceUsage})
+}
+
+// SetQuotas replaces the quotas of every namespace.  They are checked when intents are prepared, see PrepareIntent.
+func (db *Instance) SetQuotas(quotas map[string]Quota) {
+	db.sendNamespace(NamespaceRequest{action: setQuotas, Quotas: quotas})
+}
+
+// DropNamespace discards the storage tree of a namespace and returns what it held
+func (db *Instance) DropNamespace(name string) Usage {
+	return db.sendNamespace(NamespaceRequest{action: dropNamespace, Name: name})[name]
+}
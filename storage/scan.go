@@ -0,0 +1,110 @@
+/******
+This file is part of Vaelen/DB.
+
+Copyright 2017, Andrew Young <andrew@vaelen.org>
+
+    Vaelen/DB is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+    Vaelen/DB is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+    You should have received a copy of the GNU General Public License
+along with Vaelen/DB.  If not, see <http://www.gnu.org/licenses/>.
+******/
+
+package storage
+
+import (
+	"math"
+	"time"
+)
+
+// ScanRequest is used to list the keys in the storage tree a page at a time.
+type ScanRequest struct {
+	// Namespace is the namespace whose keys are listed
+	Namespace string
+	Cursor    uint32
+	Count     int
+	Result    chan ScanResult
+}
+
+// ScanResult is returned from Scan
+type ScanResult struct {
+	Keys []string
+	// Next is the cursor to continue from, or zero once every key has been returned
+	Next uint32
+}
+
+// Scan returns the keys of leaves at or after the cursor, stopping once at least count keys have been found.
+// Cursors are positions in the storage tree rather than keys, so a scan returns every key that exists for its whole duration
+// even if other keys are added or removed in the meantime.  Tombstones are skipped.
+func (db *Hashtable) Scan(cursor uint32, count int) ([]string, uint32) {
+	keys := make([]string, 0, count)
+	last, full := scanNode(db.root, 0, 0, cursor, count, &keys)
+	if !full || last == math.MaxUint32 {
+		return keys, 0
+	}
+	return keys, last + 1
+}
+
+// scanNode adds the live keys in a node's subtree that are at or after the cursor.
+// The position of a leaf is the bytes of its path read as a big endian number.
+// It returns the position of the last leaf visited and true once count keys have been found.
+func scanNode(n *Node, depth uint, position uint32, cursor uint32, count int, keys *[]string) (uint32, bool) {
+	if depth == 4 {
+		for _, key := range n.keys() {
+			if hasLiveVersion(n.GetVersions(key)) {
+				*keys = append(*keys, key)
+			}
+		}
+		return position, len(*keys) >= count
+	}
+	shift := 8 * (3 - depth)
+	for b, child := range n.Children {
+		if child == nil {
+			continue
+		}
+		p := position | uint32(b)<<shift
+		if p|(1<<shift-1) < cursor {
+			// Every leaf in this subtree is before the cursor
+			continue
+		}
+		if last, full := scanNode(child, depth+1, p, cursor, count, keys); full {
+			return last, true
+		}
+	}
+	return 0, false
+}
+
+func hasLiveVersion(versions []NodeKeyValuePair) bool {
+	now := time.Now()
+	for _, v := range versions {
+		if v.Live(now) {
+			return true
+		}
+	}
+	return false
+}
+
+// Scan returns a page of keys starting at the given cursor along with the cursor of the next page, which is zero after the last page
+func (db *Instance) Scan(cursor uint32, count int) ([]string, uint32) {
+	return db.ScanNamespace("", cursor, count)
+}
+
+// ScanNamespace is like Scan but lists the internal keys of the given namespace
+func (db *Instance) ScanNamespace(namespace string, cursor uint32, count int) ([]string, uint32) {
+	request := ScanRequest{
+		Namespace: namespace,
+		Cursor:    cursor,
+		Count:     count,
+		Result:    make(chan ScanResult),
+	}
+	db.scanChannel <- request
+	result := <-request.Result
+	return result.Keys, result.Next
+}
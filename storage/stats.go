@@ -0,0 +1,66 @@
+/******
+This file is part of Vaelen/DB.
+
+Copyright 2017, Andrew Young <andrew@vaelen.org>
+
+    Vaelen/DB is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+    Vaelen/DB is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+    You should have received a copy of the GNU General Public License
+along with Vaelen/DB.  If not, see <http://www.gnu.org/licenses/>.
+******/
+
+package storage
+
+import (
+	"sync"
+	"time"
+)
+
+// queueSize is the number of requests of each kind that can wait for the storage thread before senders block
+const queueSize = 256
+
+// WriteStats reports the time spent writing a file to disk
+type WriteStats struct {
+	// Count is the number of times the file was written
+	Count uint64
+	// Total is the time spent writing it
+	Total time.Duration
+	// Last is how long the last write took
+	Last time.Duration
+}
+
+// fileWrites holds the statistics of the writes of a file
+type fileWrites struct {
+	sync.Mutex
+	stats WriteStats
+}
+
+// record adds a write that started at the given time
+func (w *fileWrites) record(start time.Time) {
+	w.Lock()
+	defer w.Unlock()
+	w.stats.Count++
+	w.stats.Last = time.Since(start)
+	w.stats.Total += w.stats.Last
+}
+
+// IndexDefinitionWrites returns statistics about the writes of the file that holds the index definitions
+func (db *Instance) IndexDefinitionWrites() WriteStats {
+	db.definitionWrites.Lock()
+	defer db.definitionWrites.Unlock()
+	return db.definitionWrites.stats
+}
+
+// QueueDepth returns the number of requests waiting for the storage thread
+func (db *Instance) QueueDepth() int {
+	return len(db.getChannel) + len(db.setChannel) + len(db.versionChannel) + len(db.digestChannel) + len(db.intentChannel) +
+		len(db.indexChannel) + len(db.scanChannel) + len(db.namespaceChannel)
+}
@@ -24,12 +24,15 @@ along with Vaelen/DB.  If not, see <http://www.gnu.org/licenses/>.
 package storage
 
 import (
-	"io"
-	"log"
-	"os"
+	"bytes"
+	"context"
+	"log/slog"
 
 	"encoding/gob"
 	"path/filepath"
+	"time"
+
+	"github.com/vaelen/db/logging"
 )
 
 // GetRequest is used to retrieve a value and optionally remove it from the storage tree.
@@ -37,19 +40,56 @@ type GetRequest struct {
 	ID     string
 	Remove bool
 	Result chan Result
+	queued queued
 }
 
 // SetRequest is used to set a value in the storage tree.
 type SetRequest struct {
-	ID     string
-	Value  string
+	ID    string
+	Value string
+	// Expires is when the value expires, it never does when it is zero
+	Expires time.Time
+	// Flags are opaque flags stored with the value
+	Flags  uint32
 	Result chan Result
+	queued queued
 }
 
 // Result is returned from Get and Set
 type Result struct {
 	ID    string
 	Value string
+	// Expires is when the value expires, it never does when it is zero
+	Expires time.Time
+	// Flags are opaque flags stored with the value
+	Flags uint32
+	// Version is the version of the value, it is empty for values that were never written with one
+	Version VersionVector
+}
+
+// VersionRequest is used to retrieve the versions of a key and optionally reconcile new versions with them.
+type VersionRequest struct {
+	ID       string
+	Versions []NodeKeyValuePair
+	Result   chan VersionResult
+	queued   queued
+}
+
+// VersionResult is returned from GetVersions and PutVersions
+type VersionResult struct {
+	ID       string
+	Versions []NodeKeyValuePair
+}
+
+// DigestRequest is used to retrieve the digest of a node in the storage tree or to change how keys are grouped.
+type DigestRequest struct {
+	ID NodeLocator
+	// Shared selects the groups whose keys are included in the digest
+	Shared SharedGroups
+	Result chan NodeDigest
+	// groups replaces the digest groups of every tree when setGroups is true
+	groups    DigestGroups
+	setGroups bool
 }
 
 // GetNodeRequest is used to request an entire node of the storage tree.
@@ -78,6 +118,20 @@ type Instance struct {
 	getChannel chan GetRequest
 	// Set sets a value in storage
 	setChannel chan SetRequest
+	// versionChannel reads and reconciles the versions of a value
+	versionChannel chan VersionRequest
+	// digestChannel retrieves the digest of a node
+	digestChannel chan DigestRequest
+	// intentChannel prepares, commits and aborts transaction intents
+	intentChannel chan IntentRequest
+	// indexChannel declares, builds and queries secondary indexes
+	indexChannel chan IndexRequest
+	// scanChannel lists the keys in storage
+	scanChannel chan ScanRequest
+	// namespaceChannel reports the usage of namespaces, sets their quotas and drops them
+	namespaceChannel chan NamespaceRequest
+	// reencryptChannel writes the files of the storage instance again with the active data key
+	reencryptChannel chan chan error
 	// GetNode retrieves a node from the storage tree, optionally removing it
 	GetNode chan GetNodeRequest
 	// SetNode sets a node in the storage tree
@@ -85,58 +139,173 @@ type Instance struct {
 	// Shutdown stops the storage worker thread
 	Shutdown chan bool
 	// Logger is the logger instance used by the storage instance
-	Logger *log.Logger
+	Logger *slog.Logger
 	// Path is the path to the data file maintained by this storage instance
-	Path    string
-	storage *Hashtable
+	Path string
+	// Keyring encrypts the files written by this storage instance.  They are not encrypted when it is nil.
+	Keyring *Keyring
+	// storage is the tree of the default namespace, the other namespaces each have their own tree
+	storage    *namespaceTree
+	namespaces map[string]*namespaceTree
+	// quotas limits how much the namespaces that have one store, see SetQuotas
+	quotas map[string]Quota
+	// empty stands in for the trees of namespaces that have none
+	empty *namespaceTree
+	// groups splits the keys of every tree into the groups whose digests are kept apart
+	groups  DigestGroups
+	intents *intents
+	indexes map[string]*index
+	// definitionWrites holds the statistics of the writes of the index definitions
+	definitionWrites *fileWrites
+	// indexGeneration is the generation given to the last index declared
+	indexGeneration uint64
+	// expiring holds when the first live version of each key that expires does so
+	expiring map[string]time.Time
+	// timer collects the timing of the requests sent through a view returned by Timed
+	timer *Timer
+	// ctx is the context the requests sent through a view returned by WithContext are logged with
+	ctx context.Context
+	// loaded is closed once the data file and the index definitions have been loaded
+	loaded chan bool
+}
+
+// New creates a new Storage instance and starts the storage thread, which loads the data file if it exists before it handles any request.
+// Requests can be sent straight away, they wait for the data to be loaded.
+func New(logger *slog.Logger, dbPath string) *Instance {
+	return NewEncrypted(logger, dbPath, nil)
 }
 
-// New creates a new Storage instance. It also loads the data file if it exists and starts the storage thread.
-func New(logWriter io.Writer, dbPath string) *Instance {
+// NewEncrypted creates a new Storage instance whose files are encrypted with the given keyring
+func NewEncrypted(logger *slog.Logger, dbPath string, keyring *Keyring) *Instance {
 	db := &Instance{
-		getChannel: make(chan GetRequest),
-		setChannel: make(chan SetRequest),
-		GetNode:    make(chan GetNodeRequest),
-		SetNode:    make(chan SetNodeRequest),
-		Shutdown:   make(chan bool),
-		Logger:     log.New(logWriter, "[STORAGE] ", log.LstdFlags),
-		Path:       dbPath,
-		storage:    NewHashtable(),
+		getChannel:       make(chan GetRequest, queueSize),
+		setChannel:       make(chan SetRequest, queueSize),
+		versionChannel:   make(chan VersionRequest, queueSize),
+		digestChannel:    make(chan DigestRequest, queueSize),
+		intentChannel:    make(chan IntentRequest, queueSize),
+		indexChannel:     make(chan IndexRequest, queueSize),
+		scanChannel:      make(chan ScanRequest, queueSize),
+		namespaceChannel: make(chan NamespaceRequest, queueSize),
+		reencryptChannel: make(chan chan error),
+		GetNode:          make(chan GetNodeRequest),
+		SetNode:          make(chan SetNodeRequest),
+		Shutdown:         make(chan bool),
+		Logger:           logger,
+		Path:             dbPath,
+		Keyring:          keyring,
+		storage:          newNamespaceTree(),
+		namespaces:       make(map[string]*namespaceTree),
+		quotas:           make(map[string]Quota),
+		empty:            newNamespaceTree(),
+		intents:          newIntents(),
+		indexes:          make(map[string]*index),
+		definitionWrites: &fileWrites{},
+		expiring:         make(map[string]time.Time),
+		loaded:           make(chan bool),
 	}
-	db.load()
 	go db.start()
 	return db
 }
 
+// Loaded returns a channel that is closed once the data file and the index definitions have been loaded
+func (db *Instance) Loaded() <-chan bool {
+	return db.loaded
+}
+
 func (db *Instance) start() {
-	db.Logger.Printf("Started: %s\n", db.Path)
+	db.load()
+	db.loadIndexes()
+	close(db.loaded)
+	db.Logger.Info("Started", "path", db.Path)
+	sweep := time.NewTicker(expirySweepInterval)
+	defer sweep.Stop()
 	done := false
 	for {
 		select {
 		case get := <-db.getChannel:
-			value := db.storage.Get(get.ID)
+			timing := get.queued.pick()
+			tree := db.findFor(get.ID)
+			pair, _ := tree.lookup(get.ID, time.Now())
 			if get.Remove {
-				db.storage.Remove(get.ID)
+				tree.Remove(get.ID)
+				db.changed(get.ID)
 			}
 			result := Result{
-				ID:    get.ID,
-				Value: value,
+				ID:      get.ID,
+				Value:   pair.Value,
+				Expires: pair.Expires,
+				Flags:   pair.Flags,
+				Version: pair.Version,
 			}
-			//db.Logger.Printf("Get - Key: %s, Value: %s\n", get.ID, value)
+			db.Logger.DebugContext(get.queued.context(), "Get", "key", get.ID, "remove", get.Remove)
+			timing.applied()
 			get.Result <- result
 		case set := <-db.setChannel:
-			db.storage.Set(set.ID, set.Value)
+			timing := set.queued.pick()
+			db.treeFor(set.ID).SetItem(set.ID, set.Value, set.Flags, set.Expires)
+			db.changed(set.ID)
 			result := Result{
-				ID:    set.ID,
-				Value: set.Value,
+				ID:      set.ID,
+				Value:   set.Value,
+				Expires: set.Expires,
+				Flags:   set.Flags,
 			}
-			//db.Logger.Printf("Set - Key: %s, Value: %s\n", set.ID, set.Value)
+			db.Logger.DebugContext(set.queued.context(), "Set", "key", set.ID, "size", len(set.Value))
+			timing.applied()
 			set.Result <- result
 			db.save()
+		case version := <-db.versionChannel:
+			timing := version.queued.pick()
+			tree := db.findFor(version.ID)
+			if len(version.Versions) > 0 {
+				tree = db.treeFor(version.ID)
+			}
+			for _, v := range version.Versions {
+				tree.PutVersion(v)
+			}
+			if len(version.Versions) > 0 {
+				db.changed(version.ID)
+			}
+			result := VersionResult{
+				ID:       version.ID,
+				Versions: tree.GetVersions(version.ID),
+			}
+			timing.applied()
+			version.Result <- result
+			if len(version.Versions) > 0 {
+				db.save()
+			}
+		case digest := <-db.digestChannel:
+			if digest.setGroups {
+				db.setGroups(digest.groups)
+				digest.Result <- NodeDigest{}
+				break
+			}
+			digest.Result <- db.find(digest.ID.Namespace).NodeDigest(digest.ID, digest.Shared)
+		case intent := <-db.intentChannel:
+			timing := intent.queued.pick()
+			err := db.handleIntent(intent)
+			timing.applied()
+			if intent.action == commitIntent {
+				db.save()
+			}
+			intent.Result <- err
+		case indexRequest := <-db.indexChannel:
+			indexRequest.Result <- db.handleIndex(indexRequest)
+		case scan := <-db.scanChannel:
+			keys, next := db.find(scan.Namespace).Scan(scan.Cursor, scan.Count)
+			scan.Result <- ScanResult{Keys: keys, Next: next}
+		case namespace := <-db.namespaceChannel:
+			namespace.Result <- db.handleNamespace(namespace)
+		case result := <-db.reencryptChannel:
+			db.save()
+			result <- db.saveIndexes()
 		case getNode := <-db.GetNode:
-			node, _ := db.storage.FindNode(getNode.ID)
+			tree := db.find(getNode.ID.Namespace)
+			node, _ := tree.FindNode(getNode.ID)
 			if node != nil && getNode.Remove {
-				db.storage.RemoveNode(getNode.ID)
+				tree.RemoveNode(getNode.ID)
+				db.changed(node.subtreeKeys()...)
 			}
 			result := NodeResult{
 				ID:    getNode.ID,
@@ -144,46 +313,33 @@ func (db *Instance) start() {
 			}
 			getNode.Result <- result
 		case setNode := <-db.SetNode:
-			node := db.storage.SetNode(setNode.ID, setNode.Value)
+			tree := db.tree(setNode.ID.Namespace)
+			replaced, _ := tree.FindNode(setNode.ID)
+			node := tree.SetNode(setNode.ID, setNode.Value)
+			db.changed(append(replaced.subtreeKeys(), node.subtreeKeys()...)...)
 			result := NodeResult{
 				ID:    setNode.ID,
 				Value: node,
 			}
 			setNode.Result <- result
 			db.save()
+		case now := <-sweep.C:
+			db.expire(now)
 		case <-db.Shutdown:
 			done = true
-			db.Logger.Printf("Stopping...\n")
+			db.Logger.Info("Stopping")
 		}
 		if done {
 			break
 		}
 	}
-	db.Logger.Printf("Stopped\n")
+	db.Logger.Info("Stopped")
 }
 
+// save is where the storage trees would be written to the data directory.  They are only kept in memory for now:
+// gob can not encode a Hashtable, whose root is unexported, so there is no storage file to write or to encrypt
+// until the trees have an encoding of their own.
 func (db *Instance) save() {
-	if true {
-		// TODO: Implement custom save/load routine
-		return
-	}
-	if db.Path == "" {
-		return
-	}
-	filename := filepath.Join(db.Path, "storage.gob")
-	f, err := os.OpenFile(filename, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0640)
-	if err != nil {
-		db.Logger.Fatalf("Could not open file for saving. File: %s, Error: %s\n", filename, err.Error())
-		return
-	}
-	defer f.Close()
-	enc := gob.NewEncoder(f)
-	err = enc.Encode(db.storage)
-	if err != nil {
-		db.Logger.Fatalf("Could not save storage. File: %s, Error: %s\n", filename, err.Error())
-		return
-	}
-	db.Logger.Printf("Storage saved: %s\n", filename)
 }
 
 func (db *Instance) load() {
@@ -192,43 +348,40 @@ func (db *Instance) load() {
 	}
 
 	filename := filepath.Join(db.Path, "storage.gob")
-	f, err := os.OpenFile(filename, os.O_RDONLY, 0640)
-	if err != nil {
-		db.Logger.Printf("Could not open file for loading. File: %s, Error: %s\n", filename, err.Error())
+	b, err := db.Keyring.ReadFile(filename)
+	if err != nil && !IsKeyError(err) {
+		db.Logger.Warn("Could not open file for loading", "file", filename, "error", err)
 		return
 	}
-	defer f.Close()
-	dec := gob.NewDecoder(f)
-	err = dec.Decode(db.storage)
+	if err == nil {
+		err = gob.NewDecoder(bytes.NewReader(b)).Decode(db.storage.Hashtable)
+	}
 	if err != nil {
-		db.Logger.Fatalf("Could not load storage. File: %s, Error: %s\n", filename, err.Error())
+		logging.Fatal(db.Logger, "Could not load storage", "file", filename, "error", err)
 		return
 	}
-	db.Logger.Printf("Storage loaded: %s\n", filename)
+	db.storage.nodes = db.storage.root.count()
+	keys := db.storage.root.subtreeKeys()
+	db.storage.account(keys...)
+	db.track(keys...)
+	db.Logger.Info("Storage loaded", "file", filename)
+}
+
+// Reencrypt writes the files of the storage instance again so that they are encrypted with the active data key
+func (db *Instance) Reencrypt() error {
+	result := make(chan error)
+	db.reencryptChannel <- result
+	return <-result
 }
 
 // Get returns the value of the given key
 func (db *Instance) Get(id string) string {
-	request := GetRequest{
-		ID:     id,
-		Remove: false,
-		Result: make(chan Result),
-	}
-	db.getChannel <- request
-	result := <-request.Result
-	return result.Value
+	return db.Lookup(id).Value
 }
 
 // Set sets the value of the given key
 func (db *Instance) Set(id string, value string) string {
-	request := SetRequest{
-		ID:     id,
-		Value:  value,
-		Result: make(chan Result),
-	}
-	db.setChannel <- request
-	result := <-request.Result
-	return result.Value
+	return db.SetItem(id, value, 0, time.Time{})
 }
 
 // Remove removes the given key
@@ -237,12 +390,59 @@ func (db *Instance) Remove(id string) string {
 		ID:     id,
 		Remove: true,
 		Result: make(chan Result),
+		queued: db.queue(),
 	}
 	db.getChannel <- request
 	result := <-request.Result
 	return result.Value
 }
 
+// GetVersions returns every version of the given key, including tombstones
+func (db *Instance) GetVersions(id string) []NodeKeyValuePair {
+	return db.PutVersions(id, nil)
+}
+
+// PutVersions reconciles the given versions of a key with the stored versions and returns the result
+func (db *Instance) PutVersions(id string, versions []NodeKeyValuePair) []NodeKeyValuePair {
+	request := VersionRequest{
+		ID:       id,
+		Versions: versions,
+		Result:   make(chan VersionResult),
+		queued:   db.queue(),
+	}
+	db.versionChannel <- request
+	result := <-request.Result
+	return result.Versions
+}
+
+// Digest returns the digest of the given node of the storage tree along with the digests of its children
+func (db *Instance) Digest(id NodeLocator) NodeDigest {
+	return db.SharedDigest(id, nil)
+}
+
+// SharedDigest returns the digest of the given node of the storage tree and of its children over the keys in the selected groups
+func (db *Instance) SharedDigest(id NodeLocator, shared SharedGroups) NodeDigest {
+	request := DigestRequest{
+		ID:     id,
+		Shared: shared,
+		Result: make(chan NodeDigest),
+	}
+	db.digestChannel <- request
+	return <-request.Result
+}
+
+// SetDigestGroups changes how keys are grouped for their digests and recalculates the digests of every tree.
+// The groups function is called from the storage thread, so it must not send requests to the storage instance.
+func (db *Instance) SetDigestGroups(groups DigestGroups) {
+	request := DigestRequest{
+		Result:    make(chan NodeDigest),
+		groups:    groups,
+		setGroups: true,
+	}
+	db.digestChannel <- request
+	<-request.Result
+}
+
 // Close shuts down the storage instance
 func (db *Instance) Close() {
 	db.Shutdown <- true
@@ -20,12 +20,22 @@ along with Vaelen/DB.  If not, see <http://www.gnu.org/licenses/>.
 package storage
 
 import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
 	"math/rand"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/vaelen/db/logging"
 )
 
+// testLogger is the logger of the storage instances used by the tests
+var testLogger = logging.Text(os.Stderr).Logger("storage")
+
 func randomString(n int) string {
 	var letterRunes = []rune("abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ")
 	b := make([]rune, n)
@@ -55,7 +65,7 @@ func TestStorage(t *testing.T) {
 	}
 
 	// Create a storage instance
-	s := New(os.Stderr, "")
+	s := New(testLogger, "")
 	defer s.Close()
 
 	t.Logf("Adding random strings\n")
@@ -136,3 +146,611 @@ func TestHashtable(t *testing.T) {
 	}
 
 }
+
+// TestVersions tests reconciling concurrent versions of a key
+func TestVersions(t *testing.T) {
+	s := New(testLogger, "")
+	defer s.Close()
+
+	key := "foo"
+	a := NodeKeyValuePair{Key: key, Value: "a", Version: VersionVector{"x": 1}}
+	b := NodeKeyValuePair{Key: key, Value: "b", Version: VersionVector{"y": 1}}
+	c := NodeKeyValuePair{Key: key, Value: "c", Version: VersionVector{"x": 1, "y": 1}.Increment("x")}
+
+	versions := s.PutVersions(key, []NodeKeyValuePair{a})
+	if len(versions) != 1 {
+		t.Fatalf("Expected 1 version, got %d\n", len(versions))
+	}
+
+	versions = s.PutVersions(key, []NodeKeyValuePair{b})
+	if len(versions) != 2 {
+		t.Fatalf("Expected 2 concurrent versions, got %d\n", len(versions))
+	}
+
+	// An older version should be ignored
+	versions = s.PutVersions(key, []NodeKeyValuePair{{Key: key, Value: "old", Version: VersionVector{}}})
+	if len(versions) != 2 {
+		t.Fatalf("Expected old version to be ignored, got %d versions\n", len(versions))
+	}
+
+	versions = s.PutVersions(key, []NodeKeyValuePair{c})
+	if len(versions) != 1 || versions[0].Value != "c" {
+		t.Fatalf("Expected descendant version to replace siblings, got %v\n", versions)
+	}
+
+	if v := s.Get(key); v != "c" {
+		t.Errorf("Expected value c, got %s\n", v)
+	}
+
+	s.PutVersions(key, []NodeKeyValuePair{{Key: key, Deleted: true, Version: c.Version.Increment("y")}})
+	if v := s.Get(key); v != "" {
+		t.Errorf("Expected tombstone to hide value, got %s\n", v)
+	}
+}
+
+// TestExpiry tests that expired values are hidden and then swept into tombstones that keep their version
+func TestExpiry(t *testing.T) {
+	s := New(testLogger, "")
+	defer s.Close()
+
+	deadline := time.Now().Add(100 * time.Millisecond)
+	s.SetItem("plain", "a", 0, deadline)
+	if result := s.Lookup("plain"); result.Value != "a" || !result.Expires.Equal(deadline) {
+		t.Fatalf("Unexpected result: %+v\n", result)
+	}
+	version := VersionVector{"x": 1}
+	s.PutVersions("versioned", []NodeKeyValuePair{{Key: "versioned", Value: "b", Version: version, Expires: deadline}})
+
+	time.Sleep(150 * time.Millisecond)
+	if v := s.Get("plain"); v != "" {
+		t.Errorf("Expected expired value to be hidden, got %s\n", v)
+	}
+	if keys, _ := s.Scan(0, 10); len(keys) != 0 {
+		t.Errorf("Expected expired keys to be left out of scans, got %v\n", keys)
+	}
+
+	time.Sleep(2 * expirySweepInterval)
+	if versions := s.GetVersions("plain"); len(versions) != 0 {
+		t.Errorf("Expected unversioned value to be removed, got %v\n", versions)
+	}
+	versions := s.GetVersions("versioned")
+	if len(versions) != 1 || !versions[0].Deleted || versions[0].Version.Compare(version) != Equal {
+		t.Fatalf("Expected a tombstone with the same version, got %v\n", versions)
+	}
+
+	// A replica that has not swept the value yet must not bring it back
+	versions = s.PutVersions("versioned", []NodeKeyValuePair{{Key: "versioned", Value: "b", Version: version, Expires: deadline}})
+	if len(versions) != 1 || !versions[0].Deleted {
+		t.Errorf("Expected the tombstone to be kept, got %v\n", versions)
+	}
+}
+
+// TestIntents tests that prepared intents lock their keys and are only visible once committed
+func TestIntents(t *testing.T) {
+	s := New(testLogger, "")
+	defer s.Close()
+
+	a := NodeKeyValuePair{Key: "a", Value: "1", Version: VersionVector{"x": 1}}
+	b := NodeKeyValuePair{Key: "b", Value: "2", Version: VersionVector{"x": 1}}
+
+	if err := s.PrepareIntent("t1", []NodeKeyValuePair{a, b}); err != nil {
+		t.Fatalf("Prepare Error: %s\n", err.Error())
+	}
+	if err := s.PrepareIntent("t2", []NodeKeyValuePair{b}); err != ErrIntentConflict {
+		t.Fatalf("Expected a conflict, got %v\n", err)
+	}
+	if v := s.Get("a"); v != "" {
+		t.Fatalf("Intent was visible before commit: %s\n", v)
+	}
+
+	s.CommitIntent("t1")
+	if s.Get("a") != "1" || s.Get("b") != "2" {
+		t.Fatalf("Intents were not committed. a: %s, b: %s\n", s.Get("a"), s.Get("b"))
+	}
+
+	c := NodeKeyValuePair{Key: "b", Value: "3", Version: VersionVector{"x": 2}}
+	if err := s.PrepareIntent("t2", []NodeKeyValuePair{c}); err != nil {
+		t.Fatalf("Prepare Error after commit: %s\n", err.Error())
+	}
+	s.AbortIntent("t2")
+	if v := s.Get("b"); v != "2" {
+		t.Fatalf("Aborted intent was applied: %s\n", v)
+	}
+	if err := s.PrepareIntent("t3", []NodeKeyValuePair{c}); err != nil {
+		t.Fatalf("Prepare Error after abort: %s\n", err.Error())
+	}
+
+	// Intents that are prepared but not committed count against the quota
+	s.SetQuotas(map[string]Quota{"team": {MaxKeys: 1}})
+	first := NodeKeyValuePair{Key: NamespaceKey("team", "a"), Value: "1", Version: VersionVector{"x": 1}}
+	second := NodeKeyValuePair{Key: NamespaceKey("team", "b"), Value: "2", Version: VersionVector{"x": 1}}
+	if err := s.PrepareIntent("t4", []NodeKeyValuePair{first}); err != nil {
+		t.Fatalf("Prepare Error: %s\n", err.Error())
+	}
+	if err := s.PrepareIntent("t5", []NodeKeyValuePair{second}); err == nil {
+		t.Fatalf("Prepared intents over the quota\n")
+	} else if _, ok := err.(*QuotaError); !ok {
+		t.Fatalf("Expected a QuotaError, got %v\n", err)
+	}
+	s.AbortIntent("t4")
+	if err := s.PrepareIntent("t5", []NodeKeyValuePair{second}); err != nil {
+		t.Fatalf("Prepare Error after abort: %s\n", err.Error())
+	}
+}
+
+// TestDigest tests that digests only depend on the contents of the tree
+func TestDigest(t *testing.T) {
+	a := NewHashtable()
+	b := NewHashtable()
+
+	keys := make([]string, 0, 1000)
+	for i := 0; i < 1000; i++ {
+		keys = append(keys, randomString(10))
+	}
+
+	for _, k := range keys {
+		a.Set(k, k)
+	}
+	for i := len(keys) - 1; i >= 0; i-- {
+		b.Set(keys[i], keys[i])
+	}
+	if a.Digest() == 0 || a.Digest() != b.Digest() {
+		t.Fatalf("Expected equal digests, got %X and %X\n", a.Digest(), b.Digest())
+	}
+
+	b.Set(keys[0], "changed")
+	if a.Digest() == b.Digest() {
+		t.Fatalf("Expected digests to differ after a change\n")
+	}
+	id := GetNodeLocator(keys[0])
+	if a.NodeDigest(id, nil).Digest == b.NodeDigest(id, nil).Digest {
+		t.Errorf("Expected leaf digests to differ after a change\n")
+	}
+
+	b.Set(keys[0], keys[0])
+	if a.Digest() != b.Digest() {
+		t.Fatalf("Expected digests to match after undoing a change\n")
+	}
+
+	// Move a subtree between trees and check the incremental digests against a full recalculation
+	c := NewHashtable()
+	moved := NodeLocator{ID: id.ID, Bytes: 2}
+	node, _ := b.FindNode(moved)
+	b.RemoveNode(moved)
+	c.SetNode(moved, node)
+	for _, h := range []*Hashtable{b, c} {
+		digest := h.Digest()
+		h.root.rehash(nil)
+		if digest != h.Digest() {
+			t.Errorf("Incremental digest %X does not match recalculated digest %X\n", digest, h.Digest())
+		}
+	}
+	b.SetNode(moved, node)
+	if a.Digest() != b.Digest() {
+		t.Fatalf("Expected digests to match after moving a node back\n")
+	}
+
+	for _, k := range keys {
+		a.Remove(k)
+	}
+	if a.Digest() != 0 {
+		t.Errorf("Expected empty tree to have a zero digest, got %X\n", a.Digest())
+	}
+}
+
+// TestDigestGroups tests that trees holding different sets of keys have the same digests over the groups they share
+func TestDigestGroups(t *testing.T) {
+	groups := func(key string) string {
+		return key[:1]
+	}
+	shared := func(group string) bool {
+		return group == "a"
+	}
+	a := NewHashtable()
+	b := NewHashtable()
+	a.SetGroups(groups)
+	for i := 0; i < 500; i++ {
+		key := fmt.Sprintf("a%d", i)
+		a.Set(key, key)
+		b.Set(key, key)
+		a.Set(fmt.Sprintf("b%d", i), "only in a")
+	}
+	// Grouping the keys after they were written gives the same digests as writing them into groups
+	b.SetGroups(groups)
+
+	if a.Digest() == b.Digest() {
+		t.Fatalf("Expected the digests of every group to differ\n")
+	}
+	root := NodeLocator{}
+	if a.NodeDigest(root, shared).Digest != b.NodeDigest(root, shared).Digest {
+		t.Fatalf("Expected the digests of the shared group to match\n")
+	}
+	for child, digest := range a.NodeDigest(root, shared).Children {
+		if b.NodeDigest(root, shared).Children[child] != digest {
+			t.Fatalf("Expected the digests of the shared group to match for child %X\n", child)
+		}
+	}
+	leaf := GetNodeLocator("b1")
+	for _, v := range a.NodeDigest(leaf, shared).Values {
+		if v.Key[:1] != "a" {
+			t.Fatalf("Expected only the keys of the shared group, got %s\n", v.Key)
+		}
+	}
+
+	b.Set("a1", "changed")
+	if a.NodeDigest(root, shared).Digest == b.NodeDigest(root, shared).Digest {
+		t.Fatalf("Expected the digests of the shared group to differ after a change\n")
+	}
+	digest := b.Digest()
+	b.root.rehash(groups)
+	if digest != b.Digest() {
+		t.Errorf("Incremental digest %X does not match recalculated digest %X\n", digest, b.Digest())
+	}
+}
+
+// TestIndexes tests building, querying and dropping secondary indexes, and that a build of a dropped index cannot touch one declared again
+func TestIndexes(t *testing.T) {
+	s := New(testLogger, "")
+	defer s.Close()
+
+	for i := 0; i < 100; i++ {
+		s.Set(fmt.Sprintf("user-%d", i), fmt.Sprintf(`{"name": "user %d", "age": %d}`, i, i%50))
+	}
+	s.Set("other", "not json")
+
+	if _, err := s.CreateIndex(IndexDefinition{Name: "age", Path: "$.age"}); err != nil {
+		t.Fatalf("CreateIndex Error: %s\n", err.Error())
+	}
+	if _, err := s.CreateIndex(IndexDefinition{Name: "age", Path: "age"}); err != ErrIndexExists {
+		t.Fatalf("Expected ErrIndexExists, got %v\n", err)
+	}
+	for i := 0; i < 500; i++ {
+		if status := s.Indexes(); len(status) == 1 && status[0].Ready {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	status := s.Indexes()
+	if len(status) != 1 || !status[0].Ready || status[0].Progress != 1 || status[0].Entries != 100 {
+		t.Fatalf("Unexpected index status: %+v\n", status)
+	}
+
+	entries, err := s.QueryIndex(IndexQuery{Index: "age", Equal: "7"})
+	if err != nil {
+		t.Fatalf("QueryIndex Error: %s\n", err.Error())
+	}
+	if len(entries) != 2 || entries[0].Key != "user-57" || entries[1].Key != "user-7" || entries[0].Value != "7" {
+		t.Fatalf("Unexpected equality results: %+v\n", entries)
+	}
+
+	// Writes after the build are indexed as they happen
+	s.Set("user-7", `{"name": "user 7", "age": 70}`)
+	s.Remove("user-57")
+	s.PutVersions("user-100", []NodeKeyValuePair{{Key: "user-100", Value: `{"age": 10}`, Version: VersionVector{"x": 1}}})
+	if entries, _ := s.QueryIndex(IndexQuery{Index: "age", Equal: "7"}); len(entries) != 0 {
+		t.Fatalf("Stale entries after writes: %+v\n", entries)
+	}
+
+	entries, err = s.QueryIndex(IndexQuery{Index: "age", Min: "10", Max: "12", MaxExclusive: true})
+	if err != nil {
+		t.Fatalf("QueryIndex Error: %s\n", err.Error())
+	}
+	expected := []string{"user-10", "user-100", "user-60", "user-11", "user-61"}
+	if len(entries) != len(expected) {
+		t.Fatalf("Range results: %+v, Expected: %v\n", entries, expected)
+	}
+	for i, e := range entries {
+		if e.Key != expected[i] {
+			t.Errorf("Range result %d: %s, Expected: %s\n", i, e.Key, expected[i])
+		}
+	}
+
+	if entries, _ := s.QueryIndex(IndexQuery{Index: "age", Min: "49", Limit: 3}); len(entries) != 3 || entries[2].Value != "70" {
+		t.Errorf("Unexpected open range results: %+v\n", entries)
+	}
+	if _, err := s.QueryIndex(IndexQuery{Index: "age", Min: "1", Max: `"a"`}); err == nil {
+		t.Errorf("Expected an error for bounds of different types\n")
+	}
+
+	if err := s.DropIndex("age"); err != nil {
+		t.Fatalf("DropIndex Error: %s\n", err.Error())
+	}
+	if _, err := s.QueryIndex(IndexQuery{Index: "age", Equal: "1"}); err != ErrUnknownIndex {
+		t.Fatalf("Expected ErrUnknownIndex, got %v\n", err)
+	}
+
+	// A step from the build of the dropped index must not mark the new one as built
+	result := s.sendIndex(IndexRequest{Definition: IndexDefinition{Name: "age", Path: "age"}, action: createIndex})
+	if result.Err != nil {
+		t.Fatalf("CreateIndex Error: %s\n", result.Err.Error())
+	}
+	stale := s.sendIndex(IndexRequest{Definition: IndexDefinition{Name: "age"}, action: buildIndex, step: indexSteps - 1, generation: result.generation - 1})
+	if stale.Err != errIndexReplaced {
+		t.Fatalf("Expected errIndexReplaced, got %v\n", stale.Err)
+	}
+	if status := s.Indexes(); status[0].Ready || status[0].Progress != 0 {
+		t.Fatalf("Stale build step changed the index: %+v\n", status)
+	}
+}
+
+// TestScan tests paging through the live keys with a cursor
+func TestScan(t *testing.T) {
+	s := New(testLogger, "")
+	defer s.Close()
+
+	expected := make(map[string]bool)
+	for i := 0; i < 1000; i++ {
+		key := fmt.Sprintf("key-%d", i)
+		expected[key] = true
+		s.Set(key, key)
+	}
+	s.PutVersions("removed", []NodeKeyValuePair{{Key: "removed", Version: VersionVector{"x": 1}, Deleted: true}})
+
+	seen := make(map[string]bool)
+	cursor, pages := uint32(0), 0
+	for {
+		keys, next := s.Scan(cursor, 10)
+		pages++
+		for _, key := range keys {
+			if seen[key] {
+				t.Errorf("Key returned twice: %s\n", key)
+			}
+			seen[key] = true
+		}
+		if next == 0 {
+			break
+		}
+		if next <= cursor {
+			t.Fatalf("Cursor went backwards. Cursor: %d, Next: %d\n", cursor, next)
+		}
+		cursor = next
+	}
+	if len(seen) != len(expected) {
+		t.Fatalf("Keys: %d, Expected: %d\n", len(seen), len(expected))
+	}
+	for key := range expected {
+		if !seen[key] {
+			t.Errorf("Key not returned: %s\n", key)
+		}
+	}
+	if pages < 90 {
+		t.Errorf("Pages: %d, Expected around 100\n", pages)
+	}
+}
+
+// TestNamespaces tests namespace keys and the usage kept for each namespace
+func TestNamespaces(t *testing.T) {
+	s := New(testLogger, "")
+	defer s.Close()
+
+	if _, err := s.CreateIndex(IndexDefinition{Name: "n", Path: "n"}); err != nil {
+		t.Fatalf("CreateIndex Error: %s\n", err.Error())
+	}
+	key := NamespaceKey("team", "a")
+	if key != "__ns/team/a" || NamespaceKey("", "a") != "a" {
+		t.Fatalf("Unexpected namespace key: %s\n", key)
+	}
+	if ns, inner := SplitNamespace(key); ns != "team" || inner != "a" {
+		t.Fatalf("Unexpected split: %s, %s\n", ns, inner)
+	}
+	if ValidNamespace("") || ValidNamespace("a/b") || !ValidNamespace("team-1") {
+		t.Fatalf("Unexpected namespace validation\n")
+	}
+
+	s.Set("a", `{"n": 1}`)
+	s.Set(key, `{"n": 1}`)
+	s.Set(NamespaceKey("team", "b"), "bb")
+	s.Remove(NamespaceKey("team", "b"))
+	s.Set(NamespaceKey("team", "c"), "cc")
+	if s.Get("a") != `{"n": 1}` || s.Get(key) != `{"n": 1}` {
+		t.Fatalf("Values were not stored\n")
+	}
+	if s.Get(NamespaceKey("other", "a")) != "" {
+		t.Fatalf("Namespaces are not isolated\n")
+	}
+
+	usage := s.Namespaces()
+	if usage["team"].Keys != 2 || usage["team"].Bytes != int64(len(key)+8+len(NamespaceKey("team", "c"))+2) {
+		t.Fatalf("Unexpected usage: %+v\n", usage)
+	}
+	if usage[""].Keys != 1 {
+		t.Fatalf("Unexpected default usage: %+v\n", usage)
+	}
+	// Node counts are kept as nodes are added and removed, so they match a walk of each tree
+	s.Remove(NamespaceKey("team", "c"))
+	s.Set(NamespaceKey("team", "c"), "cc")
+	usage = s.Namespaces()
+	if usage[""].Nodes != s.storage.root.count() || usage["team"].Nodes != s.namespaces["team"].root.count() {
+		t.Fatalf("Unexpected node counts: %+v\n", usage)
+	}
+	if keys, _ := s.ScanNamespace("team", 0, 10); len(keys) != 2 {
+		t.Fatalf("Unexpected scan: %v\n", keys)
+	}
+	if keys, _ := s.Scan(0, 10); len(keys) != 1 || keys[0] != "a" {
+		t.Fatalf("Unexpected default scan: %v\n", keys)
+	}
+
+	for i := 0; i < 500; i++ {
+		if status := s.Indexes(); status[0].Ready {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if entries, _ := s.QueryIndex(IndexQuery{Index: "n", Equal: "1", Namespace: "team"}); len(entries) != 1 || entries[0].Key != key {
+		t.Fatalf("Unexpected namespace query: %+v\n", entries)
+	}
+
+	if dropped := s.DropNamespace("team"); dropped.Keys != 2 {
+		t.Fatalf("Unexpected dropped usage: %+v\n", dropped)
+	}
+	if s.Get(key) != "" {
+		t.Fatalf("Dropped namespace still has values\n")
+	}
+	if _, ok := s.Namespaces()["team"]; ok {
+		t.Fatalf("Dropped namespace still has a tree\n")
+	}
+	if entries, _ := s.QueryIndex(IndexQuery{Index: "n", Equal: "1"}); len(entries) != 1 || entries[0].Key != "a" {
+		t.Fatalf("Unexpected query after drop: %+v\n", entries)
+	}
+	if s.Get("a") != `{"n": 1}` {
+		t.Fatalf("Default namespace was affected by the drop\n")
+	}
+}
+
+// TestEncryption tests that files are encrypted with wrapped data keys that can be rotated
+func TestEncryption(t *testing.T) {
+	dir, err := ioutil.TempDir("", "vdb-encryption")
+	if err != nil {
+		t.Fatalf("TempDir Error: %s\n", err.Error())
+	}
+	defer os.RemoveAll(dir)
+	master := bytes.Repeat([]byte{1}, KeySize)
+	other := bytes.Repeat([]byte{2}, KeySize)
+
+	keyring, err := OpenKeyring(dir, master)
+	if err != nil {
+		t.Fatalf("OpenKeyring Error: %s\n", err.Error())
+	}
+	s := NewEncrypted(testLogger, dir, keyring)
+	if _, err := s.CreateIndex(IndexDefinition{Name: "secret-index", Path: "age"}); err != nil {
+		t.Fatalf("CreateIndex Error: %s\n", err.Error())
+	}
+	s.Close()
+	filename := filepath.Join(dir, "indexes.gob")
+	if b, _ := ioutil.ReadFile(filename); len(b) == 0 || bytes.Contains(b, []byte("secret-index")) {
+		t.Fatalf("Index definitions were not encrypted\n")
+	}
+	s = NewEncrypted(testLogger, dir, keyring)
+	if status := s.Indexes(); len(status) != 1 || status[0].Name != "secret-index" {
+		t.Fatalf("Unexpected index status: %+v\n", status)
+	}
+	s.Close()
+
+	if _, err := OpenKeyring(dir, other); err != ErrWrongKey {
+		t.Fatalf("Expected ErrWrongKey, got %v\n", err)
+	}
+	var none *Keyring
+	if err := none.Check(dir); err == nil {
+		t.Fatalf("Encrypted files were read without a key\n")
+	}
+	// Files that are not encrypted are skipped
+	if err := ioutil.WriteFile(filepath.Join(dir, "plain.log"), []byte("not encrypted"), 0600); err != nil {
+		t.Fatalf("WriteFile Error: %s\n", err.Error())
+	}
+	if err := keyring.Check(dir); err != nil {
+		t.Fatalf("Check Error: %s\n", err.Error())
+	}
+
+	// Files encrypted with the old data key can be read until it is retired
+	old := keyring.Active()
+	if err := keyring.Rotate(other); err != nil {
+		t.Fatalf("Rotate Error: %s\n", err.Error())
+	}
+	if keyring.Active() == old {
+		t.Fatalf("Rotate did not change the active key\n")
+	}
+	if _, err := keyring.ReadFile(filename); err != nil {
+		t.Fatalf("ReadFile Error: %s\n", err.Error())
+	}
+	if err := keyring.Retire(); err != nil {
+		t.Fatalf("Retire Error: %s\n", err.Error())
+	}
+	if _, err := keyring.ReadFile(filename); err != ErrUnknownKey {
+		t.Fatalf("Expected ErrUnknownKey, got %v\n", err)
+	}
+	if err := keyring.WriteFile(filename, []byte("plain")); err != nil {
+		t.Fatalf("WriteFile Error: %s\n", err.Error())
+	}
+	if _, err := OpenKeyring(dir, master); err != ErrWrongKey {
+		t.Fatalf("Expected ErrWrongKey, got %v\n", err)
+	}
+	reopened, err := OpenKeyring(dir, other)
+	if err != nil {
+		t.Fatalf("OpenKeyring Error: %s\n", err.Error())
+	}
+	if b, err := reopened.ReadFile(filename); err != nil || string(b) != "plain" {
+		t.Fatalf("Unexpected file: %q, Error: %v\n", b, err)
+	}
+}
+
+// TestCompression tests that large values are compressed transparently
+func TestCompression(t *testing.T) {
+	s := New(testLogger, "")
+	defer s.Close()
+
+	large := fmt.Sprintf(`{"name": "large", "padding": "%s"}`, strings.Repeat("abc", 1000))
+	huge := fmt.Sprintf(`{"name": "huge", "padding": "%s"}`, strings.Repeat("xyz", 50000))
+	s.Set("small", `{"name": "small"}`)
+	s.Set("large", large)
+	s.Set("huge", huge)
+	if v := s.Get("large"); v != large {
+		t.Fatalf("Large value was not decompressed\n")
+	}
+	if v := s.Get("huge"); v != huge {
+		t.Fatalf("Huge value was not decompressed\n")
+	}
+	usage := s.Usage()
+	if usage.Keys != 3 || usage.Compressed != 2 || usage.Bytes != int64(len("small")+17+len("large")+len(large)+len("huge")+len(huge)) {
+		t.Fatalf("Unexpected usage: %+v\n", usage)
+	}
+	if usage.Ratio() < 10 {
+		t.Fatalf("Unexpected compression ratio: %f\n", usage.Ratio())
+	}
+
+	if _, err := s.CreateIndex(IndexDefinition{Name: "name", Path: "name"}); err != nil {
+		t.Fatalf("CreateIndex Error: %s\n", err.Error())
+	}
+	var entries []IndexEntry
+	var err error
+	for i := 0; i < 500; i++ {
+		if entries, err = s.QueryIndex(IndexQuery{Index: "name", Equal: `"large"`}); err != ErrIndexBuilding {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err != nil || len(entries) != 1 || entries[0].Key != "large" {
+		t.Fatalf("Unexpected entries: %v, Error: %v\n", entries, err)
+	}
+
+	// Replicas that do not compress have the same digests
+	compressed := NewHashtable()
+	compressed.Set("large", large)
+	threshold := CompressionThreshold
+	CompressionThreshold = 0
+	defer func() { CompressionThreshold = threshold }()
+	plain := NewHashtable()
+	plain.Set("large", large)
+	if compressed.NodeDigest(NodeLocator{}, nil).Digest != plain.NodeDigest(NodeLocator{}, nil).Digest {
+		t.Fatalf("Compression changed the digest\n")
+	}
+}
+
+// TestTimed tests collecting the timing of requests sent through a timed view of a storage instance
+func TestTimed(t *testing.T) {
+	s := New(testLogger, "")
+	defer s.Close()
+
+	var timer Timer
+	timed := s.Timed(&timer)
+	timed.Set("foo", "bar")
+	if v := timed.Get("foo"); v != "bar" {
+		t.Fatalf("Unexpected value: %s\n", v)
+	}
+	timed.PutVersions("baz", []NodeKeyValuePair{{Key: "baz", Value: "qux", Version: VersionVector{"x": 1}}})
+	if err := timed.PrepareIntent("t1", []NodeKeyValuePair{{Key: "quux", Value: "corge", Version: VersionVector{"x": 1}}}); err != nil {
+		t.Fatalf("PrepareIntent Error: %s\n", err.Error())
+	}
+	timed.CommitIntent("t1")
+	s.Get("foo")
+
+	timing := timer.Timing()
+	if timing.Requests != 5 {
+		t.Fatalf("Expected 5 timed requests, got %d\n", timing.Requests)
+	}
+	if timing.Wait < 0 || timing.Apply <= 0 || timing.Sync < 0 {
+		t.Fatalf("Unexpected timing: %+v\n", timing)
+	}
+	if v := s.Get("quux"); v != "corge" {
+		t.Fatalf("Expected the committed intent to be read through the instance, got %q\n", v)
+	}
+}
@@ -0,0 +1,129 @@
+/******
+This file is part of Vaelen/DB.
+
+Copyright 2017, Andrew Young <andrew@vaelen.org>
+
+    Vaelen/DB is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+    Vaelen/DB is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+    You should have received a copy of the GNU General Public License
+along with Vaelen/DB.  If not, see <http://www.gnu.org/licenses/>.
+******/
+
+package storage
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Timing breaks down the time that storage requests spent in a storage instance
+type Timing struct {
+	// Requests is the number of storage requests
+	Requests int
+	// Wait is the time the requests were queued before the storage thread picked them up
+	Wait time.Duration
+	// Apply is the time the storage thread spent reading and changing the storage tree
+	Apply time.Duration
+	// Sync is the time spent writing and syncing the transaction log, which the server adds to the timing itself
+	Sync time.Duration
+}
+
+// Timer collects the timing of the storage requests made on behalf of one operation.  It may be shared by several goroutines.
+type Timer struct {
+	lock   sync.Mutex
+	timing Timing
+}
+
+// Add adds to the collected timing.  Nothing is collected by a nil timer.
+func (t *Timer) Add(timing Timing) {
+	if t == nil {
+		return
+	}
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.timing.Requests += timing.Requests
+	t.timing.Wait += timing.Wait
+	t.timing.Apply += timing.Apply
+	t.timing.Sync += timing.Sync
+}
+
+// Timing returns the timing collected so far
+func (t *Timer) Timing() Timing {
+	if t == nil {
+		return Timing{}
+	}
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	return t.timing
+}
+
+// Timed returns a view of the storage instance whose requests add their timing to the given timer
+func (db *Instance) Timed(t *Timer) *Instance {
+	timed := *db
+	timed.timer = t
+	return &timed
+}
+
+// WithContext returns a view of the storage instance whose requests are logged with the given context,
+// so that the storage thread's log lines carry the request's ID
+func (db *Instance) WithContext(ctx context.Context) *Instance {
+	view := *db
+	view.ctx = ctx
+	return &view
+}
+
+// queued records when and on behalf of which request a request was sent to the storage thread
+type queued struct {
+	ctx   context.Context
+	timer *Timer
+	at    time.Time
+}
+
+// queue starts timing a request if the instance has a timer
+func (db *Instance) queue() queued {
+	if db.timer == nil {
+		return queued{ctx: db.ctx}
+	}
+	return queued{ctx: db.ctx, timer: db.timer, at: time.Now()}
+}
+
+// context returns the context of the request the storage request was sent for
+func (q queued) context() context.Context {
+	if q.ctx == nil {
+		return context.Background()
+	}
+	return q.ctx
+}
+
+// applying times a request picked up by the storage thread
+type applying struct {
+	timer *Timer
+	wait  time.Duration
+	start time.Time
+}
+
+// pick records how long a request waited and starts timing how long it takes to apply
+func (q queued) pick() applying {
+	if q.timer == nil {
+		return applying{}
+	}
+	now := time.Now()
+	return applying{timer: q.timer, wait: now.Sub(q.at), start: now}
+}
+
+// applied records how long the request took to apply
+func (a applying) applied() {
+	if a.timer == nil {
+		return
+	}
+	a.timer.Add(Timing{Requests: 1, Wait: a.wait, Apply: time.Since(a.start)})
+}
@@ -0,0 +1,143 @@
+/******
+This file is part of Vaelen/DB.
+
+Copyright 2017, Andrew Young <andrew@vaelen.org>
+
+    Vaelen/DB is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+    Vaelen/DB is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+    You should have received a copy of the GNU General Public License
+along with Vaelen/DB.  If not, see <http://www.gnu.org/licenses/>.
+******/
+
+package storage
+
+import (
+	"fmt"
+	"time"
+)
+
+// Ordering describes how two version vectors relate to each other
+type Ordering int
+
+const (
+	// Equal means both version vectors describe the same history
+	Equal Ordering = iota
+	// Before means the first version vector is an ancestor of the second
+	Before
+	// After means the first version vector descends from the second
+	After
+	// Concurrent means neither version vector descends from the other
+	Concurrent
+)
+
+// Timestamp is a hybrid logical clock reading.
+// Physical is a wall clock time in Unix nanoseconds and Logical orders events that share the same physical time.
+type Timestamp struct {
+	Physical int64
+	Logical  uint32
+}
+
+// Before returns true if the timestamp is older than the given timestamp
+func (t Timestamp) Before(o Timestamp) bool {
+	return t.Physical < o.Physical || (t.Physical == o.Physical && t.Logical < o.Logical)
+}
+
+// IsZero returns true if the timestamp has not been set
+func (t Timestamp) IsZero() bool {
+	return t.Physical == 0 && t.Logical == 0
+}
+
+// String formats the timestamp as an RFC3339 time followed by the logical counter
+func (t Timestamp) String() string {
+	return fmt.Sprintf("%s/%d", time.Unix(0, t.Physical).UTC().Format(time.RFC3339Nano), t.Logical)
+}
+
+// VersionVector tracks the number of updates each replica has coordinated for a value
+type VersionVector map[string]uint64
+
+// Copy returns a copy of the version vector
+func (v VersionVector) Copy() VersionVector {
+	c := make(VersionVector, len(v))
+	for k, x := range v {
+		c[k] = x
+	}
+	return c
+}
+
+// Increment returns a copy of the version vector with the counter for the given replica incremented
+func (v VersionVector) Increment(replica string) VersionVector {
+	c := v.Copy()
+	c[replica]++
+	return c
+}
+
+// Merge returns a version vector that descends from both version vectors
+func (v VersionVector) Merge(o VersionVector) VersionVector {
+	c := v.Copy()
+	for k, x := range o {
+		if x > c[k] {
+			c[k] = x
+		}
+	}
+	return c
+}
+
+// Compare returns the ordering of this version vector relative to the given version vector
+func (v VersionVector) Compare(o VersionVector) Ordering {
+	before, after := false, false
+	for k, x := range v {
+		if x > o[k] {
+			after = true
+		} else if x < o[k] {
+			before = true
+		}
+	}
+	for k, x := range o {
+		if _, ok := v[k]; !ok && x > 0 {
+			before = true
+		}
+	}
+	switch {
+	case before && after:
+		return Concurrent
+	case before:
+		return Before
+	case after:
+		return After
+	}
+	return Equal
+}
+
+// Reconcile adds a new version to a set of siblings.
+// Siblings that the new version descends from are discarded.
+// If an existing sibling already descends from the new version, the new version is discarded.
+// The returned boolean is true if the set of siblings was changed.
+func Reconcile(siblings []NodeKeyValuePair, v NodeKeyValuePair) ([]NodeKeyValuePair, bool) {
+	result := make([]NodeKeyValuePair, 0, len(siblings)+1)
+	for _, s := range siblings {
+		switch v.Version.Compare(s.Version) {
+		case Before:
+			// We already have something newer
+			return siblings, false
+		case Equal:
+			if s.Value == v.Value && s.Deleted == v.Deleted && s.Expires.Equal(v.Expires) && s.Flags == v.Flags {
+				return siblings, false
+			}
+			if s.Deleted && !v.Deleted && !v.Expires.IsZero() {
+				// The tombstone is what the expired version was swept into
+				return siblings, false
+			}
+		case Concurrent:
+			result = append(result, s)
+		}
+	}
+	return append(result, v), true
+}
@@ -0,0 +1,205 @@
+/******
+This file is part of Vaelen/DB.
+
+Copyright 2017, Andrew Young <andrew@vaelen.org>
+
+    Vaelen/DB is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+    Vaelen/DB is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+    You should have received a copy of the GNU General Public License
+along with Vaelen/DB.  If not, see <http://www.gnu.org/licenses/>.
+******/
+
+package trace
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ScopeName is the instrumentation scope exported spans are reported under
+const ScopeName = "github.com/vaelen/db"
+
+// OTLP status codes
+const (
+	statusUnset = 0
+	statusError = 2
+)
+
+// otlpRequest is the body of an OTLP/HTTP JSON export request
+type otlpRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpAttribute `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceID           string          `json:"traceId"`
+	SpanID            string          `json:"spanId"`
+	ParentSpanID      string          `json:"parentSpanId,omitempty"`
+	Name              string          `json:"name"`
+	Kind              Kind            `json:"kind"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+	Attributes        []otlpAttribute `json:"attributes,omitempty"`
+	Status            otlpStatus      `json:"status"`
+}
+
+type otlpAttribute struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+type otlpValue struct {
+	StringValue string `json:"stringValue"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+// logSpan is a span written by LogExporter
+type logSpan struct {
+	Service string `json:"service"`
+	otlpSpan
+}
+
+// otlpAttributes converts a map of attributes to the OTLP format
+func otlpAttributes(attributes map[string]string) []otlpAttribute {
+	a := make([]otlpAttribute, 0, len(attributes))
+	for key, value := range attributes {
+		a = append(a, otlpAttribute{Key: key, Value: otlpValue{StringValue: value}})
+	}
+	return a
+}
+
+// unixNano formats a time as OTLP does
+func unixNano(t time.Time) string {
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
+
+// toOTLP converts a finished span to the OTLP format
+func toOTLP(span *Span) otlpSpan {
+	span.Lock()
+	defer span.Unlock()
+	s := otlpSpan{
+		TraceID:           span.Context.TraceID.String(),
+		SpanID:            span.Context.SpanID.String(),
+		Name:              span.Name,
+		Kind:              span.Kind,
+		StartTimeUnixNano: unixNano(span.Start),
+		EndTimeUnixNano:   unixNano(span.End),
+		Attributes:        otlpAttributes(span.Attributes),
+		Status:            otlpStatus{Code: statusUnset},
+	}
+	if !span.Parent.IsZero() {
+		s.ParentSpanID = span.Parent.String()
+	}
+	if span.Error != "" {
+		s.Status = otlpStatus{Code: statusError, Message: span.Error}
+	}
+	return s
+}
+
+// OTLPExporter sends spans to an OpenTelemetry collector using OTLP/HTTP with JSON encoding
+type OTLPExporter struct {
+	// Endpoint is the URL spans are posted to, usually ending in /v1/traces
+	Endpoint string
+	Client   *http.Client
+}
+
+// NewOTLPExporter creates an exporter that posts spans to the given collector URL
+func NewOTLPExporter(endpoint string) *OTLPExporter {
+	return &OTLPExporter{
+		Endpoint: endpoint,
+		Client:   &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Export posts a batch of spans to the collector
+func (e *OTLPExporter) Export(service string, spans []*Span) error {
+	scope := otlpScopeSpans{
+		Scope: otlpScope{Name: ScopeName},
+		Spans: make([]otlpSpan, 0, len(spans)),
+	}
+	for _, span := range spans {
+		scope.Spans = append(scope.Spans, toOTLP(span))
+	}
+	request := otlpRequest{
+		ResourceSpans: []otlpResourceSpans{{
+			Resource:   otlpResource{Attributes: otlpAttributes(map[string]string{"service.name": service})},
+			ScopeSpans: []otlpScopeSpans{scope},
+		}},
+	}
+	body, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+	response, err := e.Client.Post(e.Endpoint, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	io.Copy(ioutil.Discard, response.Body)
+	if response.StatusCode/100 != 2 {
+		return fmt.Errorf("collector returned %s", response.Status)
+	}
+	return nil
+}
+
+// LogExporter writes spans as JSON, one span per line, in the same format OTLPExporter sends them
+type LogExporter struct {
+	lock   sync.Mutex
+	writer io.Writer
+}
+
+// NewLogExporter creates an exporter that writes spans to the given writer
+func NewLogExporter(w io.Writer) *LogExporter {
+	return &LogExporter{writer: w}
+}
+
+// Export writes a batch of spans
+func (e *LogExporter) Export(service string, spans []*Span) error {
+	var b bytes.Buffer
+	encoder := json.NewEncoder(&b)
+	for _, span := range spans {
+		if err := encoder.Encode(logSpan{Service: service, otlpSpan: toOTLP(span)}); err != nil {
+			return err
+		}
+	}
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	_, err := e.writer.Write(b.Bytes())
+	return err
+}
@@ -0,0 +1,453 @@
+/******
+This file is part of Vaelen/DB.
+
+Copyright 2017, Andrew Young <andrew@vaelen.org>
+
+    Vaelen/DB is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+    Vaelen/DB is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+    You should have received a copy of the GNU General Public License
+along with Vaelen/DB.  If not, see <http://www.gnu.org/licenses/>.
+******/
+
+// Package trace records spans of the work done on behalf of a request and propagates them between processes.
+// Span contexts travel in gRPC metadata using the W3C Trace Context format and finished spans are exported
+// in the OpenTelemetry (OTLP) format, so traces can be followed from the client through every shard they touch.
+package trace
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// TraceparentHeader is the metadata key span contexts are sent in
+const TraceparentHeader = "traceparent"
+
+const (
+	// BatchSize is the number of finished spans that are exported together
+	BatchSize = 512
+	// BatchInterval is the longest a finished span waits before it is exported
+	BatchInterval = time.Second
+	// queueSize is the number of finished spans that can wait to be exported before new ones are dropped
+	queueSize = 4096
+)
+
+// TraceID identifies a trace
+type TraceID [16]byte
+
+// String returns the trace ID in hex
+func (t TraceID) String() string {
+	return hex.EncodeToString(t[:])
+}
+
+// IsZero returns true if the trace ID is not set
+func (t TraceID) IsZero() bool {
+	return t == TraceID{}
+}
+
+// SpanID identifies a span within a trace
+type SpanID [8]byte
+
+// String returns the span ID in hex
+func (s SpanID) String() string {
+	return hex.EncodeToString(s[:])
+}
+
+// IsZero returns true if the span ID is not set
+func (s SpanID) IsZero() bool {
+	return s == SpanID{}
+}
+
+// SpanContext identifies a span across processes
+type SpanContext struct {
+	TraceID TraceID
+	SpanID  SpanID
+	// Sampled is true if the spans of the trace are exported
+	Sampled bool
+}
+
+// IsValid returns true if both the trace and span IDs are set
+func (c SpanContext) IsValid() bool {
+	return !c.TraceID.IsZero() && !c.SpanID.IsZero()
+}
+
+// Traceparent formats the span context as a W3C traceparent header
+func (c SpanContext) Traceparent() string {
+	flags := 0
+	if c.Sampled {
+		flags = 1
+	}
+	return fmt.Sprintf("00-%s-%s-%02x", c.TraceID, c.SpanID, flags)
+}
+
+// ParseTraceparent parses a W3C traceparent header
+func ParseTraceparent(value string) (SpanContext, error) {
+	var c SpanContext
+	parts := strings.Split(strings.TrimSpace(value), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" || (parts[0] == "00" && len(parts) != 4) {
+		return c, fmt.Errorf("invalid traceparent: %q", value)
+	}
+	var flags [1]byte
+	if err := decodeHex(c.TraceID[:], parts[1]); err != nil {
+		return c, fmt.Errorf("invalid trace ID in traceparent: %q", value)
+	}
+	if err := decodeHex(c.SpanID[:], parts[2]); err != nil {
+		return c, fmt.Errorf("invalid span ID in traceparent: %q", value)
+	}
+	if err := decodeHex(flags[:], parts[3]); err != nil {
+		return c, fmt.Errorf("invalid flags in traceparent: %q", value)
+	}
+	if !c.IsValid() {
+		return c, fmt.Errorf("invalid traceparent: %q", value)
+	}
+	c.Sampled = flags[0]&1 == 1
+	return c, nil
+}
+
+// decodeHex decodes a hex string that must fill b exactly
+func decodeHex(b []byte, s string) error {
+	if hex.DecodedLen(len(s)) != len(b) {
+		return hex.ErrLength
+	}
+	_, err := hex.Decode(b, []byte(s))
+	return err
+}
+
+// Kind describes the relationship between a span and its parent
+type Kind int
+
+const (
+	// Internal spans cover work done within a process
+	Internal Kind = iota + 1
+	// Server spans cover requests received from another process
+	Server
+	// Client spans cover requests sent to another process
+	Client
+)
+
+// String returns the name of the span kind
+func (k Kind) String() string {
+	switch k {
+	case Server:
+		return "server"
+	case Client:
+		return "client"
+	default:
+		return "internal"
+	}
+}
+
+// Span records a piece of work done on behalf of a request.
+// The methods of a nil span do nothing, so code does not need to check whether tracing is enabled.
+type Span struct {
+	sync.Mutex
+	Name    string
+	Kind    Kind
+	Context SpanContext
+	// Parent is the span this span was started within, it is zero for the first span of a trace
+	Parent     SpanID
+	Start      time.Time
+	End        time.Time
+	Attributes map[string]string
+	// Error is the error the work failed with, it is empty if the work succeeded
+	Error  string
+	tracer *Tracer
+}
+
+// SpanContext returns the context that identifies the span
+func (s *Span) SpanContext() SpanContext {
+	if s == nil {
+		return SpanContext{}
+	}
+	return s.Context
+}
+
+// SetAttribute records a detail of the work done
+func (s *Span) SetAttribute(key string, value string) {
+	if s == nil {
+		return
+	}
+	s.Lock()
+	defer s.Unlock()
+	s.Attributes[key] = value
+}
+
+// SetError records that the work failed. Nothing is recorded when err is nil.
+func (s *Span) SetError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.Lock()
+	defer s.Unlock()
+	s.Error = err.Error()
+}
+
+// Finish ends the span and queues it to be exported if its trace is sampled
+func (s *Span) Finish() {
+	if s == nil {
+		return
+	}
+	s.Lock()
+	finished := !s.End.IsZero()
+	if !finished {
+		s.End = time.Now()
+	}
+	s.Unlock()
+	if !finished && s.Context.Sampled {
+		s.tracer.queue(s)
+	}
+}
+
+type spanKey struct{}
+
+type remoteKey struct{}
+
+// FromContext returns the span carried by a context, or nil if there is none
+func FromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanKey{}).(*Span)
+	return span
+}
+
+// WithSpan returns a copy of ctx that carries the given span
+func WithSpan(ctx context.Context, span *Span) context.Context {
+	if span == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, spanKey{}, span)
+}
+
+// WithRemoteParent returns a copy of ctx whose next span continues a trace started in another process
+func WithRemoteParent(ctx context.Context, parent SpanContext) context.Context {
+	if !parent.IsValid() {
+		return ctx
+	}
+	return context.WithValue(ctx, remoteKey{}, parent)
+}
+
+// Detach returns a background context that carries the span of ctx but is not cancelled with it.
+// It is used for work that outlives the request it was started for.
+func Detach(ctx context.Context) context.Context {
+	return WithSpan(context.Background(), FromContext(ctx))
+}
+
+// parent returns the span context new spans started from ctx descend from
+func parent(ctx context.Context) (SpanContext, bool) {
+	if span := FromContext(ctx); span != nil {
+		return span.Context, true
+	}
+	remote, ok := ctx.Value(remoteKey{}).(SpanContext)
+	return remote, ok
+}
+
+// Exporter sends finished spans to wherever they are collected
+type Exporter interface {
+	Export(service string, spans []*Span) error
+}
+
+// Tracer starts spans and exports them in batches once they are finished.
+// The methods of a nil tracer start no spans, which leaves tracing disabled.
+type Tracer struct {
+	Logger *slog.Logger
+	// Service is the name of the process the spans are reported for
+	Service string
+	// SampleRate is the fraction of new traces that are exported.  Traces started in another process follow the decision made there.
+	SampleRate float64
+	exporter   Exporter
+	spans      chan *Span
+	done       chan bool
+	dropped    uint64
+	lock       sync.Mutex
+	closed     bool
+}
+
+// NewTracer creates a tracer that sends spans to the given exporter and starts its export thread
+func NewTracer(logger *slog.Logger, service string, exporter Exporter) *Tracer {
+	t := &Tracer{
+		Logger:     logger,
+		Service:    service,
+		SampleRate: 1,
+		exporter:   exporter,
+		spans:      make(chan *Span, queueSize),
+		done:       make(chan bool),
+	}
+	go t.run()
+	return t
+}
+
+// Start starts a span within the span carried by ctx and returns a context that carries the new span.
+// The span must be finished by calling Finish.
+func (t *Tracer) Start(ctx context.Context, name string, kind Kind) (context.Context, *Span) {
+	if t == nil {
+		return ctx, nil
+	}
+	span := &Span{
+		Name:       name,
+		Kind:       kind,
+		Start:      time.Now(),
+		Attributes: make(map[string]string),
+		tracer:     t,
+	}
+	if p, ok := parent(ctx); ok {
+		span.Context = SpanContext{TraceID: p.TraceID, Sampled: p.Sampled}
+		span.Parent = p.SpanID
+	} else {
+		rand.Read(span.Context.TraceID[:])
+		span.Context.Sampled = t.sample(span.Context.TraceID)
+	}
+	rand.Read(span.Context.SpanID[:])
+	return WithSpan(ctx, span), span
+}
+
+// sample decides whether a new trace is exported.
+// The decision is taken from the trace ID so that it is the same wherever it is made.
+func (t *Tracer) sample(id TraceID) bool {
+	if t.SampleRate >= 1 {
+		return true
+	}
+	return float64(binary.BigEndian.Uint64(id[8:])>>11)/(1<<53) < t.SampleRate
+}
+
+// Dropped returns the number of finished spans that were dropped because the export queue was full
+func (t *Tracer) Dropped() uint64 {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	return t.dropped
+}
+
+// queue hands a finished span to the export thread
+func (t *Tracer) queue(span *Span) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if t.closed {
+		return
+	}
+	select {
+	case t.spans <- span:
+	default:
+		t.dropped++
+	}
+}
+
+// run exports finished spans until the tracer is closed
+func (t *Tracer) run() {
+	defer close(t.done)
+	ticker := time.NewTicker(BatchInterval)
+	defer ticker.Stop()
+	batch := make([]*Span, 0, BatchSize)
+	for {
+		select {
+		case span, ok := <-t.spans:
+			if !ok {
+				t.export(batch)
+				return
+			}
+			batch = append(batch, span)
+			if len(batch) >= BatchSize {
+				batch = t.export(batch)
+			}
+		case <-ticker.C:
+			batch = t.export(batch)
+		}
+	}
+}
+
+// export sends a batch of spans to the exporter and returns an empty batch
+func (t *Tracer) export(batch []*Span) []*Span {
+	if len(batch) == 0 {
+		return batch
+	}
+	if err := t.exporter.Export(t.Service, batch); err != nil {
+		t.Logger.Warn("Could not export spans", "spans", len(batch), "error", err)
+	}
+	return make([]*Span, 0, BatchSize)
+}
+
+// Close exports the spans that have already finished and stops the export thread
+func (t *Tracer) Close() {
+	if t == nil {
+		return
+	}
+	t.lock.Lock()
+	if !t.closed {
+		t.closed = true
+		close(t.spans)
+	}
+	t.lock.Unlock()
+	<-t.done
+}
+
+// Inject returns a copy of ctx that sends the span context of the span it carries to the process a request is sent to
+func Inject(ctx context.Context) context.Context {
+	span := FromContext(ctx)
+	if span == nil {
+		return ctx
+	}
+	return metadata.AppendToOutgoingContext(ctx, TraceparentHeader, span.Context.Traceparent())
+}
+
+// Extract returns a copy of ctx whose next span continues the trace of the process a request was received from
+func Extract(ctx context.Context) context.Context {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ctx
+	}
+	values := md.Get(TraceparentHeader)
+	if len(values) == 0 {
+		return ctx
+	}
+	remote, err := ParseTraceparent(values[0])
+	if err != nil {
+		return ctx
+	}
+	return WithRemoteParent(ctx, remote)
+}
+
+// StartServer starts a server span for a gRPC request, continuing the caller's trace if it sent one
+func (t *Tracer) StartServer(ctx context.Context, method string) (context.Context, *Span) {
+	if t == nil {
+		return ctx, nil
+	}
+	ctx, span := t.Start(Extract(ctx), method, Server)
+	span.SetAttribute("rpc.system", "grpc")
+	span.SetAttribute("rpc.method", method)
+	return ctx, span
+}
+
+// UnaryClientInterceptor starts a client span for every request sent over a connection and sends its span context with the request
+func (t *Tracer) UnaryClientInterceptor(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	if t == nil {
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+	ctx, span := t.Start(ctx, method, Client)
+	defer span.Finish()
+	span.SetAttribute("rpc.system", "grpc")
+	span.SetAttribute("rpc.method", method)
+	span.SetAttribute("net.peer.name", cc.Target())
+	err := invoker(Inject(ctx), method, req, reply, cc, opts...)
+	Status(span, err)
+	return err
+}
+
+// Status records the gRPC status code of a request on its span
+func Status(span *Span, err error) {
+	span.SetAttribute("rpc.grpc.status_code", fmt.Sprint(int(status.Code(err))))
+	span.SetError(err)
+}
@@ -0,0 +1,187 @@
+/******
+This file is part of Vaelen/DB.
+
+Copyright 2017, Andrew Young <andrew@vaelen.org>
+
+    Vaelen/DB is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+    Vaelen/DB is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+    You should have received a copy of the GNU General Public License
+along with Vaelen/DB.  If not, see <http://www.gnu.org/licenses/>.
+******/
+
+package trace
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/vaelen/db/logging"
+)
+
+// testLogger is the logger of the tracers used by the tests
+var testLogger = logging.Text(ioutil.Discard).Logger("trace")
+
+// TestTraceparent tests formatting and parsing span contexts
+func TestTraceparent(t *testing.T) {
+	header := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	c, err := ParseTraceparent(header)
+	if err != nil {
+		t.Fatalf("Parse Error: %s\n", err.Error())
+	}
+	if c.TraceID.String() != "4bf92f3577b34da6a3ce929d0e0e4736" || c.SpanID.String() != "00f067aa0ba902b7" || !c.Sampled {
+		t.Fatalf("Unexpected span context: %+v\n", c)
+	}
+	if c.Traceparent() != header {
+		t.Fatalf("Expected %s, got %s\n", header, c.Traceparent())
+	}
+	for _, invalid := range []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e473-00f067aa0ba902b7-01",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+	} {
+		if _, err := ParseTraceparent(invalid); err == nil {
+			t.Errorf("Expected %q to be rejected\n", invalid)
+		}
+	}
+	if _, err := ParseTraceparent("01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00-extra"); err != nil {
+		t.Errorf("Expected later versions to be accepted: %s\n", err.Error())
+	}
+}
+
+// memoryExporter keeps exported spans in memory
+type memoryExporter struct {
+	sync.Mutex
+	spans []*Span
+}
+
+func (e *memoryExporter) Export(service string, spans []*Span) error {
+	e.Lock()
+	defer e.Unlock()
+	e.spans = append(e.spans, spans...)
+	return nil
+}
+
+// TestTracer tests that spans are linked to their parents and that sampling decisions are followed
+func TestTracer(t *testing.T) {
+	exporter := &memoryExporter{}
+	tracer := NewTracer(testLogger, "test", exporter)
+
+	ctx, root := tracer.Start(context.Background(), "root", Server)
+	_, child := tracer.Start(ctx, "child", Internal)
+	child.SetError(errors.New("failed"))
+	child.Finish()
+	root.Finish()
+	root.Finish()
+
+	remote := SpanContext{TraceID: TraceID{1}, SpanID: SpanID{2}}
+	_, unsampled := tracer.Start(WithRemoteParent(context.Background(), remote), "unsampled", Server)
+	unsampled.Finish()
+
+	tracer.SampleRate = 0
+	_, dropped := tracer.Start(context.Background(), "dropped", Server)
+	dropped.Finish()
+	tracer.Close()
+
+	if len(exporter.spans) != 2 {
+		t.Fatalf("Expected 2 exported spans, got %d\n", len(exporter.spans))
+	}
+	if exporter.spans[0] != child || exporter.spans[1] != root {
+		t.Fatalf("Unexpected spans: %v\n", exporter.spans)
+	}
+	if child.Context.TraceID != root.Context.TraceID || child.Parent != root.Context.SpanID || !root.Parent.IsZero() {
+		t.Errorf("Child is not linked to its parent: %+v %+v\n", root.Context, child)
+	}
+	if child.Error != "failed" {
+		t.Errorf("Expected error to be recorded, got %q\n", child.Error)
+	}
+	if unsampled.Context.TraceID != remote.TraceID || unsampled.Parent != remote.SpanID {
+		t.Errorf("Remote parent was not continued: %+v\n", unsampled)
+	}
+
+	var span *Span
+	span.SetAttribute("key", "value")
+	span.Finish()
+	var disabled *Tracer
+	if _, span := disabled.Start(context.Background(), "disabled", Internal); span != nil {
+		t.Errorf("Expected no span from a nil tracer\n")
+	}
+}
+
+// TestExporters tests that spans are exported in the OTLP JSON format
+func TestExporters(t *testing.T) {
+	var lock sync.Mutex
+	var requests []string
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		lock.Lock()
+		requests = append(requests, r.Method+" "+r.URL.Path+" "+r.Header.Get("Content-Type")+" "+string(body))
+		lock.Unlock()
+	}))
+	defer collector.Close()
+
+	var log bytes.Buffer
+	otlp := NewTracer(testLogger, "vdb-test", NewOTLPExporter(collector.URL+"/v1/traces"))
+	file := NewTracer(testLogger, "vdb-test", NewLogExporter(&log))
+	for _, tracer := range []*Tracer{otlp, file} {
+		ctx, parent := tracer.Start(context.Background(), "parent", Client)
+		_, span := tracer.Start(ctx, "span", Server)
+		span.SetAttribute("rpc.method", "/api.Database/Get")
+		span.SetError(errors.New("failed"))
+		span.Finish()
+		parent.Finish()
+		tracer.Close()
+	}
+
+	if len(requests) != 1 {
+		t.Fatalf("Expected 1 export request, got %d\n", len(requests))
+	}
+	for _, expected := range []string{
+		"POST /v1/traces application/json ",
+		`"resource":{"attributes":[{"key":"service.name","value":{"stringValue":"vdb-test"}}]}`,
+		`"name":"span","kind":2,`,
+		`"attributes":[{"key":"rpc.method","value":{"stringValue":"/api.Database/Get"}}],"status":{"code":2,"message":"failed"}`,
+		`"parentSpanId":"`,
+	} {
+		if !strings.Contains(requests[0], expected) {
+			t.Errorf("Expected export request to contain %s, got %s\n", expected, requests[0])
+		}
+	}
+
+	lines := strings.Split(strings.TrimSpace(log.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 logged spans, got %d\n", len(lines))
+	}
+	var logged struct {
+		Service      string
+		TraceID      string
+		ParentSpanID string
+		Name         string
+		Kind         int
+	}
+	if err := json.Unmarshal([]byte(lines[0]), &logged); err != nil {
+		t.Fatalf("Unmarshal Error: %s\n", err.Error())
+	}
+	if logged.Service != "vdb-test" || logged.Name != "span" || logged.Kind != int(Server) || len(logged.TraceID) != 32 || len(logged.ParentSpanID) != 16 {
+		t.Errorf("Unexpected logged span: %s\n", lines[0])
+	}
+}